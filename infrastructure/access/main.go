@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ec2transitgateway"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+
+	"aurora-bluegreen-lab/internal/components"
+	labconfig "aurora-bluegreen-lab/internal/config"
+	"aurora-bluegreen-lab/internal/cost"
+	"aurora-bluegreen-lab/internal/labels"
+	"aurora-bluegreen-lab/internal/providers"
+)
+
+func main() {
+	pulumi.Run(func(ctx *pulumi.Context) error {
+		// Load configuration
+		cfg := config.New(ctx, "")
+		settings, err := labconfig.LoadAccess(cfg)
+		if err != nil {
+			return err
+		}
+
+		lb, err := labels.New(ctx, cfg)
+		if err != nil {
+			return err
+		}
+
+		// Create the AWS provider for the stack's region
+		provider, region, err := providers.New(ctx, cfg, lb)
+		if err != nil {
+			return err
+		}
+		inRegion := pulumi.Provider(provider)
+
+		// Reference the VPC stack
+		vpcStackRef, err := pulumi.NewStackReference(ctx, settings.VpcStackName, nil)
+		if err != nil {
+			return err
+		}
+		securityGroupId := vpcStackRef.GetStringOutput(pulumi.String("instanceConnectSecurityGroupId"))
+
+		// The endpoint goes into a private subnet outside the Aurora tier; an
+		// existing VPC may have no EKS subnets, in which case subnetId is needed
+		subnetId := pulumi.String(settings.SubnetId).ToStringOutput()
+		if settings.SubnetId == "" {
+			subnetId = vpcStackRef.GetOutput(pulumi.String("eksSubnet1Id")).ApplyT(func(v interface{}) (string, error) {
+				id, _ := v.(string)
+				if id == "" {
+					return "", fmt.Errorf("VPC stack %s has no EKS subnets; set subnetId to place the endpoint", settings.VpcStackName)
+				}
+				return id, nil
+			}).(pulumi.StringOutput)
+		}
+
+		// The endpoint reaches any instance in the VPC whose security group
+		// admits SSH from the endpoint's group, as the EC2 group does. Client
+		// IPs are not preserved, so the instances see the endpoint's address
+		endpoint, err := ec2transitgateway.NewInstanceConnectEndpoint(ctx, lb.Name("instance-connect-endpoint"), &ec2transitgateway.InstanceConnectEndpointArgs{
+			SubnetId:         subnetId,
+			SecurityGroupIds: pulumi.StringArray{securityGroupId},
+			PreserveClientIp: pulumi.Bool(false),
+			Tags:             lb.Tags(lb.Name("instance-connect-endpoint")),
+		}, inRegion)
+		if err != nil {
+			return err
+		}
+
+		// Export outputs
+		ctx.Export("region", pulumi.String(region))
+		ctx.Export("instanceConnectEndpointId", endpoint.ID())
+		ctx.Export("subnetId", subnetId)
+		ctx.Export("connectCommand", pulumi.String(fmt.Sprintf(
+			"aws ec2-instance-connect ssh --region %s --connection-type eice --instance-id <instance-id> --os-user ec2-user", region)))
+
+		// EC2 Instance Connect Endpoints are free; the SSH traffic stays in the VPC
+		var estimate cost.Estimate
+		if err := estimate.Export(ctx); err != nil {
+			return err
+		}
+
+		// Publish the key outputs for runtime discovery without Pulumi access
+		outputParameters, err := components.NewLabOutputParameters(ctx, lb.Name("access-outputs"), &components.LabOutputParametersArgs{
+			Labels: lb,
+			Stack:  "access",
+			Values: map[string]pulumi.StringInput{
+				"region":                    pulumi.String(region),
+				"instanceConnectEndpointId": endpoint.ID(),
+			},
+		}, inRegion)
+		if err != nil {
+			return err
+		}
+		ctx.Export("outputParameterPrefix", pulumi.String(outputParameters.Prefix))
+
+		return nil
+	})
+}
@@ -1,204 +1,329 @@
 package main
 
 import (
-	"fmt"
-
-	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/rds"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+
+	"aurora-bluegreen-lab/internal/components"
+	labconfig "aurora-bluegreen-lab/internal/config"
+	"aurora-bluegreen-lab/internal/cost"
+	"aurora-bluegreen-lab/internal/dbconn"
+	"aurora-bluegreen-lab/internal/labels"
+	"aurora-bluegreen-lab/internal/providers"
 )
 
 func main() {
 	pulumi.Run(func(ctx *pulumi.Context) error {
 		// Load configuration
 		cfg := config.New(ctx, "")
-
-		projectName := cfg.Get("projectName")
-		if projectName == "" {
-			projectName = "aurora-bluegreen-lab"
+		settings, err := labconfig.LoadAurora(cfg)
+		if err != nil {
+			return err
 		}
 
-		dbName := cfg.Get("databaseName")
-		if dbName == "" {
-			dbName = "lab_db"
+		lb, err := labels.New(ctx, cfg)
+		if err != nil {
+			return err
 		}
 
-		dbUsername := cfg.Get("masterUsername")
-		if dbUsername == "" {
-			dbUsername = "admin"
+		// Create the AWS provider for the stack's region
+		provider, region, err := providers.New(ctx, cfg, lb)
+		if err != nil {
+			return err
 		}
+		inRegion := pulumi.Provider(provider)
 
 		dbPassword := cfg.RequireSecret("masterPassword")
 
-		engineVersion := cfg.Get("engineVersion")
-		if engineVersion == "" {
-			engineVersion = "8.0.mysql_aurora.3.04.0"
-		}
-
-		instanceClass := cfg.Get("instanceClass")
-		if instanceClass == "" {
-			instanceClass = "db.r6g.xlarge"
+		// Parameter groups start from the lab defaults below; the parameters config
+		// object (or parametersFile JSON) overrides or adds entries by name, and
+		// greenParameters (or greenParametersFile) is layered on top for green.
+		// The family follows engineVersion (aurora-mysql5.7 or aurora-mysql8.0)
+		defaults := components.ParameterSet{
+			Family: settings.ParameterGroupFamily,
+			Cluster: []components.Parameter{
+				{Name: "character_set_server", Value: "utf8mb4"},
+				{Name: "collation_server", Value: "utf8mb4_unicode_ci"},
+				// binlog_format is static and takes effect after the next reboot
+				{Name: "binlog_format", Value: settings.BinlogFormat, ApplyMethod: "pending-reboot"},
+				{Name: "binlog_row_image", Value: settings.BinlogRowImage},
+			},
+			Instance: []components.Parameter{
+				{Name: "max_connections", Value: "1000"},
+			},
 		}
+		parameters := defaults.Merge(settings.Parameters)
 
 		// Reference VPC stack outputs
-		vpcStack := cfg.Require("vpcStackName")
-		vpcStackRef, err := pulumi.NewStackReference(ctx, vpcStack, nil)
+		vpcStackRef, err := pulumi.NewStackReference(ctx, settings.VpcStackName, nil)
 		if err != nil {
 			return err
 		}
 
-		auroraSubnet1Id := vpcStackRef.GetStringOutput(pulumi.String("auroraSubnet1Id"))
-		auroraSubnet2Id := vpcStackRef.GetStringOutput(pulumi.String("auroraSubnet2Id"))
-		auroraSecurityGroupId := vpcStackRef.GetStringOutput(pulumi.String("auroraSecurityGroupId"))
+		// Reference the secondary region's VPC stack outputs
+		var secondary *components.SecondaryClusterArgs
+		if settings.SecondaryRegion != "" {
+			secondaryVpcStackRef, err := pulumi.NewStackReference(ctx, settings.SecondaryVpcStackName, nil)
+			if err != nil {
+				return err
+			}
+			secondary = &components.SecondaryClusterArgs{
+				Region: settings.SecondaryRegion,
+				SubnetIds: pulumi.StringArray{
+					secondaryVpcStackRef.GetStringOutput(pulumi.String("auroraSubnet1Id")),
+					secondaryVpcStackRef.GetStringOutput(pulumi.String("auroraSubnet2Id")),
+				},
+				SecurityGroupId: secondaryVpcStackRef.GetStringOutput(pulumi.String("auroraSecurityGroupId")),
+			}
+		}
 
-		// Create DB Subnet Group
-		dbSubnetGroup, err := rds.NewSubnetGroup(ctx, fmt.Sprintf("%s-db-subnet-group", projectName), &rds.SubnetGroupArgs{
-			Name: pulumi.String(fmt.Sprintf("%s-aurora-subnet-group", projectName)),
+		// The external replica and the proxy are placed in the lab VPC and
+		// reachable from the simulator instances
+		if settings.ExternalReplica != nil {
+			settings.ExternalReplica.VpcId = vpcStackRef.GetStringOutput(pulumi.String("vpcId"))
+			settings.ExternalReplica.ClientSecurityGroupId = vpcStackRef.GetStringOutput(pulumi.String("ec2SecurityGroupId"))
+		}
+		if settings.RdsProxy != nil {
+			settings.RdsProxy.VpcId = vpcStackRef.GetStringOutput(pulumi.String("vpcId"))
+			settings.RdsProxy.ClientSecurityGroupId = vpcStackRef.GetStringOutput(pulumi.String("ec2SecurityGroupId"))
+		}
+
+		// Dual-stack endpoints follow the VPC stack's enableIpv6; VPC stacks
+		// deployed before it existed have no ipv6Enabled output
+		networkType := vpcStackRef.GetOutput(pulumi.String("ipv6Enabled")).ApplyT(func(enabled interface{}) string {
+			if enabled == true {
+				return "DUAL"
+			}
+			return "IPV4"
+		}).(pulumi.StringOutput)
+
+		// Create the Aurora cluster
+		aurora, err := components.NewLabAuroraCluster(ctx, lb.Name("aurora"), &components.LabAuroraClusterArgs{
+			Labels: lb,
 			SubnetIds: pulumi.StringArray{
-				auroraSubnet1Id,
-				auroraSubnet2Id,
-			},
-			Tags: pulumi.StringMap{
-				"Name":    pulumi.String(fmt.Sprintf("%s-aurora-subnet-group", projectName)),
-				"Project": pulumi.String(projectName),
+				vpcStackRef.GetStringOutput(pulumi.String("auroraSubnet1Id")),
+				vpcStackRef.GetStringOutput(pulumi.String("auroraSubnet2Id")),
 			},
-		})
+			SecurityGroupId:         vpcStackRef.GetStringOutput(pulumi.String("auroraSecurityGroupId")),
+			NetworkType:             networkType,
+			DatabaseName:            settings.DatabaseName,
+			MasterUsername:          settings.MasterUsername,
+			MasterPassword:          dbPassword,
+			EngineVersion:           settings.EngineVersion,
+			InstanceClass:           settings.InstanceClass,
+			StorageType:             settings.StorageType,
+			SnapshotIdentifier:      settings.SnapshotIdentifier,
+			DeletionProtection:      settings.DeletionProtection,
+			FinalSnapshotIdentifier: settings.FinalSnapshotIdentifier,
+			MonitoringInterval:      settings.MonitoringInterval,
+			IamAuthentication:       settings.IamAuthentication,
+			BacktrackWindow:         settings.BacktrackWindow,
+			ActivityStream:          settings.ActivityStream,
+			ActivityStreamKmsKeyId:  settings.ActivityStreamKmsKeyId,
+			Parameters:              parameters,
+			GreenParameters:         settings.GreenParameters,
+			GlobalDatabase:          settings.GlobalDatabase,
+			Secondary:               secondary,
+			ReaderAutoScaling:       settings.ReaderAutoScaling,
+			BackupCopy:              settings.BackupCopy,
+			ExternalReplica:         settings.ExternalReplica,
+			RdsProxy:                settings.RdsProxy,
+
+			PerformanceInsightsEnabled:         settings.PerformanceInsightsEnabled,
+			PerformanceInsightsRetentionPeriod: settings.PerformanceInsightsRetentionPeriod,
+			PerformanceInsightsKmsKeyId:        settings.PerformanceInsightsKmsKeyId,
+
+			AutoMinorVersionUpgrade:    settings.AutoMinorVersionUpgrade,
+			PreferredMaintenanceWindow: settings.PreferredMaintenanceWindow,
+			PreferredBackupWindow:      settings.PreferredBackupWindow,
+		}, inRegion)
 		if err != nil {
 			return err
 		}
 
-		// Create DB Cluster Parameter Group
-		clusterParameterGroup, err := rds.NewClusterParameterGroup(ctx, fmt.Sprintf("%s-cluster-pg", projectName), &rds.ClusterParameterGroupArgs{
-			Name:        pulumi.String(fmt.Sprintf("%s-aurora-cluster-pg", projectName)),
-			Family:      pulumi.String("aurora-mysql8.0"),
-			Description: pulumi.String("Cluster parameter group for Aurora Blue-Green lab"),
-			Parameters: rds.ClusterParameterGroupParameterArray{
-				&rds.ClusterParameterGroupParameterArgs{
-					Name:  pulumi.String("character_set_server"),
-					Value: pulumi.String("utf8mb4"),
-				},
-				&rds.ClusterParameterGroupParameterArgs{
-					Name:  pulumi.String("collation_server"),
-					Value: pulumi.String("utf8mb4_unicode_ci"),
-				},
-			},
-			Tags: pulumi.StringMap{
-				"Name":    pulumi.String(fmt.Sprintf("%s-aurora-cluster-pg", projectName)),
-				"Project": pulumi.String(projectName),
-			},
-		})
-		if err != nil {
-			return err
+		// Export outputs
+		ctx.Export("region", pulumi.String(region))
+		ctx.Export("clusterIdentifier", aurora.Cluster.ClusterIdentifier)
+		ctx.Export("clusterArn", aurora.Cluster.Arn)
+		ctx.Export("clusterResourceId", aurora.Cluster.ClusterResourceId)
+		ctx.Export("clusterEndpoint", aurora.Cluster.Endpoint)
+		ctx.Export("clusterReaderEndpoint", aurora.Cluster.ReaderEndpoint)
+		ctx.Export("clusterPort", aurora.Cluster.Port)
+		ctx.Export("databaseName", aurora.Cluster.DatabaseName)
+		ctx.Export("masterUsername", aurora.Cluster.MasterUsername)
+		ctx.Export("engineVersion", aurora.Cluster.EngineVersion)
+		ctx.Export("storageType", pulumi.String(settings.StorageType))
+		ctx.Export("networkType", aurora.Cluster.NetworkType)
+		ctx.Export("snapshotIdentifier", pulumi.String(settings.SnapshotIdentifier))
+		ctx.Export("writerInstanceId", aurora.Writer.ID())
+		ctx.Export("readerInstanceId", aurora.Reader.ID())
+		ctx.Export("writerInstanceArn", aurora.Writer.Arn)
+		ctx.Export("writerInstanceEndpoint", aurora.Writer.Endpoint)
+		ctx.Export("readerInstanceEndpoint", aurora.Reader.Endpoint)
+		ctx.Export("deletionProtection", aurora.Cluster.DeletionProtection)
+		ctx.Export("skipFinalSnapshot", aurora.Cluster.SkipFinalSnapshot)
+		ctx.Export("monitoringInterval", aurora.Writer.MonitoringInterval)
+		ctx.Export("iamAuthentication", aurora.Cluster.IamDatabaseAuthenticationEnabled)
+		ctx.Export("backtrackWindow", pulumi.Int(settings.BacktrackWindow))
+		if aurora.ReaderScalingTarget != nil {
+			ctx.Export("readerScalingResourceId", aurora.ReaderScalingTarget.ResourceId)
+			ctx.Export("readerMinCapacity", aurora.ReaderScalingTarget.MinCapacity)
+			ctx.Export("readerMaxCapacity", aurora.ReaderScalingTarget.MaxCapacity)
+			ctx.Export("readerScalingPolicyArn", aurora.ReaderScalingPolicy.Arn)
+		}
+		if aurora.BackupPlan != nil {
+			ctx.Export("backupPlanId", aurora.BackupPlan.ID())
+			ctx.Export("backupVaultArn", aurora.BackupVault.Arn)
+			ctx.Export("backupCopyRegion", pulumi.String(settings.BackupCopy.Region))
+			ctx.Export("backupCopyVaultArn", aurora.BackupCopyVault.Arn)
+			ctx.Export("backupCopyKmsKeyArn", aurora.BackupCopyKey.Arn)
+		}
+		if aurora.ExternalReplica != nil {
+			ctx.Export("externalReplicaIdentifier", aurora.ExternalReplica.Identifier)
+			ctx.Export("externalReplicaEndpoint", aurora.ExternalReplica.Address)
+			ctx.Export("externalReplicaEngineVersion", aurora.ExternalReplica.EngineVersionActual)
+		}
+		if aurora.Proxy != nil {
+			ctx.Export("rdsProxyName", aurora.Proxy.Name)
+			ctx.Export("proxyEndpoint", aurora.Proxy.Endpoint)
+		}
+		ctx.Export("autoMinorVersionUpgrade", aurora.Writer.AutoMinorVersionUpgrade)
+		ctx.Export("preferredMaintenanceWindow", aurora.Cluster.PreferredMaintenanceWindow)
+		ctx.Export("preferredBackupWindow", aurora.Cluster.PreferredBackupWindow)
+		ctx.Export("performanceInsightsEnabled", aurora.Writer.PerformanceInsightsEnabled)
+		if settings.PerformanceInsightsEnabled {
+			ctx.Export("performanceInsightsRetentionPeriod", aurora.Writer.PerformanceInsightsRetentionPeriod)
+			ctx.Export("performanceInsightsKmsKeyId", aurora.Writer.PerformanceInsightsKmsKeyId)
+			// Performance Insights console pages of the instances
+			ctx.Export("writerPerformanceInsightsUrl", pulumi.Sprintf(
+				"https://%s.console.aws.amazon.com/rds/home?region=%s#performance-insights-v20206:/resourceId/%s/resourceName/%s",
+				region, region, aurora.Writer.DbiResourceId, aurora.Writer.Identifier,
+			))
+			ctx.Export("readerPerformanceInsightsUrl", pulumi.Sprintf(
+				"https://%s.console.aws.amazon.com/rds/home?region=%s#performance-insights-v20206:/resourceId/%s/resourceName/%s",
+				region, region, aurora.Reader.DbiResourceId, aurora.Reader.Identifier,
+			))
+		}
+		if aurora.ActivityStream != nil {
+			ctx.Export("activityStreamKinesisStreamName", aurora.ActivityStream.KinesisStreamName)
+			ctx.Export("activityStreamKmsKeyId", aurora.ActivityStream.KmsKeyId)
+		}
+		if aurora.MonitoringRole != nil {
+			ctx.Export("monitoringRoleArn", aurora.MonitoringRole.Arn)
+		}
+		if aurora.GlobalCluster != nil {
+			ctx.Export("globalClusterIdentifier", aurora.GlobalCluster.GlobalClusterIdentifier)
+		}
+		if aurora.SecondaryCluster != nil {
+			ctx.Export("secondaryRegion", pulumi.String(settings.SecondaryRegion))
+			ctx.Export("secondaryClusterIdentifier", aurora.SecondaryCluster.ClusterIdentifier)
+			ctx.Export("secondaryClusterEndpoint", aurora.SecondaryCluster.Endpoint)
+			ctx.Export("secondaryClusterReaderEndpoint", aurora.SecondaryCluster.ReaderEndpoint)
+			ctx.Export("secondaryInstanceEndpoint", aurora.SecondaryInstance.Endpoint)
 		}
 
-		// Create DB Parameter Group (for instances)
-		instanceParameterGroup, err := rds.NewParameterGroup(ctx, fmt.Sprintf("%s-instance-pg", projectName), &rds.ParameterGroupArgs{
-			Name:        pulumi.String(fmt.Sprintf("%s-aurora-instance-pg", projectName)),
-			Family:      pulumi.String("aurora-mysql8.0"),
-			Description: pulumi.String("Instance parameter group for Aurora Blue-Green lab"),
-			Parameters: rds.ParameterGroupParameterArray{
-				&rds.ParameterGroupParameterArgs{
-					Name:  pulumi.String("max_connections"),
-					Value: pulumi.String("1000"),
-				},
-			},
-			Tags: pulumi.StringMap{
-				"Name":    pulumi.String(fmt.Sprintf("%s-aurora-instance-pg", projectName)),
-				"Project": pulumi.String(projectName),
-			},
-		})
-		if err != nil {
-			return err
+		// Export copy-paste connection strings of every endpoint, without the
+		// password; the writer's and reader's also one by one
+		connection := func(endpoint pulumi.StringOutput) pulumi.StringMapOutput {
+			return pulumi.All(endpoint, aurora.Cluster.Port, aurora.Cluster.DatabaseName, aurora.Cluster.MasterUsername).ApplyT(
+				func(v []interface{}) map[string]string {
+					return dbconn.For(v[0].(string), v[1].(int), v[2].(string), v[3].(string)).Map()
+				}).(pulumi.StringMapOutput)
+		}
+		connections := pulumi.StringMapMap{
+			"writer":         connection(aurora.Cluster.Endpoint),
+			"reader":         connection(aurora.Cluster.ReaderEndpoint),
+			"writerInstance": connection(aurora.Writer.Endpoint),
+			"readerInstance": connection(aurora.Reader.Endpoint),
+		}
+		if aurora.SecondaryCluster != nil {
+			connections["secondaryReader"] = connection(aurora.SecondaryCluster.ReaderEndpoint)
+			connections["secondaryInstance"] = connection(aurora.SecondaryInstance.Endpoint)
+		}
+		ctx.Export("connectionStrings", connections)
+		for _, endpoint := range []string{"writer", "reader"} {
+			endpointStrings := connections[endpoint].ToStringMapOutput()
+			ctx.Export(endpoint+"Dsn", endpointStrings.MapIndex(pulumi.String("dsn")))
+			ctx.Export(endpoint+"JdbcUrl", endpointStrings.MapIndex(pulumi.String("jdbcUrl")))
+			ctx.Export(endpoint+"MysqlCommand", endpointStrings.MapIndex(pulumi.String("mysqlCommand")))
 		}
 
-		// Create Aurora Cluster
-		cluster, err := rds.NewCluster(ctx, fmt.Sprintf("%s-aurora-cluster", projectName), &rds.ClusterArgs{
-			ClusterIdentifier:              pulumi.String(fmt.Sprintf("%s-aurora-cluster", projectName)),
-			Engine:                         pulumi.String("aurora-mysql"),
-			EngineVersion:                  pulumi.String(engineVersion),
-			DatabaseName:                   pulumi.String(dbName),
-			MasterUsername:                 pulumi.String(dbUsername),
-			MasterPassword:                 dbPassword,
-			DbSubnetGroupName:              dbSubnetGroup.Name,
-			VpcSecurityGroupIds:            pulumi.StringArray{auroraSecurityGroupId},
-			DbClusterParameterGroupName:    clusterParameterGroup.Name,
-			BackupRetentionPeriod:          pulumi.Int(7),
-			PreferredBackupWindow:          pulumi.String("03:00-04:00"),
-			PreferredMaintenanceWindow:     pulumi.String("mon:04:00-mon:05:00"),
-			EnabledCloudwatchLogsExports:   pulumi.StringArray{
-				pulumi.String("error"),
-				pulumi.String("general"),
-				pulumi.String("slowquery"),
-			},
-			StorageEncrypted:               pulumi.Bool(true),
-			ApplyImmediately:               pulumi.Bool(true),
-			SkipFinalSnapshot:              pulumi.Bool(true),
-			Tags: pulumi.StringMap{
-				"Name":    pulumi.String(fmt.Sprintf("%s-aurora-cluster", projectName)),
-				"Project": pulumi.String(projectName),
-			},
-		})
-		if err != nil {
-			return err
+		ctx.Export("clusterParameterGroupName", aurora.ClusterParameterGroup.Name)
+		ctx.Export("instanceParameterGroupName", aurora.InstanceParameterGroup.Name)
+		ctx.Export("parameterGroupFamily", aurora.ClusterParameterGroup.Family)
+		if aurora.GreenClusterParameterGroup != nil {
+			ctx.Export("greenClusterParameterGroupName", aurora.GreenClusterParameterGroup.Name)
+			ctx.Export("greenInstanceParameterGroupName", aurora.GreenInstanceParameterGroup.Name)
+			ctx.Export("greenParameterGroupFamily", aurora.GreenClusterParameterGroup.Family)
 		}
+		// The version bgctl create upgrades the green environment to
+		ctx.Export("greenEngineVersion", pulumi.String(settings.GreenEngineVersion))
+		ctx.Export("binlogFormat", pulumi.String(parameters.Value("binlog_format")))
+		ctx.Export("binlogRowImage", pulumi.String(parameters.Value("binlog_row_image")))
+		ctx.Export("binlogRetentionHours", pulumi.Int(settings.BinlogRetentionHours))
 
-		// Create Aurora Writer Instance
-		writerInstance, err := rds.NewClusterInstance(ctx, fmt.Sprintf("%s-writer-instance", projectName), &rds.ClusterInstanceArgs{
-			Identifier:              pulumi.String(fmt.Sprintf("%s-writer-instance", projectName)),
-			ClusterIdentifier:       cluster.ID(),
-			InstanceClass:           pulumi.String(instanceClass),
-			Engine:                  pulumi.String("aurora-mysql"),
-			EngineVersion:           pulumi.String(engineVersion),
-			DbParameterGroupName:    instanceParameterGroup.Name,
-			PubliclyAccessible:      pulumi.Bool(false),
-			AutoMinorVersionUpgrade: pulumi.Bool(false),
-			PerformanceInsightsEnabled: pulumi.Bool(true),
-			PerformanceInsightsRetentionPeriod: pulumi.Int(7),
-			Tags: pulumi.StringMap{
-				"Name":    pulumi.String(fmt.Sprintf("%s-writer-instance", projectName)),
-				"Project": pulumi.String(projectName),
-				"Role":    pulumi.String("writer"),
-			},
-		})
-		if err != nil {
+		// Estimate the monthly cost of the instances and keys
+		var estimate cost.Estimate
+		instances := 2
+		if settings.ReaderAutoScaling != nil {
+			// Aurora Auto Scaling keeps at least MinCapacity readers, the
+			// stack's reader included
+			instances += settings.ReaderAutoScaling.MinCapacity - 1
+		}
+		if settings.SecondaryRegion != "" {
+			instances++
+		}
+		estimate.RdsInstances(settings.InstanceClass, instances, settings.StorageType == "aurora-iopt1")
+		if aurora.ActivityStreamKey != nil {
+			estimate.KmsKeys(1)
+		}
+		if aurora.BackupCopyKey != nil {
+			estimate.KmsKeys(1)
+		}
+		if settings.ExternalReplica != nil {
+			estimate.MysqlInstances(settings.ExternalReplica.InstanceClass, 1, 20)
+		}
+		if settings.RdsProxy != nil {
+			// The proxy's targets are the primary cluster's instances
+			proxied := instances
+			if settings.SecondaryRegion != "" {
+				proxied--
+			}
+			estimate.RdsProxy(settings.InstanceClass, proxied)
+			estimate.Secrets(1)
+		}
+		if err := estimate.Export(ctx); err != nil {
 			return err
 		}
 
-		// Create Aurora Reader Instance
-		readerInstance, err := rds.NewClusterInstance(ctx, fmt.Sprintf("%s-reader-instance", projectName), &rds.ClusterInstanceArgs{
-			Identifier:              pulumi.String(fmt.Sprintf("%s-reader-instance", projectName)),
-			ClusterIdentifier:       cluster.ID(),
-			InstanceClass:           pulumi.String(instanceClass),
-			Engine:                  pulumi.String("aurora-mysql"),
-			EngineVersion:           pulumi.String(engineVersion),
-			DbParameterGroupName:    instanceParameterGroup.Name,
-			PubliclyAccessible:      pulumi.Bool(false),
-			AutoMinorVersionUpgrade: pulumi.Bool(false),
-			PerformanceInsightsEnabled: pulumi.Bool(true),
-			PerformanceInsightsRetentionPeriod: pulumi.Int(7),
-			Tags: pulumi.StringMap{
-				"Name":    pulumi.String(fmt.Sprintf("%s-reader-instance", projectName)),
-				"Project": pulumi.String(projectName),
-				"Role":    pulumi.String("reader"),
-			},
-		}, pulumi.DependsOn([]pulumi.Resource{writerInstance}))
+		// Publish the key outputs for runtime discovery without Pulumi access;
+		// the simulator finds the proxy with --proxy-endpoint auto
+		values := map[string]pulumi.StringInput{
+			"region":                pulumi.String(region),
+			"clusterIdentifier":     aurora.Cluster.ClusterIdentifier,
+			"clusterArn":            aurora.Cluster.Arn,
+			"clusterResourceId":     aurora.Cluster.ClusterResourceId,
+			"clusterEndpoint":       aurora.Cluster.Endpoint,
+			"clusterReaderEndpoint": aurora.Cluster.ReaderEndpoint,
+			"clusterPort":           pulumi.Sprintf("%d", aurora.Cluster.Port),
+			"databaseName":          aurora.Cluster.DatabaseName,
+			"masterUsername":        aurora.Cluster.MasterUsername,
+			"engineVersion":         aurora.Cluster.EngineVersion,
+			"writerJdbcUrl":         connections["writer"].ToStringMapOutput().MapIndex(pulumi.String("jdbcUrl")),
+			"readerJdbcUrl":         connections["reader"].ToStringMapOutput().MapIndex(pulumi.String("jdbcUrl")),
+		}
+		if aurora.Proxy != nil {
+			values["proxyEndpoint"] = aurora.Proxy.Endpoint
+		}
+		outputParameters, err := components.NewLabOutputParameters(ctx, lb.Name("aurora-outputs"), &components.LabOutputParametersArgs{
+			Labels: lb,
+			Stack:  "aurora",
+			Values: values,
+		}, inRegion)
 		if err != nil {
 			return err
 		}
-
-		// Export outputs
-		ctx.Export("clusterIdentifier", cluster.ClusterIdentifier)
-		ctx.Export("clusterArn", cluster.Arn)
-		ctx.Export("clusterEndpoint", cluster.Endpoint)
-		ctx.Export("clusterReaderEndpoint", cluster.ReaderEndpoint)
-		ctx.Export("clusterPort", cluster.Port)
-		ctx.Export("databaseName", cluster.DatabaseName)
-		ctx.Export("masterUsername", cluster.MasterUsername)
-		ctx.Export("engineVersion", cluster.EngineVersion)
-		ctx.Export("writerInstanceId", writerInstance.ID())
-		ctx.Export("readerInstanceId", readerInstance.ID())
-		ctx.Export("writerInstanceEndpoint", writerInstance.Endpoint)
-		ctx.Export("readerInstanceEndpoint", readerInstance.Endpoint)
+		ctx.Export("outputParameterPrefix", pulumi.String(outputParameters.Prefix))
 
 		return nil
 	})
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/budgets"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/costexplorer"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/sns"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+
+	"aurora-bluegreen-lab/internal/components"
+	labconfig "aurora-bluegreen-lab/internal/config"
+	"aurora-bluegreen-lab/internal/cost"
+	"aurora-bluegreen-lab/internal/labels"
+	"aurora-bluegreen-lab/internal/providers"
+)
+
+func main() {
+	pulumi.Run(func(ctx *pulumi.Context) error {
+		// Load configuration
+		cfg := config.New(ctx, "")
+		settings, err := labconfig.LoadBudget(cfg)
+		if err != nil {
+			return err
+		}
+
+		lb, err := labels.New(ctx, cfg)
+		if err != nil {
+			return err
+		}
+
+		// Create the AWS provider for the stack's region
+		provider, region, err := providers.New(ctx, cfg, lb)
+		if err != nil {
+			return err
+		}
+		inRegion := pulumi.Provider(provider)
+
+		// Create the SNS topic the alerts are published to, e.g. for a chat
+		// integration or a classroom mailing list
+		alertTopic, err := sns.NewTopic(ctx, lb.Name("budget-alerts"), &sns.TopicArgs{
+			Name: pulumi.String(lb.Name("budget-alerts")),
+			Tags: lb.Tags(lb.Name("budget-alerts")),
+		}, inRegion)
+		if err != nil {
+			return err
+		}
+		// AWS Budgets checks that it may publish when the budget is created
+		topicPolicy, err := sns.NewTopicPolicy(ctx, lb.Name("budget-alerts-policy"), &sns.TopicPolicyArgs{
+			Arn: alertTopic.Arn,
+			Policy: pulumi.Sprintf(`{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Principal": {"Service": "budgets.amazonaws.com"},
+      "Action": "sns:Publish",
+      "Resource": %q
+    }
+  ]
+}`, alertTopic.Arn),
+		}, inRegion)
+		if err != nil {
+			return err
+		}
+
+		// Alert on the actual spend at each threshold and on the forecast;
+		// the emails are sent by AWS Budgets and need no confirmation
+		subscriber := func(notificationType string, threshold float64) budgets.BudgetNotificationInput {
+			return &budgets.BudgetNotificationArgs{
+				NotificationType:         pulumi.String(notificationType),
+				ComparisonOperator:       pulumi.String("GREATER_THAN"),
+				Threshold:                pulumi.Float64(threshold),
+				ThresholdType:            pulumi.String("PERCENTAGE"),
+				SubscriberEmailAddresses: pulumi.ToStringArray(settings.AlertEmails),
+				SubscriberSnsTopicArns:   pulumi.StringArray{alertTopic.Arn},
+			}
+		}
+		var notifications budgets.BudgetNotificationArray
+		for _, threshold := range settings.AlertThresholds {
+			notifications = append(notifications, subscriber("ACTUAL", threshold))
+		}
+		if settings.ForecastThreshold > 0 {
+			notifications = append(notifications, subscriber("FORECASTED", settings.ForecastThreshold))
+		}
+
+		// The budget covers the costs tagged Project=<projectName>, which
+		// every lab resource carries (see internal/labels)
+		budgetName := lb.Name("monthly")
+		budget, err := budgets.NewBudget(ctx, lb.Name("budget"), &budgets.BudgetArgs{
+			Name:        pulumi.String(budgetName),
+			BudgetType:  pulumi.String("COST"),
+			TimeUnit:    pulumi.String("MONTHLY"),
+			LimitAmount: pulumi.String(fmt.Sprintf("%.2f", settings.MonthlyLimitUsd)),
+			LimitUnit:   pulumi.String("USD"),
+			CostFilters: budgets.BudgetCostFilterArray{
+				&budgets.BudgetCostFilterArgs{
+					Name:   pulumi.String("TagKeyValue"),
+					Values: pulumi.StringArray{pulumi.String("user:Project$" + lb.ProjectName)},
+				},
+			},
+			Notifications: notifications,
+			Tags:          lb.Tags(budgetName),
+		}, inRegion, pulumi.DependsOn([]pulumi.Resource{topicPolicy}))
+		if err != nil {
+			return err
+		}
+
+		// Costs only carry the Project tag once it is an active cost
+		// allocation tag
+		if settings.ActivateCostAllocationTag {
+			_, err = costexplorer.NewCostAllocationTag(ctx, lb.Name("project-cost-allocation-tag"), &costexplorer.CostAllocationTagArgs{
+				TagKey: pulumi.String("Project"),
+				Status: pulumi.String("Active"),
+			}, inRegion)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Export outputs
+		ctx.Export("region", pulumi.String(region))
+		ctx.Export("budgetName", budget.Name)
+		ctx.Export("monthlyLimitUsd", pulumi.Float64(settings.MonthlyLimitUsd))
+		ctx.Export("alertThresholds", pulumi.ToFloat64Array(settings.AlertThresholds))
+		ctx.Export("forecastThreshold", pulumi.Float64(settings.ForecastThreshold))
+		ctx.Export("alertTopicArn", alertTopic.Arn)
+		ctx.Export("costFilter", pulumi.String("Project="+lb.ProjectName))
+
+		// Budgets without actions are free, and so are the alert emails
+		var estimate cost.Estimate
+		if err := estimate.Export(ctx); err != nil {
+			return err
+		}
+
+		// Publish the key outputs for runtime discovery without Pulumi access
+		outputParameters, err := components.NewLabOutputParameters(ctx, lb.Name("budget-outputs"), &components.LabOutputParametersArgs{
+			Labels: lb,
+			Stack:  "budget",
+			Values: map[string]pulumi.StringInput{
+				"region":        pulumi.String(region),
+				"budgetName":    budget.Name,
+				"alertTopicArn": alertTopic.Arn,
+			},
+		}, inRegion)
+		if err != nil {
+			return err
+		}
+		ctx.Export("outputParameterPrefix", pulumi.String(outputParameters.Prefix))
+
+		return nil
+	})
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+
+	"aurora-bluegreen-lab/internal/bluegreen"
+	"aurora-bluegreen-lab/internal/experiments"
+)
+
+const backtrackUsage = `Usage: bgctl backtrack [flags]
+
+Rewinds a cluster with Backtrack enabled (backtrackWindow in the aurora stack)
+to an earlier point in time, by default the old blue cluster left by the last
+switchover (<clusterIdentifier>-old1). Without -to, prints the cluster's
+backtrack window. Backtracks are registered as backtrack-<time> runs in the
+experiment registry of the monitoring stack, if it has one.
+
+  bgctl backtrack                     show how far the old blue cluster can go back
+  bgctl backtrack -to 15m             rewind it to 15 minutes ago
+  bgctl backtrack -to 2026-01-02T15:04:05Z -cluster aurora-bluegreen-lab-aurora-cluster
+
+Flags:
+`
+
+func backtrackCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("backtrack", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), backtrackUsage)
+		fs.PrintDefaults()
+	}
+	var lab labFlags
+	lab.register(fs)
+	cluster := fs.String("cluster", "", "Cluster to backtrack (default: the old blue cluster, the aurora stack's clusterIdentifier output with -old1)")
+	to := fs.String("to", "", "Time to backtrack to: RFC 3339 (2026-01-02T15:04:05Z) or a duration ago (15m, 1h30m)")
+	force := fs.Bool("force", false, "Backtrack even if binary log replication from the cluster is disrupted")
+	useEarliest := fs.Bool("use-earliest", false, "Use the earliest consistent time before -to when -to itself is not available")
+	registryTable := fs.String("registry-table", "", "Experiment registry table to register the backtrack in (default: the monitoring stack's experimentTableName output)")
+	var rf runFlag
+	rf.register(fs)
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	var target time.Time
+	if *to != "" {
+		var err error
+		if target, err = parseBacktrackTo(*to, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	clusterIdentifier, region := *cluster, lab.region
+	if clusterIdentifier == "" || region == "" {
+		reader := lab.reader()
+		aurora, err := reader.Outputs(ctx, "aurora")
+		if err != nil {
+			return err
+		}
+		if clusterIdentifier == "" {
+			if aurora.String("clusterIdentifier") == "" {
+				return fmt.Errorf("the aurora stack has no clusterIdentifier output; pass -cluster")
+			}
+			clusterIdentifier = bluegreen.OldBlueClusterIdentifier(aurora.String("clusterIdentifier"))
+		}
+		if region == "" {
+			region = aurora.String("region")
+		}
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	if err != nil {
+		return fmt.Errorf("loading AWS configuration: %w", err)
+	}
+	client := bluegreen.New(cfg)
+
+	window, err := client.BacktrackWindow(ctx, clusterIdentifier)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("[INFO] Cluster %s: backtrack window %s, earliest backtrack time %s\n",
+		clusterIdentifier, window.Window, window.Earliest.UTC().Format(time.RFC3339))
+	if target.IsZero() {
+		return nil
+	}
+	if target.Before(window.Earliest) && !*useEarliest {
+		return fmt.Errorf("%s is before the earliest backtrack time %s; pass -use-earliest to backtrack as far as possible",
+			target.UTC().Format(time.RFC3339), window.Earliest.UTC().Format(time.RFC3339))
+	}
+
+	started := time.Now().UTC()
+	runID, source, err := rf.identify("backtrack", started)
+	if err != nil {
+		return err
+	}
+	registry := openRegistry(ctx, lab, cfg, *registryTable)
+	run := &experiments.Run{
+		RunID:     runID,
+		Source:    source,
+		Name:      "backtrack",
+		Status:    experiments.StatusRunning,
+		StartedAt: started,
+		Config: map[string]string{
+			"cluster":     clusterIdentifier,
+			"to":          target.UTC().Format(time.RFC3339),
+			"force":       strconv.FormatBool(*force),
+			"useEarliest": strconv.FormatBool(*useEarliest),
+		},
+	}
+	registerRun(ctx, registry, run)
+
+	fmt.Printf("[INFO] Backtracking %s to %s; the cluster is unavailable until it completes\n",
+		clusterIdentifier, target.UTC().Format(time.RFC3339))
+	b, err := client.Backtrack(ctx, bluegreen.BacktrackOptions{
+		ClusterIdentifier: clusterIdentifier,
+		To:                target,
+		Force:             *force,
+		UseEarliest:       *useEarliest,
+	}, func(b *bluegreen.Backtrack) {
+		fmt.Printf("[INFO] %s backtrack %s: %s\n", time.Now().UTC().Format(time.RFC3339), b.ID, b.Status)
+	})
+	run.Finish(time.Now().UTC(), err)
+	if err == nil {
+		// The cluster may have gone back further than -to with -use-earliest
+		run.Results = map[string]float64{"rewindSeconds": b.From.Sub(b.To).Seconds()}
+	}
+	registerRun(context.WithoutCancel(ctx), registry, run)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("[SUCCESS] Cluster %s backtracked to %s\n", clusterIdentifier, b.To.UTC().Format(time.RFC3339))
+	return nil
+}
+
+// parseBacktrackTo parses -to as an RFC 3339 time or as a duration before now.
+func parseBacktrackTo(value string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return time.Time{}, fmt.Errorf("-to must be an RFC 3339 time or a positive duration ago, got %q", value)
+	}
+	return now.Add(-d), nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseBacktrackTo(t *testing.T) {
+	now := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	for value, want := range map[string]time.Time{
+		"2026-01-02T14:30:00Z": time.Date(2026, 1, 2, 14, 30, 0, 0, time.UTC),
+		"15m":                  time.Date(2026, 1, 2, 14, 45, 0, 0, time.UTC),
+		"1h30m":                time.Date(2026, 1, 2, 13, 30, 0, 0, time.UTC),
+	} {
+		got, err := parseBacktrackTo(value, now)
+		if err != nil {
+			t.Errorf("%s: %v", value, err)
+			continue
+		}
+		if !got.Equal(want) {
+			t.Errorf("%s: got %s, want %s", value, got, want)
+		}
+	}
+
+	for _, value := range []string{"yesterday", "-15m", "0s", "2026-01-02 14:30"} {
+		if _, err := parseBacktrackTo(value, now); err == nil {
+			t.Errorf("%s: got no error", value)
+		}
+	}
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+
+	"aurora-bluegreen-lab/internal/bluegreen"
+	"aurora-bluegreen-lab/internal/chaos"
+	"aurora-bluegreen-lab/internal/experiments"
+)
+
+const chaosUsage = `Usage: bgctl chaos [flags] <action>
+
+Injects a fault into the lab during a workload run, so the simulator and
+lab-report measure how the application rides through it, like a switchover:
+
+Actions:
+  reboot-writer    Reboot the cluster's writer; Aurora restarts it in place
+                   without a failover, and the cluster has no writer until it
+                   is back
+  reboot-readers   Reboot the cluster's readers at once (or -instance)
+  latency          Add -delay of network latency on the simulator host for
+                   -duration (AWS FIS, AWSFIS-Run-Network-Latency)
+  az-impairment    Deny the traffic of the lab's subnets in one Availability
+                   Zone for -duration (AWS FIS, aws:network:disrupt-connectivity),
+                   by default the writer's zone
+  experiment       Start an experiment from the FIS template -template, e.g.
+                   one of the fis stack's (reboot-writer,
+                   simulator-network-disruption)
+
+latency and az-impairment run a one-off experiment template, deleted when
+the experiment ends, with the FIS role of -role-arn, by default the fis
+stack's. An interrupted experiment is stopped. -timeline appends chaos-started and chaos-completed events to a
+timeline for lab-report, and the runs are registered as chaos-<action>-<time>
+runs in the experiment registry of the monitoring stack, if it has one.
+
+  bgctl chaos reboot-writer
+  bgctl chaos reboot-readers -instance lab-instance-2
+  bgctl chaos latency -role-arn arn:aws:iam::123456789012:role/lab-fis -delay 200ms -duration 5m
+  bgctl chaos az-impairment -role-arn arn:aws:iam::123456789012:role/lab-fis -duration 2m
+  bgctl chaos experiment -template reboot-writer
+
+Flags:
+`
+
+// chaosActions are the actions of bgctl chaos.
+var chaosActions = []string{"reboot-writer", "reboot-readers", "latency", "az-impairment", "experiment"}
+
+func chaosCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("chaos", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), chaosUsage)
+		fs.PrintDefaults()
+	}
+	var lab labFlags
+	lab.register(fs)
+	cluster := fs.String("cluster", "", "Cluster to inject the fault into (default: the aurora stack's clusterIdentifier output)")
+	instance := fs.String("instance", "", "Reader reboot-readers reboots (default: all readers)")
+	roleArn := fs.String("role-arn", "", "IAM role FIS assumes for latency and az-impairment (default: the fis stack's roleArn output)")
+	template := fs.String("template", "", "Name tag of the FIS experiment template experiment starts, or its end after the project name")
+	delay := fs.Duration("delay", 200*time.Millisecond, "Network latency latency adds")
+	duration := fs.Duration("duration", 2*time.Minute, "How long latency and az-impairment last")
+	iface := fs.String("interface", "ens5", "Network interface of the simulator host latency delays")
+	instanceID := fs.String("instance-id", "", "Simulator instance latency delays (default: the ec2 stack's instanceId output)")
+	az := fs.String("az", "", "Availability Zone az-impairment disrupts (default: the writer's)")
+	vpcID := fs.String("vpc-id", "", "VPC whose subnets az-impairment disrupts (default: the vpc stack's vpcId output)")
+	timeout := fs.Duration("timeout", 30*time.Minute, "How long to wait for the fault to end")
+	timelinePath := fs.String("timeline", "", "Timeline file (JSON Lines) to append the chaos events to, for lab-report --timeline")
+	registryTable := fs.String("registry-table", "", "Experiment registry table to register the run in (default: the monitoring stack's experimentTableName output)")
+	var rf runFlag
+	rf.register(fs)
+
+	// Flags may come before or after the action
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("an action is required")
+	}
+	action := fs.Arg(0)
+	fs.Parse(fs.Args()[1:])
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments after %s: %s", action, strings.Join(fs.Args(), " "))
+	}
+	fisAction := action == "latency" || action == "az-impairment"
+	switch {
+	case !contains(chaosActions, action):
+		fs.Usage()
+		return fmt.Errorf("unknown action %q", action)
+	case action == "experiment" && *template == "":
+		return fmt.Errorf("experiment needs the template to start with -template")
+	case *template != "" && action != "experiment":
+		return fmt.Errorf("-template is a flag of experiment")
+	case fisAction && (*duration < time.Minute || *duration > 12*time.Hour):
+		return fmt.Errorf("-duration must be between 1m and 12h, got %s", *duration)
+	case action == "latency" && (*delay <= 0 || *delay > time.Minute):
+		return fmt.Errorf("-delay must be between 1ms and 1m, got %s", *delay)
+	case *instance != "" && action != "reboot-readers":
+		return fmt.Errorf("-instance is a flag of reboot-readers")
+	}
+
+	clusterIdentifier, region := *cluster, lab.region
+	if clusterIdentifier == "" || region == "" {
+		aurora, err := lab.reader().Outputs(ctx, "aurora")
+		if err != nil {
+			return err
+		}
+		if clusterIdentifier == "" {
+			if clusterIdentifier = aurora.String("clusterIdentifier"); clusterIdentifier == "" {
+				return fmt.Errorf("the aurora stack has no clusterIdentifier output; pass -cluster")
+			}
+		}
+		if region == "" {
+			region = aurora.String("region")
+		}
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	if err != nil {
+		return fmt.Errorf("loading AWS configuration: %w", err)
+	}
+	client := bluegreen.New(cfg)
+	client.PollInterval = 5 * time.Second
+	status, err := client.LabStatus(ctx, clusterIdentifier)
+	if err != nil {
+		return err
+	}
+	cs, err := clusterStatus(status, clusterIdentifier)
+	if err != nil {
+		return err
+	}
+
+	// What the fault targets, and the experiment of the FIS actions
+	var instances []string
+	var experiment chaos.Experiment
+	switch action {
+	case "reboot-writer", "reboot-readers":
+		if instances, err = rebootTargets(cs, action == "reboot-writer", *instance); err != nil {
+			return err
+		}
+	case "latency":
+		if *instanceID == "" {
+			ec2Outputs, err := lab.reader().Outputs(ctx, "ec2")
+			if err != nil {
+				return err
+			}
+			if *instanceID = ec2Outputs.String("instanceId"); *instanceID == "" {
+				return fmt.Errorf("the ec2 stack has no instanceId output (Auto Scaling Group); pass -instance-id")
+			}
+		}
+		arn, err := instanceArn(ctx, ec2.NewFromConfig(cfg), region, *instanceID)
+		if err != nil {
+			return err
+		}
+		experiment = chaos.NetworkLatency(region, arn, *iface, *delay, *duration)
+	case "az-impairment":
+		if *az == "" {
+			if *az = writerZone(cs); *az == "" {
+				return fmt.Errorf("the writer of %s has no Availability Zone; pass -az", clusterIdentifier)
+			}
+		}
+		if *vpcID == "" {
+			vpc, err := lab.reader().Outputs(ctx, "vpc")
+			if err != nil {
+				return err
+			}
+			if *vpcID = vpc.String("vpcId"); *vpcID == "" {
+				return fmt.Errorf("the vpc stack has no vpcId output; pass -vpc-id")
+			}
+		}
+		experiment = chaos.AzImpairment(*vpcID, *az, *duration)
+	}
+	if fisAction && *roleArn == "" {
+		fisOutputs, err := lab.reader().Outputs(ctx, "fis")
+		if err != nil {
+			return fmt.Errorf("%s runs an AWS FIS experiment; pass its role with -role-arn or deploy the fis stack: %w", action, err)
+		}
+		if *roleArn = fisOutputs.String("roleArn"); *roleArn == "" {
+			return fmt.Errorf("the fis stack has no roleArn output; pass -role-arn")
+		}
+	}
+
+	started := time.Now().UTC()
+	runID, source, err := rf.identify("chaos-"+action, started)
+	if err != nil {
+		return err
+	}
+
+	var tl *eventTimeline
+	if *timelinePath != "" {
+		if tl, err = openTimeline(*timelinePath, runID); err != nil {
+			return err
+		}
+		defer tl.close()
+	}
+
+	registry := openRegistry(ctx, lab, cfg, *registryTable)
+	run := &experiments.Run{
+		RunID:     runID,
+		Source:    source,
+		Name:      "chaos-" + action,
+		Status:    experiments.StatusRunning,
+		StartedAt: started,
+		Config:    map[string]string{"cluster": clusterIdentifier, "action": action},
+		Timings:   map[string]time.Time{},
+		Results:   map[string]float64{},
+	}
+	switch action {
+	case "reboot-writer", "reboot-readers":
+		run.Config["instances"] = strings.Join(instances, ",")
+	case "latency":
+		run.Config["instanceId"] = *instanceID
+		run.Config["delay"] = delay.String()
+		run.Config["duration"] = duration.String()
+	case "az-impairment":
+		run.Config["availabilityZone"] = *az
+		run.Config["vpcId"] = *vpcID
+		run.Config["duration"] = duration.String()
+	case "experiment":
+		run.Config["template"] = *template
+	}
+	registerRun(ctx, registry, run)
+
+	waitCtx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+	detail := chaosDetail(action, run.Config)
+	fmt.Printf("[INFO] %s: %s\n", action, detail)
+	run.Timings["chaos-started"] = time.Now().UTC()
+	tl.record("chaos-started", action+": "+detail)
+	onState := func(s *chaos.State) {
+		fmt.Printf("[INFO] %s experiment %s: %s\n", time.Now().UTC().Format(time.RFC3339), s.ID, s.Status)
+	}
+	if fisAction || action == "experiment" {
+		var s *chaos.State
+		if action == "experiment" {
+			s, err = chaos.New(cfg).StartTemplate(waitCtx, *template, onState)
+		} else {
+			experiment.RoleArn = *roleArn
+			experiment.Tags = map[string]string{"Name": "bgctl-chaos-" + action, "Cluster": clusterIdentifier}
+			s, err = chaos.New(cfg).Run(waitCtx, experiment, onState)
+		}
+		if s != nil {
+			run.Config["experimentId"] = s.ID
+		}
+	} else {
+		err = client.RebootInstances(waitCtx, instances, func(r *bluegreen.Reboot) {
+			fmt.Printf("[INFO] %s instance %s: %s\n", time.Now().UTC().Format(time.RFC3339), r.Instance, r.Status)
+		})
+	}
+	if err == nil {
+		completed := time.Now().UTC()
+		run.Timings["chaos-completed"] = completed
+		run.Results["chaosSeconds"] = completed.Sub(run.Timings["chaos-started"]).Seconds()
+		tl.record("chaos-completed", action)
+	} else {
+		if waitCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+			err = fmt.Errorf("%s did not end within %s: %w", action, *timeout, err)
+		}
+		run.Timings["chaos-failed"] = time.Now().UTC()
+		tl.record("chaos-failed", err.Error())
+	}
+	run.Finish(time.Now().UTC(), err)
+	registerRun(context.WithoutCancel(ctx), registry, run)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("[SUCCESS] %s ended after %.0fs\n", action, run.Results["chaosSeconds"])
+	return nil
+}
+
+// rebootTargets returns the instances to reboot: the writer, the readers or
+// the reader given.
+func rebootTargets(cluster bluegreen.ClusterStatus, writer bool, reader string) ([]string, error) {
+	var instances []string
+	for _, instance := range cluster.Instances {
+		switch {
+		case writer != instance.Writer, reader != "" && reader != instance.Identifier:
+		case instance.Status != "available":
+			return nil, fmt.Errorf("instance %s is %s; reboot available instances", instance.Identifier, instance.Status)
+		default:
+			instances = append(instances, instance.Identifier)
+		}
+	}
+	switch {
+	case len(instances) > 0:
+		return instances, nil
+	case writer:
+		return nil, fmt.Errorf("cluster %s has no writer", cluster.Identifier)
+	case reader != "":
+		return nil, fmt.Errorf("%s is not a reader of %s", reader, cluster.Identifier)
+	}
+	return nil, fmt.Errorf("cluster %s has no readers", cluster.Identifier)
+}
+
+// writerZone returns the Availability Zone of the cluster's writer.
+func writerZone(cluster bluegreen.ClusterStatus) string {
+	for _, instance := range cluster.Instances {
+		if instance.Writer {
+			return instance.AvailabilityZone
+		}
+	}
+	return ""
+}
+
+// instanceArn returns the ARN of an EC2 instance, whose account FIS needs.
+func instanceArn(ctx context.Context, client *ec2.Client, region, instanceID string) (string, error) {
+	out, err := client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{InstanceIds: []string{instanceID}})
+	if err != nil {
+		return "", fmt.Errorf("describing instance %s: %w", instanceID, err)
+	}
+	if len(out.Reservations) == 0 {
+		return "", fmt.Errorf("instance %s not found", instanceID)
+	}
+	return fmt.Sprintf("arn:aws:ec2:%s:%s:instance/%s", region, aws.ToString(out.Reservations[0].OwnerId), instanceID), nil
+}
+
+// chaosDetail describes the fault from the run's configuration.
+func chaosDetail(action string, config map[string]string) string {
+	switch action {
+	case "latency":
+		return fmt.Sprintf("%s of network latency on %s for %s", config["delay"], config["instanceId"], config["duration"])
+	case "az-impairment":
+		return fmt.Sprintf("network disruption of %s in %s for %s", config["availabilityZone"], config["vpcId"], config["duration"])
+	case "experiment":
+		return "starting an experiment from template " + config["template"]
+	}
+	return "rebooting " + config["instances"]
+}
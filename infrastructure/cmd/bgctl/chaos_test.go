@@ -0,0 +1,51 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"aurora-bluegreen-lab/internal/bluegreen"
+)
+
+func TestRebootTargets(t *testing.T) {
+	cluster := bluegreen.ClusterStatus{
+		Identifier: "lab-cluster",
+		Instances: []bluegreen.InstanceStatus{
+			{Identifier: "lab-instance-1", Status: "available", Writer: true, AvailabilityZone: "us-east-1a"},
+			{Identifier: "lab-instance-2", Status: "available", AvailabilityZone: "us-east-1b"},
+			{Identifier: "lab-instance-3", Status: "available", AvailabilityZone: "us-east-1c"},
+		},
+	}
+	for _, tc := range []struct {
+		writer bool
+		reader string
+		want   []string
+	}{
+		{true, "", []string{"lab-instance-1"}},
+		{false, "", []string{"lab-instance-2", "lab-instance-3"}},
+		{false, "lab-instance-3", []string{"lab-instance-3"}},
+	} {
+		if got, err := rebootTargets(cluster, tc.writer, tc.reader); err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("writer %v, reader %q: got %v, %v, want %v", tc.writer, tc.reader, got, err, tc.want)
+		}
+	}
+	if _, err := rebootTargets(cluster, false, "lab-instance-1"); err == nil || !strings.Contains(err.Error(), "is not a reader of lab-cluster") {
+		t.Errorf("writer as the reader: got %v", err)
+	}
+	if zone := writerZone(cluster); zone != "us-east-1a" {
+		t.Errorf("writer zone: got %q", zone)
+	}
+
+	cluster.Instances[2].Status = "rebooting"
+	if _, err := rebootTargets(cluster, false, "lab-instance-2"); err != nil {
+		t.Errorf("another reader rebooting: got %v", err)
+	}
+	if _, err := rebootTargets(cluster, false, ""); err == nil || !strings.Contains(err.Error(), "lab-instance-3 is rebooting") {
+		t.Errorf("reader rebooting: got %v", err)
+	}
+	cluster.Instances = cluster.Instances[:1]
+	if _, err := rebootTargets(cluster, false, ""); err == nil || !strings.Contains(err.Error(), "has no readers") {
+		t.Errorf("without readers: got %v", err)
+	}
+}
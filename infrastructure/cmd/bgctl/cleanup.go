@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+
+	"aurora-bluegreen-lab/internal/bluegreen"
+	"aurora-bluegreen-lab/internal/experiments"
+	"aurora-bluegreen-lab/internal/runid"
+)
+
+const cleanupUsage = `Usage: bgctl cleanup [flags]
+
+Cleans up after a switchover. The Blue/Green deployment objects of the lab
+cluster that were switched over are deleted; the clusters stay. The old blue
+cluster the switchover left (<clusterIdentifier>-old1) keeps running, and
+costing, until it is deleted too: -delete-old-blue deletes it and its
+instances once the operator confirms by typing its identifier (-yes skips
+the prompt), and -retain-snapshot takes a final snapshot of it first, to
+restore it from later.
+
+Deleting the old blue cluster ends the rollback options of bgctl rollback,
+and the snapshot does not follow the lab cluster's writes. Cleanups are
+registered as cleanup-<time> runs in the experiment registry of the
+monitoring stack, if it has one.
+
+  bgctl cleanup                                    delete the switched over deployments
+  bgctl cleanup -delete-old-blue -retain-snapshot  also delete the old blue cluster, after a final snapshot
+  bgctl cleanup -delete-old-blue -yes              without the confirmation prompt
+
+Flags:
+`
+
+func cleanupCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("cleanup", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), cleanupUsage)
+		fs.PrintDefaults()
+	}
+	var lab labFlags
+	lab.register(fs)
+	cluster := fs.String("cluster", "", "Lab cluster (default: the aurora stack's clusterIdentifier output)")
+	deploymentID := fs.String("deployment", "", "Switched over Blue/Green deployment to delete (default: all of the lab cluster's)")
+	deleteOldBlue := fs.Bool("delete-old-blue", false, "Also delete the old blue cluster and its instances, after confirmation")
+	retainSnapshot := fs.Bool("retain-snapshot", false, "Take a final snapshot of the old blue cluster before deleting it")
+	snapshotID := fs.String("snapshot-id", "", "Identifier of the final snapshot (default: <old blue cluster>-final-<time>)")
+	yes := fs.Bool("yes", false, "Delete the old blue cluster without the confirmation prompt")
+	registryTable := fs.String("registry-table", "", "Experiment registry table to register the cleanup in (default: the monitoring stack's experimentTableName output)")
+	var rf runFlag
+	rf.register(fs)
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if (*retainSnapshot || *snapshotID != "") && !*deleteOldBlue {
+		return fmt.Errorf("-retain-snapshot and -snapshot-id apply to -delete-old-blue")
+	}
+
+	clusterIdentifier, region := *cluster, lab.region
+	if clusterIdentifier == "" || region == "" {
+		aurora, err := lab.reader().Outputs(ctx, "aurora")
+		if err != nil {
+			return err
+		}
+		if clusterIdentifier == "" {
+			if clusterIdentifier = aurora.String("clusterIdentifier"); clusterIdentifier == "" {
+				return fmt.Errorf("the aurora stack has no clusterIdentifier output; pass -cluster")
+			}
+		}
+		if region == "" {
+			region = aurora.String("region")
+		}
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	if err != nil {
+		return fmt.Errorf("loading AWS configuration: %w", err)
+	}
+	client := bluegreen.New(cfg)
+
+	oldBlue := bluegreen.OldBlueClusterIdentifier(clusterIdentifier)
+	status, err := client.LabStatus(ctx, clusterIdentifier)
+	if err != nil {
+		return err
+	}
+	deployments, err := cleanupDeployments(status, *deploymentID)
+	if err != nil {
+		return err
+	}
+	var old *bluegreen.ClusterStatus
+	if *deleteOldBlue {
+		c, err := clusterStatus(status, oldBlue)
+		if err != nil {
+			return fmt.Errorf("the old blue cluster %s does not exist (deleted, or %s was never switched over)", oldBlue, clusterIdentifier)
+		}
+		old = &c
+	}
+	if len(deployments) == 0 && old == nil {
+		fmt.Printf("[INFO] Nothing to clean up: %s has no switched over deployment\n", clusterIdentifier)
+		if _, err := clusterStatus(status, oldBlue); err == nil {
+			fmt.Printf("[INFO] The old blue cluster %s keeps running; delete it with -delete-old-blue\n", oldBlue)
+		}
+		return nil
+	}
+
+	started := time.Now().UTC()
+	snapshot := *snapshotID
+	if *retainSnapshot && snapshot == "" {
+		snapshot = bluegreen.FinalSnapshotIdentifier(oldBlue, started)
+	}
+	fmt.Printf("[INFO] Cleaning up after the switchover of %s:\n", clusterIdentifier)
+	for _, step := range cleanupSteps(deployments, old, snapshot) {
+		fmt.Printf("  - %s\n", step)
+	}
+	if old == nil {
+		if _, err := clusterStatus(status, oldBlue); err == nil {
+			fmt.Printf("[INFO] The old blue cluster %s keeps running; delete it with -delete-old-blue\n", oldBlue)
+		}
+	} else if !*yes {
+		if err := confirm(os.Stdin, os.Stdout, oldBlue); err != nil {
+			return err
+		}
+	}
+
+	runID, source, err := rf.identify("cleanup", started)
+	if err != nil {
+		return err
+	}
+	registry := openRegistry(ctx, lab, cfg, *registryTable)
+	run := &experiments.Run{
+		RunID:     runID,
+		Source:    source,
+		Name:      "cleanup",
+		Status:    experiments.StatusRunning,
+		StartedAt: started,
+		Config: map[string]string{
+			"cluster":       clusterIdentifier,
+			"deleteOldBlue": strconv.FormatBool(*deleteOldBlue),
+		},
+		Results: map[string]float64{},
+	}
+	if snapshot != "" {
+		run.Config["snapshot"] = snapshot
+	}
+	registerRun(ctx, registry, run)
+
+	err = cleanup(ctx, client, deployments, old, snapshot, run)
+	run.Finish(time.Now().UTC(), err)
+	registerRun(context.WithoutCancel(ctx), registry, run)
+	if err != nil {
+		return err
+	}
+	if old != nil {
+		fmt.Printf("[SUCCESS] Deleted %d deployment(s) and the old blue cluster %s\n", len(deployments), oldBlue)
+		if snapshot != "" {
+			fmt.Printf("[INFO] Restore it from snapshot %s with rds restore-db-cluster-from-snapshot\n", snapshot)
+		}
+		return nil
+	}
+	fmt.Printf("[SUCCESS] Deleted %d deployment(s)\n", len(deployments))
+	return nil
+}
+
+// cleanup deletes the deployments, then takes the snapshot of the old blue
+// cluster, if any, and deletes the cluster, recording the results in run.
+func cleanup(ctx context.Context, client *bluegreen.Client, deployments []bluegreen.Deployment, old *bluegreen.ClusterStatus, snapshot string, run *experiments.Run) error {
+	step := func(format string, args ...any) {
+		fmt.Printf("[INFO] %s %s\n", time.Now().UTC().Format(time.RFC3339), fmt.Sprintf(format, args...))
+	}
+	for _, d := range deployments {
+		// After a switchover the target is the lab cluster: it is never
+		// deleted with the deployment
+		if err := client.Delete(ctx, d.ID, false); err != nil {
+			return err
+		}
+		if err := client.WaitDeleted(ctx, d.ID); err != nil {
+			return err
+		}
+		step("deleted deployment %s", d.ID)
+		run.Results["deletedDeployments"]++
+	}
+	if old == nil {
+		return nil
+	}
+
+	if snapshot != "" {
+		step("taking snapshot %s of %s; this takes a few minutes", snapshot, old.Identifier)
+		snapshotStarted := time.Now()
+		if err := client.Snapshot(ctx, old.Identifier, snapshot, map[string]string{runid.Tag: run.RunID}); err != nil {
+			return fmt.Errorf("%w; the old blue cluster was not deleted", err)
+		}
+		step("snapshot %s is available", snapshot)
+		run.Results["snapshotSeconds"] = time.Since(snapshotStarted).Seconds()
+	}
+	deleteStarted := time.Now()
+	if err := client.DeleteCluster(ctx, old.Identifier, func(s string) { step("%s", s) }); err != nil {
+		return err
+	}
+	run.Results["deleteSeconds"] = time.Since(deleteStarted).Seconds()
+	return nil
+}
+
+// cleanupDeployments returns the switched over deployments to delete: id,
+// else all of the lab cluster's. Deployments that were not switched over are
+// left alone; deleting them is a decision about their green environment.
+func cleanupDeployments(status *bluegreen.LabStatus, id string) ([]bluegreen.Deployment, error) {
+	var deployments []bluegreen.Deployment
+	for _, d := range status.Deployments {
+		if id != "" && d.ID != id {
+			continue
+		}
+		if d.Status != bluegreen.StatusSwitchoverCompleted {
+			if id != "" {
+				return nil, fmt.Errorf("deployment %s is %s, not switched over; delete it with the RDS console or CLI, deciding whether its green environment goes too", d.ID, d.Status)
+			}
+			continue
+		}
+		deployments = append(deployments, d.Deployment)
+	}
+	if id != "" && len(deployments) == 0 {
+		return nil, fmt.Errorf("deployment %s is not a deployment of the lab cluster", id)
+	}
+	return deployments, nil
+}
+
+// cleanupSteps describes what a cleanup will do, in order.
+func cleanupSteps(deployments []bluegreen.Deployment, old *bluegreen.ClusterStatus, snapshot string) []string {
+	var steps []string
+	for _, d := range deployments {
+		steps = append(steps, fmt.Sprintf("delete Blue/Green deployment %s (%s, %s -> %s)",
+			d.ID, d.Name, d.SourceClusterIdentifier(), d.TargetClusterIdentifier()))
+	}
+	if old == nil {
+		return steps
+	}
+	if snapshot != "" {
+		steps = append(steps, fmt.Sprintf("take final snapshot %s of %s", snapshot, old.Identifier))
+	}
+	var instances []string
+	for _, instance := range old.Instances {
+		instances = append(instances, instance.Identifier)
+	}
+	detail := "no instances"
+	if len(instances) > 0 {
+		detail = "instances " + strings.Join(instances, ", ")
+	}
+	steps = append(steps, fmt.Sprintf("DELETE cluster %s (%s, %s)", old.Identifier, old.EngineVersion, detail))
+	if snapshot == "" {
+		steps[len(steps)-1] += " without a snapshot"
+	}
+	return steps
+}
+
+// confirm asks the operator to type the identifier of the cluster to delete.
+func confirm(r io.Reader, w io.Writer, identifier string) error {
+	fmt.Fprintf(w, "Type %s to delete it: ", identifier)
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if strings.TrimSpace(line) != identifier {
+		return fmt.Errorf("not confirmed; nothing was deleted")
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"aurora-bluegreen-lab/internal/bluegreen"
+)
+
+func TestCleanupDeployments(t *testing.T) {
+	status := rollbackTestStatus()
+	status.Deployments = append(status.Deployments, bluegreen.DeploymentStatus{Deployment: bluegreen.Deployment{
+		ID:        "bgd-def",
+		Status:    bluegreen.StatusAvailable,
+		SourceArn: "arn:aws:rds:us-east-1:123456789012:cluster:lab",
+	}})
+
+	deployments, err := cleanupDeployments(status, "")
+	if err != nil || len(deployments) != 1 || deployments[0].ID != "bgd-abc" {
+		t.Errorf("all: got %v, %v", deployments, err)
+	}
+	if _, err := cleanupDeployments(status, "bgd-def"); err == nil || !strings.Contains(err.Error(), "not switched over") {
+		t.Errorf("available deployment: %v", err)
+	}
+	if _, err := cleanupDeployments(status, "bgd-xyz"); err == nil {
+		t.Error("unknown deployment: no error")
+	}
+}
+
+func TestCleanupSteps(t *testing.T) {
+	status := rollbackTestStatus()
+	old := status.Clusters[1]
+	deployments := []bluegreen.Deployment{status.Deployments[0].Deployment}
+
+	steps := cleanupSteps(deployments, &old, "lab-old1-final-20260102-150405")
+	want := []string{
+		"delete Blue/Green deployment bgd-abc (, lab-old1 -> lab)",
+		"take final snapshot lab-old1-final-20260102-150405 of lab-old1",
+		"DELETE cluster lab-old1 (5.7.mysql_aurora.2.12.1, instances lab-writer-old1)",
+	}
+	if strings.Join(steps, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got %q\nwant %q", steps, want)
+	}
+	if steps := cleanupSteps(deployments, &old, ""); !strings.HasSuffix(steps[1], "without a snapshot") {
+		t.Errorf("without snapshot: %q", steps)
+	}
+	if steps := cleanupSteps(deployments, nil, ""); len(steps) != 1 {
+		t.Errorf("deployments only: %q", steps)
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	var out bytes.Buffer
+	if err := confirm(strings.NewReader("lab-old1\n"), &out, "lab-old1"); err != nil {
+		t.Errorf("typed the identifier: %v", err)
+	}
+	if !strings.Contains(out.String(), "Type lab-old1 to delete it") {
+		t.Errorf("prompt %q", out.String())
+	}
+	for _, input := range []string{"yes\n", "", "lab\n"} {
+		if err := confirm(strings.NewReader(input), &out, "lab-old1"); err == nil {
+			t.Errorf("%q confirmed", input)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+
+	"aurora-bluegreen-lab/internal/bluegreen"
+	"aurora-bluegreen-lab/internal/experiments"
+	"aurora-bluegreen-lab/internal/runid"
+)
+
+const createUsage = `Usage: bgctl create [flags]
+
+Creates a Blue/Green deployment of the lab cluster and waits until the green
+environment is AVAILABLE. The green environment runs -target-engine-version,
+by default the aurora stack's greenEngineVersion, with the stack's green
+parameter groups when it has them.
+
+A major version upgrade, such as Aurora MySQL 2 (MySQL 5.7) to Aurora MySQL 3
+(MySQL 8.0), needs green parameter groups of the new family: set
+greenEngineVersion in the aurora stack, which creates them in the
+aurora-mysql8.0 family, or pass groups of that family. Deployments are
+registered as create-<time> runs in the experiment registry of the
+monitoring stack, if it has one.
+
+  bgctl create                                          the aurora stack's greenEngineVersion
+  bgctl create -target-engine-version 8.0.mysql_aurora.3.08.0
+  bgctl create -no-wait                                 return once RDS accepted the deployment
+
+Flags:
+`
+
+func createCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("create", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), createUsage)
+		fs.PrintDefaults()
+	}
+	var lab labFlags
+	lab.register(fs)
+	name := fs.String("name", "", "Deployment name (default: lab-bg-<time>)")
+	targetVersion := fs.String("target-engine-version", "", "Engine version of the green environment (default: the aurora stack's greenEngineVersion, else the blue version)")
+	clusterParameterGroup := fs.String("cluster-parameter-group", "", "Green cluster parameter group (default: the aurora stack's greenClusterParameterGroupName)")
+	instanceParameterGroup := fs.String("instance-parameter-group", "", "Green instance parameter group (default: the aurora stack's greenInstanceParameterGroupName)")
+	instanceClass := fs.String("target-instance-class", "", "Instance class of the green instances (default: the blue instances')")
+	noWait := fs.Bool("no-wait", false, "Do not wait until the green environment is AVAILABLE")
+	registryTable := fs.String("registry-table", "", "Experiment registry table to register the deployment in (default: the monitoring stack's experimentTableName output)")
+	var rf runFlag
+	rf.register(fs)
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	aurora, err := lab.reader().Outputs(ctx, "aurora")
+	if err != nil {
+		return err
+	}
+	clusterArn, region := aurora.String("clusterArn"), lab.region
+	if clusterArn == "" {
+		return fmt.Errorf("the aurora stack has no clusterArn output (run pulumi up in aurora/)")
+	}
+	if region == "" {
+		region = aurora.String("region")
+	}
+
+	target := greenTarget{
+		blueVersion:            aurora.String("engineVersion"),
+		engineVersion:          *targetVersion,
+		clusterParameterGroup:  *clusterParameterGroup,
+		instanceParameterGroup: *instanceParameterGroup,
+	}
+	if target.engineVersion == "" {
+		target.engineVersion = aurora.String("greenEngineVersion")
+	}
+	// The stack's green parameter groups, unless both are given
+	if target.clusterParameterGroup == "" && target.instanceParameterGroup == "" {
+		target.clusterParameterGroup = aurora.String("greenClusterParameterGroupName")
+		target.instanceParameterGroup = aurora.String("greenInstanceParameterGroupName")
+		target.parameterGroupFamily = aurora.String("greenParameterGroupFamily")
+	}
+	major, err := target.check()
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	if err != nil {
+		return fmt.Errorf("loading AWS configuration: %w", err)
+	}
+	client := bluegreen.New(cfg)
+
+	started := time.Now().UTC()
+	runID, source, err := rf.identify("create", started)
+	if err != nil {
+		return err
+	}
+	if *name == "" {
+		*name = "lab-bg-" + started.Format("20060102-150405")
+	}
+	registry := openRegistry(ctx, lab, cfg, *registryTable)
+	run := &experiments.Run{
+		RunID:     runID,
+		Source:    source,
+		Name:      "create",
+		Status:    experiments.StatusRunning,
+		StartedAt: started,
+		Config: map[string]string{
+			"deployment":                   *name,
+			"blueEngineVersion":            target.blueVersion,
+			"targetEngineVersion":          target.engineVersion,
+			"majorVersionUpgrade":          strconv.FormatBool(major),
+			"targetClusterParameterGroup":  target.clusterParameterGroup,
+			"targetInstanceParameterGroup": target.instanceParameterGroup,
+			"targetInstanceClass":          *instanceClass,
+		},
+	}
+	registerRun(ctx, registry, run)
+
+	upgrade := "same engine version"
+	if target.engineVersion != "" {
+		upgrade = target.blueVersion + " -> " + target.engineVersion
+		if major {
+			upgrade += " (major version upgrade)"
+		}
+	}
+	fmt.Printf("[INFO] Creating Blue/Green deployment %s: %s\n", *name, upgrade)
+	if target.clusterParameterGroup != "" || target.instanceParameterGroup != "" {
+		fmt.Printf("[INFO] Green parameter groups: cluster %s, instance %s\n", dash(target.clusterParameterGroup), dash(target.instanceParameterGroup))
+	}
+	d, err := client.Create(ctx, bluegreen.CreateOptions{
+		Name:                         *name,
+		SourceArn:                    clusterArn,
+		TargetEngineVersion:          target.engineVersion,
+		TargetClusterParameterGroup:  target.clusterParameterGroup,
+		TargetInstanceParameterGroup: target.instanceParameterGroup,
+		TargetInstanceClass:          *instanceClass,
+		Tags:                         map[string]string{runid.Tag: run.RunID},
+	})
+	id := ""
+	if err == nil {
+		id = d.ID
+		run.Config["deploymentId"] = id
+		fmt.Printf("[INFO] Deployment %s created\n", id)
+		if !*noWait {
+			fmt.Println("[INFO] Waiting for the green environment; this takes 20-60 minutes, longer for a major version upgrade")
+			d, err = client.WaitAvailable(ctx, id, func(d *bluegreen.Deployment) {
+				fmt.Printf("[INFO] %s deployment %s: %s\n", time.Now().UTC().Format(time.RFC3339), d.ID, d.Status)
+			})
+		}
+	}
+	run.Finish(time.Now().UTC(), err)
+	if err == nil && !*noWait {
+		run.Results = map[string]float64{"provisionSeconds": run.Duration(time.Now()).Seconds()}
+	}
+	registerRun(context.WithoutCancel(ctx), registry, run)
+	if err != nil {
+		if ctx.Err() != nil && id != "" {
+			fmt.Printf("[INFO] RDS keeps creating deployment %s; follow it with bgctl watch\n", id)
+		}
+		return err
+	}
+	if *noWait {
+		fmt.Printf("[SUCCESS] Deployment %s is being created; follow it with bgctl watch\n", id)
+		return nil
+	}
+	fmt.Printf("[SUCCESS] Green cluster %s is available; switch over with bgctl switchover -auto\n", d.TargetClusterIdentifier())
+	return nil
+}
+
+// greenTarget is the green environment of a new deployment.
+type greenTarget struct {
+	// blueVersion is the blue cluster's engine version
+	blueVersion string
+	// engineVersion is the green version; empty keeps the blue one
+	engineVersion          string
+	clusterParameterGroup  string
+	instanceParameterGroup string
+	// parameterGroupFamily is the family of the parameter groups when they
+	// are the aurora stack's; empty when unknown
+	parameterGroupFamily string
+}
+
+// check returns whether the target is a major version upgrade, and fails
+// when the green environment cannot run the target version: a downgrade, or a
+// major version upgrade without parameter groups of the new family.
+func (g greenTarget) check() (bool, error) {
+	blue, target := mysqlVersion(g.blueVersion), mysqlVersion(g.engineVersion)
+	if target == "" {
+		target = blue
+	}
+	if blue == "" || target == "" {
+		// Unknown versions are left for RDS to check
+		return false, nil
+	}
+	family := "aurora-mysql" + target
+	if target < blue {
+		return false, fmt.Errorf("%s is an older major version than the blue cluster's %s; Blue/Green deployments cannot downgrade", g.engineVersion, g.blueVersion)
+	}
+	major := target != blue
+	if major && g.clusterParameterGroup == "" {
+		return true, fmt.Errorf("the major version upgrade to %s needs green parameter groups of the %s family: set greenEngineVersion in the aurora stack, or pass -cluster-parameter-group", g.engineVersion, family)
+	}
+	if g.parameterGroupFamily != "" && g.parameterGroupFamily != family {
+		return major, fmt.Errorf("the aurora stack's green parameter groups are of the %s family, %s needs %s: set greenEngineVersion in the aurora stack to %s, or pass -cluster-parameter-group and -instance-parameter-group",
+			g.parameterGroupFamily, dash(g.engineVersion), family, dash(g.engineVersion))
+	}
+	return major, nil
+}
+
+// mysqlVersion returns the MySQL version of an Aurora MySQL engine version,
+// e.g. 5.7 for 5.7.mysql_aurora.2.12.1; empty for other versions.
+func mysqlVersion(engineVersion string) string {
+	version, _, found := strings.Cut(engineVersion, ".mysql_aurora.")
+	if !found {
+		return ""
+	}
+	return version
+}
+
+func dash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
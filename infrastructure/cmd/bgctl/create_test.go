@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGreenTargetCheck(t *testing.T) {
+	for name, tc := range map[string]struct {
+		target greenTarget
+		major  bool
+		err    string
+	}{
+		"minor upgrade": {
+			target: greenTarget{blueVersion: "8.0.mysql_aurora.3.04.0", engineVersion: "8.0.mysql_aurora.3.08.0"},
+		},
+		"same version with green parameters": {
+			target: greenTarget{blueVersion: "8.0.mysql_aurora.3.04.0", clusterParameterGroup: "lab-cluster-pg-green", parameterGroupFamily: "aurora-mysql8.0"},
+		},
+		"5.7 to 8.0": {
+			target: greenTarget{
+				blueVersion: "5.7.mysql_aurora.2.12.1", engineVersion: "8.0.mysql_aurora.3.08.0",
+				clusterParameterGroup: "lab-cluster-pg-green", instanceParameterGroup: "lab-instance-pg-green", parameterGroupFamily: "aurora-mysql8.0",
+			},
+			major: true,
+		},
+		"5.7 to 8.0 with given parameter groups": {
+			target: greenTarget{blueVersion: "5.7.mysql_aurora.2.12.1", engineVersion: "8.0.mysql_aurora.3.08.0", clusterParameterGroup: "my-80-pg"},
+			major:  true,
+		},
+		"5.7 to 8.0 without parameter groups": {
+			target: greenTarget{blueVersion: "5.7.mysql_aurora.2.12.1", engineVersion: "8.0.mysql_aurora.3.08.0"},
+			major:  true,
+			err:    "needs green parameter groups of the aurora-mysql8.0 family",
+		},
+		"5.7 to 8.0 with 5.7 parameter groups": {
+			target: greenTarget{
+				blueVersion: "5.7.mysql_aurora.2.12.1", engineVersion: "8.0.mysql_aurora.3.08.0",
+				clusterParameterGroup: "lab-cluster-pg-green", parameterGroupFamily: "aurora-mysql5.7",
+			},
+			major: true,
+			err:   "are of the aurora-mysql5.7 family",
+		},
+		"downgrade": {
+			target: greenTarget{blueVersion: "8.0.mysql_aurora.3.04.0", engineVersion: "5.7.mysql_aurora.2.12.1"},
+			err:    "cannot downgrade",
+		},
+	} {
+		major, err := tc.target.check()
+		if major != tc.major {
+			t.Errorf("%s: major %v, want %v", name, major, tc.major)
+		}
+		switch {
+		case tc.err == "" && err != nil:
+			t.Errorf("%s: %v", name, err)
+		case tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)):
+			t.Errorf("%s: got %v, want an error mentioning %q", name, err, tc.err)
+		}
+	}
+}
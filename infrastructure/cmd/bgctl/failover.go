@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+
+	"aurora-bluegreen-lab/internal/bluegreen"
+	"aurora-bluegreen-lab/internal/experiments"
+	"aurora-bluegreen-lab/internal/report"
+)
+
+const failoverUsage = `Usage: bgctl failover [flags]
+
+Fails the lab cluster over to a reader with FailoverDBCluster, the ordinary
+Aurora failover, and waits until the reader is the writer and the cluster is
+available. Run it during a simulator run, like a switchover, to measure the
+downtime of a failover with the same simulator and report as the Blue/Green
+switchover it is compared with.
+
+-timeline appends the failover-started and failover-completed events to a
+timeline that lab-report shows as the failover window. Failovers are
+registered as failover-<time> runs in the experiment registry of the
+monitoring stack, if it has one, so lab-report list compares them with the
+switchover runs.
+
+  bgctl failover                                    fail over to the reader RDS picks
+  bgctl failover -target-instance lab-instance-2    fail over to this reader
+  bgctl failover -timeline timeline.jsonl           record the failover for lab-report
+
+Flags:
+`
+
+func failoverCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("failover", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), failoverUsage)
+		fs.PrintDefaults()
+	}
+	var lab labFlags
+	lab.register(fs)
+	cluster := fs.String("cluster", "", "Cluster to fail over (default: the aurora stack's clusterIdentifier output)")
+	targetInstance := fs.String("target-instance", "", "Reader to promote to the writer (default: the reader RDS picks by failover priority)")
+	timeout := fs.Duration("timeout", 10*time.Minute, "How long to wait for the failover to complete")
+	interval := fs.Duration("interval", 2*time.Second, "How often the cluster is polled; the completion time is known to this interval")
+	timelinePath := fs.String("timeline", "", "Timeline file (JSON Lines) to append the failover events to, for lab-report --timeline")
+	registryTable := fs.String("registry-table", "", "Experiment registry table to register the failover in (default: the monitoring stack's experimentTableName output)")
+	var rf runFlag
+	rf.register(fs)
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if *interval < time.Second {
+		return fmt.Errorf("-interval must be at least 1s, got %s", *interval)
+	}
+
+	clusterIdentifier, region := *cluster, lab.region
+	if clusterIdentifier == "" || region == "" {
+		aurora, err := lab.reader().Outputs(ctx, "aurora")
+		if err != nil {
+			return err
+		}
+		if clusterIdentifier == "" {
+			if clusterIdentifier = aurora.String("clusterIdentifier"); clusterIdentifier == "" {
+				return fmt.Errorf("the aurora stack has no clusterIdentifier output; pass -cluster")
+			}
+		}
+		if region == "" {
+			region = aurora.String("region")
+		}
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	if err != nil {
+		return fmt.Errorf("loading AWS configuration: %w", err)
+	}
+	client := bluegreen.New(cfg)
+	client.PollInterval = *interval
+
+	status, err := client.LabStatus(ctx, clusterIdentifier)
+	if err != nil {
+		return err
+	}
+	cs, err := clusterStatus(status, clusterIdentifier)
+	if err != nil {
+		return err
+	}
+	writer, err := failoverCheck(cs, *targetInstance)
+	if err != nil {
+		return err
+	}
+
+	started := time.Now().UTC()
+	runID, source, err := rf.identify("failover", started)
+	if err != nil {
+		return err
+	}
+
+	var tl *eventTimeline
+	if *timelinePath != "" {
+		if tl, err = openTimeline(*timelinePath, runID); err != nil {
+			return err
+		}
+		defer tl.close()
+	}
+
+	registry := openRegistry(ctx, lab, cfg, *registryTable)
+	run := &experiments.Run{
+		RunID:     runID,
+		Source:    source,
+		Name:      "failover",
+		Status:    experiments.StatusRunning,
+		StartedAt: started,
+		Config: map[string]string{
+			"cluster":        clusterIdentifier,
+			"writer":         writer,
+			"targetInstance": *targetInstance,
+		},
+		Timings: map[string]time.Time{},
+		Results: map[string]float64{},
+	}
+	registerRun(ctx, registry, run)
+
+	target := *targetInstance
+	if target == "" {
+		target = "the reader RDS picks"
+	}
+	fmt.Printf("[INFO] Failing over %s from writer %s to %s\n", clusterIdentifier, writer, target)
+	run.Timings[report.EventFailoverStarted] = time.Now().UTC()
+	tl.record(report.EventFailoverStarted, fmt.Sprintf("%s from %s", clusterIdentifier, writer))
+
+	waitCtx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+	f, err := client.Failover(waitCtx, clusterIdentifier, *targetInstance, func(f *bluegreen.Failover) {
+		fmt.Printf("[INFO] %s cluster %s: %s, writer %s\n", time.Now().UTC().Format(time.RFC3339), f.ClusterIdentifier, f.Status, dash(f.Writer))
+	})
+	if err == nil {
+		completed := time.Now().UTC()
+		run.Timings[report.EventFailoverCompleted] = completed
+		run.Results["failoverSeconds"] = completed.Sub(run.Timings[report.EventFailoverStarted]).Seconds()
+		tl.record(report.EventFailoverCompleted, fmt.Sprintf("%s is the writer", f.Writer))
+	} else {
+		if waitCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+			err = fmt.Errorf("the failover of %s did not complete within %s", clusterIdentifier, *timeout)
+		}
+		run.Timings["failover-failed"] = time.Now().UTC()
+		tl.record("failover-failed", err.Error())
+	}
+	run.Finish(time.Now().UTC(), err)
+	registerRun(context.WithoutCancel(ctx), registry, run)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("[SUCCESS] Failed over %s in %.0fs; %s is the writer\n", clusterIdentifier, run.Results["failoverSeconds"], f.Writer)
+	return nil
+}
+
+// failoverCheck returns the cluster's writer, and fails when the cluster
+// cannot fail over to targetInstance (any reader when empty).
+func failoverCheck(cluster bluegreen.ClusterStatus, targetInstance string) (string, error) {
+	if cluster.Status != "available" {
+		return "", fmt.Errorf("cluster %s is %s; fail over an available cluster", cluster.Identifier, cluster.Status)
+	}
+	writer := ""
+	var readers []string
+	for _, instance := range cluster.Instances {
+		switch {
+		case instance.Writer:
+			writer = instance.Identifier
+		case instance.Status == "available":
+			readers = append(readers, instance.Identifier)
+		}
+	}
+	if writer == "" {
+		return "", fmt.Errorf("cluster %s has no writer", cluster.Identifier)
+	}
+	if len(readers) == 0 {
+		return "", fmt.Errorf("cluster %s has no available reader to fail over to", cluster.Identifier)
+	}
+	switch {
+	case targetInstance == "":
+	case targetInstance == writer:
+		return "", fmt.Errorf("%s is the writer of %s already; pass a reader (%s)", targetInstance, cluster.Identifier, strings.Join(readers, ", "))
+	default:
+		found := false
+		for _, reader := range readers {
+			found = found || reader == targetInstance
+		}
+		if !found {
+			return "", fmt.Errorf("%s is not an available reader of %s (%s)", targetInstance, cluster.Identifier, strings.Join(readers, ", "))
+		}
+	}
+	return writer, nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"aurora-bluegreen-lab/internal/bluegreen"
+)
+
+func TestFailoverCheck(t *testing.T) {
+	cluster := bluegreen.ClusterStatus{
+		Identifier: "lab-cluster",
+		Status:     "available",
+		Instances: []bluegreen.InstanceStatus{
+			{Identifier: "lab-instance-1", Status: "available", Writer: true},
+			{Identifier: "lab-instance-2", Status: "available"},
+			{Identifier: "lab-instance-3", Status: "rebooting"},
+		},
+	}
+	for _, target := range []string{"", "lab-instance-2"} {
+		if writer, err := failoverCheck(cluster, target); err != nil || writer != "lab-instance-1" {
+			t.Errorf("%q: got %s, %v", target, writer, err)
+		}
+	}
+
+	for target, want := range map[string]string{
+		"lab-instance-1": "is the writer of lab-cluster already",
+		"lab-instance-3": "is not an available reader of lab-cluster (lab-instance-2)",
+	} {
+		if _, err := failoverCheck(cluster, target); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: got %v, want %q", target, err, want)
+		}
+	}
+
+	cluster.Instances = cluster.Instances[:1]
+	if _, err := failoverCheck(cluster, ""); err == nil || !strings.Contains(err.Error(), "no available reader") {
+		t.Errorf("without readers: got %v", err)
+	}
+	cluster.Status = "failing-over"
+	if _, err := failoverCheck(cluster, ""); err == nil || !strings.Contains(err.Error(), "is failing-over") {
+		t.Errorf("failing over: got %v", err)
+	}
+}
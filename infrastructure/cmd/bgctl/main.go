@@ -0,0 +1,124 @@
+// Command bgctl operates a deployed lab from the operator's machine:
+//
+//	bgctl simulator start|stop|restart|status   control the simulator service
+//	bgctl simulator logs [-n 100] [-f] [-run ID] print or follow the simulator log
+//	bgctl simulator stats|pause|resume|rate     query or steer the running simulator
+//	bgctl simulator mark -event E [-detail D]   mark an experiment event in the simulator log
+//	bgctl preflight [-strict] [-no-sql]         check the cluster for Blue/Green blockers and integrations
+//	bgctl versions [-major] [-set V|latest]     list the upgrade targets, set the green engine version
+//	bgctl create [-target-engine-version V]     create a Blue/Green deployment, e.g. 5.7 to 8.0
+//	bgctl backtrack [-to 15m] [-cluster ID]     rewind the old blue cluster
+//	bgctl schema-change -file changes.sql       run replication-safe DDL on the green environment
+//	bgctl validate-green [-checks file]         check the green environment against blue
+//	bgctl switchover [-auto] [-measure]         switch over, with -auto once the health gates pass
+//	bgctl rollback [-mode reverse|restore]      roll back to the old blue environment after a switchover
+//	bgctl cleanup [-delete-old-blue]            delete the deployment and the old blue cluster after a switchover
+//	bgctl failover [-target-instance ID]        fail over the cluster, the baseline for the switchover
+//	bgctl chaos <action> [-duration 2m]         reboot instances, add latency or impair an AZ
+//	bgctl replica setup|status|repoint          follow the external replica through a switchover
+//	bgctl watch [-interval 5s] [-once]          follow deployments, roles, lag and connections
+//
+// The simulator host is reached with SSM Run Command by default (no SSH port
+// or key pair needed) or with SSH, and command output is streamed to the
+// terminal while it runs (internal/remote). The host is looked up in the ec2
+// stack outputs unless it is given with -instance-id or -ssh-host.
+//
+// bgctl switchover posts its progress to a Slack incoming webhook or any
+// HTTPS endpoint given with -webhook or LAB_WEBHOOK_URL (internal/notify).
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+
+	"aurora-bluegreen-lab/internal/stacks"
+)
+
+// command is a bgctl subcommand; args follow the subcommand name.
+type command struct {
+	summary string
+	run     func(ctx context.Context, args []string) error
+}
+
+var commands = map[string]command{
+	"backtrack":      {"Rewind the old blue cluster (or -cluster) with Aurora Backtrack", backtrackCommand},
+	"chaos":          {"Reboot the writer or readers, or inject network latency or an AZ impairment with AWS FIS", chaosCommand},
+	"cleanup":        {"Delete the switched over deployments and, after confirmation, the old blue cluster", cleanupCommand},
+	"create":         {"Create a Blue/Green deployment of the lab cluster, including major version upgrades", createCommand},
+	"failover":       {"Fail the cluster over to a reader, to compare its downtime with a switchover's", failoverCommand},
+	"preflight":      {"Check the cluster for Blue/Green blockers and list the external integrations a switchover affects", preflightCommand},
+	"rollback":       {"Roll back to the old blue environment with a reverse deployment or by restoring the old blue cluster", rollbackCommand},
+	"replica":        {"Set up, check and repoint the external MySQL replica of the cluster's binary log", replicaCommand},
+	"schema-change":  {"Run replication-safe DDL on the green environment before the switchover", schemaChangeCommand},
+	"simulator":      {"Control the workload simulator on the simulator host", simulatorCommand},
+	"switchover":     {"Switch the Blue/Green deployment over, with -auto once the health gates pass", switchoverCommand},
+	"validate-green": {"Check the green environment's row counts, engine version, parameters, grants and query latency", validateGreenCommand},
+	"versions":       {"List the engine versions the cluster can be upgraded to and set the green engine version", versionsCommand},
+	"watch":          {"Live view of the Blue/Green deployments, instance roles, replica lag and connections", watchCommand},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: bgctl <command> [arguments]\n\nCommands:\n")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun 'bgctl <command> -h' for the command's flags.\n")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	name := os.Args[1]
+	if name == "help" || name == "-h" || name == "-help" || name == "--help" {
+		usage()
+		return
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "[ERROR] unknown command %q\n\n", name)
+		usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := cmd.run(ctx, os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// labFlags are the flags locating the deployed lab, shared by the commands.
+type labFlags struct {
+	infraDir  string
+	stackName string
+	org       string
+	region    string
+	// outputsFile is a lab-deploy outputs file read instead of the stacks
+	outputsFile string
+}
+
+func (f *labFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.infraDir, "infra-dir", ".", "Path to the infrastructure directory containing the stack projects")
+	fs.StringVar(&f.stackName, "stack", "dev", "Pulumi stack name of the lab stacks")
+	fs.StringVar(&f.org, "org", "", "Pulumi organization of the stacks (default: output of 'pulumi whoami')")
+	fs.StringVar(&f.region, "region", "", "AWS region (default: the stack's region output)")
+	fs.StringVar(&f.outputsFile, "outputs-file", "", "Read the stack outputs from this lab-outputs.json written by lab-deploy instead of Pulumi")
+}
+
+// reader returns the reader of the lab's stack outputs.
+func (f *labFlags) reader() *stacks.Reader {
+	return &stacks.Reader{InfraDir: f.infraDir, Org: f.org, Stack: f.stackName, File: f.outputsFile}
+}
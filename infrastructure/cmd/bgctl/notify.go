@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"aurora-bluegreen-lab/internal/notify"
+)
+
+// notifyFlags is the -webhook flag of the commands posting their progress.
+type notifyFlags struct {
+	webhook string
+}
+
+func (f *notifyFlags) register(fs *flag.FlagSet) {
+	// The URL is a secret, so $LAB_WEBHOOK_URL is not shown as the default
+	fs.StringVar(&f.webhook, "webhook", "", "Slack incoming webhook or HTTPS URL to post the command's events to (default: $"+notify.EnvVar+")")
+}
+
+// open returns the notifier of the command's events under the run ID; nil
+// without a webhook.
+func (f *notifyFlags) open(command, runID string) (*notifier, error) {
+	webhook := f.webhook
+	if webhook == "" {
+		webhook = os.Getenv(notify.EnvVar)
+	}
+	n, err := notify.New(webhook)
+	if err != nil || n == nil {
+		return nil, err
+	}
+	return &notifier{n: n, source: "bgctl/" + command, runID: runID}, nil
+}
+
+// notifier posts a command's events to the webhook; a nil notifier posts
+// nothing.
+type notifier struct {
+	n      *notify.Notifier
+	source string
+	runID  string
+}
+
+// post posts an event. The command does not depend on it: a webhook that
+// cannot be reached is only reported, and events are posted even when the
+// command was interrupted.
+func (n *notifier) post(ctx context.Context, event, text string, fields ...notify.Field) {
+	if n == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), notify.Timeout)
+	defer cancel()
+	err := n.n.Send(ctx, notify.Message{Source: n.source, Event: event, Text: text, RunID: n.runID, Fields: fields})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[WARNING] Not notified: %v\n", err)
+	}
+}
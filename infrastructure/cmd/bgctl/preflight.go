@@ -0,0 +1,381 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+
+	"aurora-bluegreen-lab/internal/bluegreen"
+	"aurora-bluegreen-lab/internal/remote"
+	"aurora-bluegreen-lab/internal/stacks"
+)
+
+const preflightUsage = `Usage: bgctl preflight [flags]
+
+Checks the lab cluster for known Blue/Green blockers before a deployment is
+created, and lists the external integrations the switchover affects:
+
+  cluster         the cluster and its instances are available
+  engine version  Blue/Green deployments support the cluster's engine version
+                  (Aurora MySQL 2.10 and later, or Aurora MySQL 3)
+  storage         the cluster is provisioned (not Aurora Serverless v1), with
+                  its storage type and encryption
+  replication     the cluster is not a read replica, and does not replicate
+                  from an external MySQL source (SHOW REPLICA STATUS)
+  binary logging  binlog_format is not OFF in the cluster parameter group, and
+                  the writer was rebooted since it was set
+  maintenance     no maintenance actions or modifications are pending on the
+                  cluster or its instances
+  XA transactions no prepared XA transactions on the writer (XA RECOVER), which
+                  block the switchover
+  deployments     no other deployment of the cluster is unfinished
+
+  zero-ETL        zero-ETL integrations with the cluster as their source
+  dms             the DMS task of the dms stack reading the cluster endpoint
+  replica         the external replica of the aurora stack (externalReplica)
+  read replicas   clusters replicating from the cluster, e.g. cross-Region
+
+The replication and XA transactions checks run SQL on the writer from the
+simulator host, like bgctl schema-change; -no-sql skips them. The other
+checks fail the command, and every failed check says how to fix it.
+Integrations are warnings (-strict fails on them too): a switchover moves
+the cluster endpoint to the green cluster,
+whose binary log files and positions differ, so a CDC task or replica
+reading the old positions stops or misses changes until it is restarted at
+the new ones, and zero-ETL integrations restrict Blue/Green deployments of
+their source. DMS tasks outside the lab's dms stack are not detected.
+
+  bgctl preflight
+  bgctl preflight -strict
+  bgctl preflight -no-sql              without the simulator host
+
+Flags:
+`
+
+func preflightCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("preflight", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), preflightUsage)
+		fs.PrintDefaults()
+	}
+	var lab labFlags
+	lab.register(fs)
+	var hf hostFlags
+	hf.register(fs)
+	strict := fs.Bool("strict", false, "Fail when the cluster has external integrations")
+	noSQL := fs.Bool("no-sql", false, "Skip the checks that run SQL on the writer (inbound replication, XA transactions)")
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	aurora, err := lab.reader().Outputs(ctx, "aurora")
+	if err != nil {
+		return err
+	}
+	in := preflightInputs{
+		clusterIdentifier: aurora.String("clusterIdentifier"),
+		replicaEndpoint:   aurora.String("externalReplicaEndpoint"),
+	}
+	if in.clusterIdentifier == "" {
+		return fmt.Errorf("the aurora stack has no clusterIdentifier output")
+	}
+	region := lab.region
+	if region == "" {
+		region = aurora.String("region")
+	}
+	// Without a dms stack there is no DMS task to report
+	if dms, err := lab.reader().Outputs(ctx, "dms"); err == nil {
+		in.dms = dms
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	if err != nil {
+		return fmt.Errorf("loading AWS configuration: %w", err)
+	}
+	client := bluegreen.New(cfg)
+	if in.status, err = client.LabStatus(ctx, in.clusterIdentifier); err != nil {
+		return err
+	}
+	if in.readiness, err = client.Readiness(ctx, in.clusterIdentifier); err != nil {
+		return err
+	}
+	if !*noSQL {
+		cluster, err := clusterStatus(in.status, in.clusterIdentifier)
+		if err != nil {
+			return err
+		}
+		database := aurora.String("databaseName")
+		if database == "" {
+			return fmt.Errorf("the aurora stack has no databaseName output; pass -no-sql")
+		}
+		host, err := hf.connect(ctx, lab)
+		if err != nil {
+			return fmt.Errorf("%w (pass -no-sql to skip the SQL checks)", err)
+		}
+		in.sql = querySQLChecks(ctx, remote.NewMySQL(host, cluster.Endpoint, database), in.readiness.EngineVersion)
+	}
+	if clusterArn := aurora.String("clusterArn"); clusterArn != "" {
+		if in.integrations, err = client.ZeroEtlIntegrations(ctx, clusterArn); err != nil {
+			return err
+		}
+	}
+
+	required, integrations := preflightGates(in)
+	fmt.Printf("[INFO] Preflight checks of %s:\n", in.clusterIdentifier)
+	var failed, warnings []string
+	for _, g := range required {
+		result := "PASS"
+		if !g.passed {
+			result = "FAIL"
+			failed = append(failed, g.name+": "+g.detail)
+		}
+		fmt.Printf("  [%s] %-15s %s\n", result, g.name, g.detail)
+	}
+	for _, g := range integrations {
+		result := "PASS"
+		if !g.passed {
+			result = "WARN"
+			warnings = append(warnings, g.name)
+			if *strict {
+				result = "FAIL"
+				failed = append(failed, g.name+": "+g.detail)
+			}
+		}
+		fmt.Printf("  [%s] %-15s %s\n", result, g.name, g.detail)
+	}
+	if *noSQL {
+		fmt.Println("[INFO] Skipped the replication and XA transactions SQL checks (-no-sql)")
+	}
+	if len(failed) > 0 {
+		fmt.Println("[INFO] Fix before creating the deployment:")
+		for _, f := range failed {
+			fmt.Printf("  - %s\n", f)
+		}
+		return fmt.Errorf("%d preflight checks failed", len(failed))
+	}
+	if len(warnings) > 0 {
+		fmt.Printf("[WARNING] Plan for the external integrations before the switchover: %s\n", strings.Join(warnings, ", "))
+		return nil
+	}
+	fmt.Printf("[SUCCESS] %s is ready for a Blue/Green deployment\n", in.clusterIdentifier)
+	return nil
+}
+
+// preflightInputs are what the preflight checks look at.
+type preflightInputs struct {
+	clusterIdentifier string
+	status            *bluegreen.LabStatus
+	readiness         *bluegreen.Readiness
+	// sql are the results of the SQL checks on the writer, nil when skipped
+	sql          *sqlChecks
+	integrations []bluegreen.Integration
+	// dms are the dms stack outputs, nil without the stack
+	dms stacks.Outputs
+	// replicaEndpoint is the aurora stack's external replica, if any
+	replicaEndpoint string
+}
+
+// preflightGates returns the checks a deployment needs to pass and the
+// integration checks, which fail when the cluster has the integration.
+func preflightGates(in preflightInputs) (required, integrations []gate) {
+	cluster, err := clusterStatus(in.status, in.clusterIdentifier)
+	switch {
+	case err != nil:
+		required = append(required, gate{"cluster", false, err.Error()})
+	case cluster.Status != "available":
+		required = append(required, gate{"cluster", false, fmt.Sprintf("%s is %s", cluster.Identifier, cluster.Status)})
+	default:
+		var unavailable []string
+		for _, instance := range cluster.Instances {
+			if instance.Status != "available" {
+				unavailable = append(unavailable, instance.Identifier+" is "+dash(instance.Status))
+			}
+		}
+		if len(unavailable) > 0 {
+			required = append(required, gate{"cluster", false, strings.Join(unavailable, ", ")})
+		} else {
+			required = append(required, gate{"cluster", true, fmt.Sprintf("%s and its %d instances are available", cluster.Identifier, len(cluster.Instances))})
+		}
+	}
+
+	r := in.readiness
+	if bluegreen.SupportsBlueGreen(r.EngineVersion) {
+		required = append(required, gate{"engine version", true, fmt.Sprintf("%s (%s)", r.EngineVersion, dash(r.EngineVersionStatus))})
+	} else {
+		required = append(required, gate{"engine version", false, r.EngineVersion +
+			" does not support Blue/Green deployments; upgrade the cluster in place to Aurora MySQL 2.10 or later, or 3, first"})
+	}
+
+	if r.EngineMode != "provisioned" {
+		required = append(required, gate{"storage", false, fmt.Sprintf(
+			"engine mode %s; Blue/Green deployments need a provisioned cluster (Serverless v2 instances are fine), restore a snapshot to one", dash(r.EngineMode))})
+	} else {
+		storageType := r.StorageType
+		if storageType == "" {
+			storageType = "aurora"
+		}
+		encrypted := "unencrypted"
+		if r.StorageEncrypted {
+			encrypted = "encrypted"
+		}
+		required = append(required, gate{"storage", true, fmt.Sprintf("provisioned, storage type %s, %s", storageType, encrypted)})
+	}
+
+	switch {
+	case r.ReplicationSource != "":
+		required = append(required, gate{"replication", false, "the cluster is a read replica of " + r.ReplicationSource +
+			"; promote it (aws rds promote-read-replica-db-cluster) before a Blue/Green deployment"})
+	case in.sql != nil && in.sql.replicationErr != nil:
+		required = append(required, gate{"replication", false, "checking inbound replication: " + in.sql.replicationErr.Error()})
+	case in.sql != nil && in.sql.replicationSource != "":
+		required = append(required, gate{"replication", false, "the writer replicates from the external source " + in.sql.replicationSource +
+			"; stop and reset it (CALL mysql.rds_stop_replication; CALL mysql.rds_reset_external_master) before a Blue/Green deployment"})
+	case in.sql != nil:
+		required = append(required, gate{"replication", true, "not a read replica, no inbound binlog replication"})
+	default:
+		required = append(required, gate{"replication", true, "not a read replica"})
+	}
+
+	switch {
+	case r.BinlogFormat == "OFF":
+		required = append(required, gate{"binary logging", false, fmt.Sprintf(
+			"binlog_format is OFF in %s; set binlogFormat in the aurora stack (ROW) and reboot the writer", dash(r.ParameterGroup))})
+	case r.WriterParameterStatus == "pending-reboot":
+		required = append(required, gate{"binary logging", false, fmt.Sprintf(
+			"binlog_format is %s in %s, but the writer is pending-reboot; reboot the writer so it takes effect", r.BinlogFormat, dash(r.ParameterGroup))})
+	default:
+		required = append(required, gate{"binary logging", true, fmt.Sprintf("binlog_format is %s in %s, writer %s", r.BinlogFormat, dash(r.ParameterGroup), dash(r.WriterParameterStatus))})
+	}
+
+	required = append(required, maintenanceGate(r, in.status, in.clusterIdentifier))
+
+	if in.sql != nil {
+		switch {
+		case in.sql.xaErr != nil:
+			required = append(required, gate{"XA transactions", false, "checking XA transactions: " + in.sql.xaErr.Error()})
+		case in.sql.xaTransactions > 0:
+			required = append(required, gate{"XA transactions", false, fmt.Sprintf(
+				"%d prepared XA transactions on the writer (XA RECOVER); commit or roll them back (XA COMMIT / XA ROLLBACK 'xid') before the switchover", in.sql.xaTransactions)})
+		default:
+			required = append(required, gate{"XA transactions", true, "no prepared XA transactions"})
+		}
+	}
+
+	var unfinished []string
+	for _, d := range in.status.Deployments {
+		if d.Status != bluegreen.StatusSwitchoverCompleted {
+			unfinished = append(unfinished, fmt.Sprintf("%s is %s", d.ID, d.Status))
+		}
+	}
+	if len(unfinished) > 0 {
+		required = append(required, gate{"deployments", false, strings.Join(unfinished, ", ") + "; switch it over or delete it first"})
+	} else {
+		required = append(required, gate{"deployments", true, "no unfinished Blue/Green deployment"})
+	}
+
+	if len(in.integrations) > 0 {
+		var names []string
+		for _, i := range in.integrations {
+			names = append(names, fmt.Sprintf("%s (%s)", i.Name, i.Status))
+		}
+		integrations = append(integrations, gate{"zero-ETL", false, strings.Join(names, ", ") +
+			" replicate the cluster; Blue/Green deployments of an integration's source are restricted, check the integration after the switchover"})
+	} else {
+		integrations = append(integrations, gate{"zero-ETL", true, "no zero-ETL integration"})
+	}
+
+	if task := in.dms.String("replicationTaskId"); task != "" {
+		integrations = append(integrations, gate{"dms", false, fmt.Sprintf(
+			"task %s (%s) reads %s; stop it before the switchover and restart its CDC at the green cluster's binary log position after it",
+			task, dash(in.dms.String("migrationType")), dash(in.dms.String("sourceServerName")))})
+	} else {
+		integrations = append(integrations, gate{"dms", true, "no dms stack"})
+	}
+
+	if in.replicaEndpoint != "" {
+		integrations = append(integrations, gate{"replica", false, in.replicaEndpoint +
+			" replicates the cluster's binary log; run bgctl replica repoint after the switchover"})
+	} else {
+		integrations = append(integrations, gate{"replica", true, "no external replica"})
+	}
+
+	if len(r.ReadReplicas) > 0 {
+		integrations = append(integrations, gate{"read replicas", false, strings.Join(r.ReadReplicas, ", ") +
+			" replicate the cluster and stay with the old blue cluster after the switchover; recreate them from the new cluster"})
+	} else {
+		integrations = append(integrations, gate{"read replicas", true, "no read replica clusters"})
+	}
+	return required, integrations
+}
+
+// maintenanceGate fails when maintenance actions or modifications are
+// pending on the cluster or its instances: RDS may apply them to the blue
+// environment while the deployment runs.
+func maintenanceGate(r *bluegreen.Readiness, status *bluegreen.LabStatus, clusterIdentifier string) gate {
+	var pending []string
+	for _, a := range r.PendingMaintenance {
+		p := fmt.Sprintf("%s: %s", a.Resource, a.Action)
+		if a.Description != "" {
+			p += " (" + a.Description + ")"
+		}
+		if !a.AutoAppliedAfter.IsZero() {
+			p += ", auto-applied after " + a.AutoAppliedAfter.UTC().Format(time.RFC3339)
+		}
+		pending = append(pending, p)
+	}
+	if cluster, err := clusterStatus(status, clusterIdentifier); err == nil {
+		if len(cluster.Pending) > 0 {
+			pending = append(pending, fmt.Sprintf("%s: pending %s", cluster.Identifier, strings.Join(cluster.Pending, ", ")))
+		}
+		for _, instance := range cluster.Instances {
+			if len(instance.Pending) > 0 {
+				pending = append(pending, fmt.Sprintf("%s: pending %s", instance.Identifier, strings.Join(instance.Pending, ", ")))
+			}
+		}
+	}
+	if len(pending) == 0 {
+		return gate{"maintenance", true, "no pending maintenance actions or modifications"}
+	}
+	return gate{"maintenance", false, strings.Join(pending, "; ") +
+		"; apply them first (aws rds apply-pending-maintenance-action --opt-in-type immediate, or the next maintenance window)"}
+}
+
+// sqlChecks are the results of the checks that run SQL on the writer.
+type sqlChecks struct {
+	// replicationSource is the external source the writer replicates from,
+	// empty without inbound replication
+	replicationSource string
+	replicationErr    error
+	xaTransactions    int
+	xaErr             error
+}
+
+// querySQLChecks runs the SQL checks on the writer through the cluster
+// endpoint. Aurora MySQL 2 (MySQL 5.7) only knows SHOW SLAVE STATUS.
+func querySQLChecks(ctx context.Context, db *remote.MySQL, engineVersion string) *sqlChecks {
+	checks := &sqlChecks{}
+	statement := "SHOW REPLICA STATUS"
+	if mysqlVersion(engineVersion) == "5.7" {
+		statement = "SHOW SLAVE STATUS"
+	}
+	rows, err := db.Query(ctx, statement)
+	checks.replicationErr = err
+	checks.replicationSource = replicationSource(rows)
+	rows, err = db.Query(ctx, "XA RECOVER")
+	checks.xaTransactions, checks.xaErr = len(rows), err
+	return checks
+}
+
+// replicationSource returns the source host of SHOW REPLICA STATUS rows,
+// its second column.
+func replicationSource(rows [][]string) string {
+	if len(rows) == 0 || len(rows[0]) < 2 {
+		return ""
+	}
+	return rows[0][1]
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+
+	"aurora-bluegreen-lab/internal/bluegreen"
+	"aurora-bluegreen-lab/internal/stacks"
+)
+
+func TestPreflightGates(t *testing.T) {
+	in := preflightInputs{
+		clusterIdentifier: "lab-cluster",
+		status: &bluegreen.LabStatus{Clusters: []bluegreen.ClusterStatus{{
+			Identifier: "lab-cluster",
+			Status:     "available",
+			Instances: []bluegreen.InstanceStatus{
+				{Identifier: "lab-writer", Status: "available", Writer: true},
+				{Identifier: "lab-reader", Status: "available"},
+			},
+		}}},
+		readiness: &bluegreen.Readiness{
+			ClusterIdentifier:     "lab-cluster",
+			EngineVersion:         "8.0.mysql_aurora.3.08.0",
+			EngineVersionStatus:   "available",
+			EngineMode:            "provisioned",
+			StorageType:           "aurora-iopt1",
+			StorageEncrypted:      true,
+			ParameterGroup:        "lab-cluster-params",
+			BinlogFormat:          "ROW",
+			WriterParameterStatus: "in-sync",
+		},
+		sql: &sqlChecks{},
+	}
+	required, integrations := preflightGates(in)
+	for _, g := range append(required, integrations...) {
+		if !g.passed {
+			t.Errorf("%s failed: %s", g.name, g.detail)
+		}
+	}
+
+	in.status.Clusters[0].Instances[1].Status = "rebooting"
+	in.status.Deployments = []bluegreen.DeploymentStatus{{Deployment: bluegreen.Deployment{ID: "bgd-1", Status: bluegreen.StatusAvailable}}}
+	in.readiness = &bluegreen.Readiness{
+		ClusterIdentifier:     "lab-cluster",
+		EngineVersion:         "5.7.mysql_aurora.2.07.10",
+		EngineMode:            "serverless",
+		ReplicationSource:     "arn:aws:rds:us-west-2:123456789012:cluster:lab-source",
+		ReadReplicas:          []string{"arn:aws:rds:eu-west-1:123456789012:cluster:lab-replica"},
+		ParameterGroup:        "default.aurora-mysql5.7",
+		BinlogFormat:          "OFF",
+		WriterParameterStatus: "in-sync",
+		PendingMaintenance: []bluegreen.MaintenanceAction{
+			{Resource: "lab-writer", Action: "system-update", Description: "New Operating System update is available"},
+		},
+	}
+	in.sql = &sqlChecks{xaTransactions: 2}
+	in.integrations = []bluegreen.Integration{{Name: "lab-to-redshift", Status: "active"}}
+	in.dms = stacks.Outputs{
+		"replicationTaskId": auto.OutputValue{Value: "lab-dms-task"},
+		"migrationType":     auto.OutputValue{Value: "full-load-and-cdc"},
+		"sourceServerName":  auto.OutputValue{Value: "lab-cluster.cluster-xyz.us-east-1.rds.amazonaws.com"},
+	}
+	in.replicaEndpoint = "lab-external-replica.xyz.us-east-1.rds.amazonaws.com"
+	required, integrations = preflightGates(in)
+	for _, g := range append(required, integrations...) {
+		if g.passed {
+			t.Errorf("%s passed: %s", g.name, g.detail)
+		}
+	}
+	for name, want := range map[string]string{
+		"cluster":         "lab-reader is rebooting",
+		"engine version":  "upgrade the cluster in place",
+		"storage":         "engine mode serverless",
+		"replication":     "read replica of arn:aws:rds:us-west-2:123456789012:cluster:lab-source",
+		"binary logging":  "binlog_format is OFF in default.aurora-mysql5.7",
+		"maintenance":     "lab-writer: system-update (New Operating System update is available)",
+		"XA transactions": "2 prepared XA transactions",
+		"zero-ETL":        "lab-to-redshift (active)",
+		"dms":             "task lab-dms-task (full-load-and-cdc)",
+		"replica":         "bgctl replica repoint",
+		"read replicas":   "lab-replica",
+	} {
+		found := false
+		for _, g := range append(required, integrations...) {
+			if g.name == name {
+				found = true
+				if !strings.Contains(g.detail, want) {
+					t.Errorf("%s: got %q, want %q in it", name, g.detail, want)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("no %s gate", name)
+		}
+	}
+
+	// Inbound replication is only visible in SQL, and a changed binlog_format
+	// only takes effect after a reboot of the writer
+	in.readiness.ReplicationSource = ""
+	in.readiness.BinlogFormat = "ROW"
+	in.readiness.WriterParameterStatus = "pending-reboot"
+	in.sql = &sqlChecks{replicationSource: "10.0.0.5"}
+	required, _ = preflightGates(in)
+	for _, g := range required {
+		if g.name == "replication" && !strings.Contains(g.detail, "external source 10.0.0.5") {
+			t.Errorf("replication: got %q", g.detail)
+		}
+		if g.name == "binary logging" && !strings.Contains(g.detail, "pending-reboot") {
+			t.Errorf("binary logging: got %q", g.detail)
+		}
+	}
+}
+
+func TestReplicationSource(t *testing.T) {
+	if got := replicationSource(nil); got != "" {
+		t.Errorf("no replication: got %q", got)
+	}
+	if got := replicationSource([][]string{{"Waiting for source to send event", "10.0.0.5", "repl", "3306"}}); got != "10.0.0.5" {
+		t.Errorf("got %q", got)
+	}
+}
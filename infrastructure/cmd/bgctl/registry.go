@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"aurora-bluegreen-lab/internal/experiments"
+	"aurora-bluegreen-lab/internal/runid"
+)
+
+// runFlag is the -run-id flag of the commands registering runs.
+type runFlag struct {
+	id string
+}
+
+func (f *runFlag) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.id, "run-id", runid.FromEnv(), "Run ID to join, e.g. of the lab-scenario run the command is part of (default: $"+runid.EnvVar+", else a new <command>-<time> run)")
+}
+
+// identify returns the run ID and source the command started at started
+// registers under: the joined run as bgctl/<command>, else a run of its own.
+func (f *runFlag) identify(command string, started time.Time) (id, source string, err error) {
+	if f.id == "" {
+		return runid.New(command, started), experiments.SourceBgctl, nil
+	}
+	if err := runid.Validate(f.id); err != nil {
+		return "", "", err
+	}
+	return f.id, experiments.CommandSource(command), nil
+}
+
+// openRegistry returns the experiment registry bgctl registers its runs in:
+// table, else the monitoring stack's. Without one runs are not registered.
+func openRegistry(ctx context.Context, lab labFlags, cfg aws.Config, table string) *experiments.Registry {
+	if table == "" {
+		var err error
+		if table, err = experiments.TableFromStacks(ctx, lab.reader()); err != nil || table == "" {
+			fmt.Println("[INFO] Not registering the run: no experiment registry (deploy the monitoring stack or pass -registry-table)")
+			return nil
+		}
+	}
+	return experiments.New(cfg, table)
+}
+
+// registerRun writes the run to the registry, if any; the command does not
+// depend on it.
+func registerRun(ctx context.Context, registry *experiments.Registry, run *experiments.Run) {
+	if registry == nil {
+		return
+	}
+	if err := registry.Put(ctx, run); err != nil {
+		fmt.Fprintf(os.Stderr, "[WARNING] %v\n", err)
+		return
+	}
+	fmt.Printf("[INFO] Registered run %s (%s) in %s\n", run.RunID, run.Status, registry.Table())
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"aurora-bluegreen-lab/internal/experiments"
+)
+
+func TestRunFlagIdentify(t *testing.T) {
+	started := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	var own runFlag
+	id, source, err := own.identify("switchover", started)
+	if err != nil || id != "switchover-20260102-150405" || source != experiments.SourceBgctl {
+		t.Errorf("own run: got %q %q (%v)", id, source, err)
+	}
+
+	joined := runFlag{id: "minor-upgrade-20260102-150000"}
+	id, source, err = joined.identify("switchover", started)
+	if err != nil || id != "minor-upgrade-20260102-150000" || source != "bgctl/switchover" {
+		t.Errorf("joined run: got %q %q (%v)", id, source, err)
+	}
+
+	if _, _, err := (&runFlag{id: "two words"}).identify("switchover", started); err == nil {
+		t.Error("an invalid run ID got no error")
+	}
+}
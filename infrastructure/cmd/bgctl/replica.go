@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+
+	"aurora-bluegreen-lab/internal/bluegreen"
+	"aurora-bluegreen-lab/internal/remote"
+)
+
+const replicaUsage = `Usage: bgctl replica [flags] <action>
+
+Controls the external replica of the aurora stack (externalReplica), an RDS
+for MySQL instance replicating the cluster endpoint's binary log outside the
+Blue/Green deployment, like a downstream replica or binlog consumer would.
+
+Actions:
+  setup     Copy the schema (without rows) and start replicating from the
+            cluster's current binary log position
+  status    Show the replica's replication threads, position, lag and errors
+  repoint   Restart replication at the binary log position RDS reports for
+            the new blue cluster after the latest switchover (or -log-file
+            and -log-pos)
+
+A switchover moves the cluster endpoint to the green cluster, whose binary
+log files and positions differ: replication from the old position stops
+with an I/O error, or resumes at the wrong events. status shows it, and
+repoint resumes at the coordinates of the switchover event.
+
+  bgctl replica setup
+  bgctl replica status
+  bgctl replica repoint
+  bgctl replica repoint -log-file mysql-bin-changelog.000003 -log-pos 804
+
+Flags:
+`
+
+func replicaCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("replica", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), replicaUsage)
+		fs.PrintDefaults()
+	}
+	var lab labFlags
+	var hf hostFlags
+	lab.register(fs)
+	hf.register(fs)
+	endpoint := fs.String("replica", "", "Endpoint of the external replica (default: the aurora stack's externalReplicaEndpoint output)")
+	source := fs.String("source", "", "Endpoint the replica replicates from (default: the aurora stack's clusterEndpoint output)")
+	database := fs.String("database", "", "Database whose schema setup copies (default: the aurora stack's databaseName output)")
+	logFile := fs.String("log-file", "", "Binary log file repoint restarts at (default: from the latest switchover event)")
+	logPos := fs.Int64("log-pos", 0, "Binary log position in -log-file (repoint)")
+	since := fs.Duration("since", 24*time.Hour, "How far back repoint looks for the switchover event (at most 14 days)")
+
+	// Flags may come before or after the action
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("an action is required")
+	}
+	action := fs.Arg(0)
+	fs.Parse(fs.Args()[1:])
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments after %s: %s", action, strings.Join(fs.Args(), " "))
+	}
+	if action != "setup" && action != "status" && action != "repoint" {
+		fs.Usage()
+		return fmt.Errorf("unknown action %q", action)
+	}
+	if (*logFile == "") != (*logPos == 0) {
+		return fmt.Errorf("-log-file and -log-pos go together")
+	}
+
+	replicaEndpoint, sourceEndpoint, databaseName, region := *endpoint, *source, *database, lab.region
+	if replicaEndpoint == "" || sourceEndpoint == "" || databaseName == "" || region == "" {
+		aurora, err := lab.reader().Outputs(ctx, "aurora")
+		if err != nil {
+			return err
+		}
+		if replicaEndpoint == "" {
+			if replicaEndpoint = aurora.String("externalReplicaEndpoint"); replicaEndpoint == "" {
+				return fmt.Errorf("the aurora stack has no externalReplicaEndpoint output; set externalReplica in the aurora stack or pass -replica")
+			}
+		}
+		if sourceEndpoint == "" {
+			if sourceEndpoint = aurora.String("clusterEndpoint"); sourceEndpoint == "" {
+				return fmt.Errorf("the aurora stack has no clusterEndpoint output; pass -source")
+			}
+		}
+		if databaseName == "" {
+			if databaseName = aurora.String("databaseName"); databaseName == "" {
+				return fmt.Errorf("the aurora stack has no databaseName output; pass -database")
+			}
+		}
+		if region == "" {
+			region = aurora.String("region")
+		}
+	}
+
+	host, err := hf.connect(ctx, lab)
+	if err != nil {
+		return err
+	}
+	replica := remote.NewReplica(host, replicaEndpoint, sourceEndpoint, databaseName)
+
+	switch action {
+	case "setup":
+		fmt.Printf("[INFO] Setting up replication from %s to %s\n", sourceEndpoint, replicaEndpoint)
+		if err := replica.Setup(ctx, os.Stdout); err != nil {
+			return err
+		}
+		fmt.Println("[SUCCESS] The external replica is replicating; follow it with bgctl replica status")
+		return nil
+
+	case "repoint":
+		file, position := *logFile, *logPos
+		if file == "" {
+			cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+			if err != nil {
+				return fmt.Errorf("loading AWS configuration: %w", err)
+			}
+			p, err := bluegreen.New(cfg).SwitchoverBinlogPosition(ctx, time.Now().Add(-*since))
+			if err != nil {
+				return err
+			}
+			if p == nil {
+				return fmt.Errorf("no switchover reported binary log coordinates in the last %s; pass -since, or -log-file and -log-pos", *since)
+			}
+			fmt.Printf("[INFO] %s reported %s:%d after the switchover at %s\n", p.Source, p.File, p.Position, p.Time.UTC().Format(time.RFC3339))
+			file, position = p.File, p.Position
+		}
+		if err := replica.Repoint(ctx, file, position, os.Stdout); err != nil {
+			return err
+		}
+		fmt.Printf("[SUCCESS] The external replica resumed at %s:%d\n", file, position)
+		return nil
+	}
+
+	status, err := replica.Status(ctx)
+	if err != nil {
+		return err
+	}
+	if status == nil {
+		fmt.Printf("[WARNING] Replication is not set up on %s; run bgctl replica setup\n", replicaEndpoint)
+		return nil
+	}
+	printReplicaStatus(status)
+	return nil
+}
+
+// replicaStatusColumns are the SHOW SLAVE STATUS columns bgctl replica status
+// prints.
+var replicaStatusColumns = []string{
+	"Master_Host", "Slave_IO_Running", "Slave_SQL_Running",
+	"Master_Log_File", "Read_Master_Log_Pos", "Relay_Master_Log_File", "Exec_Master_Log_Pos",
+	"Seconds_Behind_Master", "Last_IO_Error", "Last_SQL_Error",
+}
+
+// printReplicaStatus prints the replication status and what it means.
+func printReplicaStatus(status map[string]string) {
+	for _, column := range replicaStatusColumns {
+		if value := status[column]; value != "" {
+			fmt.Printf("  %-22s %s\n", column+":", value)
+		}
+	}
+	healthy, detail := replicaHealth(status)
+	if healthy {
+		fmt.Printf("[SUCCESS] %s\n", detail)
+	} else {
+		fmt.Printf("[WARNING] %s\n", detail)
+	}
+}
+
+// replicaHealth tells whether both replication threads run, and otherwise
+// why not.
+func replicaHealth(status map[string]string) (bool, string) {
+	ioThread, sqlThread := status["Slave_IO_Running"], status["Slave_SQL_Running"]
+	switch {
+	case ioThread == "Yes" && sqlThread == "Yes":
+		return true, fmt.Sprintf("Replicating, %s seconds behind the source", status["Seconds_Behind_Master"])
+	case strings.Contains(status["Last_IO_Error"], "1236"):
+		// The source has no such binary log file or position, as after a
+		// switchover to a cluster with its own binary logs
+		return false, "The source does not have the replica's binary log position; after a switchover, run bgctl replica repoint"
+	case ioThread == "Connecting":
+		return false, "The replica cannot connect to the source: " + dash(status["Last_IO_Error"])
+	case status["Last_SQL_Error"] != "":
+		return false, "Applying an event failed: " + status["Last_SQL_Error"]
+	case status["Last_IO_Error"] != "":
+		return false, "Reading the binary log failed: " + status["Last_IO_Error"]
+	}
+	return false, fmt.Sprintf("Replication is stopped (I/O thread %s, SQL thread %s); run bgctl replica repoint or setup", dash(ioThread), dash(sqlThread))
+}
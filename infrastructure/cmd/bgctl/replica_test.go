@@ -0,0 +1,42 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReplicaHealth(t *testing.T) {
+	for _, tt := range []struct {
+		status  map[string]string
+		healthy bool
+		detail  string
+	}{
+		{
+			status:  map[string]string{"Slave_IO_Running": "Yes", "Slave_SQL_Running": "Yes", "Seconds_Behind_Master": "0"},
+			healthy: true,
+			detail:  "0 seconds behind",
+		},
+		{
+			status: map[string]string{"Slave_IO_Running": "No", "Slave_SQL_Running": "Yes",
+				"Last_IO_Error": "Got fatal error 1236 from source when reading data from binary log: 'Could not find first log file name in binary log index file'"},
+			detail: "bgctl replica repoint",
+		},
+		{
+			status: map[string]string{"Slave_IO_Running": "Connecting", "Slave_SQL_Running": "Yes", "Last_IO_Error": "error connecting to master"},
+			detail: "cannot connect",
+		},
+		{
+			status: map[string]string{"Slave_IO_Running": "Yes", "Slave_SQL_Running": "No", "Last_SQL_Error": "Error 'Table 'labdb.test_0001' doesn't exist'"},
+			detail: "Applying an event failed",
+		},
+		{
+			status: map[string]string{"Slave_IO_Running": "No", "Slave_SQL_Running": "No"},
+			detail: "Replication is stopped",
+		},
+	} {
+		healthy, detail := replicaHealth(tt.status)
+		if healthy != tt.healthy || !strings.Contains(detail, tt.detail) {
+			t.Errorf("%v: got %v %q, want %v and %q", tt.status, healthy, detail, tt.healthy, tt.detail)
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+
+	"aurora-bluegreen-lab/internal/bluegreen"
+	"aurora-bluegreen-lab/internal/experiments"
+	"aurora-bluegreen-lab/internal/runid"
+)
+
+// Rollback modes.
+const (
+	rollbackReverse = "reverse"
+	rollbackRestore = "restore"
+)
+
+const rollbackUsage = `Usage: bgctl rollback [flags]
+
+Rolls the lab cluster back after a switchover, to the old blue environment
+the switchover left (<clusterIdentifier>-old1). Two ways back:
+
+  reverse  create a reverse Blue/Green deployment of the lab cluster whose
+           green environment has the old blue cluster's engine version,
+           parameter groups and writer instance class, and wait until it is
+           AVAILABLE; then switch over to it with bgctl switchover. The
+           writes made since the switchover are kept, but Blue/Green
+           deployments cannot downgrade, so this does not undo a major
+           version upgrade.
+  restore  point the application back at the old blue cluster itself: the
+           lab cluster and its instances are renamed with a -rolledback1
+           suffix and the old blue cluster and its instances take their
+           identifiers, and so their endpoints, again. This also undoes a
+           version upgrade, but the old blue cluster stopped replicating at
+           the switchover: writes made since then are only in the
+           -rolledback1 cluster.
+
+Before anything changes, the safety checks below run. When the old blue
+cluster no longer exists there is nothing to roll back to and bgctl stops;
+the other checks can be overridden with -force.
+
+  old blue         the old blue cluster and its instances are available and have
+                   no pending modifications
+  lab cluster      the lab cluster and its instances are available and have no
+                   pending modifications
+  deployments      no Blue/Green deployment of the lab cluster is in progress
+
+Rollbacks are registered as rollback-<time> runs in the experiment registry
+of the monitoring stack, if it has one.
+
+  bgctl rollback                       create a reverse deployment to the old blue settings
+  bgctl rollback -mode restore         rename the old blue cluster back into service
+  bgctl rollback -mode restore -force  even when a safety check fails
+
+Flags:
+`
+
+func rollbackCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("rollback", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), rollbackUsage)
+		fs.PrintDefaults()
+	}
+	var lab labFlags
+	lab.register(fs)
+	cluster := fs.String("cluster", "", "Lab cluster to roll back (default: the aurora stack's clusterIdentifier output)")
+	mode := fs.String("mode", rollbackReverse, "How to roll back: reverse (a reverse Blue/Green deployment) or restore (rename the old blue cluster back)")
+	force := fs.Bool("force", false, "Roll back even when a safety check other than the old blue cluster's existence fails")
+	name := fs.String("name", "", "Name of the reverse deployment (default: lab-rollback-<time>)")
+	noWait := fs.Bool("no-wait", false, "Do not wait until the reverse deployment's green environment is AVAILABLE")
+	registryTable := fs.String("registry-table", "", "Experiment registry table to register the rollback in (default: the monitoring stack's experimentTableName output)")
+	var rf runFlag
+	rf.register(fs)
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if *mode != rollbackReverse && *mode != rollbackRestore {
+		return fmt.Errorf("-mode must be %s or %s, got %q", rollbackReverse, rollbackRestore, *mode)
+	}
+
+	clusterIdentifier, region := *cluster, lab.region
+	if clusterIdentifier == "" || region == "" {
+		aurora, err := lab.reader().Outputs(ctx, "aurora")
+		if err != nil {
+			return err
+		}
+		if clusterIdentifier == "" {
+			if clusterIdentifier = aurora.String("clusterIdentifier"); clusterIdentifier == "" {
+				return fmt.Errorf("the aurora stack has no clusterIdentifier output; pass -cluster")
+			}
+		}
+		if region == "" {
+			region = aurora.String("region")
+		}
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	if err != nil {
+		return fmt.Errorf("loading AWS configuration: %w", err)
+	}
+	client := bluegreen.New(cfg)
+
+	oldBlue := bluegreen.OldBlueClusterIdentifier(clusterIdentifier)
+	status, err := client.LabStatus(ctx, clusterIdentifier)
+	if err != nil {
+		return err
+	}
+	if _, err := clusterStatus(status, oldBlue); err != nil {
+		return fmt.Errorf("the old blue cluster %s does not exist (deleted, or %s was never switched over); there is nothing to roll back to", oldBlue, clusterIdentifier)
+	}
+	fmt.Printf("[INFO] Safety checks for rolling %s back to %s:\n", clusterIdentifier, oldBlue)
+	var failed []string
+	for _, g := range rollbackGates(status, clusterIdentifier) {
+		result := "PASS"
+		if !g.passed {
+			result = "FAIL"
+			failed = append(failed, g.name+": "+g.detail)
+		}
+		fmt.Printf("  [%s] %-15s %s\n", result, g.name, g.detail)
+	}
+	if len(failed) > 0 {
+		if !*force {
+			return fmt.Errorf("rollback safety checks failed (%s); pass -force to roll back anyway", strings.Join(failed, "; "))
+		}
+		fmt.Println("[WARNING] Rolling back despite failed safety checks (-force)")
+	}
+
+	started := time.Now().UTC()
+	runID, source, err := rf.identify("rollback", started)
+	if err != nil {
+		return err
+	}
+	registry := openRegistry(ctx, lab, cfg, *registryTable)
+	run := &experiments.Run{
+		RunID:     runID,
+		Source:    source,
+		Name:      "rollback",
+		Status:    experiments.StatusRunning,
+		StartedAt: started,
+		Config: map[string]string{
+			"cluster": clusterIdentifier,
+			"oldBlue": oldBlue,
+			"mode":    *mode,
+			"force":   strconv.FormatBool(*force),
+		},
+	}
+	registerRun(ctx, registry, run)
+
+	if *mode == rollbackRestore {
+		fmt.Printf("[WARNING] Writes made since the switchover are not in %s; they stay in %s\n",
+			oldBlue, bluegreen.RolledBackClusterIdentifier(clusterIdentifier))
+		fmt.Printf("[INFO] Restoring %s as %s; the application has no writer until the renames complete\n", oldBlue, clusterIdentifier)
+		err = client.Restore(ctx, clusterIdentifier, func(step string) {
+			fmt.Printf("[INFO] %s %s\n", time.Now().UTC().Format(time.RFC3339), step)
+		})
+		run.Finish(time.Now().UTC(), err)
+		if err == nil {
+			run.Results = map[string]float64{"restoreSeconds": run.Duration(time.Now()).Seconds()}
+		}
+		registerRun(context.WithoutCancel(ctx), registry, run)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("[SUCCESS] %s serves the application again; the rolled back cluster is %s\n",
+			clusterIdentifier, bluegreen.RolledBackClusterIdentifier(clusterIdentifier))
+		return nil
+	}
+
+	d, err := createReverse(ctx, client, clusterIdentifier, oldBlue, *name, *noWait, run)
+	run.Finish(time.Now().UTC(), err)
+	if err == nil && !*noWait {
+		run.Results = map[string]float64{"provisionSeconds": run.Duration(time.Now()).Seconds()}
+	}
+	registerRun(context.WithoutCancel(ctx), registry, run)
+	if err != nil {
+		if ctx.Err() != nil && d != nil {
+			fmt.Printf("[INFO] RDS keeps creating deployment %s; follow it with bgctl watch\n", d.ID)
+		}
+		return err
+	}
+	if *noWait {
+		fmt.Printf("[SUCCESS] Reverse deployment %s is being created; follow it with bgctl watch\n", d.ID)
+		return nil
+	}
+	fmt.Printf("[SUCCESS] Reverse green cluster %s is available; roll back with bgctl switchover -auto -deployment %s\n",
+		d.TargetClusterIdentifier(), d.ID)
+	return nil
+}
+
+// createReverse creates a deployment of the lab cluster whose green
+// environment has the old blue cluster's settings, and waits until it is
+// AVAILABLE unless noWait is set. The deployment is returned once created,
+// also with an error.
+func createReverse(ctx context.Context, client *bluegreen.Client, clusterIdentifier, oldBlue, name string, noWait bool, run *experiments.Run) (*bluegreen.Deployment, error) {
+	current, err := client.ClusterSettings(ctx, clusterIdentifier)
+	if err != nil {
+		return nil, err
+	}
+	old, err := client.ClusterSettings(ctx, oldBlue)
+	if err != nil {
+		return nil, err
+	}
+	target := greenTarget{
+		blueVersion:            current.EngineVersion,
+		clusterParameterGroup:  old.ClusterParameterGroup,
+		instanceParameterGroup: old.InstanceParameterGroup,
+	}
+	if old.EngineVersion != current.EngineVersion {
+		target.engineVersion = old.EngineVersion
+	}
+	if _, err := target.check(); err != nil {
+		return nil, fmt.Errorf("%w; roll back with -mode restore instead", err)
+	}
+
+	if name == "" {
+		name = "lab-rollback-" + run.StartedAt.Format("20060102-150405")
+	}
+	run.Config["deployment"] = name
+	run.Config["targetEngineVersion"] = old.EngineVersion
+	run.Config["targetClusterParameterGroup"] = old.ClusterParameterGroup
+	run.Config["targetInstanceParameterGroup"] = old.InstanceParameterGroup
+	run.Config["targetInstanceClass"] = old.InstanceClass
+	fmt.Printf("[INFO] Creating reverse Blue/Green deployment %s: %s -> %s, parameter groups %s and %s, %s\n",
+		name, current.EngineVersion, old.EngineVersion, dash(old.ClusterParameterGroup), dash(old.InstanceParameterGroup), dash(old.InstanceClass))
+	d, err := client.Create(ctx, bluegreen.CreateOptions{
+		Name:                         name,
+		SourceArn:                    current.Arn,
+		TargetEngineVersion:          target.engineVersion,
+		TargetClusterParameterGroup:  old.ClusterParameterGroup,
+		TargetInstanceParameterGroup: old.InstanceParameterGroup,
+		TargetInstanceClass:          old.InstanceClass,
+		Tags:                         map[string]string{runid.Tag: run.RunID},
+	})
+	if err != nil {
+		return nil, err
+	}
+	run.Config["deploymentId"] = d.ID
+	fmt.Printf("[INFO] Deployment %s created\n", d.ID)
+	if noWait {
+		return d, nil
+	}
+	fmt.Println("[INFO] Waiting for the reverse green environment; this takes 20-60 minutes")
+	available, err := client.WaitAvailable(ctx, d.ID, func(d *bluegreen.Deployment) {
+		fmt.Printf("[INFO] %s deployment %s: %s\n", time.Now().UTC().Format(time.RFC3339), d.ID, d.Status)
+	})
+	if available != nil {
+		d = available
+	}
+	return d, err
+}
+
+// rollbackGates are the safety checks of a rollback that -force overrides.
+func rollbackGates(status *bluegreen.LabStatus, clusterIdentifier string) []gate {
+	gates := []gate{
+		clusterGate("old blue", status, bluegreen.OldBlueClusterIdentifier(clusterIdentifier)),
+		clusterGate("lab cluster", status, clusterIdentifier),
+	}
+
+	g := gate{name: "deployments", passed: true, detail: "no deployment in progress"}
+	var active []string
+	for _, d := range status.Deployments {
+		if d.Status != bluegreen.StatusSwitchoverCompleted {
+			active = append(active, fmt.Sprintf("%s is %s", d.ID, d.Status))
+		}
+	}
+	if len(active) > 0 {
+		g.passed = false
+		g.detail = strings.Join(active, ", ") + "; finish or delete it first"
+	}
+	return append(gates, g)
+}
+
+// clusterGate passes when the cluster and its instances are available and
+// have no pending modifications.
+func clusterGate(name string, status *bluegreen.LabStatus, identifier string) gate {
+	g := gate{name: name}
+	cluster, err := clusterStatus(status, identifier)
+	if err != nil {
+		g.detail = err.Error()
+		return g
+	}
+	problems := pendingProblems("cluster "+cluster.Identifier, cluster.Status, cluster.Pending)
+	for _, instance := range cluster.Instances {
+		problems = append(problems, pendingProblems("instance "+instance.Identifier, instance.Status, instance.Pending)...)
+	}
+	if len(problems) > 0 {
+		g.detail = strings.Join(problems, ", ")
+		return g
+	}
+	g.passed = true
+	g.detail = fmt.Sprintf("%s available, %s, %d instances", cluster.Identifier, cluster.EngineVersion, len(cluster.Instances))
+	return g
+}
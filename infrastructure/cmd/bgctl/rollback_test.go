@@ -0,0 +1,53 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"aurora-bluegreen-lab/internal/bluegreen"
+)
+
+func rollbackTestStatus() *bluegreen.LabStatus {
+	return &bluegreen.LabStatus{
+		Deployments: []bluegreen.DeploymentStatus{{Deployment: bluegreen.Deployment{
+			ID:        "bgd-abc",
+			Status:    bluegreen.StatusSwitchoverCompleted,
+			SourceArn: "arn:aws:rds:us-east-1:123456789012:cluster:lab-old1",
+			TargetArn: "arn:aws:rds:us-east-1:123456789012:cluster:lab",
+		}}},
+		Clusters: []bluegreen.ClusterStatus{
+			{Identifier: "lab", Status: "available", EngineVersion: "8.0.mysql_aurora.3.08.0",
+				Instances: []bluegreen.InstanceStatus{{Identifier: "lab-writer", Status: "available", Writer: true}}},
+			{Identifier: "lab-old1", Status: "available", EngineVersion: "5.7.mysql_aurora.2.12.1",
+				Instances: []bluegreen.InstanceStatus{{Identifier: "lab-writer-old1", Status: "available", Writer: true}}},
+		},
+	}
+}
+
+func TestRollbackGates(t *testing.T) {
+	if failed := failedGates(rollbackGates(rollbackTestStatus(), "lab")); failed != nil {
+		t.Errorf("after a switchover: failed %v", failed)
+	}
+
+	status := rollbackTestStatus()
+	status.Clusters[1].Instances[0].Status = "stopped"
+	status.Deployments = append(status.Deployments, bluegreen.DeploymentStatus{Deployment: bluegreen.Deployment{
+		ID:        "bgd-def",
+		Status:    bluegreen.StatusProvisioning,
+		SourceArn: "arn:aws:rds:us-east-1:123456789012:cluster:lab",
+	}})
+	gates := rollbackGates(status, "lab")
+	if failed := failedGates(gates); !reflect.DeepEqual(failed, []string{"old blue", "deployments"}) {
+		t.Errorf("stopped old blue writer and a deployment in progress: failed %v", failed)
+	}
+	if detail := gates[2].detail; !strings.Contains(detail, "bgd-def is PROVISIONING") {
+		t.Errorf("deployments detail %q", detail)
+	}
+
+	status = rollbackTestStatus()
+	status.Clusters = status.Clusters[:1]
+	if failed := failedGates(rollbackGates(status, "lab")); !reflect.DeepEqual(failed, []string{"old blue"}) {
+		t.Errorf("without the old blue cluster: failed %v", failed)
+	}
+}
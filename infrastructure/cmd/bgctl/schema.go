@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+
+	"aurora-bluegreen-lab/internal/bluegreen"
+	"aurora-bluegreen-lab/internal/experiments"
+	"aurora-bluegreen-lab/internal/remote"
+	"aurora-bluegreen-lab/internal/schemachange"
+)
+
+const schemaChangeUsage = `Usage: bgctl schema-change -file <sql> [flags]
+
+Runs DDL on the green environment of the lab cluster's Blue/Green deployment
+before the switchover, through the green cluster's writer endpoint, from the
+simulator host with the simulator's database credentials.
+
+The green cluster replicates the blue cluster's writes until the switchover,
+so only changes replication survives are accepted: CREATE TABLE, CREATE
+INDEX, DROP INDEX and ALTER TABLE adding columns at the end of a table or
+adding and dropping indexes. Statements that write data, drop or rename
+tables or columns, change column types or positions, or add unique, primary
+key or foreign key constraints are rejected before anything runs.
+
+The green cluster is read-only unless its DB cluster parameter group sets
+read_only to 0 (greenParameters of the aurora stack). Statements run in
+order and stop at the first failure. Schema changes are registered as
+schema-change-<time> runs in the experiment registry of the monitoring
+stack, if it has one.
+
+  bgctl schema-change -file add-note-column.sql
+  bgctl schema-change -file add-note-column.sql -check     only check the statements
+  echo 'CREATE INDEX idx_note ON orders (note)' | bgctl schema-change -file -
+
+Flags:
+`
+
+func schemaChangeCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("schema-change", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), schemaChangeUsage)
+		fs.PrintDefaults()
+	}
+	var lab labFlags
+	var hf hostFlags
+	lab.register(fs)
+	hf.register(fs)
+	file := fs.String("file", "", "SQL file with the DDL statements, - for standard input (required)")
+	check := fs.Bool("check", false, "Only check the statements, without connecting to the green cluster")
+	cluster := fs.String("cluster", "", "Blue cluster of the deployment (default: the aurora stack's clusterIdentifier output)")
+	deploymentID := fs.String("deployment", "", "Blue/Green deployment ID (default: the cluster's only deployment that is not switched over)")
+	database := fs.String("database", "", "Database the statements run in (default: the aurora stack's databaseName output)")
+	registryTable := fs.String("registry-table", "", "Experiment registry table to register the schema change in (default: the monitoring stack's experimentTableName output)")
+	var rf runFlag
+	rf.register(fs)
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if *file == "" {
+		fs.Usage()
+		return fmt.Errorf("-file is required")
+	}
+
+	script, err := readSQL(*file)
+	if err != nil {
+		return err
+	}
+	statements, err := schemachange.Validate(script)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("[SUCCESS] %d statements are safe to run on the green environment\n", len(statements))
+	if *check {
+		return nil
+	}
+
+	clusterIdentifier, region, databaseName := *cluster, lab.region, *database
+	if clusterIdentifier == "" || region == "" || databaseName == "" {
+		aurora, err := lab.reader().Outputs(ctx, "aurora")
+		if err != nil {
+			return err
+		}
+		if clusterIdentifier == "" {
+			if clusterIdentifier = aurora.String("clusterIdentifier"); clusterIdentifier == "" {
+				return fmt.Errorf("the aurora stack has no clusterIdentifier output; pass -cluster")
+			}
+		}
+		if region == "" {
+			region = aurora.String("region")
+		}
+		if databaseName == "" {
+			if databaseName = aurora.String("databaseName"); databaseName == "" {
+				return fmt.Errorf("the aurora stack has no databaseName output; pass -database")
+			}
+		}
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	if err != nil {
+		return fmt.Errorf("loading AWS configuration: %w", err)
+	}
+	client := bluegreen.New(cfg)
+
+	status, err := client.LabStatus(ctx, clusterIdentifier)
+	if err != nil {
+		return err
+	}
+	d, err := switchoverDeployment(status, clusterIdentifier, *deploymentID)
+	if err != nil {
+		return err
+	}
+	endpoint, err := greenEndpoint(status, d)
+	if err != nil {
+		return err
+	}
+	host, err := hf.connect(ctx, lab)
+	if err != nil {
+		return err
+	}
+
+	started := time.Now().UTC()
+	runID, source, err := rf.identify("schema-change", started)
+	if err != nil {
+		return err
+	}
+	registry := openRegistry(ctx, lab, cfg, *registryTable)
+	run := &experiments.Run{
+		RunID:     runID,
+		Source:    source,
+		Name:      "schema-change",
+		Status:    experiments.StatusRunning,
+		StartedAt: started,
+		Config: map[string]string{
+			"cluster":    clusterIdentifier,
+			"deployment": d.ID,
+			"green":      d.TargetClusterIdentifier(),
+			"database":   databaseName,
+			"file":       *file,
+			"statements": strconv.Itoa(len(statements)),
+		},
+	}
+	registerRun(ctx, registry, run)
+
+	fmt.Printf("[INFO] Running %d statements in %s on %s (%s)\n", len(statements), databaseName, d.TargetClusterIdentifier(), endpoint)
+	err = remote.NewMySQL(host, endpoint, databaseName).Apply(ctx, statements, os.Stdout)
+	run.Finish(time.Now().UTC(), err)
+	registerRun(context.WithoutCancel(ctx), registry, run)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("[SUCCESS] Schema change applied to %s; switch over with bgctl switchover\n", d.TargetClusterIdentifier())
+	return nil
+}
+
+// readSQL reads the SQL file, or standard input for "-".
+func readSQL(path string) (string, error) {
+	if path == "-" {
+		b, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("reading standard input: %w", err)
+		}
+		return string(b), nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading %s: %w", path, err)
+	}
+	return string(b), nil
+}
+
+// greenEndpoint returns the writer endpoint of the deployment's green
+// cluster, which must be ready for the schema change.
+func greenEndpoint(status *bluegreen.LabStatus, d *bluegreen.Deployment) (string, error) {
+	if d.Status != bluegreen.StatusAvailable {
+		return "", fmt.Errorf("Blue/Green deployment %s is %s; schema changes run on a green environment that is AVAILABLE", d.ID, d.Status)
+	}
+	green, err := clusterStatus(status, d.TargetClusterIdentifier())
+	if err != nil {
+		return "", fmt.Errorf("green %w", err)
+	}
+	if green.Endpoint == "" {
+		return "", fmt.Errorf("green cluster %s has no endpoint yet", green.Identifier)
+	}
+	return green.Endpoint, nil
+}
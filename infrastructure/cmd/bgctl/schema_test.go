@@ -0,0 +1,34 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"aurora-bluegreen-lab/internal/bluegreen"
+)
+
+func TestGreenEndpoint(t *testing.T) {
+	d := &bluegreen.Deployment{
+		ID:        "bgd-abc",
+		Status:    bluegreen.StatusAvailable,
+		SourceArn: "arn:aws:rds:us-east-1:123456789012:cluster:lab",
+		TargetArn: "arn:aws:rds:us-east-1:123456789012:cluster:lab-green-x1y2z3",
+	}
+	status := &bluegreen.LabStatus{Clusters: []bluegreen.ClusterStatus{
+		{Identifier: "lab", Endpoint: "lab.cluster-abc.us-east-1.rds.amazonaws.com"},
+		{Identifier: "lab-green-x1y2z3", Endpoint: "lab-green-x1y2z3.cluster-abc.us-east-1.rds.amazonaws.com"},
+	}}
+	if got, err := greenEndpoint(status, d); err != nil || got != "lab-green-x1y2z3.cluster-abc.us-east-1.rds.amazonaws.com" {
+		t.Errorf("got %q, %v", got, err)
+	}
+
+	d.Status = bluegreen.StatusProvisioning
+	if _, err := greenEndpoint(status, d); err == nil || !strings.Contains(err.Error(), "AVAILABLE") {
+		t.Errorf("provisioning deployment: got %v", err)
+	}
+	d.Status = bluegreen.StatusAvailable
+	status.Clusters = status.Clusters[:1]
+	if _, err := greenEndpoint(status, d); err == nil {
+		t.Error("no error without the green cluster")
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+
+	"aurora-bluegreen-lab/internal/remote"
+	"aurora-bluegreen-lab/internal/stacks"
+)
+
+// hostFlags are the flags selecting how the simulator host is reached.
+type hostFlags struct {
+	transport  string
+	instanceID string
+	sshHost    string
+	sshUser    string
+	sshKey     string
+}
+
+func (f *hostFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.transport, "transport", "ssm", "How to reach the simulator host: ssm (SSM Run Command) or ssh")
+	fs.StringVar(&f.instanceID, "instance-id", "", "Simulator instance ID (default: the ec2 stack's instanceId output; required for Auto Scaling Groups)")
+	fs.StringVar(&f.sshHost, "ssh-host", "", "Simulator host for -transport ssh (default: the ec2 stack's publicDns output)")
+	fs.StringVar(&f.sshUser, "ssh-user", "ec2-user", "SSH user for -transport ssh")
+	fs.StringVar(&f.sshKey, "ssh-key", "", "SSH private key file for -transport ssh (default: SSH agent/config)")
+}
+
+// connect returns the simulator host, reading the ec2 stack outputs only for
+// what the flags leave open.
+func (f *hostFlags) connect(ctx context.Context, lab labFlags) (remote.Host, error) {
+	if f.transport != "ssm" && f.transport != "ssh" {
+		return nil, fmt.Errorf("-transport must be ssm or ssh, got %q", f.transport)
+	}
+	var ec2 stacks.Outputs
+	if (f.transport == "ssm" && (f.instanceID == "" || lab.region == "")) || (f.transport == "ssh" && f.sshHost == "") {
+		reader := lab.reader()
+		var err error
+		if ec2, err = reader.Outputs(ctx, "ec2"); err != nil {
+			return nil, err
+		}
+	}
+
+	if f.transport == "ssh" {
+		host := f.sshHost
+		if host == "" {
+			host = ec2.String("publicDns")
+		}
+		if host == "" {
+			return nil, fmt.Errorf("the ec2 stack has no publicDns output; pass -ssh-host")
+		}
+		return remote.NewSSH(host, f.sshUser, f.sshKey), nil
+	}
+
+	instanceID, region := f.instanceID, lab.region
+	if instanceID == "" {
+		instanceID = ec2.String("instanceId")
+	}
+	if region == "" {
+		region = ec2.String("region")
+	}
+	if instanceID == "" {
+		return nil, fmt.Errorf("the ec2 stack has no instanceId output (Auto Scaling Group); pass -instance-id")
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	if err != nil {
+		return nil, fmt.Errorf("loading AWS configuration: %w", err)
+	}
+	return remote.NewSSM(cfg, instanceID), nil
+}
+
+const simulatorUsage = `Usage: bgctl simulator [flags] <action>
+
+Actions:
+  start     Start the workload-simulator service
+  stop      Stop the service
+  restart   Restart the service (e.g. after editing /etc/workload-simulator/simulator.env)
+  status    Show the service status and its latest log lines
+  logs      Print the service log (-n lines, -f to follow); -run prints a lab-scenario run's log
+
+Control API actions, on the simulator running on the host (the service or a
+lab-scenario run), through its HTTP control API. The API is opt-in: the
+simulator must run with --control-port, which lab-scenario runs and the ec2
+stack's default simulatorOptions pass; -control-port names the same port.
+  stats     Show the current counters, rates and state
+  pause     Stop sending operations; the run goes on
+  resume    Send operations again
+  rate      Change the write rate to -rate (per worker, or of all workers with --total-rate)
+  mark      Mark the event -event (with -detail) in the simulator log, with its counters at that moment
+
+Flags:
+`
+
+func simulatorCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("simulator", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), simulatorUsage)
+		fs.PrintDefaults()
+	}
+	var lab labFlags
+	var hf hostFlags
+	lab.register(fs)
+	hf.register(fs)
+	lines := fs.Int("n", 100, "Number of log lines to print (logs)")
+	follow := fs.Bool("f", false, "Keep printing new log lines until interrupted (logs)")
+	runID := fs.String("run", "", "Print the log of this lab-scenario run instead of the service log (logs)")
+	controlPort := fs.Int("control-port", remote.ControlPort, "Port the simulator was started with --control-port on (control API actions)")
+	rate := fs.Int("rate", 0, "New write rate (rate)")
+	event := fs.String("event", "", "Event to mark, e.g. switchover-started (mark)")
+	detail := fs.String("detail", "", "Detail of the marked event (mark)")
+
+	// Flags may come before or after the action
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("an action is required")
+	}
+	action := fs.Arg(0)
+	fs.Parse(fs.Args()[1:])
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments after %s: %s", action, strings.Join(fs.Args(), " "))
+	}
+
+	var service func(remote.Host) error
+	switch action {
+	case "start":
+		service = func(h remote.Host) error { return remote.NewService(h).Start(ctx, os.Stdout) }
+	case "stop":
+		service = func(h remote.Host) error { return remote.NewService(h).Stop(ctx, os.Stdout) }
+	case "restart":
+		service = func(h remote.Host) error { return remote.NewService(h).Restart(ctx, os.Stdout) }
+	case "status":
+		service = func(h remote.Host) error { return remote.NewService(h).Status(ctx, os.Stdout) }
+	case "logs":
+		service = func(h remote.Host) error {
+			if *runID != "" {
+				return remote.NewSimulatorRun(h, *runID).Logs(ctx, *lines, *follow, os.Stdout)
+			}
+			return remote.NewService(h).Logs(ctx, *lines, *follow, os.Stdout)
+		}
+	case "stats":
+		service = func(h remote.Host) error {
+			stats, err := remote.NewControl(h, *controlPort).Stats(ctx)
+			if err != nil {
+				return err
+			}
+			printSimulatorStats(stats)
+			return nil
+		}
+	case "pause":
+		service = func(h remote.Host) error {
+			if err := remote.NewControl(h, *controlPort).Pause(ctx); err != nil {
+				return err
+			}
+			fmt.Println("[SUCCESS] The workload is paused")
+			return nil
+		}
+	case "resume":
+		service = func(h remote.Host) error {
+			if err := remote.NewControl(h, *controlPort).Resume(ctx); err != nil {
+				return err
+			}
+			fmt.Println("[SUCCESS] The workload is resumed")
+			return nil
+		}
+	case "rate":
+		if *rate < 1 {
+			return fmt.Errorf("rate requires -rate of at least 1")
+		}
+		service = func(h remote.Host) error {
+			if err := remote.NewControl(h, *controlPort).SetRate(ctx, *rate); err != nil {
+				return err
+			}
+			fmt.Printf("[SUCCESS] The write rate is %d\n", *rate)
+			return nil
+		}
+	case "mark":
+		if *event == "" {
+			return fmt.Errorf("mark requires -event")
+		}
+		service = func(h remote.Host) error {
+			if err := remote.NewControl(h, *controlPort).MarkEvent(ctx, *event, *detail, time.Now()); err != nil {
+				return err
+			}
+			fmt.Printf("[SUCCESS] Marked %s\n", *event)
+			return nil
+		}
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown action %q", action)
+	}
+
+	host, err := hf.connect(ctx, lab)
+	if err != nil {
+		return err
+	}
+	err = service(host)
+	if *follow && ctx.Err() != nil {
+		// Interrupting -f is the normal way to stop following
+		return nil
+	}
+	return err
+}
+
+func printSimulatorStats(stats *remote.SimulatorStats) {
+	fmt.Printf("[INFO] Run %s: %s at %s\n", stats.RunID, stats.State, stats.Timestamp)
+	fmt.Printf("[INFO] Requests: %d total, %d success, %d failed (%.2f%% success)\n",
+		stats.Requests.Total, stats.Requests.Success, stats.Requests.Failed, stats.Requests.SuccessRate)
+	rate := fmt.Sprintf("%d writes/sec/worker", stats.Rate.WriteRate)
+	if stats.Rate.TotalRate > 0 {
+		rate = fmt.Sprintf("%d writes/sec shared by all workers", stats.Rate.TotalRate)
+	}
+	fmt.Printf("[INFO] Rate: %s; target %.1f ops/sec, achieved %.1f ops/sec\n", rate, stats.Rate.Target, stats.Rate.Achieved)
+	if stats.Profile != "" {
+		fmt.Printf("[INFO] Load profile: %s\n", stats.Profile)
+	}
+	if b := stats.Backpressure; b != nil {
+		fmt.Printf("[INFO] Backpressure: %d in flight, %d queued, %d shed\n", b.InFlight, b.Queued, b.Shed)
+	}
+}
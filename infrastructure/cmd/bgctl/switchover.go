@@ -0,0 +1,902 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+
+	"aurora-bluegreen-lab/internal/bluegreen"
+	"aurora-bluegreen-lab/internal/experiments"
+	"aurora-bluegreen-lab/internal/notify"
+	"aurora-bluegreen-lab/internal/remote"
+	"aurora-bluegreen-lab/internal/report"
+)
+
+const switchoverUsage = `Usage: bgctl switchover [flags]
+
+Switches the lab cluster's Blue/Green deployment over to the green
+environment and waits until the switchover completes. With -auto, the
+gatekeeper first checks these gates every -interval and switches over once
+they all pass in -consecutive-checks checks in a row, or aborts without
+switching over when they do not within -window. A failed check restarts the
+count, and the wait before the next check doubles after each failed check,
+up to -max-interval:
+
+  deployment       the deployment is AVAILABLE
+  replica lag      the green cluster's AuroraBinlogReplicaLag is at most -max-replica-lag
+  error rate       the share of the simulator's writes that failed since the previous
+                   check, from its metrics endpoint (--enable-metrics), is at most
+                   -max-error-rate
+  pending changes  the blue and green clusters and instances are available and have
+                   no pending modifications
+
+With -validate, the green environment checks of bgctl validate-green run
+first (-checks, or the default checks), and a failing check aborts without
+switching over.
+
+With -measure, bgctl polls the deployment every second and follows its RDS
+events while it switches over, then prints the deployment's timeline,
+created -> available -> switchover-in-progress -> completed, with the RDS
+time of each stage (the polled time when RDS has no event for it) and the
+durations between them in milliseconds. -timeline appends the switchover's
+start and completion, with RDS's times under -measure, to a timeline file
+under the run ID (-run-id, $LAB_RUN_ID), so lab-report --timeline puts the
+switchover window next to the simulator's downtime of the same run.
+
+With -webhook or $LAB_WEBHOOK_URL, the gate wait, the switchover's start and
+its completion or failure are posted to a Slack incoming webhook or any
+HTTPS endpoint, so a team running the lab together can follow along.
+
+RDS rolls the switchover back when it does not complete within -timeout.
+When the switchover fails, times out or is rolled back, bgctl leaves the
+deployment as it is, reports why and which environment serves the
+application, and suggests what to do before retrying. Switchovers are
+registered as switchover-<time> runs in the experiment registry of the
+monitoring stack, if it has one.
+
+  bgctl switchover                          switch over now
+  bgctl switchover -timeout 15m             give RDS up to 15 minutes before it rolls back
+  bgctl switchover -auto                    switch over once the gates pass, within 10 minutes
+  bgctl switchover -validate -checks green-checks.yaml -auto
+  bgctl switchover -auto -window 30m -max-replica-lag 2s -max-error-rate 0.5
+  bgctl switchover -auto -consecutive-checks 5 -interval 10s
+  bgctl switchover -auto -metrics-url ""    without the error rate gate (no simulator)
+  bgctl switchover -measure -timeline switchover.jsonl -run-id minor-upgrade-1
+  bgctl switchover -auto -webhook https://hooks.slack.com/services/...
+
+Flags:
+`
+
+func switchoverCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("switchover", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), switchoverUsage)
+		fs.PrintDefaults()
+	}
+	var lab labFlags
+	var hf hostFlags
+	var vf validateFlags
+	lab.register(fs)
+	hf.register(fs)
+	vf.register(fs)
+	cluster := fs.String("cluster", "", "Blue cluster of the deployment (default: the aurora stack's clusterIdentifier output)")
+	deploymentID := fs.String("deployment", "", "Blue/Green deployment ID (default: the cluster's only deployment that is not switched over)")
+	auto := fs.Bool("auto", false, "Switch over once all gates pass, abort when they do not pass within -window")
+	validate := fs.Bool("validate", false, "Run the green environment checks of validate-green first, abort when one fails")
+	window := fs.Duration("window", 10*time.Minute, "How long -auto waits for the gates to pass")
+	interval := fs.Duration("interval", 15*time.Second, "How often -auto checks the gates while they pass")
+	maxInterval := fs.Duration("max-interval", 2*time.Minute, "Longest wait between gate checks when -auto backs off after failed checks")
+	consecutiveChecks := fs.Int("consecutive-checks", 3, "Gate checks in a row that must pass before -auto switches over")
+	maxReplicaLag := fs.Duration("max-replica-lag", time.Second, "Largest green replica lag that passes the replica lag gate")
+	maxErrorRate := fs.Float64("max-error-rate", 1, "Largest share of failed simulator writes in percent that passes the error rate gate")
+	metricsURL := fs.String("metrics-url", "http://localhost:8080/metrics", "Simulator metrics endpoint, read on the simulator host; empty skips the error rate gate")
+	timeout := fs.Duration("timeout", bluegreen.DefaultSwitchoverTimeout, "RDS switchover timeout (30s to 1h); the switchover is rolled back when it takes longer")
+	measure := fs.Bool("measure", false, "Poll the deployment every second, follow its RDS events and print its stage timeline with millisecond durations")
+	timelinePath := fs.String("timeline", "", "Timeline file (JSON Lines) to append the switchover events to, for lab-report --timeline")
+	registryTable := fs.String("registry-table", "", "Experiment registry table to register the switchover in (default: the monitoring stack's experimentTableName output)")
+	var rf runFlag
+	var nf notifyFlags
+	rf.register(fs)
+	nf.register(fs)
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if *interval < time.Second {
+		return fmt.Errorf("-interval must be at least 1s, got %s", *interval)
+	}
+	if *maxInterval < *interval {
+		return fmt.Errorf("-max-interval must be at least -interval (%s), got %s", *interval, *maxInterval)
+	}
+	if *consecutiveChecks < 1 {
+		return fmt.Errorf("-consecutive-checks must be at least 1, got %d", *consecutiveChecks)
+	}
+	if minWindow := time.Duration(*consecutiveChecks-1) * *interval; *window < *interval || *window < minWindow {
+		return fmt.Errorf("-window must leave time for %d checks every -interval (%s), got %s", *consecutiveChecks, *interval, *window)
+	}
+	if *timeout < bluegreen.MinSwitchoverTimeout || *timeout > bluegreen.MaxSwitchoverTimeout {
+		return fmt.Errorf("-timeout must be between 30s and 1h, got %s", *timeout)
+	}
+	var checks *greenChecks
+	if *validate {
+		var err error
+		if checks, err = vf.checks(); err != nil {
+			return err
+		}
+	}
+
+	clusterIdentifier, region := *cluster, lab.region
+	if clusterIdentifier == "" || region == "" {
+		aurora, err := lab.reader().Outputs(ctx, "aurora")
+		if err != nil {
+			return err
+		}
+		if clusterIdentifier == "" {
+			if clusterIdentifier = aurora.String("clusterIdentifier"); clusterIdentifier == "" {
+				return fmt.Errorf("the aurora stack has no clusterIdentifier output; pass -cluster")
+			}
+		}
+		if region == "" {
+			region = aurora.String("region")
+		}
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	if err != nil {
+		return fmt.Errorf("loading AWS configuration: %w", err)
+	}
+	client := bluegreen.New(cfg)
+	if *measure {
+		client.PollInterval = measureInterval
+	}
+
+	status, err := client.LabStatus(ctx, clusterIdentifier)
+	if err != nil {
+		return err
+	}
+	d, err := switchoverDeployment(status, clusterIdentifier, *deploymentID)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("[INFO] Blue/Green deployment %s (%s): %s -> %s, %s\n",
+		d.Name, d.ID, d.SourceClusterIdentifier(), d.TargetClusterIdentifier(), d.Status)
+
+	var v *greenValidator
+	if *validate {
+		if v, err = vf.validator(ctx, lab, hf, status, d, checks); err != nil {
+			return err
+		}
+	}
+
+	var g *gatekeeper
+	if *auto {
+		g = &gatekeeper{
+			client:     client,
+			cw:         cloudwatch.NewFromConfig(cfg),
+			cluster:    clusterIdentifier,
+			deployment: d.ID,
+			metricsURL: *metricsURL,
+			thresholds: gateThresholds{maxReplicaLag: *maxReplicaLag, maxErrorRate: *maxErrorRate},
+			schedule:   gateSchedule{consecutive: *consecutiveChecks, interval: *interval, maxInterval: *maxInterval},
+		}
+		if *metricsURL != "" {
+			if g.host, err = hf.connect(ctx, lab); err != nil {
+				return err
+			}
+		}
+	}
+
+	started := time.Now().UTC()
+	runID, source, err := rf.identify("switchover", started)
+	if err != nil {
+		return err
+	}
+	n, err := nf.open("switchover", runID)
+	if err != nil {
+		return err
+	}
+	var tl *eventTimeline
+	if *timelinePath != "" {
+		if tl, err = openTimeline(*timelinePath, runID); err != nil {
+			return err
+		}
+		defer tl.close()
+	}
+	registry := openRegistry(ctx, lab, cfg, *registryTable)
+	run := &experiments.Run{
+		RunID:     runID,
+		Source:    source,
+		Name:      "switchover",
+		Status:    experiments.StatusRunning,
+		StartedAt: started,
+		Config: map[string]string{
+			"cluster":    clusterIdentifier,
+			"deployment": d.ID,
+			"auto":       strconv.FormatBool(*auto),
+			"timeout":    timeout.String(),
+			"measure":    strconv.FormatBool(*measure),
+		},
+		Timings: map[string]time.Time{},
+		Results: map[string]float64{},
+	}
+	if *auto {
+		run.Config["window"] = window.String()
+		run.Config["interval"] = interval.String()
+		run.Config["maxInterval"] = maxInterval.String()
+		run.Config["consecutiveChecks"] = strconv.Itoa(*consecutiveChecks)
+		run.Config["maxReplicaLag"] = maxReplicaLag.String()
+		run.Config["maxErrorRate"] = strconv.FormatFloat(*maxErrorRate, 'f', -1, 64)
+		run.Config["metricsUrl"] = *metricsURL
+	}
+	if *validate {
+		run.Config["checks"] = vf.describe()
+	}
+	registerRun(ctx, registry, run)
+
+	if v != nil {
+		var gates []gate
+		gates, err = v.validate(ctx)
+		run.Results["failedChecks"] = validationResults(gates)["failedChecks"]
+		if err == nil {
+			run.Timings["validation-passed"] = time.Now().UTC()
+			fmt.Println("[SUCCESS] The green environment passed all checks")
+		} else {
+			err = fmt.Errorf("%w; not switching over", err)
+			n.post(ctx, "validation-failed", fmt.Sprintf("Not switching over %s: %v", d.ID, err))
+		}
+	}
+	if err == nil {
+		var m *bluegreen.Measurement
+		if *measure {
+			m = bluegreen.NewMeasurement(d.Created)
+		}
+		err = switchover(ctx, client, g, d, *window, *timeout, run, tl, n, m)
+	}
+	run.Finish(time.Now().UTC(), err)
+	registerRun(context.WithoutCancel(ctx), registry, run)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("[SUCCESS] Switched over to %s; %s is the old blue cluster\n",
+		d.TargetClusterIdentifier(), bluegreen.OldBlueClusterIdentifier(d.SourceClusterIdentifier()))
+	if tl != nil {
+		fmt.Printf("[INFO] Report the simulator's downtime of run %s: lab-report --stats <simulator stats.jsonl> --timeline %s\n", runID, *timelinePath)
+	}
+	return nil
+}
+
+// switchover waits for the gatekeeper's gates, if any, and switches the
+// deployment over, recording the steps and results in run and tl and posting
+// them to n. With a
+// measurement m, the deployment's RDS events are followed meanwhile and its
+// stage timeline is printed. A failed switchover is explained before its
+// error is returned.
+func switchover(ctx context.Context, client *bluegreen.Client, g *gatekeeper, d *bluegreen.Deployment, window, timeout time.Duration, run *experiments.Run, tl *eventTimeline, n *notifier, m *bluegreen.Measurement) error {
+	if g != nil {
+		fmt.Printf("[INFO] Waiting up to %s for the gates to pass %d checks in a row, checking every %s\n",
+			window, g.schedule.consecutive, g.schedule.interval)
+		n.post(ctx, "gates-waiting", fmt.Sprintf("Waiting up to %s for the gates of %s to pass", window, d.ID))
+		checks, err := g.wait(ctx, window)
+		run.Results["gateChecks"] = float64(checks)
+		if err != nil {
+			n.post(ctx, "gates-failed", fmt.Sprintf("Not switching over %s: %v", d.ID, err))
+			return err
+		}
+		run.Timings["gates-passed"] = time.Now().UTC()
+		run.Results["gateWaitSeconds"] = time.Since(run.StartedAt).Seconds()
+		fmt.Println("[SUCCESS] All gates passed")
+	}
+
+	fmt.Printf("[INFO] Switching over %s to %s (timeout %s)\n", d.SourceClusterIdentifier(), d.TargetClusterIdentifier(), timeout)
+	n.post(ctx, report.EventSwitchoverStarted, fmt.Sprintf("Switching over %s to %s (timeout %s)", d.SourceClusterIdentifier(), d.TargetClusterIdentifier(), timeout),
+		notify.Field{Name: "Deployment", Value: d.ID})
+	switchoverStarted := time.Now().UTC()
+	run.Timings["switchover-started"] = switchoverStarted
+	if m == nil {
+		tl.record(report.EventSwitchoverStarted, d.ID)
+	} else {
+		m.Observe(d, switchoverStarted)
+		since := d.Created
+		if since.IsZero() {
+			since = switchoverStarted.Add(-time.Hour)
+		}
+		stop := followEvents(ctx, client, d.ID, since, m)
+		defer stop()
+	}
+	_, err := client.Switchover(ctx, d.ID, timeout, func(d *bluegreen.Deployment) {
+		now := time.Now().UTC()
+		if m != nil {
+			m.Observe(d, now)
+			fmt.Printf("[INFO] %s deployment %s: %s\n", now.Format(bluegreen.EventTimeFormat), d.ID, d.Status)
+			return
+		}
+		fmt.Printf("[INFO] %s deployment %s: %s\n", now.Format(time.RFC3339), d.ID, d.Status)
+	})
+	if err != nil {
+		run.Timings["switchover-failed"] = time.Now().UTC()
+		tl.record("switchover-failed", err.Error())
+		// Nothing is deleted or retried: the deployment stays for the
+		// operator to inspect, only its state is checked
+		status, statusErr := client.LabStatus(context.WithoutCancel(ctx), d.SourceClusterIdentifier())
+		if statusErr != nil {
+			fmt.Fprintf(os.Stderr, "[WARNING] %v\n", statusErr)
+		}
+		f := bluegreen.DiagnoseSwitchover(err)
+		renderSwitchoverFailure(os.Stderr, f, d, status)
+		fields := []notify.Field{{Name: "Deployment", Value: d.ID}, {Name: "Blue environment", Value: "not serving"}}
+		if f.BlueServing {
+			fields[1].Value = "still serving"
+		}
+		n.post(ctx, "switchover-failed", fmt.Sprintf("The switchover of %s did not complete: %s", d.ID, f.Reason), fields...)
+		return err
+	}
+	run.Timings["switchover-completed"] = time.Now().UTC()
+	run.Results["switchoverSeconds"] = time.Since(switchoverStarted).Seconds()
+	completed := fmt.Sprintf("Switched over to %s; %s is the old blue cluster",
+		d.TargetClusterIdentifier(), bluegreen.OldBlueClusterIdentifier(d.SourceClusterIdentifier()))
+	fields := []notify.Field{
+		{Name: "Deployment", Value: d.ID},
+		{Name: "Switchover", Value: fmt.Sprintf("%.1f s (polled by bgctl)", run.Results["switchoverSeconds"])},
+	}
+	if m == nil {
+		tl.record(report.EventSwitchoverCompleted, d.ID)
+		n.post(ctx, report.EventSwitchoverCompleted, completed, fields...)
+		return nil
+	}
+
+	awaitCompletedEvent(ctx, client, d.ID, m)
+	stages := m.Stages()
+	renderMeasurement(os.Stdout, d.ID, stages)
+	for _, s := range stages {
+		switch s.Name {
+		case bluegreen.StageSwitchoverInProgress:
+			tl.recordAt(s.Time(), report.EventSwitchoverStarted, d.ID+" ("+stageSource(s)+")")
+		case bluegreen.StageCompleted:
+			tl.recordAt(s.Time(), report.EventSwitchoverCompleted, d.ID+" ("+stageSource(s)+")")
+		}
+	}
+	if ms, ok := measuredSwitchover(stages); ok {
+		run.Results["measuredSwitchoverMillis"] = float64(ms.Milliseconds())
+		fields = append(fields, notify.Field{Name: "Measured switchover", Value: fmt.Sprintf("%d ms (switchover-in-progress to completed)", ms.Milliseconds())})
+	}
+	n.post(ctx, report.EventSwitchoverCompleted, completed, fields...)
+	return nil
+}
+
+// measureInterval is how often -measure polls the deployment and its events.
+const measureInterval = time.Second
+
+// completedEventGrace is how long -measure waits after the switchover for
+// its RDS event, which RDS publishes a few seconds late.
+const completedEventGrace = time.Minute
+
+// followEvents polls the deployment's RDS events since the given time into
+// m every measureInterval and prints the new ones, until the returned
+// function is called.
+func followEvents(ctx context.Context, client *bluegreen.Client, id string, since time.Time, m *bluegreen.Measurement) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		warned := false
+		for {
+			if err := pollEvents(ctx, client, id, since, m); err != nil && ctx.Err() == nil && !warned {
+				fmt.Fprintf(os.Stderr, "[WARNING] %v; the timeline falls back to the polled statuses\n", err)
+				warned = true
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(measureInterval):
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// pollEvents adds the deployment's RDS events since the given time to m and
+// prints the new ones.
+func pollEvents(ctx context.Context, client *bluegreen.Client, id string, since time.Time, m *bluegreen.Measurement) error {
+	events, err := client.DeploymentEvents(ctx, id, since)
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		if m.AddEvent(e) {
+			fmt.Printf("[INFO] %s RDS event of %s: %s\n", e.Time.Format(bluegreen.EventTimeFormat), id, e.Message)
+		}
+	}
+	return nil
+}
+
+// awaitCompletedEvent polls the deployment's RDS events until the completed
+// stage has its event, for up to completedEventGrace.
+func awaitCompletedEvent(ctx context.Context, client *bluegreen.Client, id string, m *bluegreen.Measurement) {
+	deadline := time.Now().Add(completedEventGrace)
+	for {
+		for _, s := range m.Stages() {
+			if s.Name == bluegreen.StageCompleted && !s.Event.IsZero() {
+				return
+			}
+		}
+		if time.Now().After(deadline) {
+			fmt.Fprintf(os.Stderr, "[WARNING] No RDS event of the completed switchover within %s; using the polled time\n", completedEventGrace)
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(measureInterval):
+		}
+		// Errors were reported while following the events
+		_ = pollEvents(ctx, client, id, time.Now().Add(-time.Hour), m)
+	}
+}
+
+// renderMeasurement prints the stage timeline of a deployment: the time of
+// each stage, where it comes from, and the time since the previous stage in
+// milliseconds.
+func renderMeasurement(w io.Writer, id string, stages []bluegreen.Stage) {
+	fmt.Fprintf(w, "[INFO] Timeline of deployment %s:\n", id)
+	var previous time.Time
+	for _, s := range stages {
+		line := fmt.Sprintf("  %-23s %s  %-6s", s.Name, s.Time().UTC().Format(bluegreen.EventTimeFormat), stageSource(s))
+		if !previous.IsZero() {
+			line += fmt.Sprintf("  %+d ms", s.Time().Sub(previous).Milliseconds())
+		}
+		if !s.Event.IsZero() && !s.Observed.IsZero() {
+			line += fmt.Sprintf("  (polled %+d ms)", s.Observed.Sub(s.Event).Milliseconds())
+		}
+		fmt.Fprintln(w, strings.TrimRight(line, " "))
+		previous = s.Time()
+	}
+	if d, ok := measuredSwitchover(stages); ok {
+		fmt.Fprintf(w, "[INFO] Switchover took %d ms (switchover-in-progress to completed)\n", d.Milliseconds())
+	}
+}
+
+// stageSource tells whether the stage's time is RDS's or polled.
+func stageSource(s bluegreen.Stage) string {
+	if s.Event.IsZero() {
+		return "polled"
+	}
+	return "rds"
+}
+
+// measuredSwitchover returns the time from the switchover-in-progress stage
+// to the completed one.
+func measuredSwitchover(stages []bluegreen.Stage) (time.Duration, bool) {
+	var started, completed time.Time
+	for _, s := range stages {
+		switch s.Name {
+		case bluegreen.StageSwitchoverInProgress:
+			started = s.Time()
+		case bluegreen.StageCompleted:
+			completed = s.Time()
+		}
+	}
+	if started.IsZero() || completed.IsZero() {
+		return 0, false
+	}
+	return completed.Sub(started), true
+}
+
+// renderSwitchoverFailure explains a failed switchover: why it failed, which
+// environment serves the application now (status, nil when unknown) and what
+// to do before retrying.
+func renderSwitchoverFailure(w io.Writer, f *bluegreen.SwitchoverFailure, d *bluegreen.Deployment, status *bluegreen.LabStatus) {
+	fmt.Fprintf(w, "[WARNING] The switchover of %s did not complete: %s\n", d.ID, f.Reason)
+	if status != nil {
+		for _, deployment := range status.Deployments {
+			if deployment.ID == d.ID {
+				fmt.Fprintf(w, "[INFO] Deployment %s is %s now\n", d.ID, deployment.Status)
+			}
+		}
+		for _, cluster := range status.Clusters {
+			if cluster.Identifier != d.SourceClusterIdentifier() {
+				continue
+			}
+			writer := "no writer"
+			for _, instance := range cluster.Instances {
+				if instance.Writer {
+					writer = fmt.Sprintf("writer %s %s", instance.Identifier, instance.Status)
+				}
+			}
+			level := "[INFO]"
+			if cluster.Status != "available" || !f.BlueServing {
+				level = "[WARNING]"
+			}
+			fmt.Fprintf(w, "%s Blue cluster %s is %s (%s)\n", level, cluster.Identifier, cluster.Status, writer)
+		}
+	}
+	if f.BlueServing {
+		fmt.Fprintln(w, "[INFO] The blue environment still serves the application; nothing was switched over")
+	} else {
+		fmt.Fprintln(w, "[WARNING] Check which environment serves the application with bgctl watch before retrying")
+	}
+	if len(f.Guidance) > 0 {
+		fmt.Fprintln(w, "[INFO] Before retrying:")
+		for _, step := range f.Guidance {
+			fmt.Fprintf(w, "  - %s\n", step)
+		}
+	}
+}
+
+// switchoverDeployment returns the deployment of the lab status to switch
+// over: id, else the only one that is not switched over.
+func switchoverDeployment(status *bluegreen.LabStatus, clusterIdentifier, id string) (*bluegreen.Deployment, error) {
+	var candidates []bluegreen.Deployment
+	for _, d := range status.Deployments {
+		if id != "" && d.ID == id {
+			return &d.Deployment, nil
+		}
+		if d.Status != bluegreen.StatusSwitchoverCompleted {
+			candidates = append(candidates, d.Deployment)
+		}
+	}
+	switch {
+	case id != "":
+		return nil, fmt.Errorf("Blue/Green deployment %s is not a deployment of %s", id, clusterIdentifier)
+	case len(candidates) == 0:
+		return nil, fmt.Errorf("%s has no Blue/Green deployment to switch over", clusterIdentifier)
+	case len(candidates) > 1:
+		ids := make([]string, len(candidates))
+		for i, d := range candidates {
+			ids[i] = d.ID
+		}
+		return nil, fmt.Errorf("%s has several Blue/Green deployments (%s); pass -deployment", clusterIdentifier, strings.Join(ids, ", "))
+	}
+	return &candidates[0], nil
+}
+
+// gatekeeper checks the gates of an automated switchover.
+type gatekeeper struct {
+	client     *bluegreen.Client
+	cw         *cloudwatch.Client
+	cluster    string
+	deployment string
+	// host serves the simulator's metrics endpoint; nil skips the error
+	// rate gate
+	host       remote.Host
+	metricsURL string
+	thresholds gateThresholds
+	schedule   gateSchedule
+}
+
+// gateSchedule decides when the gatekeeper checks the gates again and when
+// they have passed often enough to switch over.
+type gateSchedule struct {
+	// consecutive is the number of checks in a row that must pass
+	consecutive int
+	// interval is the wait after a passing check, and the first wait after
+	// a failed one; each further failed check doubles it up to maxInterval
+	interval    time.Duration
+	maxInterval time.Duration
+
+	passed int
+	failed int
+	delay  time.Duration
+}
+
+// observe records the result of a check and reports whether the gates have
+// now passed enough checks in a row. A failed check restarts the count and
+// backs off the next check.
+func (s *gateSchedule) observe(passed bool) bool {
+	if !passed {
+		s.passed = 0
+		s.failed++
+		s.delay = s.interval
+		for i := 1; i < s.failed && s.delay < s.maxInterval; i++ {
+			s.delay *= 2
+		}
+		s.delay = min(s.delay, s.maxInterval)
+		return false
+	}
+	s.passed++
+	s.failed = 0
+	s.delay = s.interval
+	return s.passed >= s.consecutive
+}
+
+// wait checks the gates on the gatekeeper's schedule until they pass enough
+// checks in a row, and fails when they do not within window. It returns the
+// number of checks.
+func (g *gatekeeper) wait(ctx context.Context, window time.Duration) (int, error) {
+	deadline := time.Now().Add(window)
+	schedule := g.schedule
+	var previous *writeCounts
+	for checks := 1; ; checks++ {
+		in := g.measure(ctx, previous)
+		if ctx.Err() != nil {
+			return checks, ctx.Err()
+		}
+		previous = in.writes
+
+		gates := evaluateGates(in, g.thresholds)
+		fmt.Printf("[INFO] %s gate check %d:\n", time.Now().UTC().Format(time.RFC3339), checks)
+		var failed []string
+		for _, gate := range gates {
+			result := "PASS"
+			if !gate.passed {
+				result = "FAIL"
+				failed = append(failed, gate.name+": "+gate.detail)
+			}
+			fmt.Printf("  [%s] %-15s %s\n", result, gate.name, gate.detail)
+		}
+		if schedule.observe(len(failed) == 0) {
+			return checks, nil
+		}
+		if len(failed) == 0 {
+			fmt.Printf("[INFO] The gates passed %d of %d checks in a row\n", schedule.passed, schedule.consecutive)
+		}
+		if time.Now().Add(schedule.delay).After(deadline) {
+			if len(failed) == 0 {
+				return checks, fmt.Errorf("the gates passed only %d of %d checks in a row within %s, not switching over",
+					schedule.passed, schedule.consecutive, window)
+			}
+			return checks, fmt.Errorf("the gates did not pass within %s, not switching over (%s)", window, strings.Join(failed, "; "))
+		}
+		if len(failed) > 0 && schedule.delay > schedule.interval {
+			fmt.Printf("[INFO] Backing off, next check in %s\n", schedule.delay)
+		}
+
+		select {
+		case <-ctx.Done():
+			return checks, ctx.Err()
+		case <-time.After(schedule.delay):
+		}
+	}
+}
+
+// measure collects the inputs of a gate check; failures to collect one fail
+// its gate, so the check is repeated after the backoff.
+func (g *gatekeeper) measure(ctx context.Context, previous *writeCounts) gateInputs {
+	in := gateInputs{previousWrites: previous}
+	in.status, in.statusErr = g.client.LabStatus(ctx, g.cluster)
+	if in.statusErr == nil {
+		for _, d := range in.status.Deployments {
+			if d.ID == g.deployment {
+				in.deployment = d.Deployment
+			}
+		}
+		if green := in.deployment.TargetClusterIdentifier(); green != "" {
+			in.replicaLag, in.replicaLagErr = fetchBinlogReplicaLag(ctx, g.cw, green, time.Now())
+		}
+	}
+	if g.host != nil {
+		in.metricsEnabled = true
+		var out string
+		if out, in.writesErr = g.host.Run(ctx, "curl -sf --max-time 5 "+remote.Quote(g.metricsURL)); in.writesErr == nil {
+			var writes writeCounts
+			if writes, in.writesErr = parseWriteCounts(out); in.writesErr == nil {
+				in.writes = &writes
+			}
+		}
+	}
+	return in
+}
+
+// fetchBinlogReplicaLag returns the latest AuroraBinlogReplicaLag of the green
+// cluster in seconds, nil without a datapoint in the metrics window.
+func fetchBinlogReplicaLag(ctx context.Context, cw *cloudwatch.Client, clusterIdentifier string, now time.Time) (*float64, error) {
+	out, err := cw.GetMetricData(ctx, &cloudwatch.GetMetricDataInput{
+		MetricDataQueries: []types.MetricDataQuery{{
+			Id: aws.String("lag"),
+			MetricStat: &types.MetricStat{
+				Metric: &types.Metric{
+					Namespace:  aws.String("AWS/RDS"),
+					MetricName: aws.String("AuroraBinlogReplicaLag"),
+					Dimensions: []types.Dimension{
+						{Name: aws.String("DBClusterIdentifier"), Value: aws.String(clusterIdentifier)},
+					},
+				},
+				Period: aws.Int32(60),
+				Stat:   aws.String("Maximum"),
+			},
+		}},
+		StartTime: aws.Time(now.Add(-metricsWindow)),
+		EndTime:   aws.Time(now),
+		ScanBy:    types.ScanByTimestampDescending,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fetching replica lag: %w", err)
+	}
+	for _, r := range out.MetricDataResults {
+		if len(r.Values) > 0 {
+			latest := r.Values[0]
+			return &latest, nil
+		}
+	}
+	return nil, nil
+}
+
+// gateThresholds are the limits of the gates.
+type gateThresholds struct {
+	maxReplicaLag time.Duration
+	// maxErrorRate is in percent
+	maxErrorRate float64
+}
+
+// gateInputs are the measurements of a gate check.
+type gateInputs struct {
+	status     *bluegreen.LabStatus
+	statusErr  error
+	deployment bluegreen.Deployment
+	// replicaLag is the green cluster's AuroraBinlogReplicaLag in seconds
+	replicaLag    *float64
+	replicaLagErr error
+	// metricsEnabled is set when the error rate gate is checked
+	metricsEnabled bool
+	// writes are the simulator's write counters now and at the previous check
+	writes, previousWrites *writeCounts
+	writesErr              error
+}
+
+// gate is the result of a gate in a check.
+type gate struct {
+	name   string
+	passed bool
+	detail string
+}
+
+// evaluateGates checks the gates against the inputs.
+func evaluateGates(in gateInputs, th gateThresholds) []gate {
+	if in.statusErr != nil {
+		return []gate{{name: "deployment", detail: in.statusErr.Error()}}
+	}
+	gates := []gate{deploymentGate(in.deployment), replicaLagGate(in, th)}
+	if in.metricsEnabled {
+		gates = append(gates, errorRateGate(in, th))
+	}
+	return append(gates, pendingGate(in.status, in.deployment))
+}
+
+func deploymentGate(d bluegreen.Deployment) gate {
+	g := gate{name: "deployment", detail: d.Status}
+	switch {
+	case d.ID == "":
+		g.detail = "the deployment no longer exists"
+	case d.Status == bluegreen.StatusAvailable:
+		g.passed = true
+	case d.StatusDetails != "":
+		g.detail += ": " + d.StatusDetails
+	}
+	return g
+}
+
+func replicaLagGate(in gateInputs, th gateThresholds) gate {
+	g := gate{name: "replica lag"}
+	switch {
+	case in.replicaLagErr != nil:
+		g.detail = in.replicaLagErr.Error()
+	case in.replicaLag == nil:
+		g.detail = fmt.Sprintf("no AuroraBinlogReplicaLag datapoint of the green cluster in the last %s", metricsWindow)
+	case *in.replicaLag < 0:
+		// Aurora reports -1 while binlog replication is not running
+		g.detail = "binlog replication to the green cluster is not running"
+	default:
+		lag := time.Duration(*in.replicaLag * float64(time.Second))
+		g.passed = lag <= th.maxReplicaLag
+		g.detail = fmt.Sprintf("%s (max %s)", lag, th.maxReplicaLag)
+	}
+	return g
+}
+
+func errorRateGate(in gateInputs, th gateThresholds) gate {
+	g := gate{name: "error rate"}
+	if in.writesErr != nil {
+		g.detail = fmt.Sprintf("reading the simulator metrics: %v", in.writesErr)
+		return g
+	}
+	if in.previousWrites == nil || in.writes.success < in.previousWrites.success || in.writes.failure < in.previousWrites.failure {
+		// The rate is over the writes between two checks; a restarted
+		// simulator starts its counters again
+		g.detail = "first sample of the simulator writes, rate from the next check"
+		return g
+	}
+	success := in.writes.success - in.previousWrites.success
+	failure := in.writes.failure - in.previousWrites.failure
+	if success+failure == 0 {
+		g.detail = "no simulator writes since the previous check"
+		return g
+	}
+	rate := failure / (success + failure) * 100
+	g.passed = rate <= th.maxErrorRate
+	g.detail = fmt.Sprintf("%.2f%% of %.0f writes failed (max %g%%)", rate, success+failure, th.maxErrorRate)
+	return g
+}
+
+func pendingGate(status *bluegreen.LabStatus, d bluegreen.Deployment) gate {
+	g := gate{name: "pending changes"}
+	blue, green := d.SourceClusterIdentifier(), d.TargetClusterIdentifier()
+	var problems []string
+	found := 0
+	for _, cluster := range status.Clusters {
+		if cluster.Identifier != blue && cluster.Identifier != green {
+			continue
+		}
+		found++
+		problems = append(problems, pendingProblems("cluster "+cluster.Identifier, cluster.Status, cluster.Pending)...)
+		for _, instance := range cluster.Instances {
+			problems = append(problems, pendingProblems("instance "+instance.Identifier, instance.Status, instance.Pending)...)
+		}
+	}
+	switch {
+	case found < 2:
+		g.detail = "the blue or green cluster was not found"
+	case len(problems) > 0:
+		g.detail = strings.Join(problems, ", ")
+	default:
+		g.passed = true
+		g.detail = "blue and green available, no pending modifications"
+	}
+	return g
+}
+
+func pendingProblems(name, status string, pending []string) []string {
+	var problems []string
+	if status != "available" {
+		problems = append(problems, name+" is "+status)
+	}
+	if len(pending) > 0 {
+		problems = append(problems, name+" has pending "+strings.Join(pending, ", "))
+	}
+	return problems
+}
+
+// writeCounts are the simulator's aurora_write_requests_total counters.
+type writeCounts struct {
+	success float64
+	failure float64
+}
+
+// parseWriteCounts reads the write counters from the simulator's Prometheus
+// text exposition.
+func parseWriteCounts(metrics string) (writeCounts, error) {
+	var counts writeCounts
+	found := false
+	scanner := bufio.NewScanner(strings.NewReader(metrics))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "# TYPE aurora_write_requests_total ") {
+			found = true
+		}
+		if !strings.HasPrefix(line, "aurora_write_requests_total{") {
+			continue
+		}
+		// aurora_write_requests_total{status="success",} 1234.0
+		labels, value, ok := strings.Cut(strings.TrimPrefix(line, "aurora_write_requests_total{"), "}")
+		fields := strings.Fields(value)
+		if !ok || len(fields) == 0 {
+			return counts, fmt.Errorf("malformed metric line %q", line)
+		}
+		n, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return counts, fmt.Errorf("malformed metric line %q", line)
+		}
+		switch {
+		case strings.Contains(labels, `status="success"`):
+			counts.success = n
+		case strings.Contains(labels, `status="failure"`):
+			counts.failure = n
+		}
+	}
+	if !found {
+		return counts, errors.New("no aurora_write_requests_total metric; is the simulator running with --enable-metrics?")
+	}
+	return counts, nil
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"aurora-bluegreen-lab/internal/bluegreen"
+)
+
+const simulatorMetrics = `# HELP aurora_write_requests_total Total write requests
+# TYPE aurora_write_requests_total counter
+aurora_write_requests_total{status="success",} 9900.0
+aurora_write_requests_total{status="failure",} 100.0
+# HELP aurora_connection_errors_total Total connection errors
+# TYPE aurora_connection_errors_total counter
+aurora_connection_errors_total{error_type="timeout",} 100.0
+`
+
+func TestParseWriteCounts(t *testing.T) {
+	got, err := parseWriteCounts(simulatorMetrics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != (writeCounts{success: 9900, failure: 100}) {
+		t.Errorf("got %+v", got)
+	}
+	if _, err := parseWriteCounts("# TYPE jvm_threads_current gauge\n"); err == nil {
+		t.Error("no error without the write counters")
+	}
+}
+
+func gateTestInputs() gateInputs {
+	lag := 0.4
+	return gateInputs{
+		status: &bluegreen.LabStatus{Clusters: []bluegreen.ClusterStatus{
+			{Identifier: "lab", Status: "available", Instances: []bluegreen.InstanceStatus{{Identifier: "lab-writer", Status: "available", Writer: true}}},
+			{Identifier: "lab-green-x1", Status: "available", Instances: []bluegreen.InstanceStatus{{Identifier: "lab-writer-green", Status: "available", Writer: true}}},
+		}},
+		deployment: bluegreen.Deployment{
+			ID:        "bgd-abc",
+			Status:    bluegreen.StatusAvailable,
+			SourceArn: "arn:aws:rds:us-east-1:123456789012:cluster:lab",
+			TargetArn: "arn:aws:rds:us-east-1:123456789012:cluster:lab-green-x1",
+		},
+		replicaLag:     &lag,
+		metricsEnabled: true,
+		previousWrites: &writeCounts{success: 1000, failure: 2},
+		writes:         &writeCounts{success: 1995, failure: 7},
+	}
+}
+
+func failedGates(gates []gate) []string {
+	var failed []string
+	for _, g := range gates {
+		if !g.passed {
+			failed = append(failed, g.name)
+		}
+	}
+	return failed
+}
+
+func TestEvaluateGates(t *testing.T) {
+	th := gateThresholds{maxReplicaLag: time.Second, maxErrorRate: 1}
+	if failed := failedGates(evaluateGates(gateTestInputs(), th)); len(failed) > 0 {
+		t.Fatalf("failed gates %v", failed)
+	}
+
+	for name, tc := range map[string]struct {
+		change func(in *gateInputs)
+		gate   string
+	}{
+		"switchover in progress": {func(in *gateInputs) { in.deployment.Status = bluegreen.StatusSwitchoverInProgress }, "deployment"},
+		"lagging green":          {func(in *gateInputs) { lag := 2.5; in.replicaLag = &lag }, "replica lag"},
+		"replication stopped":    {func(in *gateInputs) { lag := -1.0; in.replicaLag = &lag }, "replica lag"},
+		"no lag datapoint":       {func(in *gateInputs) { in.replicaLag = nil }, "replica lag"},
+		"failing writes":         {func(in *gateInputs) { in.writes.failure = 50 }, "error rate"},
+		"first sample":           {func(in *gateInputs) { in.previousWrites = nil }, "error rate"},
+		"idle simulator":         {func(in *gateInputs) { in.writes = in.previousWrites }, "error rate"},
+		"pending instance class": {func(in *gateInputs) { in.status.Clusters[1].Instances[0].Pending = []string{"DBInstanceClass"} }, "pending changes"},
+		"modifying blue":         {func(in *gateInputs) { in.status.Clusters[0].Status = "modifying" }, "pending changes"},
+	} {
+		in := gateTestInputs()
+		tc.change(&in)
+		if failed := failedGates(evaluateGates(in, th)); len(failed) != 1 || failed[0] != tc.gate {
+			t.Errorf("%s: failed gates %v, want %s", name, failed, tc.gate)
+		}
+	}
+
+	// Without a metrics endpoint there is no error rate gate
+	in := gateTestInputs()
+	in.metricsEnabled = false
+	for _, g := range evaluateGates(in, th) {
+		if g.name == "error rate" {
+			t.Error("error rate gate without metrics")
+		}
+	}
+}
+
+func TestErrorRateGateDetail(t *testing.T) {
+	g := errorRateGate(gateTestInputs(), gateThresholds{maxErrorRate: 1})
+	if !strings.Contains(g.detail, "0.50% of 1000 writes") {
+		t.Errorf("detail %q", g.detail)
+	}
+}
+
+func TestGateSchedule(t *testing.T) {
+	s := gateSchedule{consecutive: 3, interval: 10 * time.Second, maxInterval: 30 * time.Second}
+	steps := []struct {
+		passed bool
+		done   bool
+		delay  time.Duration
+	}{
+		{passed: true, delay: 10 * time.Second},
+		{passed: true, delay: 10 * time.Second},
+		// A failed check restarts the count and backs off
+		{passed: false, delay: 10 * time.Second},
+		{passed: false, delay: 20 * time.Second},
+		{passed: false, delay: 30 * time.Second},
+		{passed: false, delay: 30 * time.Second},
+		{passed: true, delay: 10 * time.Second},
+		{passed: true, delay: 10 * time.Second},
+		{passed: true, done: true, delay: 10 * time.Second},
+	}
+	for i, step := range steps {
+		if done := s.observe(step.passed); done != step.done || s.delay != step.delay {
+			t.Errorf("check %d (passed %t): got done %t, delay %s; want %t, %s", i+1, step.passed, done, s.delay, step.done, step.delay)
+		}
+	}
+}
+
+func TestSwitchoverDeployment(t *testing.T) {
+	status := &bluegreen.LabStatus{Deployments: []bluegreen.DeploymentStatus{
+		{Deployment: bluegreen.Deployment{ID: "bgd-old", Status: bluegreen.StatusSwitchoverCompleted}},
+		{Deployment: bluegreen.Deployment{ID: "bgd-new", Status: bluegreen.StatusAvailable}},
+	}}
+	if d, err := switchoverDeployment(status, "lab", ""); err != nil || d.ID != "bgd-new" {
+		t.Errorf("got %v, %v", d, err)
+	}
+	if _, err := switchoverDeployment(status, "lab", "bgd-other"); err == nil {
+		t.Error("no error for an unknown deployment")
+	}
+
+	status.Deployments = append(status.Deployments, bluegreen.DeploymentStatus{Deployment: bluegreen.Deployment{ID: "bgd-newer", Status: bluegreen.StatusProvisioning}})
+	if _, err := switchoverDeployment(status, "lab", ""); err == nil {
+		t.Error("no error for several deployments")
+	}
+	if d, err := switchoverDeployment(status, "lab", "bgd-newer"); err != nil || d.ID != "bgd-newer" {
+		t.Errorf("got %v, %v", d, err)
+	}
+}
+
+func TestRenderSwitchoverFailure(t *testing.T) {
+	d := &bluegreen.Deployment{ID: "bgd-abc", SourceArn: "arn:aws:rds:us-east-1:123456789012:cluster:lab"}
+	status := &bluegreen.LabStatus{
+		Deployments: []bluegreen.DeploymentStatus{{Deployment: bluegreen.Deployment{ID: "bgd-abc", Status: bluegreen.StatusAvailable}}},
+		Clusters: []bluegreen.ClusterStatus{
+			{Identifier: "lab", Status: "available", Instances: []bluegreen.InstanceStatus{{Identifier: "lab-writer", Status: "available", Writer: true}}},
+		},
+	}
+	var buf bytes.Buffer
+	renderSwitchoverFailure(&buf, &bluegreen.SwitchoverFailure{
+		Reason:      "RDS rolled it back",
+		BlueServing: true,
+		Guidance:    []string{"Retry with a longer switchover timeout"},
+	}, d, status)
+	out := buf.String()
+	for _, want := range []string{
+		"[WARNING] The switchover of bgd-abc did not complete: RDS rolled it back",
+		"Deployment bgd-abc is AVAILABLE now",
+		"[INFO] Blue cluster lab is available (writer lab-writer available)",
+		"still serves the application",
+		"  - Retry with a longer switchover timeout",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRenderMeasurement(t *testing.T) {
+	created := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	started := created.Add(time.Hour + 1120*time.Millisecond)
+	stages := []bluegreen.Stage{
+		{Name: bluegreen.StageCreated, Event: created},
+		{Name: bluegreen.StageSwitchoverInProgress, Event: started, Observed: started.Add(412 * time.Millisecond)},
+		{Name: bluegreen.StageCompleted, Observed: started.Add(28236 * time.Millisecond)},
+	}
+	if d, ok := measuredSwitchover(stages); !ok || d != 28236*time.Millisecond {
+		t.Errorf("measured switchover %v, %v", d, ok)
+	}
+	var buf bytes.Buffer
+	renderMeasurement(&buf, "bgd-abc", stages)
+	out := buf.String()
+	for _, want := range []string{
+		"  created                 2026-01-02T15:00:00.000Z  rds\n",
+		"  switchover-in-progress  2026-01-02T16:00:01.120Z  rds     +3601120 ms  (polled +412 ms)\n",
+		"  completed               2026-01-02T16:00:29.356Z  polled  +28236 ms\n",
+		"[INFO] Switchover took 28236 ms",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+	if _, ok := measuredSwitchover(stages[:2]); ok {
+		t.Error("measured a switchover without its completion")
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"aurora-bluegreen-lab/internal/report"
+)
+
+// eventTimeline appends the events of a command to a timeline file for
+// lab-report --timeline, with the command's run ID; a nil timeline records
+// nothing.
+type eventTimeline struct {
+	f     *os.File
+	enc   *json.Encoder
+	runID string
+}
+
+func openTimeline(path, runID string) (*eventTimeline, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &eventTimeline{f: f, enc: json.NewEncoder(f), runID: runID}, nil
+}
+
+func (t *eventTimeline) record(event, detail string) {
+	t.recordAt(time.Now().UTC(), event, detail)
+}
+
+// recordAt records an event that happened at a known time, e.g. RDS's.
+func (t *eventTimeline) recordAt(at time.Time, event, detail string) {
+	if t == nil {
+		return
+	}
+	if err := t.enc.Encode(report.Event{Timestamp: at.UTC(), Event: event, Detail: detail, RunID: t.runID}); err != nil {
+		fmt.Fprintf(os.Stderr, "[WARNING] Recording %s in the timeline: %v\n", event, err)
+	}
+}
+
+func (t *eventTimeline) close() {
+	t.f.Close()
+}
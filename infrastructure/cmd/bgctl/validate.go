@@ -0,0 +1,717 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"gopkg.in/yaml.v3"
+
+	"aurora-bluegreen-lab/internal/bluegreen"
+	"aurora-bluegreen-lab/internal/experiments"
+	"aurora-bluegreen-lab/internal/remote"
+	"aurora-bluegreen-lab/internal/schemachange"
+)
+
+const validateGreenUsage = `Usage: bgctl validate-green [flags]
+
+Checks the green environment of the lab cluster's Blue/Green deployment
+before the switchover and fails when any check fails:
+
+  row counts      every table has as many rows on green as on blue, within a
+                  tolerance in percent (writes continue while both are counted)
+  engine version  the green cluster runs the expected engine version
+  parameters      the green cluster's global variables have the expected values
+  grants          the database users have the same privileges on green as on blue
+  query latency   sample queries run on green within their latency limit
+
+The checks are configured in a YAML file (-checks, see
+green-checks.example.yaml); without one, the row counts of every table
+(within 1%), the engine version (the aurora stack's greenEngineVersion) and
+the grants of every user are checked. Queries run from the simulator host
+with the simulator's database credentials, over SSM or SSH like bgctl
+simulator. bgctl switchover -validate runs the same checks and does not
+switch over when one fails. Validations are registered as
+validate-green-<time> runs in the experiment registry of the monitoring
+stack, if it has one.
+
+  bgctl validate-green
+  bgctl validate-green -checks green-checks.yaml
+
+Flags:
+`
+
+func validateGreenCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("validate-green", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), validateGreenUsage)
+		fs.PrintDefaults()
+	}
+	var lab labFlags
+	var hf hostFlags
+	var vf validateFlags
+	lab.register(fs)
+	hf.register(fs)
+	vf.register(fs)
+	cluster := fs.String("cluster", "", "Blue cluster of the deployment (default: the aurora stack's clusterIdentifier output)")
+	deploymentID := fs.String("deployment", "", "Blue/Green deployment ID (default: the cluster's only deployment that is not switched over)")
+	registryTable := fs.String("registry-table", "", "Experiment registry table to register the validation in (default: the monitoring stack's experimentTableName output)")
+	var rf runFlag
+	rf.register(fs)
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	checks, err := vf.checks()
+	if err != nil {
+		return err
+	}
+
+	clusterIdentifier, region := *cluster, lab.region
+	if clusterIdentifier == "" || region == "" {
+		aurora, err := lab.reader().Outputs(ctx, "aurora")
+		if err != nil {
+			return err
+		}
+		if clusterIdentifier == "" {
+			if clusterIdentifier = aurora.String("clusterIdentifier"); clusterIdentifier == "" {
+				return fmt.Errorf("the aurora stack has no clusterIdentifier output; pass -cluster")
+			}
+		}
+		if region == "" {
+			region = aurora.String("region")
+		}
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	if err != nil {
+		return fmt.Errorf("loading AWS configuration: %w", err)
+	}
+	client := bluegreen.New(cfg)
+
+	status, err := client.LabStatus(ctx, clusterIdentifier)
+	if err != nil {
+		return err
+	}
+	d, err := switchoverDeployment(status, clusterIdentifier, *deploymentID)
+	if err != nil {
+		return err
+	}
+	v, err := vf.validator(ctx, lab, hf, status, d, checks)
+	if err != nil {
+		return err
+	}
+
+	started := time.Now().UTC()
+	runID, source, err := rf.identify("validate-green", started)
+	if err != nil {
+		return err
+	}
+	registry := openRegistry(ctx, lab, cfg, *registryTable)
+	run := &experiments.Run{
+		RunID:     runID,
+		Source:    source,
+		Name:      "validate-green",
+		Status:    experiments.StatusRunning,
+		StartedAt: started,
+		Config: map[string]string{
+			"cluster":    clusterIdentifier,
+			"deployment": d.ID,
+			"green":      d.TargetClusterIdentifier(),
+			"checks":     vf.describe(),
+		},
+	}
+	registerRun(ctx, registry, run)
+
+	gates, err := v.validate(ctx)
+	run.Results = validationResults(gates)
+	run.Finish(time.Now().UTC(), err)
+	registerRun(context.WithoutCancel(ctx), registry, run)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("[SUCCESS] The green environment %s passed all %d checks\n", d.TargetClusterIdentifier(), len(gates))
+	return nil
+}
+
+// validateFlags are the flags of the green environment checks, shared by
+// validate-green and switchover -validate.
+type validateFlags struct {
+	checksFile string
+	database   string
+}
+
+func (f *validateFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.checksFile, "checks", "", "YAML file with the green environment checks (default: row counts, engine version and grants)")
+	fs.StringVar(&f.database, "database", "", "Database the checks run in (default: the aurora stack's databaseName output)")
+}
+
+// checks loads the checks file, or returns the default checks.
+func (f *validateFlags) checks() (*greenChecks, error) {
+	if f.checksFile == "" {
+		return defaultGreenChecks(), nil
+	}
+	data, err := os.ReadFile(f.checksFile)
+	if err != nil {
+		return nil, err
+	}
+	checks, err := parseGreenChecks(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", f.checksFile, err)
+	}
+	return checks, nil
+}
+
+// describe names the checks for the run's configuration.
+func (f *validateFlags) describe() string {
+	if f.checksFile == "" {
+		return "default"
+	}
+	return f.checksFile
+}
+
+// validator returns the validator of the deployment's environments, reading
+// the aurora stack outputs only for what the flags and checks leave open.
+func (f *validateFlags) validator(ctx context.Context, lab labFlags, hf hostFlags, status *bluegreen.LabStatus, d *bluegreen.Deployment, checks *greenChecks) (*greenValidator, error) {
+	blue, err := clusterStatus(status, d.SourceClusterIdentifier())
+	if err != nil {
+		return nil, err
+	}
+	green, err := clusterStatus(status, d.TargetClusterIdentifier())
+	if err != nil {
+		return nil, err
+	}
+	if blue.Endpoint == "" || green.Endpoint == "" {
+		return nil, fmt.Errorf("the blue or green cluster of Blue/Green deployment %s has no endpoint yet", d.ID)
+	}
+
+	database, expectedVersion := f.database, ""
+	if checks.EngineVersion != nil {
+		expectedVersion = checks.EngineVersion.Expected
+	}
+	if database == "" || (checks.EngineVersion != nil && expectedVersion == "") {
+		aurora, err := lab.reader().Outputs(ctx, "aurora")
+		if err != nil {
+			return nil, err
+		}
+		if database == "" {
+			if database = aurora.String("databaseName"); database == "" {
+				return nil, fmt.Errorf("the aurora stack has no databaseName output; pass -database")
+			}
+		}
+		if checks.EngineVersion != nil && expectedVersion == "" {
+			// Without a green engine version the deployment keeps the blue one
+			if expectedVersion = aurora.String("greenEngineVersion"); expectedVersion == "" {
+				expectedVersion = blue.EngineVersion
+			}
+		}
+	}
+
+	host, err := hf.connect(ctx, lab)
+	if err != nil {
+		return nil, err
+	}
+	return &greenValidator{
+		checks:          checks,
+		blue:            remote.NewMySQL(host, blue.Endpoint, database),
+		green:           remote.NewMySQL(host, green.Endpoint, database),
+		greenCluster:    green,
+		expectedVersion: expectedVersion,
+	}, nil
+}
+
+// clusterStatus returns the cluster of the lab status.
+func clusterStatus(status *bluegreen.LabStatus, identifier string) (bluegreen.ClusterStatus, error) {
+	for _, cluster := range status.Clusters {
+		if cluster.Identifier == identifier {
+			return cluster, nil
+		}
+	}
+	return bluegreen.ClusterStatus{}, fmt.Errorf("cluster %s not found", identifier)
+}
+
+// greenChecks are the checks of the green environment; a nil or empty check
+// is skipped.
+type greenChecks struct {
+	RowCounts     *rowCountCheck      `yaml:"rowCounts"`
+	EngineVersion *engineVersionCheck `yaml:"engineVersion"`
+	// Parameters are the expected values of global variables
+	Parameters map[string]string `yaml:"parameters"`
+	Grants     *grantsCheck      `yaml:"grants"`
+	Queries    []queryCheck      `yaml:"queries"`
+}
+
+type rowCountCheck struct {
+	// Tables default to every base table of the database
+	Tables []string `yaml:"tables"`
+	// Tolerance is the largest difference in percent of the blue count
+	Tolerance float64 `yaml:"tolerance"`
+}
+
+type engineVersionCheck struct {
+	// Expected defaults to the aurora stack's greenEngineVersion
+	Expected string `yaml:"expected"`
+}
+
+type grantsCheck struct {
+	// Users default to every user except the RDS and MySQL system users
+	Users []string `yaml:"users"`
+}
+
+type queryCheck struct {
+	Name       string       `yaml:"name"`
+	SQL        string       `yaml:"sql"`
+	MaxLatency yamlDuration `yaml:"maxLatency"`
+	// Runs is the number of runs whose median latency is checked
+	Runs int `yaml:"runs"`
+}
+
+// yamlDuration is a duration written as a string, e.g. 50ms.
+type yamlDuration time.Duration
+
+// UnmarshalYAML parses a duration string.
+func (d *yamlDuration) UnmarshalYAML(node *yaml.Node) error {
+	parsed, err := time.ParseDuration(node.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: invalid duration %q (use e.g. 50ms or 1s)", node.Line, node.Value)
+	}
+	*d = yamlDuration(parsed)
+	return nil
+}
+
+// Check defaults.
+const (
+	defaultRowCountTolerance = 1
+	defaultQueryRuns         = 5
+)
+
+// defaultGreenChecks are the checks without a checks file.
+func defaultGreenChecks() *greenChecks {
+	return &greenChecks{
+		RowCounts:     &rowCountCheck{Tolerance: defaultRowCountTolerance},
+		EngineVersion: &engineVersionCheck{},
+		Grants:        &grantsCheck{},
+	}
+}
+
+var (
+	// Names are spliced into the queries, so only plain identifiers pass
+	identifierPattern = regexp.MustCompile(`^[A-Za-z0-9_$]{1,64}$`)
+	userPattern       = regexp.MustCompile(`^[A-Za-z0-9_.$-]{1,32}$`)
+)
+
+func parseGreenChecks(data []byte) (*greenChecks, error) {
+	var c greenChecks
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&c); err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	if c.RowCounts == nil && c.EngineVersion == nil && len(c.Parameters) == 0 && c.Grants == nil && len(c.Queries) == 0 {
+		problems = append(problems, "no checks (set rowCounts, engineVersion, parameters, grants or queries)")
+	}
+	if c.RowCounts != nil {
+		if c.RowCounts.Tolerance < 0 || c.RowCounts.Tolerance > 100 {
+			problems = append(problems, fmt.Sprintf("rowCounts.tolerance must be between 0 and 100 percent (got %g)", c.RowCounts.Tolerance))
+		}
+		for _, table := range c.RowCounts.Tables {
+			if !identifierPattern.MatchString(table) {
+				problems = append(problems, fmt.Sprintf("rowCounts.tables: invalid table name %q", table))
+			}
+		}
+	}
+	for _, name := range sortedKeys(c.Parameters) {
+		if !identifierPattern.MatchString(name) {
+			problems = append(problems, fmt.Sprintf("parameters: invalid variable name %q", name))
+		}
+	}
+	if c.Grants != nil {
+		for _, user := range c.Grants.Users {
+			if !userPattern.MatchString(user) {
+				problems = append(problems, fmt.Sprintf("grants.users: invalid user name %q", user))
+			}
+		}
+	}
+	for i := range c.Queries {
+		q := &c.Queries[i]
+		if q.Name == "" {
+			q.Name = fmt.Sprintf("query %d", i+1)
+		}
+		if q.Runs == 0 {
+			q.Runs = defaultQueryRuns
+		}
+		if q.Runs < 1 || q.Runs > 100 {
+			problems = append(problems, fmt.Sprintf("queries[%s].runs must be between 1 and 100 (got %d)", q.Name, q.Runs))
+		}
+		if q.MaxLatency <= 0 {
+			problems = append(problems, fmt.Sprintf("queries[%s].maxLatency must be positive", q.Name))
+		}
+		if err := checkReadOnlyQuery(q.SQL); err != nil {
+			problems = append(problems, fmt.Sprintf("queries[%s].sql %v", q.Name, err))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid checks:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+	return &c, nil
+}
+
+// checkReadOnlyQuery accepts a single SELECT statement, which cannot write to
+// the green cluster.
+func checkReadOnlyQuery(sql string) error {
+	statements, err := schemachange.Split(sql)
+	if err != nil {
+		return err
+	}
+	if len(statements) != 1 {
+		return fmt.Errorf("must be a single statement (got %d)", len(statements))
+	}
+	words := strings.Fields(strings.ToUpper(statements[0]))
+	if words[0] != "SELECT" && words[0] != "WITH" {
+		return fmt.Errorf("must be a SELECT statement")
+	}
+	for _, word := range words {
+		if word == "INTO" || word == "UPDATE" {
+			return fmt.Errorf("must not use INTO or FOR UPDATE")
+		}
+	}
+	return nil
+}
+
+// greenValidator runs the checks against the blue and green writer
+// endpoints.
+type greenValidator struct {
+	checks          *greenChecks
+	blue, green     *remote.MySQL
+	greenCluster    bluegreen.ClusterStatus
+	expectedVersion string
+}
+
+// validate runs the checks and prints each result. It fails when a check
+// fails; a check that cannot be measured fails.
+func (v *greenValidator) validate(ctx context.Context) ([]gate, error) {
+	fmt.Printf("[INFO] Validating the green environment %s\n", v.greenCluster.Identifier)
+	var gates []gate
+	var failed []string
+	add := func(g gate) {
+		result := "PASS"
+		if !g.passed {
+			result = "FAIL"
+			failed = append(failed, g.name+": "+g.detail)
+		}
+		fmt.Printf("  [%s] %-15s %s\n", result, g.name, g.detail)
+		gates = append(gates, g)
+	}
+
+	c := v.checks
+	if c.RowCounts != nil {
+		add(v.rowCounts(ctx))
+	}
+	if ctx.Err() != nil {
+		return gates, ctx.Err()
+	}
+	if c.EngineVersion != nil {
+		add(engineVersionGate(v.greenCluster.EngineVersion, v.expectedVersion))
+	}
+	if len(c.Parameters) > 0 {
+		actual, err := v.parameters(ctx)
+		add(parametersGate(c.Parameters, actual, err))
+	}
+	if c.Grants != nil {
+		add(v.grants(ctx))
+	}
+	for _, q := range c.Queries {
+		latencies, err := v.green.Latency(ctx, q.SQL, q.Runs)
+		add(latencyGate(q, latencies, err))
+	}
+	if ctx.Err() != nil {
+		return gates, ctx.Err()
+	}
+	if len(failed) > 0 {
+		return gates, fmt.Errorf("%d of %d green environment checks failed (%s)", len(failed), len(gates), strings.Join(failed, "; "))
+	}
+	return gates, nil
+}
+
+func (v *greenValidator) rowCounts(ctx context.Context) gate {
+	fail := func(err error) gate { return gate{name: "row counts", detail: err.Error()} }
+	tables := v.checks.RowCounts.Tables
+	if len(tables) == 0 {
+		rows, err := v.blue.Query(ctx, "SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE() AND table_type = 'BASE TABLE' ORDER BY table_name")
+		if err != nil {
+			return fail(err)
+		}
+		for _, row := range rows {
+			tables = append(tables, row[0])
+		}
+	}
+	if len(tables) == 0 {
+		return gate{name: "row counts", passed: true, detail: "no tables in the database"}
+	}
+	blue, err := v.countRows(ctx, v.blue, tables)
+	if err != nil {
+		return fail(err)
+	}
+	green, err := v.countRows(ctx, v.green, tables)
+	if err != nil {
+		return fail(err)
+	}
+	return rowCountGate(tables, blue, green, v.checks.RowCounts.Tolerance)
+}
+
+// countRows counts the rows of every table in one query.
+func (v *greenValidator) countRows(ctx context.Context, db *remote.MySQL, tables []string) (map[string]int64, error) {
+	rows, err := db.Query(ctx, countQuery(tables))
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]int64{}
+	for _, row := range rows {
+		if len(row) != 2 {
+			return nil, fmt.Errorf("unexpected row count row %q", row)
+		}
+		n, err := strconv.ParseInt(row[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid row count %q of %s", row[1], row[0])
+		}
+		counts[row[0]] = n
+	}
+	return counts, nil
+}
+
+func countQuery(tables []string) string {
+	selects := make([]string, len(tables))
+	for i, table := range tables {
+		selects[i] = fmt.Sprintf("SELECT '%s', COUNT(*) FROM `%s`", table, strings.ReplaceAll(table, "`", "``"))
+	}
+	return strings.Join(selects, "\nUNION ALL ")
+}
+
+func (v *greenValidator) parameters(ctx context.Context) (map[string]string, error) {
+	names := sortedKeys(v.checks.Parameters)
+	columns := make([]string, len(names))
+	for i, name := range names {
+		columns[i] = "@@global." + name
+	}
+	rows, err := v.green.Query(ctx, "SELECT "+strings.Join(columns, ", "))
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) != 1 || len(rows[0]) != len(names) {
+		return nil, fmt.Errorf("unexpected result %q", rows)
+	}
+	actual := map[string]string{}
+	for i, name := range names {
+		actual[name] = rows[0][i]
+	}
+	return actual, nil
+}
+
+// grantsQuery lists the global, schema and table privileges of every user.
+const grantsQuery = `SELECT grantee, privilege_type, '*.*' FROM information_schema.user_privileges
+UNION ALL SELECT grantee, privilege_type, CONCAT(table_schema, '.*') FROM information_schema.schema_privileges
+UNION ALL SELECT grantee, privilege_type, CONCAT(table_schema, '.', table_name) FROM information_schema.table_privileges`
+
+func (v *greenValidator) grants(ctx context.Context) gate {
+	blue, err := v.blue.Query(ctx, grantsQuery)
+	if err != nil {
+		return gate{name: "grants", detail: err.Error()}
+	}
+	green, err := v.green.Query(ctx, grantsQuery)
+	if err != nil {
+		return gate{name: "grants", detail: err.Error()}
+	}
+	users := v.checks.Grants.Users
+	return grantsGate(userGrants(blue, users), userGrants(green, users))
+}
+
+// systemUsers are the users RDS and MySQL manage themselves.
+var systemUsers = regexp.MustCompile(`^(rds.*|mysql\..*|AWS_.*)$`)
+
+// userGrants returns the privileges of the users, all but the system users
+// without any, as "user@host PRIVILEGE ON scope".
+func userGrants(rows [][]string, users []string) map[string]bool {
+	grants := map[string]bool{}
+	for _, row := range rows {
+		if len(row) != 3 {
+			continue
+		}
+		// Grantees are quoted: 'user'@'host'
+		user, host, _ := strings.Cut(row[0], "@")
+		user, host = strings.Trim(user, "'"), strings.Trim(host, "'")
+		if len(users) > 0 && !contains(users, user) || len(users) == 0 && systemUsers.MatchString(user) {
+			continue
+		}
+		grants[fmt.Sprintf("%s@%s %s ON %s", user, host, row[1], row[2])] = true
+	}
+	return grants
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// rowCountGate compares the row counts of the tables.
+func rowCountGate(tables []string, blue, green map[string]int64, tolerance float64) gate {
+	g := gate{name: "row counts"}
+	var problems []string
+	largest, largestTable := 0.0, ""
+	for _, table := range tables {
+		b, bOK := blue[table]
+		gr, grOK := green[table]
+		if !bOK || !grOK {
+			problems = append(problems, table+" not counted")
+			continue
+		}
+		diff := math.Abs(float64(gr-b)) / math.Max(float64(b), 1) * 100
+		if diff > tolerance {
+			problems = append(problems, fmt.Sprintf("%s: blue %d, green %d (%.2f%%)", table, b, gr, diff))
+		}
+		if diff >= largest {
+			largest, largestTable = diff, table
+		}
+	}
+	if len(problems) > 0 {
+		g.detail = fmt.Sprintf("%s (max %g%%)", strings.Join(problems, ", "), tolerance)
+		return g
+	}
+	g.passed = true
+	g.detail = fmt.Sprintf("%d tables within %g%% of blue (largest difference %s: %.2f%%)", len(tables), tolerance, largestTable, largest)
+	return g
+}
+
+func engineVersionGate(actual, expected string) gate {
+	g := gate{name: "engine version", passed: actual == expected}
+	if g.passed {
+		g.detail = actual
+	} else {
+		g.detail = fmt.Sprintf("%s, expected %s", dash(actual), expected)
+	}
+	return g
+}
+
+// parametersGate compares the global variables; ON/OFF and TRUE/FALSE equal
+// 1/0, and case is ignored.
+func parametersGate(expected, actual map[string]string, err error) gate {
+	g := gate{name: "parameters"}
+	if err != nil {
+		g.detail = err.Error()
+		return g
+	}
+	var problems []string
+	for _, name := range sortedKeys(expected) {
+		if normalizeVariable(actual[name]) != normalizeVariable(expected[name]) {
+			problems = append(problems, fmt.Sprintf("%s is %s, expected %s", name, actual[name], expected[name]))
+		}
+	}
+	if len(problems) > 0 {
+		g.detail = strings.Join(problems, ", ")
+		return g
+	}
+	g.passed = true
+	g.detail = fmt.Sprintf("%d variables as expected", len(expected))
+	return g
+}
+
+func normalizeVariable(value string) string {
+	switch v := strings.ToUpper(strings.TrimSpace(value)); v {
+	case "ON", "TRUE":
+		return "1"
+	case "OFF", "FALSE":
+		return "0"
+	default:
+		return v
+	}
+}
+
+// grantsGate compares the privileges on blue and green.
+func grantsGate(blue, green map[string]bool) gate {
+	g := gate{name: "grants"}
+	var missing, extra []string
+	for _, grant := range sortedKeys(blue) {
+		if !green[grant] {
+			missing = append(missing, grant)
+		}
+	}
+	for _, grant := range sortedKeys(green) {
+		if !blue[grant] {
+			extra = append(extra, grant)
+		}
+	}
+	var problems []string
+	if len(missing) > 0 {
+		problems = append(problems, "missing on green: "+abbreviateList(missing, 3))
+	}
+	if len(extra) > 0 {
+		problems = append(problems, "only on green: "+abbreviateList(extra, 3))
+	}
+	if len(problems) > 0 {
+		g.detail = strings.Join(problems, "; ")
+		return g
+	}
+	users := map[string]bool{}
+	for grant := range blue {
+		user, _, _ := strings.Cut(grant, " ")
+		users[user] = true
+	}
+	g.passed = true
+	g.detail = fmt.Sprintf("%d privileges of %d users as on blue", len(blue), len(users))
+	return g
+}
+
+func abbreviateList(values []string, n int) string {
+	if len(values) <= n {
+		return strings.Join(values, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(values[:n], ", "), len(values)-n)
+}
+
+// latencyGate checks the median latency of the query's runs.
+func latencyGate(q queryCheck, latencies []time.Duration, err error) gate {
+	g := gate{name: "query latency"}
+	if err != nil {
+		g.detail = fmt.Sprintf("%s: %v", q.Name, err)
+		return g
+	}
+	sorted := append([]time.Duration(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	median := sorted[len(sorted)/2]
+	g.passed = median <= time.Duration(q.MaxLatency)
+	g.detail = fmt.Sprintf("%s: median %s of %d runs (max %s)", q.Name, median, len(sorted), time.Duration(q.MaxLatency))
+	return g
+}
+
+// validationResults are the results of a validation for the registry.
+func validationResults(gates []gate) map[string]float64 {
+	failed := 0
+	for _, g := range gates {
+		if !g.passed {
+			failed++
+		}
+	}
+	return map[string]float64{"checks": float64(len(gates)), "failedChecks": float64(failed)}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
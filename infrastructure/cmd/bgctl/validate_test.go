@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseGreenChecksExample(t *testing.T) {
+	data, err := os.ReadFile("green-checks.example.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := parseGreenChecks(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.RowCounts == nil || len(c.RowCounts.Tables) != 3 || c.EngineVersion == nil || len(c.Parameters) != 3 || c.Grants == nil {
+		t.Errorf("got %+v", c)
+	}
+	if len(c.Queries) != 2 || c.Queries[0].Runs != defaultQueryRuns || c.Queries[1].Runs != 10 || time.Duration(c.Queries[0].MaxLatency) != 50*time.Millisecond {
+		t.Errorf("queries: got %+v", c.Queries)
+	}
+}
+
+func TestParseGreenChecksErrors(t *testing.T) {
+	_, err := parseGreenChecks([]byte(`
+rowCounts:
+  tables: ["orders; DROP TABLE x"]
+  tolerance: 150
+parameters:
+  "read_only = 0": "1"
+queries:
+  - sql: DELETE FROM orders
+    maxLatency: 10ms
+  - name: locking
+    sql: SELECT * FROM orders FOR UPDATE
+`))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{
+		"rowCounts.tolerance must be between 0 and 100",
+		`invalid table name "orders; DROP TABLE x"`,
+		`invalid variable name "read_only = 0"`,
+		"queries[query 1].sql must be a SELECT statement",
+		"queries[locking].maxLatency must be positive",
+		"queries[locking].sql must not use INTO or FOR UPDATE",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not contain %q:\n%v", want, err)
+		}
+	}
+
+	if _, err := parseGreenChecks([]byte("{}")); err == nil || !strings.Contains(err.Error(), "no checks") {
+		t.Errorf("empty checks: got %v", err)
+	}
+}
+
+func TestRowCountGate(t *testing.T) {
+	tables := []string{"test_0001", "test_0002"}
+	blue := map[string]int64{"test_0001": 10000, "test_0002": 0}
+	if g := rowCountGate(tables, blue, map[string]int64{"test_0001": 10050, "test_0002": 0}, 1); !g.passed ||
+		g.detail != "2 tables within 1% of blue (largest difference test_0001: 0.50%)" {
+		t.Errorf("got %+v", g)
+	}
+	if g := rowCountGate(tables, blue, map[string]int64{"test_0001": 9000}, 1); g.passed ||
+		g.detail != "test_0001: blue 10000, green 9000 (10.00%), test_0002 not counted (max 1%)" {
+		t.Errorf("got %+v", g)
+	}
+	if got := countQuery(tables); got != "SELECT 'test_0001', COUNT(*) FROM `test_0001`\nUNION ALL SELECT 'test_0002', COUNT(*) FROM `test_0002`" {
+		t.Errorf("count query: %s", got)
+	}
+}
+
+func TestParametersGate(t *testing.T) {
+	expected := map[string]string{"binlog_format": "ROW", "innodb_file_per_table": "ON", "max_connections": "1000"}
+	if g := parametersGate(expected, map[string]string{"binlog_format": "row", "innodb_file_per_table": "1", "max_connections": "1000"}, nil); !g.passed {
+		t.Errorf("got %+v", g)
+	}
+	if g := parametersGate(expected, map[string]string{"binlog_format": "MIXED", "innodb_file_per_table": "1", "max_connections": "1000"}, nil); g.passed ||
+		g.detail != "binlog_format is MIXED, expected ROW" {
+		t.Errorf("got %+v", g)
+	}
+	if g := parametersGate(expected, nil, errors.New("Unknown system variable")); g.passed {
+		t.Errorf("got %+v", g)
+	}
+}
+
+func TestGrantsGate(t *testing.T) {
+	blueRows := [][]string{
+		{"'admin'@'%'", "SELECT", "*.*"},
+		{"'app'@'%'", "INSERT", "labdb.*"},
+		{"'rdsadmin'@'localhost'", "SUPER", "*.*"},
+		{"'mysql.sys'@'localhost'", "TRIGGER", "sys.sys_config"},
+	}
+	greenRows := [][]string{
+		{"'admin'@'%'", "SELECT", "*.*"},
+		{"'rdsadmin'@'localhost'", "SHUTDOWN", "*.*"},
+	}
+
+	blue, green := userGrants(blueRows, nil), userGrants(greenRows, nil)
+	if g := grantsGate(blue, green); g.passed || g.detail != "missing on green: app@% INSERT ON labdb.*" {
+		t.Errorf("got %+v", g)
+	}
+	if g := grantsGate(userGrants(blueRows, []string{"admin"}), userGrants(greenRows, []string{"admin"})); !g.passed ||
+		g.detail != "1 privileges of 1 users as on blue" {
+		t.Errorf("got %+v", g)
+	}
+}
+
+func TestLatencyGate(t *testing.T) {
+	q := queryCheck{Name: "recent rows", MaxLatency: yamlDuration(10 * time.Millisecond)}
+	latencies := []time.Duration{50 * time.Millisecond, 4 * time.Millisecond, 6 * time.Millisecond}
+	if g := latencyGate(q, latencies, nil); !g.passed || g.detail != "recent rows: median 6ms of 3 runs (max 10ms)" {
+		t.Errorf("got %+v", g)
+	}
+	q.MaxLatency = yamlDuration(5 * time.Millisecond)
+	if g := latencyGate(q, latencies, nil); g.passed {
+		t.Errorf("got %+v", g)
+	}
+}
+
+func TestEngineVersionGate(t *testing.T) {
+	if g := engineVersionGate("8.0.mysql_aurora.3.08.0", "8.0.mysql_aurora.3.08.0"); !g.passed {
+		t.Errorf("got %+v", g)
+	}
+	if g := engineVersionGate("8.0.mysql_aurora.3.05.2", "8.0.mysql_aurora.3.08.0"); g.passed ||
+		g.detail != "8.0.mysql_aurora.3.05.2, expected 8.0.mysql_aurora.3.08.0" {
+		t.Errorf("got %+v", g)
+	}
+}
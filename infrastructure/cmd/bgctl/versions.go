@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+
+	"aurora-bluegreen-lab/internal/bluegreen"
+)
+
+const versionsUsage = `Usage: bgctl versions [flags]
+
+Lists the Aurora MySQL versions the lab cluster can be upgraded to, from
+RDS's valid upgrade targets of its current engine version, and marks the
+ones a Blue/Green deployment can upgrade to: Aurora MySQL 2.10 and later
+(MySQL 5.7) and Aurora MySQL 3 (MySQL 8.0), from a current version that
+Blue/Green deployments support too.
+
+-set writes a target into the aurora stack's greenEngineVersion config, the
+default engine version of bgctl create and the major-upgrade scenario; a
+major version target also makes the stack create green parameter groups of
+its family. Run pulumi up in aurora/ afterwards. "latest" picks the newest
+target Blue/Green deployments support.
+
+  bgctl versions                       list the upgrade targets
+  bgctl versions -major                only major version upgrades
+  bgctl versions -set 8.0.mysql_aurora.3.08.0
+  bgctl versions -set latest
+
+Flags:
+`
+
+func versionsCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("versions", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), versionsUsage)
+		fs.PrintDefaults()
+	}
+	var lab labFlags
+	lab.register(fs)
+	cluster := fs.String("cluster", "", "Lab cluster (default: the aurora stack's clusterIdentifier output)")
+	engineVersion := fs.String("engine-version", "", "Engine version to list the upgrade targets of (default: the cluster's)")
+	major := fs.Bool("major", false, "List only major version upgrades")
+	set := fs.String("set", "", "Upgrade target to write into the aurora stack's greenEngineVersion config, or latest")
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	aurora, err := lab.reader().Outputs(ctx, "aurora")
+	if err != nil {
+		return err
+	}
+	clusterIdentifier, region := *cluster, lab.region
+	if clusterIdentifier == "" {
+		if clusterIdentifier = aurora.String("clusterIdentifier"); clusterIdentifier == "" {
+			return fmt.Errorf("the aurora stack has no clusterIdentifier output; pass -cluster")
+		}
+	}
+	if region == "" {
+		region = aurora.String("region")
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	if err != nil {
+		return fmt.Errorf("loading AWS configuration: %w", err)
+	}
+	client := bluegreen.New(cfg)
+
+	current := *engineVersion
+	if current == "" {
+		settings, err := client.ClusterSettings(ctx, clusterIdentifier)
+		if err != nil {
+			return err
+		}
+		current = settings.EngineVersion
+	}
+	all, err := client.UpgradeTargets(ctx, current)
+	if err != nil {
+		return err
+	}
+	targets := all
+	if *major {
+		targets = nil
+		for _, t := range all {
+			if t.Major {
+				targets = append(targets, t)
+			}
+		}
+	}
+
+	green := aurora.String("greenEngineVersion")
+	fmt.Printf("[INFO] %s runs %s", clusterIdentifier, current)
+	if !bluegreen.SupportsBlueGreen(current) {
+		fmt.Print("; Blue/Green deployments do not support it, upgrade in place first")
+	}
+	fmt.Println()
+	if len(targets) == 0 {
+		fmt.Println("[INFO] No upgrade targets")
+	} else {
+		renderVersions(os.Stdout, targets, green)
+	}
+	if *set == "" {
+		return nil
+	}
+
+	// -major only filters the list: -set latest means the newest of it
+	choices := all
+	if *major && *set == "latest" {
+		choices = targets
+	}
+	target, err := chooseTarget(choices, *set)
+	if err != nil {
+		return err
+	}
+	if err := lab.reader().SetConfig(ctx, "aurora", "greenEngineVersion", target.EngineVersion); err != nil {
+		return err
+	}
+	fmt.Printf("[SUCCESS] Set greenEngineVersion of the aurora stack %s to %s\n", lab.stackName, target.EngineVersion)
+	if target.Major {
+		fmt.Println("[INFO] Run pulumi up in aurora/ to create the green parameter groups of the new family, then bgctl create")
+	} else {
+		fmt.Println("[INFO] Run pulumi up in aurora/ to export it, then bgctl create")
+	}
+	return nil
+}
+
+// chooseTarget returns the upgrade target named by -set: a listed target
+// Blue/Green deployments support, or latest for the newest of them.
+func chooseTarget(targets []bluegreen.UpgradeTarget, version string) (bluegreen.UpgradeTarget, error) {
+	if version == "latest" {
+		for i := len(targets) - 1; i >= 0; i-- {
+			if targets[i].BlueGreen {
+				return targets[i], nil
+			}
+		}
+		return bluegreen.UpgradeTarget{}, fmt.Errorf("no upgrade target is supported by Blue/Green deployments")
+	}
+	for _, t := range targets {
+		if t.EngineVersion != version {
+			continue
+		}
+		if !t.BlueGreen {
+			return t, fmt.Errorf("Blue/Green deployments cannot upgrade to %s", version)
+		}
+		return t, nil
+	}
+	return bluegreen.UpgradeTarget{}, fmt.Errorf("%s is not an upgrade target of the cluster's engine version (see the list)", version)
+}
+
+// renderVersions prints the upgrade targets as a table; green is the aurora
+// stack's greenEngineVersion, marked with *.
+func renderVersions(w io.Writer, targets []bluegreen.UpgradeTarget, green string) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  VERSION\tUPGRADE\tBLUE/GREEN\tDESCRIPTION")
+	marked := false
+	for _, t := range targets {
+		mark := " "
+		if t.EngineVersion == green {
+			mark, marked = "*", true
+		}
+		upgrade := "minor"
+		if t.Major {
+			upgrade = "major"
+		}
+		if t.AutoUpgrade {
+			upgrade += ", auto"
+		}
+		blueGreen := "no"
+		if t.BlueGreen {
+			blueGreen = "yes"
+		}
+		fmt.Fprintf(tw, "%s %s\t%s\t%s\t%s\n", mark, t.EngineVersion, upgrade, blueGreen, dash(t.Description))
+	}
+	tw.Flush()
+	if marked {
+		fmt.Fprintln(w, "* the aurora stack's greenEngineVersion")
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"aurora-bluegreen-lab/internal/bluegreen"
+)
+
+func versionsTestTargets() []bluegreen.UpgradeTarget {
+	return []bluegreen.UpgradeTarget{
+		{EngineVersion: "5.7.mysql_aurora.2.11.6", Description: "Aurora MySQL 2.11.6", BlueGreen: true},
+		{EngineVersion: "5.7.mysql_aurora.2.12.1", Description: "Aurora MySQL 2.12.1", AutoUpgrade: true, BlueGreen: true},
+		{EngineVersion: "8.0.mysql_aurora.3.04.0", Major: true, BlueGreen: true},
+		{EngineVersion: "8.0.mysql_aurora.3.08.0", Major: true, BlueGreen: true},
+		{EngineVersion: "8.0.mysql_aurora.3.09.0", Major: true},
+	}
+}
+
+func TestChooseTarget(t *testing.T) {
+	targets := versionsTestTargets()
+	if got, err := chooseTarget(targets, "latest"); err != nil || got.EngineVersion != "8.0.mysql_aurora.3.08.0" {
+		t.Errorf("latest: got %v, %v", got.EngineVersion, err)
+	}
+	if got, err := chooseTarget(targets, "5.7.mysql_aurora.2.12.1"); err != nil || got.Major {
+		t.Errorf("listed minor target: got %+v, %v", got, err)
+	}
+	if _, err := chooseTarget(targets, "8.0.mysql_aurora.3.09.0"); err == nil || !strings.Contains(err.Error(), "cannot upgrade") {
+		t.Errorf("target without Blue/Green support: %v", err)
+	}
+	if _, err := chooseTarget(targets, "8.0.mysql_aurora.3.99.0"); err == nil {
+		t.Error("unlisted target: no error")
+	}
+	if _, err := chooseTarget(targets[4:], "latest"); err == nil {
+		t.Error("latest without Blue/Green targets: no error")
+	}
+}
+
+func TestRenderVersions(t *testing.T) {
+	var buf bytes.Buffer
+	renderVersions(&buf, versionsTestTargets(), "8.0.mysql_aurora.3.08.0")
+	out := buf.String()
+	for _, want := range []string{
+		"  5.7.mysql_aurora.2.12.1  minor, auto  yes         Aurora MySQL 2.12.1\n",
+		"* 8.0.mysql_aurora.3.08.0  major        yes         -\n",
+		"  8.0.mysql_aurora.3.09.0  major        no          -\n",
+		"* the aurora stack's greenEngineVersion\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+}
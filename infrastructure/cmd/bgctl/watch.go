@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+
+	"aurora-bluegreen-lab/internal/bluegreen"
+)
+
+const watchUsage = `Usage: bgctl watch [flags]
+
+Shows a live view of the lab cluster's Blue/Green deployments, the blue,
+green and old blue clusters with the role of each instance, and the latest
+replica lag and connection count of every instance from CloudWatch,
+refreshed until interrupted (Ctrl+C).
+
+  bgctl watch                 refresh every 5 seconds
+  bgctl watch -interval 15s
+  bgctl watch -once           print the view once, e.g. into a log
+
+Flags:
+`
+
+// clearScreen moves the cursor home and clears the terminal.
+const clearScreen = "\x1b[H\x1b[2J"
+
+// metricsWindow is how far back the latest CloudWatch datapoint of an
+// instance is looked for; the AWS/RDS metrics are published every minute.
+const metricsWindow = 5 * time.Minute
+
+func watchCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), watchUsage)
+		fs.PrintDefaults()
+	}
+	var lab labFlags
+	lab.register(fs)
+	cluster := fs.String("cluster", "", "Cluster to watch (default: the aurora stack's clusterIdentifier output)")
+	interval := fs.Duration("interval", 5*time.Second, "Refresh interval")
+	once := fs.Bool("once", false, "Print the view once instead of refreshing it")
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if *interval < time.Second {
+		return fmt.Errorf("-interval must be at least 1s, got %s", *interval)
+	}
+
+	clusterIdentifier, region := *cluster, lab.region
+	if clusterIdentifier == "" || region == "" {
+		aurora, err := lab.reader().Outputs(ctx, "aurora")
+		if err != nil {
+			return err
+		}
+		if clusterIdentifier == "" {
+			if clusterIdentifier = aurora.String("clusterIdentifier"); clusterIdentifier == "" {
+				return fmt.Errorf("the aurora stack has no clusterIdentifier output; pass -cluster")
+			}
+		}
+		if region == "" {
+			region = aurora.String("region")
+		}
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	if err != nil {
+		return fmt.Errorf("loading AWS configuration: %w", err)
+	}
+	client := bluegreen.New(cfg)
+	cw := cloudwatch.NewFromConfig(cfg)
+
+	// A terminal gets the view redrawn in place; anything else one view per
+	// refresh
+	redraw := false
+	if info, err := os.Stdout.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		redraw = !*once
+	}
+	for {
+		now := time.Now()
+		status, err := client.LabStatus(ctx, clusterIdentifier)
+		var metrics map[string]instanceMetrics
+		if err == nil {
+			metrics, err = fetchInstanceMetrics(ctx, cw, status, now)
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil && *once {
+			return err
+		}
+
+		var view bytes.Buffer
+		if redraw {
+			view.WriteString(clearScreen)
+		}
+		fmt.Fprintf(&view, "Aurora Blue/Green watch: %s (%s)   %s\n", clusterIdentifier, region, now.UTC().Format(time.RFC3339))
+		if err != nil {
+			// Keep watching through throttling and network errors
+			fmt.Fprintf(&view, "\n[ERROR] %v\n", err)
+		} else {
+			renderWatch(&view, status, metrics)
+		}
+		if !*once {
+			fmt.Fprintf(&view, "\nRefreshing every %s, Ctrl+C to quit\n", *interval)
+		}
+		os.Stdout.Write(view.Bytes())
+		if *once {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(*interval):
+		}
+	}
+}
+
+// instanceMetrics are the latest CloudWatch values of an instance; nil when
+// the instance has no datapoint in the metrics window.
+type instanceMetrics struct {
+	// replicaLag is AuroraReplicaLag in milliseconds, only published for readers
+	replicaLag *float64
+	// connections is DatabaseConnections
+	connections *float64
+}
+
+// fetchInstanceMetrics fetches the latest replica lag and connection count of
+// every instance of the status' clusters.
+func fetchInstanceMetrics(ctx context.Context, cw *cloudwatch.Client, status *bluegreen.LabStatus, now time.Time) (map[string]instanceMetrics, error) {
+	var queries []types.MetricDataQuery
+	instances := map[string]string{}
+	for _, cluster := range status.Clusters {
+		for _, instance := range cluster.Instances {
+			for _, metric := range []string{"AuroraReplicaLag", "DatabaseConnections"} {
+				id := fmt.Sprintf("m%d", len(queries))
+				instances[id] = instance.Identifier
+				queries = append(queries, types.MetricDataQuery{
+					Id:    aws.String(id),
+					Label: aws.String(metric),
+					MetricStat: &types.MetricStat{
+						Metric: &types.Metric{
+							Namespace:  aws.String("AWS/RDS"),
+							MetricName: aws.String(metric),
+							Dimensions: []types.Dimension{
+								{Name: aws.String("DBInstanceIdentifier"), Value: aws.String(instance.Identifier)},
+							},
+						},
+						Period: aws.Int32(60),
+						Stat:   aws.String("Maximum"),
+					},
+				})
+			}
+		}
+	}
+	metrics := map[string]instanceMetrics{}
+	if len(queries) == 0 {
+		return metrics, nil
+	}
+
+	// GetMetricData takes up to 500 queries, far more than the lab's instances
+	out, err := cw.GetMetricData(ctx, &cloudwatch.GetMetricDataInput{
+		MetricDataQueries: queries,
+		StartTime:         aws.Time(now.Add(-metricsWindow)),
+		EndTime:           aws.Time(now),
+		ScanBy:            types.ScanByTimestampDescending,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fetching instance metrics: %w", err)
+	}
+	for _, r := range out.MetricDataResults {
+		if len(r.Values) == 0 {
+			continue
+		}
+		instance := instances[aws.ToString(r.Id)]
+		m := metrics[instance]
+		latest := r.Values[0]
+		if aws.ToString(r.Label) == "AuroraReplicaLag" {
+			m.replicaLag = &latest
+		} else {
+			m.connections = &latest
+		}
+		metrics[instance] = m
+	}
+	return metrics, nil
+}
+
+// renderWatch writes the deployments and the clusters' instances.
+func renderWatch(w io.Writer, status *bluegreen.LabStatus, metrics map[string]instanceMetrics) {
+	if len(status.Deployments) == 0 {
+		fmt.Fprintf(w, "\nNo Blue/Green deployment\n")
+	}
+	for _, d := range status.Deployments {
+		fmt.Fprintf(w, "\nBlue/Green deployment %s (%s): %s\n", d.Name, d.ID, d.Status)
+		if d.StatusDetails != "" {
+			fmt.Fprintf(w, "  %s\n", d.StatusDetails)
+		}
+		if len(d.Tasks) > 0 {
+			tasks := make([]string, len(d.Tasks))
+			for i, task := range d.Tasks {
+				tasks[i] = fmt.Sprintf("%s %s", task.Name, task.Status)
+			}
+			fmt.Fprintf(w, "  Tasks: %s\n", strings.Join(tasks, ", "))
+		}
+		for _, member := range d.Switchover {
+			fmt.Fprintf(w, "  %s -> %s: %s\n", member.Source, member.Target, member.Status)
+		}
+	}
+
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CLUSTER\tROLE\tSTATUS\tENGINE\tINSTANCE\tINSTANCE ROLE\tINSTANCE STATUS\tCLASS\tLAG (ms)\tCONNECTIONS")
+	for _, cluster := range status.Clusters {
+		role := cluster.Role
+		if role == "" {
+			role = "-"
+		}
+		if len(cluster.Instances) == 0 {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t-\t-\t-\t-\t-\t-\n", cluster.Identifier, role, cluster.Status, cluster.EngineVersion)
+		}
+		for i, instance := range cluster.Instances {
+			// The cluster columns only on its first instance
+			columns := fmt.Sprintf("%s\t%s\t%s\t%s", cluster.Identifier, role, cluster.Status, cluster.EngineVersion)
+			if i > 0 {
+				columns = "\t\t\t"
+			}
+			instanceRole := "reader"
+			if instance.Writer {
+				instanceRole = "writer"
+			}
+			m := metrics[instance.Identifier]
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", columns, instance.Identifier, instanceRole,
+				instance.Status, instance.Class, formatMetric(m.replicaLag, "%.0f"), formatMetric(m.connections, "%.0f"))
+		}
+	}
+	tw.Flush()
+}
+
+func formatMetric(value *float64, format string) string {
+	if value == nil {
+		return "-"
+	}
+	return fmt.Sprintf(format, *value)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"aurora-bluegreen-lab/internal/bluegreen"
+)
+
+func TestRenderWatch(t *testing.T) {
+	lag, connections, writerConnections := 12.0, 40.0, 95.0
+	status := &bluegreen.LabStatus{
+		Deployments: []bluegreen.DeploymentStatus{{
+			Deployment: bluegreen.Deployment{ID: "bgd-abc", Name: "lab-upgrade", Status: bluegreen.StatusSwitchoverInProgress},
+			Tasks:      []bluegreen.Task{{Name: "CREATING_READ_REPLICA_OF_SOURCE", Status: "COMPLETED"}},
+			Switchover: []bluegreen.MemberSwitchover{{Source: "lab", Target: "lab-green-x1", Status: "SWITCHOVER_IN_PROGRESS"}},
+		}},
+		Clusters: []bluegreen.ClusterStatus{
+			{Identifier: "lab", Status: "available", EngineVersion: "8.0.mysql_aurora.3.05.2", Role: bluegreen.RoleBlue, Instances: []bluegreen.InstanceStatus{
+				{Identifier: "lab-writer", Status: "available", Class: "db.r6g.large", Writer: true},
+				{Identifier: "lab-reader", Status: "available", Class: "db.r6g.large"},
+			}},
+			{Identifier: "lab-green-x1", Status: "available", EngineVersion: "8.0.mysql_aurora.3.08.0", Role: bluegreen.RoleGreen},
+		},
+	}
+	metrics := map[string]instanceMetrics{
+		"lab-writer": {connections: &writerConnections},
+		"lab-reader": {replicaLag: &lag, connections: &connections},
+	}
+
+	var b strings.Builder
+	renderWatch(&b, status, metrics)
+	view := b.String()
+	for _, want := range []string{
+		"Blue/Green deployment lab-upgrade (bgd-abc): SWITCHOVER_IN_PROGRESS",
+		"Tasks: CREATING_READ_REPLICA_OF_SOURCE COMPLETED",
+		"lab -> lab-green-x1: SWITCHOVER_IN_PROGRESS",
+	} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view is missing %q:\n%s", want, view)
+		}
+	}
+
+	lines := strings.Split(view, "\n")
+	for _, row := range [][]string{
+		{"lab", "blue", "available", "8.0.mysql_aurora.3.05.2", "lab-writer", "writer", "available", "db.r6g.large", "-", "95"},
+		{"lab-reader", "reader", "available", "db.r6g.large", "12", "40"},
+		{"lab-green-x1", "green", "available", "8.0.mysql_aurora.3.08.0", "-", "-", "-", "-", "-", "-"},
+	} {
+		found := false
+		for _, line := range lines {
+			if strings.Join(strings.Fields(line), " ") == strings.Join(row, " ") {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("view has no row %v:\n%s", row, view)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+// Command lab-bluegreen-events is the AWS Lambda function of the monitoring
+// stack that records RDS Blue/Green deployment events. EventBridge invokes it
+// with every "RDS Blue Green Deployment Event"; it stores the event with the
+// millisecond timestamp RDS gave it and the run ID the deployment is tagged
+// with (internal/runid) in a DynamoDB table and publishes it as CloudWatch
+// metrics, a server-side timeline that lab-report merges with the simulator's
+// client-side measurements of the same run (-events-table).
+//
+// It runs on the provided.al2023 runtime as the bootstrap executable (the
+// monitoring stack builds and uploads it) and reads its settings from the
+// environment:
+//
+//	TABLE_NAME         DynamoDB table of the events (see bluegreen.AttrDeploymentID)
+//	METRICS_NAMESPACE  CloudWatch namespace of the metrics; empty publishes none
+//	RETENTION_DAYS     days after which DynamoDB expires the events
+//
+// Metrics, all with the event's time:
+//
+//	Events              1 per event, dimensions DeploymentId and Event
+//	SwitchoverDuration  seconds from switchover-started to switchover-completed,
+//	                    dimension DeploymentId
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"aurora-bluegreen-lab/internal/bluegreen"
+	"aurora-bluegreen-lab/internal/golambda"
+)
+
+// recorder stores and publishes Blue/Green events.
+type recorder struct {
+	dynamodb   *dynamodb.Client
+	cloudwatch *cloudwatch.Client
+	bluegreen  *bluegreen.Client
+	table      string
+	namespace  string
+	retention  time.Duration
+	// runIDs caches the run IDs of the deployments across the invocations
+	// of a warm function; a deployment's tags do not change
+	runIDs map[string]string
+}
+
+// result is the response of an invocation.
+type result struct {
+	DeploymentID string `json:"deploymentId"`
+	Event        string `json:"event"`
+	EventTime    string `json:"eventTime"`
+	RunID        string `json:"runId,omitempty"`
+	// SwitchoverSeconds is set for switchover-completed events whose
+	// switchover-started event was recorded
+	SwitchoverSeconds *float64 `json:"switchoverSeconds,omitempty"`
+}
+
+func main() {
+	r, err := newRecorder(context.Background())
+	if err == nil {
+		err = golambda.Serve(func(ctx context.Context, event json.RawMessage) (interface{}, error) {
+			return r.record(ctx, event, time.Now().UTC())
+		})
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func newRecorder(ctx context.Context) (*recorder, error) {
+	r := &recorder{
+		table:     os.Getenv("TABLE_NAME"),
+		namespace: os.Getenv("METRICS_NAMESPACE"),
+		runIDs:    map[string]string{},
+	}
+	if r.table == "" {
+		return nil, fmt.Errorf("TABLE_NAME must be set")
+	}
+	days, err := strconv.Atoi(os.Getenv("RETENTION_DAYS"))
+	if err != nil || days < 1 {
+		return nil, fmt.Errorf("RETENTION_DAYS must be a positive number of days, got %q", os.Getenv("RETENTION_DAYS"))
+	}
+	r.retention = time.Duration(days) * 24 * time.Hour
+
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading AWS configuration: %w", err)
+	}
+	r.dynamodb = dynamodb.NewFromConfig(cfg)
+	r.cloudwatch = cloudwatch.NewFromConfig(cfg)
+	r.bluegreen = bluegreen.New(cfg)
+	return r, nil
+}
+
+// record stores the event and publishes its metrics. EventBridge delivers
+// events at least once; a redelivered event overwrites its item.
+func (r *recorder) record(ctx context.Context, raw json.RawMessage, now time.Time) (*result, error) {
+	e, err := bluegreen.ParseEventBridgeEvent(raw)
+	if err != nil {
+		return nil, err
+	}
+	res := &result{DeploymentID: e.DeploymentID, Event: e.Name, EventTime: e.Time.Format(bluegreen.EventTimeFormat)}
+	res.RunID = r.runID(ctx, e.DeploymentID)
+
+	_, err = r.dynamodb.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(r.table),
+		Item:      eventItem(e, res.RunID, now.Add(r.retention)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("storing event %s of %s: %w", e.EventID, e.DeploymentID, err)
+	}
+	fmt.Printf("[INFO] Recorded %s (%s) of %s at %s (run %s)\n", e.Name, e.EventID, e.DeploymentID, res.EventTime, dash(res.RunID))
+
+	if e.Name == bluegreen.EventSwitchoverCompleted {
+		started, err := r.switchoverStarted(ctx, e)
+		if err != nil {
+			return nil, err
+		}
+		if !started.IsZero() {
+			seconds := e.Time.Sub(started).Seconds()
+			res.SwitchoverSeconds = &seconds
+		}
+	}
+
+	if r.namespace == "" {
+		return res, nil
+	}
+	_, err = r.cloudwatch.PutMetricData(ctx, &cloudwatch.PutMetricDataInput{
+		Namespace:  aws.String(r.namespace),
+		MetricData: eventMetrics(e, res.SwitchoverSeconds),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("publishing metrics of event %s of %s: %w", e.EventID, e.DeploymentID, err)
+	}
+	return res, nil
+}
+
+// runID returns the run ID the deployment is tagged with, or that an earlier
+// event of the deployment was stored with once the deployment is gone (its
+// deployment-deleted event); empty when it has none. The event is recorded
+// without a run ID when neither can be read.
+func (r *recorder) runID(ctx context.Context, deploymentID string) string {
+	if id, ok := r.runIDs[deploymentID]; ok {
+		return id
+	}
+	d, err := r.bluegreen.Describe(ctx, deploymentID)
+	if err == nil {
+		r.runIDs[deploymentID] = d.RunID
+		return d.RunID
+	}
+	id, storedErr := r.storedRunID(ctx, deploymentID)
+	if storedErr != nil {
+		fmt.Printf("[WARNING] Recording the event of %s without a run ID: %v; %v\n", deploymentID, err, storedErr)
+		return ""
+	}
+	return id
+}
+
+// storedRunID returns the run ID of the latest event of the deployment stored
+// with one; empty when none was.
+func (r *recorder) storedRunID(ctx context.Context, deploymentID string) (string, error) {
+	out, err := r.dynamodb.Query(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(r.table),
+		KeyConditionExpression: aws.String("#deployment = :deployment"),
+		FilterExpression:       aws.String("attribute_exists(#run)"),
+		ExpressionAttributeNames: map[string]string{
+			"#deployment": bluegreen.AttrDeploymentID,
+			"#run":        bluegreen.AttrRunID,
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":deployment": &types.AttributeValueMemberS{Value: deploymentID},
+		},
+		// Newest first; a deployment has few events, so one page holds them
+		ScanIndexForward: aws.Bool(false),
+	})
+	if err != nil {
+		return "", fmt.Errorf("querying the run ID of %s: %w", deploymentID, err)
+	}
+	for _, item := range out.Items {
+		if value, ok := item[bluegreen.AttrRunID].(*types.AttributeValueMemberS); ok {
+			return value.Value, nil
+		}
+	}
+	return "", nil
+}
+
+// switchoverStarted returns the time of the latest switchover-started event
+// of the deployment before e, or the zero time when none was recorded.
+func (r *recorder) switchoverStarted(ctx context.Context, e *bluegreen.DeploymentEvent) (time.Time, error) {
+	out, err := r.dynamodb.Query(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(r.table),
+		KeyConditionExpression: aws.String("#deployment = :deployment AND #key < :key"),
+		FilterExpression:       aws.String("#event = :started"),
+		ExpressionAttributeNames: map[string]string{
+			"#deployment": bluegreen.AttrDeploymentID,
+			"#key":        bluegreen.AttrEventKey,
+			"#event":      bluegreen.AttrEvent,
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":deployment": &types.AttributeValueMemberS{Value: e.DeploymentID},
+			":key":        &types.AttributeValueMemberS{Value: e.Key()},
+			":started":    &types.AttributeValueMemberS{Value: bluegreen.EventSwitchoverStarted},
+		},
+		// Newest first; a deployment has few events, so one page holds them
+		ScanIndexForward: aws.Bool(false),
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("querying the switchover start of %s: %w", e.DeploymentID, err)
+	}
+	for _, item := range out.Items {
+		if value, ok := item[bluegreen.AttrEventTime].(*types.AttributeValueMemberS); ok {
+			return time.Parse(bluegreen.EventTimeFormat, value.Value)
+		}
+	}
+	return time.Time{}, nil
+}
+
+// eventItem returns the table item of the event of the run runID (none when
+// empty), expiring at expires.
+func eventItem(e *bluegreen.DeploymentEvent, runID string, expires time.Time) map[string]types.AttributeValue {
+	item := map[string]types.AttributeValue{
+		bluegreen.AttrDeploymentID: &types.AttributeValueMemberS{Value: e.DeploymentID},
+		bluegreen.AttrEventKey:     &types.AttributeValueMemberS{Value: e.Key()},
+		bluegreen.AttrEventTime:    &types.AttributeValueMemberS{Value: e.Time.Format(bluegreen.EventTimeFormat)},
+		bluegreen.AttrEventID:      &types.AttributeValueMemberS{Value: e.EventID},
+		bluegreen.AttrEvent:        &types.AttributeValueMemberS{Value: e.Name},
+		bluegreen.AttrMessage:      &types.AttributeValueMemberS{Value: e.Message},
+		bluegreen.AttrExpiresAt:    &types.AttributeValueMemberN{Value: strconv.FormatInt(expires.Unix(), 10)},
+	}
+	if runID != "" {
+		item[bluegreen.AttrRunID] = &types.AttributeValueMemberS{Value: runID}
+	}
+	return item
+}
+
+func dash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// eventMetrics returns the metrics of the event: its count and, with
+// switchoverSeconds, the switchover's duration.
+func eventMetrics(e *bluegreen.DeploymentEvent, switchoverSeconds *float64) []cwtypes.MetricDatum {
+	deployment := cwtypes.Dimension{Name: aws.String("DeploymentId"), Value: aws.String(e.DeploymentID)}
+	metrics := []cwtypes.MetricDatum{{
+		MetricName: aws.String("Events"),
+		Dimensions: []cwtypes.Dimension{deployment, {Name: aws.String("Event"), Value: aws.String(e.Name)}},
+		Timestamp:  aws.Time(e.Time),
+		Value:      aws.Float64(1),
+		Unit:       cwtypes.StandardUnitCount,
+	}}
+	if switchoverSeconds != nil {
+		metrics = append(metrics, cwtypes.MetricDatum{
+			MetricName: aws.String("SwitchoverDuration"),
+			Dimensions: []cwtypes.Dimension{deployment},
+			Timestamp:  aws.Time(e.Time),
+			Value:      switchoverSeconds,
+			Unit:       cwtypes.StandardUnitSeconds,
+		})
+	}
+	return metrics
+}
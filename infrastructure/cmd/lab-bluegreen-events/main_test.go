@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"aurora-bluegreen-lab/internal/bluegreen"
+)
+
+func testEvent() *bluegreen.DeploymentEvent {
+	return &bluegreen.DeploymentEvent{
+		DeploymentID: "bgd-abc123",
+		Time:         time.Date(2026, 1, 2, 15, 4, 41, 500000000, time.UTC),
+		EventID:      "RDS-EVENT-0248",
+		Name:         bluegreen.EventSwitchoverCompleted,
+		Message:      "Switchover completed on blue/green deployment.",
+	}
+}
+
+func TestEventItem(t *testing.T) {
+	item := eventItem(testEvent(), "minor-upgrade-20260102-150405", time.Date(2026, 1, 16, 15, 0, 0, 0, time.UTC))
+	for key, want := range map[string]string{
+		"DeploymentId": "bgd-abc123",
+		"EventKey":     "2026-01-02T15:04:41.500Z#RDS-EVENT-0248",
+		"EventTime":    "2026-01-02T15:04:41.500Z",
+		"EventId":      "RDS-EVENT-0248",
+		"Event":        "switchover-completed",
+		"RunId":        "minor-upgrade-20260102-150405",
+	} {
+		if got, ok := item[key].(*types.AttributeValueMemberS); !ok || got.Value != want {
+			t.Errorf("%s = %v, want %q", key, item[key], want)
+		}
+	}
+	if got, ok := item["ExpiresAt"].(*types.AttributeValueMemberN); !ok || got.Value != "1768575600" {
+		t.Errorf("ExpiresAt = %v", item["ExpiresAt"])
+	}
+	if item := eventItem(testEvent(), "", time.Now()); item["RunId"] != nil {
+		t.Errorf("an event without a run ID got RunId %v", item["RunId"])
+	}
+}
+
+func TestEventMetrics(t *testing.T) {
+	if metrics := eventMetrics(testEvent(), nil); len(metrics) != 1 || aws.ToString(metrics[0].MetricName) != "Events" {
+		t.Errorf("got %+v, want only Events", metrics)
+	}
+
+	seconds := 36.5
+	metrics := eventMetrics(testEvent(), &seconds)
+	if len(metrics) != 2 {
+		t.Fatalf("got %d metrics, want 2", len(metrics))
+	}
+	duration := metrics[1]
+	if aws.ToString(duration.MetricName) != "SwitchoverDuration" || aws.ToFloat64(duration.Value) != 36.5 ||
+		!aws.ToTime(duration.Timestamp).Equal(testEvent().Time) || len(duration.Dimensions) != 1 {
+		t.Errorf("got %+v", duration)
+	}
+}
@@ -0,0 +1,515 @@
+// Command lab-deploy stands up (or tears down) all lab stacks in dependency
+// order using the Pulumi Automation API:
+//
+//	vpc -> aurora -> registry -> ec2 -> monitoring -> ops -> scheduler -> dms -> fis -> budget -> access
+//
+// Stack references between the components are wired automatically and the
+// outputs of every stack are printed as a single consolidated summary. The
+// outputs are also written to lab-outputs.json and lab-outputs.env
+// (internal/stacks.File), which bgctl and lab-scenario read with
+// -outputs-file and shell scripts and the simulator container can source.
+//
+// Before a stack is updated, its preview is checked against the lab
+// guardrails (internal/guardrails); any violation stops the deployment. The
+// preview also yields the stack's estimated monthly cost (internal/cost),
+// and the summary adds the estimates of all stacks up.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optdestroy"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optpreview"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+
+	"aurora-bluegreen-lab/internal/guardrails"
+	"aurora-bluegreen-lab/internal/stacks"
+)
+
+// labStack describes one Pulumi project of the lab and how to configure it.
+type labStack struct {
+	// dir is the component directory relative to the infrastructure root
+	dir string
+	// project is the Pulumi project name declared in the component's Pulumi.yaml
+	project string
+	// enabled reports whether an optional stack is deployed; nil for the
+	// stacks that always are
+	enabled func(o options) bool
+	// config returns the stack configuration derived from the deployer options
+	config func(o options, refs stackRefs) auto.ConfigMap
+}
+
+// options holds the command line flags.
+type options struct {
+	infraDir       string
+	stackName      string
+	org            string
+	region         string
+	projectName    string
+	owner          string
+	runId          string
+	vpcCidr        string
+	sshCidr        string
+	masterPassword string
+	engineVersion  string
+	instanceClass  string
+	keyName        string
+	instanceType   string
+	privateSim     bool
+	simulatorImage bool
+	alarmEmail     string
+	monitoring     bool
+	ops            bool
+	scheduler      bool
+	stopCluster    bool
+	dms            bool
+	fis            bool
+	budget         bool
+	budgetLimit    string
+	budgetEmails   []string
+	access         bool
+	outputsFile    string
+	destroy        bool
+	// guardrail overrides
+	allowPublicSsh  bool
+	maxInstanceSize string
+}
+
+// stackRefs holds the fully qualified names used for Pulumi stack references.
+type stackRefs struct {
+	vpc      string
+	aurora   string
+	registry string
+	ec2      string
+}
+
+// labStacks lists all components in deployment order.
+var labStacks = []labStack{
+	{
+		dir:     "vpc",
+		project: "aurora-bluegreen-vpc",
+		config: func(o options, _ stackRefs) auto.ConfigMap {
+			cfg := auto.ConfigMap{}
+			setIfNotEmpty(cfg, "vpcCidr", o.vpcCidr, false)
+			setIfNotEmpty(cfg, "sshCidr", o.sshCidr, false)
+			if o.privateSim {
+				cfg["natGateway"] = auto.ConfigValue{Value: "true"}
+			}
+			return cfg
+		},
+	},
+	{
+		dir:     "aurora",
+		project: "aurora-bluegreen-aurora",
+		config: func(o options, refs stackRefs) auto.ConfigMap {
+			cfg := auto.ConfigMap{"vpcStackName": {Value: refs.vpc}}
+			setIfNotEmpty(cfg, "masterPassword", o.masterPassword, true)
+			setIfNotEmpty(cfg, "engineVersion", o.engineVersion, false)
+			setIfNotEmpty(cfg, "instanceClass", o.instanceClass, false)
+			return cfg
+		},
+	},
+	{
+		dir:     "registry",
+		project: "aurora-bluegreen-registry",
+		enabled: func(o options) bool { return o.simulatorImage },
+		config: func(_ options, _ stackRefs) auto.ConfigMap {
+			return auto.ConfigMap{}
+		},
+	},
+	{
+		dir:     "ec2",
+		project: "aurora-bluegreen-ec2",
+		config: func(o options, refs stackRefs) auto.ConfigMap {
+			cfg := auto.ConfigMap{
+				"vpcStackName":    {Value: refs.vpc},
+				"auroraStackName": {Value: refs.aurora},
+			}
+			setIfNotEmpty(cfg, "keyName", o.keyName, false)
+			setIfNotEmpty(cfg, "instanceType", o.instanceType, false)
+			if o.privateSim {
+				cfg["privateSimulator"] = auto.ConfigValue{Value: "true"}
+			}
+			// The image runs as the simulator service, which reads the
+			// credentials the stack stores in Secrets Manager
+			if o.simulatorImage {
+				cfg["registryStackName"] = auto.ConfigValue{Value: refs.registry}
+				setIfNotEmpty(cfg, "dbPassword", o.masterPassword, true)
+			}
+			return cfg
+		},
+	},
+	{
+		dir:     "monitoring",
+		project: "aurora-bluegreen-monitoring",
+		enabled: func(o options) bool { return o.monitoring },
+		config: func(o options, refs stackRefs) auto.ConfigMap {
+			cfg := auto.ConfigMap{
+				"auroraStackName": {Value: refs.aurora},
+				"ec2StackName":    {Value: refs.ec2},
+			}
+			setIfNotEmpty(cfg, "alarmEmail", o.alarmEmail, false)
+			return cfg
+		},
+	},
+	{
+		dir:     "ops",
+		project: "aurora-bluegreen-ops",
+		enabled: func(o options) bool { return o.ops },
+		config: func(_ options, refs stackRefs) auto.ConfigMap {
+			return auto.ConfigMap{"auroraStackName": {Value: refs.aurora}}
+		},
+	},
+	{
+		dir:     "scheduler",
+		project: "aurora-bluegreen-scheduler",
+		enabled: func(o options) bool { return o.scheduler },
+		config: func(o options, refs stackRefs) auto.ConfigMap {
+			return auto.ConfigMap{
+				"ec2StackName":    {Value: refs.ec2},
+				"auroraStackName": {Value: refs.aurora},
+				"stopCluster":     {Value: strconv.FormatBool(o.stopCluster)},
+			}
+		},
+	},
+	{
+		dir:     "dms",
+		project: "aurora-bluegreen-dms",
+		enabled: func(o options) bool { return o.dms },
+		config: func(o options, refs stackRefs) auto.ConfigMap {
+			cfg := auto.ConfigMap{
+				"vpcStackName":    {Value: refs.vpc},
+				"auroraStackName": {Value: refs.aurora},
+			}
+			// The source endpoint connects as the master user
+			setIfNotEmpty(cfg, "dbPassword", o.masterPassword, true)
+			return cfg
+		},
+	},
+	{
+		dir:     "fis",
+		project: "aurora-bluegreen-fis",
+		enabled: func(o options) bool { return o.fis },
+		config: func(_ options, refs stackRefs) auto.ConfigMap {
+			return auto.ConfigMap{"auroraStackName": {Value: refs.aurora}}
+		},
+	},
+	{
+		dir:     "budget",
+		project: "aurora-bluegreen-budget",
+		enabled: func(o options) bool { return o.budget },
+		config: func(o options, _ stackRefs) auto.ConfigMap {
+			cfg := auto.ConfigMap{}
+			setIfNotEmpty(cfg, "monthlyLimitUsd", o.budgetLimit, false)
+			if len(o.budgetEmails) > 0 {
+				emails, _ := json.Marshal(o.budgetEmails)
+				cfg["alertEmails"] = auto.ConfigValue{Value: string(emails)}
+			}
+			return cfg
+		},
+	},
+	{
+		dir:     "access",
+		project: "aurora-bluegreen-access",
+		enabled: func(o options) bool { return o.access },
+		config: func(_ options, refs stackRefs) auto.ConfigMap {
+			return auto.ConfigMap{"vpcStackName": {Value: refs.vpc}}
+		},
+	},
+}
+
+func main() {
+	var o options
+	flag.StringVar(&o.infraDir, "infra-dir", ".", "Path to the infrastructure directory containing the stack projects")
+	flag.StringVar(&o.stackName, "stack", "dev", "Pulumi stack name used for every component")
+	flag.StringVar(&o.org, "org", "", "Pulumi organization for stack references (default: output of 'pulumi whoami')")
+	flag.StringVar(&o.region, "region", "us-east-1", "AWS region")
+	flag.StringVar(&o.projectName, "project-name", "aurora-bluegreen-lab", "Project name used for resource naming")
+	flag.StringVar(&o.owner, "owner", "", "Owner tag of every resource, for cost attribution")
+	flag.StringVar(&o.runId, "run-id", "", "RunId tag of every resource, e.g. the experiment run")
+	flag.StringVar(&o.vpcCidr, "vpc-cidr", "", "CIDR block for the VPC (default: stack default)")
+	flag.StringVar(&o.sshCidr, "ssh-cidr", "", "CIDR block allowed to SSH to the simulator host, e.g. your-ip/32 (default: stack default 0.0.0.0/0)")
+	flag.StringVar(&o.masterPassword, "master-password", os.Getenv("AURORA_MASTER_PASSWORD"), "Aurora master password (default: $AURORA_MASTER_PASSWORD)")
+	flag.StringVar(&o.engineVersion, "engine-version", "", "Aurora MySQL engine version (default: stack default)")
+	flag.StringVar(&o.instanceClass, "instance-class", "", "Aurora instance class (default: stack default)")
+	flag.StringVar(&o.keyName, "key-name", "", "EC2 key pair name for the workload simulator host")
+	flag.StringVar(&o.instanceType, "instance-type", "", "EC2 instance type (default: stack default)")
+	flag.BoolVar(&o.privateSim, "private-simulator", false, "Launch the simulator without a public IP, behind a NAT gateway in the VPC stack")
+	flag.BoolVar(&o.simulatorImage, "simulator-image", false, "Also deploy the registry stack and run the simulator as its container image (needs a local Docker)")
+	flag.StringVar(&o.alarmEmail, "alarm-email", "", "Email address for monitoring alarm notifications")
+	flag.BoolVar(&o.monitoring, "monitoring", false, "Also deploy the monitoring stack")
+	flag.BoolVar(&o.ops, "ops", false, "Also deploy the ops stack (scheduled snapshots)")
+	flag.BoolVar(&o.scheduler, "scheduler", false, "Also deploy the scheduler stack (stops the lab outside working hours)")
+	flag.BoolVar(&o.stopCluster, "stop-cluster", false, "With -scheduler, also stop the Aurora cluster outside working hours")
+	flag.BoolVar(&o.dms, "dms", false, "Also deploy the dms stack (DMS replication of the cluster to S3, an external integration)")
+	flag.BoolVar(&o.fis, "fis", false, "Also deploy the fis stack (AWS FIS experiment templates for bgctl chaos experiment)")
+	flag.BoolVar(&o.budget, "budget", false, "Also deploy the budget stack (AWS Budget of the lab's Project tag with alerts)")
+	flag.StringVar(&o.budgetLimit, "budget-limit", "", "With -budget, the monthly budget in USD (default: stack default 100)")
+	flag.Func("budget-email", "With -budget, an email address for the budget alerts (repeatable)", func(email string) error {
+		o.budgetEmails = append(o.budgetEmails, email)
+		return nil
+	})
+	flag.BoolVar(&o.access, "access", false, "Also deploy the access stack (EC2 Instance Connect Endpoint for SSH without public IPs)")
+	flag.StringVar(&o.outputsFile, "outputs-file", "lab-outputs.json", "File the outputs of the deployed stacks are written to as JSON, and as KEY=value lines next to it with the extension .env (empty: not written)")
+	flag.BoolVar(&o.destroy, "destroy", false, "Destroy all stacks in reverse dependency order")
+	flag.BoolVar(&o.allowPublicSsh, "allow-public-ssh", false, "Allow SSH open to 0.0.0.0/0 despite the no-public-ssh guardrail")
+	flag.StringVar(&o.maxInstanceSize, "max-instance-size", guardrails.DefaultMaxInstanceSize, "Largest EC2/RDS instance size allowed by the instance-size-ceiling guardrail")
+	flag.Parse()
+
+	if err := run(context.Background(), o); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, o options) error {
+	infraDir, err := filepath.Abs(o.infraDir)
+	if err != nil {
+		return err
+	}
+
+	var selected []labStack
+	for _, s := range labStacks {
+		if s.enabled != nil && !s.enabled(o) {
+			continue
+		}
+		selected = append(selected, s)
+	}
+
+	// Select or create every stack up front so configuration problems are
+	// reported before any resources are touched
+	stacks := make([]auto.Stack, len(selected))
+	for i, s := range selected {
+		workDir := filepath.Join(infraDir, s.dir)
+		if o.org == "" {
+			ws, err := auto.NewLocalWorkspace(ctx, auto.WorkDir(workDir))
+			if err != nil {
+				return fmt.Errorf("creating workspace for %s: %w", s.dir, err)
+			}
+			if o.org, err = ws.WhoAmI(ctx); err != nil {
+				return fmt.Errorf("determining Pulumi organization (are you logged in?): %w", err)
+			}
+		}
+
+		stackName := auto.FullyQualifiedStackName(o.org, s.project, o.stackName)
+		stack, err := auto.UpsertStackLocalSource(ctx, stackName, workDir)
+		if err != nil {
+			return fmt.Errorf("selecting stack %s: %w", stackName, err)
+		}
+		stacks[i] = stack
+	}
+
+	refs := stackRefs{
+		vpc:      auto.FullyQualifiedStackName(o.org, "aurora-bluegreen-vpc", o.stackName),
+		aurora:   auto.FullyQualifiedStackName(o.org, "aurora-bluegreen-aurora", o.stackName),
+		registry: auto.FullyQualifiedStackName(o.org, "aurora-bluegreen-registry", o.stackName),
+		ec2:      auto.FullyQualifiedStackName(o.org, "aurora-bluegreen-ec2", o.stackName),
+	}
+
+	if o.destroy {
+		for i := len(stacks) - 1; i >= 0; i-- {
+			fmt.Printf("[INFO] Destroying %s\n", stacks[i].Name())
+			if _, err := stacks[i].Destroy(ctx, optdestroy.ProgressStreams(os.Stdout)); err != nil {
+				return fmt.Errorf("destroying %s: %w", stacks[i].Name(), err)
+			}
+			fmt.Printf("[SUCCESS] %s destroyed\n", stacks[i].Name())
+		}
+		return nil
+	}
+
+	for i, s := range selected {
+		cfg := s.config(o, refs)
+		cfg["region"] = auto.ConfigValue{Value: o.region}
+		cfg["projectName"] = auto.ConfigValue{Value: o.projectName}
+		setIfNotEmpty(cfg, "owner", o.owner, false)
+		setIfNotEmpty(cfg, "runId", o.runId, false)
+		if err := stacks[i].SetAllConfig(ctx, cfg); err != nil {
+			return fmt.Errorf("configuring %s: %w", stacks[i].Name(), err)
+		}
+		if err := requireConfig(ctx, stacks[i], s); err != nil {
+			return err
+		}
+	}
+
+	outputs := map[string]auto.OutputMap{}
+	for i, s := range selected {
+		fmt.Printf("[INFO] Checking guardrails for %s\n", stacks[i].Name())
+		preview, err := previewStack(ctx, stacks[i])
+		if err != nil {
+			return err
+		}
+		if err := checkGuardrails(stacks[i].Name(), preview.resources, o); err != nil {
+			return err
+		}
+		if estimate, ok := preview.outputs[costOutput].(float64); ok {
+			fmt.Printf("[INFO] Estimated monthly cost of %s: $%.2f\n", stacks[i].Name(), estimate)
+		}
+
+		fmt.Printf("[INFO] Deploying %s\n", stacks[i].Name())
+		res, err := stacks[i].Up(ctx, optup.ProgressStreams(os.Stdout))
+		if err != nil {
+			return fmt.Errorf("deploying %s: %w", stacks[i].Name(), err)
+		}
+		outputs[s.dir] = res.Outputs
+		fmt.Printf("[SUCCESS] %s deployed\n", stacks[i].Name())
+	}
+
+	printOutputs(selected, outputs)
+	if o.outputsFile != "" {
+		return writeOutputs(o, outputs)
+	}
+	return nil
+}
+
+// writeOutputs writes the outputs of the deployed stacks to the outputs file
+// and the env file next to it.
+func writeOutputs(o options, outputs map[string]auto.OutputMap) error {
+	f := stacks.NewFile(o.org, o.stackName, outputs)
+	envFile := strings.TrimSuffix(o.outputsFile, filepath.Ext(o.outputsFile)) + ".env"
+	if err := f.WriteJSON(o.outputsFile); err != nil {
+		return fmt.Errorf("writing outputs file: %w", err)
+	}
+	if err := f.WriteEnv(envFile); err != nil {
+		return fmt.Errorf("writing outputs file: %w", err)
+	}
+	fmt.Printf("\n[INFO] Outputs written to %s and %s\n", o.outputsFile, envFile)
+	return nil
+}
+
+// costOutput is the stack output holding a stack's estimated monthly cost.
+const costOutput = "estimatedMonthlyCostUsd"
+
+// stackPreview is what a preview plans: the resources to create or update
+// and the stack outputs known before the update.
+type stackPreview struct {
+	resources []guardrails.Resource
+	outputs   map[string]interface{}
+}
+
+// previewStack previews the stack and collects its planned resources and outputs.
+func previewStack(ctx context.Context, stack auto.Stack) (*stackPreview, error) {
+	engineEvents := make(chan events.EngineEvent)
+	preview := &stackPreview{}
+	collected := make(chan struct{})
+	go func() {
+		defer close(collected)
+		for e := range engineEvents {
+			// The stack resource reports the program's exports
+			if e.ResOutputsEvent != nil {
+				step := e.ResOutputsEvent.Metadata
+				if step.Type == "pulumi:pulumi:Stack" && step.New != nil {
+					preview.outputs = step.New.Outputs
+				}
+				continue
+			}
+			if e.ResourcePreEvent == nil {
+				continue
+			}
+			step := e.ResourcePreEvent.Metadata
+			switch step.Op {
+			case apitype.OpDelete, apitype.OpDeleteReplaced, apitype.OpReadDiscard, apitype.OpDiscardReplaced:
+				continue
+			}
+			if step.New == nil {
+				continue
+			}
+			preview.resources = append(preview.resources, guardrails.Resource{URN: step.URN, Type: step.Type, Inputs: step.New.Inputs})
+		}
+	}()
+
+	_, err := stack.Preview(ctx, optpreview.EventStreams(engineEvents))
+	<-collected
+	if err != nil {
+		return nil, fmt.Errorf("previewing %s: %w", stack.Name(), err)
+	}
+	return preview, nil
+}
+
+// checkGuardrails fails when a planned resource of the stack violates a lab
+// guardrail.
+func checkGuardrails(stackName string, resources []guardrails.Resource, o options) error {
+	violations, err := guardrails.Check(guardrails.Config{
+		AllowPublicSsh:  o.allowPublicSsh,
+		MaxInstanceSize: o.maxInstanceSize,
+	}, resources)
+	if err != nil {
+		return err
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+
+	for _, v := range violations {
+		fmt.Fprintf(os.Stderr, "[POLICY] %s\n", v)
+	}
+	return fmt.Errorf("%s violates %d guardrail(s); fix the configuration or pass -allow-public-ssh / -max-instance-size to override",
+		stackName, len(violations))
+}
+
+// requireConfig fails fast when a stack is missing configuration that has no default.
+func requireConfig(ctx context.Context, stack auto.Stack, s labStack) error {
+	var required []string
+	switch s.dir {
+	case "aurora":
+		required = []string{"masterPassword"}
+	case "ec2":
+		required = []string{"keyName"}
+	}
+
+	for _, key := range required {
+		if _, err := stack.GetConfig(ctx, key); err != nil {
+			return fmt.Errorf("%s requires config %q (pass the matching flag or run 'pulumi config set %s' in %s/)",
+				stack.Name(), key, key, s.dir)
+		}
+	}
+	return nil
+}
+
+// printOutputs prints the outputs of every deployed stack, hiding secrets,
+// and the total of their cost estimates.
+func printOutputs(selected []labStack, outputs map[string]auto.OutputMap) {
+	var total float64
+	fmt.Println()
+	fmt.Println("==========================================")
+	fmt.Println("Lab Outputs")
+	fmt.Println("==========================================")
+	for _, s := range selected {
+		fmt.Printf("\n[%s]\n", s.dir)
+		keys := make([]string, 0, len(outputs[s.dir]))
+		for key := range outputs[s.dir] {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			value := outputs[s.dir][key]
+			if value.Secret {
+				fmt.Printf("  %-28s [secret]\n", key)
+				continue
+			}
+			fmt.Printf("  %-28s %v\n", key, value.Value)
+		}
+		if estimate, ok := outputs[s.dir][costOutput].Value.(float64); ok {
+			total += estimate
+		}
+	}
+
+	fmt.Println()
+	fmt.Printf("Estimated monthly cost: $%.2f (on-demand list prices, without usage-based charges)\n", total)
+}
+
+func setIfNotEmpty(cfg auto.ConfigMap, key, value string, secret bool) {
+	if value != "" {
+		cfg[key] = auto.ConfigValue{Value: value, Secret: secret}
+	}
+}
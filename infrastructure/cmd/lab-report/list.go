@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+
+	"aurora-bluegreen-lab/internal/experiments"
+)
+
+const listUsage = `Usage: lab-report list [flags] [run ID...]
+
+Lists the runs lab-scenario, bgctl and the simulator registered in the
+monitoring stack's experiment registry, oldest first. With run IDs, compares
+those runs side by side: their configuration, the times of their steps
+(from the start of each run) and their results.
+
+  lab-report list
+  lab-report list -name minor-upgrade-write-heavy -last 5
+  lab-report list minor-upgrade-20260102-150405 minor-upgrade-20260109-093012
+
+Flags:
+`
+
+// listOptions holds the flags of lab-report list.
+type listOptions struct {
+	table  string
+	region string
+	name   string
+	source string
+	last   int
+}
+
+func listCommand(ctx context.Context, args []string) error {
+	var o listOptions
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), listUsage)
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&o.table, "registry-table", os.Getenv("MONITORING_EXPERIMENT_TABLE_NAME"),
+		"Experiment registry table, the monitoring stack's experimentTableName output (default: $MONITORING_EXPERIMENT_TABLE_NAME of lab-outputs.env)")
+	fs.StringVar(&o.region, "region", "", "AWS region of the table (default: AWS SDK default region)")
+	fs.StringVar(&o.name, "name", "", "List only the runs of this scenario or command")
+	fs.StringVar(&o.source, "source", "", "List only the runs registered by lab-scenario, bgctl (including bgctl/<command>) or simulator")
+	fs.IntVar(&o.last, "last", 0, "List only the last n runs (default: all)")
+	fs.Parse(args)
+	if o.table == "" {
+		fs.Usage()
+		return fmt.Errorf("-registry-table is required")
+	}
+
+	var opts []func(*config.LoadOptions) error
+	if o.region != "" {
+		opts = append(opts, config.WithRegion(o.region))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return fmt.Errorf("loading AWS configuration: %w", err)
+	}
+	registry := experiments.New(cfg, o.table)
+
+	if fs.NArg() > 0 {
+		var runs []experiments.Run
+		for _, runID := range fs.Args() {
+			registered, err := registry.Get(ctx, runID)
+			if err != nil {
+				return err
+			}
+			if len(registered) == 0 {
+				return fmt.Errorf("run %s is not registered in %s", runID, o.table)
+			}
+			runs = append(runs, registered...)
+		}
+		return renderComparison(os.Stdout, runs, time.Now())
+	}
+
+	runs, err := registry.List(ctx)
+	if err != nil {
+		return err
+	}
+	runs = filterRuns(runs, o)
+	if len(runs) == 0 {
+		fmt.Fprintf(os.Stderr, "[INFO] No runs registered in %s\n", o.table)
+		return nil
+	}
+	return renderList(os.Stdout, runs, time.Now())
+}
+
+// filterRuns keeps the runs matching the -name and -source filters, the last
+// -last of them.
+func filterRuns(runs []experiments.Run, o listOptions) []experiments.Run {
+	var kept []experiments.Run
+	for _, run := range runs {
+		if (o.name == "" || run.Name == o.name) && (o.source == "" || run.Source == o.source || experiments.Tool(run.Source) == o.source) {
+			kept = append(kept, run)
+		}
+	}
+	if o.last > 0 && len(kept) > o.last {
+		kept = kept[len(kept)-o.last:]
+	}
+	return kept
+}
+
+// renderList prints one line per run with its results.
+func renderList(w io.Writer, runs []experiments.Run, now time.Time) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RUN ID\tSOURCE\tNAME\tSTARTED (UTC)\tDURATION\tSTATUS\tRESULTS")
+	for _, run := range runs {
+		var results []string
+		for _, key := range sortedKeys(run.Results) {
+			results = append(results, key+"="+formatResult(run.Results[key]))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", run.RunID, run.Source, dash(run.Name),
+			run.StartedAt.UTC().Format("2006-01-02 15:04:05"), run.Duration(now).Round(time.Second),
+			run.Status, dash(strings.Join(results, " ")))
+	}
+	return tw.Flush()
+}
+
+// renderComparison prints the runs side by side, one column per run and
+// source: their configuration, step times and results.
+func renderComparison(w io.Writer, runs []experiments.Run, now time.Time) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	row := func(label string, value func(run experiments.Run) string) {
+		fmt.Fprint(tw, label)
+		for _, run := range runs {
+			fmt.Fprint(tw, "\t", dash(value(run)))
+		}
+		fmt.Fprintln(tw)
+	}
+
+	row("RUN ID", func(run experiments.Run) string { return run.RunID })
+	row("source", func(run experiments.Run) string { return run.Source })
+	row("name", func(run experiments.Run) string { return run.Name })
+	row("status", func(run experiments.Run) string { return run.Status })
+	row("started (UTC)", func(run experiments.Run) string { return run.StartedAt.UTC().Format("2006-01-02 15:04:05") })
+	row("duration", func(run experiments.Run) string { return run.Duration(now).Round(time.Second).String() })
+	row("error", func(run experiments.Run) string { return run.Error })
+
+	configs := map[string]bool{}
+	timings := map[string]time.Duration{}
+	results := map[string]bool{}
+	for _, run := range runs {
+		for key := range run.Config {
+			configs[key] = true
+		}
+		for key, t := range run.Timings {
+			// Steps are ordered by their earliest offset across the runs
+			if offset, ok := timings[key]; !ok || t.Sub(run.StartedAt) < offset {
+				timings[key] = t.Sub(run.StartedAt)
+			}
+		}
+		for key := range run.Results {
+			results[key] = true
+		}
+	}
+
+	for _, key := range sortedKeys(configs) {
+		row("config "+key, func(run experiments.Run) string { return run.Config[key] })
+	}
+	steps := sortedKeys(timings)
+	sort.SliceStable(steps, func(i, j int) bool { return timings[steps[i]] < timings[steps[j]] })
+	for _, key := range steps {
+		row("timing "+key, func(run experiments.Run) string {
+			t, ok := run.Timings[key]
+			if !ok {
+				return ""
+			}
+			return "+" + t.Sub(run.StartedAt).Round(time.Second).String()
+		})
+	}
+	for _, key := range sortedKeys(results) {
+		row("result "+key, func(run experiments.Run) string {
+			value, ok := run.Results[key]
+			if !ok {
+				return ""
+			}
+			return formatResult(value)
+		})
+	}
+	return tw.Flush()
+}
+
+// formatResult formats a result with at most two decimals.
+func formatResult(value float64) string {
+	return strconv.FormatFloat(math.Round(value*100)/100, 'f', -1, 64)
+}
+
+func dash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
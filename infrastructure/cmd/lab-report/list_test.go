@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"aurora-bluegreen-lab/internal/experiments"
+)
+
+func testRuns() []experiments.Run {
+	t0 := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	t1 := t0.Add(7 * 24 * time.Hour)
+	return []experiments.Run{
+		{
+			RunID: "minor-upgrade-20260102-150000", Source: experiments.SourceScenario, Name: "minor-upgrade",
+			Status: experiments.StatusSucceeded, StartedAt: t0, EndedAt: t0.Add(30 * time.Minute),
+			Config:  map[string]string{"workload": "--write-workers 10"},
+			Timings: map[string]time.Time{"switchover-started": t0.Add(20 * time.Minute), "deployment-created": t0.Add(2 * time.Minute)},
+			Results: map[string]float64{"successRate": 99.8765, "switchoverSeconds": 41},
+		},
+		{
+			RunID: "minor-upgrade-20260109-150000", Source: experiments.SourceScenario, Name: "minor-upgrade",
+			Status: experiments.StatusFailed, StartedAt: t1, EndedAt: t1.Add(25 * time.Minute),
+			Config:  map[string]string{"workload": "--write-workers 20"},
+			Timings: map[string]time.Time{"deployment-created": t1.Add(2 * time.Minute)},
+			Error:   "switchover timed out",
+		},
+	}
+}
+
+func TestRenderList(t *testing.T) {
+	var buf bytes.Buffer
+	if err := renderList(&buf, testRuns(), time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines:\n%s", len(lines), buf.String())
+	}
+	for _, want := range []string{"2026-01-02 15:00:00", "30m0s", "succeeded", "successRate=99.88 switchoverSeconds=41"} {
+		if !strings.Contains(lines[1], want) {
+			t.Errorf("first run %q does not contain %q", lines[1], want)
+		}
+	}
+	if fields := strings.Fields(lines[2]); fields[len(fields)-1] != "-" {
+		t.Errorf("second run %q has results", lines[2])
+	}
+}
+
+func TestRenderComparison(t *testing.T) {
+	var buf bytes.Buffer
+	if err := renderComparison(&buf, testRuns(), time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"config workload",
+		"--write-workers 10",
+		"--write-workers 20",
+		"error",
+		"switchover timed out",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("comparison does not contain %q:\n%s", want, out)
+		}
+	}
+
+	// Steps follow the run, missing values are dashes
+	created := strings.Index(out, "timing deployment-created")
+	started := strings.Index(out, "timing switchover-started")
+	if created < 0 || started < created {
+		t.Errorf("steps out of order:\n%s", out)
+	}
+	if !strings.Contains(out[started:], "+20m0s") || !strings.Contains(out[started:], "-") {
+		t.Errorf("switchover-started row:\n%s", out[started:])
+	}
+}
+
+func TestFilterRuns(t *testing.T) {
+	runs := append(testRuns(), experiments.Run{RunID: "backtrack-20260110-090000", Source: experiments.SourceBgctl, Name: "backtrack"})
+	if got := filterRuns(runs, listOptions{name: "minor-upgrade"}); len(got) != 2 {
+		t.Errorf("-name: got %d runs", len(got))
+	}
+	if got := filterRuns(runs, listOptions{source: experiments.SourceBgctl}); len(got) != 1 || got[0].Name != "backtrack" {
+		t.Errorf("-source: got %+v", got)
+	}
+	if got := filterRuns(runs, listOptions{last: 2}); len(got) != 2 || got[1].Source != experiments.SourceBgctl {
+		t.Errorf("-last: got %+v", got)
+	}
+	joined := append(runs, experiments.Run{RunID: "minor-upgrade-20260109-093012", Source: experiments.CommandSource("switchover"), Name: "switchover"})
+	if got := filterRuns(joined, listOptions{source: experiments.SourceBgctl}); len(got) != 2 || got[1].Name != "switchover" {
+		t.Errorf("-source with a joined command: got %+v", got)
+	}
+}
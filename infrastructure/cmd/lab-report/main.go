@@ -0,0 +1,213 @@
+// Command lab-report turns the output of a lab run into a single shareable
+// Markdown or HTML report:
+//
+//	simulator JSON Lines (--output-format json)   required
+//	switchover timeline (JSON Lines, lab-scenario) optional
+//	RDS Blue/Green events (monitoring stack)       optional
+//	Aurora replica lag from CloudWatch             optional
+//
+// The report summarises the run (requests, error window, switchover window,
+// recovery times) and charts the successful and failed requests, the latency
+// percentiles of every operation and the replica lag over the run; see
+// internal/report. The Blue/Green events the monitoring stack recorded with
+// RDS's timestamps are merged into the timeline as rds:<event>: those of the
+// run (the run ID of the statistics, or -run-id; see internal/runid), else all
+// the events within the run's time window.
+//
+// With -webhook or LAB_WEBHOOK_URL, the report's summary is also posted as
+// the run's downtime report to a Slack incoming webhook or any HTTPS
+// endpoint (internal/notify).
+//
+// lab-report list lists and compares the runs registered in the monitoring
+// stack's experiment registry (internal/experiments).
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+
+	"aurora-bluegreen-lab/internal/notify"
+	"aurora-bluegreen-lab/internal/report"
+)
+
+// options holds the command line flags.
+type options struct {
+	stats    string
+	timeline string
+	events   string
+	runID    string
+	clusters string
+	region   string
+	format   string
+	output   string
+	title    string
+	webhook  string
+}
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "list" {
+		if err := listCommand(context.Background(), os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	var o options
+	flag.StringVar(&o.stats, "stats", "", "Simulator statistics file written with --output-format json (required)")
+	flag.StringVar(&o.timeline, "timeline", "", "bgctl switchover timeline in JSON Lines format")
+	flag.StringVar(&o.events, "events-table", "", "DynamoDB table of the RDS Blue/Green events recorded by the monitoring stack (its eventTableName output) to merge into the timeline")
+	flag.StringVar(&o.runID, "run-id", "", "Report only the timeline and RDS Blue/Green events of this run (default: the run ID of the -stats file)")
+	flag.StringVar(&o.clusters, "clusters", "", "Comma-separated Aurora cluster identifiers (blue and green) to chart the CloudWatch replica lag of")
+	flag.StringVar(&o.region, "region", "", "AWS region of the clusters (default: AWS SDK default region)")
+	flag.StringVar(&o.format, "format", "", "Report format: markdown or html (default: from the -output extension, else markdown)")
+	flag.StringVar(&o.output, "output", "", "Report file (default: standard output)")
+	flag.StringVar(&o.title, "title", "Aurora Blue/Green Switchover Report", "Report title")
+	flag.StringVar(&o.webhook, "webhook", "", "Slack incoming webhook or HTTPS URL to post the downtime report to (default: $"+notify.EnvVar+")")
+	flag.Parse()
+
+	if err := run(context.Background(), o); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, o options) error {
+	if o.stats == "" {
+		return fmt.Errorf("-stats is required")
+	}
+	format, err := reportFormat(o.format, o.output)
+	if err != nil {
+		return err
+	}
+	if o.webhook == "" {
+		o.webhook = os.Getenv(notify.EnvVar)
+	}
+	notifier, err := notify.New(o.webhook)
+	if err != nil {
+		return err
+	}
+
+	stats, err := report.ReadStats(o.stats)
+	if err != nil {
+		return err
+	}
+
+	runID := o.runID
+	if runID == "" {
+		runID = stats.RunID()
+	}
+
+	var timeline []report.Event
+	if o.timeline != "" {
+		if timeline, err = report.ReadTimeline(o.timeline); err != nil {
+			return err
+		}
+		if runID != "" && len(timeline) > 0 {
+			// A timeline file appended to by several runs, e.g. by bgctl switchover -timeline
+			if ofRun := report.RunEvents(timeline, runID); len(ofRun) > 0 {
+				timeline = ofRun
+			} else {
+				fmt.Fprintf(os.Stderr, "[WARNING] No timeline events of run %s; reporting all %d events of %s\n", runID, len(timeline), o.timeline)
+			}
+		}
+	}
+
+	var cfg aws.Config
+	if o.events != "" || o.clusters != "" {
+		var opts []func(*config.LoadOptions) error
+		if o.region != "" {
+			opts = append(opts, config.WithRegion(o.region))
+		}
+		if cfg, err = config.LoadDefaultConfig(ctx, opts...); err != nil {
+			return fmt.Errorf("loading AWS configuration: %w", err)
+		}
+	}
+	window := report.Window{Start: stats.Start(), End: stats.End()}
+
+	if o.events != "" {
+		events, err := report.BlueGreenEvents(ctx, cfg, o.events, window)
+		if err != nil {
+			return err
+		}
+		if runID != "" && len(events) > 0 {
+			// Deployments created without the run's ID are not tagged with it
+			if ofRun := report.RunEvents(events, runID); len(ofRun) > 0 {
+				events = ofRun
+			} else {
+				fmt.Fprintf(os.Stderr, "[WARNING] No RDS Blue/Green events of run %s; merging the %d events within the run's time window\n", runID, len(events))
+			}
+		}
+		timeline = report.MergeTimelines(timeline, events)
+	}
+
+	var lag []report.Series
+	if o.clusters != "" {
+		var clusters []string
+		for _, cluster := range strings.Split(o.clusters, ",") {
+			if cluster = strings.TrimSpace(cluster); cluster != "" {
+				clusters = append(clusters, cluster)
+			}
+		}
+		if lag, err = report.ReplicaLag(ctx, cfg, clusters, window); err != nil {
+			return err
+		}
+	}
+
+	r := report.New(o.title, filepath.Base(o.stats), stats, timeline, lag)
+
+	var w io.Writer = os.Stdout
+	if o.output != "" {
+		f, err := os.Create(o.output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if format == "html" {
+		err = r.HTML(w)
+	} else {
+		err = r.Markdown(w)
+	}
+	if err != nil {
+		return fmt.Errorf("rendering report: %w", err)
+	}
+	if o.output != "" {
+		fmt.Fprintf(os.Stderr, "[SUCCESS] Report written to %s\n", o.output)
+	}
+	if notifier != nil {
+		// The report is written; a webhook that cannot be reached only costs the post
+		if err := notifier.Send(ctx, r.Notification("lab-report")); err != nil {
+			fmt.Fprintf(os.Stderr, "[WARNING] Not notified: %v\n", err)
+		} else {
+			fmt.Fprintln(os.Stderr, "[SUCCESS] Downtime report posted to the webhook")
+		}
+	}
+	return nil
+}
+
+// reportFormat resolves the report format from the flag or the output file
+// extension.
+func reportFormat(format, output string) (string, error) {
+	switch format {
+	case "markdown", "html":
+		return format, nil
+	case "":
+		switch strings.ToLower(filepath.Ext(output)) {
+		case ".html", ".htm":
+			return "html", nil
+		}
+		return "markdown", nil
+	}
+	return "", fmt.Errorf("-format must be markdown or html, got %q", format)
+}
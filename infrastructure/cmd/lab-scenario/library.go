@@ -0,0 +1,61 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// library holds the predefined scenarios, one file per scenario named after
+// it.
+//
+//go:embed scenarios/*.yaml
+var library embed.FS
+
+// libraryNames returns the names of the predefined scenarios in order.
+func libraryNames() []string {
+	entries, _ := library.ReadDir("scenarios")
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".yaml"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// libraryFile returns the YAML of a predefined scenario.
+func libraryFile(name string) ([]byte, error) {
+	data, err := library.ReadFile(path.Join("scenarios", name+".yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("unknown scenario %q (predefined scenarios: %s)", name, strings.Join(libraryNames(), ", "))
+	}
+	return data, nil
+}
+
+// libraryScenario loads a predefined scenario.
+func libraryScenario(name string) (*Scenario, error) {
+	data, err := libraryFile(name)
+	if err != nil {
+		return nil, err
+	}
+	s, err := parseScenario(data)
+	if err != nil {
+		return nil, fmt.Errorf("scenario %s: %w", name, err)
+	}
+	return s, nil
+}
+
+// resolveScenario loads a scenario file when arg looks like a path or names
+// an existing file, and a predefined scenario otherwise.
+func resolveScenario(arg string) (*Scenario, error) {
+	if strings.ContainsRune(arg, os.PathSeparator) || strings.HasSuffix(arg, ".yaml") || strings.HasSuffix(arg, ".yml") {
+		return loadScenario(arg)
+	}
+	if _, err := os.Stat(arg); err == nil {
+		return loadScenario(arg)
+	}
+	return libraryScenario(arg)
+}
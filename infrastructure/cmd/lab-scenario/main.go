@@ -0,0 +1,616 @@
+// Command lab-scenario runs a complete Blue/Green experiment described by a
+// scenario:
+//
+//	start simulator -> warmup -> create Blue/Green deployment -> wait until
+//	available -> switchover -> cooldown -> stop simulator -> fetch outputs -> report
+//
+// A scenario's fault starts an AWS FIS experiment template, e.g. one of the
+// fis stack's, during the switchover (internal/chaos).
+//
+// Scenarios are YAML files or one of the predefined scenarios embedded in the
+// command (scenarios/*.yaml):
+//
+//	lab-scenario list
+//	lab-scenario show <name>
+//	lab-scenario run [flags] <name | file>
+//
+// The simulator is controlled on the simulator host with SSM RunCommand (the
+// default) or SSH. The lab cluster and the simulator host are looked up in the
+// aurora and ec2 stack outputs. Every step is recorded in a switchover
+// timeline next to the simulator statistics; the deployment, switchover and
+// fault steps are also marked in the simulator log through its control API.
+// Both are merged into a Markdown and HTML report (internal/report) in the
+// run's output directory. With the monitoring stack's experiment registry,
+// the run is registered with its configuration, step times and results
+// (internal/experiments) for lab-report list. With -webhook or
+// LAB_WEBHOOK_URL, the run's milestones and its downtime report are posted to
+// a Slack incoming webhook or any HTTPS endpoint (internal/notify).
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+
+	"aurora-bluegreen-lab/internal/bluegreen"
+	"aurora-bluegreen-lab/internal/chaos"
+	"aurora-bluegreen-lab/internal/experiments"
+	"aurora-bluegreen-lab/internal/notify"
+	"aurora-bluegreen-lab/internal/remote"
+	"aurora-bluegreen-lab/internal/report"
+	"aurora-bluegreen-lab/internal/runid"
+	"aurora-bluegreen-lab/internal/stacks"
+)
+
+// options holds the command line flags.
+type options struct {
+	scenario            string
+	infraDir            string
+	stackName           string
+	org                 string
+	outputsFile         string
+	targetEngineVersion string
+	transport           string
+	instanceID          string
+	sshHost             string
+	sshUser             string
+	sshKey              string
+	outputDir           string
+	registryTable       string
+	webhook             string
+}
+
+// reader returns the reader of the lab's stack outputs.
+func (o options) reader() *stacks.Reader {
+	return &stacks.Reader{InfraDir: o.infraDir, Org: o.org, Stack: o.stackName, File: o.outputsFile}
+}
+
+// environment is the deployed lab the scenario runs against.
+type environment struct {
+	region            string
+	clusterArn        string
+	clusterIdentifier string
+	instanceID        string
+	sshHost           string
+	// green parameter groups of the aurora stack, if it has any
+	greenClusterParameterGroup  string
+	greenInstanceParameterGroup string
+	// greenEngineVersion is the aurora stack's greenEngineVersion, if set
+	greenEngineVersion string
+}
+
+const usage = `Usage:
+  lab-scenario list                        List the predefined scenarios
+  lab-scenario show <name>                 Print a predefined scenario (to copy and edit)
+  lab-scenario run [flags] <name | file>   Run a predefined scenario or a scenario file
+
+Run 'lab-scenario run -h' for the run flags.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch command, args := os.Args[1], os.Args[2:]; command {
+	case "list":
+		err = list()
+	case "show":
+		if len(args) != 1 {
+			fmt.Fprint(os.Stderr, usage)
+			os.Exit(2)
+		}
+		err = show(args[0])
+	case "run":
+		err = runCommand(args)
+	case "help", "-h", "-help", "--help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "[ERROR] unknown command %q\n\n%s", command, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// list prints the predefined scenarios with their descriptions.
+func list() error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tDESCRIPTION")
+	for _, name := range libraryNames() {
+		s, err := libraryScenario(name)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s\t%s\n", s.Name, s.Description)
+	}
+	return w.Flush()
+}
+
+func show(name string) error {
+	data, err := libraryFile(name)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(data)
+	return err
+}
+
+// runCommand parses the run flags, which may come before or after the
+// scenario, and runs it.
+func runCommand(args []string) error {
+	var o options
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: lab-scenario run [flags] <name | file>\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&o.infraDir, "infra-dir", ".", "Path to the infrastructure directory containing the stack projects")
+	fs.StringVar(&o.stackName, "stack", "dev", "Pulumi stack name of the aurora and ec2 stacks")
+	fs.StringVar(&o.org, "org", "", "Pulumi organization of the stacks (default: output of 'pulumi whoami')")
+	fs.StringVar(&o.outputsFile, "outputs-file", "", "Read the stack outputs from this lab-outputs.json written by lab-deploy instead of Pulumi")
+	fs.StringVar(&o.targetEngineVersion, "target-engine-version", "", "Override the scenario's green engine version (major-upgrade defaults to the aurora stack's greenEngineVersion)")
+	fs.StringVar(&o.transport, "transport", "ssm", "How to control the simulator host: ssm (SSM RunCommand) or ssh")
+	fs.StringVar(&o.instanceID, "instance-id", "", "Simulator instance ID (default: the ec2 stack's instanceId output; required for Auto Scaling Groups)")
+	fs.StringVar(&o.sshHost, "ssh-host", "", "Simulator host for -transport ssh (default: the ec2 stack's publicDns output)")
+	fs.StringVar(&o.sshUser, "ssh-user", "ec2-user", "SSH user for -transport ssh")
+	fs.StringVar(&o.sshKey, "ssh-key", "", "SSH private key file for -transport ssh (default: SSH agent/config)")
+	fs.StringVar(&o.outputDir, "output-dir", "runs", "Directory the run's outputs and report are written to (one subdirectory per run)")
+	fs.StringVar(&o.registryTable, "registry-table", "", "Experiment registry table to register the run in (default: the monitoring stack's experimentTableName output)")
+	fs.StringVar(&o.webhook, "webhook", "", "Slack incoming webhook or HTTPS URL to post the run's milestones and downtime report to (default: $"+notify.EnvVar+")")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("a scenario name or file is required (see 'lab-scenario list')")
+	}
+	o.scenario = fs.Arg(0)
+	fs.Parse(fs.Args()[1:])
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments after the scenario: %s", strings.Join(fs.Args(), " "))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return run(ctx, o)
+}
+
+func run(ctx context.Context, o options) error {
+	if o.transport != "ssm" && o.transport != "ssh" {
+		return fmt.Errorf("-transport must be ssm or ssh, got %q", o.transport)
+	}
+	if o.webhook == "" {
+		o.webhook = os.Getenv(notify.EnvVar)
+	}
+	if _, err := notify.New(o.webhook); err != nil {
+		return err
+	}
+	sc, err := resolveScenario(o.scenario)
+	if err != nil {
+		return err
+	}
+	if o.targetEngineVersion != "" {
+		sc.BlueGreen.TargetEngineVersion = o.targetEngineVersion
+	}
+
+	env, err := loadEnvironment(ctx, o, sc)
+	if err != nil {
+		return err
+	}
+	if sc.BlueGreen.MajorVersionUpgrade && sc.BlueGreen.TargetEngineVersion == "" {
+		if env.greenEngineVersion == "" {
+			return fmt.Errorf("scenario %s is a major version upgrade: set greenEngineVersion in the aurora stack or pass the new engine version with -target-engine-version", sc.Name)
+		}
+		sc.BlueGreen.TargetEngineVersion = env.greenEngineVersion
+	}
+	if sc.BlueGreen.UseGreenParameterGroups {
+		sc.BlueGreen.TargetClusterParameterGroup = env.greenClusterParameterGroup
+		sc.BlueGreen.TargetInstanceParameterGroup = env.greenInstanceParameterGroup
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(env.region))
+	if err != nil {
+		return fmt.Errorf("loading AWS configuration: %w", err)
+	}
+
+	runID := runid.New(sc.Name, time.Now())
+	dir := filepath.Join(o.outputDir, runID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tl, err := newTimeline(filepath.Join(dir, "timeline.jsonl"), runID)
+	if err != nil {
+		return err
+	}
+	defer tl.close()
+	posts, err := newNotifications(o.webhook, runID)
+	if err != nil {
+		return err
+	}
+	defer posts.close()
+
+	var host remote.Host = remote.NewSSM(cfg, env.instanceID)
+	if o.transport == "ssh" {
+		host = remote.NewSSH(env.sshHost, o.sshUser, o.sshKey)
+	}
+	r := &runner{
+		scenario: sc,
+		env:      env,
+		runID:    runID,
+		dir:      dir,
+		timeline: tl,
+		posts:    posts,
+		sim:      remote.NewSimulatorRun(host, runID),
+		control:  remote.NewControl(host, remote.ControlPort),
+		bg:       bluegreen.New(cfg),
+		cfg:      cfg,
+		registry: loadRegistry(ctx, o, cfg),
+	}
+
+	fmt.Printf("[INFO] Running scenario %s as %s against %s\n", sc.Name, runID, env.clusterIdentifier)
+	fmt.Printf("[INFO] bgctl commands join the run with -run-id %s or %s=%s\n", runID, runid.EnvVar, runID)
+	r.register(ctx, o)
+	posts.post(notify.Message{Event: "scenario-started", Text: fmt.Sprintf("Running scenario %s against %s", sc.Name, env.clusterIdentifier)})
+	runErr := r.experiment(ctx)
+
+	// Always stop the simulator and keep what was measured, even after a
+	// failure or an interrupt
+	cleanupCtx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	if err := r.finish(cleanupCtx); err != nil {
+		runErr = errors.Join(runErr, err)
+	}
+	r.registerResult(cleanupCtx, runErr)
+	if r.report != nil {
+		posts.post(r.report.Notification("lab-scenario"))
+	}
+	if runErr != nil {
+		posts.post(notify.Message{Event: "scenario-failed", Text: fmt.Sprintf("Scenario %s failed: %v", sc.Name, runErr)})
+	}
+	return runErr
+}
+
+// runner runs one scenario.
+type runner struct {
+	scenario *Scenario
+	env      *environment
+	runID    string
+	dir      string
+	timeline *timeline
+	// posts is nil without a webhook
+	posts   *notifications
+	sim     *remote.SimulatorRun
+	control *remote.Control
+	// marks are the milestones being marked on the simulator
+	marks sync.WaitGroup
+	bg    *bluegreen.Client
+	cfg   aws.Config
+	// registry is nil when the run is not registered
+	registry *experiments.Registry
+	entry    *experiments.Run
+	report   *report.Report
+
+	started    bool
+	deployment *bluegreen.Deployment
+	// greenIdentifier is the green cluster's identifier before the switchover
+	greenIdentifier string
+	switchedOver    bool
+}
+
+// experiment starts the workload and runs the Blue/Green deployment.
+func (r *runner) experiment(ctx context.Context) error {
+	sc := r.scenario
+	pid, err := r.sim.Start(ctx, sc.Workload.Options)
+	if err != nil {
+		return err
+	}
+	r.started = true
+	r.timeline.record("simulator-started", fmt.Sprintf("run %s, pid %s", r.runID, pid))
+
+	if err := sleep(ctx, sc.Workload.Warmup, "Warming up"); err != nil {
+		return err
+	}
+	if err := r.sim.Check(ctx); err != nil {
+		return err
+	}
+
+	r.deployment, err = r.bg.Create(ctx, bluegreen.CreateOptions{
+		Name:                         r.runID,
+		SourceArn:                    r.env.clusterArn,
+		TargetEngineVersion:          sc.BlueGreen.TargetEngineVersion,
+		TargetClusterParameterGroup:  sc.BlueGreen.TargetClusterParameterGroup,
+		TargetInstanceParameterGroup: sc.BlueGreen.TargetInstanceParameterGroup,
+		TargetInstanceClass:          sc.BlueGreen.TargetInstanceClass,
+		Tags:                         map[string]string{runid.Tag: r.runID, "Scenario": sc.Name},
+	})
+	if err != nil {
+		return err
+	}
+	r.milestone(ctx, "deployment-created", r.deployment.ID)
+
+	available, err := r.bg.WaitAvailable(ctx, r.deployment.ID, r.recordStatus)
+	if err != nil {
+		return err
+	}
+	r.greenIdentifier = available.TargetClusterIdentifier()
+
+	if err := sleep(ctx, sc.BlueGreen.SwitchoverDelay, "Waiting before the switchover"); err != nil {
+		return err
+	}
+	if err := r.sim.Check(ctx); err != nil {
+		return err
+	}
+
+	r.milestone(ctx, report.EventSwitchoverStarted, r.deployment.ID)
+	waitFault := r.startFault(ctx)
+	if _, err := r.bg.Switchover(ctx, r.deployment.ID, time.Duration(sc.BlueGreen.SwitchoverTimeout), r.recordStatus); err != nil {
+		r.milestone(ctx, "switchover-failed", err.Error())
+		f := bluegreen.DiagnoseSwitchover(err)
+		fmt.Fprintf(os.Stderr, "[WARNING] The switchover did not complete: %s\n", f.Reason)
+		for _, step := range f.Guidance {
+			fmt.Fprintf(os.Stderr, "[INFO] %s\n", step)
+		}
+		return errors.Join(err, waitFault())
+	}
+	r.switchedOver = true
+	r.milestone(ctx, report.EventSwitchoverCompleted, r.deployment.ID)
+	if err := waitFault(); err != nil {
+		return err
+	}
+
+	return sleep(ctx, sc.Workload.Cooldown, "Cooling down")
+}
+
+// startFault starts the scenario's FIS experiment in the background after
+// the fault's delay, and returns a function that waits until it ends.
+func (r *runner) startFault(ctx context.Context) func() error {
+	fault := r.scenario.Fault
+	if fault.Template == "" {
+		return func() error { return nil }
+	}
+	done := make(chan error, 1)
+	go func() {
+		if err := sleep(ctx, fault.Delay, "Waiting before the fault"); err != nil {
+			done <- err
+			return
+		}
+		r.milestone(ctx, "fault-started", fault.Template)
+		s, err := chaos.New(r.cfg).StartTemplate(ctx, fault.Template, func(s *chaos.State) {
+			r.timeline.record("fault-status", s.ID+" "+s.Status)
+		})
+		if err != nil {
+			r.milestone(ctx, "fault-failed", err.Error())
+			done <- fmt.Errorf("fault %s: %w", fault.Template, err)
+			return
+		}
+		r.milestone(ctx, "fault-completed", s.ID)
+		done <- nil
+	}()
+	return func() error { return <-done }
+}
+
+// milestone records a step of the experiment in the timeline, posts it to
+// the webhook, if any, and marks it on the simulator through its control
+// API, in the background: the simulator logs the event with its counters at
+// that moment. A simulator that cannot be reached only costs the mark.
+func (r *runner) milestone(ctx context.Context, event, detail string) {
+	e := r.timeline.record(event, detail)
+	r.posts.post(notify.Message{Time: e.Timestamp, Event: event, Text: fmt.Sprintf("Scenario %s: %s %s", r.scenario.Name, event, detail)})
+	r.marks.Add(1)
+	go func() {
+		defer r.marks.Done()
+		if err := r.control.MarkEvent(ctx, event, detail, e.Timestamp); err != nil {
+			fmt.Fprintf(os.Stderr, "[WARNING] Marking %s on the simulator: %v\n", event, err)
+		}
+	}()
+}
+
+// finish stops the simulator, fetches its outputs, deletes the deployment
+// when the scenario asks for it and renders the report.
+func (r *runner) finish(ctx context.Context) error {
+	if !r.started {
+		return nil
+	}
+	r.marks.Wait()
+	var errs []error
+	if err := r.sim.Stop(ctx); err != nil {
+		errs = append(errs, err)
+	} else {
+		r.timeline.record("simulator-stopped", r.runID)
+	}
+
+	if r.deployment != nil && r.scenario.BlueGreen.DeleteDeployment {
+		// A green environment that was never switched over is only a copy
+		// of the lab cluster, so it is deleted with the deployment
+		if err := r.bg.Delete(ctx, r.deployment.ID, !r.switchedOver); err != nil {
+			errs = append(errs, err)
+		} else {
+			r.timeline.record("deployment-deleted", r.deployment.ID)
+		}
+	}
+
+	fmt.Println("[INFO] Fetching the simulator outputs")
+	if err := r.sim.Collect(ctx, r.dir); err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+	if err := r.writeReport(ctx); err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+	fmt.Printf("[SUCCESS] Report written to %s\n", filepath.Join(r.dir, "report.html"))
+	return errors.Join(errs...)
+}
+
+func (r *runner) writeReport(ctx context.Context) error {
+	stats, err := report.ReadStats(filepath.Join(r.dir, "stats.jsonl"))
+	if err != nil {
+		return err
+	}
+	events, err := report.ReadTimeline(r.timeline.path)
+	if err != nil {
+		return err
+	}
+
+	// The switchover renames green to the blue identifier and blue to -old1
+	clusters := []string{r.env.clusterIdentifier}
+	if r.greenIdentifier != "" {
+		clusters = append(clusters, r.greenIdentifier)
+	}
+	if r.switchedOver {
+		clusters = append(clusters, bluegreen.OldBlueClusterIdentifier(r.env.clusterIdentifier))
+	}
+	lag, err := report.ReplicaLag(ctx, r.cfg, clusters, report.Window{Start: stats.Start(), End: stats.End()})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[WARNING] Report without replica lag: %v\n", err)
+	}
+
+	title := fmt.Sprintf("Scenario %s (%s)", r.scenario.Name, r.runID)
+	rep := report.New(title, "stats.jsonl", stats, events, lag)
+	r.report = rep
+	for name, render := range map[string]func(w *os.File) error{
+		"report.md":   func(w *os.File) error { return rep.Markdown(w) },
+		"report.html": func(w *os.File) error { return rep.HTML(w) },
+	} {
+		f, err := os.Create(filepath.Join(r.dir, name))
+		if err != nil {
+			return err
+		}
+		if err := render(f); err != nil {
+			f.Close()
+			return fmt.Errorf("rendering %s: %w", name, err)
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *runner) recordStatus(d *bluegreen.Deployment) {
+	detail := d.Status
+	if d.StatusDetails != "" {
+		detail += ": " + d.StatusDetails
+	}
+	r.timeline.record("deployment-status", detail)
+}
+
+// timeline records the run's events as JSON Lines for internal/report and
+// echoes them to the terminal. The fault records its events concurrently
+// with the switchover.
+type timeline struct {
+	mu    sync.Mutex
+	path  string
+	runID string
+	f     *os.File
+	enc   *json.Encoder
+	// times holds when each step first happened, the run's registered
+	// timings; status updates are left out
+	times map[string]time.Time
+}
+
+func newTimeline(path, runID string) (*timeline, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &timeline{path: path, runID: runID, f: f, enc: json.NewEncoder(f), times: map[string]time.Time{}}, nil
+}
+
+func (t *timeline) record(event, detail string) report.Event {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	e := report.Event{Timestamp: time.Now().UTC(), Event: event, Detail: detail, RunID: t.runID}
+	if _, ok := t.times[event]; !ok && event != "deployment-status" && event != "fault-status" {
+		t.times[event] = e.Timestamp
+	}
+	fmt.Printf("[INFO] %s %s %s\n", e.Timestamp.Format("15:04:05"), event, detail)
+	if err := t.enc.Encode(e); err != nil {
+		fmt.Fprintf(os.Stderr, "[WARNING] Recording %s in the timeline: %v\n", event, err)
+	}
+	return e
+}
+
+func (t *timeline) close() {
+	t.f.Close()
+}
+
+// sleep waits for d unless ctx is cancelled first.
+func sleep(ctx context.Context, d Duration, what string) error {
+	if d <= 0 {
+		return nil
+	}
+	fmt.Printf("[INFO] %s for %s\n", what, time.Duration(d))
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(time.Duration(d)):
+		return nil
+	}
+}
+
+// loadEnvironment reads the cluster and simulator host from the aurora and
+// ec2 stack outputs; flags override the host.
+func loadEnvironment(ctx context.Context, o options, sc *Scenario) (*environment, error) {
+	reader := o.reader()
+	aurora, err := reader.Outputs(ctx, "aurora")
+	if err != nil {
+		return nil, err
+	}
+	ec2, err := reader.Outputs(ctx, "ec2")
+	if err != nil {
+		return nil, err
+	}
+
+	env := &environment{
+		region:            aurora.String("region"),
+		clusterArn:        aurora.String("clusterArn"),
+		clusterIdentifier: aurora.String("clusterIdentifier"),
+		instanceID:        ec2.String("instanceId"),
+		sshHost:           ec2.String("publicDns"),
+
+		greenClusterParameterGroup:  aurora.String("greenClusterParameterGroupName"),
+		greenInstanceParameterGroup: aurora.String("greenInstanceParameterGroupName"),
+		greenEngineVersion:          aurora.String("greenEngineVersion"),
+	}
+	if o.instanceID != "" {
+		env.instanceID = o.instanceID
+	}
+	if o.sshHost != "" {
+		env.sshHost = o.sshHost
+	}
+
+	var missing []string
+	if env.clusterArn == "" || env.region == "" {
+		missing = append(missing, "the aurora stack has no clusterArn/region outputs (run pulumi up in aurora/)")
+	}
+	if sc.BlueGreen.UseGreenParameterGroups && env.greenClusterParameterGroup == "" {
+		missing = append(missing, "the aurora stack has no green parameter groups (set greenParameters in aurora/)")
+	}
+	if ec2.String("simulatorService") == "" {
+		missing = append(missing, "the ec2 stack does not run the simulator service (set auroraStackName and dbPassword in ec2/)")
+	}
+	if o.transport == "ssm" && env.instanceID == "" {
+		missing = append(missing, "no simulator instance ID (pass -instance-id with an instance of the Auto Scaling Group)")
+	}
+	if o.transport == "ssh" && env.sshHost == "" {
+		missing = append(missing, "no simulator host (pass -ssh-host)")
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("cannot run the scenario:\n  - %s", strings.Join(missing, "\n  - "))
+	}
+	return env, nil
+}
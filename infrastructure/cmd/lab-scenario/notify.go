@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"aurora-bluegreen-lab/internal/notify"
+)
+
+// notifications posts the run's milestones and downtime report to the
+// webhook in the background, in order, so a slow webhook does not delay the
+// experiment. A nil notifications posts nothing.
+type notifications struct {
+	n      *notify.Notifier
+	source string
+	runID  string
+	queue  chan notify.Message
+	done   chan struct{}
+}
+
+// newNotifications returns the notifications of the run to the webhook, or
+// nil without one.
+func newNotifications(webhook, runID string) (*notifications, error) {
+	n, err := notify.New(webhook)
+	if err != nil || n == nil {
+		return nil, err
+	}
+	p := &notifications{
+		n:      n,
+		source: "lab-scenario",
+		runID:  runID,
+		queue:  make(chan notify.Message, 64),
+		done:   make(chan struct{}),
+	}
+	go func() {
+		defer close(p.done)
+		for m := range p.queue {
+			ctx, cancel := context.WithTimeout(context.Background(), notify.Timeout)
+			if err := n.Send(ctx, m); err != nil {
+				fmt.Fprintf(os.Stderr, "[WARNING] Not notified: %v\n", err)
+			}
+			cancel()
+		}
+	}()
+	return p, nil
+}
+
+// post queues a message of the run; it is dropped when the webhook is too
+// far behind.
+func (p *notifications) post(m notify.Message) {
+	if p == nil {
+		return
+	}
+	m.Source, m.RunID = p.source, p.runID
+	select {
+	case p.queue <- m:
+	default:
+		fmt.Fprintf(os.Stderr, "[WARNING] Not notified of %s: the webhook is too slow\n", m.Event)
+	}
+}
+
+// close waits until the queued messages are posted.
+func (p *notifications) close() {
+	if p == nil {
+		return
+	}
+	close(p.queue)
+	<-p.done
+}
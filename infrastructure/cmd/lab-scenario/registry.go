@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"aurora-bluegreen-lab/internal/experiments"
+	"aurora-bluegreen-lab/internal/report"
+)
+
+// loadRegistry returns the experiment registry the run is registered in: the
+// -registry-table, else the monitoring stack's. Without one the run is not
+// registered.
+func loadRegistry(ctx context.Context, o options, cfg aws.Config) *experiments.Registry {
+	table := o.registryTable
+	if table == "" {
+		var err error
+		if table, err = experiments.TableFromStacks(ctx, o.reader()); err != nil || table == "" {
+			fmt.Println("[INFO] Not registering the run: no experiment registry (deploy the monitoring stack or pass -registry-table)")
+			return nil
+		}
+	}
+	return experiments.New(cfg, table)
+}
+
+// register registers the run as running.
+func (r *runner) register(ctx context.Context, o options) {
+	if r.registry == nil {
+		return
+	}
+	r.entry = &experiments.Run{
+		RunID:     r.runID,
+		Source:    experiments.SourceScenario,
+		Name:      r.scenario.Name,
+		Status:    experiments.StatusRunning,
+		StartedAt: time.Now().UTC(),
+		Config:    runConfig(r.scenario, r.env, o),
+	}
+	r.put(ctx)
+}
+
+// registerResult registers how the run ended, its step times and, when the
+// report was written, its results.
+func (r *runner) registerResult(ctx context.Context, runErr error) {
+	if r.entry == nil {
+		return
+	}
+	r.entry.Finish(time.Now().UTC(), runErr)
+	r.entry.Timings = r.timeline.times
+	if r.report != nil {
+		r.entry.Results = runResults(r.report)
+	}
+	r.put(ctx)
+}
+
+// put writes the run to the registry; the experiment does not depend on it.
+func (r *runner) put(ctx context.Context) {
+	if err := r.registry.Put(ctx, r.entry); err != nil {
+		fmt.Fprintf(os.Stderr, "[WARNING] %v\n", err)
+		return
+	}
+	fmt.Printf("[INFO] Registered run %s (%s) in %s\n", r.runID, r.entry.Status, r.registry.Table())
+}
+
+// runConfig returns the configuration of the run to register; unset values
+// are left out.
+func runConfig(sc *Scenario, env *environment, o options) map[string]string {
+	config := map[string]string{
+		"cluster":           env.clusterIdentifier,
+		"transport":         o.transport,
+		"workload":          sc.Workload.Options,
+		"warmup":            time.Duration(sc.Workload.Warmup).String(),
+		"cooldown":          time.Duration(sc.Workload.Cooldown).String(),
+		"switchoverDelay":   time.Duration(sc.BlueGreen.SwitchoverDelay).String(),
+		"switchoverTimeout": time.Duration(sc.BlueGreen.SwitchoverTimeout).String(),
+
+		"targetEngineVersion":          sc.BlueGreen.TargetEngineVersion,
+		"targetClusterParameterGroup":  sc.BlueGreen.TargetClusterParameterGroup,
+		"targetInstanceParameterGroup": sc.BlueGreen.TargetInstanceParameterGroup,
+		"targetInstanceClass":          sc.BlueGreen.TargetInstanceClass,
+	}
+	if sc.Fault.Template != "" {
+		config["faultTemplate"] = sc.Fault.Template
+		config["faultDelay"] = time.Duration(sc.Fault.Delay).String()
+	}
+	for key, value := range config {
+		if value == "" {
+			delete(config, key)
+		}
+	}
+	return config
+}
+
+// runResults returns the headline numbers of the report to register.
+func runResults(rep *report.Report) map[string]float64 {
+	requests := rep.Stats.Last.Requests
+	results := map[string]float64{
+		"requests":          float64(requests.Total),
+		"failedRequests":    float64(requests.Failed),
+		"successRate":       requests.SuccessRate,
+		"impactedIntervals": float64(rep.Impacted),
+		// an error window of 0 is a run without failed requests
+		"errorWindowSeconds": 0,
+	}
+	if rep.ErrorWindow != nil {
+		results["errorWindowSeconds"] = rep.ErrorWindow.Duration().Seconds()
+	}
+	if rep.Switchover != nil {
+		results["switchoverSeconds"] = rep.Switchover.Duration().Seconds()
+	}
+	if rep.Stats.Final != nil {
+		for _, p := range rep.Stats.Final.Recovery {
+			if p.Max > results["recoveryMaxMs"] {
+				results["recoveryMaxMs"] = p.Max
+			}
+		}
+	}
+	return results
+}
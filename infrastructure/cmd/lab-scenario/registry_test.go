@@ -0,0 +1,68 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"aurora-bluegreen-lab/internal/report"
+)
+
+func TestRunResults(t *testing.T) {
+	t0 := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	stats := &report.Stats{
+		Intervals: []report.Interval{
+			{Start: t0, End: t0.Add(10 * time.Second), Success: 1000},
+			{Start: t0.Add(10 * time.Second), End: t0.Add(20 * time.Second), Success: 400, Failed: 25},
+			{Start: t0.Add(20 * time.Second), End: t0.Add(30 * time.Second), Success: 1000},
+		},
+		Last: &report.Record{Requests: report.Requests{Total: 2425, Success: 2400, Failed: 25, SuccessRate: 98.97}},
+		Final: &report.Record{Recovery: []report.Percentiles{
+			{Strategy: "reconnect", Max: 850},
+			{Strategy: "failover", Max: 1200},
+		}},
+	}
+	timeline := []report.Event{
+		{Timestamp: t0.Add(12 * time.Second), Event: report.EventSwitchoverStarted},
+		{Timestamp: t0.Add(18 * time.Second), Event: report.EventSwitchoverCompleted},
+	}
+
+	got := runResults(report.New("run", "stats.jsonl", stats, timeline, nil))
+	want := map[string]float64{
+		"requests":           2425,
+		"failedRequests":     25,
+		"successRate":        98.97,
+		"impactedIntervals":  1,
+		"errorWindowSeconds": 10,
+		"switchoverSeconds":  6,
+		"recoveryMaxMs":      1200,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestRunConfig(t *testing.T) {
+	sc := &Scenario{
+		Name:     "minor-upgrade",
+		Workload: Workload{Options: "--write-workers 20", Warmup: Duration(2 * time.Minute), Cooldown: Duration(time.Minute)},
+		BlueGreen: BlueGreen{
+			TargetEngineVersion: "8.0.mysql_aurora.3.08.0",
+			SwitchoverTimeout:   Duration(5 * time.Minute),
+		},
+	}
+	got := runConfig(sc, &environment{clusterIdentifier: "lab-cluster"}, options{transport: "ssm"})
+	want := map[string]string{
+		"cluster":             "lab-cluster",
+		"transport":           "ssm",
+		"workload":            "--write-workers 20",
+		"warmup":              "2m0s",
+		"cooldown":            "1m0s",
+		"switchoverDelay":     "0s",
+		"switchoverTimeout":   "5m0s",
+		"targetEngineVersion": "8.0.mysql_aurora.3.08.0",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"aurora-bluegreen-lab/internal/bluegreen"
+)
+
+// Scenario describes one experiment: the workload the simulator runs and the
+// Blue/Green deployment it runs against.
+type Scenario struct {
+	// Name prefixes the run ID and the Blue/Green deployment name
+	Name        string    `yaml:"name"`
+	Description string    `yaml:"description"`
+	Workload    Workload  `yaml:"workload"`
+	BlueGreen   BlueGreen `yaml:"blueGreen"`
+	Fault       Fault     `yaml:"fault"`
+}
+
+// Workload configures the simulator run.
+type Workload struct {
+	// Options are workload simulator command line options, e.g.
+	// "--write-workers 20 --workload transactions"
+	Options string `yaml:"options"`
+	// Warmup is how long the workload runs before the deployment is created
+	Warmup Duration `yaml:"warmup"`
+	// Cooldown is how long the workload keeps running after the switchover
+	Cooldown Duration `yaml:"cooldown"`
+}
+
+// BlueGreen configures the green environment and the switchover. Empty
+// targets keep the blue environment's settings.
+type BlueGreen struct {
+	TargetEngineVersion          string `yaml:"targetEngineVersion"`
+	TargetClusterParameterGroup  string `yaml:"targetClusterParameterGroup"`
+	TargetInstanceParameterGroup string `yaml:"targetInstanceParameterGroup"`
+	TargetInstanceClass          string `yaml:"targetInstanceClass"`
+	// UseGreenParameterGroups targets the aurora stack's green parameter
+	// groups (its greenParameters config) instead of named parameter groups
+	UseGreenParameterGroups bool `yaml:"useGreenParameterGroups"`
+	// MajorVersionUpgrade marks TargetEngineVersion as a new major version,
+	// which needs parameter groups of the new family; the version may be
+	// left to the --target-engine-version flag
+	MajorVersionUpgrade bool `yaml:"majorVersionUpgrade"`
+	// SwitchoverDelay is how long the workload runs against the available
+	// green environment before the switchover
+	SwitchoverDelay Duration `yaml:"switchoverDelay"`
+	// SwitchoverTimeout is the RDS switchover timeout (30s to 1h)
+	SwitchoverTimeout Duration `yaml:"switchoverTimeout"`
+	// DeleteDeployment deletes the Blue/Green deployment at the end of the
+	// run; the old blue cluster is kept
+	DeleteDeployment bool `yaml:"deleteDeployment"`
+}
+
+// Fault injects a fault during the switchover with an AWS FIS experiment
+// template, e.g. one of the fis stack's. An empty template injects none.
+type Fault struct {
+	// Template is the template's Name tag, or its end after the project
+	// name (reboot-writer, simulator-network-disruption)
+	Template string `yaml:"template"`
+	// Delay is how long after the switchover started the experiment starts
+	Delay Duration `yaml:"delay"`
+}
+
+// Duration is a time.Duration written as a Go duration string ("90s", "2m").
+type Duration time.Duration
+
+// UnmarshalYAML parses a duration string.
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	parsed, err := time.ParseDuration(node.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: invalid duration %q (use e.g. 90s or 2m)", node.Line, node.Value)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Scenario defaults.
+const (
+	defaultWarmup            = Duration(2 * time.Minute)
+	defaultCooldown          = Duration(2 * time.Minute)
+	defaultSwitchoverTimeout = Duration(bluegreen.DefaultSwitchoverTimeout)
+)
+
+var scenarioNamePattern = regexp.MustCompile(`^[a-z][a-z0-9-]{0,39}$`)
+
+// reservedOptions are simulator options set by the runner for every run.
+var reservedOptions = []string{"aurora-endpoint", "username", "password", "run-id", "output-format", "output-file", "control-port"}
+
+// loadScenario reads a scenario file, applies the defaults and validates it.
+func loadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := parseScenario(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+func parseScenario(data []byte) (*Scenario, error) {
+	s := &Scenario{
+		Workload:  Workload{Warmup: defaultWarmup, Cooldown: defaultCooldown},
+		BlueGreen: BlueGreen{SwitchoverTimeout: defaultSwitchoverTimeout},
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(s); err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	if !scenarioNamePattern.MatchString(s.Name) {
+		problems = append(problems, fmt.Sprintf("name must start with a letter and contain at most 40 lowercase letters, digits and hyphens (got %q)", s.Name))
+	}
+	for _, option := range strings.Fields(s.Workload.Options) {
+		for _, reserved := range reservedOptions {
+			if option == "--"+reserved || strings.HasPrefix(option, "--"+reserved+"=") {
+				problems = append(problems, fmt.Sprintf("workload.options must not set --%s; the runner sets it", reserved))
+			}
+		}
+	}
+	for _, d := range []struct {
+		key   string
+		value Duration
+	}{
+		{"workload.warmup", s.Workload.Warmup},
+		{"workload.cooldown", s.Workload.Cooldown},
+		{"blueGreen.switchoverDelay", s.BlueGreen.SwitchoverDelay},
+		{"fault.delay", s.Fault.Delay},
+	} {
+		if d.value < 0 {
+			problems = append(problems, fmt.Sprintf("%s must not be negative", d.key))
+		}
+	}
+	bg := s.BlueGreen
+	if bg.UseGreenParameterGroups && (bg.TargetClusterParameterGroup != "" || bg.TargetInstanceParameterGroup != "") {
+		problems = append(problems, "blueGreen.useGreenParameterGroups cannot be combined with targetClusterParameterGroup or targetInstanceParameterGroup")
+	}
+	if bg.MajorVersionUpgrade && !bg.UseGreenParameterGroups && bg.TargetClusterParameterGroup == "" {
+		problems = append(problems, "blueGreen.majorVersionUpgrade needs parameter groups of the new engine family (targetClusterParameterGroup or useGreenParameterGroups)")
+	}
+	if s.Fault.Delay > 0 && s.Fault.Template == "" {
+		problems = append(problems, "fault.delay needs fault.template")
+	}
+	if timeout := time.Duration(s.BlueGreen.SwitchoverTimeout); timeout < bluegreen.MinSwitchoverTimeout || timeout > bluegreen.MaxSwitchoverTimeout {
+		problems = append(problems, fmt.Sprintf("blueGreen.switchoverTimeout must be between 30s and 1h (got %s)", timeout))
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid scenario:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+	return s, nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLibrary(t *testing.T) {
+	names := libraryNames()
+	if len(names) < 5 {
+		t.Fatalf("library: got %v", names)
+	}
+	for _, name := range names {
+		s, err := resolveScenario(name)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if s.Name != name || s.Description == "" {
+			t.Errorf("%s: got name %q, description %q", name, s.Name, s.Description)
+		}
+	}
+
+	s, err := libraryScenario("minor-upgrade-write-heavy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.BlueGreen.TargetEngineVersion != "8.0.mysql_aurora.3.08.0" || !s.BlueGreen.DeleteDeployment {
+		t.Errorf("scenario: got %+v", s)
+	}
+	if time.Duration(s.Workload.Cooldown) != 3*time.Minute || time.Duration(s.BlueGreen.SwitchoverDelay) != time.Minute {
+		t.Errorf("durations: got cooldown %v, switchover delay %v", time.Duration(s.Workload.Cooldown), time.Duration(s.BlueGreen.SwitchoverDelay))
+	}
+
+	s, err = libraryScenario("minor-upgrade-writer-reboot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Fault.Template != "reboot-writer" || time.Duration(s.Fault.Delay) != 10*time.Second {
+		t.Errorf("fault: got %+v", s.Fault)
+	}
+
+	if _, err := resolveScenario("no-such-scenario"); err == nil || !strings.Contains(err.Error(), "minor-upgrade-read-heavy") {
+		t.Errorf("unknown scenario: got %v", err)
+	}
+}
+
+func TestParseScenarioDefaults(t *testing.T) {
+	s, err := parseScenario([]byte("name: params-only\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Workload.Warmup != defaultWarmup || s.Workload.Cooldown != defaultCooldown ||
+		s.BlueGreen.SwitchoverTimeout != defaultSwitchoverTimeout || s.BlueGreen.DeleteDeployment {
+		t.Errorf("defaults: got %+v", s)
+	}
+}
+
+func TestParseScenarioErrors(t *testing.T) {
+	_, err := parseScenario([]byte(`name: Minor_Upgrade
+workload:
+  options: --write-workers 10 --output-file=/tmp/x.jsonl --run-id mine
+  warmup: -1m
+blueGreen:
+  targetInstanceParameterGroup: lab-instance-params
+  useGreenParameterGroups: true
+  switchoverTimeout: 2h
+`))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{
+		`name must start with a letter and contain at most 40 lowercase letters, digits and hyphens (got "Minor_Upgrade")`,
+		"workload.options must not set --output-file; the runner sets it",
+		"workload.options must not set --run-id; the runner sets it",
+		"workload.warmup must not be negative",
+		"blueGreen.useGreenParameterGroups cannot be combined with targetClusterParameterGroup or targetInstanceParameterGroup",
+		"blueGreen.switchoverTimeout must be between 30s and 1h (got 2h0m0s)",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("missing problem %q in:\n%v", want, err)
+		}
+	}
+
+	for name, content := range map[string]string{
+		"unknown key":      "name: a\nworkload:\n  writers: 10\n",
+		"invalid duration": "name: a\nworkload:\n  warmup: 2 minutes\n",
+		"major upgrade":    "name: a\nblueGreen:\n  majorVersionUpgrade: true\n  targetEngineVersion: 9.0.x\n",
+		"fault delay":      "name: a\nfault:\n  delay: 10s\n",
+		"negative delay":   "name: a\nfault:\n  template: reboot-writer\n  delay: -10s\n",
+	} {
+		if _, err := parseScenario([]byte(content)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
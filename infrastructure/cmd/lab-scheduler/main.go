@@ -0,0 +1,213 @@
+// Command lab-scheduler is the AWS Lambda function of the scheduler stack.
+// EventBridge Scheduler invokes it with {"action": "stop"} after working
+// hours and {"action": "start"} in the morning, so the simulator host and,
+// optionally, the Aurora cluster do not run overnight.
+//
+// It runs on the provided.al2023 runtime as the bootstrap executable (the
+// scheduler stack builds and uploads it) and reads its targets from the
+// environment; unset targets are skipped:
+//
+//	INSTANCE_ID         simulator instance to stop and start
+//	AUTO_SCALING_GROUP  simulator Auto Scaling Group, scaled to zero and back
+//	SIMULATOR_COUNT     instance count of the Auto Scaling Group when started
+//	CLUSTER_IDENTIFIER  Aurora cluster to stop and start
+//
+// The simulator is stopped before and started after the cluster.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+
+	"aurora-bluegreen-lab/internal/golambda"
+)
+
+// event is the input of the schedules.
+type event struct {
+	// Action is stop or start
+	Action string `json:"action"`
+}
+
+// result is the response of an invocation.
+type result struct {
+	Action string `json:"action"`
+	// Changed lists the targets that were stopped or started
+	Changed []string `json:"changed"`
+}
+
+// scheduler stops and starts the lab's targets.
+type scheduler struct {
+	ec2         *ec2.Client
+	autoscaling *autoscaling.Client
+	rds         *rds.Client
+
+	instanceID        string
+	autoScalingGroup  string
+	simulatorCount    int32
+	clusterIdentifier string
+}
+
+func main() {
+	s, err := newScheduler(context.Background())
+	if err == nil {
+		err = golambda.Serve(func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+			action, err := parseEvent(payload)
+			if err != nil {
+				return nil, err
+			}
+			return s.run(ctx, action)
+		})
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func newScheduler(ctx context.Context) (*scheduler, error) {
+	s := &scheduler{
+		instanceID:        os.Getenv("INSTANCE_ID"),
+		autoScalingGroup:  os.Getenv("AUTO_SCALING_GROUP"),
+		clusterIdentifier: os.Getenv("CLUSTER_IDENTIFIER"),
+	}
+	if s.autoScalingGroup != "" {
+		count, err := strconv.Atoi(os.Getenv("SIMULATOR_COUNT"))
+		if err != nil || count < 1 {
+			return nil, fmt.Errorf("SIMULATOR_COUNT must be a positive number with AUTO_SCALING_GROUP, got %q", os.Getenv("SIMULATOR_COUNT"))
+		}
+		s.simulatorCount = int32(count)
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading AWS configuration: %w", err)
+	}
+	s.ec2 = ec2.NewFromConfig(cfg)
+	s.autoscaling = autoscaling.NewFromConfig(cfg)
+	s.rds = rds.NewFromConfig(cfg)
+	return s, nil
+}
+
+// progressive is the log wording of each action.
+var progressive = map[string]string{"stop": "Stopping", "start": "Starting"}
+
+// parseEvent returns the action of a schedule's input.
+func parseEvent(payload json.RawMessage) (string, error) {
+	var e event
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return "", fmt.Errorf("invalid event %s: %w", payload, err)
+	}
+	if e.Action != "stop" && e.Action != "start" {
+		return "", fmt.Errorf(`event action must be "stop" or "start" (got %q)`, e.Action)
+	}
+	return e.Action, nil
+}
+
+func (s *scheduler) run(ctx context.Context, action string) (*result, error) {
+	res := &result{Action: action, Changed: []string{}}
+	steps := []func(context.Context, string, *result) error{s.simulator, s.cluster}
+	if action == "start" {
+		steps = []func(context.Context, string, *result) error{s.cluster, s.simulator}
+	}
+	for _, step := range steps {
+		if err := step(ctx, action, res); err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+// simulator stops or starts the simulator instance and Auto Scaling Group.
+// Stopping a stopped instance or starting a running one has no effect.
+func (s *scheduler) simulator(ctx context.Context, action string, res *result) error {
+	if s.instanceID != "" {
+		var err error
+		if action == "stop" {
+			_, err = s.ec2.StopInstances(ctx, &ec2.StopInstancesInput{InstanceIds: []string{s.instanceID}})
+		} else {
+			_, err = s.ec2.StartInstances(ctx, &ec2.StartInstancesInput{InstanceIds: []string{s.instanceID}})
+		}
+		if err != nil {
+			return fmt.Errorf("%s instance %s: %w", strings.ToLower(progressive[action]), s.instanceID, err)
+		}
+		res.Changed = append(res.Changed, "instance/"+s.instanceID)
+		fmt.Printf("[INFO] %s instance %s\n", progressive[action], s.instanceID)
+	}
+
+	if s.autoScalingGroup != "" {
+		// Stopped group instances would be replaced as unhealthy, so the
+		// group is scaled in instead
+		size := s.simulatorCount
+		if action == "stop" {
+			size = 0
+		}
+		_, err := s.autoscaling.UpdateAutoScalingGroup(ctx, &autoscaling.UpdateAutoScalingGroupInput{
+			AutoScalingGroupName: aws.String(s.autoScalingGroup),
+			MinSize:              aws.Int32(size),
+			DesiredCapacity:      aws.Int32(size),
+		})
+		if err != nil {
+			return fmt.Errorf("scaling Auto Scaling Group %s to %d: %w", s.autoScalingGroup, size, err)
+		}
+		res.Changed = append(res.Changed, "autoScalingGroup/"+s.autoScalingGroup)
+		fmt.Printf("[INFO] Scaled Auto Scaling Group %s to %d\n", s.autoScalingGroup, size)
+	}
+	return nil
+}
+
+// cluster stops or starts the Aurora cluster when it is in a state that
+// allows it.
+func (s *scheduler) cluster(ctx context.Context, action string, res *result) error {
+	if s.clusterIdentifier == "" {
+		return nil
+	}
+	out, err := s.rds.DescribeDBClusters(ctx, &rds.DescribeDBClustersInput{
+		DBClusterIdentifier: aws.String(s.clusterIdentifier),
+	})
+	if err != nil {
+		return fmt.Errorf("describing cluster %s: %w", s.clusterIdentifier, err)
+	}
+	if len(out.DBClusters) == 0 {
+		return fmt.Errorf("cluster %s not found", s.clusterIdentifier)
+	}
+	status := aws.ToString(out.DBClusters[0].Status)
+	if !clusterChange(action, status) {
+		fmt.Printf("[INFO] Cluster %s is %s; leaving it as is\n", s.clusterIdentifier, status)
+		return nil
+	}
+
+	if action == "stop" {
+		_, err = s.rds.StopDBCluster(ctx, &rds.StopDBClusterInput{DBClusterIdentifier: aws.String(s.clusterIdentifier)})
+	} else {
+		_, err = s.rds.StartDBCluster(ctx, &rds.StartDBClusterInput{DBClusterIdentifier: aws.String(s.clusterIdentifier)})
+	}
+	if err != nil {
+		return fmt.Errorf("%s cluster %s: %w", strings.ToLower(progressive[action]), s.clusterIdentifier, err)
+	}
+	res.Changed = append(res.Changed, "cluster/"+s.clusterIdentifier)
+	fmt.Printf("[INFO] %s cluster %s\n", progressive[action], s.clusterIdentifier)
+	return nil
+}
+
+// clusterChange reports whether a cluster in status can be stopped or
+// started: only available clusters stop and only stopped clusters start, so
+// a cluster that is busy (e.g. during a switchover) is left alone.
+func clusterChange(action, status string) bool {
+	switch action {
+	case "stop":
+		return status == "available"
+	case "start":
+		return status == "stopped"
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseEvent(t *testing.T) {
+	for _, tt := range []struct {
+		payload string
+		want    string
+		wantErr bool
+	}{
+		{payload: `{"action": "stop"}`, want: "stop"},
+		{payload: `{"action": "start"}`, want: "start"},
+		{payload: `{"action": "restart"}`, wantErr: true},
+		{payload: `{}`, wantErr: true},
+		{payload: `not json`, wantErr: true},
+	} {
+		got, err := parseEvent(json.RawMessage(tt.payload))
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseEvent(%s) = %q, %v; want %q, error %v", tt.payload, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestClusterChange(t *testing.T) {
+	for _, tt := range []struct {
+		action, status string
+		want           bool
+	}{
+		{"stop", "available", true},
+		{"stop", "stopped", false},
+		{"stop", "stopping", false},
+		{"stop", "modifying", false},
+		{"start", "stopped", true},
+		{"start", "available", false},
+		{"start", "starting", false},
+	} {
+		if got := clusterChange(tt.action, tt.status); got != tt.want {
+			t.Errorf("clusterChange(%q, %q) = %v, want %v", tt.action, tt.status, got, tt.want)
+		}
+	}
+}
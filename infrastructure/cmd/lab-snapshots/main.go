@@ -0,0 +1,186 @@
+// Command lab-snapshots is the AWS Lambda function of the ops stack. On each
+// scheduled invocation it takes a manual snapshot of the lab cluster and
+// deletes the snapshots it created earlier that are older than the retention
+// period, so every experiment window starts with a fresh restore point.
+//
+// It runs on the provided.al2023 runtime as the bootstrap executable (the ops
+// stack builds and uploads it) and reads its settings from the environment:
+//
+//	CLUSTER_IDENTIFIER  cluster to snapshot
+//	SNAPSHOT_PREFIX     prefix of the snapshot identifiers it creates and prunes
+//	RETENTION_DAYS      age in days after which its snapshots are deleted
+//	SNAPSHOT_TAGS       JSON object of the lab's project-wide tags
+//
+// Snapshots created with tags do not copy the cluster's, so the snapshots
+// are tagged with SNAPSHOT_TAGS plus CreatedBy.
+//
+// Only manual snapshots whose identifier starts with SNAPSHOT_PREFIX are ever
+// deleted.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/aws/aws-sdk-go-v2/service/rds/types"
+
+	"aurora-bluegreen-lab/internal/golambda"
+)
+
+// snapshotter snapshots a cluster and prunes its old snapshots.
+type snapshotter struct {
+	rds               *rds.Client
+	clusterIdentifier string
+	prefix            string
+	retention         time.Duration
+	tags              []types.Tag
+}
+
+// result is the response of an invocation.
+type result struct {
+	Created string   `json:"created,omitempty"`
+	Deleted []string `json:"deleted"`
+}
+
+func main() {
+	s, err := newSnapshotter(context.Background())
+	if err == nil {
+		err = golambda.Serve(func(ctx context.Context, _ json.RawMessage) (interface{}, error) {
+			return s.run(ctx, time.Now().UTC())
+		})
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func newSnapshotter(ctx context.Context) (*snapshotter, error) {
+	s := &snapshotter{
+		clusterIdentifier: os.Getenv("CLUSTER_IDENTIFIER"),
+		prefix:            os.Getenv("SNAPSHOT_PREFIX"),
+	}
+	days, err := strconv.Atoi(os.Getenv("RETENTION_DAYS"))
+	if err != nil || days < 1 {
+		return nil, fmt.Errorf("RETENTION_DAYS must be a positive number of days, got %q", os.Getenv("RETENTION_DAYS"))
+	}
+	s.retention = time.Duration(days) * 24 * time.Hour
+	if s.clusterIdentifier == "" || s.prefix == "" {
+		return nil, fmt.Errorf("CLUSTER_IDENTIFIER and SNAPSHOT_PREFIX must be set")
+	}
+	if s.tags, err = snapshotTags(os.Getenv("SNAPSHOT_TAGS")); err != nil {
+		return nil, err
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading AWS configuration: %w", err)
+	}
+	s.rds = rds.NewFromConfig(cfg)
+	return s, nil
+}
+
+// run prunes the expired snapshots and starts a new one; the snapshot is
+// taken even when pruning fails.
+func (s *snapshotter) run(ctx context.Context, now time.Time) (*result, error) {
+	res := &result{Deleted: []string{}}
+	pruneErr := s.prune(ctx, now, res)
+
+	id := snapshotIdentifier(s.prefix, now)
+	_, err := s.rds.CreateDBClusterSnapshot(ctx, &rds.CreateDBClusterSnapshotInput{
+		DBClusterIdentifier:         aws.String(s.clusterIdentifier),
+		DBClusterSnapshotIdentifier: aws.String(id),
+		Tags:                        s.tags,
+	})
+	if err != nil {
+		err = fmt.Errorf("creating snapshot %s of %s: %w", id, s.clusterIdentifier, err)
+	} else {
+		res.Created = id
+		fmt.Printf("[SUCCESS] Creating snapshot %s of %s\n", id, s.clusterIdentifier)
+	}
+	return res, errors.Join(pruneErr, err)
+}
+
+// prune deletes the snapshots of the prefix created before the retention
+// period.
+func (s *snapshotter) prune(ctx context.Context, now time.Time, res *result) error {
+	var snapshots []types.DBClusterSnapshot
+	pages := rds.NewDescribeDBClusterSnapshotsPaginator(s.rds, &rds.DescribeDBClusterSnapshotsInput{
+		SnapshotType: aws.String("manual"),
+	})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("listing cluster snapshots: %w", err)
+		}
+		snapshots = append(snapshots, page.DBClusterSnapshots...)
+	}
+
+	var errs []error
+	for _, id := range expiredSnapshots(snapshots, s.prefix, now.Add(-s.retention)) {
+		_, err := s.rds.DeleteDBClusterSnapshot(ctx, &rds.DeleteDBClusterSnapshotInput{
+			DBClusterSnapshotIdentifier: aws.String(id),
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("deleting snapshot %s: %w", id, err))
+			continue
+		}
+		res.Deleted = append(res.Deleted, id)
+		fmt.Printf("[INFO] Deleted snapshot %s\n", id)
+	}
+	return errors.Join(errs...)
+}
+
+// snapshotIdentifier names the snapshot taken at now.
+func snapshotIdentifier(prefix string, now time.Time) string {
+	return prefix + "-" + now.UTC().Format("20060102-1504")
+}
+
+// expiredSnapshots returns the available snapshots named by
+// snapshotIdentifier with the prefix that were created before cutoff.
+func expiredSnapshots(snapshots []types.DBClusterSnapshot, prefix string, cutoff time.Time) []string {
+	var expired []string
+	for _, snapshot := range snapshots {
+		id := aws.ToString(snapshot.DBClusterSnapshotIdentifier)
+		if !strings.HasPrefix(id, prefix+"-") || aws.ToString(snapshot.Status) != "available" {
+			continue
+		}
+		if created := aws.ToTime(snapshot.SnapshotCreateTime); !created.IsZero() && created.Before(cutoff) {
+			expired = append(expired, id)
+		}
+	}
+	return expired
+}
+
+// snapshotTags returns the tags of the snapshots: the tags of the JSON
+// object raw, sorted by key, plus CreatedBy.
+func snapshotTags(raw string) ([]types.Tag, error) {
+	tags := map[string]string{}
+	if raw != "" {
+		if err := json.Unmarshal([]byte(raw), &tags); err != nil {
+			return nil, fmt.Errorf("SNAPSHOT_TAGS must be a JSON object of tags: %w", err)
+		}
+	}
+	tags["CreatedBy"] = "lab-snapshots"
+
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	result := make([]types.Tag, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, types.Tag{Key: aws.String(key), Value: aws.String(tags[key])})
+	}
+	return result, nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rds/types"
+)
+
+func TestSnapshotIdentifier(t *testing.T) {
+	now := time.Date(2026, 3, 9, 7, 45, 30, 0, time.UTC)
+	if got, want := snapshotIdentifier("lab-scheduled", now), "lab-scheduled-20260309-0745"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestExpiredSnapshots(t *testing.T) {
+	now := time.Date(2026, 3, 9, 8, 0, 0, 0, time.UTC)
+	snapshot := func(id, status string, age time.Duration) types.DBClusterSnapshot {
+		return types.DBClusterSnapshot{
+			DBClusterSnapshotIdentifier: aws.String(id),
+			Status:                      aws.String(status),
+			SnapshotCreateTime:          aws.Time(now.Add(-age)),
+		}
+	}
+	day := 24 * time.Hour
+	snapshots := []types.DBClusterSnapshot{
+		snapshot("lab-scheduled-20260220-0800", "available", 17*day),
+		snapshot("lab-scheduled-20260301-0800", "available", 8*day),
+		snapshot("lab-scheduled-20260305-0800", "available", 4*day),
+		snapshot("lab-scheduled-20260228-0800", "creating", 9*day),
+		// Snapshots of other prefixes are never deleted
+		snapshot("lab-before-upgrade", "available", 30*day),
+		snapshot("other-scheduled-20260101-0800", "available", 60*day),
+	}
+
+	got := expiredSnapshots(snapshots, "lab-scheduled", now.Add(-7*day))
+	want := []string{"lab-scheduled-20260220-0800", "lab-scheduled-20260301-0800"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestSnapshotTags(t *testing.T) {
+	tags, err := snapshotTags(`{"Project": "lab", "Environment": "dev"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, tag := range tags {
+		got = append(got, aws.ToString(tag.Key)+"="+aws.ToString(tag.Value))
+	}
+	if want := []string{"CreatedBy=lab-snapshots", "Environment=dev", "Project=lab"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if tags, err := snapshotTags(""); err != nil || len(tags) != 1 {
+		t.Errorf("snapshotTags(\"\") = %v, %v; want only CreatedBy", tags, err)
+	}
+	if _, err := snapshotTags("Project=lab"); err == nil {
+		t.Error("expected an error for a value that is not a JSON object")
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+
+	"aurora-bluegreen-lab/internal/components"
+	labconfig "aurora-bluegreen-lab/internal/config"
+	"aurora-bluegreen-lab/internal/cost"
+	"aurora-bluegreen-lab/internal/labels"
+	"aurora-bluegreen-lab/internal/providers"
+)
+
+func main() {
+	pulumi.Run(func(ctx *pulumi.Context) error {
+		// Load configuration
+		cfg := config.New(ctx, "")
+		settings, err := labconfig.LoadDms(cfg)
+		if err != nil {
+			return err
+		}
+		dbPassword := cfg.RequireSecret("dbPassword")
+
+		lb, err := labels.New(ctx, cfg)
+		if err != nil {
+			return err
+		}
+
+		// Create the AWS provider for the stack's region
+		provider, region, err := providers.New(ctx, cfg, lb)
+		if err != nil {
+			return err
+		}
+		inRegion := pulumi.Provider(provider)
+
+		// Reference the VPC stack for the network and the Aurora stack for
+		// the source endpoint
+		vpcStackRef, err := pulumi.NewStackReference(ctx, settings.VpcStackName, nil)
+		if err != nil {
+			return err
+		}
+		auroraStackRef, err := pulumi.NewStackReference(ctx, settings.AuroraStackName, nil)
+		if err != nil {
+			return err
+		}
+
+		args := &components.LabDmsReplicationArgs{
+			Labels: lb,
+			Region: region,
+			VpcId:  vpcStackRef.GetStringOutput(pulumi.String("vpcId")),
+			SubnetIds: pulumi.StringArray{
+				vpcStackRef.GetStringOutput(pulumi.String("auroraSubnet1Id")),
+				vpcStackRef.GetStringOutput(pulumi.String("auroraSubnet2Id")),
+			},
+			AuroraSecurityGroupId: vpcStackRef.GetStringOutput(pulumi.String("auroraSecurityGroupId")),
+			ClusterEndpoint:       auroraStackRef.GetStringOutput(pulumi.String("clusterEndpoint")),
+			DatabaseName:          auroraStackRef.GetStringOutput(pulumi.String("databaseName")),
+			MasterUsername:        auroraStackRef.GetStringOutput(pulumi.String("masterUsername")),
+			MasterPassword:        dbPassword,
+			TablePattern:          settings.TablePattern,
+			InstanceClass:         settings.ReplicationInstanceClass,
+			MigrationType:         settings.MigrationType,
+			CreateDmsVpcRole:      settings.CreateDmsVpcRole,
+		}
+		if settings.S3GatewayEndpoint {
+			// Existing VPCs export no route tables
+			args.PrivateRouteTableId = vpcStackRef.GetOutput(pulumi.String("privateRouteTableId")).ApplyT(func(v interface{}) (string, error) {
+				routeTableId, _ := v.(string)
+				if routeTableId == "" {
+					return "", fmt.Errorf("VPC stack %s exports no privateRouteTableId; set s3GatewayEndpoint false when the VPC already reaches S3", settings.VpcStackName)
+				}
+				return routeTableId, nil
+			}).(pulumi.StringOutput)
+		}
+		replication, err := components.NewLabDmsReplication(ctx, lb.Name("dms"), args, inRegion)
+		if err != nil {
+			return err
+		}
+
+		// Export outputs
+		ctx.Export("region", pulumi.String(region))
+		ctx.Export("replicationInstanceArn", replication.ReplicationInstance.ReplicationInstanceArn)
+		ctx.Export("replicationTaskArn", replication.ReplicationTask.ReplicationTaskArn)
+		ctx.Export("replicationTaskId", replication.ReplicationTask.ReplicationTaskId)
+		ctx.Export("migrationType", pulumi.String(settings.MigrationType))
+		ctx.Export("sourceEndpointArn", replication.SourceEndpoint.EndpointArn)
+		ctx.Export("sourceServerName", replication.SourceEndpoint.ServerName)
+		ctx.Export("targetBucketName", replication.Bucket.Bucket)
+		if replication.S3GatewayEndpoint != nil {
+			ctx.Export("s3GatewayEndpointId", replication.S3GatewayEndpoint.ID())
+		}
+
+		// The replication instance; the task, endpoints and the gateway
+		// endpoint are free, the bucket's storage negligible
+		var estimate cost.Estimate
+		estimate.DmsInstances(settings.ReplicationInstanceClass, 1, 20)
+		if err := estimate.Export(ctx); err != nil {
+			return err
+		}
+
+		// Publish the key outputs for runtime discovery without Pulumi access
+		outputParameters, err := components.NewLabOutputParameters(ctx, lb.Name("dms-outputs"), &components.LabOutputParametersArgs{
+			Labels: lb,
+			Stack:  "dms",
+			Values: map[string]pulumi.StringInput{
+				"region":            pulumi.String(region),
+				"replicationTaskId": replication.ReplicationTask.ReplicationTaskId,
+				"sourceServerName":  replication.SourceEndpoint.ServerName.Elem(),
+				"targetBucketName":  replication.Bucket.Bucket,
+			},
+		}, inRegion)
+		if err != nil {
+			return err
+		}
+		ctx.Export("outputParameterPrefix", pulumi.String(outputParameters.Prefix))
+
+		return nil
+	})
+}
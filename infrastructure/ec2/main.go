@@ -1,54 +1,99 @@
 package main
 
 import (
-	"encoding/base64"
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ec2"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+
+	"aurora-bluegreen-lab/internal/components"
+	labconfig "aurora-bluegreen-lab/internal/config"
+	"aurora-bluegreen-lab/internal/cost"
+	"aurora-bluegreen-lab/internal/labels"
+	"aurora-bluegreen-lab/internal/providers"
 )
 
 func main() {
 	pulumi.Run(func(ctx *pulumi.Context) error {
 		// Load configuration
 		cfg := config.New(ctx, "")
-
-		projectName := cfg.Get("projectName")
-		if projectName == "" {
-			projectName = "aurora-bluegreen-lab"
+		settings, err := labconfig.LoadEc2(cfg)
+		if err != nil {
+			return err
 		}
 
-		instanceType := cfg.Get("instanceType")
-		if instanceType == "" {
-			instanceType = "t3.xlarge"
+		lb, err := labels.New(ctx, cfg)
+		if err != nil {
+			return err
 		}
 
-		keyName := cfg.Get("keyName")
-		if keyName == "" {
-			return fmt.Errorf("keyName is required. Please set it with: pulumi config set keyName <your-key-pair-name>")
+		// Create the AWS provider for the stack's region
+		provider, region, err := providers.New(ctx, cfg, lb)
+		if err != nil {
+			return err
 		}
+		inRegion := pulumi.Provider(provider)
 
 		// Reference VPC stack outputs
-		vpcStack := cfg.Require("vpcStackName")
-		vpcStackRef, err := pulumi.NewStackReference(ctx, vpcStack, nil)
+		vpcStackRef, err := pulumi.NewStackReference(ctx, settings.VpcStackName, nil)
 		if err != nil {
 			return err
 		}
 
+		// One subnet per availability zone: the public EC2 subnets, or the
+		// private EKS subnets behind the NAT gateway. VPC stacks without a
+		// second EC2 subnet (existing VPCs) keep every instance in the first
 		ec2SubnetId := vpcStackRef.GetStringOutput(pulumi.String("ec2SubnetId"))
+		subnetIds := []pulumi.StringInput{
+			ec2SubnetId,
+			pulumi.All(vpcStackRef.GetOutput(pulumi.String("ec2Subnet2Id")), ec2SubnetId).ApplyT(func(ids []interface{}) string {
+				if id, ok := ids[0].(string); ok && id != "" {
+					return id
+				}
+				return ids[1].(string)
+			}).(pulumi.StringOutput),
+		}
+		if settings.PrivateSimulator {
+			subnetIds = []pulumi.StringInput{
+				privateSubnetId(vpcStackRef, settings.VpcStackName, "eksSubnet1Id"),
+				privateSubnetId(vpcStackRef, settings.VpcStackName, "eksSubnet2Id"),
+			}
+		}
 		ec2SecurityGroupId := vpcStackRef.GetStringOutput(pulumi.String("ec2SecurityGroupId"))
 
 		// Reference Aurora stack outputs (optional, for convenience)
-		auroraStackName := cfg.Get("auroraStackName")
-		var clusterEndpoint pulumi.StringOutput
-		if auroraStackName != "" {
-			auroraStackRef, err := pulumi.NewStackReference(ctx, auroraStackName, nil)
+		var clusterEndpoint, masterUsername, clusterResourceId pulumi.StringOutput
+		hasClusterEndpoint := false
+		if settings.AuroraStackName != "" {
+			auroraStackRef, err := pulumi.NewStackReference(ctx, settings.AuroraStackName, nil)
 			if err == nil {
 				clusterEndpoint = auroraStackRef.GetStringOutput(pulumi.String("clusterEndpoint"))
+				masterUsername = auroraStackRef.GetStringOutput(pulumi.String("masterUsername"))
+				clusterResourceId = auroraStackRef.GetStringOutput(pulumi.String("clusterResourceId"))
+				hasClusterEndpoint = true
 			}
 		}
 
+		// With the Aurora password configured, the simulator runs as a systemd
+		// service reading the endpoint and credentials from SSM and Secrets Manager
+		dbPassword, err := cfg.TrySecret("dbPassword")
+		hasDbPassword := err == nil
+
+		// Validate that the instance type supports the selected architecture
+		instanceTypeInfo, err := ec2.GetInstanceType(ctx, &ec2.GetInstanceTypeArgs{
+			InstanceType: settings.InstanceType,
+		}, inRegion)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(instanceTypeInfo.SupportedArchitectures, settings.Architecture) {
+			return fmt.Errorf("instanceType %s does not support architecture %s (supported: %v)",
+				settings.InstanceType, settings.Architecture, instanceTypeInfo.SupportedArchitectures)
+		}
+
 		// Get the latest Amazon Linux 2023 AMI
 		ami, err := ec2.LookupAmi(ctx, &ec2.LookupAmiArgs{
 			MostRecent: pulumi.BoolRef(true),
@@ -56,166 +101,252 @@ func main() {
 			Filters: []ec2.GetAmiFilter{
 				{
 					Name:   "name",
-					Values: []string{"al2023-ami-2023.*-x86_64"},
+					Values: []string{"al2023-ami-2023.*-" + settings.Architecture},
 				},
 				{
 					Name:   "architecture",
-					Values: []string{"x86_64"},
+					Values: []string{settings.Architecture},
 				},
 				{
 					Name:   "virtualization-type",
 					Values: []string{"hvm"},
 				},
 			},
-		})
+		}, inRegion)
 		if err != nil {
 			return err
 		}
 
-		// User data script to install Java and prepare the workload simulator
-		userData := `#!/bin/bash
-set -e
-
-# Update system
-yum update -y
-
-# Install Amazon Corretto 17 (OpenJDK)
-yum install -y java-17-amazon-corretto-headless
-
-# Install MySQL client for testing
-yum install -y mysql
-
-# Install git (for cloning the workload simulator if needed)
-yum install -y git
-
-# Create directory for workload simulator
-mkdir -p /opt/workload-simulator
-chown ec2-user:ec2-user /opt/workload-simulator
-
-# Create a helper script to run the workload simulator
-cat > /opt/workload-simulator/run-simulator.sh << 'EOF'
-#!/bin/bash
-# Helper script to run the workload simulator
-# Usage: ./run-simulator.sh <aurora-endpoint> [additional-options]
-
-if [ -z "$1" ]; then
-  echo "Usage: $0 <aurora-endpoint> [additional-options]"
-  echo "Example: $0 my-cluster.cluster-xxxxx.us-east-1.rds.amazonaws.com --write-workers 10"
-  exit 1
-fi
-
-AURORA_ENDPOINT=$1
-shift
-
-java -jar /opt/workload-simulator/workload-simulator.jar \
-  --aurora-endpoint "$AURORA_ENDPOINT" \
-  --database-name lab_db \
-  --write-workers 10 \
-  --write-rate 100 \
-  --connection-pool-size 100 \
-  "$@"
-EOF
-
-chmod +x /opt/workload-simulator/run-simulator.sh
-chown ec2-user:ec2-user /opt/workload-simulator/run-simulator.sh
-
-# Create a README with instructions
-cat > /opt/workload-simulator/README.txt << 'EOF'
-Aurora Blue-Green Deployment Lab - Workload Simulator
-
-This directory contains the workload simulator for testing Aurora Blue-Green deployments.
-
-SETUP:
-1. Upload the workload-simulator.jar file to this directory:
-   scp -i your-key.pem workload-simulator.jar ec2-user@<instance-ip>:/opt/workload-simulator/
-
-USAGE:
-1. Run the workload simulator directly:
-   java -jar workload-simulator.jar \
-     --aurora-endpoint <your-cluster-endpoint> \
-     --database-name lab_db \
-     --write-workers 10 \
-     --write-rate 100 \
-     --connection-pool-size 100
-
-2. Or use the helper script:
-   ./run-simulator.sh <your-cluster-endpoint>
-
-3. To run with custom parameters:
-   ./run-simulator.sh <your-cluster-endpoint> --write-workers 20 --write-rate 200
-
-AVAILABLE PARAMETERS:
-  --aurora-endpoint       : Aurora cluster writer endpoint (required)
-  --database-name         : Database name (default: lab_db)
-  --write-workers         : Number of concurrent write workers (default: 10)
-  --write-rate            : Writes per second per worker (default: 100)
-  --connection-pool-size  : Database connection pool size (default: 100)
-  --log-interval          : Statistics log interval in seconds (default: 10)
-
-TESTING THE BLUE-GREEN DEPLOYMENT:
-1. Start the workload simulator
-2. Observe the console output showing successful write operations
-3. In AWS Console or CLI, create a Blue-Green deployment for your Aurora cluster
-4. Keep the workload simulator running during the upgrade
-5. Watch for connection errors during the Blue-Green switchover
-6. Validate that the workload resumes after the switchover completes
-
-For more information, see the project documentation at:
-/home/ec2-user/aurora-blue-green-deployment-lab/README.md
-EOF
-
-chown ec2-user:ec2-user /opt/workload-simulator/README.txt
-
-echo "EC2 instance setup completed successfully" > /var/log/user-data.log
-`
-
-		userDataEncoded := pulumi.String(userData).ToStringOutput().ApplyT(func(s string) string {
-			return base64.StdEncoding.EncodeToString([]byte(s))
-		}).(pulumi.StringOutput)
+		// Configure the systemd simulator service when the endpoint and password
+		// are available
+		var service *components.SimulatorServiceArgs
+		if hasClusterEndpoint && hasDbPassword {
+			service = &components.SimulatorServiceArgs{
+				ClusterEndpoint: clusterEndpoint,
+				MasterUsername:  masterUsername,
+				DbPassword:      dbPassword,
+				Options:         settings.SimulatorOptions,
+			}
+		}
 
-		// Create EC2 instance
-		instance, err := ec2.NewInstance(ctx, fmt.Sprintf("%s-workload-simulator", projectName), &ec2.InstanceArgs{
-			InstanceType:               pulumi.String(instanceType),
-			Ami:                        pulumi.String(ami.Id),
-			SubnetId:                   ec2SubnetId,
-			VpcSecurityGroupIds:        pulumi.StringArray{ec2SecurityGroupId},
-			KeyName:                    pulumi.String(keyName),
-			UserDataBase64:             userDataEncoded,
-			AssociatePublicIpAddress:   pulumi.Bool(true),
-			DisableApiTermination:      pulumi.Bool(false),
-			InstanceInitiatedShutdownBehavior: pulumi.String("stop"),
-			Monitoring:                 pulumi.Bool(true),
-			EbsOptimized:               pulumi.Bool(true),
-			RootBlockDevice: &ec2.InstanceRootBlockDeviceArgs{
-				VolumeSize:          pulumi.Int(30),
-				VolumeType:          pulumi.String("gp3"),
-				DeleteOnTermination: pulumi.Bool(true),
-				Encrypted:           pulumi.Bool(true),
-			},
-			Tags: pulumi.StringMap{
-				"Name":    pulumi.String(fmt.Sprintf("%s-workload-simulator", projectName)),
-				"Project": pulumi.String(projectName),
-				"Role":    pulumi.String("workload-simulator"),
-			},
-		})
+		// Run the registry stack's simulator image instead of the jar: the
+		// ref of the image its docker-build resource pushed, pinned to the
+		// digest, so instances pull exactly that build. It must be built for
+		// the instances' architecture
+		if service != nil && settings.RegistryStackName != "" {
+			registryStackRef, err := pulumi.NewStackReference(ctx, settings.RegistryStackName, nil)
+			if err != nil {
+				return err
+			}
+			service.Image = pulumi.All(
+				registryStackRef.GetOutput(pulumi.String("simulatorImageUri")),
+				registryStackRef.GetOutput(pulumi.String("platform")),
+			).ApplyT(func(outputs []interface{}) (string, error) {
+				image, _ := outputs[0].(string)
+				platform, _ := outputs[1].(string)
+				if image == "" {
+					return "", fmt.Errorf("registry stack %s has no simulatorImageUri; deploy it first", settings.RegistryStackName)
+				}
+				if want := imagePlatforms[settings.Architecture]; platform != want {
+					return "", fmt.Errorf("registry stack %s builds %s images; set its platform to %s for architecture %s",
+						settings.RegistryStackName, platform, want, settings.Architecture)
+				}
+				return image, nil
+			}).(pulumi.StringOutput)
+		}
+
+		// Create the simulator host
+		hostArgs := &components.LabSimulatorHostArgs{
+			Labels:               lb,
+			Region:               region,
+			InstanceType:         settings.InstanceType,
+			AmiId:                ami.Id,
+			KeyName:              settings.KeyName,
+			SubnetIds:            subnetIds,
+			SecurityGroupId:      ec2SecurityGroupId,
+			Private:              settings.PrivateSimulator,
+			InstanceCount:        settings.InstanceCount,
+			AllocateEip:          settings.AllocateEip,
+			Count:                settings.SimulatorCount,
+			UseSpot:              settings.UseSpot,
+			OnDemandBaseCapacity: settings.SpotOnDemandBaseCapacity,
+			SpotInstanceTypes:    settings.SpotInstanceTypes,
+			Service:              service,
+			JarPath:              settings.SimulatorJar,
+			MetricsNamespace:     settings.SimulatorMetricsNamespace,
+			ExperimentTable:      settings.ExperimentTableName,
+		}
+		if settings.SimulatorLogs {
+			hostArgs.LogRetentionDays = settings.SimulatorLogRetentionDays
+		}
+		if settings.IamDbUser != "" && hasClusterEndpoint {
+			hostArgs.IamDbUser = settings.IamDbUser
+			hostArgs.ClusterResourceId = clusterResourceId
+		}
+		host, err := components.NewLabSimulatorHost(ctx, lb.Name("simulator"), hostArgs, inRegion)
 		if err != nil {
 			return err
 		}
 
+		// Key outputs published to SSM Parameter Store for runtime discovery
+		// without Pulumi access
+		outputValues := map[string]pulumi.StringInput{"region": pulumi.String(region)}
+		if service != nil {
+			outputValues["clusterEndpointParameter"] = pulumi.String(host.EndpointParameterName)
+			outputValues["credentialsSecretArn"] = host.CredentialsSecret.Arn
+			if service.Image != nil {
+				outputValues["simulatorImage"] = service.Image
+			}
+		}
+		if host.LogGroup != nil {
+			outputValues["simulatorLogGroup"] = host.LogGroup.Name
+		}
+		// Estimate the monthly on-demand cost of the simulator instances
+		var estimate cost.Estimate
+		instances := settings.InstanceCount
+		if settings.SimulatorCount > 0 {
+			instances = settings.SimulatorCount
+		}
+		estimate.Ec2Instances(settings.InstanceType, instances)
+		estimate.Gp3Volumes(30, instances)
+		if !settings.PrivateSimulator {
+			estimate.PublicIPv4(instances)
+		}
+		if host.CredentialsSecret != nil {
+			estimate.Secrets(1)
+		}
+
+		publishOutputs := func() error {
+			if err := estimate.Export(ctx); err != nil {
+				return err
+			}
+			outputParameters, err := components.NewLabOutputParameters(ctx, lb.Name("ec2-outputs"), &components.LabOutputParametersArgs{
+				Labels: lb,
+				Stack:  "ec2",
+				Values: outputValues,
+			}, inRegion)
+			if err != nil {
+				return err
+			}
+			ctx.Export("outputParameterPrefix", pulumi.String(outputParameters.Prefix))
+			return nil
+		}
+
+		if host.Group != nil {
+			// Export outputs
+			ctx.Export("region", pulumi.String(region))
+			ctx.Export("simulatorCount", pulumi.Int(settings.SimulatorCount))
+			ctx.Export("autoScalingGroupName", host.Group.Name)
+			ctx.Export("useSpot", pulumi.Bool(settings.UseSpot))
+			ctx.Export("privateSimulator", pulumi.Bool(settings.PrivateSimulator))
+			if hostArgs.IamDbUser != "" {
+				ctx.Export("iamDbUser", pulumi.String(hostArgs.IamDbUser))
+			}
+			ctx.Export("launchTemplateId", host.LaunchTemplate.ID())
+			ctx.Export("instanceType", pulumi.String(settings.InstanceType))
+			ctx.Export("architecture", pulumi.String(settings.Architecture))
+			ctx.Export("amiId", pulumi.String(ami.Id))
+			ctx.Export("clusterEndpointParameter", pulumi.String(host.EndpointParameterName))
+			ctx.Export("credentialsSecretArn", host.CredentialsSecret.Arn)
+			ctx.Export("simulatorService", pulumi.String("workload-simulator.service"))
+			if service.Image != nil {
+				ctx.Export("simulatorImage", service.Image)
+			}
+			if host.ArtifactsBucket != nil {
+				ctx.Export("artifactsBucket", host.ArtifactsBucket.Bucket)
+				ctx.Export("simulatorJarUri", pulumi.Sprintf("s3://%s/%s", host.ArtifactsBucket.Bucket, host.Jar.Key))
+			}
+			if host.LogGroup != nil {
+				ctx.Export("simulatorLogGroup", host.LogGroup.Name)
+			}
+			ctx.Export("workloadSimulatorPath", pulumi.String("/opt/workload-simulator"))
+			ctx.Export("auroraClusterEndpoint", clusterEndpoint)
+			outputValues["autoScalingGroupName"] = host.Group.Name
+			return publishOutputs()
+		}
+		instance := host.Instance
+
+		// The Elastic IPs replace the addresses the instances launched with
+		publicIp := func(i int) pulumi.StringOutput {
+			if host.Eips != nil {
+				return host.Eips[i].PublicIp
+			}
+			return host.Instances[i].PublicIp
+		}
+		publicDns := instance.PublicDns
+		if host.Eips != nil {
+			publicDns = host.Eips[0].PublicDns
+		}
+
 		// Export outputs
+		ctx.Export("region", pulumi.String(region))
 		ctx.Export("instanceId", instance.ID())
-		ctx.Export("publicIp", instance.PublicIp)
-		ctx.Export("publicDns", instance.PublicDns)
+		if !settings.PrivateSimulator {
+			ctx.Export("publicIp", publicIp(0))
+			ctx.Export("publicDns", publicDns)
+		}
+		if host.Eips != nil {
+			var allocationIds pulumi.StringArray
+			for _, eip := range host.Eips {
+				allocationIds = append(allocationIds, eip.AllocationId)
+			}
+			ctx.Export("eipAllocationId", host.Eips[0].AllocationId)
+			ctx.Export("eipAllocationIds", allocationIds)
+		}
 		ctx.Export("privateIp", instance.PrivateIp)
 		ctx.Export("instanceType", instance.InstanceType)
+		ctx.Export("architecture", pulumi.String(settings.Architecture))
+		ctx.Export("amiId", pulumi.String(ami.Id))
 		ctx.Export("availabilityZone", instance.AvailabilityZone)
 
-		// Export connection information
-		ctx.Export("sshCommand", pulumi.Sprintf("ssh -i %s.pem ec2-user@%s", keyName, instance.PublicDns))
+		// Export every instance, in the order of their names, to compare the
+		// clients in each zone
+		var instanceIds, privateIps, publicIps, availabilityZones pulumi.StringArray
+		for i, instance := range host.Instances {
+			instanceIds = append(instanceIds, instance.ID())
+			privateIps = append(privateIps, instance.PrivateIp)
+			publicIps = append(publicIps, publicIp(i))
+			availabilityZones = append(availabilityZones, instance.AvailabilityZone)
+		}
+		ctx.Export("instanceCount", pulumi.Int(len(host.Instances)))
+		ctx.Export("instanceIds", instanceIds)
+		ctx.Export("instancePrivateIps", privateIps)
+		if !settings.PrivateSimulator {
+			ctx.Export("instancePublicIps", publicIps)
+		}
+		ctx.Export("instanceAvailabilityZones", availabilityZones)
+		ctx.Export("useSpot", pulumi.Bool(settings.UseSpot))
+		ctx.Export("privateSimulator", pulumi.Bool(settings.PrivateSimulator))
+		if hostArgs.IamDbUser != "" {
+			ctx.Export("iamDbUser", pulumi.String(hostArgs.IamDbUser))
+		}
+		if service != nil {
+			ctx.Export("clusterEndpointParameter", pulumi.String(host.EndpointParameterName))
+			ctx.Export("credentialsSecretArn", host.CredentialsSecret.Arn)
+			ctx.Export("simulatorService", pulumi.String("workload-simulator.service"))
+			if service.Image != nil {
+				ctx.Export("simulatorImage", service.Image)
+			}
+		}
+		if host.ArtifactsBucket != nil {
+			ctx.Export("artifactsBucket", host.ArtifactsBucket.Bucket)
+			ctx.Export("simulatorJarUri", pulumi.Sprintf("s3://%s/%s", host.ArtifactsBucket.Bucket, host.Jar.Key))
+		}
+		if host.LogGroup != nil {
+			ctx.Export("simulatorLogGroup", host.LogGroup.Name)
+		}
+
+		// Export connection information; a private instance is reached with
+		// Session Manager, or over SSH through the access stack's endpoint
+		if settings.PrivateSimulator {
+			ctx.Export("ssmSessionCommand", pulumi.Sprintf("aws ssm start-session --region %s --target %s", region, instance.ID()))
+		} else {
+			ctx.Export("sshCommand", pulumi.Sprintf("ssh -i %s.pem ec2-user@%s", settings.KeyName, publicDns))
+		}
 		ctx.Export("workloadSimulatorPath", pulumi.String("/opt/workload-simulator"))
 
 		// Export Aurora endpoint if available
-		if auroraStackName != "" && clusterEndpoint != nil {
+		if hasClusterEndpoint {
 			ctx.Export("auroraClusterEndpoint", clusterEndpoint)
 			ctx.Export("runSimulatorCommand", pulumi.Sprintf(
 				"/opt/workload-simulator/run-simulator.sh %s",
@@ -223,6 +354,38 @@ echo "EC2 instance setup completed successfully" > /var/log/user-data.log
 			))
 		}
 
-		return nil
+		outputValues["instanceId"] = instance.ID().ToStringOutput()
+		outputValues["instanceIds"] = instanceIds.ToStringArrayOutput().ApplyT(func(ids []string) string {
+			return strings.Join(ids, ",")
+		}).(pulumi.StringOutput)
+		if settings.PrivateSimulator {
+			outputValues["privateIp"] = instance.PrivateIp
+		} else {
+			outputValues["publicDns"] = publicDns
+		}
+		return publishOutputs()
 	})
 }
+
+// imagePlatforms are the container image platforms of the EC2 architectures.
+var imagePlatforms = map[string]string{
+	"x86_64": "linux/amd64",
+	"arm64":  "linux/arm64",
+}
+
+// privateSubnetId returns the VPC stack's EKS subnet output, a private
+// subnet, once the stack has a NAT gateway for the private instances to
+// install packages and reach SSM through.
+func privateSubnetId(vpcStackRef *pulumi.StackReference, vpcStackName, output string) pulumi.StringOutput {
+	return pulumi.All(
+		vpcStackRef.GetOutput(pulumi.String(output)),
+		vpcStackRef.GetOutput(pulumi.String("natGatewayId")),
+	).ApplyT(func(outputs []interface{}) (string, error) {
+		subnetId, _ := outputs[0].(string)
+		natGatewayId, _ := outputs[1].(string)
+		if subnetId == "" || natGatewayId == "" {
+			return "", fmt.Errorf("privateSimulator needs the EKS subnets and a NAT gateway in VPC stack %s; set natGateway there", vpcStackName)
+		}
+		return subnetId, nil
+	}).(pulumi.StringOutput)
+}
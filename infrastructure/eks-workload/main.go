@@ -0,0 +1,465 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes"
+	appsv1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/apps/v1"
+	autoscalingv2 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/autoscaling/v2"
+	batchv1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/batch/v1"
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+
+	"aurora-bluegreen-lab/internal/components"
+	labconfig "aurora-bluegreen-lab/internal/config"
+	"aurora-bluegreen-lab/internal/cost"
+	"aurora-bluegreen-lab/internal/labels"
+	"aurora-bluegreen-lab/internal/providers"
+)
+
+const (
+	// workloadName names the service account, Deployment and Service, as
+	// the manifests in workload-simulator/kubernetes do
+	workloadName = "workload-simulator"
+	metricsPort  = 8080
+	// awsCliImage reads the password with the pod's IRSA credentials
+	awsCliImage = "public.ecr.aws/aws-cli/aws-cli:2.17.0"
+	// credentialsPath is the in-memory volume the password is written to
+	credentialsPath = "/credentials"
+)
+
+func main() {
+	pulumi.Run(func(ctx *pulumi.Context) error {
+		// Load configuration
+		cfg := config.New(ctx, "")
+		settings, err := labconfig.LoadEksWorkload(cfg)
+		if err != nil {
+			return err
+		}
+		dbPassword := cfg.RequireSecret("dbPassword")
+
+		lb, err := labels.New(ctx, cfg)
+		if err != nil {
+			return err
+		}
+
+		// Create the AWS provider for the stack's region
+		provider, region, err := providers.New(ctx, cfg, lb)
+		if err != nil {
+			return err
+		}
+		inRegion := pulumi.Provider(provider)
+
+		// The cluster is the kubeconfig's, or the ambient one's; its context
+		// selects among the clusters of one kubeconfig
+		kubeProvider, err := kubernetes.NewProvider(ctx, lb.Name("eks-workload-kubernetes"), &kubernetes.ProviderArgs{
+			Kubeconfig: optionalString(settings.Kubeconfig),
+			Context:    optionalString(settings.KubeContext),
+		})
+		if err != nil {
+			return err
+		}
+		inCluster := pulumi.Provider(kubeProvider)
+
+		// Reference the Aurora stack for the endpoint and credentials, and the
+		// registry stack for the simulator image
+		auroraStackRef, err := pulumi.NewStackReference(ctx, settings.AuroraStackName, nil)
+		if err != nil {
+			return err
+		}
+		image := pulumi.String(settings.Image).ToStringOutput()
+		if settings.RegistryStackName != "" {
+			registryStackRef, err := pulumi.NewStackReference(ctx, settings.RegistryStackName, nil)
+			if err != nil {
+				return err
+			}
+			image = registryStackRef.GetStringOutput(pulumi.String("simulatorImageUri"))
+		}
+
+		identityArgs := &components.LabWorkloadIdentityArgs{
+			Labels:          lb,
+			OidcProviderArn: settings.OidcProviderArn,
+			Namespace:       settings.Namespace,
+			ServiceAccount:  workloadName,
+			DbPassword:      dbPassword,
+		}
+		if settings.Observability {
+			identityArgs.ExporterNamespace = settings.MonitoringNamespace
+			identityArgs.ExporterServiceAccount = exporterServiceAccount
+		}
+		identity, err := components.NewLabWorkloadIdentity(ctx, lb.Name("eks-workload"), identityArgs, inRegion)
+		if err != nil {
+			return err
+		}
+
+		podLabels := pulumi.StringMap{
+			"app":     pulumi.String(workloadName),
+			"project": pulumi.String(lb.ProjectName),
+		}
+		metadata := func(name string, annotations pulumi.StringMap) *metav1.ObjectMetaArgs {
+			m := &metav1.ObjectMetaArgs{
+				Name:      optionalString(name),
+				Namespace: pulumi.String(settings.Namespace),
+				Labels:    podLabels,
+			}
+			if annotations != nil {
+				m.Annotations = annotations
+			}
+			return m
+		}
+
+		// The default namespace exists in every cluster
+		namespaceOptions := []pulumi.ResourceOption{inCluster}
+		if settings.Namespace != "default" {
+			namespace, err := corev1.NewNamespace(ctx, lb.Name("eks-workload-namespace"), &corev1.NamespaceArgs{
+				Metadata: &metav1.ObjectMetaArgs{
+					Name:   pulumi.String(settings.Namespace),
+					Labels: podLabels,
+				},
+			}, inCluster)
+			if err != nil {
+				return err
+			}
+			namespaceOptions = append(namespaceOptions, pulumi.DependsOn([]pulumi.Resource{namespace}))
+		}
+
+		// The pods assume the role through the EKS Pod Identity Webhook, which
+		// injects the web identity token of the annotated service account
+		serviceAccount, err := corev1.NewServiceAccount(ctx, lb.Name("eks-workload-service-account"), &corev1.ServiceAccountArgs{
+			Metadata: metadata(workloadName, pulumi.StringMap{
+				"eks.amazonaws.com/role-arn": identity.Role.Arn,
+			}),
+		}, namespaceOptions...)
+		if err != nil {
+			return err
+		}
+
+		// The ConfigMap is auto-named, so a change creates a new one and rolls
+		// the pods referencing it
+		data := pulumi.StringMap{
+			"AWS_REGION":      pulumi.String(region),
+			"AURORA_ENDPOINT": auroraStackRef.GetStringOutput(pulumi.String("clusterEndpoint")),
+			"USERNAME":        auroraStackRef.GetStringOutput(pulumi.String("masterUsername")),
+			"DATABASE_NAME":   auroraStackRef.GetStringOutput(pulumi.String("databaseName")),
+			"PASSWORD_SECRET": identity.PasswordSecret.Name,
+			"SIMULATOR_ARGS":  pulumi.String(stackArgs(settings)),
+			"SIMULATOR_OPTS":  pulumi.String(settings.SimulatorOptions),
+		}
+		if lb.RunId != "" {
+			data["LAB_RUN_ID"] = pulumi.String(lb.RunId)
+		}
+		configMap, err := corev1.NewConfigMap(ctx, lb.Name("eks-workload-config"), &corev1.ConfigMapArgs{
+			Metadata: metadata("", nil),
+			Data:     data,
+		}, namespaceOptions...)
+		if err != nil {
+			return err
+		}
+
+		template := podTemplate(settings, podLabels, image, serviceAccount, configMap)
+
+		// A Deployment runs until the stack is destroyed; a Job runs its pods
+		// for the configured duration and is not awaited, as its pods would
+		// hold pulumi up until they finish
+		var workload pulumi.StringOutput
+		autoscaled := settings.MaxReplicas > settings.Replicas
+		if settings.Kind == "job" {
+			job, err := batchv1.NewJob(ctx, lb.Name("eks-workload-job"), &batchv1.JobArgs{
+				Metadata: metadata("", pulumi.StringMap{"pulumi.com/skipAwait": pulumi.String("true")}),
+				Spec: &batchv1.JobSpecArgs{
+					Parallelism:  pulumi.Int(settings.Replicas),
+					Completions:  pulumi.Int(settings.Replicas),
+					BackoffLimit: pulumi.Int(0),
+					Template:     template,
+				},
+			}, namespaceOptions...)
+			if err != nil {
+				return err
+			}
+			workload = job.Metadata.Name().Elem()
+		} else {
+			// The HorizontalPodAutoscaler owns the replica count when there is
+			// one, so updates do not reset it
+			var replicas pulumi.IntPtrInput
+			if !autoscaled {
+				replicas = pulumi.Int(settings.Replicas)
+			}
+			deployment, err := appsv1.NewDeployment(ctx, lb.Name("eks-workload-deployment"), &appsv1.DeploymentArgs{
+				Metadata: metadata(workloadName, nil),
+				Spec: &appsv1.DeploymentSpecArgs{
+					Replicas: replicas,
+					Selector: &metav1.LabelSelectorArgs{MatchLabels: podLabels},
+					Template: template,
+				},
+			}, namespaceOptions...)
+			if err != nil {
+				return err
+			}
+			workload = deployment.Metadata.Name().Elem()
+
+			if autoscaled {
+				_, err = newAutoscaler(ctx, lb, settings, metadata(workloadName, nil), deployment, inCluster)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		// The Service gives the pods' metrics a stable name for Prometheus
+		var service *corev1.Service
+		if settings.EnableMetrics {
+			service, err = corev1.NewService(ctx, lb.Name("eks-workload-service"), &corev1.ServiceArgs{
+				Metadata: metadata(workloadName, pulumi.StringMap{
+					"prometheus.io/scrape": pulumi.String("true"),
+					"prometheus.io/port":   pulumi.String(fmt.Sprint(metricsPort)),
+				}),
+				Spec: &corev1.ServiceSpecArgs{
+					Type:     pulumi.String("ClusterIP"),
+					Selector: podLabels,
+					Ports: corev1.ServicePortArray{
+						corev1.ServicePortArgs{
+							Name:       pulumi.String("metrics"),
+							Port:       pulumi.Int(metricsPort),
+							TargetPort: pulumi.Int(metricsPort),
+							Protocol:   pulumi.String("TCP"),
+						},
+					},
+				},
+			}, namespaceOptions...)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Prometheus and Grafana with the CloudWatch exporter of the cluster
+		if settings.Observability {
+			var grafanaPassword pulumi.StringOutput
+			if settings.HasGrafanaAdminPassword {
+				grafanaPassword = cfg.RequireSecret("grafanaAdminPassword")
+			}
+			_, err = newObservability(ctx, lb, &observabilityArgs{
+				settings:          settings,
+				region:            region,
+				clusterIdentifier: auroraStackRef.GetStringOutput(pulumi.String("clusterIdentifier")),
+				exporterRoleArn:   identity.ExporterRole.Arn,
+				grafanaPassword:   grafanaPassword,
+				simulatorLabels:   podLabels,
+				service:           service,
+			}, inCluster)
+			if err != nil {
+				return err
+			}
+			ctx.Export("monitoringNamespace", pulumi.String(settings.MonitoringNamespace))
+			ctx.Export("exporterRoleArn", identity.ExporterRole.Arn)
+		}
+
+		// Export outputs
+		ctx.Export("region", pulumi.String(region))
+		ctx.Export("namespace", pulumi.String(settings.Namespace))
+		ctx.Export("serviceAccountName", serviceAccount.Metadata.Name().Elem())
+		ctx.Export("roleArn", identity.Role.Arn)
+		ctx.Export("passwordSecretName", identity.PasswordSecret.Name)
+		ctx.Export("image", image)
+		ctx.Export("kind", pulumi.String(settings.Kind))
+		ctx.Export("workloadName", workload)
+		ctx.Export("replicas", pulumi.Int(settings.Replicas))
+		ctx.Export("maxReplicas", pulumi.Int(settings.MaxReplicas))
+
+		// The pods run on the cluster's nodes, billed with the cluster; the
+		// stack adds the password secret
+		var estimate cost.Estimate
+		estimate.Secrets(1)
+		if err := estimate.Export(ctx); err != nil {
+			return err
+		}
+
+		// Publish the key outputs for runtime discovery without Pulumi access
+		outputParameters, err := components.NewLabOutputParameters(ctx, lb.Name("eks-workload-outputs"), &components.LabOutputParametersArgs{
+			Labels: lb,
+			Stack:  "eks-workload",
+			Values: map[string]pulumi.StringInput{
+				"region":       pulumi.String(region),
+				"namespace":    pulumi.String(settings.Namespace),
+				"roleArn":      identity.Role.Arn,
+				"workloadName": workload,
+			},
+		}, inRegion)
+		if err != nil {
+			return err
+		}
+		ctx.Export("outputParameterPrefix", pulumi.String(outputParameters.Prefix))
+
+		return nil
+	})
+}
+
+// stackArgs returns the simulator options the stack sets for every pod;
+// simulatorOptions may not repeat them.
+func stackArgs(settings *labconfig.EksWorkload) string {
+	var args []string
+	if settings.EnableMetrics {
+		args = append(args, "--enable-metrics")
+	}
+	if settings.Kind == "job" {
+		args = append(args, "--duration", strconv.Itoa(settings.Duration))
+	}
+	return strings.Join(args, " ")
+}
+
+// podTemplate returns the simulator pods: an init container reads the
+// password from Secrets Manager into an in-memory volume, from which the
+// simulator container passes it in DB_PASSWORD.
+func podTemplate(settings *labconfig.EksWorkload, podLabels pulumi.StringMap, image pulumi.StringOutput, serviceAccount *corev1.ServiceAccount, configMap *corev1.ConfigMap) *corev1.PodTemplateSpecArgs {
+	envFrom := corev1.EnvFromSourceArray{
+		corev1.EnvFromSourceArgs{
+			ConfigMapRef: &corev1.ConfigMapEnvSourceArgs{Name: configMap.Metadata.Name()},
+		},
+	}
+	credentials := corev1.VolumeMountArray{
+		corev1.VolumeMountArgs{Name: pulumi.String("credentials"), MountPath: pulumi.String(credentialsPath)},
+	}
+
+	simulator := corev1.ContainerArgs{
+		Name:  pulumi.String(workloadName),
+		Image: image,
+		// The image's entrypoint passes the password on the command line;
+		// DB_PASSWORD keeps it out of the process list
+		Command: pulumi.StringArray{
+			pulumi.String("sh"),
+			pulumi.String("-c"),
+			pulumi.String(`export DB_PASSWORD="$(cat ` + credentialsPath + `/password)"
+exec java $JAVA_OPTS -jar workload-simulator.jar \
+  --aurora-endpoint "$AURORA_ENDPOINT" \
+  --username "$USERNAME" \
+  --database-name "$DATABASE_NAME" \
+  $SIMULATOR_ARGS $SIMULATOR_OPTS`),
+		},
+		EnvFrom:      envFrom,
+		VolumeMounts: credentials,
+		Resources: &corev1.ResourceRequirementsArgs{
+			Requests: pulumi.StringMap{"cpu": pulumi.String("1000m"), "memory": pulumi.String("2Gi")},
+			Limits:   pulumi.StringMap{"cpu": pulumi.String("2000m"), "memory": pulumi.String("4Gi")},
+		},
+	}
+	if settings.EnableMetrics {
+		simulator.Ports = corev1.ContainerPortArray{
+			corev1.ContainerPortArgs{
+				Name:          pulumi.String("metrics"),
+				ContainerPort: pulumi.Int(metricsPort),
+				Protocol:      pulumi.String("TCP"),
+			},
+		}
+		simulator.ReadinessProbe = &corev1.ProbeArgs{
+			HttpGet: &corev1.HTTPGetActionArgs{
+				Path: pulumi.String("/metrics"),
+				Port: pulumi.Int(metricsPort),
+			},
+			InitialDelaySeconds: pulumi.Int(30),
+			PeriodSeconds:       pulumi.Int(10),
+			TimeoutSeconds:      pulumi.Int(5),
+			FailureThreshold:    pulumi.Int(3),
+		}
+	}
+
+	// A Deployment restarts exited pods; a Job's pods stop after the run
+	restartPolicy := "Always"
+	if settings.Kind == "job" {
+		restartPolicy = "Never"
+	}
+
+	return &corev1.PodTemplateSpecArgs{
+		Metadata: &metav1.ObjectMetaArgs{
+			Labels: podLabels,
+			Annotations: pulumi.StringMap{
+				"prometheus.io/scrape": pulumi.String(strconv.FormatBool(settings.EnableMetrics)),
+				"prometheus.io/port":   pulumi.String(fmt.Sprint(metricsPort)),
+				"prometheus.io/path":   pulumi.String("/metrics"),
+			},
+		},
+		Spec: &corev1.PodSpecArgs{
+			ServiceAccountName: serviceAccount.Metadata.Name(),
+			InitContainers: corev1.ContainerArray{
+				corev1.ContainerArgs{
+					Name:  pulumi.String("db-password"),
+					Image: pulumi.String(awsCliImage),
+					Command: pulumi.StringArray{
+						pulumi.String("sh"),
+						pulumi.String("-c"),
+						pulumi.String(`aws secretsmanager get-secret-value --secret-id "$PASSWORD_SECRET" --query SecretString --output text > ` + credentialsPath + `/password`),
+					},
+					EnvFrom:      envFrom,
+					VolumeMounts: credentials,
+				},
+			},
+			Containers: corev1.ContainerArray{simulator},
+			Volumes: corev1.VolumeArray{
+				corev1.VolumeArgs{
+					Name:     pulumi.String("credentials"),
+					EmptyDir: &corev1.EmptyDirVolumeSourceArgs{Medium: pulumi.String("Memory")},
+				},
+			},
+			RestartPolicy:                 pulumi.String(restartPolicy),
+			TerminationGracePeriodSeconds: pulumi.Int(30),
+		},
+	}
+}
+
+// newAutoscaler scales the Deployment between replicas and maxReplicas on
+// CPU utilization, with the behavior of workload-simulator/kubernetes/hpa.yaml:
+// scale up quickly, scale down slowly after five stable minutes.
+func newAutoscaler(ctx *pulumi.Context, lb *labels.Labels, settings *labconfig.EksWorkload, metadata *metav1.ObjectMetaArgs, deployment *appsv1.Deployment, opts ...pulumi.ResourceOption) (*autoscalingv2.HorizontalPodAutoscaler, error) {
+	return autoscalingv2.NewHorizontalPodAutoscaler(ctx, lb.Name("eks-workload-autoscaler"), &autoscalingv2.HorizontalPodAutoscalerArgs{
+		Metadata: metadata,
+		Spec: &autoscalingv2.HorizontalPodAutoscalerSpecArgs{
+			ScaleTargetRef: &autoscalingv2.CrossVersionObjectReferenceArgs{
+				ApiVersion: pulumi.String("apps/v1"),
+				Kind:       pulumi.String("Deployment"),
+				Name:       deployment.Metadata.Name().Elem(),
+			},
+			MinReplicas: pulumi.Int(settings.Replicas),
+			MaxReplicas: pulumi.Int(settings.MaxReplicas),
+			Metrics: autoscalingv2.MetricSpecArray{
+				autoscalingv2.MetricSpecArgs{
+					Type: pulumi.String("Resource"),
+					Resource: &autoscalingv2.ResourceMetricSourceArgs{
+						Name: pulumi.String("cpu"),
+						Target: &autoscalingv2.MetricTargetArgs{
+							Type:               pulumi.String("Utilization"),
+							AverageUtilization: pulumi.Int(settings.TargetCpuUtilization),
+						},
+					},
+				},
+			},
+			Behavior: &autoscalingv2.HorizontalPodAutoscalerBehaviorArgs{
+				ScaleDown: &autoscalingv2.HPAScalingRulesArgs{
+					StabilizationWindowSeconds: pulumi.Int(300),
+					Policies: autoscalingv2.HPAScalingPolicyArray{
+						autoscalingv2.HPAScalingPolicyArgs{Type: pulumi.String("Percent"), Value: pulumi.Int(50), PeriodSeconds: pulumi.Int(60)},
+						autoscalingv2.HPAScalingPolicyArgs{Type: pulumi.String("Pods"), Value: pulumi.Int(2), PeriodSeconds: pulumi.Int(60)},
+					},
+					SelectPolicy: pulumi.String("Min"),
+				},
+				ScaleUp: &autoscalingv2.HPAScalingRulesArgs{
+					StabilizationWindowSeconds: pulumi.Int(60),
+					Policies: autoscalingv2.HPAScalingPolicyArray{
+						autoscalingv2.HPAScalingPolicyArgs{Type: pulumi.String("Percent"), Value: pulumi.Int(100), PeriodSeconds: pulumi.Int(30)},
+						autoscalingv2.HPAScalingPolicyArgs{Type: pulumi.String("Pods"), Value: pulumi.Int(2), PeriodSeconds: pulumi.Int(30)},
+					},
+					SelectPolicy: pulumi.String("Max"),
+				},
+			},
+		},
+	}, opts...)
+}
+
+// optionalString returns nil for empty strings so unset values are omitted.
+func optionalString(value string) pulumi.StringPtrInput {
+	if value == "" {
+		return nil
+	}
+	return pulumi.String(value)
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes"
+	"github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/apiextensions"
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	helmv3 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/helm/v3"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	labconfig "aurora-bluegreen-lab/internal/config"
+	"aurora-bluegreen-lab/internal/labels"
+)
+
+const (
+	prometheusCommunityRepo = "https://prometheus-community.github.io/helm-charts"
+	// The chart versions are pinned so an update of the stack does not
+	// upgrade Prometheus or Grafana
+	kubePrometheusStackVersion = "65.1.1"
+	cloudWatchExporterVersion  = "0.25.3"
+	// exporterServiceAccount is the service account of the CloudWatch
+	// exporter, which its IRSA role trusts
+	exporterServiceAccount = "cloudwatch-exporter"
+)
+
+// dashboard is the Grafana dashboard of the simulator's request rate,
+// latency and errors next to the Aurora cluster's CloudWatch metrics.
+//
+//go:embed dashboards/aurora-bluegreen.json
+var dashboard string
+
+// observabilityArgs are the inputs of the observability add-on.
+type observabilityArgs struct {
+	settings          *labconfig.EksWorkload
+	region            string
+	clusterIdentifier pulumi.StringOutput
+	exporterRoleArn   pulumi.StringOutput
+	grafanaPassword   pulumi.StringOutput
+	simulatorLabels   pulumi.StringMap
+	// service is the simulator's metrics Service the ServiceMonitor selects
+	service pulumi.Resource
+}
+
+// exporterMetrics are the AWS/RDS metrics of the cluster the exporter
+// scrapes, with the statistic graphed by the dashboard.
+var exporterMetrics = []struct{ name, statistic string }{
+	{"CPUUtilization", "Average"},
+	{"DatabaseConnections", "Average"},
+	{"CommitLatency", "Average"},
+	{"DMLThroughput", "Average"},
+	{"AuroraReplicaLagMaximum", "Maximum"},
+	{"AuroraBinlogReplicaLag", "Maximum"},
+}
+
+// exporterConfig returns the CloudWatch exporter's configuration. The
+// cluster is selected by a pattern that also matches the green clusters RDS
+// names after it, so the dashboard follows a Blue/Green deployment; JSON is
+// valid YAML, which the exporter reads.
+func exporterConfig(region, clusterIdentifier string) (string, error) {
+	var metrics []map[string]interface{}
+	for _, m := range exporterMetrics {
+		metrics = append(metrics, map[string]interface{}{
+			"aws_namespace":   "AWS/RDS",
+			"aws_metric_name": m.name,
+			"aws_dimensions":  []string{"DBClusterIdentifier"},
+			"aws_dimension_select_regex": map[string][]string{
+				"DBClusterIdentifier": {fmt.Sprintf("%s(-green-.*)?", clusterIdentifier)},
+			},
+			"aws_statistics": []string{m.statistic},
+			"period_seconds": 60,
+		})
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"region":  region,
+		"metrics": metrics,
+	})
+	return string(data), err
+}
+
+// newObservability installs kube-prometheus-stack and the CloudWatch
+// exporter with Helm, has Prometheus scrape the simulator's Service, and
+// provisions the lab's Grafana dashboard.
+func newObservability(ctx *pulumi.Context, lb *labels.Labels, args *observabilityArgs, opts ...pulumi.ResourceOption) (*helmv3.Release, error) {
+	settings := args.settings
+
+	// Without grafanaAdminPassword Grafana keeps the chart's admin password
+	grafana := pulumi.Map{}
+	if settings.HasGrafanaAdminPassword {
+		grafana["adminPassword"] = args.grafanaPassword
+	}
+	stack, err := helmv3.NewRelease(ctx, lb.Name("eks-kube-prometheus-stack"), &helmv3.ReleaseArgs{
+		Name:            pulumi.String("kube-prometheus-stack"),
+		Chart:           pulumi.String("kube-prometheus-stack"),
+		Version:         pulumi.String(kubePrometheusStackVersion),
+		Namespace:       pulumi.String(settings.MonitoringNamespace),
+		CreateNamespace: pulumi.Bool(true),
+		RepositoryOpts:  &helmv3.RepositoryOptsArgs{Repo: pulumi.String(prometheusCommunityRepo)},
+		Values: pulumi.Map{
+			// Prometheus selects the ServiceMonitors of every release and
+			// namespace, not only those labelled with its own release
+			"prometheus": pulumi.Map{
+				"prometheusSpec": pulumi.Map{
+					"serviceMonitorSelectorNilUsesHelmValues": pulumi.Bool(false),
+				},
+			},
+			"grafana": grafana,
+		},
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	afterStack := append([]pulumi.ResourceOption{pulumi.DependsOn([]pulumi.Resource{stack})}, opts...)
+
+	config := args.clusterIdentifier.ApplyT(func(identifier string) (string, error) {
+		return exporterConfig(args.region, identifier)
+	}).(pulumi.StringOutput)
+	_, err = helmv3.NewRelease(ctx, lb.Name("eks-cloudwatch-exporter"), &helmv3.ReleaseArgs{
+		Name:           pulumi.String("cloudwatch-exporter"),
+		Chart:          pulumi.String("prometheus-cloudwatch-exporter"),
+		Version:        pulumi.String(cloudWatchExporterVersion),
+		Namespace:      pulumi.String(settings.MonitoringNamespace),
+		RepositoryOpts: &helmv3.RepositoryOptsArgs{Repo: pulumi.String(prometheusCommunityRepo)},
+		Values: pulumi.Map{
+			"config": config,
+			"serviceAccount": pulumi.Map{
+				"create": pulumi.Bool(true),
+				"name":   pulumi.String(exporterServiceAccount),
+				"annotations": pulumi.Map{
+					"eks.amazonaws.com/role-arn": args.exporterRoleArn,
+				},
+			},
+			// CloudWatch has the metrics by the minute; scraping more often
+			// only adds GetMetricData calls
+			"serviceMonitor": pulumi.Map{
+				"enabled":  pulumi.Bool(true),
+				"interval": pulumi.String("60s"),
+				"timeout":  pulumi.String("30s"),
+			},
+		},
+	}, afterStack...)
+	if err != nil {
+		return nil, err
+	}
+
+	// The ServiceMonitor CRD comes with kube-prometheus-stack; the endpoint
+	// matches workload-simulator/kubernetes/servicemonitor.yaml
+	_, err = apiextensions.NewCustomResource(ctx, lb.Name("eks-workload-service-monitor"), &apiextensions.CustomResourceArgs{
+		ApiVersion: pulumi.String("monitoring.coreos.com/v1"),
+		Kind:       pulumi.String("ServiceMonitor"),
+		Metadata: &metav1.ObjectMetaArgs{
+			Name:      pulumi.String(workloadName),
+			Namespace: pulumi.String(settings.Namespace),
+			Labels:    args.simulatorLabels,
+		},
+		OtherFields: kubernetes.UntypedArgs{
+			"spec": pulumi.Map{
+				"selector": pulumi.Map{"matchLabels": args.simulatorLabels},
+				"endpoints": pulumi.Array{
+					pulumi.Map{
+						"port":          pulumi.String("metrics"),
+						"path":          pulumi.String("/metrics"),
+						"interval":      pulumi.String("5s"),
+						"scrapeTimeout": pulumi.String("4s"),
+					},
+				},
+			},
+		},
+	}, append([]pulumi.ResourceOption{pulumi.DependsOn([]pulumi.Resource{stack, args.service})}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Grafana's sidecar loads the dashboards of ConfigMaps labelled
+	// grafana_dashboard in its namespace
+	_, err = corev1.NewConfigMap(ctx, lb.Name("eks-grafana-dashboard"), &corev1.ConfigMapArgs{
+		Metadata: &metav1.ObjectMetaArgs{
+			Name:      pulumi.String(lb.Name("dashboard")),
+			Namespace: pulumi.String(settings.MonitoringNamespace),
+			Labels:    pulumi.StringMap{"grafana_dashboard": pulumi.String("1")},
+		},
+		Data: pulumi.StringMap{"aurora-bluegreen.json": pulumi.String(dashboard)},
+	}, afterStack...)
+	if err != nil {
+		return nil, err
+	}
+
+	return stack, nil
+}
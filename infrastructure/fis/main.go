@@ -0,0 +1,86 @@
+package main
+
+import (
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+
+	"aurora-bluegreen-lab/internal/components"
+	labconfig "aurora-bluegreen-lab/internal/config"
+	"aurora-bluegreen-lab/internal/cost"
+	"aurora-bluegreen-lab/internal/labels"
+	"aurora-bluegreen-lab/internal/providers"
+)
+
+func main() {
+	pulumi.Run(func(ctx *pulumi.Context) error {
+		// Load configuration
+		cfg := config.New(ctx, "")
+		settings, err := labconfig.LoadFis(cfg)
+		if err != nil {
+			return err
+		}
+
+		lb, err := labels.New(ctx, cfg)
+		if err != nil {
+			return err
+		}
+
+		// Create the AWS provider for the stack's region
+		provider, region, err := providers.New(ctx, cfg, lb)
+		if err != nil {
+			return err
+		}
+		inRegion := pulumi.Provider(provider)
+
+		// Reference the Aurora stack for the writer instance
+		auroraStackRef, err := pulumi.NewStackReference(ctx, settings.AuroraStackName, nil)
+		if err != nil {
+			return err
+		}
+
+		experiments, err := components.NewLabFisExperiments(ctx, lb.Name("fis"), &components.LabFisExperimentsArgs{
+			Labels:                   lb,
+			Region:                   region,
+			WriterInstanceArn:        auroraStackRef.GetStringOutput(pulumi.String("writerInstanceArn")),
+			NetworkDisruptionMinutes: settings.NetworkDisruptionMinutes,
+			NetworkDisruptionPort:    settings.NetworkDisruptionPort,
+		}, inRegion)
+		if err != nil {
+			return err
+		}
+
+		// Export outputs
+		ctx.Export("region", pulumi.String(region))
+		ctx.Export("roleArn", experiments.Role.Arn)
+		ctx.Export("rebootWriterTemplateId", experiments.RebootWriter.ID())
+		ctx.Export("rebootWriterTemplateName", pulumi.String(experiments.RebootWriterName))
+		ctx.Export("simulatorNetworkTemplateId", experiments.SimulatorNetwork.ID())
+		ctx.Export("simulatorNetworkTemplateName", pulumi.String(experiments.SimulatorNetworkName))
+		ctx.Export("networkDisruptionMinutes", pulumi.Int(settings.NetworkDisruptionMinutes))
+
+		// Templates are free; experiments are billed per action-minute while
+		// they run
+		var estimate cost.Estimate
+		if err := estimate.Export(ctx); err != nil {
+			return err
+		}
+
+		// Publish the key outputs for runtime discovery without Pulumi access
+		outputParameters, err := components.NewLabOutputParameters(ctx, lb.Name("fis-outputs"), &components.LabOutputParametersArgs{
+			Labels: lb,
+			Stack:  "fis",
+			Values: map[string]pulumi.StringInput{
+				"region":                       pulumi.String(region),
+				"roleArn":                      experiments.Role.Arn,
+				"rebootWriterTemplateName":     pulumi.String(experiments.RebootWriterName),
+				"simulatorNetworkTemplateName": pulumi.String(experiments.SimulatorNetworkName),
+			},
+		}, inRegion)
+		if err != nil {
+			return err
+		}
+		ctx.Export("outputParameterPrefix", pulumi.String(outputParameters.Prefix))
+
+		return nil
+	})
+}
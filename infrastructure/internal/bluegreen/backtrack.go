@@ -0,0 +1,137 @@
+package bluegreen
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+)
+
+// Backtrack statuses.
+const (
+	BacktrackPending   = "pending"
+	BacktrackApplying  = "applying"
+	BacktrackCompleted = "completed"
+	BacktrackFailed    = "failed"
+)
+
+// OldBlueClusterIdentifier returns the identifier RDS gives the blue cluster
+// when a deployment of the cluster is switched over.
+func OldBlueClusterIdentifier(clusterIdentifier string) string {
+	return clusterIdentifier + oldBlueSuffix
+}
+
+// BacktrackWindow describes how far a cluster can be backtracked.
+type BacktrackWindow struct {
+	ClusterIdentifier string
+	// Window is the target backtrack window; 0 when Backtrack is disabled
+	Window time.Duration
+	// Earliest is the earliest time the cluster can be backtracked to
+	Earliest time.Time
+}
+
+// BacktrackOptions describes a backtrack of a cluster.
+type BacktrackOptions struct {
+	ClusterIdentifier string
+	To                time.Time
+	// Force backtracks even when the cluster's binary log replication would
+	// be disrupted
+	Force bool
+	// UseEarliest backtracks to the earliest consistent time before To when
+	// To itself is not available
+	UseEarliest bool
+}
+
+// Backtrack is the state of a backtrack.
+type Backtrack struct {
+	ID                string
+	ClusterIdentifier string
+	Status            string
+	To                time.Time
+	From              time.Time
+}
+
+// BacktrackWindow returns the backtrack window of a cluster and fails when
+// Backtrack is not enabled on it.
+func (c *Client) BacktrackWindow(ctx context.Context, clusterIdentifier string) (*BacktrackWindow, error) {
+	cluster, err := c.describeCluster(ctx, clusterIdentifier)
+	if err != nil {
+		return nil, err
+	}
+	window := aws.ToInt64(cluster.BacktrackWindow)
+	if window == 0 {
+		return nil, fmt.Errorf("Backtrack is not enabled on cluster %s (set backtrackWindow in the aurora stack before the cluster is created)", clusterIdentifier)
+	}
+	return &BacktrackWindow{
+		ClusterIdentifier: clusterIdentifier,
+		Window:            time.Duration(window) * time.Second,
+		Earliest:          aws.ToTime(cluster.EarliestBacktrackTime),
+	}, nil
+}
+
+// Backtrack rewinds the cluster to opts.To and waits until the backtrack
+// completes, reporting every status change to onStatus. The cluster is
+// unavailable while the backtrack is applied.
+func (c *Client) Backtrack(ctx context.Context, opts BacktrackOptions, onStatus func(*Backtrack)) (*Backtrack, error) {
+	input := &rds.BacktrackDBClusterInput{
+		DBClusterIdentifier: aws.String(opts.ClusterIdentifier),
+		BacktrackTo:         aws.Time(opts.To),
+	}
+	if opts.Force {
+		input.Force = aws.Bool(true)
+	}
+	if opts.UseEarliest {
+		input.UseEarliestTimeOnPointInTimeUnavailable = aws.Bool(true)
+	}
+	out, err := c.rds.BacktrackDBCluster(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("backtracking cluster %s: %w", opts.ClusterIdentifier, err)
+	}
+	id := aws.ToString(out.BacktrackIdentifier)
+
+	interval := c.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	last := ""
+	for {
+		described, err := c.rds.DescribeDBClusterBacktracks(ctx, &rds.DescribeDBClusterBacktracksInput{
+			DBClusterIdentifier: aws.String(opts.ClusterIdentifier),
+			BacktrackIdentifier: aws.String(id),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("describing backtrack %s of cluster %s: %w", id, opts.ClusterIdentifier, err)
+		}
+		if len(described.DBClusterBacktracks) == 0 {
+			return nil, fmt.Errorf("backtrack %s of cluster %s not found", id, opts.ClusterIdentifier)
+		}
+		bt := described.DBClusterBacktracks[0]
+		b := &Backtrack{
+			ID:                id,
+			ClusterIdentifier: opts.ClusterIdentifier,
+			Status:            aws.ToString(bt.Status),
+			To:                aws.ToTime(bt.BacktrackTo),
+			From:              aws.ToTime(bt.BacktrackedFrom),
+		}
+		if b.Status != last {
+			last = b.Status
+			if onStatus != nil {
+				onStatus(b)
+			}
+		}
+		switch b.Status {
+		case BacktrackCompleted:
+			return b, nil
+		case BacktrackFailed:
+			return b, fmt.Errorf("backtrack %s of cluster %s failed", id, opts.ClusterIdentifier)
+		}
+
+		select {
+		case <-ctx.Done():
+			return b, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
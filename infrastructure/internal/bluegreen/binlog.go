@@ -0,0 +1,72 @@
+package bluegreen
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/aws/aws-sdk-go-v2/service/rds/types"
+)
+
+// binlogPositionPattern matches the event RDS records for the green writer
+// when a switchover completes, e.g. "Binary log coordinates in green
+// environment after switchover: file mysql-bin-changelog.000003 and position
+// 804".
+var binlogPositionPattern = regexp.MustCompile(`Binary log coordinates in green environment after switchover: file (\S+) and position (\d+)`)
+
+// BinlogPosition is a binary log file and position of a cluster.
+type BinlogPosition struct {
+	File     string
+	Position int64
+	// Time and Source are the time and source of the RDS event reporting it
+	Time   time.Time
+	Source string
+}
+
+// parseBinlogPosition returns the coordinates in an RDS event message, nil
+// when it has none.
+func parseBinlogPosition(message string) *BinlogPosition {
+	m := binlogPositionPattern.FindStringSubmatch(message)
+	if m == nil {
+		return nil
+	}
+	position, err := strconv.ParseInt(m[2], 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &BinlogPosition{File: m[1], Position: position}
+}
+
+// SwitchoverBinlogPosition returns the binary log coordinates of the new blue
+// cluster after the latest switchover since the given time, from the RDS
+// events of the instances; nil when no switchover reported them. External
+// replicas of the old blue cluster resume replication there. RDS keeps
+// events for 14 days.
+func (c *Client) SwitchoverBinlogPosition(ctx context.Context, since time.Time) (*BinlogPosition, error) {
+	var latest *BinlogPosition
+	paginator := rds.NewDescribeEventsPaginator(c.rds, &rds.DescribeEventsInput{
+		SourceType: types.SourceTypeDbInstance,
+		StartTime:  aws.Time(since),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("describing RDS events: %w", err)
+		}
+		for _, e := range page.Events {
+			p := parseBinlogPosition(aws.ToString(e.Message))
+			if p == nil {
+				continue
+			}
+			p.Time, p.Source = aws.ToTime(e.Date), aws.ToString(e.SourceIdentifier)
+			if latest == nil || p.Time.After(latest.Time) {
+				latest = p
+			}
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,13 @@
+package bluegreen
+
+import "testing"
+
+func TestParseBinlogPosition(t *testing.T) {
+	p := parseBinlogPosition("Binary log coordinates in green environment after switchover: file mysql-bin-changelog.000003 and position 804")
+	if p == nil || p.File != "mysql-bin-changelog.000003" || p.Position != 804 {
+		t.Errorf("got %+v, want mysql-bin-changelog.000003:804", p)
+	}
+	if p := parseBinlogPosition("Switchover from DB cluster lab to lab-green-x1 completed."); p != nil {
+		t.Errorf("got %+v from a message without coordinates", p)
+	}
+}
@@ -0,0 +1,276 @@
+// Package bluegreen creates, switches over and deletes RDS Blue/Green
+// deployments of the lab cluster and waits for their status changes.
+//
+// Status changes are reported to a callback as they are observed, so callers
+// can record a timeline of the deployment next to the workload measurements.
+// Backtrack rewinds a cluster with Backtrack enabled, such as the old blue
+// cluster after a switchover, for rollback experiments, Restore renames the
+// old blue cluster back into service, and Failover fails the cluster over
+// the ordinary way, as a baseline for the switchover.
+//
+// DeploymentEvent is the RDS Blue/Green event the monitoring stack records
+// server-side, and the schema of its event table. SwitchoverBinlogPosition
+// finds where external replicas resume after a switchover, and
+// ZeroEtlIntegrations the integrations a deployment does not move.
+package bluegreen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/aws/aws-sdk-go-v2/service/rds/types"
+
+	"aurora-bluegreen-lab/internal/runid"
+)
+
+// Blue/Green deployment statuses.
+const (
+	StatusProvisioning          = "PROVISIONING"
+	StatusAvailable             = "AVAILABLE"
+	StatusSwitchoverInProgress  = "SWITCHOVER_IN_PROGRESS"
+	StatusSwitchoverCompleted   = "SWITCHOVER_COMPLETED"
+	StatusInvalidConfiguration  = "INVALID_CONFIGURATION"
+	StatusSwitchoverFailed      = "SWITCHOVER_FAILED"
+	StatusProvisioningFailed    = "PROVISIONING_FAILED"
+	StatusInvalidXregionSetting = "INVALID_XREGION_CONFIGURATION"
+)
+
+// DefaultPollInterval is how often deployment status is polled.
+const DefaultPollInterval = 15 * time.Second
+
+// Switchover timeouts RDS accepts, and the one it applies when none is given.
+const (
+	MinSwitchoverTimeout     = 30 * time.Second
+	MaxSwitchoverTimeout     = time.Hour
+	DefaultSwitchoverTimeout = 5 * time.Minute
+)
+
+// switchoverGrace is how long past its timeout a switchover is waited for
+// before it is considered stalled, leaving RDS time to roll it back.
+const switchoverGrace = 10 * time.Minute
+
+// Client manages Blue/Green deployments.
+type Client struct {
+	rds *rds.Client
+	// PollInterval is the status polling interval (default DefaultPollInterval)
+	PollInterval time.Duration
+}
+
+// New returns a Client for the given AWS configuration.
+func New(cfg aws.Config) *Client {
+	return &Client{rds: rds.NewFromConfig(cfg), PollInterval: DefaultPollInterval}
+}
+
+// CreateOptions describes the green environment of a new deployment. Empty
+// target values keep the blue environment's settings.
+type CreateOptions struct {
+	Name string
+	// SourceArn is the ARN of the blue cluster
+	SourceArn                    string
+	TargetEngineVersion          string
+	TargetClusterParameterGroup  string
+	TargetInstanceParameterGroup string
+	TargetInstanceClass          string
+	Tags                         map[string]string
+}
+
+// Deployment is the state of a Blue/Green deployment.
+type Deployment struct {
+	ID     string
+	Name   string
+	Status string
+	// StatusDetails explains failed or invalid statuses
+	StatusDetails string
+	SourceArn     string
+	TargetArn     string
+	// Created is when the deployment was created
+	Created time.Time
+	// RunID is the run the deployment was created for, its RunId tag (see
+	// internal/runid); empty when it has none
+	RunID string
+}
+
+// TargetClusterIdentifier returns the identifier of the green cluster, which
+// RDS names "<blue identifier>-green-<suffix>".
+func (d *Deployment) TargetClusterIdentifier() string {
+	return clusterIdentifier(d.TargetArn)
+}
+
+// SourceClusterIdentifier returns the identifier of the blue cluster.
+func (d *Deployment) SourceClusterIdentifier() string {
+	return clusterIdentifier(d.SourceArn)
+}
+
+func clusterIdentifier(arn string) string {
+	return arn[strings.LastIndex(arn, ":")+1:]
+}
+
+// Create starts creating a Blue/Green deployment; use WaitAvailable to wait
+// until the green environment is ready.
+func (c *Client) Create(ctx context.Context, opts CreateOptions) (*Deployment, error) {
+	input := &rds.CreateBlueGreenDeploymentInput{
+		BlueGreenDeploymentName: aws.String(opts.Name),
+		Source:                  aws.String(opts.SourceArn),
+	}
+	if opts.TargetEngineVersion != "" {
+		input.TargetEngineVersion = aws.String(opts.TargetEngineVersion)
+	}
+	if opts.TargetClusterParameterGroup != "" {
+		input.TargetDBClusterParameterGroupName = aws.String(opts.TargetClusterParameterGroup)
+	}
+	if opts.TargetInstanceParameterGroup != "" {
+		input.TargetDBParameterGroupName = aws.String(opts.TargetInstanceParameterGroup)
+	}
+	if opts.TargetInstanceClass != "" {
+		input.TargetDBInstanceClass = aws.String(opts.TargetInstanceClass)
+	}
+	for key, value := range opts.Tags {
+		input.Tags = append(input.Tags, types.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+
+	out, err := c.rds.CreateBlueGreenDeployment(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("creating Blue/Green deployment %s: %w", opts.Name, err)
+	}
+	return deployment(out.BlueGreenDeployment), nil
+}
+
+// Describe returns the current state of a deployment.
+func (c *Client) Describe(ctx context.Context, id string) (*Deployment, error) {
+	out, err := c.rds.DescribeBlueGreenDeployments(ctx, &rds.DescribeBlueGreenDeploymentsInput{
+		BlueGreenDeploymentIdentifier: aws.String(id),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("describing Blue/Green deployment %s: %w", id, err)
+	}
+	if len(out.BlueGreenDeployments) == 0 {
+		return nil, fmt.Errorf("Blue/Green deployment %s not found", id)
+	}
+	return deployment(&out.BlueGreenDeployments[0]), nil
+}
+
+// WaitAvailable waits until the green environment is ready for switchover.
+func (c *Client) WaitAvailable(ctx context.Context, id string, onStatus func(*Deployment)) (*Deployment, error) {
+	return c.wait(ctx, id, StatusAvailable, onStatus)
+}
+
+// Switchover switches the deployment over to the green environment and waits
+// until it completes. timeout is the RDS switchover timeout (default
+// DefaultSwitchoverTimeout); RDS rolls the switchover back when it is
+// exceeded. A switchover still in progress switchoverGrace after its timeout
+// fails with ErrSwitchoverStalled.
+func (c *Client) Switchover(ctx context.Context, id string, timeout time.Duration, onStatus func(*Deployment)) (*Deployment, error) {
+	input := &rds.SwitchoverBlueGreenDeploymentInput{BlueGreenDeploymentIdentifier: aws.String(id)}
+	if timeout > 0 {
+		input.SwitchoverTimeout = aws.Int32(int32(timeout.Seconds()))
+	} else {
+		timeout = DefaultSwitchoverTimeout
+	}
+	if _, err := c.rds.SwitchoverBlueGreenDeployment(ctx, input); err != nil {
+		return nil, fmt.Errorf("switching over Blue/Green deployment %s: %w", id, err)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout+switchoverGrace)
+	defer cancel()
+	d, err := c.wait(waitCtx, id, StatusSwitchoverCompleted, onStatus)
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		return d, fmt.Errorf("Blue/Green deployment %s: %w after %s", id, ErrSwitchoverStalled, timeout+switchoverGrace)
+	}
+	return d, err
+}
+
+// Delete deletes the deployment. The blue cluster (renamed with an -old1
+// suffix after a switchover) is kept; deleteTarget also deletes the green
+// cluster of a deployment that was not switched over.
+func (c *Client) Delete(ctx context.Context, id string, deleteTarget bool) error {
+	input := &rds.DeleteBlueGreenDeploymentInput{BlueGreenDeploymentIdentifier: aws.String(id)}
+	if deleteTarget {
+		input.DeleteTarget = aws.Bool(true)
+	}
+	if _, err := c.rds.DeleteBlueGreenDeployment(ctx, input); err != nil {
+		return fmt.Errorf("deleting Blue/Green deployment %s: %w", id, err)
+	}
+	return nil
+}
+
+// wait polls the deployment until it reaches status, reporting every status
+// change to onStatus, and fails on failed or invalid statuses.
+func (c *Client) wait(ctx context.Context, id, status string, onStatus func(*Deployment)) (*Deployment, error) {
+	interval := c.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	last := ""
+	for {
+		d, err := c.Describe(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		previous := last
+		if d.Status != last {
+			last = d.Status
+			if onStatus != nil {
+				onStatus(d)
+			}
+		}
+		switch d.Status {
+		case status:
+			return d, nil
+		case StatusInvalidConfiguration, StatusInvalidXregionSetting, StatusProvisioningFailed, StatusSwitchoverFailed:
+			return d, &StatusError{Deployment: d}
+		case StatusAvailable:
+			// A switchover that exceeds its timeout is rolled back
+			if previous == StatusSwitchoverInProgress {
+				return d, &StatusError{Deployment: d, RolledBack: true}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return d, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// StatusError is returned when a deployment reaches a failed or invalid
+// status, or its switchover is rolled back.
+type StatusError struct {
+	Deployment *Deployment
+	// RolledBack is set when the switchover was rolled back
+	RolledBack bool
+}
+
+func (e *StatusError) Error() string {
+	msg := fmt.Sprintf("Blue/Green deployment %s is %s", e.Deployment.ID, e.Deployment.Status)
+	if e.RolledBack {
+		msg = fmt.Sprintf("Blue/Green deployment %s switchover was rolled back", e.Deployment.ID)
+	}
+	if e.Deployment.StatusDetails != "" {
+		msg += ": " + e.Deployment.StatusDetails
+	}
+	return msg
+}
+
+func deployment(d *types.BlueGreenDeployment) *Deployment {
+	result := &Deployment{
+		ID:            aws.ToString(d.BlueGreenDeploymentIdentifier),
+		Name:          aws.ToString(d.BlueGreenDeploymentName),
+		Status:        aws.ToString(d.Status),
+		StatusDetails: aws.ToString(d.StatusDetails),
+		SourceArn:     aws.ToString(d.Source),
+		TargetArn:     aws.ToString(d.Target),
+		Created:       aws.ToTime(d.CreateTime),
+	}
+	for _, tag := range d.TagList {
+		if aws.ToString(tag.Key) == runid.Tag {
+			result.RunID = aws.ToString(tag.Value)
+		}
+	}
+	return result
+}
@@ -0,0 +1,24 @@
+package bluegreen
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rds/types"
+)
+
+func TestDeploymentRunID(t *testing.T) {
+	d := deployment(&types.BlueGreenDeployment{
+		BlueGreenDeploymentIdentifier: aws.String("bgd-abc123"),
+		TagList: []types.Tag{
+			{Key: aws.String("Scenario"), Value: aws.String("minor-upgrade")},
+			{Key: aws.String("RunId"), Value: aws.String("minor-upgrade-20260102-150405")},
+		},
+	})
+	if d.ID != "bgd-abc123" || d.RunID != "minor-upgrade-20260102-150405" {
+		t.Errorf("got %+v", d)
+	}
+	if d := deployment(&types.BlueGreenDeployment{}); d.RunID != "" {
+		t.Errorf("untagged deployment: got run ID %q", d.RunID)
+	}
+}
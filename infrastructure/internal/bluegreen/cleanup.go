@@ -0,0 +1,167 @@
+package bluegreen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/aws/aws-sdk-go-v2/service/rds/types"
+)
+
+// FinalSnapshotIdentifier returns the identifier of the snapshot taken of a
+// cluster before it is deleted at t.
+func FinalSnapshotIdentifier(clusterIdentifier string, t time.Time) string {
+	return clusterIdentifier + "-final-" + t.UTC().Format("20060102-150405")
+}
+
+// WaitDeleted waits until the deployment no longer exists, e.g. after Delete.
+func (c *Client) WaitDeleted(ctx context.Context, id string) error {
+	for {
+		_, err := c.rds.DescribeBlueGreenDeployments(ctx, &rds.DescribeBlueGreenDeploymentsInput{
+			BlueGreenDeploymentIdentifier: aws.String(id),
+		})
+		var notFound *types.BlueGreenDeploymentNotFoundFault
+		if errors.As(err, &notFound) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("describing Blue/Green deployment %s: %w", id, err)
+		}
+		if err := c.sleep(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+// Snapshot takes a manual snapshot of the cluster and waits until it is
+// available.
+func (c *Client) Snapshot(ctx context.Context, clusterIdentifier, snapshotIdentifier string, tags map[string]string) error {
+	input := &rds.CreateDBClusterSnapshotInput{
+		DBClusterIdentifier:         aws.String(clusterIdentifier),
+		DBClusterSnapshotIdentifier: aws.String(snapshotIdentifier),
+	}
+	for key, value := range tags {
+		input.Tags = append(input.Tags, types.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+	if _, err := c.rds.CreateDBClusterSnapshot(ctx, input); err != nil {
+		return fmt.Errorf("creating snapshot %s of %s: %w", snapshotIdentifier, clusterIdentifier, err)
+	}
+	for {
+		out, err := c.rds.DescribeDBClusterSnapshots(ctx, &rds.DescribeDBClusterSnapshotsInput{
+			DBClusterSnapshotIdentifier: aws.String(snapshotIdentifier),
+		})
+		if err != nil {
+			return fmt.Errorf("describing snapshot %s: %w", snapshotIdentifier, err)
+		}
+		if len(out.DBClusterSnapshots) > 0 {
+			switch status := aws.ToString(out.DBClusterSnapshots[0].Status); status {
+			case "available":
+				return nil
+			case "failed", "deleted":
+				return fmt.Errorf("snapshot %s of %s is %s", snapshotIdentifier, clusterIdentifier, status)
+			}
+		}
+		if err := c.sleep(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+// DeleteCluster deletes the cluster and its instances without a final
+// snapshot (take one with Snapshot first), and waits until they are gone:
+// deletion protection is turned off, the instances are deleted, then the
+// cluster. Each step is reported to onStep once it is done.
+func (c *Client) DeleteCluster(ctx context.Context, clusterIdentifier string, onStep func(string)) error {
+	cluster, err := c.describeCluster(ctx, clusterIdentifier)
+	if err != nil {
+		return err
+	}
+	step := func(format string, args ...any) {
+		if onStep != nil {
+			onStep(fmt.Sprintf(format, args...))
+		}
+	}
+
+	if aws.ToBool(cluster.DeletionProtection) {
+		_, err := c.rds.ModifyDBCluster(ctx, &rds.ModifyDBClusterInput{
+			DBClusterIdentifier: aws.String(clusterIdentifier),
+			DeletionProtection:  aws.Bool(false),
+			ApplyImmediately:    aws.Bool(true),
+		})
+		if err != nil {
+			return fmt.Errorf("turning off deletion protection of %s: %w", clusterIdentifier, err)
+		}
+		step("turned off deletion protection of cluster %s", clusterIdentifier)
+	}
+
+	instances := memberIdentifiers(cluster)
+	for _, id := range instances {
+		_, err := c.rds.DeleteDBInstance(ctx, &rds.DeleteDBInstanceInput{DBInstanceIdentifier: aws.String(id)})
+		var notFound *types.DBInstanceNotFoundFault
+		if err != nil && !errors.As(err, &notFound) {
+			return fmt.Errorf("deleting instance %s: %w", id, err)
+		}
+	}
+	for _, id := range instances {
+		if err := c.waitInstanceDeleted(ctx, id); err != nil {
+			return err
+		}
+		step("deleted instance %s", id)
+	}
+
+	_, err = c.rds.DeleteDBCluster(ctx, &rds.DeleteDBClusterInput{
+		DBClusterIdentifier: aws.String(clusterIdentifier),
+		SkipFinalSnapshot:   aws.Bool(true),
+	})
+	if err != nil {
+		return fmt.Errorf("deleting cluster %s: %w", clusterIdentifier, err)
+	}
+	for {
+		out, err := c.findCluster(ctx, clusterIdentifier)
+		if err != nil {
+			return err
+		}
+		if out == nil {
+			step("deleted cluster %s", clusterIdentifier)
+			return nil
+		}
+		if err := c.sleep(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+// waitInstanceDeleted waits until the instance no longer exists.
+func (c *Client) waitInstanceDeleted(ctx context.Context, identifier string) error {
+	for {
+		out, err := c.rds.DescribeDBInstances(ctx, &rds.DescribeDBInstancesInput{
+			Filters: []types.Filter{{Name: aws.String("db-instance-id"), Values: []string{identifier}}},
+		})
+		if err != nil {
+			return fmt.Errorf("describing instance %s: %w", identifier, err)
+		}
+		if len(out.DBInstances) == 0 {
+			return nil
+		}
+		if err := c.sleep(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+// sleep waits for the poll interval unless ctx is cancelled first.
+func (c *Client) sleep(ctx context.Context) error {
+	interval := c.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(interval):
+		return nil
+	}
+}
@@ -0,0 +1,120 @@
+package bluegreen
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Names of the RDS Blue/Green deployment events, as the monitoring stack's
+// event recorder (cmd/lab-bluegreen-events) stores them.
+const (
+	EventDeploymentAvailable = "deployment-available"
+	EventDeploymentFailed    = "deployment-failed"
+	EventDeploymentDeleted   = "deployment-deleted"
+	EventSwitchoverStarted   = "switchover-started"
+	EventSwitchoverCompleted = "switchover-completed"
+	EventSwitchoverCanceled  = "switchover-canceled"
+)
+
+// eventNames maps the RDS event IDs of Blue/Green deployments to names.
+var eventNames = map[string]string{
+	"RDS-EVENT-0244": EventDeploymentAvailable,
+	"RDS-EVENT-0245": EventDeploymentFailed,
+	"RDS-EVENT-0246": EventDeploymentDeleted,
+	"RDS-EVENT-0247": EventSwitchoverStarted,
+	"RDS-EVENT-0248": EventSwitchoverCompleted,
+	"RDS-EVENT-0249": EventSwitchoverCanceled,
+}
+
+// EventTimeFormat formats event times with milliseconds and a fixed width,
+// so they sort as strings.
+const EventTimeFormat = "2006-01-02T15:04:05.000Z"
+
+// Attributes of the items of the monitoring stack's Blue/Green event table,
+// one item per event.
+const (
+	// AttrDeploymentID is the partition key
+	AttrDeploymentID = "DeploymentId"
+	// AttrEventKey is the sort key, see DeploymentEvent.Key
+	AttrEventKey  = "EventKey"
+	AttrEventTime = "EventTime"
+	AttrEventID   = "EventId"
+	AttrEvent     = "Event"
+	AttrMessage   = "Message"
+	// AttrRunID is the RunId tag of the deployment (see internal/runid);
+	// missing for deployments without one
+	AttrRunID = "RunId"
+	// AttrExpiresAt is the item's TTL in Unix seconds
+	AttrExpiresAt = "ExpiresAt"
+)
+
+// DeploymentEvent is an RDS Blue/Green deployment event with the time RDS
+// recorded it.
+type DeploymentEvent struct {
+	DeploymentID string
+	// Time is when the event happened, with millisecond precision
+	Time time.Time
+	// EventID is the RDS event ID, e.g. RDS-EVENT-0247
+	EventID string
+	// Name is the event's name (e.g. EventSwitchoverStarted), or the event ID
+	// in lower case for events without one
+	Name    string
+	Message string
+}
+
+// eventBridgeEvent is an "RDS Blue Green Deployment Event" EventBridge event.
+type eventBridgeEvent struct {
+	DetailType string `json:"detail-type"`
+	Time       string `json:"time"`
+	Detail     struct {
+		SourceIdentifier string `json:"SourceIdentifier"`
+		Date             string `json:"Date"`
+		EventID          string `json:"EventID"`
+		Message          string `json:"Message"`
+	} `json:"detail"`
+}
+
+// ParseEventBridgeEvent parses an RDS Blue/Green deployment event delivered by
+// EventBridge. The time is the detail's, which unlike the envelope's has
+// milliseconds.
+func ParseEventBridgeEvent(data []byte) (*DeploymentEvent, error) {
+	var e eventBridgeEvent
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("parsing event: %w", err)
+	}
+	if e.DetailType != "RDS Blue Green Deployment Event" {
+		return nil, fmt.Errorf("not an RDS Blue/Green deployment event: %q", e.DetailType)
+	}
+	if e.Detail.SourceIdentifier == "" || e.Detail.EventID == "" {
+		return nil, fmt.Errorf("event has no SourceIdentifier or EventID")
+	}
+	date := e.Detail.Date
+	if date == "" {
+		date = e.Time
+	}
+	t, err := time.Parse(time.RFC3339Nano, date)
+	if err != nil {
+		return nil, fmt.Errorf("parsing event time: %w", err)
+	}
+
+	name, ok := eventNames[e.Detail.EventID]
+	if !ok {
+		name = strings.ToLower(e.Detail.EventID)
+	}
+	return &DeploymentEvent{
+		DeploymentID: e.Detail.SourceIdentifier,
+		Time:         t.UTC().Truncate(time.Millisecond),
+		EventID:      e.Detail.EventID,
+		Name:         name,
+		Message:      e.Detail.Message,
+	}, nil
+}
+
+// Key returns the event's sort key in the event table: its time in
+// EventTimeFormat and its event ID, so events of the same millisecond are
+// kept apart.
+func (e *DeploymentEvent) Key() string {
+	return e.Time.Format(EventTimeFormat) + "#" + e.EventID
+}
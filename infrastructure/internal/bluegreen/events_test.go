@@ -0,0 +1,66 @@
+package bluegreen
+
+import (
+	"testing"
+	"time"
+)
+
+// switchoverStartedEvent is an EventBridge event as RDS sends it.
+const switchoverStartedEvent = `{
+  "version": "0",
+  "id": "68f6e973-1a0c-d37b-f2f2-94a7f62ffd4e",
+  "detail-type": "RDS Blue Green Deployment Event",
+  "source": "aws.rds",
+  "account": "123456789012",
+  "time": "2026-01-02T15:04:05Z",
+  "region": "us-east-1",
+  "resources": ["arn:aws:rds:us-east-1:123456789012:deployment:bgd-abc123"],
+  "detail": {
+    "EventCategories": ["notification"],
+    "SourceType": "BLUE_GREEN_DEPLOYMENT",
+    "SourceArn": "arn:aws:rds:us-east-1:123456789012:deployment:bgd-abc123",
+    "Date": "2026-01-02T15:04:05.123456Z",
+    "Message": "Switchover from DB cluster lab to lab-green-x1 started.",
+    "SourceIdentifier": "bgd-abc123",
+    "EventID": "RDS-EVENT-0247"
+  }
+}`
+
+func TestParseEventBridgeEvent(t *testing.T) {
+	e, err := ParseEventBridgeEvent([]byte(switchoverStartedEvent))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DeploymentEvent{
+		DeploymentID: "bgd-abc123",
+		Time:         time.Date(2026, 1, 2, 15, 4, 5, 123000000, time.UTC),
+		EventID:      "RDS-EVENT-0247",
+		Name:         EventSwitchoverStarted,
+		Message:      "Switchover from DB cluster lab to lab-green-x1 started.",
+	}
+	if *e != want {
+		t.Errorf("got %+v, want %+v", *e, want)
+	}
+	if got := e.Key(); got != "2026-01-02T15:04:05.123Z#RDS-EVENT-0247" {
+		t.Errorf("Key() = %q", got)
+	}
+
+	unknown, err := ParseEventBridgeEvent([]byte(`{"detail-type": "RDS Blue Green Deployment Event", "time": "2026-01-02T15:04:05Z",
+		"detail": {"SourceIdentifier": "bgd-abc123", "EventID": "RDS-EVENT-0307"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unknown.Name != "rds-event-0307" || !unknown.Time.Equal(time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)) {
+		t.Errorf("got %+v, want the event ID as name and the envelope's time", *unknown)
+	}
+
+	for _, event := range []string{
+		`{"detail-type": "RDS DB Cluster Event", "detail": {"SourceIdentifier": "lab", "EventID": "RDS-EVENT-0001"}}`,
+		`{"detail-type": "RDS Blue Green Deployment Event", "detail": {"EventID": "RDS-EVENT-0247"}}`,
+		`{"detail-type": "RDS Blue Green Deployment Event", "detail": {"SourceIdentifier": "bgd-abc123", "EventID": "RDS-EVENT-0247", "Date": "yesterday"}}`,
+	} {
+		if _, err := ParseEventBridgeEvent([]byte(event)); err == nil {
+			t.Errorf("%s: got no error", event)
+		}
+	}
+}
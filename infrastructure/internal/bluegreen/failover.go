@@ -0,0 +1,104 @@
+package bluegreen
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/aws/aws-sdk-go-v2/service/rds/types"
+)
+
+// Failover is the state of a cluster failover.
+type Failover struct {
+	ClusterIdentifier string
+	// From is the writer before the failover, Writer the current one
+	From   string
+	Writer string
+	// Status is the cluster's status (failing-over, available)
+	Status string
+}
+
+// Done reports whether another instance is the writer and the cluster is
+// available again.
+func (f *Failover) Done() bool {
+	return f.Writer != "" && f.Writer != f.From && f.Status == "available"
+}
+
+// Failover fails the cluster over with FailoverDBCluster, to targetInstance
+// or to the reader RDS picks when it is empty, and waits until the failover
+// is Done, reporting every change of the writer or cluster status to
+// onStatus. Its completion time is known to the poll interval.
+func (c *Client) Failover(ctx context.Context, clusterIdentifier, targetInstance string, onStatus func(*Failover)) (*Failover, error) {
+	cluster, err := c.describeCluster(ctx, clusterIdentifier)
+	if err != nil {
+		return nil, err
+	}
+	from := writerInstance(cluster)
+
+	input := &rds.FailoverDBClusterInput{DBClusterIdentifier: aws.String(clusterIdentifier)}
+	if targetInstance != "" {
+		input.TargetDBInstanceIdentifier = aws.String(targetInstance)
+	}
+	if _, err := c.rds.FailoverDBCluster(ctx, input); err != nil {
+		return nil, fmt.Errorf("failing over cluster %s: %w", clusterIdentifier, err)
+	}
+
+	interval := c.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	var last Failover
+	for {
+		cluster, err := c.describeCluster(ctx, clusterIdentifier)
+		if err != nil {
+			return nil, err
+		}
+		f := &Failover{
+			ClusterIdentifier: clusterIdentifier,
+			From:              from,
+			Writer:            writerInstance(cluster),
+			Status:            aws.ToString(cluster.Status),
+		}
+		if *f != last {
+			last = *f
+			if onStatus != nil {
+				onStatus(f)
+			}
+		}
+		if f.Done() {
+			return f, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return f, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (c *Client) describeCluster(ctx context.Context, clusterIdentifier string) (*types.DBCluster, error) {
+	out, err := c.rds.DescribeDBClusters(ctx, &rds.DescribeDBClustersInput{
+		DBClusterIdentifier: aws.String(clusterIdentifier),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("describing cluster %s: %w", clusterIdentifier, err)
+	}
+	if len(out.DBClusters) == 0 {
+		return nil, fmt.Errorf("cluster %s not found", clusterIdentifier)
+	}
+	return &out.DBClusters[0], nil
+}
+
+// writerInstance returns the identifier of the cluster's writer, empty while
+// it has none.
+func writerInstance(cluster *types.DBCluster) string {
+	for _, member := range cluster.DBClusterMembers {
+		if aws.ToBool(member.IsClusterWriter) {
+			return aws.ToString(member.DBInstanceIdentifier)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,97 @@
+package bluegreen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/rds/types"
+)
+
+// ErrSwitchoverStalled is returned by Switchover when the switchover neither
+// completed nor was rolled back well after its timeout.
+var ErrSwitchoverStalled = errors.New("switchover neither completed nor was rolled back")
+
+// SwitchoverFailure explains why a switchover did not complete.
+type SwitchoverFailure struct {
+	// Reason says what happened, with the deployment's status details
+	Reason string
+	// BlueServing is set when the blue environment still serves the
+	// application: the switchover was rolled back or never started
+	BlueServing bool
+	// Guidance are the steps to take before retrying
+	Guidance []string
+}
+
+// switchoverCauses map words of a deployment's status details to what to do
+// about them; RDS blocks or rolls back a switchover on these guardrails.
+var switchoverCauses = []struct {
+	words    []string
+	guidance string
+}{
+	{[]string{"long-running", "long running", "transaction"},
+		"Long-running transactions on the blue writer blocked the switchover: list them with SELECT * FROM information_schema.innodb_trx ORDER BY trx_started, then commit, kill or pause them (batch jobs, open sessions) before retrying"},
+	{[]string{"ddl", "metadata lock"},
+		"DDL was running on the blue cluster: wait for it to finish and do not start schema changes during the switchover"},
+	{[]string{"lag", "replica", "replication"},
+		"Replication to the green environment was behind: wait until its AuroraBinlogReplicaLag is close to zero (bgctl switchover -auto gates on it) and reduce the write load"},
+	{[]string{"external replication", "binlog"},
+		"External binary log replication from the blue cluster is in the way: stop it or make sure it is caught up before retrying"},
+	{[]string{"parameter group", "pending"},
+		"A cluster or instance has pending changes: wait until they are applied or reboot the instance, check bgctl watch"},
+}
+
+// DiagnoseSwitchover explains a Switchover error: why the switchover failed,
+// whether the blue environment still serves the application and what to do.
+func DiagnoseSwitchover(err error) *SwitchoverFailure {
+	f := &SwitchoverFailure{}
+	var statusErr *StatusError
+	var stateFault *types.InvalidBlueGreenDeploymentStateFault
+	var notFound *types.BlueGreenDeploymentNotFoundFault
+	details := ""
+	switch {
+	case errors.As(err, &statusErr) && statusErr.RolledBack:
+		f.Reason = "the switchover did not complete within its timeout or hit a guardrail, and RDS rolled it back"
+		f.BlueServing = true
+		details = statusErr.Deployment.StatusDetails
+	case errors.As(err, &statusErr):
+		f.Reason = fmt.Sprintf("the deployment is %s", statusErr.Deployment.Status)
+		details = statusErr.Deployment.StatusDetails
+		f.Guidance = append(f.Guidance, "Check the deployment's RDS events (console, or the monitoring stack's event table) for the failed step; if the deployment does not return to AVAILABLE, delete it and create a new one")
+	case errors.As(err, &stateFault):
+		f.Reason = "RDS did not start the switchover: the deployment is not in a state that allows it"
+		f.BlueServing = true
+		details = stateFault.ErrorMessage()
+		f.Guidance = append(f.Guidance, "Wait until the deployment is AVAILABLE (bgctl watch) and retry")
+	case errors.As(err, &notFound):
+		f.Reason = "the deployment does not exist"
+		f.BlueServing = true
+	case errors.Is(err, ErrSwitchoverStalled):
+		f.Reason = "the switchover is still in progress long after its timeout"
+		f.Guidance = append(f.Guidance, "Follow the deployment with bgctl watch; RDS completes or rolls back the switchover on its own, do not delete the deployment while it is in progress")
+	case errors.Is(err, context.Canceled):
+		f.Reason = "waiting for the switchover was interrupted"
+		f.Guidance = append(f.Guidance, "RDS continues the switchover; follow it with bgctl watch")
+	default:
+		f.Reason = err.Error()
+		return f
+	}
+	if details != "" {
+		f.Reason += ": " + details
+	}
+
+	lower := strings.ToLower(details)
+	for _, cause := range switchoverCauses {
+		for _, word := range cause.words {
+			if strings.Contains(lower, word) {
+				f.Guidance = append(f.Guidance, cause.guidance)
+				break
+			}
+		}
+	}
+	if statusErr != nil && statusErr.RolledBack {
+		f.Guidance = append(f.Guidance, "Retry with a longer switchover timeout (up to 1h) or during lower write traffic")
+	}
+	return f
+}
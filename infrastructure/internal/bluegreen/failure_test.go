@@ -0,0 +1,42 @@
+package bluegreen
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rds/types"
+)
+
+func TestDiagnoseSwitchover(t *testing.T) {
+	rolledBack := &StatusError{
+		Deployment: &Deployment{ID: "bgd-abc", Status: StatusAvailable, StatusDetails: "Switchover was rolled back because of long-running transactions on the source"},
+		RolledBack: true,
+	}
+	f := DiagnoseSwitchover(fmt.Errorf("switching over: %w", rolledBack))
+	if !f.BlueServing || !strings.Contains(f.Reason, "rolled it back: Switchover was rolled back because of long-running") {
+		t.Errorf("rolled back: %+v", f)
+	}
+	if len(f.Guidance) != 2 || !strings.Contains(f.Guidance[0], "information_schema.innodb_trx") || !strings.Contains(f.Guidance[1], "longer switchover timeout") {
+		t.Errorf("rolled back guidance: %q", f.Guidance)
+	}
+
+	failed := &StatusError{Deployment: &Deployment{ID: "bgd-abc", Status: StatusSwitchoverFailed}}
+	if f := DiagnoseSwitchover(failed); f.BlueServing || f.Reason != "the deployment is SWITCHOVER_FAILED" {
+		t.Errorf("failed: %+v", f)
+	}
+
+	notAvailable := &types.InvalidBlueGreenDeploymentStateFault{Message: aws.String("The deployment is PROVISIONING")}
+	if f := DiagnoseSwitchover(fmt.Errorf("switching over: %w", notAvailable)); !f.BlueServing || !strings.Contains(f.Reason, "PROVISIONING") {
+		t.Errorf("invalid state: %+v", f)
+	}
+
+	if f := DiagnoseSwitchover(fmt.Errorf("bgd-abc: %w", ErrSwitchoverStalled)); f.BlueServing || len(f.Guidance) != 1 {
+		t.Errorf("stalled: %+v", f)
+	}
+	if f := DiagnoseSwitchover(context.Canceled); f.BlueServing || len(f.Guidance) != 1 {
+		t.Errorf("interrupted: %+v", f)
+	}
+}
@@ -0,0 +1,51 @@
+package bluegreen
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/aws/aws-sdk-go-v2/service/rds/types"
+)
+
+// Integration is a zero-ETL integration replicating a cluster into a
+// warehouse such as Redshift.
+type Integration struct {
+	Name      string
+	Status    string
+	TargetArn string
+}
+
+// ZeroEtlIntegrations returns the zero-ETL integrations whose source is the
+// cluster with the given ARN. Blue/Green deployments of an integration's
+// source are restricted, and the integration follows the cluster, not its
+// endpoint.
+func (c *Client) ZeroEtlIntegrations(ctx context.Context, clusterArn string) ([]Integration, error) {
+	var integrations []types.Integration
+	paginator := rds.NewDescribeIntegrationsPaginator(c.rds, &rds.DescribeIntegrationsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("describing zero-ETL integrations: %w", err)
+		}
+		integrations = append(integrations, page.Integrations...)
+	}
+	return integrationsOf(integrations, clusterArn), nil
+}
+
+// integrationsOf returns the integrations sourced from clusterArn.
+func integrationsOf(integrations []types.Integration, clusterArn string) []Integration {
+	var of []Integration
+	for _, i := range integrations {
+		if aws.ToString(i.SourceArn) != clusterArn {
+			continue
+		}
+		of = append(of, Integration{
+			Name:      aws.ToString(i.IntegrationName),
+			Status:    string(i.Status),
+			TargetArn: aws.ToString(i.TargetArn),
+		})
+	}
+	return of
+}
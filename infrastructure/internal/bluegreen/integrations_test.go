@@ -0,0 +1,20 @@
+package bluegreen
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rds/types"
+)
+
+func TestIntegrationsOf(t *testing.T) {
+	const clusterArn = "arn:aws:rds:us-east-1:123456789012:cluster:aurora-bluegreen-lab-cluster"
+	integrations := integrationsOf([]types.Integration{
+		{IntegrationName: aws.String("lab-to-redshift"), SourceArn: aws.String(clusterArn), Status: types.IntegrationStatusActive,
+			TargetArn: aws.String("arn:aws:redshift-serverless:us-east-1:123456789012:namespace/lab")},
+		{IntegrationName: aws.String("other"), SourceArn: aws.String(clusterArn + "-old1"), Status: types.IntegrationStatusActive},
+	}, clusterArn)
+	if len(integrations) != 1 || integrations[0].Name != "lab-to-redshift" || integrations[0].Status != "active" {
+		t.Errorf("got %+v, want lab-to-redshift", integrations)
+	}
+}
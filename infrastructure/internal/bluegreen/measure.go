@@ -0,0 +1,187 @@
+package bluegreen
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/aws/aws-sdk-go-v2/service/rds/types"
+)
+
+// Stages of a deployment, from its creation to the completed switchover, in
+// order.
+const (
+	StageCreated              = "created"
+	StageAvailable            = "available"
+	StageSwitchoverInProgress = "switchover-in-progress"
+	StageCompleted            = "completed"
+)
+
+// Stages lists the stages in order.
+var Stages = []string{StageCreated, StageAvailable, StageSwitchoverInProgress, StageCompleted}
+
+// stageEvents are the RDS events that mark the stages.
+var stageEvents = map[string]string{
+	EventDeploymentAvailable: StageAvailable,
+	EventSwitchoverStarted:   StageSwitchoverInProgress,
+	EventSwitchoverCompleted: StageCompleted,
+}
+
+// stageStatuses are the deployment statuses of the stages.
+var stageStatuses = map[string]string{
+	StatusProvisioning:         StageCreated,
+	StatusAvailable:            StageAvailable,
+	StatusSwitchoverInProgress: StageSwitchoverInProgress,
+	StatusSwitchoverCompleted:  StageCompleted,
+}
+
+// Stage is when a deployment reached a stage.
+type Stage struct {
+	Name string
+	// Event is when RDS recorded the stage: the deployment's creation time,
+	// or the time of the stage's RDS event; zero when unknown
+	Event time.Time
+	// Observed is when polling first saw the deployment change to the
+	// stage's status; zero when it was not seen changing
+	Observed time.Time
+}
+
+// Time returns the RDS time of the stage, else its observed time.
+func (s Stage) Time() time.Time {
+	if !s.Event.IsZero() {
+		return s.Event
+	}
+	return s.Observed
+}
+
+// Measurement collects the stages of a deployment from its status changes
+// and RDS events. It is safe for concurrent use.
+type Measurement struct {
+	mu     sync.Mutex
+	stages map[string]*Stage
+	// status is the last status observed
+	status string
+	events map[string]bool
+}
+
+// NewMeasurement returns a measurement of a deployment created at created.
+func NewMeasurement(created time.Time) *Measurement {
+	m := &Measurement{stages: map[string]*Stage{}, events: map[string]bool{}}
+	if !created.IsZero() {
+		m.stage(StageCreated).Event = created
+	}
+	return m
+}
+
+func (m *Measurement) stage(name string) *Stage {
+	s := m.stages[name]
+	if s == nil {
+		s = &Stage{Name: name}
+		m.stages[name] = s
+	}
+	return s
+}
+
+// Observe records the deployment's status seen at t. Only changes count:
+// the status seen first was reached before the measurement started.
+func (m *Measurement) Observe(d *Deployment, t time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	previous := m.status
+	m.status = d.Status
+	if previous == "" || previous == d.Status {
+		return
+	}
+	if name, ok := stageStatuses[d.Status]; ok {
+		if s := m.stage(name); s.Observed.IsZero() {
+			s.Observed = t
+		}
+	}
+}
+
+// AddEvent records an RDS event of the deployment, and reports whether it
+// is new.
+func (m *Measurement) AddEvent(e DeploymentEvent) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := e.Time.Format(EventTimeFormat) + "#" + e.Message
+	if m.events[key] {
+		return false
+	}
+	m.events[key] = true
+	if name, ok := stageEvents[e.Name]; ok {
+		if s := m.stage(name); s.Event.IsZero() || e.Time.After(s.Event) {
+			// A retried switchover starts again; the latest one counts
+			s.Event = e.Time
+		}
+	}
+	return true
+}
+
+// Stages returns the stages reached, in order.
+func (m *Measurement) Stages() []Stage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var stages []Stage
+	for _, name := range Stages {
+		if s := m.stages[name]; s != nil {
+			stages = append(stages, *s)
+		}
+	}
+	return stages
+}
+
+// DeploymentEvents returns the RDS events of the deployment since the given
+// time, oldest first. RDS keeps events for 14 days. DescribeEvents does not
+// return event IDs, so the events are named by their message (eventName).
+func (c *Client) DeploymentEvents(ctx context.Context, id string, since time.Time) ([]DeploymentEvent, error) {
+	var events []DeploymentEvent
+	paginator := rds.NewDescribeEventsPaginator(c.rds, &rds.DescribeEventsInput{
+		SourceType:       types.SourceTypeBlueGreenDeployment,
+		SourceIdentifier: aws.String(id),
+		StartTime:        aws.Time(since),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("describing RDS events of %s: %w", id, err)
+		}
+		for _, e := range page.Events {
+			message := aws.ToString(e.Message)
+			events = append(events, DeploymentEvent{
+				DeploymentID: id,
+				Time:         aws.ToTime(e.Date).UTC(),
+				Name:         eventName(message),
+				Message:      message,
+			})
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	return events, nil
+}
+
+// eventName returns the name of an RDS Blue/Green deployment event by its
+// message, as RDS words it for RDS-EVENT-0244 to RDS-EVENT-0249; empty for
+// other events.
+func eventName(message string) string {
+	switch m := strings.ToLower(message); {
+	case strings.Contains(m, "switchover") && strings.Contains(m, "started"):
+		return EventSwitchoverStarted
+	case strings.Contains(m, "switchover completed"):
+		return EventSwitchoverCompleted
+	case strings.Contains(m, "switchover canceled") || strings.Contains(m, "switchover cancelled"):
+		return EventSwitchoverCanceled
+	case strings.Contains(m, "tasks completed"):
+		return EventDeploymentAvailable
+	case strings.Contains(m, "deployment deleted"):
+		return EventDeploymentDeleted
+	case strings.Contains(m, "failed"):
+		return EventDeploymentFailed
+	}
+	return ""
+}
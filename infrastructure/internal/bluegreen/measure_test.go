@@ -0,0 +1,57 @@
+package bluegreen
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventName(t *testing.T) {
+	for message, want := range map[string]string{
+		"Blue/Green Deployment tasks completed. You can make more modifications to the green environment databases or switch over the deployment.": EventDeploymentAvailable,
+		"Switchover from DB cluster lab to lab-green-abc123 started.":                                                                              EventSwitchoverStarted,
+		"Switchover completed on Blue/Green Deployment.":                                                                                           EventSwitchoverCompleted,
+		"Switchover canceled on Blue/Green Deployment due to a timeout.":                                                                           EventSwitchoverCanceled,
+		"Blue/Green Deployment deleted.":                                                                                                           EventDeploymentDeleted,
+		"Creation of Blue/Green Deployment failed because the source cluster has no binary logging.":                                               EventDeploymentFailed,
+		"Blue/Green Deployment creation started.":                                                                                                  "",
+	} {
+		if got := eventName(message); got != want {
+			t.Errorf("%q: got %q, want %q", message, got, want)
+		}
+	}
+}
+
+func TestMeasurement(t *testing.T) {
+	created := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	m := NewMeasurement(created)
+	at := func(d time.Duration) time.Time { return created.Add(d) }
+
+	// Measuring starts once the deployment is available
+	m.Observe(&Deployment{Status: StatusAvailable}, at(time.Hour))
+	m.AddEvent(DeploymentEvent{Time: at(25 * time.Minute), Name: EventDeploymentAvailable, Message: "tasks completed"})
+	m.Observe(&Deployment{Status: StatusSwitchoverInProgress}, at(time.Hour+1500*time.Millisecond))
+	m.AddEvent(DeploymentEvent{Time: at(time.Hour + 1120*time.Millisecond), Name: EventSwitchoverStarted, Message: "started"})
+	m.Observe(&Deployment{Status: StatusSwitchoverCompleted}, at(time.Hour+31*time.Second))
+	if m.AddEvent(DeploymentEvent{Time: at(time.Hour + 1120*time.Millisecond), Name: EventSwitchoverStarted, Message: "started"}) {
+		t.Error("the same event added twice")
+	}
+
+	want := []Stage{
+		{Name: StageCreated, Event: created},
+		{Name: StageAvailable, Event: at(25 * time.Minute)},
+		{Name: StageSwitchoverInProgress, Event: at(time.Hour + 1120*time.Millisecond), Observed: at(time.Hour + 1500*time.Millisecond)},
+		{Name: StageCompleted, Observed: at(time.Hour + 31*time.Second)},
+	}
+	got := m.Stages()
+	if len(got) != len(want) {
+		t.Fatalf("got %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("stage %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if got[3].Time() != at(time.Hour+31*time.Second) {
+		t.Errorf("completed without an event: Time() = %s", got[3].Time())
+	}
+}
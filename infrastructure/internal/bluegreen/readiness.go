@@ -0,0 +1,161 @@
+package bluegreen
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/aws/aws-sdk-go-v2/service/rds/types"
+)
+
+// SupportsBlueGreen reports whether Blue/Green deployments support a cluster
+// running engineVersion: Aurora MySQL 2.10 and later (MySQL 5.7) and Aurora
+// MySQL 3 (MySQL 8.0). Aurora MySQL 1 (MySQL 5.6) and earlier Aurora MySQL 2
+// versions are not supported.
+func SupportsBlueGreen(engineVersion string) bool {
+	mysql, aurora, found := strings.Cut(engineVersion, ".mysql_aurora.")
+	if !found {
+		return false
+	}
+	parts := strings.Split(aurora, ".")
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return false
+	}
+	switch {
+	case mysql == "8.0" && major >= 3:
+		return true
+	case mysql == "5.7" && major == 2 && len(parts) > 1:
+		minor, err := strconv.Atoi(parts[1])
+		return err == nil && minor >= 10
+	}
+	return false
+}
+
+// Readiness is the configuration of a cluster that decides whether a
+// Blue/Green deployment of it can be created and switched over.
+type Readiness struct {
+	ClusterIdentifier string
+	EngineVersion     string
+	// EngineVersionStatus is the engine version's status in RDS (available,
+	// deprecated), empty when RDS no longer lists it
+	EngineVersionStatus string
+	// EngineMode is provisioned for clusters of provisioned and Serverless v2
+	// instances
+	EngineMode       string
+	StorageType      string
+	StorageEncrypted bool
+	// ReplicationSource is the cluster this one is a read replica of, empty
+	// when it is not a replica
+	ReplicationSource string
+	// ReadReplicas are the clusters replicating from this one, such as
+	// cross-Region read replicas
+	ReadReplicas []string
+	// ParameterGroup is the cluster parameter group and BinlogFormat its
+	// binlog_format (OFF when the group does not set it)
+	ParameterGroup string
+	BinlogFormat   string
+	// WriterParameterStatus is the writer's parameter group status, e.g.
+	// pending-reboot when binlog_format has not taken effect yet
+	WriterParameterStatus string
+	// PendingMaintenance are the maintenance actions pending on the cluster
+	// and its instances
+	PendingMaintenance []MaintenanceAction
+}
+
+// MaintenanceAction is a pending maintenance action of a cluster or instance.
+type MaintenanceAction struct {
+	// Resource is the identifier of the cluster or instance
+	Resource    string
+	Action      string
+	Description string
+	// AutoAppliedAfter and ForcedApply are zero when not scheduled
+	AutoAppliedAfter time.Time
+	ForcedApply      time.Time
+}
+
+// Readiness describes the configuration of a cluster that Blue/Green
+// deployments depend on.
+func (c *Client) Readiness(ctx context.Context, identifier string) (*Readiness, error) {
+	cluster, err := c.describeCluster(ctx, identifier)
+	if err != nil {
+		return nil, err
+	}
+	r := &Readiness{
+		ClusterIdentifier: identifier,
+		EngineVersion:     aws.ToString(cluster.EngineVersion),
+		EngineMode:        aws.ToString(cluster.EngineMode),
+		StorageType:       aws.ToString(cluster.StorageType),
+		StorageEncrypted:  aws.ToBool(cluster.StorageEncrypted),
+		ReplicationSource: aws.ToString(cluster.ReplicationSourceIdentifier),
+		ReadReplicas:      cluster.ReadReplicaIdentifiers,
+		ParameterGroup:    aws.ToString(cluster.DBClusterParameterGroup),
+		BinlogFormat:      "OFF",
+	}
+	var instances []string
+	for _, member := range cluster.DBClusterMembers {
+		instances = append(instances, aws.ToString(member.DBInstanceIdentifier))
+		if aws.ToBool(member.IsClusterWriter) {
+			r.WriterParameterStatus = aws.ToString(member.DBClusterParameterGroupStatus)
+		}
+	}
+
+	versions, err := c.rds.DescribeDBEngineVersions(ctx, &rds.DescribeDBEngineVersionsInput{
+		Engine:        cluster.Engine,
+		EngineVersion: cluster.EngineVersion,
+		IncludeAll:    aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("describing engine version %s: %w", r.EngineVersion, err)
+	}
+	if len(versions.DBEngineVersions) > 0 {
+		r.EngineVersionStatus = aws.ToString(versions.DBEngineVersions[0].Status)
+	}
+
+	parameters := rds.NewDescribeDBClusterParametersPaginator(c.rds, &rds.DescribeDBClusterParametersInput{
+		DBClusterParameterGroupName: cluster.DBClusterParameterGroup,
+	})
+	for parameters.HasMorePages() {
+		page, err := parameters.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("describing cluster parameter group %s: %w", r.ParameterGroup, err)
+		}
+		for _, p := range page.Parameters {
+			if aws.ToString(p.ParameterName) == "binlog_format" && p.ParameterValue != nil {
+				r.BinlogFormat = aws.ToString(p.ParameterValue)
+			}
+		}
+	}
+
+	filters := []types.Filter{{Name: aws.String("db-cluster-id"), Values: []string{identifier}}}
+	if len(instances) > 0 {
+		filters = append(filters, types.Filter{Name: aws.String("db-instance-id"), Values: instances})
+	}
+	for _, filter := range filters {
+		actions := rds.NewDescribePendingMaintenanceActionsPaginator(c.rds, &rds.DescribePendingMaintenanceActionsInput{
+			Filters: []types.Filter{filter},
+		})
+		for actions.HasMorePages() {
+			page, err := actions.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("describing pending maintenance actions of %s: %w", identifier, err)
+			}
+			for _, resource := range page.PendingMaintenanceActions {
+				for _, action := range resource.PendingMaintenanceActionDetails {
+					r.PendingMaintenance = append(r.PendingMaintenance, MaintenanceAction{
+						Resource:         clusterIdentifier(aws.ToString(resource.ResourceIdentifier)),
+						Action:           aws.ToString(action.Action),
+						Description:      aws.ToString(action.Description),
+						AutoAppliedAfter: aws.ToTime(action.AutoAppliedAfterDate),
+						ForcedApply:      aws.ToTime(action.ForcedApplyDate),
+					})
+				}
+			}
+		}
+	}
+	return r, nil
+}
@@ -0,0 +1,21 @@
+package bluegreen
+
+import "testing"
+
+func TestSupportsBlueGreen(t *testing.T) {
+	for version, want := range map[string]bool{
+		"8.0.mysql_aurora.3.08.0":  true,
+		"8.0.mysql_aurora.3.01.0":  true,
+		"5.7.mysql_aurora.2.12.1":  true,
+		"5.7.mysql_aurora.2.10.0":  true,
+		"5.7.mysql_aurora.2.09.2":  false,
+		"5.7.mysql_aurora.2.07.10": false,
+		"5.6.mysql_aurora.1.23.4":  false,
+		"5.6.10a":                  false,
+		"":                         false,
+	} {
+		if got := SupportsBlueGreen(version); got != want {
+			t.Errorf("%q: got %t, want %t", version, got, want)
+		}
+	}
+}
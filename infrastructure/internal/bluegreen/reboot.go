@@ -0,0 +1,80 @@
+package bluegreen
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/aws/aws-sdk-go-v2/service/rds/types"
+)
+
+// Reboot is the state of an instance reboot.
+type Reboot struct {
+	Instance string
+	Status   string
+	// Restarted is set once the instance was seen rebooting
+	Restarted bool
+}
+
+// Done reports whether the instance restarted and is available again.
+func (r *Reboot) Done() bool {
+	return r.Restarted && r.Status == "available"
+}
+
+// RebootInstances reboots the instances at once and waits until they are all
+// available again, reporting every status change to onStatus. Rebooting an
+// Aurora writer restarts it in place; the cluster has no writer until it is
+// back, and does not fail over.
+func (c *Client) RebootInstances(ctx context.Context, instances []string, onStatus func(*Reboot)) error {
+	reboots := map[string]*Reboot{}
+	for _, instance := range instances {
+		if _, err := c.rds.RebootDBInstance(ctx, &rds.RebootDBInstanceInput{DBInstanceIdentifier: aws.String(instance)}); err != nil {
+			return fmt.Errorf("rebooting instance %s: %w", instance, err)
+		}
+		reboots[instance] = &Reboot{Instance: instance}
+	}
+
+	interval := c.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	for {
+		out, err := c.rds.DescribeDBInstances(ctx, &rds.DescribeDBInstancesInput{
+			Filters: []types.Filter{{Name: aws.String("db-instance-id"), Values: instances}},
+		})
+		if err != nil {
+			return fmt.Errorf("describing instances: %w", err)
+		}
+		done := true
+		for _, instance := range out.DBInstances {
+			r := reboots[aws.ToString(instance.DBInstanceIdentifier)]
+			if r == nil {
+				continue
+			}
+			status := aws.ToString(instance.DBInstanceStatus)
+			if status != r.Status {
+				r.Status = status
+				// RDS may still report the instance available right after
+				// the call
+				r.Restarted = r.Restarted || status != "available"
+				if onStatus != nil {
+					onStatus(r)
+				}
+			}
+		}
+		for _, r := range reboots {
+			done = done && r.Done()
+		}
+		if done {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package bluegreen
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/aws/aws-sdk-go-v2/service/rds/types"
+)
+
+// oldBlueSuffix is the suffix RDS adds to the identifiers of the blue cluster
+// and its instances when a deployment is switched over.
+const oldBlueSuffix = "-old1"
+
+// rolledBackSuffix is the suffix Restore adds to the identifiers of the
+// cluster and instances it takes out of service.
+const rolledBackSuffix = "-rolledback1"
+
+// RolledBackClusterIdentifier returns the identifier Restore gives the cluster
+// that served the application before the rollback.
+func RolledBackClusterIdentifier(clusterIdentifier string) string {
+	return clusterIdentifier + rolledBackSuffix
+}
+
+// ClusterSettings are the settings of a cluster a reverse deployment copies
+// to its green environment.
+type ClusterSettings struct {
+	Identifier            string
+	Arn                   string
+	EngineVersion         string
+	ClusterParameterGroup string
+	// InstanceParameterGroup and InstanceClass are the writer's
+	InstanceParameterGroup string
+	InstanceClass          string
+}
+
+// ClusterSettings describes the settings of a cluster and its writer.
+func (c *Client) ClusterSettings(ctx context.Context, clusterIdentifier string) (*ClusterSettings, error) {
+	cluster, err := c.describeCluster(ctx, clusterIdentifier)
+	if err != nil {
+		return nil, err
+	}
+	s := &ClusterSettings{
+		Identifier:            clusterIdentifier,
+		Arn:                   aws.ToString(cluster.DBClusterArn),
+		EngineVersion:         aws.ToString(cluster.EngineVersion),
+		ClusterParameterGroup: aws.ToString(cluster.DBClusterParameterGroup),
+	}
+	if writer := writerInstance(cluster); writer != "" {
+		out, err := c.rds.DescribeDBInstances(ctx, &rds.DescribeDBInstancesInput{DBInstanceIdentifier: aws.String(writer)})
+		if err != nil {
+			return nil, fmt.Errorf("describing instance %s: %w", writer, err)
+		}
+		for _, instance := range out.DBInstances {
+			s.InstanceClass = aws.ToString(instance.DBInstanceClass)
+			if len(instance.DBParameterGroups) > 0 {
+				s.InstanceParameterGroup = aws.ToString(instance.DBParameterGroups[0].DBParameterGroupName)
+			}
+		}
+	}
+	return s, nil
+}
+
+// Restore points the application back at the old blue cluster of
+// clusterIdentifier by renaming, the way a switchover does: the cluster and
+// its instances get a -rolledback1 suffix, then the old blue cluster and its
+// instances lose their -old1 suffix, so the cluster's and instances'
+// endpoints lead to the old blue environment again. Nothing is deleted, but
+// writes made since the switchover are only in the rolled back cluster.
+// Each rename is reported to onStep once it is done.
+func (c *Client) Restore(ctx context.Context, clusterIdentifier string, onStep func(string)) error {
+	current, err := c.describeCluster(ctx, clusterIdentifier)
+	if err != nil {
+		return err
+	}
+	old, err := c.describeCluster(ctx, OldBlueClusterIdentifier(clusterIdentifier))
+	if err != nil {
+		return err
+	}
+
+	renames := restoreRenames(clusterIdentifier, memberIdentifiers(current), memberIdentifiers(old))
+	for _, r := range renames {
+		kind := "instance"
+		if r.cluster {
+			kind = "cluster"
+			_, err = c.rds.ModifyDBCluster(ctx, &rds.ModifyDBClusterInput{
+				DBClusterIdentifier:    aws.String(r.from),
+				NewDBClusterIdentifier: aws.String(r.to),
+				ApplyImmediately:       aws.Bool(true),
+			})
+		} else {
+			_, err = c.rds.ModifyDBInstance(ctx, &rds.ModifyDBInstanceInput{
+				DBInstanceIdentifier:    aws.String(r.from),
+				NewDBInstanceIdentifier: aws.String(r.to),
+				ApplyImmediately:        aws.Bool(true),
+			})
+		}
+		if err != nil {
+			return fmt.Errorf("renaming %s %s to %s: %w", kind, r.from, r.to, err)
+		}
+		if err := c.waitRenamed(ctx, r.cluster, r.to); err != nil {
+			return fmt.Errorf("renaming %s %s to %s: %w", kind, r.from, r.to, err)
+		}
+		if onStep != nil {
+			onStep(fmt.Sprintf("renamed %s %s to %s", kind, r.from, r.to))
+		}
+	}
+	return nil
+}
+
+// rename is a step of Restore.
+type rename struct {
+	cluster  bool
+	from, to string
+}
+
+// restoreRenames returns the renames that restore the old blue cluster of
+// clusterIdentifier, in order: the identifiers must be free before they are
+// taken. current and old are the instances of the cluster and the old blue
+// cluster.
+func restoreRenames(clusterIdentifier string, current, old []string) []rename {
+	var renames []rename
+	for _, id := range current {
+		renames = append(renames, rename{from: id, to: id + rolledBackSuffix})
+	}
+	renames = append(renames,
+		rename{cluster: true, from: clusterIdentifier, to: RolledBackClusterIdentifier(clusterIdentifier)},
+		rename{cluster: true, from: OldBlueClusterIdentifier(clusterIdentifier), to: clusterIdentifier})
+	for _, id := range old {
+		if to := strings.TrimSuffix(id, oldBlueSuffix); to != id {
+			renames = append(renames, rename{from: id, to: to})
+		}
+	}
+	return renames
+}
+
+func memberIdentifiers(cluster *types.DBCluster) []string {
+	var ids []string
+	for _, member := range cluster.DBClusterMembers {
+		ids = append(ids, aws.ToString(member.DBInstanceIdentifier))
+	}
+	return ids
+}
+
+// waitRenamed waits until the cluster or instance is available under its new
+// identifier.
+func (c *Client) waitRenamed(ctx context.Context, cluster bool, identifier string) error {
+	interval := c.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	for {
+		status := ""
+		if cluster {
+			out, err := c.findCluster(ctx, identifier)
+			if err != nil {
+				return err
+			}
+			if out != nil {
+				status = aws.ToString(out.Status)
+			}
+		} else {
+			out, err := c.rds.DescribeDBInstances(ctx, &rds.DescribeDBInstancesInput{
+				Filters: []types.Filter{{Name: aws.String("db-instance-id"), Values: []string{identifier}}},
+			})
+			if err != nil {
+				return fmt.Errorf("describing instance %s: %w", identifier, err)
+			}
+			if len(out.DBInstances) > 0 {
+				status = aws.ToString(out.DBInstances[0].DBInstanceStatus)
+			}
+		}
+		if status == "available" {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// findCluster returns the cluster, nil when it does not exist (e.g. while it
+// is renamed).
+func (c *Client) findCluster(ctx context.Context, clusterIdentifier string) (*types.DBCluster, error) {
+	out, err := c.rds.DescribeDBClusters(ctx, &rds.DescribeDBClustersInput{
+		Filters: []types.Filter{{Name: aws.String("db-cluster-id"), Values: []string{clusterIdentifier}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("describing cluster %s: %w", clusterIdentifier, err)
+	}
+	if len(out.DBClusters) == 0 {
+		return nil, nil
+	}
+	return &out.DBClusters[0], nil
+}
@@ -0,0 +1,23 @@
+package bluegreen
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRestoreRenames(t *testing.T) {
+	got := restoreRenames("lab",
+		[]string{"lab-writer", "lab-reader-1"},
+		[]string{"lab-writer-old1", "lab-reader-1-old1", "lab-reader-added"})
+	want := []rename{
+		{from: "lab-writer", to: "lab-writer-rolledback1"},
+		{from: "lab-reader-1", to: "lab-reader-1-rolledback1"},
+		{cluster: true, from: "lab", to: "lab-rolledback1"},
+		{cluster: true, from: "lab-old1", to: "lab"},
+		{from: "lab-writer-old1", to: "lab-writer"},
+		{from: "lab-reader-1-old1", to: "lab-reader-1"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
@@ -0,0 +1,194 @@
+package bluegreen
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/aws/aws-sdk-go-v2/service/rds/types"
+)
+
+// Cluster roles in a Blue/Green deployment.
+const (
+	RoleBlue  = "blue"
+	RoleGreen = "green"
+)
+
+// LabStatus is the state of the lab cluster, its Blue/Green deployments and
+// the clusters they involve, as bgctl watch shows it.
+type LabStatus struct {
+	// Deployments are the deployments of the lab cluster, including switched
+	// over ones whose blue cluster is the old blue cluster now
+	Deployments []DeploymentStatus
+	// Clusters are the lab cluster, the green clusters of the deployments
+	// and the old blue cluster left by a switchover, those that exist
+	Clusters []ClusterStatus
+}
+
+// DeploymentStatus is a deployment with its tasks and switchover progress.
+type DeploymentStatus struct {
+	Deployment
+	// Tasks are the deployment's tasks (e.g. CREATING_READ_REPLICA_OF_SOURCE)
+	// with their statuses
+	Tasks []Task
+	// Switchover is the switchover state of each blue member
+	Switchover []MemberSwitchover
+}
+
+// Task is a Blue/Green deployment task.
+type Task struct {
+	Name   string
+	Status string
+}
+
+// MemberSwitchover is the switchover state of a blue cluster or instance and
+// its green counterpart, by identifier.
+type MemberSwitchover struct {
+	Source string
+	Target string
+	Status string
+}
+
+// ClusterStatus is a cluster and its instances.
+type ClusterStatus struct {
+	Identifier    string
+	Status        string
+	EngineVersion string
+	Endpoint      string
+	// Role is RoleBlue or RoleGreen in an active deployment, else empty
+	Role string
+	// Pending are the settings with pending modifications (e.g. EngineVersion)
+	Pending   []string
+	Instances []InstanceStatus
+}
+
+// InstanceStatus is a cluster instance.
+type InstanceStatus struct {
+	Identifier string
+	Status     string
+	Class      string
+	Writer     bool
+	// AvailabilityZone is the instance's zone, e.g. for an AZ impairment
+	AvailabilityZone string
+	// Pending are the settings with pending modifications (e.g. DBInstanceClass)
+	Pending []string
+}
+
+// LabStatus describes the deployments of clusterIdentifier and the clusters
+// they involve. Deployments that were switched over are found through the
+// old blue cluster (OldBlueClusterIdentifier), which is their source then.
+func (c *Client) LabStatus(ctx context.Context, clusterIdentifier string) (*LabStatus, error) {
+	out, err := c.rds.DescribeBlueGreenDeployments(ctx, &rds.DescribeBlueGreenDeploymentsInput{})
+	if err != nil {
+		return nil, fmt.Errorf("describing Blue/Green deployments: %w", err)
+	}
+
+	s := &LabStatus{}
+	identifiers := []string{clusterIdentifier, OldBlueClusterIdentifier(clusterIdentifier)}
+	roles := map[string]string{}
+	for i := range out.BlueGreenDeployments {
+		d := deploymentStatus(&out.BlueGreenDeployments[i])
+		source := d.SourceClusterIdentifier()
+		if source != clusterIdentifier && source != OldBlueClusterIdentifier(clusterIdentifier) {
+			continue
+		}
+		s.Deployments = append(s.Deployments, d)
+		// After a switchover the target has the lab cluster's identifier
+		if target := d.TargetClusterIdentifier(); target != "" && target != clusterIdentifier {
+			identifiers = append(identifiers, target)
+			if d.Status != StatusSwitchoverCompleted {
+				roles[target] = RoleGreen
+			}
+		}
+		if d.Status != StatusSwitchoverCompleted {
+			roles[source] = RoleBlue
+		}
+	}
+
+	clusters, err := c.rds.DescribeDBClusters(ctx, &rds.DescribeDBClustersInput{
+		Filters: []types.Filter{{Name: aws.String("db-cluster-id"), Values: identifiers}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("describing clusters: %w", err)
+	}
+	instances, err := c.rds.DescribeDBInstances(ctx, &rds.DescribeDBInstancesInput{
+		Filters: []types.Filter{{Name: aws.String("db-cluster-id"), Values: identifiers}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("describing instances: %w", err)
+	}
+	byIdentifier := map[string]types.DBInstance{}
+	for _, instance := range instances.DBInstances {
+		byIdentifier[aws.ToString(instance.DBInstanceIdentifier)] = instance
+	}
+
+	for _, cluster := range clusters.DBClusters {
+		cs := ClusterStatus{
+			Identifier:    aws.ToString(cluster.DBClusterIdentifier),
+			Status:        aws.ToString(cluster.Status),
+			EngineVersion: aws.ToString(cluster.EngineVersion),
+			Endpoint:      aws.ToString(cluster.Endpoint),
+			Pending:       pendingModifications(cluster.PendingModifiedValues),
+		}
+		cs.Role = roles[cs.Identifier]
+		for _, member := range cluster.DBClusterMembers {
+			instance := byIdentifier[aws.ToString(member.DBInstanceIdentifier)]
+			cs.Instances = append(cs.Instances, InstanceStatus{
+				Identifier:       aws.ToString(member.DBInstanceIdentifier),
+				Status:           aws.ToString(instance.DBInstanceStatus),
+				Class:            aws.ToString(instance.DBInstanceClass),
+				Writer:           aws.ToBool(member.IsClusterWriter),
+				AvailabilityZone: aws.ToString(instance.AvailabilityZone),
+				Pending:          pendingModifications(instance.PendingModifiedValues),
+			})
+		}
+		// The writer first, then the readers by name
+		sort.Slice(cs.Instances, func(i, j int) bool {
+			if cs.Instances[i].Writer != cs.Instances[j].Writer {
+				return cs.Instances[i].Writer
+			}
+			return cs.Instances[i].Identifier < cs.Instances[j].Identifier
+		})
+		s.Clusters = append(s.Clusters, cs)
+	}
+	sort.Slice(s.Clusters, func(i, j int) bool { return s.Clusters[i].Identifier < s.Clusters[j].Identifier })
+	return s, nil
+}
+
+func deploymentStatus(d *types.BlueGreenDeployment) DeploymentStatus {
+	s := DeploymentStatus{Deployment: *deployment(d)}
+	for _, task := range d.Tasks {
+		s.Tasks = append(s.Tasks, Task{Name: aws.ToString(task.Name), Status: aws.ToString(task.Status)})
+	}
+	for _, member := range d.SwitchoverDetails {
+		s.Switchover = append(s.Switchover, MemberSwitchover{
+			Source: clusterIdentifier(aws.ToString(member.SourceMember)),
+			Target: clusterIdentifier(aws.ToString(member.TargetMember)),
+			Status: aws.ToString(member.Status),
+		})
+	}
+	return s
+}
+
+// pendingModifications returns the names of the set fields of a cluster's or
+// instance's PendingModifiedValues. AllocatedStorage is left out: Aurora
+// always reports it for clusters.
+func pendingModifications(values any) []string {
+	v := reflect.ValueOf(values)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return nil
+	}
+	v = v.Elem()
+	var pending []string
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() || field.Name == "AllocatedStorage" || v.Field(i).IsZero() {
+			continue
+		}
+		pending = append(pending, field.Name)
+	}
+	return pending
+}
@@ -0,0 +1,26 @@
+package bluegreen
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rds/types"
+)
+
+func TestPendingModifications(t *testing.T) {
+	if got := pendingModifications((*types.PendingModifiedValues)(nil)); got != nil {
+		t.Errorf("nil values: got %v", got)
+	}
+	// Aurora reports AllocatedStorage 1 on every cluster
+	if got := pendingModifications(&types.ClusterPendingModifiedValues{AllocatedStorage: aws.Int32(1)}); got != nil {
+		t.Errorf("AllocatedStorage only: got %v", got)
+	}
+	got := pendingModifications(&types.PendingModifiedValues{
+		DBInstanceClass: aws.String("db.r6g.xlarge"),
+		EngineVersion:   aws.String("8.0.mysql_aurora.3.08.0"),
+	})
+	if want := []string{"DBInstanceClass", "EngineVersion"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,90 @@
+package bluegreen
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+)
+
+// UpgradeTarget is an engine version a cluster can be upgraded to.
+type UpgradeTarget struct {
+	EngineVersion string
+	Description   string
+	// Major is set for major version upgrades, e.g. MySQL 5.7 to 8.0
+	Major bool
+	// AutoUpgrade is set for the target of automatic minor version upgrades
+	AutoUpgrade bool
+	// BlueGreen reports whether a Blue/Green deployment of a cluster on the
+	// current version can upgrade to the target (SupportsBlueGreen of both)
+	BlueGreen bool
+}
+
+// UpgradeTargets lists the Aurora MySQL versions a cluster running
+// engineVersion can be upgraded to, oldest first.
+func (c *Client) UpgradeTargets(ctx context.Context, engineVersion string) ([]UpgradeTarget, error) {
+	out, err := c.rds.DescribeDBEngineVersions(ctx, &rds.DescribeDBEngineVersionsInput{
+		Engine:        aws.String("aurora-mysql"),
+		EngineVersion: aws.String(engineVersion),
+		IncludeAll:    aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("describing engine version %s: %w", engineVersion, err)
+	}
+	if len(out.DBEngineVersions) == 0 {
+		return nil, fmt.Errorf("engine version %s of aurora-mysql not found", engineVersion)
+	}
+	var targets []UpgradeTarget
+	for _, t := range out.DBEngineVersions[0].ValidUpgradeTarget {
+		version := aws.ToString(t.EngineVersion)
+		targets = append(targets, UpgradeTarget{
+			EngineVersion: version,
+			Description:   aws.ToString(t.Description),
+			Major:         aws.ToBool(t.IsMajorVersionUpgrade),
+			AutoUpgrade:   aws.ToBool(t.AutoUpgrade),
+			BlueGreen:     SupportsBlueGreen(engineVersion) && SupportsBlueGreen(version),
+		})
+	}
+	sort.SliceStable(targets, func(i, j int) bool {
+		return CompareVersions(targets[i].EngineVersion, targets[j].EngineVersion) < 0
+	})
+	return targets, nil
+}
+
+// CompareVersions compares two Aurora MySQL engine versions, e.g.
+// 8.0.mysql_aurora.3.04.0 and 8.0.mysql_aurora.3.10.0, by their numbers:
+// -1 when a is older, 1 when it is newer, 0 when they are the same.
+func CompareVersions(a, b string) int {
+	as, bs := versionNumbers(a), versionNumbers(b)
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		switch {
+		case as[i] < bs[i]:
+			return -1
+		case as[i] > bs[i]:
+			return 1
+		}
+	}
+	switch {
+	case len(as) < len(bs):
+		return -1
+	case len(as) > len(bs):
+		return 1
+	}
+	return 0
+}
+
+// versionNumbers returns the numbers of an engine version in order; parts
+// without a number, like mysql_aurora, are skipped.
+func versionNumbers(version string) []int {
+	var numbers []int
+	for _, part := range strings.FieldsFunc(version, func(r rune) bool { return r == '.' || r == '_' }) {
+		if n, err := strconv.Atoi(part); err == nil {
+			numbers = append(numbers, n)
+		}
+	}
+	return numbers
+}
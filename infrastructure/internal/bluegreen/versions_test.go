@@ -0,0 +1,20 @@
+package bluegreen
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want int
+	}{
+		{"8.0.mysql_aurora.3.04.0", "8.0.mysql_aurora.3.10.0", -1},
+		{"8.0.mysql_aurora.3.10.0", "8.0.mysql_aurora.3.04.0", 1},
+		{"5.7.mysql_aurora.2.12.1", "8.0.mysql_aurora.3.04.0", -1},
+		{"8.0.mysql_aurora.3.04.0", "8.0.mysql_aurora.3.04.0", 0},
+		{"8.0.mysql_aurora.3.04.0", "8.0.mysql_aurora.3.04.0.1", -1},
+	} {
+		if got := CompareVersions(tc.a, tc.b); got != tc.want {
+			t.Errorf("CompareVersions(%s, %s) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
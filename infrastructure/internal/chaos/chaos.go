@@ -0,0 +1,268 @@
+// Package chaos injects faults into the lab with AWS Fault Injection Service
+// (FIS), so the lab doubles as a general Aurora resilience testing
+// environment: network latency on the simulator host, and the impairment of
+// an Availability Zone's subnets.
+//
+// bgctl chaos runs each experiment from a one-off template that is deleted
+// when the experiment ends, or from the templates of the fis stack, found by
+// their Name tag (StartTemplate). Instance reboots go through RDS directly
+// (bluegreen.Client.RebootInstances).
+package chaos
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/fis"
+	"github.com/aws/aws-sdk-go-v2/service/fis/types"
+)
+
+// DefaultPollInterval is how often an experiment's state is polled.
+const DefaultPollInterval = 5 * time.Second
+
+// Client runs FIS experiments.
+type Client struct {
+	fis *fis.Client
+	// PollInterval is the state polling interval (default DefaultPollInterval)
+	PollInterval time.Duration
+}
+
+// New returns a Client for the given AWS configuration.
+func New(cfg aws.Config) *Client {
+	return &Client{fis: fis.NewFromConfig(cfg), PollInterval: DefaultPollInterval}
+}
+
+// Experiment is a FIS experiment template: one action on one target.
+type Experiment struct {
+	Description string
+	// RoleArn is the role FIS assumes to inject the fault
+	RoleArn string
+	Action  types.CreateExperimentTemplateActionInput
+	// Target is the target named TargetName in the action's targets
+	TargetName string
+	Target     types.CreateExperimentTemplateTargetInput
+	Tags       map[string]string
+}
+
+// State is the state of a running experiment.
+type State struct {
+	ID     string
+	Status string
+	// Reason explains failed and stopped experiments
+	Reason string
+}
+
+// Done reports whether the experiment has ended.
+func (s *State) Done() bool {
+	// cancelled is newer than the SDK's statuses
+	switch types.ExperimentStatus(s.Status) {
+	case types.ExperimentStatusCompleted, types.ExperimentStatusStopped, types.ExperimentStatusFailed, "cancelled":
+		return true
+	}
+	return false
+}
+
+// NetworkLatency adds delay to the network traffic of an EC2 instance for
+// duration, with the AWSFIS-Run-Network-Latency SSM document, which installs
+// tc on the instance if needed.
+func NetworkLatency(region, instanceArn, iface string, delay, duration time.Duration) Experiment {
+	document := fmt.Sprintf(`{"DelayMilliseconds":"%d","Interface":"%s","DurationSeconds":"%d","InstallDependencies":"True"}`,
+		delay.Milliseconds(), iface, int(duration.Seconds()))
+	return Experiment{
+		Description: fmt.Sprintf("%s of network latency for %s", delay, duration),
+		Action: types.CreateExperimentTemplateActionInput{
+			ActionId: aws.String("aws:ssm:send-command"),
+			Parameters: map[string]string{
+				"documentArn":        "arn:aws:ssm:" + region + "::document/AWSFIS-Run-Network-Latency",
+				"documentParameters": document,
+				"duration":           isoDuration(duration),
+			},
+			Targets: map[string]string{"Instances": "instances"},
+		},
+		TargetName: "instances",
+		Target: types.CreateExperimentTemplateTargetInput{
+			ResourceType:  aws.String("aws:ec2:instance"),
+			ResourceArns:  []string{instanceArn},
+			SelectionMode: aws.String("ALL"),
+		},
+	}
+}
+
+// AzImpairment denies the traffic of the VPC's subnets in availabilityZone
+// for duration, like a network outage of the zone, by replacing their
+// network ACLs.
+func AzImpairment(vpcID, availabilityZone string, duration time.Duration) Experiment {
+	return Experiment{
+		Description: fmt.Sprintf("network disruption of %s for %s", availabilityZone, duration),
+		Action: types.CreateExperimentTemplateActionInput{
+			ActionId: aws.String("aws:network:disrupt-connectivity"),
+			Parameters: map[string]string{
+				"duration": isoDuration(duration),
+				"scope":    "all",
+			},
+			Targets: map[string]string{"Subnets": "subnets"},
+		},
+		TargetName: "subnets",
+		Target: types.CreateExperimentTemplateTargetInput{
+			ResourceType: aws.String("aws:ec2:subnet"),
+			Parameters: map[string]string{
+				"availabilityZoneIdentifier": availabilityZone,
+				"vpc":                        vpcID,
+			},
+			SelectionMode: aws.String("ALL"),
+		},
+	}
+}
+
+// Run creates a template of the experiment, starts it and waits until it
+// ends, reporting every status change to onStatus. The template is deleted
+// afterwards, and the experiment is stopped when ctx is cancelled.
+func (c *Client) Run(ctx context.Context, e Experiment, onStatus func(*State)) (*State, error) {
+	template, err := c.fis.CreateExperimentTemplate(ctx, &fis.CreateExperimentTemplateInput{
+		Description:    aws.String(e.Description),
+		RoleArn:        aws.String(e.RoleArn),
+		StopConditions: []types.CreateExperimentTemplateStopConditionInput{{Source: aws.String("none")}},
+		Actions:        map[string]types.CreateExperimentTemplateActionInput{"fault": e.Action},
+		Targets:        map[string]types.CreateExperimentTemplateTargetInput{e.TargetName: e.Target},
+		Tags:           e.Tags,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating the experiment template: %w", err)
+	}
+	templateID := aws.ToString(template.ExperimentTemplate.Id)
+	defer func() {
+		// The template is only needed to start the experiment
+		_, _ = c.fis.DeleteExperimentTemplate(context.WithoutCancel(ctx), &fis.DeleteExperimentTemplateInput{Id: aws.String(templateID)})
+	}()
+	return c.start(ctx, templateID, e.Tags, onStatus)
+}
+
+// StartTemplate starts an experiment from the template tagged with name and
+// waits until it ends, like Run. name is the template's Name tag, or its
+// last part after the project name (reboot-writer for
+// aurora-bluegreen-lab-reboot-writer) when only one template ends with it.
+func (c *Client) StartTemplate(ctx context.Context, name string, onStatus func(*State)) (*State, error) {
+	templates := map[string]string{}
+	paginator := fis.NewListExperimentTemplatesPaginator(c.fis, &fis.ListExperimentTemplatesInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("listing experiment templates: %w", err)
+		}
+		for _, template := range page.ExperimentTemplates {
+			templates[aws.ToString(template.Id)] = template.Tags["Name"]
+		}
+	}
+	templateID, err := matchTemplate(templates, name)
+	if err != nil {
+		return nil, err
+	}
+	return c.start(ctx, templateID, map[string]string{"Name": name}, onStatus)
+}
+
+// matchTemplate returns the ID of the template named name in templates (ID
+// to Name tag), by its full name or its unique suffix.
+func matchTemplate(templates map[string]string, name string) (string, error) {
+	var suffixed, names []string
+	for id, templateName := range templates {
+		switch {
+		case templateName == name:
+			return id, nil
+		case strings.HasSuffix(templateName, "-"+name):
+			suffixed = append(suffixed, id)
+		}
+		if templateName != "" {
+			names = append(names, templateName)
+		}
+	}
+	sort.Strings(names)
+	switch {
+	case len(suffixed) == 1:
+		return suffixed[0], nil
+	case len(suffixed) > 1:
+		return "", fmt.Errorf("%d experiment templates end with %q; pass the full name (%s)", len(suffixed), name, strings.Join(names, ", "))
+	case len(names) == 0:
+		return "", fmt.Errorf("no experiment template named %q; deploy the fis stack", name)
+	}
+	return "", fmt.Errorf("no experiment template named %q (%s)", name, strings.Join(names, ", "))
+}
+
+// start starts an experiment from a template and waits until it ends.
+func (c *Client) start(ctx context.Context, templateID string, tags map[string]string, onStatus func(*State)) (*State, error) {
+	out, err := c.fis.StartExperiment(ctx, &fis.StartExperimentInput{
+		ExperimentTemplateId: aws.String(templateID),
+		ClientToken:          aws.String(strconv.FormatInt(time.Now().UnixNano(), 36)),
+		Tags:                 tags,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("starting an experiment from template %s: %w", templateID, err)
+	}
+	id := aws.ToString(out.Experiment.Id)
+
+	interval := c.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	last := ""
+	for {
+		got, err := c.fis.GetExperiment(ctx, &fis.GetExperimentInput{Id: aws.String(id)})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, c.stop(ctx, id)
+			}
+			return nil, fmt.Errorf("getting experiment %s: %w", id, err)
+		}
+		s := &State{ID: id}
+		if state := got.Experiment.State; state != nil {
+			s.Status, s.Reason = string(state.Status), aws.ToString(state.Reason)
+		}
+		if s.Status != last {
+			last = s.Status
+			if onStatus != nil {
+				onStatus(s)
+			}
+		}
+		if s.Done() {
+			if s.Status != string(types.ExperimentStatusCompleted) {
+				return s, fmt.Errorf("experiment %s is %s: %s", id, s.Status, s.Reason)
+			}
+			return s, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return s, c.stop(ctx, id)
+		case <-time.After(interval):
+		}
+	}
+}
+
+// stop stops an interrupted experiment, so its fault does not outlive the
+// command, and returns the context's error.
+func (c *Client) stop(ctx context.Context, id string) error {
+	if _, err := c.fis.StopExperiment(context.WithoutCancel(ctx), &fis.StopExperimentInput{Id: aws.String(id)}); err != nil {
+		return fmt.Errorf("%w; stopping experiment %s failed: %v", ctx.Err(), id, err)
+	}
+	return fmt.Errorf("%w; experiment %s was stopped", ctx.Err(), id)
+}
+
+// isoDuration formats a duration as an ISO 8601 duration (PT1M30S), as FIS
+// action parameters expect.
+func isoDuration(d time.Duration) string {
+	s := "PT"
+	if h := int(d.Hours()); h > 0 {
+		s += strconv.Itoa(h) + "H"
+	}
+	if m := int(d.Minutes()) % 60; m > 0 {
+		s += strconv.Itoa(m) + "M"
+	}
+	if sec := int(d.Seconds()) % 60; sec > 0 || s == "PT" {
+		s += strconv.Itoa(sec) + "S"
+	}
+	return s
+}
@@ -0,0 +1,63 @@
+package chaos
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsoDuration(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		0:                               "PT0S",
+		90 * time.Second:                "PT1M30S",
+		2 * time.Minute:                 "PT2M",
+		time.Hour + 2*time.Minute + 3e9: "PT1H2M3S",
+		12 * time.Hour:                  "PT12H",
+	} {
+		if got := isoDuration(d); got != want {
+			t.Errorf("%s: got %s, want %s", d, got, want)
+		}
+	}
+}
+
+func TestNetworkLatency(t *testing.T) {
+	e := NetworkLatency("us-east-1", "arn:aws:ec2:us-east-1:123456789012:instance/i-0abc", "ens5", 200*time.Millisecond, 5*time.Minute)
+	if got, want := e.Action.Parameters["documentParameters"], `{"DelayMilliseconds":"200","Interface":"ens5","DurationSeconds":"300","InstallDependencies":"True"}`; got != want {
+		t.Errorf("document parameters: got %s, want %s", got, want)
+	}
+	if got := e.Action.Parameters["duration"]; got != "PT5M" {
+		t.Errorf("duration: got %s", got)
+	}
+	if e.Action.Targets["Instances"] != e.TargetName {
+		t.Errorf("the action targets %v, not %s", e.Action.Targets, e.TargetName)
+	}
+}
+
+func TestMatchTemplate(t *testing.T) {
+	templates := map[string]string{
+		"EXT1": "aurora-bluegreen-lab-reboot-writer",
+		"EXT2": "aurora-bluegreen-lab-simulator-network-disruption",
+		"EXT3": "other-lab-simulator-network-disruption",
+		"EXT4": "",
+	}
+	for name, want := range map[string]string{
+		"aurora-bluegreen-lab-reboot-writer":     "EXT1",
+		"reboot-writer":                          "EXT1",
+		"other-lab-simulator-network-disruption": "EXT3",
+	} {
+		if got, err := matchTemplate(templates, name); err != nil || got != want {
+			t.Errorf("%s: got %s, %v, want %s", name, got, err, want)
+		}
+	}
+	for name, want := range map[string]string{
+		"simulator-network-disruption": "2 experiment templates end with",
+		"reboot":                       `no experiment template named "reboot" (aurora-bluegreen-lab-reboot-writer, aurora-bluegreen-lab-simulator-network-disruption, other-lab-simulator-network-disruption)`,
+	} {
+		if _, err := matchTemplate(templates, name); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: got %v, want %q", name, err, want)
+		}
+	}
+	if _, err := matchTemplate(nil, "reboot-writer"); err == nil || !strings.Contains(err.Error(), "deploy the fis stack") {
+		t.Errorf("without templates: got %v", err)
+	}
+}
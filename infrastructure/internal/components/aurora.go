@@ -0,0 +1,376 @@
+package components
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/appautoscaling"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/backup"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ec2"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/iam"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/kms"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/rds"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/secretsmanager"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"aurora-bluegreen-lab/internal/labels"
+)
+
+// LabAuroraClusterArgs configures a LabAuroraCluster.
+type LabAuroraClusterArgs struct {
+	Labels *labels.Labels
+
+	// SubnetIds and SecurityGroupId place the cluster in the lab VPC
+	SubnetIds       pulumi.StringArrayInput
+	SecurityGroupId pulumi.StringInput
+	// NetworkType is IPV4 or DUAL, for dual-stack endpoints in subnets with
+	// IPv6 CIDR blocks; nil leaves the RDS default, IPV4
+	NetworkType pulumi.StringPtrInput
+
+	// DatabaseName and MasterUsername are ignored when restoring from SnapshotIdentifier
+	DatabaseName   string
+	MasterUsername string
+	MasterPassword pulumi.StringInput
+	EngineVersion  string
+	InstanceClass  string
+	// StorageType is aurora (Aurora Standard) or aurora-iopt1 (I/O-Optimized)
+	StorageType string
+
+	// SnapshotIdentifier restores the cluster from an existing snapshot
+	SnapshotIdentifier string
+	DeletionProtection bool
+	// FinalSnapshotIdentifier keeps a final snapshot on destroy; empty skips it
+	FinalSnapshotIdentifier string
+	// MonitoringInterval is the Enhanced Monitoring interval in seconds (0 disables it)
+	MonitoringInterval int
+	// IamAuthentication enables IAM database authentication on the cluster
+	IamAuthentication bool
+	// PerformanceInsightsEnabled turns on Performance Insights on every
+	// instance, keeping PerformanceInsightsRetentionPeriod days of data
+	// encrypted with PerformanceInsightsKmsKeyId (default: the aws/rds key)
+	PerformanceInsightsEnabled         bool
+	PerformanceInsightsRetentionPeriod int
+	PerformanceInsightsKmsKeyId        string
+	// AutoMinorVersionUpgrade lets RDS apply minor engine versions to the
+	// instances in the maintenance window
+	AutoMinorVersionUpgrade bool
+	// PreferredMaintenanceWindow (ddd:hh24:mi-ddd:hh24:mi) and
+	// PreferredBackupWindow (hh24:mi-hh24:mi) are in UTC; empty lets RDS
+	// choose
+	PreferredMaintenanceWindow string
+	PreferredBackupWindow      string
+	// BacktrackWindow is the Backtrack target window in seconds (0 disables
+	// it); it can only be enabled when the cluster is created or restored
+	BacktrackWindow int
+	// ActivityStream starts a Database Activity Stream on the cluster,
+	// encrypted with ActivityStreamKmsKeyId or a new customer managed key
+	ActivityStream         bool
+	ActivityStreamKmsKeyId string
+
+	// Parameters are the blue environment's parameter groups
+	Parameters ParameterSet
+	// GreenParameters, when set, creates "-green" parameter groups for the
+	// Blue/Green deployment
+	GreenParameters *ParameterSet
+
+	// GlobalDatabase makes the cluster the primary of an rds.GlobalCluster
+	GlobalDatabase bool
+	// Secondary, when set, adds a secondary region cluster to the Global Database
+	Secondary *SecondaryClusterArgs
+
+	// ReaderAutoScaling, when set, scales the readers with Aurora Auto Scaling
+	ReaderAutoScaling *ReaderAutoScalingArgs
+	// BackupCopy, when set, copies scheduled snapshots to another region
+	BackupCopy *BackupCopyArgs
+	// ExternalReplica, when set, adds an RDS for MySQL instance to replicate
+	// the cluster's binary log
+	ExternalReplica *ExternalReplicaArgs
+	// RdsProxy, when set, adds an RDS Proxy with the cluster as its target
+	RdsProxy *RdsProxyArgs
+}
+
+// LabAuroraCluster is the lab's Aurora MySQL cluster with a writer and a
+// reader instance, its parameter groups and optional Global Database.
+type LabAuroraCluster struct {
+	pulumi.ResourceState
+
+	SubnetGroup *rds.SubnetGroup
+	Cluster     *rds.Cluster
+	Writer      *rds.ClusterInstance
+	Reader      *rds.ClusterInstance
+
+	ClusterParameterGroup       *rds.ClusterParameterGroup
+	InstanceParameterGroup      *rds.ParameterGroup
+	GreenClusterParameterGroup  *rds.ClusterParameterGroup // nil without GreenParameters
+	GreenInstanceParameterGroup *rds.ParameterGroup        // nil without GreenParameters
+
+	MonitoringRole    *iam.Role            // nil when MonitoringInterval is 0
+	GlobalCluster     *rds.GlobalCluster   // nil without GlobalDatabase
+	SecondaryCluster  *rds.Cluster         // nil without Secondary
+	SecondaryInstance *rds.ClusterInstance // nil without Secondary
+
+	ActivityStream    *rds.ClusterActivityStream // nil without ActivityStream
+	ActivityStreamKey *kms.Key                   // nil unless the component created the key
+
+	ReaderScalingTarget *appautoscaling.Target // nil without ReaderAutoScaling
+	ReaderScalingPolicy *appautoscaling.Policy // nil without ReaderAutoScaling
+
+	BackupPlan      *backup.Plan  // nil without BackupCopy
+	BackupVault     *backup.Vault // nil without BackupCopy
+	BackupCopyVault *backup.Vault // nil without BackupCopy
+	BackupCopyKey   *kms.Key      // nil without BackupCopy
+
+	ExternalReplica               *rds.Instance       // nil without ExternalReplica
+	ExternalReplicaSecurityGroup  *ec2.SecurityGroup  // nil without ExternalReplica
+	ExternalReplicaParameterGroup *rds.ParameterGroup // nil without ExternalReplica
+
+	Proxy              *rds.Proxy             // nil without RdsProxy
+	ProxySecurityGroup *ec2.SecurityGroup     // nil without RdsProxy
+	ProxySecret        *secretsmanager.Secret // nil without RdsProxy
+}
+
+// NewLabAuroraCluster creates the lab's Aurora cluster.
+func NewLabAuroraCluster(ctx *pulumi.Context, name string, args *LabAuroraClusterArgs, opts ...pulumi.ResourceOption) (*LabAuroraCluster, error) {
+	if args.Secondary != nil && !args.GlobalDatabase {
+		return nil, fmt.Errorf("a secondary cluster requires GlobalDatabase")
+	}
+	if args.ReaderAutoScaling != nil && readerScalingMetrics[args.ReaderAutoScaling.Metric] == "" {
+		return nil, fmt.Errorf("unknown reader auto scaling metric %q", args.ReaderAutoScaling.Metric)
+	}
+
+	c := &LabAuroraCluster{}
+	err := ctx.RegisterComponentResource(typePrefix+"LabAuroraCluster", name, c, opts...)
+	if err != nil {
+		return nil, err
+	}
+	lb := args.Labels
+
+	// Restoring from a snapshot takes the database name and master username from
+	// the snapshot
+	var databaseName, masterUsername pulumi.StringPtrInput = pulumi.String(args.DatabaseName), pulumi.String(args.MasterUsername)
+	if args.SnapshotIdentifier != "" {
+		databaseName, masterUsername = nil, nil
+	}
+
+	// Create DB Subnet Group
+	c.SubnetGroup, err = rds.NewSubnetGroup(ctx, lb.Name("db-subnet-group"), &rds.SubnetGroupArgs{
+		Name:      pulumi.String(lb.Name("aurora-subnet-group")),
+		SubnetIds: args.SubnetIds,
+		Tags:      lb.Tags(lb.Name("aurora-subnet-group")),
+	}, childOptions(c)...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create DB Cluster and Instance Parameter Groups
+	c.ClusterParameterGroup, c.InstanceParameterGroup, err = newParameterGroups(ctx, lb, "", "blue", args.Parameters, childOptions(c)...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create parameter groups for the green environment, referenced when
+	// creating the Blue/Green deployment
+	if args.GreenParameters != nil {
+		c.GreenClusterParameterGroup, c.GreenInstanceParameterGroup, err = newParameterGroups(ctx, lb, "-green", "green",
+			args.Parameters.Merge(args.GreenParameters), childOptions(c)...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Create Aurora Global Cluster
+	var globalClusterIdentifier pulumi.StringPtrInput
+	if args.GlobalDatabase {
+		c.GlobalCluster, err = rds.NewGlobalCluster(ctx, lb.Name("global-cluster"), &rds.GlobalClusterArgs{
+			GlobalClusterIdentifier: pulumi.String(lb.Name("global-cluster")),
+			Engine:                  pulumi.String("aurora-mysql"),
+			EngineVersion:           pulumi.String(args.EngineVersion),
+			DatabaseName:            pulumi.String(args.DatabaseName),
+			StorageEncrypted:        pulumi.Bool(true),
+			DeletionProtection:      pulumi.Bool(args.DeletionProtection),
+			Tags:                    lb.Tags(lb.Name("global-cluster")),
+		}, childOptions(c)...)
+		if err != nil {
+			return nil, err
+		}
+		globalClusterIdentifier = c.GlobalCluster.ID()
+	}
+
+	// Create Aurora Cluster
+	c.Cluster, err = rds.NewCluster(ctx, lb.Name("aurora-cluster"), &rds.ClusterArgs{
+		ClusterIdentifier:                pulumi.String(lb.Name("aurora-cluster")),
+		Engine:                           pulumi.String("aurora-mysql"),
+		EngineVersion:                    pulumi.String(args.EngineVersion),
+		SnapshotIdentifier:               pulumi.StringPtrFromPtr(optionalString(args.SnapshotIdentifier)),
+		DatabaseName:                     databaseName,
+		MasterUsername:                   masterUsername,
+		MasterPassword:                   args.MasterPassword,
+		DbSubnetGroupName:                c.SubnetGroup.Name,
+		VpcSecurityGroupIds:              pulumi.StringArray{args.SecurityGroupId},
+		NetworkType:                      args.NetworkType,
+		DbClusterParameterGroupName:      c.ClusterParameterGroup.Name,
+		GlobalClusterIdentifier:          globalClusterIdentifier,
+		IamDatabaseAuthenticationEnabled: pulumi.Bool(args.IamAuthentication),
+		BacktrackWindow:                  pulumi.Int(args.BacktrackWindow),
+		BackupRetentionPeriod:            pulumi.Int(7),
+		CopyTagsToSnapshot:               pulumi.Bool(true),
+		PreferredBackupWindow:            pulumi.StringPtrFromPtr(optionalString(args.PreferredBackupWindow)),
+		PreferredMaintenanceWindow:       pulumi.StringPtrFromPtr(optionalString(args.PreferredMaintenanceWindow)),
+		EnabledCloudwatchLogsExports: pulumi.StringArray{
+			pulumi.String("error"),
+			pulumi.String("general"),
+			pulumi.String("slowquery"),
+		},
+		StorageEncrypted:        pulumi.Bool(true),
+		StorageType:             pulumi.StringPtrFromPtr(optionalString(args.StorageType)),
+		ApplyImmediately:        pulumi.Bool(true),
+		DeletionProtection:      pulumi.Bool(args.DeletionProtection),
+		SkipFinalSnapshot:       pulumi.Bool(args.FinalSnapshotIdentifier == ""),
+		FinalSnapshotIdentifier: pulumi.StringPtrFromPtr(optionalString(args.FinalSnapshotIdentifier)),
+		Tags:                    lb.Tags(lb.Name("aurora-cluster")),
+	}, childOptions(c)...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create IAM Role for RDS Enhanced Monitoring
+	var monitoringRoleArn pulumi.StringPtrInput
+	if args.MonitoringInterval > 0 {
+		c.MonitoringRole, err = iam.NewRole(ctx, lb.Name("rds-monitoring-role"), &iam.RoleArgs{
+			Name: pulumi.String(lb.Name("rds-monitoring-role")),
+			AssumeRolePolicy: pulumi.String(`{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Principal": {"Service": "monitoring.rds.amazonaws.com"},
+      "Action": "sts:AssumeRole"
+    }
+  ]
+}`),
+			Tags: lb.Tags(lb.Name("rds-monitoring-role")),
+		}, childOptions(c)...)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = iam.NewRolePolicyAttachment(ctx, lb.Name("rds-monitoring-policy"), &iam.RolePolicyAttachmentArgs{
+			Role:      c.MonitoringRole.Name,
+			PolicyArn: pulumi.String("arn:aws:iam::aws:policy/service-role/AmazonRDSEnhancedMonitoringRole"),
+		}, childOptions(c)...)
+		if err != nil {
+			return nil, err
+		}
+
+		monitoringRoleArn = c.MonitoringRole.Arn
+	}
+
+	// Retention and key may only be set with Performance Insights enabled
+	var piRetentionPeriod pulumi.IntPtrInput
+	var piKmsKeyId pulumi.StringPtrInput
+	if args.PerformanceInsightsEnabled {
+		piRetentionPeriod = pulumi.Int(args.PerformanceInsightsRetentionPeriod)
+		piKmsKeyId = pulumi.StringPtrFromPtr(optionalString(args.PerformanceInsightsKmsKeyId))
+	}
+
+	// Create Aurora Writer Instance
+	c.Writer, err = rds.NewClusterInstance(ctx, lb.Name("writer-instance"), &rds.ClusterInstanceArgs{
+		Identifier:                         pulumi.String(lb.Name("writer-instance")),
+		ClusterIdentifier:                  c.Cluster.ID(),
+		InstanceClass:                      pulumi.String(args.InstanceClass),
+		Engine:                             pulumi.String("aurora-mysql"),
+		EngineVersion:                      pulumi.String(args.EngineVersion),
+		DbParameterGroupName:               c.InstanceParameterGroup.Name,
+		PubliclyAccessible:                 pulumi.Bool(false),
+		AutoMinorVersionUpgrade:            pulumi.Bool(args.AutoMinorVersionUpgrade),
+		PreferredMaintenanceWindow:         pulumi.StringPtrFromPtr(optionalString(args.PreferredMaintenanceWindow)),
+		PerformanceInsightsEnabled:         pulumi.Bool(args.PerformanceInsightsEnabled),
+		PerformanceInsightsRetentionPeriod: piRetentionPeriod,
+		PerformanceInsightsKmsKeyId:        piKmsKeyId,
+		MonitoringInterval:                 pulumi.Int(args.MonitoringInterval),
+		MonitoringRoleArn:                  monitoringRoleArn,
+		Tags:                               lb.Tags(lb.Name("writer-instance"), labels.Role("writer")),
+	}, childOptions(c)...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create Aurora Reader Instance
+	c.Reader, err = rds.NewClusterInstance(ctx, lb.Name("reader-instance"), &rds.ClusterInstanceArgs{
+		Identifier:                         pulumi.String(lb.Name("reader-instance")),
+		ClusterIdentifier:                  c.Cluster.ID(),
+		InstanceClass:                      pulumi.String(args.InstanceClass),
+		Engine:                             pulumi.String("aurora-mysql"),
+		EngineVersion:                      pulumi.String(args.EngineVersion),
+		DbParameterGroupName:               c.InstanceParameterGroup.Name,
+		PubliclyAccessible:                 pulumi.Bool(false),
+		AutoMinorVersionUpgrade:            pulumi.Bool(args.AutoMinorVersionUpgrade),
+		PreferredMaintenanceWindow:         pulumi.StringPtrFromPtr(optionalString(args.PreferredMaintenanceWindow)),
+		PerformanceInsightsEnabled:         pulumi.Bool(args.PerformanceInsightsEnabled),
+		PerformanceInsightsRetentionPeriod: piRetentionPeriod,
+		PerformanceInsightsKmsKeyId:        piKmsKeyId,
+		MonitoringInterval:                 pulumi.Int(args.MonitoringInterval),
+		MonitoringRoleArn:                  monitoringRoleArn,
+		Tags:                               lb.Tags(lb.Name("reader-instance"), labels.Role("reader")),
+	}, childOptions(c, pulumi.DependsOn([]pulumi.Resource{c.Writer}))...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Start the activity stream once the cluster has its instances
+	if args.ActivityStream {
+		err = c.newActivityStream(ctx, args, pulumi.DependsOn([]pulumi.Resource{c.Writer, c.Reader}))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Scale the readers once the cluster has its reader
+	if args.ReaderAutoScaling != nil {
+		err = c.newReaderAutoScaling(ctx, args, pulumi.DependsOn([]pulumi.Resource{c.Reader}))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Snapshot the cluster on a schedule and copy the snapshots to another region
+	if args.BackupCopy != nil {
+		err = c.newBackupCopy(ctx, args)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Add the external replica once the cluster has its writer
+	if args.ExternalReplica != nil {
+		err = c.newExternalReplica(ctx, args)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Put the proxy in front of the cluster once it has its writer
+	if args.RdsProxy != nil {
+		err = c.newRdsProxy(ctx, args)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Create the secondary region cluster of the Global Database
+	if args.Secondary != nil {
+		err = c.newSecondaryCluster(ctx, args, pulumi.DependsOn([]pulumi.Resource{c.Writer}))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = ctx.RegisterResourceOutputs(c, pulumi.Map{
+		"clusterEndpoint":       c.Cluster.Endpoint,
+		"clusterReaderEndpoint": c.Cluster.ReaderEndpoint,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
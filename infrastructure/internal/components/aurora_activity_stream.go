@@ -0,0 +1,47 @@
+package components
+
+import (
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/kms"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/rds"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// newActivityStream starts a Database Activity Stream on the cluster. The
+// activity records are encrypted with a customer managed KMS key, created
+// here unless ActivityStreamKmsKeyId names one, and RDS creates the Kinesis
+// data stream (aws-rds-das-<cluster resource id>) they are delivered to.
+// Aurora MySQL only supports the asynchronous mode.
+func (c *LabAuroraCluster) newActivityStream(ctx *pulumi.Context, args *LabAuroraClusterArgs, opts ...pulumi.ResourceOption) error {
+	lb := args.Labels
+
+	var kmsKeyId pulumi.StringInput = pulumi.String(args.ActivityStreamKmsKeyId)
+	if args.ActivityStreamKmsKeyId == "" {
+		var err error
+		c.ActivityStreamKey, err = kms.NewKey(ctx, lb.Name("activity-stream-key"), &kms.KeyArgs{
+			Description:          pulumi.Sprintf("Database Activity Stream of %s", c.Cluster.ClusterIdentifier),
+			EnableKeyRotation:    pulumi.Bool(true),
+			DeletionWindowInDays: pulumi.Int(7),
+			Tags:                 lb.Tags(lb.Name("activity-stream-key")),
+		}, childOptions(c)...)
+		if err != nil {
+			return err
+		}
+
+		_, err = kms.NewAlias(ctx, lb.Name("activity-stream-key-alias"), &kms.AliasArgs{
+			Name:        pulumi.String("alias/" + lb.Name("activity-stream")),
+			TargetKeyId: c.ActivityStreamKey.KeyId,
+		}, childOptions(c)...)
+		if err != nil {
+			return err
+		}
+		kmsKeyId = c.ActivityStreamKey.Arn
+	}
+
+	var err error
+	c.ActivityStream, err = rds.NewClusterActivityStream(ctx, lb.Name("activity-stream"), &rds.ClusterActivityStreamArgs{
+		ResourceArn: c.Cluster.Arn,
+		Mode:        pulumi.String("async"),
+		KmsKeyId:    kmsKeyId,
+	}, childOptions(c, opts...)...)
+	return err
+}
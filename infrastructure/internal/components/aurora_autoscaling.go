@@ -0,0 +1,65 @@
+package components
+
+import (
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/appautoscaling"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// ReaderAutoScalingArgs configures Aurora Auto Scaling of the cluster's readers.
+type ReaderAutoScalingArgs struct {
+	// MinCapacity and MaxCapacity bound the number of readers, including the
+	// reader instance created by the component
+	MinCapacity int
+	MaxCapacity int
+	// Metric is cpu (average reader CPU utilization, percent) or connections
+	// (average database connections per reader)
+	Metric string
+	// TargetValue is the metric value the readers are scaled to keep
+	TargetValue float64
+}
+
+// readerScalingMetrics maps ReaderAutoScalingArgs.Metric to the predefined
+// Application Auto Scaling metric.
+var readerScalingMetrics = map[string]string{
+	"cpu":         "RDSReaderAverageCPUUtilization",
+	"connections": "RDSReaderAverageDatabaseConnections",
+}
+
+// newReaderAutoScaling registers the cluster's read replica count with
+// Application Auto Scaling and adds a target tracking policy. Readers added
+// by the policy are named application-autoscaling-* and are not managed by
+// Pulumi.
+func (c *LabAuroraCluster) newReaderAutoScaling(ctx *pulumi.Context, args *LabAuroraClusterArgs, opts ...pulumi.ResourceOption) error {
+	lb := args.Labels
+	scaling := args.ReaderAutoScaling
+
+	var err error
+	c.ReaderScalingTarget, err = appautoscaling.NewTarget(ctx, lb.Name("reader-scaling-target"), &appautoscaling.TargetArgs{
+		ServiceNamespace:  pulumi.String("rds"),
+		ScalableDimension: pulumi.String("rds:cluster:ReadReplicaCount"),
+		ResourceId:        pulumi.Sprintf("cluster:%s", c.Cluster.ClusterIdentifier),
+		MinCapacity:       pulumi.Int(scaling.MinCapacity),
+		MaxCapacity:       pulumi.Int(scaling.MaxCapacity),
+		Tags:              lb.Tags(lb.Name("reader-scaling-target")),
+	}, childOptions(c, opts...)...)
+	if err != nil {
+		return err
+	}
+
+	c.ReaderScalingPolicy, err = appautoscaling.NewPolicy(ctx, lb.Name("reader-scaling-policy"), &appautoscaling.PolicyArgs{
+		Name:              pulumi.String(lb.Name("reader-scaling-" + scaling.Metric)),
+		PolicyType:        pulumi.String("TargetTrackingScaling"),
+		ServiceNamespace:  c.ReaderScalingTarget.ServiceNamespace,
+		ScalableDimension: c.ReaderScalingTarget.ScalableDimension,
+		ResourceId:        c.ReaderScalingTarget.ResourceId,
+		TargetTrackingScalingPolicyConfiguration: &appautoscaling.PolicyTargetTrackingScalingPolicyConfigurationArgs{
+			PredefinedMetricSpecification: &appautoscaling.PolicyTargetTrackingScalingPolicyConfigurationPredefinedMetricSpecificationArgs{
+				PredefinedMetricType: pulumi.String(readerScalingMetrics[scaling.Metric]),
+			},
+			TargetValue:      pulumi.Float64(scaling.TargetValue),
+			ScaleOutCooldown: pulumi.Int(300),
+			ScaleInCooldown:  pulumi.Int(300),
+		},
+	}, childOptions(c)...)
+	return err
+}
@@ -0,0 +1,129 @@
+package components
+
+import (
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/backup"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/iam"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/kms"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"aurora-bluegreen-lab/internal/providers"
+)
+
+// BackupCopyArgs configures scheduled cluster snapshots copied to another
+// region. Aurora has no cross-region automated backup replication, so the
+// snapshots are taken and copied by AWS Backup.
+type BackupCopyArgs struct {
+	// Region receives the snapshot copies
+	Region string
+	// Schedule is the AWS Backup cron expression of the snapshots (UTC)
+	Schedule string
+	// RetentionDays is how long the snapshots and their copies are kept
+	RetentionDays int
+}
+
+// newBackupCopy creates an AWS Backup plan that snapshots the cluster into a
+// vault in the cluster's region and copies each snapshot to a vault in
+// args.BackupCopy.Region, encrypted with a customer managed key of that
+// region.
+func (c *LabAuroraCluster) newBackupCopy(ctx *pulumi.Context, args *LabAuroraClusterArgs) error {
+	lb := args.Labels
+	copyArgs := args.BackupCopy
+
+	provider, err := aws.NewProvider(ctx, lb.Name("backup-copy-provider"), &aws.ProviderArgs{
+		Region:      pulumi.String(copyArgs.Region),
+		DefaultTags: providers.DefaultTags(lb),
+	}, childOptions(c)...)
+	if err != nil {
+		return err
+	}
+	inCopyRegion := pulumi.Provider(provider)
+
+	c.BackupCopyKey, err = kms.NewKey(ctx, lb.Name("backup-copy-key"), &kms.KeyArgs{
+		Description:          pulumi.Sprintf("Cross-region snapshot copies of %s", c.Cluster.ClusterIdentifier),
+		EnableKeyRotation:    pulumi.Bool(true),
+		DeletionWindowInDays: pulumi.Int(7),
+		Tags:                 lb.Tags(lb.Name("backup-copy-key")),
+	}, childOptions(c, inCopyRegion)...)
+	if err != nil {
+		return err
+	}
+
+	// ForceDestroy lets pulumi destroy remove the vaults with their recovery points
+	c.BackupVault, err = backup.NewVault(ctx, lb.Name("backup-vault"), &backup.VaultArgs{
+		Name:         pulumi.String(lb.Name("backup-vault")),
+		ForceDestroy: pulumi.Bool(true),
+		Tags:         lb.Tags(lb.Name("backup-vault")),
+	}, childOptions(c)...)
+	if err != nil {
+		return err
+	}
+	c.BackupCopyVault, err = backup.NewVault(ctx, lb.Name("backup-copy-vault"), &backup.VaultArgs{
+		Name:         pulumi.String(lb.Name("backup-copy-vault")),
+		KmsKeyArn:    c.BackupCopyKey.Arn,
+		ForceDestroy: pulumi.Bool(true),
+		Tags:         lb.Tags(lb.Name("backup-copy-vault")),
+	}, childOptions(c, inCopyRegion)...)
+	if err != nil {
+		return err
+	}
+
+	role, err := iam.NewRole(ctx, lb.Name("backup-role"), &iam.RoleArgs{
+		Name: pulumi.String(lb.Name("backup-role")),
+		AssumeRolePolicy: pulumi.String(`{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Principal": {"Service": "backup.amazonaws.com"},
+      "Action": "sts:AssumeRole"
+    }
+  ]
+}`),
+		Tags: lb.Tags(lb.Name("backup-role")),
+	}, childOptions(c)...)
+	if err != nil {
+		return err
+	}
+	_, err = iam.NewRolePolicyAttachment(ctx, lb.Name("backup-role-policy"), &iam.RolePolicyAttachmentArgs{
+		Role:      role.Name,
+		PolicyArn: pulumi.String("arn:aws:iam::aws:policy/service-role/AWSBackupServiceRolePolicyForBackup"),
+	}, childOptions(c)...)
+	if err != nil {
+		return err
+	}
+
+	c.BackupPlan, err = backup.NewPlan(ctx, lb.Name("backup-plan"), &backup.PlanArgs{
+		Name: pulumi.String(lb.Name("backup-plan")),
+		Rules: backup.PlanRuleArray{
+			&backup.PlanRuleArgs{
+				RuleName:        pulumi.String("snapshot-and-copy"),
+				TargetVaultName: c.BackupVault.Name,
+				Schedule:        pulumi.String(copyArgs.Schedule),
+				Lifecycle: &backup.PlanRuleLifecycleArgs{
+					DeleteAfter: pulumi.Int(copyArgs.RetentionDays),
+				},
+				CopyActions: backup.PlanRuleCopyActionArray{
+					&backup.PlanRuleCopyActionArgs{
+						DestinationVaultArn: c.BackupCopyVault.Arn,
+						Lifecycle: &backup.PlanRuleCopyActionLifecycleArgs{
+							DeleteAfter: pulumi.Int(copyArgs.RetentionDays),
+						},
+					},
+				},
+			},
+		},
+		Tags: lb.Tags(lb.Name("backup-plan")),
+	}, childOptions(c)...)
+	if err != nil {
+		return err
+	}
+
+	_, err = backup.NewSelection(ctx, lb.Name("backup-selection"), &backup.SelectionArgs{
+		Name:       pulumi.String(lb.Name("aurora-cluster")),
+		PlanId:     c.BackupPlan.ID(),
+		IamRoleArn: role.Arn,
+		Resources:  pulumi.StringArray{c.Cluster.Arn},
+	}, childOptions(c)...)
+	return err
+}
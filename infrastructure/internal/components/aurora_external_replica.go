@@ -0,0 +1,113 @@
+package components
+
+import (
+	"strings"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ec2"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/rds"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"aurora-bluegreen-lab/internal/labels"
+)
+
+// ExternalReplicaArgs configures an RDS for MySQL instance that replicates
+// the cluster's binary log, standing in for an external replica or binlog
+// consumer that the Blue/Green deployment does not manage.
+type ExternalReplicaArgs struct {
+	InstanceClass string
+	// EngineVersion is an RDS for MySQL version (5.7 or 8.0, optionally with
+	// the minor version), at least the cluster's MySQL version
+	EngineVersion string
+	// VpcId and ClientSecurityGroupId place the replica in the lab VPC and
+	// let the simulator host connect to it
+	VpcId                 pulumi.StringInput
+	ClientSecurityGroupId pulumi.StringInput
+}
+
+// newExternalReplica creates the replica instance in the cluster's subnets
+// with the cluster's master credentials, and lets it connect to the cluster.
+// Replication is not started here: bgctl replica setup points it at the
+// cluster endpoint's binlog coordinates.
+func (c *LabAuroraCluster) newExternalReplica(ctx *pulumi.Context, args *LabAuroraClusterArgs) error {
+	lb := args.Labels
+	replica := args.ExternalReplica
+	major := replica.EngineVersion[:3]
+
+	var err error
+	c.ExternalReplicaSecurityGroup, err = ec2.NewSecurityGroup(ctx, lb.Name("external-replica-sg"), &ec2.SecurityGroupArgs{
+		VpcId:       replica.VpcId,
+		Description: pulumi.String("Security group for the external MySQL replica"),
+		Ingress: ec2.SecurityGroupIngressArray{
+			&ec2.SecurityGroupIngressArgs{
+				Protocol:       pulumi.String("tcp"),
+				FromPort:       pulumi.Int(3306),
+				ToPort:         pulumi.Int(3306),
+				SecurityGroups: pulumi.StringArray{replica.ClientSecurityGroupId},
+				Description:    pulumi.String("MySQL access from the EC2 security group"),
+			},
+		},
+		Egress: allOutbound(false),
+		Tags:   lb.Tags(lb.Name("external-replica-sg")),
+	}, childOptions(c)...)
+	if err != nil {
+		return err
+	}
+
+	// The replica connects to the cluster endpoint, whichever cluster it
+	// points at after a switchover
+	_, err = ec2.NewSecurityGroupRule(ctx, lb.Name("aurora-mysql-from-external-replica"), &ec2.SecurityGroupRuleArgs{
+		Type:                  pulumi.String("ingress"),
+		FromPort:              pulumi.Int(3306),
+		ToPort:                pulumi.Int(3306),
+		Protocol:              pulumi.String("tcp"),
+		SourceSecurityGroupId: c.ExternalReplicaSecurityGroup.ID(),
+		SecurityGroupId:       args.SecurityGroupId,
+		Description:           pulumi.String("Binlog replication to the external MySQL replica"),
+	}, childOptions(c)...)
+	if err != nil {
+		return err
+	}
+
+	// The replica starts without the rows written before replication began,
+	// so row events for rows it does not have are skipped instead of
+	// stopping replication
+	c.ExternalReplicaParameterGroup, err = rds.NewParameterGroup(ctx, lb.Name("external-replica-pg"), &rds.ParameterGroupArgs{
+		Name:        pulumi.String(lb.Name("external-replica-pg")),
+		Family:      pulumi.String("mysql" + major),
+		Description: pulumi.String("External MySQL replica of the lab cluster"),
+		Parameters: rds.ParameterGroupParameterArray{
+			&rds.ParameterGroupParameterArgs{Name: pulumi.String("slave_exec_mode"), Value: pulumi.String("IDEMPOTENT")},
+			&rds.ParameterGroupParameterArgs{Name: pulumi.String("character_set_server"), Value: pulumi.String("utf8mb4")},
+			&rds.ParameterGroupParameterArgs{Name: pulumi.String("collation_server"), Value: pulumi.String("utf8mb4_unicode_ci")},
+		},
+		Tags: lb.Tags(lb.Name("external-replica-pg")),
+	}, childOptions(c)...)
+	if err != nil {
+		return err
+	}
+
+	c.ExternalReplica, err = rds.NewInstance(ctx, lb.Name("external-replica"), &rds.InstanceArgs{
+		Identifier:            pulumi.String(lb.Name("external-replica")),
+		Engine:                pulumi.String("mysql"),
+		EngineVersion:         pulumi.String(replica.EngineVersion),
+		InstanceClass:         pulumi.String(replica.InstanceClass),
+		AllocatedStorage:      pulumi.Int(20),
+		StorageType:           pulumi.String("gp3"),
+		StorageEncrypted:      pulumi.Bool(true),
+		DbName:                pulumi.String(args.DatabaseName),
+		Username:              pulumi.String(args.MasterUsername),
+		Password:              args.MasterPassword,
+		DbSubnetGroupName:     c.SubnetGroup.Name,
+		VpcSecurityGroupIds:   pulumi.StringArray{c.ExternalReplicaSecurityGroup.ID()},
+		ParameterGroupName:    c.ExternalReplicaParameterGroup.Name,
+		PubliclyAccessible:    pulumi.Bool(false),
+		MultiAz:               pulumi.Bool(false),
+		BackupRetentionPeriod: pulumi.Int(0),
+		// A major version only follows the minor versions RDS releases
+		AutoMinorVersionUpgrade: pulumi.Bool(!strings.Contains(replica.EngineVersion[len(major):], ".")),
+		SkipFinalSnapshot:       pulumi.Bool(true),
+		ApplyImmediately:        pulumi.Bool(true),
+		Tags:                    lb.Tags(lb.Name("external-replica"), labels.Role("external-replica")),
+	}, childOptions(c, pulumi.DependsOn([]pulumi.Resource{c.Writer}))...)
+	return err
+}
@@ -0,0 +1,97 @@
+package components
+
+import (
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/kms"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/rds"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"aurora-bluegreen-lab/internal/labels"
+	"aurora-bluegreen-lab/internal/providers"
+)
+
+// SecondaryClusterArgs configures the secondary region cluster of a Global Database.
+type SecondaryClusterArgs struct {
+	// Region is the secondary AWS region
+	Region string
+	// SubnetIds and SecurityGroupId come from a VPC stack deployed in the secondary region
+	SubnetIds       pulumi.StringArrayInput
+	SecurityGroupId pulumi.StringInput
+}
+
+// newSecondaryCluster creates a secondary cluster with one reader instance in
+// another region using a dedicated provider. The secondary
+// replicates from the primary through the global cluster, so it has no master
+// credentials or database name of its own.
+func (c *LabAuroraCluster) newSecondaryCluster(ctx *pulumi.Context, args *LabAuroraClusterArgs, opts ...pulumi.ResourceOption) error {
+	lb := args.Labels
+
+	provider, err := aws.NewProvider(ctx, lb.Name("secondary-provider"), &aws.ProviderArgs{
+		Region:      pulumi.String(args.Secondary.Region),
+		DefaultTags: providers.DefaultTags(lb),
+	}, childOptions(c)...)
+	if err != nil {
+		return err
+	}
+	inRegion := pulumi.Provider(provider)
+
+	// Encrypted cross-region secondaries need a KMS key in their own region
+	rdsKey, err := kms.LookupAlias(ctx, &kms.LookupAliasArgs{Name: "alias/aws/rds"}, inRegion)
+	if err != nil {
+		return err
+	}
+
+	subnetGroup, err := rds.NewSubnetGroup(ctx, lb.Name("secondary-db-subnet-group"), &rds.SubnetGroupArgs{
+		Name:      pulumi.String(lb.Name("aurora-secondary-subnet-group")),
+		SubnetIds: args.Secondary.SubnetIds,
+		Tags:      lb.Tags(lb.Name("aurora-secondary-subnet-group")),
+	}, childOptions(c, inRegion)...)
+	if err != nil {
+		return err
+	}
+
+	c.SecondaryCluster, err = rds.NewCluster(ctx, lb.Name("secondary-cluster"), &rds.ClusterArgs{
+		ClusterIdentifier:       pulumi.String(lb.Name("secondary-cluster")),
+		Engine:                  pulumi.String("aurora-mysql"),
+		EngineVersion:           pulumi.String(args.EngineVersion),
+		GlobalClusterIdentifier: c.GlobalCluster.ID(),
+		DbSubnetGroupName:       subnetGroup.Name,
+		VpcSecurityGroupIds:     pulumi.StringArray{args.Secondary.SecurityGroupId},
+		StorageEncrypted:        pulumi.Bool(true),
+		StorageType:             pulumi.StringPtrFromPtr(optionalString(args.StorageType)),
+		KmsKeyId:                pulumi.String(rdsKey.TargetKeyArn),
+		SkipFinalSnapshot:       pulumi.Bool(true),
+		CopyTagsToSnapshot:      pulumi.Bool(true),
+		Tags:                    lb.Tags(lb.Name("secondary-cluster"), labels.Role("secondary")),
+	}, childOptions(c, append(opts, inRegion, pulumi.IgnoreChanges([]string{"replicationSourceIdentifier"}))...)...)
+	if err != nil {
+		return err
+	}
+
+	// The Performance Insights key belongs to the primary region, so the
+	// secondary instance uses the aws/rds key of its region. Minor versions
+	// of a Global Database are upgraded on the global cluster, so the
+	// secondary instance never upgrades on its own.
+	var piRetentionPeriod pulumi.IntPtrInput
+	if args.PerformanceInsightsEnabled {
+		piRetentionPeriod = pulumi.Int(args.PerformanceInsightsRetentionPeriod)
+	}
+
+	c.SecondaryInstance, err = rds.NewClusterInstance(ctx, lb.Name("secondary-instance"), &rds.ClusterInstanceArgs{
+		Identifier:                         pulumi.String(lb.Name("secondary-instance")),
+		ClusterIdentifier:                  c.SecondaryCluster.ID(),
+		InstanceClass:                      pulumi.String(args.InstanceClass),
+		Engine:                             pulumi.String("aurora-mysql"),
+		EngineVersion:                      pulumi.String(args.EngineVersion),
+		PubliclyAccessible:                 pulumi.Bool(false),
+		AutoMinorVersionUpgrade:            pulumi.Bool(false),
+		PerformanceInsightsEnabled:         pulumi.Bool(args.PerformanceInsightsEnabled),
+		PerformanceInsightsRetentionPeriod: piRetentionPeriod,
+		Tags:                               lb.Tags(lb.Name("secondary-instance"), labels.Role("secondary-reader")),
+	}, childOptions(c, inRegion)...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package components
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/rds"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"aurora-bluegreen-lab/internal/labels"
+)
+
+// Parameter is a single DB parameter group entry.
+type Parameter struct {
+	Name  string `json:"name" yaml:"name"`
+	Value string `json:"value" yaml:"value"`
+	// ApplyMethod is "immediate" (default) or "pending-reboot" (required for static parameters)
+	ApplyMethod string `json:"applyMethod,omitempty" yaml:"applyMethod,omitempty"`
+	// Reset, in an override, removes the parameter from the set so it falls
+	// back to the engine default
+	Reset bool `json:"reset,omitempty" yaml:"reset,omitempty"`
+}
+
+// ParameterSet holds the cluster and instance parameters of one environment.
+type ParameterSet struct {
+	// Family overrides the parameter group family (e.g., for a green environment on a newer major version)
+	Family   string      `json:"family,omitempty" yaml:"family,omitempty"`
+	Cluster  []Parameter `json:"cluster" yaml:"cluster"`
+	Instance []Parameter `json:"instance" yaml:"instance"`
+}
+
+// Merge returns a copy of the set with the overrides applied: parameters with
+// the same name are replaced in place, new ones are appended, and reset ones
+// are removed.
+func (s ParameterSet) Merge(overrides *ParameterSet) ParameterSet {
+	if overrides == nil {
+		return s
+	}
+	result := ParameterSet{
+		Family:   s.Family,
+		Cluster:  mergeParameters(s.Cluster, overrides.Cluster),
+		Instance: mergeParameters(s.Instance, overrides.Instance),
+	}
+	if overrides.Family != "" {
+		result.Family = overrides.Family
+	}
+	return result
+}
+
+func mergeParameters(base, overrides []Parameter) []Parameter {
+	result := append([]Parameter{}, base...)
+	for _, o := range overrides {
+		if o.Reset {
+			result = slices.DeleteFunc(result, func(p Parameter) bool { return p.Name == o.Name })
+			continue
+		}
+		replaced := false
+		for i := range result {
+			if result[i].Name == o.Name {
+				result[i] = o
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			result = append(result, o)
+		}
+	}
+	return result
+}
+
+// Value returns the value of the named cluster parameter, or "" when unset.
+func (s ParameterSet) Value(name string) string {
+	for _, p := range s.Cluster {
+		if p.Name == name {
+			return p.Value
+		}
+	}
+	return ""
+}
+
+func (s ParameterSet) clusterParameters() rds.ClusterParameterGroupParameterArray {
+	result := rds.ClusterParameterGroupParameterArray{}
+	for _, p := range s.Cluster {
+		result = append(result, &rds.ClusterParameterGroupParameterArgs{
+			Name:        pulumi.String(p.Name),
+			Value:       pulumi.String(p.Value),
+			ApplyMethod: pulumi.StringPtrFromPtr(optionalString(p.ApplyMethod)),
+		})
+	}
+	return result
+}
+
+func (s ParameterSet) instanceParameters() rds.ParameterGroupParameterArray {
+	result := rds.ParameterGroupParameterArray{}
+	for _, p := range s.Instance {
+		result = append(result, &rds.ParameterGroupParameterArgs{
+			Name:        pulumi.String(p.Name),
+			Value:       pulumi.String(p.Value),
+			ApplyMethod: pulumi.StringPtrFromPtr(optionalString(p.ApplyMethod)),
+		})
+	}
+	return result
+}
+
+// newParameterGroups creates the cluster and instance parameter groups for a
+// parameter set. suffix distinguishes additional environments (e.g., "-green").
+func newParameterGroups(ctx *pulumi.Context, lb *labels.Labels, suffix, environment string, set ParameterSet, opts ...pulumi.ResourceOption) (*rds.ClusterParameterGroup, *rds.ParameterGroup, error) {
+	// Descriptions force replacement, so the blue groups keep their original text
+	descriptionSuffix := ""
+	if suffix != "" {
+		descriptionSuffix = fmt.Sprintf(" (%s)", environment)
+	}
+
+	clusterParameterGroup, err := rds.NewClusterParameterGroup(ctx, lb.Name("cluster-pg"+suffix), &rds.ClusterParameterGroupArgs{
+		Name:        pulumi.String(lb.Name("aurora-cluster-pg" + suffix)),
+		Family:      pulumi.String(set.Family),
+		Description: pulumi.String("Cluster parameter group for Aurora Blue-Green lab" + descriptionSuffix),
+		Parameters:  set.clusterParameters(),
+		Tags:        lb.Tags(lb.Name("aurora-cluster-pg"+suffix), labels.Tag{Key: "BlueGreenEnvironment", Value: environment}),
+	}, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	instanceParameterGroup, err := rds.NewParameterGroup(ctx, lb.Name("instance-pg"+suffix), &rds.ParameterGroupArgs{
+		Name:        pulumi.String(lb.Name("aurora-instance-pg" + suffix)),
+		Family:      pulumi.String(set.Family),
+		Description: pulumi.String("Instance parameter group for Aurora Blue-Green lab" + descriptionSuffix),
+		Parameters:  set.instanceParameters(),
+		Tags:        lb.Tags(lb.Name("aurora-instance-pg"+suffix), labels.Tag{Key: "BlueGreenEnvironment", Value: environment}),
+	}, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return clusterParameterGroup, instanceParameterGroup, nil
+}
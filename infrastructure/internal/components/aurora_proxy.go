@@ -0,0 +1,173 @@
+package components
+
+import (
+	"encoding/json"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ec2"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/iam"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/rds"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/secretsmanager"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"aurora-bluegreen-lab/internal/labels"
+)
+
+// RdsProxyArgs configures an RDS Proxy in front of the cluster, so the
+// simulator can compare connections through the proxy with direct
+// connections to the cluster endpoint during a switchover.
+type RdsProxyArgs struct {
+	// RequireTls makes the proxy refuse connections without TLS
+	RequireTls bool
+	// MaxConnectionsPercent is the share of the writer's max_connections
+	// the proxy's pool may use (1-100)
+	MaxConnectionsPercent int
+	// VpcId and ClientSecurityGroupId place the proxy in the lab VPC and
+	// let the simulator host connect to it
+	VpcId                 pulumi.StringInput
+	ClientSecurityGroupId pulumi.StringInput
+}
+
+// newRdsProxy creates the proxy in the cluster's subnets, authenticating to
+// the cluster with the master credentials from a Secrets Manager secret, and
+// registers the cluster as its target.
+func (c *LabAuroraCluster) newRdsProxy(ctx *pulumi.Context, args *LabAuroraClusterArgs) error {
+	lb := args.Labels
+	proxy := args.RdsProxy
+
+	var err error
+	c.ProxySecurityGroup, err = ec2.NewSecurityGroup(ctx, lb.Name("rds-proxy-sg"), &ec2.SecurityGroupArgs{
+		VpcId:       proxy.VpcId,
+		Description: pulumi.String("Security group for the RDS Proxy"),
+		Ingress: ec2.SecurityGroupIngressArray{
+			&ec2.SecurityGroupIngressArgs{
+				Protocol:       pulumi.String("tcp"),
+				FromPort:       pulumi.Int(3306),
+				ToPort:         pulumi.Int(3306),
+				SecurityGroups: pulumi.StringArray{proxy.ClientSecurityGroupId},
+				Description:    pulumi.String("MySQL access from the EC2 security group"),
+			},
+		},
+		Egress: allOutbound(false),
+		Tags:   lb.Tags(lb.Name("rds-proxy-sg")),
+	}, childOptions(c)...)
+	if err != nil {
+		return err
+	}
+
+	_, err = ec2.NewSecurityGroupRule(ctx, lb.Name("aurora-mysql-from-rds-proxy"), &ec2.SecurityGroupRuleArgs{
+		Type:                  pulumi.String("ingress"),
+		FromPort:              pulumi.Int(3306),
+		ToPort:                pulumi.Int(3306),
+		Protocol:              pulumi.String("tcp"),
+		SourceSecurityGroupId: c.ProxySecurityGroup.ID(),
+		SecurityGroupId:       args.SecurityGroupId,
+		Description:           pulumi.String("Connections of the RDS Proxy"),
+	}, childOptions(c)...)
+	if err != nil {
+		return err
+	}
+
+	// The proxy logs in with the cluster's master user, which is the
+	// snapshot's when the cluster is restored
+	c.ProxySecret, err = secretsmanager.NewSecret(ctx, lb.Name("rds-proxy-credentials"), &secretsmanager.SecretArgs{
+		Name:        pulumi.String(lb.Name("rds-proxy-credentials")),
+		Description: pulumi.String("Aurora credentials the RDS Proxy connects with"),
+		// Lab secrets are deleted immediately so the stack can be recreated
+		RecoveryWindowInDays: pulumi.Int(0),
+		Tags:                 lb.Tags(lb.Name("rds-proxy-credentials")),
+	}, childOptions(c)...)
+	if err != nil {
+		return err
+	}
+
+	credentials := pulumi.All(c.Cluster.MasterUsername, args.MasterPassword).ApplyT(func(values []interface{}) (string, error) {
+		data, err := json.Marshal(map[string]string{
+			"username": values[0].(string),
+			"password": values[1].(string),
+		})
+		return string(data), err
+	}).(pulumi.StringOutput)
+
+	secretVersion, err := secretsmanager.NewSecretVersion(ctx, lb.Name("rds-proxy-credentials-version"), &secretsmanager.SecretVersionArgs{
+		SecretId:     c.ProxySecret.ID(),
+		SecretString: pulumi.ToSecret(credentials).(pulumi.StringOutput),
+	}, childOptions(c)...)
+	if err != nil {
+		return err
+	}
+
+	role, err := iam.NewRole(ctx, lb.Name("rds-proxy-role"), &iam.RoleArgs{
+		Name: pulumi.String(lb.Name("rds-proxy-role")),
+		AssumeRolePolicy: pulumi.String(`{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Principal": {"Service": "rds.amazonaws.com"},
+      "Action": "sts:AssumeRole"
+    }
+  ]
+}`),
+		Tags: lb.Tags(lb.Name("rds-proxy-role")),
+	}, childOptions(c)...)
+	if err != nil {
+		return err
+	}
+
+	policy, err := iam.NewRolePolicy(ctx, lb.Name("rds-proxy-policy"), &iam.RolePolicyArgs{
+		Role: role.ID(),
+		Policy: pulumi.Sprintf(`{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Action": "secretsmanager:GetSecretValue",
+      "Resource": "%s"
+    }
+  ]
+}`, c.ProxySecret.Arn),
+	}, childOptions(c)...)
+	if err != nil {
+		return err
+	}
+
+	c.Proxy, err = rds.NewProxy(ctx, lb.Name("rds-proxy"), &rds.ProxyArgs{
+		Name:         pulumi.String(lb.Name("rds-proxy")),
+		EngineFamily: pulumi.String("MYSQL"),
+		Auths: rds.ProxyAuthArray{
+			&rds.ProxyAuthArgs{
+				AuthScheme: pulumi.String("SECRETS"),
+				IamAuth:    pulumi.String("DISABLED"),
+				SecretArn:  c.ProxySecret.Arn,
+			},
+		},
+		RequireTls:          pulumi.Bool(proxy.RequireTls),
+		RoleArn:             role.Arn,
+		VpcSubnetIds:        args.SubnetIds,
+		VpcSecurityGroupIds: pulumi.StringArray{c.ProxySecurityGroup.ID()},
+		IdleClientTimeout:   pulumi.Int(1800),
+		Tags:                lb.Tags(lb.Name("rds-proxy"), labels.Role("rds-proxy")),
+	}, childOptions(c, pulumi.DependsOn([]pulumi.Resource{policy, secretVersion}))...)
+	if err != nil {
+		return err
+	}
+
+	targetGroup, err := rds.NewProxyDefaultTargetGroup(ctx, lb.Name("rds-proxy-target-group"), &rds.ProxyDefaultTargetGroupArgs{
+		DbProxyName: c.Proxy.Name,
+		ConnectionPoolConfig: &rds.ProxyDefaultTargetGroupConnectionPoolConfigArgs{
+			MaxConnectionsPercent: pulumi.Int(proxy.MaxConnectionsPercent),
+		},
+	}, childOptions(c)...)
+	if err != nil {
+		return err
+	}
+
+	// The proxy routes to the cluster's writer and readers; it needs an
+	// available instance to register the cluster
+	_, err = rds.NewProxyTarget(ctx, lb.Name("rds-proxy-target"), &rds.ProxyTargetArgs{
+		DbProxyName:         c.Proxy.Name,
+		TargetGroupName:     targetGroup.Name,
+		DbClusterIdentifier: c.Cluster.ClusterIdentifier,
+	}, childOptions(c, pulumi.DependsOn([]pulumi.Resource{c.Writer}))...)
+	return err
+}
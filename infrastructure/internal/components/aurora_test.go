@@ -0,0 +1,364 @@
+package components
+
+import (
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// testAuroraArgs returns cluster args for the mocks; change modifies them
+// before the cluster is created.
+func testAuroraArgs(change func(*LabAuroraClusterArgs)) pulumi.RunFunc {
+	return func(ctx *pulumi.Context) error {
+		args := &LabAuroraClusterArgs{
+			Labels:          testLabels,
+			SubnetIds:       pulumi.ToStringArray([]string{"subnet-1", "subnet-2"}),
+			SecurityGroupId: pulumi.String("sg-aurora"),
+			DatabaseName:    "lab_db",
+			MasterUsername:  "admin",
+			MasterPassword:  pulumi.String("password"),
+			EngineVersion:   "8.0.mysql_aurora.3.04.0",
+			InstanceClass:   "db.r6g.xlarge",
+			Parameters: ParameterSet{
+				Family:   "aurora-mysql8.0",
+				Cluster:  []Parameter{{Name: "binlog_format", Value: "ROW", ApplyMethod: "pending-reboot"}},
+				Instance: []Parameter{{Name: "max_connections", Value: "1000"}},
+			},
+		}
+		if change != nil {
+			change(args)
+		}
+		_, err := NewLabAuroraCluster(ctx, "test-aurora", args)
+		return err
+	}
+}
+
+func TestLabAuroraClusterEncryptedAndPrivate(t *testing.T) {
+	m, err := run(t, testAuroraArgs(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cluster := m.inputs(t, "test-aurora-cluster")
+	assertBool(t, cluster, "storageEncrypted", true)
+	assertBool(t, cluster, "skipFinalSnapshot", true)
+	for _, name := range []string{"test-writer-instance", "test-reader-instance"} {
+		assertBool(t, m.inputs(t, name), "publiclyAccessible", false)
+	}
+}
+
+func TestLabAuroraClusterAttachesParameterGroups(t *testing.T) {
+	m, err := run(t, testAuroraArgs(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertString(t, m.inputs(t, "test-aurora-cluster"), "dbClusterParameterGroupName", "test-aurora-cluster-pg")
+	for _, name := range []string{"test-writer-instance", "test-reader-instance"} {
+		assertString(t, m.inputs(t, name), "dbParameterGroupName", "test-aurora-instance-pg")
+	}
+
+	if m.registered("test-cluster-pg-green") {
+		t.Error("green parameter groups created without GreenParameters")
+	}
+}
+
+func TestLabAuroraClusterGreenParameterGroups(t *testing.T) {
+	m, err := run(t, testAuroraArgs(func(args *LabAuroraClusterArgs) {
+		args.GreenParameters = &ParameterSet{
+			Family:  "aurora-mysql8.4",
+			Cluster: []Parameter{{Name: "binlog_format", Value: "MIXED"}},
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	green := m.inputs(t, "test-cluster-pg-green")
+	assertString(t, green, "family", "aurora-mysql8.4")
+	parameters := green["parameters"].ArrayValue()
+	if len(parameters) != 1 {
+		t.Fatalf("expected the green binlog_format to replace the blue one, got %d parameters", len(parameters))
+	}
+	assertString(t, parameters[0].ObjectValue(), "value", "MIXED")
+
+	// The blue groups stay attached to the cluster
+	assertString(t, m.inputs(t, "test-aurora-cluster"), "dbClusterParameterGroupName", "test-aurora-cluster-pg")
+}
+
+func TestLabAuroraClusterEnhancedMonitoring(t *testing.T) {
+	m, err := run(t, testAuroraArgs(func(args *LabAuroraClusterArgs) {
+		args.MonitoringInterval = 60
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !m.registered("test-rds-monitoring-role") {
+		t.Fatal("monitoring role not created")
+	}
+	assertString(t, m.inputs(t, "test-writer-instance"), "monitoringRoleArn", "arn:aws:mock:::test-rds-monitoring-role")
+}
+
+func TestLabAuroraClusterPerformanceInsights(t *testing.T) {
+	m, err := run(t, testAuroraArgs(func(args *LabAuroraClusterArgs) {
+		args.PerformanceInsightsEnabled = true
+		args.PerformanceInsightsRetentionPeriod = 31
+		args.PerformanceInsightsKmsKeyId = "arn:aws:kms:us-east-1:123456789012:key/pi"
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"test-writer-instance", "test-reader-instance"} {
+		instance := m.inputs(t, name)
+		assertBool(t, instance, "performanceInsightsEnabled", true)
+		assertString(t, instance, "performanceInsightsKmsKeyId", "arn:aws:kms:us-east-1:123456789012:key/pi")
+		if got := instance["performanceInsightsRetentionPeriod"]; !got.IsNumber() || got.NumberValue() != 31 {
+			t.Errorf("%s performanceInsightsRetentionPeriod: got %v, want 31", name, got)
+		}
+	}
+
+	// Retention and key are omitted when Performance Insights is disabled
+	m, err = run(t, testAuroraArgs(func(args *LabAuroraClusterArgs) {
+		args.PerformanceInsightsRetentionPeriod = 7
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer := m.inputs(t, "test-writer-instance")
+	assertBool(t, writer, "performanceInsightsEnabled", false)
+	if _, ok := writer["performanceInsightsRetentionPeriod"]; ok {
+		t.Error("performanceInsightsRetentionPeriod set with Performance Insights disabled")
+	}
+}
+
+func TestLabAuroraClusterMaintenance(t *testing.T) {
+	m, err := run(t, testAuroraArgs(func(args *LabAuroraClusterArgs) {
+		args.AutoMinorVersionUpgrade = true
+		args.PreferredMaintenanceWindow = "sat:22:00-sat:23:00"
+		args.PreferredBackupWindow = "01:00-02:00"
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cluster := m.inputs(t, "test-aurora-cluster")
+	assertString(t, cluster, "preferredMaintenanceWindow", "sat:22:00-sat:23:00")
+	assertString(t, cluster, "preferredBackupWindow", "01:00-02:00")
+	for _, name := range []string{"test-writer-instance", "test-reader-instance"} {
+		assertBool(t, m.inputs(t, name), "autoMinorVersionUpgrade", true)
+		assertString(t, m.inputs(t, name), "preferredMaintenanceWindow", "sat:22:00-sat:23:00")
+	}
+}
+
+func TestLabAuroraClusterReaderAutoScaling(t *testing.T) {
+	m, err := run(t, testAuroraArgs(func(args *LabAuroraClusterArgs) {
+		args.ReaderAutoScaling = &ReaderAutoScalingArgs{MinCapacity: 1, MaxCapacity: 4, Metric: "connections", TargetValue: 200}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	target := m.inputs(t, "test-reader-scaling-target")
+	assertString(t, target, "scalableDimension", "rds:cluster:ReadReplicaCount")
+	assertString(t, target, "resourceId", "cluster:test-aurora-cluster")
+	config := m.inputs(t, "test-reader-scaling-policy")["targetTrackingScalingPolicyConfiguration"].ObjectValue()
+	metric := config["predefinedMetricSpecification"].ObjectValue()
+	assertString(t, metric, "predefinedMetricType", "RDSReaderAverageDatabaseConnections")
+	if got := config["targetValue"]; got.NumberValue() != 200 {
+		t.Errorf("targetValue: got %v, want 200", got)
+	}
+
+	_, err = run(t, testAuroraArgs(func(args *LabAuroraClusterArgs) {
+		args.ReaderAutoScaling = &ReaderAutoScalingArgs{MinCapacity: 1, MaxCapacity: 4, Metric: "memory", TargetValue: 50}
+	}))
+	if err == nil {
+		t.Error("unknown metric accepted")
+	}
+}
+
+func TestLabAuroraClusterBackupCopy(t *testing.T) {
+	m, err := run(t, testAuroraArgs(func(args *LabAuroraClusterArgs) {
+		args.BackupCopy = &BackupCopyArgs{Region: "us-west-2", Schedule: "cron(0 5 * * ? *)", RetentionDays: 7}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertString(t, m.inputs(t, "test-backup-copy-provider"), "region", "us-west-2")
+	assertString(t, m.inputs(t, "test-backup-copy-vault"), "kmsKeyArn", "arn:aws:mock:::test-backup-copy-key")
+	rule := m.inputs(t, "test-backup-plan")["rules"].ArrayValue()[0].ObjectValue()
+	assertString(t, rule, "targetVaultName", "test-backup-vault")
+	copyAction := rule["copyActions"].ArrayValue()[0].ObjectValue()
+	assertString(t, copyAction, "destinationVaultArn", "arn:aws:mock:::test-backup-copy-vault")
+	resources := m.inputs(t, "test-backup-selection")["resources"].ArrayValue()
+	if len(resources) != 1 || resources[0].StringValue() != "arn:aws:mock:::test-aurora-cluster" {
+		t.Errorf("backup selection resources: got %v, want the cluster", resources)
+	}
+}
+
+func TestLabAuroraClusterExternalReplica(t *testing.T) {
+	m, err := run(t, testAuroraArgs(func(args *LabAuroraClusterArgs) {
+		args.ExternalReplica = &ExternalReplicaArgs{
+			InstanceClass:         "db.t4g.micro",
+			EngineVersion:         "8.0",
+			VpcId:                 pulumi.String("vpc-1"),
+			ClientSecurityGroupId: pulumi.String("sg-ec2"),
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replica := m.inputs(t, "test-external-replica")
+	assertString(t, replica, "engine", "mysql")
+	assertString(t, replica, "dbSubnetGroupName", "test-aurora-subnet-group")
+	assertString(t, replica, "parameterGroupName", "test-external-replica-pg")
+	assertBool(t, replica, "publiclyAccessible", false)
+	assertBool(t, replica, "autoMinorVersionUpgrade", true)
+	assertString(t, m.inputs(t, "test-external-replica-pg"), "family", "mysql8.0")
+	rule := m.inputs(t, "test-aurora-mysql-from-external-replica")
+	assertString(t, rule, "securityGroupId", "sg-aurora")
+	assertString(t, rule, "sourceSecurityGroupId", "test-external-replica-sg-id")
+}
+
+func TestLabAuroraClusterRdsProxy(t *testing.T) {
+	m, err := run(t, testAuroraArgs(func(args *LabAuroraClusterArgs) {
+		args.RdsProxy = &RdsProxyArgs{
+			MaxConnectionsPercent: 50,
+			VpcId:                 pulumi.String("vpc-1"),
+			ClientSecurityGroupId: pulumi.String("sg-ec2"),
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proxy := m.inputs(t, "test-rds-proxy")
+	assertString(t, proxy, "engineFamily", "MYSQL")
+	assertBool(t, proxy, "requireTls", false)
+	if subnets := proxy["vpcSubnetIds"].ArrayValue(); len(subnets) != 2 {
+		t.Errorf("vpcSubnetIds: got %v, want the cluster's subnets", subnets)
+	}
+	auth := proxy["auths"].ArrayValue()[0].ObjectValue()
+	assertString(t, auth, "authScheme", "SECRETS")
+	assertString(t, auth, "secretArn", "arn:aws:mock:::test-rds-proxy-credentials")
+	target := m.inputs(t, "test-rds-proxy-target")
+	assertString(t, target, "dbProxyName", "test-rds-proxy")
+	assertString(t, target, "dbClusterIdentifier", "test-aurora-cluster")
+	pool := m.inputs(t, "test-rds-proxy-target-group")["connectionPoolConfig"].ObjectValue()
+	if got := pool["maxConnectionsPercent"].NumberValue(); got != 50 {
+		t.Errorf("maxConnectionsPercent: got %v, want 50", got)
+	}
+	rule := m.inputs(t, "test-aurora-mysql-from-rds-proxy")
+	assertString(t, rule, "securityGroupId", "sg-aurora")
+	assertString(t, rule, "sourceSecurityGroupId", "test-rds-proxy-sg-id")
+}
+
+func TestLabAuroraClusterIoOptimized(t *testing.T) {
+	m, err := run(t, testAuroraArgs(func(args *LabAuroraClusterArgs) {
+		args.StorageType = "aurora-iopt1"
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertString(t, m.inputs(t, "test-aurora-cluster"), "storageType", "aurora-iopt1")
+}
+
+func TestLabAuroraClusterActivityStream(t *testing.T) {
+	m, err := run(t, testAuroraArgs(func(args *LabAuroraClusterArgs) {
+		args.ActivityStream = true
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !m.registered("test-activity-stream-key") {
+		t.Fatal("activity stream KMS key not created")
+	}
+	stream := m.inputs(t, "test-activity-stream")
+	assertString(t, stream, "mode", "async")
+	assertString(t, stream, "kmsKeyId", "arn:aws:mock:::test-activity-stream-key")
+
+	// A given key is used as is
+	m, err = run(t, testAuroraArgs(func(args *LabAuroraClusterArgs) {
+		args.ActivityStream = true
+		args.ActivityStreamKmsKeyId = "alias/lab-das"
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.registered("test-activity-stream-key") {
+		t.Error("activity stream KMS key created although ActivityStreamKmsKeyId is set")
+	}
+	assertString(t, m.inputs(t, "test-activity-stream"), "kmsKeyId", "alias/lab-das")
+}
+
+func TestLabAuroraClusterSnapshotRestore(t *testing.T) {
+	m, err := run(t, testAuroraArgs(func(args *LabAuroraClusterArgs) {
+		args.SnapshotIdentifier = "lab-snapshot"
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cluster := m.inputs(t, "test-aurora-cluster")
+	assertString(t, cluster, "snapshotIdentifier", "lab-snapshot")
+	for _, key := range []string{"databaseName", "masterUsername"} {
+		if _, ok := cluster[resource.PropertyKey(key)]; ok {
+			t.Errorf("%s must come from the snapshot", key)
+		}
+	}
+}
+
+func TestLabAuroraClusterSecondaryRequiresGlobalDatabase(t *testing.T) {
+	_, err := run(t, testAuroraArgs(func(args *LabAuroraClusterArgs) {
+		args.Secondary = &SecondaryClusterArgs{Region: "us-west-2"}
+	}))
+	if err == nil {
+		t.Fatal("expected an error for a secondary cluster without GlobalDatabase")
+	}
+}
+
+func TestParameterSetMerge(t *testing.T) {
+	base := ParameterSet{
+		Family:  "aurora-mysql8.0",
+		Cluster: []Parameter{{Name: "a", Value: "1"}, {Name: "b", Value: "2"}},
+	}
+	merged := base.Merge(&ParameterSet{Cluster: []Parameter{{Name: "b", Value: "3"}, {Name: "c", Value: "4"}}})
+
+	if merged.Family != "aurora-mysql8.0" {
+		t.Errorf("family: got %q", merged.Family)
+	}
+	for name, want := range map[string]string{"a": "1", "b": "3", "c": "4"} {
+		if got := merged.Value(name); got != want {
+			t.Errorf("%s: got %q, want %q", name, got, want)
+		}
+	}
+	if base.Value("b") != "2" {
+		t.Error("Merge modified the base set")
+	}
+}
+
+func TestParameterSetMergeReset(t *testing.T) {
+	base := ParameterSet{
+		Cluster:  []Parameter{{Name: "a", Value: "1"}, {Name: "b", Value: "2"}},
+		Instance: []Parameter{{Name: "max_connections", Value: "1000"}},
+	}
+	merged := base.Merge(&ParameterSet{
+		Cluster:  []Parameter{{Name: "a", Reset: true}},
+		Instance: []Parameter{{Name: "max_connections", Reset: true}},
+	})
+
+	if len(merged.Cluster) != 1 || merged.Value("a") != "" || merged.Value("b") != "2" {
+		t.Errorf("cluster: got %+v, want only b", merged.Cluster)
+	}
+	if len(merged.Instance) != 0 {
+		t.Errorf("instance: got %+v, want none", merged.Instance)
+	}
+	if len(base.Cluster) != 2 || base.Value("a") != "1" {
+		t.Error("Merge modified the base set")
+	}
+}
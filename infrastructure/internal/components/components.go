@@ -0,0 +1,44 @@
+// Package components provides the lab's building blocks as Pulumi
+// ComponentResources:
+//
+//   - LabVpc: the VPC, subnets, route tables and security groups
+//   - LabAuroraCluster: the Aurora MySQL cluster, its parameter groups and
+//     optional Global Database secondary
+//   - LabSimulatorHost: the workload simulator instance or Auto Scaling Group
+//   - LabOutputParameters: a stack's key outputs in SSM Parameter Store
+//   - LabFunction: a Go Lambda function (cmd/lab-*) with its role and log group
+//   - LabRepository: an ECR repository of a lab image with its lifecycle policy
+//   - LabDmsReplication: a DMS task replicating the cluster to an S3 bucket
+//   - LabFisExperiments: AWS FIS experiment templates and the role FIS assumes
+//   - LabWorkloadIdentity: the IRSA roles of the simulator pods and the
+//     CloudWatch exporter on EKS, and the pods' password secret
+//
+// The stacks under infrastructure/ load their configuration, resolve stack
+// references and lookups, and pass typed args to these components, so the
+// components can be reused in other labs and tested with Pulumi mocks.
+package components
+
+import (
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// typePrefix is the package part of the component type tokens.
+const typePrefix = "aurora-bluegreen-lab:components:"
+
+// childOptions parents a resource to its component. The alias keeps
+// resources created before the components existed (without a parent) from
+// being replaced.
+func childOptions(parent pulumi.Resource, opts ...pulumi.ResourceOption) []pulumi.ResourceOption {
+	return append([]pulumi.ResourceOption{
+		pulumi.Parent(parent),
+		pulumi.Aliases([]pulumi.Alias{{NoParent: pulumi.Bool(true)}}),
+	}, opts...)
+}
+
+// optionalString returns nil for empty strings so unset values are omitted.
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
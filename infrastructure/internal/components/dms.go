@@ -0,0 +1,303 @@
+package components
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/dms"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ec2"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/iam"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/s3"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"aurora-bluegreen-lab/internal/labels"
+)
+
+// LabDmsReplicationArgs configures LabDmsReplication.
+type LabDmsReplicationArgs struct {
+	Labels *labels.Labels
+	// Region is the stack's region, for the S3 gateway endpoint
+	Region    string
+	VpcId     pulumi.StringInput
+	SubnetIds pulumi.StringArrayInput
+	// AuroraSecurityGroupId is the cluster's security group, which lets the
+	// replication instance in
+	AuroraSecurityGroupId pulumi.StringInput
+	// PrivateRouteTableId is the route table of SubnetIds the S3 gateway
+	// endpoint is added to; nil adds none
+	PrivateRouteTableId pulumi.StringInput
+
+	// The source endpoint connects to the cluster endpoint with the master
+	// user and replicates the tables of DatabaseName matching TablePattern
+	ClusterEndpoint pulumi.StringInput
+	DatabaseName    pulumi.StringInput
+	MasterUsername  pulumi.StringInput
+	MasterPassword  pulumi.StringInput
+	TablePattern    string
+
+	InstanceClass string
+	// MigrationType is full-load, cdc or full-load-and-cdc
+	MigrationType string
+	// CreateDmsVpcRole creates the account's dms-vpc-role
+	CreateDmsVpcRole bool
+}
+
+// LabDmsReplication is an AWS DMS task replicating the lab cluster's tables
+// and binary log to an S3 bucket, an external integration the Blue/Green
+// deployment does not switch over.
+type LabDmsReplication struct {
+	pulumi.ResourceState
+
+	SecurityGroup       *ec2.SecurityGroup
+	Bucket              *s3.BucketV2
+	S3GatewayEndpoint   *ec2.VpcEndpoint // nil without PrivateRouteTableId
+	ReplicationInstance *dms.ReplicationInstance
+	SourceEndpoint      *dms.Endpoint
+	TargetEndpoint      *dms.S3Endpoint
+	ReplicationTask     *dms.ReplicationTask
+}
+
+// NewLabDmsReplication creates the replication instance, its endpoints and
+// the task, and starts the task.
+func NewLabDmsReplication(ctx *pulumi.Context, name string, args *LabDmsReplicationArgs, opts ...pulumi.ResourceOption) (*LabDmsReplication, error) {
+	switch args.MigrationType {
+	case "full-load", "cdc", "full-load-and-cdc":
+	default:
+		return nil, fmt.Errorf("unknown DMS migration type %q", args.MigrationType)
+	}
+
+	c := &LabDmsReplication{}
+	err := ctx.RegisterComponentResource(typePrefix+"LabDmsReplication", name, c, opts...)
+	if err != nil {
+		return nil, err
+	}
+	lb := args.Labels
+
+	// DMS manages the replication instance's network interfaces with a role
+	// of this fixed name, one per account
+	var subnetGroupOptions []pulumi.ResourceOption
+	if args.CreateDmsVpcRole {
+		vpcRole, err := iam.NewRole(ctx, lb.Name("dms-vpc-role"), &iam.RoleArgs{
+			Name: pulumi.String("dms-vpc-role"),
+			AssumeRolePolicy: pulumi.String(`{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Principal": {"Service": "dms.amazonaws.com"},
+      "Action": "sts:AssumeRole"
+    }
+  ]
+}`),
+			Tags: lb.Tags("dms-vpc-role"),
+		}, childOptions(c)...)
+		if err != nil {
+			return nil, err
+		}
+		attachment, err := iam.NewRolePolicyAttachment(ctx, lb.Name("dms-vpc-role-policy"), &iam.RolePolicyAttachmentArgs{
+			Role:      vpcRole.Name,
+			PolicyArn: pulumi.String("arn:aws:iam::aws:policy/service-role/AmazonDMSVPCManagementRole"),
+		}, childOptions(c)...)
+		if err != nil {
+			return nil, err
+		}
+		subnetGroupOptions = append(subnetGroupOptions, pulumi.DependsOn([]pulumi.Resource{attachment}))
+	}
+
+	c.SecurityGroup, err = ec2.NewSecurityGroup(ctx, lb.Name("dms-sg"), &ec2.SecurityGroupArgs{
+		VpcId:       args.VpcId,
+		Description: pulumi.String("Security group for the DMS replication instance"),
+		Egress:      allOutbound(false),
+		Tags:        lb.Tags(lb.Name("dms-sg")),
+	}, childOptions(c)...)
+	if err != nil {
+		return nil, err
+	}
+	// The task reads the binary log through the cluster endpoint, whichever
+	// cluster it points at after a switchover
+	_, err = ec2.NewSecurityGroupRule(ctx, lb.Name("aurora-mysql-from-dms"), &ec2.SecurityGroupRuleArgs{
+		Type:                  pulumi.String("ingress"),
+		FromPort:              pulumi.Int(3306),
+		ToPort:                pulumi.Int(3306),
+		Protocol:              pulumi.String("tcp"),
+		SourceSecurityGroupId: c.SecurityGroup.ID(),
+		SecurityGroupId:       args.AuroraSecurityGroupId,
+		Description:           pulumi.String("MySQL access from the DMS replication instance"),
+	}, childOptions(c)...)
+	if err != nil {
+		return nil, err
+	}
+
+	bucketAccess, err := c.newTargetBucket(ctx, lb, args)
+	if err != nil {
+		return nil, err
+	}
+
+	subnetGroup, err := dms.NewReplicationSubnetGroup(ctx, lb.Name("dms-subnet-group"), &dms.ReplicationSubnetGroupArgs{
+		ReplicationSubnetGroupId:          pulumi.String(lb.Name("dms-subnet-group")),
+		ReplicationSubnetGroupDescription: pulumi.String("Subnets of the lab's DMS replication instance"),
+		SubnetIds:                         args.SubnetIds,
+		Tags:                              lb.Tags(lb.Name("dms-subnet-group")),
+	}, childOptions(c, subnetGroupOptions...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	c.ReplicationInstance, err = dms.NewReplicationInstance(ctx, lb.Name("dms-instance"), &dms.ReplicationInstanceArgs{
+		ReplicationInstanceId:    pulumi.String(lb.Name("dms-instance")),
+		ReplicationInstanceClass: pulumi.String(args.InstanceClass),
+		AllocatedStorage:         pulumi.Int(20),
+		ReplicationSubnetGroupId: subnetGroup.ReplicationSubnetGroupId,
+		VpcSecurityGroupIds:      pulumi.StringArray{c.SecurityGroup.ID()},
+		PubliclyAccessible:       pulumi.Bool(false),
+		MultiAz:                  pulumi.Bool(false),
+		ApplyImmediately:         pulumi.Bool(true),
+		Tags:                     lb.Tags(lb.Name("dms-instance"), labels.Role("dms")),
+	}, childOptions(c)...)
+	if err != nil {
+		return nil, err
+	}
+
+	c.SourceEndpoint, err = dms.NewEndpoint(ctx, lb.Name("dms-source"), &dms.EndpointArgs{
+		EndpointId:   pulumi.String(lb.Name("dms-source")),
+		EndpointType: pulumi.String("source"),
+		EngineName:   pulumi.String("aurora"),
+		ServerName:   args.ClusterEndpoint,
+		Port:         pulumi.Int(3306),
+		Username:     args.MasterUsername,
+		Password:     args.MasterPassword,
+		Tags:         lb.Tags(lb.Name("dms-source")),
+	}, childOptions(c)...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parquet files with the operation (I, U or D) of each row, full load
+	// included, and the time of the change
+	c.TargetEndpoint, err = dms.NewS3Endpoint(ctx, lb.Name("dms-target"), &dms.S3EndpointArgs{
+		EndpointId:           pulumi.String(lb.Name("dms-target")),
+		EndpointType:         pulumi.String("target"),
+		BucketName:           c.Bucket.Bucket,
+		ServiceAccessRoleArn: bucketAccess.Role,
+		DataFormat:           pulumi.String("parquet"),
+		IncludeOpForFullLoad: pulumi.Bool(true),
+		TimestampColumnName:  pulumi.String("dms_timestamp"),
+		Tags:                 lb.Tags(lb.Name("dms-target")),
+	}, childOptions(c, pulumi.DependsOn([]pulumi.Resource{bucketAccess}))...)
+	if err != nil {
+		return nil, err
+	}
+
+	tableMappings := args.DatabaseName.ToStringOutput().ApplyT(func(database string) (string, error) {
+		mappings, err := json.Marshal(map[string]any{
+			"rules": []map[string]any{{
+				"rule-type": "selection",
+				"rule-id":   "1",
+				"rule-name": "lab-tables",
+				"object-locator": map[string]string{
+					"schema-name": database,
+					"table-name":  args.TablePattern,
+				},
+				"rule-action": "include",
+			}},
+		})
+		return string(mappings), err
+	}).(pulumi.StringOutput)
+
+	c.ReplicationTask, err = dms.NewReplicationTask(ctx, lb.Name("dms-task"), &dms.ReplicationTaskArgs{
+		ReplicationTaskId:      pulumi.String(lb.Name("dms-task")),
+		MigrationType:          pulumi.String(args.MigrationType),
+		ReplicationInstanceArn: c.ReplicationInstance.ReplicationInstanceArn,
+		SourceEndpointArn:      c.SourceEndpoint.EndpointArn,
+		TargetEndpointArn:      c.TargetEndpoint.EndpointArn,
+		TableMappings:          tableMappings,
+		StartReplicationTask:   pulumi.Bool(true),
+		Tags:                   lb.Tags(lb.Name("dms-task")),
+	}, childOptions(c)...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ctx.RegisterResourceOutputs(c, pulumi.Map{})
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// newTargetBucket creates the private target bucket, the S3 gateway endpoint
+// the replication instance reaches it through, and the role DMS writes to it
+// with; it returns the role's policy, which the target endpoint needs.
+func (c *LabDmsReplication) newTargetBucket(ctx *pulumi.Context, lb *labels.Labels, args *LabDmsReplicationArgs) (*iam.RolePolicy, error) {
+	var err error
+	c.Bucket, err = s3.NewBucketV2(ctx, lb.Name("dms-target"), &s3.BucketV2Args{
+		BucketPrefix: pulumi.String(lb.Name("dms-target-")),
+		// Replicated lab data is deleted with the stack
+		ForceDestroy: pulumi.Bool(true),
+		Tags:         lb.Tags(lb.Name("dms-target")),
+	}, childOptions(c)...)
+	if err != nil {
+		return nil, err
+	}
+	_, err = s3.NewBucketPublicAccessBlock(ctx, lb.Name("dms-target-public-access-block"), &s3.BucketPublicAccessBlockArgs{
+		Bucket:                c.Bucket.ID(),
+		BlockPublicAcls:       pulumi.Bool(true),
+		BlockPublicPolicy:     pulumi.Bool(true),
+		IgnorePublicAcls:      pulumi.Bool(true),
+		RestrictPublicBuckets: pulumi.Bool(true),
+	}, childOptions(c)...)
+	if err != nil {
+		return nil, err
+	}
+
+	// The Aurora subnets have no route to the internet
+	if args.PrivateRouteTableId != nil {
+		c.S3GatewayEndpoint, err = ec2.NewVpcEndpoint(ctx, lb.Name("s3-gateway-endpoint"), &ec2.VpcEndpointArgs{
+			VpcId:           args.VpcId,
+			ServiceName:     pulumi.String(fmt.Sprintf("com.amazonaws.%s.s3", args.Region)),
+			VpcEndpointType: pulumi.String("Gateway"),
+			RouteTableIds:   pulumi.StringArray{args.PrivateRouteTableId},
+			Tags:            lb.Tags(lb.Name("s3-gateway-endpoint")),
+		}, childOptions(c)...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	role, err := iam.NewRole(ctx, lb.Name("dms-s3-role"), &iam.RoleArgs{
+		Name: pulumi.String(lb.Name("dms-s3-role")),
+		AssumeRolePolicy: pulumi.String(`{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Principal": {"Service": "dms.amazonaws.com"},
+      "Action": "sts:AssumeRole"
+    }
+  ]
+}`),
+		Tags: lb.Tags(lb.Name("dms-s3-role")),
+	}, childOptions(c)...)
+	if err != nil {
+		return nil, err
+	}
+	return iam.NewRolePolicy(ctx, lb.Name("dms-s3-policy"), &iam.RolePolicyArgs{
+		Role: role.Name,
+		Policy: pulumi.Sprintf(`{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Action": ["s3:PutObject", "s3:DeleteObject", "s3:PutObjectTagging"],
+      "Resource": "%s/*"
+    },
+    {
+      "Effect": "Allow",
+      "Action": "s3:ListBucket",
+      "Resource": %q
+    }
+  ]
+}`, c.Bucket.Arn, c.Bucket.Arn),
+	}, childOptions(c)...)
+}
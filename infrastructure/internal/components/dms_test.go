@@ -0,0 +1,98 @@
+package components
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+func testDmsArgs() *LabDmsReplicationArgs {
+	return &LabDmsReplicationArgs{
+		Labels:                testLabels,
+		Region:                "us-east-1",
+		VpcId:                 pulumi.String("vpc-123"),
+		SubnetIds:             pulumi.StringArray{pulumi.String("subnet-1"), pulumi.String("subnet-2")},
+		AuroraSecurityGroupId: pulumi.String("sg-aurora"),
+		PrivateRouteTableId:   pulumi.String("rtb-private"),
+		ClusterEndpoint:       pulumi.String("lab.cluster-xyz.us-east-1.rds.amazonaws.com"),
+		DatabaseName:          pulumi.String("labdb"),
+		MasterUsername:        pulumi.String("admin"),
+		MasterPassword:        pulumi.String("secret"),
+		TablePattern:          "orders%",
+		InstanceClass:         "dms.t3.micro",
+		MigrationType:         "full-load-and-cdc",
+		CreateDmsVpcRole:      true,
+	}
+}
+
+func TestLabDmsReplication(t *testing.T) {
+	m, err := run(t, func(ctx *pulumi.Context) error {
+		_, err := NewLabDmsReplication(ctx, "test-dms", testDmsArgs())
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertString(t, m.inputs(t, "test-dms-vpc-role"), "name", "dms-vpc-role")
+	rule := m.inputs(t, "test-aurora-mysql-from-dms")
+	assertString(t, rule, "securityGroupId", "sg-aurora")
+	assertString(t, rule, "sourceSecurityGroupId", "test-dms-sg-id")
+	endpoint := m.inputs(t, "test-s3-gateway-endpoint")
+	assertString(t, endpoint, "serviceName", "com.amazonaws.us-east-1.s3")
+	assertString(t, endpoint, "vpcEndpointType", "Gateway")
+
+	instance := m.inputs(t, "test-dms-instance")
+	assertString(t, instance, "replicationInstanceClass", "dms.t3.micro")
+	assertBool(t, instance, "publiclyAccessible", false)
+	source := m.inputs(t, "test-dms-source")
+	assertString(t, source, "engineName", "aurora")
+	assertString(t, source, "serverName", "lab.cluster-xyz.us-east-1.rds.amazonaws.com")
+
+	task := m.inputs(t, "test-dms-task")
+	assertString(t, task, "migrationType", "full-load-and-cdc")
+	assertBool(t, task, "startReplicationTask", true)
+	var mappings struct {
+		Rules []struct {
+			ObjectLocator struct {
+				SchemaName string `json:"schema-name"`
+				TableName  string `json:"table-name"`
+			} `json:"object-locator"`
+			RuleAction string `json:"rule-action"`
+		} `json:"rules"`
+	}
+	if err := json.Unmarshal([]byte(task["tableMappings"].StringValue()), &mappings); err != nil {
+		t.Fatal(err)
+	}
+	if len(mappings.Rules) != 1 || mappings.Rules[0].ObjectLocator.SchemaName != "labdb" ||
+		mappings.Rules[0].ObjectLocator.TableName != "orders%" || mappings.Rules[0].RuleAction != "include" {
+		t.Errorf("table mappings: got %+v", mappings.Rules)
+	}
+}
+
+func TestLabDmsReplicationWithoutAccountResources(t *testing.T) {
+	args := testDmsArgs()
+	args.PrivateRouteTableId = nil
+	args.CreateDmsVpcRole = false
+	m, err := run(t, func(ctx *pulumi.Context) error {
+		_, err := NewLabDmsReplication(ctx, "test-dms", args)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"test-dms-vpc-role", "test-s3-gateway-endpoint"} {
+		if m.registered(name) {
+			t.Errorf("%s registered", name)
+		}
+	}
+
+	args.MigrationType = "incremental"
+	if _, err := run(t, func(ctx *pulumi.Context) error {
+		_, err := NewLabDmsReplication(ctx, "test-dms", args)
+		return err
+	}); err == nil {
+		t.Error("expected an error for an unknown migration type")
+	}
+}
@@ -0,0 +1,188 @@
+package components
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/fis"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/iam"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"aurora-bluegreen-lab/internal/labels"
+)
+
+// LabFisExperimentsArgs configures LabFisExperiments.
+type LabFisExperimentsArgs struct {
+	Labels *labels.Labels
+	// Region is the stack's region, for the ARN of the SSM document
+	Region string
+	// WriterInstanceArn is the writer instance the reboot template reboots;
+	// the ARN names the instance by identifier, so after a switchover it is
+	// the green writer that took over the identifier
+	WriterInstanceArn pulumi.StringInput
+	// NetworkDisruptionMinutes is how long the network template blocks
+	// NetworkDisruptionPort on the simulator instances
+	NetworkDisruptionMinutes int
+	NetworkDisruptionPort    int
+}
+
+// LabFisExperiments is the lab's AWS FIS experiment templates with the role
+// FIS assumes to run them: a reboot of the writer, and the network
+// disruption of the simulator hosts' database connections.
+type LabFisExperiments struct {
+	pulumi.ResourceState
+
+	Role                 *iam.Role
+	RebootWriter         *fis.ExperimentTemplate
+	SimulatorNetwork     *fis.ExperimentTemplate
+	RebootWriterName     string
+	SimulatorNetworkName string
+}
+
+// fisPolicies are the managed policies of the FIS role; the network access
+// also lets bgctl chaos az-impairment use the role.
+var fisPolicies = map[string]string{
+	"rds":     "arn:aws:iam::aws:policy/service-role/AWSFaultInjectionSimulatorRDSAccess",
+	"ssm":     "arn:aws:iam::aws:policy/service-role/AWSFaultInjectionSimulatorSSMAccess",
+	"network": "arn:aws:iam::aws:policy/service-role/AWSFaultInjectionSimulatorNetworkAccess",
+}
+
+// NewLabFisExperiments creates the FIS role and the experiment templates.
+// Templates have no name in FIS; their Name tag is the name bgctl chaos
+// experiment and lab-scenario start them by.
+func NewLabFisExperiments(ctx *pulumi.Context, name string, args *LabFisExperimentsArgs, opts ...pulumi.ResourceOption) (*LabFisExperiments, error) {
+	if args.NetworkDisruptionMinutes < 1 || args.NetworkDisruptionMinutes > 720 {
+		return nil, fmt.Errorf("network disruption must last 1 to 720 minutes, got %d", args.NetworkDisruptionMinutes)
+	}
+
+	c := &LabFisExperiments{
+		RebootWriterName:     args.Labels.Name("reboot-writer"),
+		SimulatorNetworkName: args.Labels.Name("simulator-network-disruption"),
+	}
+	err := ctx.RegisterComponentResource(typePrefix+"LabFisExperiments", name, c, opts...)
+	if err != nil {
+		return nil, err
+	}
+	lb := args.Labels
+
+	c.Role, err = iam.NewRole(ctx, lb.Name("fis-role"), &iam.RoleArgs{
+		Name: pulumi.String(lb.Name("fis-role")),
+		AssumeRolePolicy: pulumi.String(`{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Principal": {"Service": "fis.amazonaws.com"},
+      "Action": "sts:AssumeRole"
+    }
+  ]
+}`),
+		Tags: lb.Tags(lb.Name("fis-role")),
+	}, childOptions(c)...)
+	if err != nil {
+		return nil, err
+	}
+	var attachments []pulumi.Resource
+	for _, access := range []string{"rds", "ssm", "network"} {
+		attachment, err := iam.NewRolePolicyAttachment(ctx, lb.Name("fis-role-"+access), &iam.RolePolicyAttachmentArgs{
+			Role:      c.Role.Name,
+			PolicyArn: pulumi.String(fisPolicies[access]),
+		}, childOptions(c)...)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+	templateOptions := append(childOptions(c), pulumi.DependsOn(attachments))
+
+	// Aurora restarts a rebooted writer in place, without a failover
+	c.RebootWriter, err = fis.NewExperimentTemplate(ctx, lb.Name("fis-reboot-writer"), &fis.ExperimentTemplateArgs{
+		Description: pulumi.String("Reboot the Aurora lab cluster's writer"),
+		RoleArn:     c.Role.Arn,
+		StopConditions: fis.ExperimentTemplateStopConditionArray{
+			fis.ExperimentTemplateStopConditionArgs{Source: pulumi.String("none")},
+		},
+		Actions: fis.ExperimentTemplateActionArray{
+			fis.ExperimentTemplateActionArgs{
+				Name:     pulumi.String("reboot-writer"),
+				ActionId: pulumi.String("aws:rds:reboot-db-instances"),
+				Parameters: fis.ExperimentTemplateActionParameterArray{
+					fis.ExperimentTemplateActionParameterArgs{Key: pulumi.String("forceFailover"), Value: pulumi.String("false")},
+				},
+				Target: fis.ExperimentTemplateActionTargetArgs{Key: pulumi.String("DBInstances"), Value: pulumi.String("writer")},
+			},
+		},
+		Targets: fis.ExperimentTemplateTargetArray{
+			fis.ExperimentTemplateTargetArgs{
+				Name:          pulumi.String("writer"),
+				ResourceType:  pulumi.String("aws:rds:db"),
+				ResourceArns:  pulumi.StringArray{args.WriterInstanceArn},
+				SelectionMode: pulumi.String("ALL"),
+			},
+		},
+		Tags: lb.Tags(c.RebootWriterName),
+	}, templateOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	// The simulator instances, a single host or the Auto Scaling Group's,
+	// are found by their role tag within the lab's project
+	document, err := json.Marshal(map[string]string{
+		"Protocol":            "tcp",
+		"Port":                fmt.Sprint(args.NetworkDisruptionPort),
+		"TrafficType":         "egress",
+		"DurationSeconds":     fmt.Sprint(args.NetworkDisruptionMinutes * 60),
+		"InstallDependencies": "True",
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.SimulatorNetwork, err = fis.NewExperimentTemplate(ctx, lb.Name("fis-simulator-network"), &fis.ExperimentTemplateArgs{
+		Description: pulumi.Sprintf("Block port %d from the workload simulator hosts for %d minutes", args.NetworkDisruptionPort, args.NetworkDisruptionMinutes),
+		RoleArn:     c.Role.Arn,
+		StopConditions: fis.ExperimentTemplateStopConditionArray{
+			fis.ExperimentTemplateStopConditionArgs{Source: pulumi.String("none")},
+		},
+		Actions: fis.ExperimentTemplateActionArray{
+			fis.ExperimentTemplateActionArgs{
+				Name:     pulumi.String("blackhole-port"),
+				ActionId: pulumi.String("aws:ssm:send-command"),
+				Parameters: fis.ExperimentTemplateActionParameterArray{
+					fis.ExperimentTemplateActionParameterArgs{
+						Key:   pulumi.String("documentArn"),
+						Value: pulumi.String("arn:aws:ssm:" + args.Region + "::document/AWSFIS-Run-Network-Blackhole-Port"),
+					},
+					fis.ExperimentTemplateActionParameterArgs{Key: pulumi.String("documentParameters"), Value: pulumi.String(string(document))},
+					fis.ExperimentTemplateActionParameterArgs{Key: pulumi.String("duration"), Value: pulumi.Sprintf("PT%dM", args.NetworkDisruptionMinutes)},
+				},
+				Target: fis.ExperimentTemplateActionTargetArgs{Key: pulumi.String("Instances"), Value: pulumi.String("simulators")},
+			},
+		},
+		Targets: fis.ExperimentTemplateTargetArray{
+			fis.ExperimentTemplateTargetArgs{
+				Name:         pulumi.String("simulators"),
+				ResourceType: pulumi.String("aws:ec2:instance"),
+				ResourceTags: fis.ExperimentTemplateTargetResourceTagArray{
+					fis.ExperimentTemplateTargetResourceTagArgs{Key: pulumi.String("Project"), Value: pulumi.String(lb.ProjectName)},
+					fis.ExperimentTemplateTargetResourceTagArgs{Key: pulumi.String("Role"), Value: pulumi.String("workload-simulator")},
+				},
+				Filters: fis.ExperimentTemplateTargetFilterArray{
+					fis.ExperimentTemplateTargetFilterArgs{Path: pulumi.String("State.Name"), Values: pulumi.StringArray{pulumi.String("running")}},
+				},
+				SelectionMode: pulumi.String("ALL"),
+			},
+		},
+		Tags: lb.Tags(c.SimulatorNetworkName),
+	}, templateOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ctx.RegisterResourceOutputs(c, pulumi.Map{})
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
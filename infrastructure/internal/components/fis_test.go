@@ -0,0 +1,90 @@
+package components
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+func testFisArgs() *LabFisExperimentsArgs {
+	return &LabFisExperimentsArgs{
+		Labels:                   testLabels,
+		Region:                   "us-east-1",
+		WriterInstanceArn:        pulumi.String("arn:aws:rds:us-east-1:123456789012:db:test-writer-instance"),
+		NetworkDisruptionMinutes: 3,
+		NetworkDisruptionPort:    3306,
+	}
+}
+
+// actionParameters returns the key/value parameters of a template's action.
+func actionParameters(t *testing.T, template resource.PropertyMap) map[string]string {
+	t.Helper()
+	actions := template["actions"].ArrayValue()
+	if len(actions) != 1 {
+		t.Fatalf("got %d actions, want 1", len(actions))
+	}
+	parameters := map[string]string{}
+	for _, p := range actions[0].ObjectValue()["parameters"].ArrayValue() {
+		parameters[p.ObjectValue()["key"].StringValue()] = p.ObjectValue()["value"].StringValue()
+	}
+	return parameters
+}
+
+func TestLabFisExperiments(t *testing.T) {
+	m, err := run(t, func(ctx *pulumi.Context) error {
+		_, err := NewLabFisExperiments(ctx, "test-fis", testFisArgs())
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertString(t, m.inputs(t, "test-fis-role"), "name", "test-fis-role")
+	for _, access := range []string{"rds", "ssm", "network"} {
+		assertString(t, m.inputs(t, "test-fis-role-"+access), "policyArn", fisPolicies[access])
+	}
+
+	reboot := m.inputs(t, "test-fis-reboot-writer")
+	if got := reboot["tags"].ObjectValue()["Name"].StringValue(); got != "test-reboot-writer" {
+		t.Errorf("reboot template name: got %s", got)
+	}
+	target := reboot["targets"].ArrayValue()[0].ObjectValue()
+	assertString(t, target, "resourceType", "aws:rds:db")
+	if arns := target["resourceArns"].ArrayValue(); len(arns) != 1 || arns[0].StringValue() != "arn:aws:rds:us-east-1:123456789012:db:test-writer-instance" {
+		t.Errorf("reboot target: got %v", arns)
+	}
+	if got := actionParameters(t, reboot)["forceFailover"]; got != "false" {
+		t.Errorf("forceFailover: got %q", got)
+	}
+
+	network := m.inputs(t, "test-fis-simulator-network")
+	parameters := actionParameters(t, network)
+	if got := parameters["duration"]; got != "PT3M" {
+		t.Errorf("duration: got %q", got)
+	}
+	var document map[string]string
+	if err := json.Unmarshal([]byte(parameters["documentParameters"]), &document); err != nil {
+		t.Fatal(err)
+	}
+	if document["Port"] != "3306" || document["DurationSeconds"] != "180" || document["TrafficType"] != "egress" {
+		t.Errorf("document parameters: got %v", document)
+	}
+	tags := map[string]string{}
+	for _, tag := range network["targets"].ArrayValue()[0].ObjectValue()["resourceTags"].ArrayValue() {
+		tags[tag.ObjectValue()["key"].StringValue()] = tag.ObjectValue()["value"].StringValue()
+	}
+	if tags["Project"] != "test" || tags["Role"] != "workload-simulator" {
+		t.Errorf("simulator target tags: got %v", tags)
+	}
+
+	args := testFisArgs()
+	args.NetworkDisruptionMinutes = 0
+	if _, err := run(t, func(ctx *pulumi.Context) error {
+		_, err := NewLabFisExperiments(ctx, "test-fis", args)
+		return err
+	}); err == nil {
+		t.Error("expected an error for a disruption of 0 minutes")
+	}
+}
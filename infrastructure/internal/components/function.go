@@ -0,0 +1,122 @@
+package components
+
+import (
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/cloudwatch"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/iam"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/lambda"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"aurora-bluegreen-lab/internal/labels"
+)
+
+// LabFunctionArgs configures LabFunction.
+type LabFunctionArgs struct {
+	Labels *labels.Labels
+	// Name is the function name suffix: {projectName}-{Name}
+	Name        string
+	Description string
+	// Bootstrap is the path of the function's executable for the
+	// provided.al2023 runtime on arm64 (see golambda.Build)
+	Bootstrap string
+	// Policy is the IAM policy document of what the function may do
+	Policy      pulumi.StringInput
+	Environment pulumi.StringMap
+	// Timeout is in seconds
+	Timeout          int
+	LogRetentionDays int
+}
+
+// LabFunction is one of the lab's Go Lambda functions (cmd/lab-*) with its
+// execution role and log group.
+type LabFunction struct {
+	pulumi.ResourceState
+
+	Function *lambda.Function
+	Role     *iam.Role
+	LogGroup *cloudwatch.LogGroup
+}
+
+// NewLabFunction creates the function, an execution role with args.Policy
+// and a log group managed by the stack, so its retention is set and it is
+// removed with the stack.
+func NewLabFunction(ctx *pulumi.Context, name string, args *LabFunctionArgs, opts ...pulumi.ResourceOption) (*LabFunction, error) {
+	c := &LabFunction{}
+	err := ctx.RegisterComponentResource(typePrefix+"LabFunction", name, c, opts...)
+	if err != nil {
+		return nil, err
+	}
+	lb := args.Labels
+	functionName := lb.Name(args.Name)
+
+	c.Role, err = iam.NewRole(ctx, lb.Name(args.Name+"-role"), &iam.RoleArgs{
+		Name: pulumi.String(lb.Name(args.Name + "-role")),
+		AssumeRolePolicy: pulumi.String(`{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Principal": {"Service": "lambda.amazonaws.com"},
+      "Action": "sts:AssumeRole"
+    }
+  ]
+}`),
+		Tags: lb.Tags(lb.Name(args.Name + "-role")),
+	}, childOptions(c)...)
+	if err != nil {
+		return nil, err
+	}
+	_, err = iam.NewRolePolicyAttachment(ctx, lb.Name(args.Name+"-role-logs"), &iam.RolePolicyAttachmentArgs{
+		Role:      c.Role.Name,
+		PolicyArn: pulumi.String("arn:aws:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole"),
+	}, childOptions(c)...)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := iam.NewRolePolicy(ctx, lb.Name(args.Name+"-policy"), &iam.RolePolicyArgs{
+		Role:   c.Role.ID(),
+		Policy: args.Policy,
+	}, childOptions(c)...)
+	if err != nil {
+		return nil, err
+	}
+
+	c.LogGroup, err = cloudwatch.NewLogGroup(ctx, lb.Name(args.Name+"-logs"), &cloudwatch.LogGroupArgs{
+		Name:            pulumi.String("/aws/lambda/" + functionName),
+		RetentionInDays: pulumi.Int(args.LogRetentionDays),
+		Tags:            lb.Tags(lb.Name(args.Name + "-logs")),
+	}, childOptions(c)...)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Function, err = lambda.NewFunction(ctx, functionName, &lambda.FunctionArgs{
+		Name:          pulumi.String(functionName),
+		Description:   pulumi.String(args.Description),
+		Runtime:       pulumi.String("provided.al2023"),
+		Architectures: pulumi.StringArray{pulumi.String("arm64")},
+		Handler:       pulumi.String("bootstrap"),
+		Code: pulumi.NewAssetArchive(map[string]interface{}{
+			"bootstrap": pulumi.NewFileAsset(args.Bootstrap),
+		}),
+		Role:       c.Role.Arn,
+		Timeout:    pulumi.Int(args.Timeout),
+		MemorySize: pulumi.Int(128),
+		Environment: &lambda.FunctionEnvironmentArgs{
+			Variables: args.Environment,
+		},
+		Tags: lb.Tags(functionName),
+	}, childOptions(c, pulumi.DependsOn([]pulumi.Resource{c.LogGroup, policy}))...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ctx.RegisterResourceOutputs(c, pulumi.Map{
+		"functionName": c.Function.Name,
+		"functionArn":  c.Function.Arn,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
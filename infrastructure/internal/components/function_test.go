@@ -0,0 +1,47 @@
+package components
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+func TestLabFunction(t *testing.T) {
+	bootstrap := filepath.Join(t.TempDir(), "bootstrap")
+	if err := os.WriteFile(bootstrap, []byte("binary"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := run(t, func(ctx *pulumi.Context) error {
+		_, err := NewLabFunction(ctx, "test-snapshots-function", &LabFunctionArgs{
+			Labels:           testLabels,
+			Name:             "snapshots",
+			Description:      "Snapshots the lab cluster",
+			Bootstrap:        bootstrap,
+			Policy:           pulumi.String(`{"Version": "2012-10-17", "Statement": []}`),
+			Environment:      pulumi.StringMap{"CLUSTER_IDENTIFIER": pulumi.String("test-cluster")},
+			Timeout:          60,
+			LogRetentionDays: 14,
+		})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	function := m.inputs(t, "test-snapshots")
+	assertString(t, function, "name", "test-snapshots")
+	assertString(t, function, "runtime", "provided.al2023")
+	assertString(t, function, "handler", "bootstrap")
+	assertString(t, function, "role", "arn:aws:mock:::test-snapshots-role")
+	if got := function["environment"].ObjectValue()["variables"].ObjectValue()["CLUSTER_IDENTIFIER"].StringValue(); got != "test-cluster" {
+		t.Errorf("CLUSTER_IDENTIFIER = %q, want test-cluster", got)
+	}
+
+	assertString(t, m.inputs(t, "test-snapshots-logs"), "name", "/aws/lambda/test-snapshots")
+	assertString(t, m.inputs(t, "test-snapshots-role-logs"), "policyArn",
+		"arn:aws:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole")
+	assertString(t, m.inputs(t, "test-snapshots-policy"), "role", "test-snapshots-role-id")
+}
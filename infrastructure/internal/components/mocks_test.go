@@ -0,0 +1,104 @@
+package components
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"aurora-bluegreen-lab/internal/labels"
+)
+
+// mocks records every registered resource so tests can assert on the inputs
+// the components pass to the AWS provider without deploying anything.
+type mocks struct {
+	mu        sync.Mutex
+	resources map[string]pulumi.MockResourceArgs
+}
+
+func (m *mocks) NewResource(args pulumi.MockResourceArgs) (string, resource.PropertyMap, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.resources == nil {
+		m.resources = map[string]pulumi.MockResourceArgs{}
+	}
+	m.resources[args.Name] = args
+
+	outputs := args.Inputs.Copy()
+	outputs["arn"] = resource.NewStringProperty("arn:aws:mock:::" + args.Name)
+	if args.TypeToken == "aws:ec2/vpc:Vpc" && args.Inputs["assignGeneratedIpv6CidrBlock"].IsBool() &&
+		args.Inputs["assignGeneratedIpv6CidrBlock"].BoolValue() {
+		outputs["ipv6CidrBlock"] = resource.NewStringProperty("2600:1f18:abcd:ef00::/56")
+	}
+	// Resources read with Get keep the ID they were read with
+	id := args.Name + "-id"
+	if args.ID != "" {
+		id = args.ID
+	}
+	return id, outputs, nil
+}
+
+func (m *mocks) Call(args pulumi.MockCallArgs) (resource.PropertyMap, error) {
+	switch args.Token {
+	case "aws:kms/getAlias:getAlias":
+		return resource.PropertyMap{
+			"targetKeyArn": resource.NewStringProperty("arn:aws:kms:us-west-2:123456789012:key/mock"),
+		}, nil
+	}
+	return args.Args, nil
+}
+
+// inputs returns the inputs of the named resource, failing the test when it
+// was not registered.
+func (m *mocks) inputs(t *testing.T, name string) resource.PropertyMap {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	args, ok := m.resources[name]
+	if !ok {
+		t.Fatalf("resource %s was not registered", name)
+	}
+	return args.Inputs
+}
+
+// registered reports whether the named resource was registered.
+func (m *mocks) registered(name string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.resources[name]
+	return ok
+}
+
+// run runs program against a fresh set of mocks.
+func run(t *testing.T, program pulumi.RunFunc) (*mocks, error) {
+	t.Helper()
+	m := &mocks{}
+	err := pulumi.RunErr(program, pulumi.WithMocks("aurora-bluegreen-lab", "test", m))
+	return m, err
+}
+
+// testLabels names every resource "test-{suffix}".
+var testLabels = &labels.Labels{ProjectName: "test", Environment: "dev", Owner: "jane"}
+
+func assertString(t *testing.T, props resource.PropertyMap, key, want string) {
+	t.Helper()
+	got, ok := props[resource.PropertyKey(key)]
+	if !ok || !got.IsString() {
+		t.Fatalf("%s: got %v, want %q", key, got, want)
+	}
+	if got.StringValue() != want {
+		t.Errorf("%s: got %q, want %q", key, got.StringValue(), want)
+	}
+}
+
+func assertBool(t *testing.T, props resource.PropertyMap, key string, want bool) {
+	t.Helper()
+	got, ok := props[resource.PropertyKey(key)]
+	if !ok || !got.IsBool() {
+		t.Fatalf("%s: got %v, want %v", key, got, want)
+	}
+	if got.BoolValue() != want {
+		t.Errorf("%s: got %v, want %v", key, got.BoolValue(), want)
+	}
+}
@@ -0,0 +1,70 @@
+package components
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ssm"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"aurora-bluegreen-lab/internal/labels"
+)
+
+// LabOutputParametersArgs configures LabOutputParameters.
+type LabOutputParametersArgs struct {
+	Labels *labels.Labels
+	// Stack is the lab stack publishing its outputs: vpc, aurora, ec2, monitoring, ops, scheduler, dms, fis, budget, access or registry
+	Stack string
+	// Values are the outputs to publish by output name
+	Values map[string]pulumi.StringInput
+}
+
+// LabOutputParameters publishes a stack's key outputs to SSM Parameter Store
+// as /{projectName}/{stack}/{output name}, so instances, EKS workloads and
+// bgctl can discover the lab at runtime without access to the Pulumi state.
+type LabOutputParameters struct {
+	pulumi.ResourceState
+
+	// Prefix is the parameter path of the stack, ending in a slash
+	Prefix     string
+	Parameters map[string]*ssm.Parameter
+}
+
+// NewLabOutputParameters creates one String parameter per value.
+func NewLabOutputParameters(ctx *pulumi.Context, name string, args *LabOutputParametersArgs, opts ...pulumi.ResourceOption) (*LabOutputParameters, error) {
+	c := &LabOutputParameters{
+		Prefix:     fmt.Sprintf("/%s/%s/", args.Labels.ProjectName, args.Stack),
+		Parameters: map[string]*ssm.Parameter{},
+	}
+	err := ctx.RegisterComponentResource(typePrefix+"LabOutputParameters", name, c, opts...)
+	if err != nil {
+		return nil, err
+	}
+	lb := args.Labels
+
+	outputNames := make([]string, 0, len(args.Values))
+	for outputName := range args.Values {
+		outputNames = append(outputNames, outputName)
+	}
+	sort.Strings(outputNames)
+	for _, outputName := range outputNames {
+		resourceName := lb.Name(fmt.Sprintf("%s-output-%s", args.Stack, outputName))
+		c.Parameters[outputName], err = ssm.NewParameter(ctx, resourceName, &ssm.ParameterArgs{
+			Name:        pulumi.String(c.Prefix + outputName),
+			Type:        pulumi.String("String"),
+			Value:       args.Values[outputName],
+			Description: pulumi.String(fmt.Sprintf("%s output of the %s stack", outputName, args.Stack)),
+			Tags:        lb.Tags(resourceName),
+		}, childOptions(c)...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = ctx.RegisterResourceOutputs(c, pulumi.Map{})
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
@@ -0,0 +1,35 @@
+package components
+
+import (
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+func TestLabOutputParameters(t *testing.T) {
+	m, err := run(t, func(ctx *pulumi.Context) error {
+		c, err := NewLabOutputParameters(ctx, "test-aurora-outputs", &LabOutputParametersArgs{
+			Labels: testLabels,
+			Stack:  "aurora",
+			Values: map[string]pulumi.StringInput{
+				"clusterEndpoint": pulumi.String("lab.cluster-abc.us-east-1.rds.amazonaws.com"),
+				"clusterPort":     pulumi.String("3306"),
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if c.Prefix != "/test/aurora/" {
+			t.Errorf("Prefix = %q, want /test/aurora/", c.Prefix)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	endpoint := m.inputs(t, "test-aurora-output-clusterEndpoint")
+	assertString(t, endpoint, "name", "/test/aurora/clusterEndpoint")
+	assertString(t, endpoint, "type", "String")
+	assertString(t, m.inputs(t, "test-aurora-output-clusterPort"), "name", "/test/aurora/clusterPort")
+}
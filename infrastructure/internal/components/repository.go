@@ -0,0 +1,91 @@
+package components
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ecr"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"aurora-bluegreen-lab/internal/labels"
+)
+
+// LabRepositoryArgs configures LabRepository.
+type LabRepositoryArgs struct {
+	Labels *labels.Labels
+	// Name is the image name, e.g. workload-simulator; the repository is
+	// named {projectName}-{Name}
+	Name string
+	// KeepImages is the number of most recent images kept; older ones and
+	// untagged images after a day are expired
+	KeepImages int
+}
+
+// LabRepository is an ECR repository of a lab image with immutable tags,
+// scanning on push and a lifecycle policy bounding its storage.
+type LabRepository struct {
+	pulumi.ResourceState
+
+	Repository      *ecr.Repository
+	LifecyclePolicy *ecr.LifecyclePolicy
+}
+
+// NewLabRepository creates the repository and its lifecycle policy.
+func NewLabRepository(ctx *pulumi.Context, name string, args *LabRepositoryArgs, opts ...pulumi.ResourceOption) (*LabRepository, error) {
+	if args.KeepImages < 1 {
+		return nil, fmt.Errorf("a repository must keep at least one image (got %d)", args.KeepImages)
+	}
+
+	c := &LabRepository{}
+	err := ctx.RegisterComponentResource(typePrefix+"LabRepository", name, c, opts...)
+	if err != nil {
+		return nil, err
+	}
+	lb := args.Labels
+
+	// Images are tagged by their content and never overwritten, so a tag
+	// names exactly one build
+	c.Repository, err = ecr.NewRepository(ctx, lb.Name(args.Name), &ecr.RepositoryArgs{
+		Name:               pulumi.String(lb.Name(args.Name)),
+		ImageTagMutability: pulumi.String("IMMUTABLE"),
+		ImageScanningConfiguration: &ecr.RepositoryImageScanningConfigurationArgs{
+			ScanOnPush: pulumi.Bool(true),
+		},
+		// Lab images are deleted with the stack
+		ForceDelete: pulumi.Bool(true),
+		Tags:        lb.Tags(lb.Name(args.Name)),
+	}, childOptions(c)...)
+	if err != nil {
+		return nil, err
+	}
+
+	// The rule for any tag status must come last
+	c.LifecyclePolicy, err = ecr.NewLifecyclePolicy(ctx, lb.Name(args.Name+"-lifecycle"), &ecr.LifecyclePolicyArgs{
+		Repository: c.Repository.Name,
+		Policy: pulumi.String(fmt.Sprintf(`{
+  "rules": [
+    {
+      "rulePriority": 1,
+      "description": "Expire untagged images after a day",
+      "selection": {"tagStatus": "untagged", "countType": "sinceImagePushed", "countUnit": "days", "countNumber": 1},
+      "action": {"type": "expire"}
+    },
+    {
+      "rulePriority": 2,
+      "description": "Keep the last %d images",
+      "selection": {"tagStatus": "any", "countType": "imageCountMoreThan", "countNumber": %d},
+      "action": {"type": "expire"}
+    }
+  ]
+}`, args.KeepImages, args.KeepImages)),
+	}, childOptions(c)...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ctx.RegisterResourceOutputs(c, pulumi.Map{})
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
@@ -0,0 +1,61 @@
+package components
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+func TestLabRepository(t *testing.T) {
+	m, err := run(t, func(ctx *pulumi.Context) error {
+		_, err := NewLabRepository(ctx, "test-workload-simulator-repository", &LabRepositoryArgs{
+			Labels:     testLabels,
+			Name:       "workload-simulator",
+			KeepImages: 5,
+		})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repository := m.inputs(t, "test-workload-simulator")
+	assertString(t, repository, "name", "test-workload-simulator")
+	assertString(t, repository, "imageTagMutability", "IMMUTABLE")
+	assertBool(t, repository["imageScanningConfiguration"].ObjectValue(), "scanOnPush", true)
+
+	var policy struct {
+		Rules []struct {
+			RulePriority int `json:"rulePriority"`
+			Selection    struct {
+				TagStatus   string `json:"tagStatus"`
+				CountType   string `json:"countType"`
+				CountNumber int    `json:"countNumber"`
+			} `json:"selection"`
+		} `json:"rules"`
+	}
+	if err := json.Unmarshal([]byte(m.inputs(t, "test-workload-simulator-lifecycle")["policy"].StringValue()), &policy); err != nil {
+		t.Fatal(err)
+	}
+	if len(policy.Rules) != 2 {
+		t.Fatalf("got %d lifecycle rules, want 2", len(policy.Rules))
+	}
+	last := policy.Rules[1]
+	if last.Selection.TagStatus != "any" || last.Selection.CountType != "imageCountMoreThan" || last.Selection.CountNumber != 5 {
+		t.Errorf("last rule: got %+v, want to keep the last 5 images", last.Selection)
+	}
+}
+
+func TestLabRepositoryKeepsAnImage(t *testing.T) {
+	_, err := run(t, func(ctx *pulumi.Context) error {
+		_, err := NewLabRepository(ctx, "test-workload-simulator-repository", &LabRepositoryArgs{
+			Labels: testLabels,
+			Name:   "workload-simulator",
+		})
+		return err
+	})
+	if err == nil {
+		t.Fatal("expected an error for a repository keeping no images")
+	}
+}
@@ -0,0 +1,382 @@
+package components
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/autoscaling"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/cloudwatch"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ec2"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/iam"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/s3"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/secretsmanager"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"aurora-bluegreen-lab/internal/labels"
+)
+
+// LabSimulatorHostArgs configures a LabSimulatorHost.
+type LabSimulatorHostArgs struct {
+	Labels *labels.Labels
+	// Region is the stack's region, used by the instances' AWS CLI calls
+	Region string
+
+	InstanceType string
+	AmiId        string
+	KeyName      string
+	// SubnetIds are the subnets the single instances take in turn, e.g. one
+	// per availability zone; the Auto Scaling Group balances across them
+	SubnetIds       []pulumi.StringInput
+	SecurityGroupId pulumi.StringInput
+	// Private launches the instances without a public IP; SubnetIds must
+	// then route to a NAT gateway for the packages and SSM
+	Private bool
+
+	// InstanceCount is the number of single instances, 1 when unset; they are
+	// named workload-simulator, workload-simulator-2 and so on
+	InstanceCount int
+	// AllocateEip associates an Elastic IP with each single instance, so its
+	// public address survives stopping and starting it
+	AllocateEip bool
+
+	// Count > 0 runs Count instances in an Auto Scaling Group instead of the
+	// single manually operated instance; it requires Service
+	Count int
+	// UseSpot launches the instances on Spot
+	UseSpot bool
+	// OnDemandBaseCapacity instances stay on-demand in the Auto Scaling Group when UseSpot is set
+	OnDemandBaseCapacity int
+	// SpotInstanceTypes are additional instance types that diversify Spot capacity
+	SpotInstanceTypes []string
+
+	// Service, when set, runs the simulator as the workload-simulator systemd service
+	Service *SimulatorServiceArgs
+	// JarPath, when set, uploads the locally built jar to S3 for the instances
+	// to download on boot
+	JarPath string
+
+	// IamDbUser, when set, allows the instances to connect to the cluster
+	// identified by ClusterResourceId as this database user with IAM
+	// database authentication
+	IamDbUser         string
+	ClusterResourceId pulumi.StringInput
+
+	// MetricsNamespace, when set, allows the instances to publish CloudWatch
+	// custom metrics to this namespace
+	MetricsNamespace string
+
+	// ExperimentTable, when set, allows the instances to register their runs
+	// in this experiment registry table of the monitoring stack
+	ExperimentTable string
+
+	// LogRetentionDays > 0 ships the instance setup log and the simulator logs
+	// to a CloudWatch Logs group kept for this many days
+	LogRetentionDays int
+}
+
+// LabSimulatorHost is the workload simulator host: a single EC2 instance or
+// an Auto Scaling Group, with the optional systemd service and S3 artifact
+// distribution.
+type LabSimulatorHost struct {
+	pulumi.ResourceState
+
+	Instances       []*ec2.Instance      // nil in Auto Scaling Group mode
+	Instance        *ec2.Instance        // the first of Instances; nil in Auto Scaling Group mode
+	Eips            []*ec2.Eip           // one per Instances; nil without AllocateEip
+	Group           *autoscaling.Group   // nil in single instance mode
+	LaunchTemplate  *ec2.LaunchTemplate  // nil in single instance mode
+	Role            *iam.Role            // nil without Private, Service, JarPath, IamDbUser, MetricsNamespace, ExperimentTable or LogRetentionDays
+	InstanceProfile *iam.InstanceProfile // nil without Private, Service, JarPath, IamDbUser, MetricsNamespace, ExperimentTable or LogRetentionDays
+
+	// EndpointParameterName and CredentialsSecret are set with Service
+	EndpointParameterName string
+	CredentialsSecret     *secretsmanager.Secret
+
+	// ArtifactsBucket and Jar are set with JarPath
+	ArtifactsBucket *s3.BucketV2
+	Jar             *s3.BucketObjectv2
+
+	// LogGroup is set with LogRetentionDays
+	LogGroup *cloudwatch.LogGroup
+}
+
+// NewLabSimulatorHost creates the workload simulator host.
+func NewLabSimulatorHost(ctx *pulumi.Context, name string, args *LabSimulatorHostArgs, opts ...pulumi.ResourceOption) (*LabSimulatorHost, error) {
+	if args.Count > 0 && args.Service == nil {
+		return nil, fmt.Errorf("an Auto Scaling Group of simulators requires the simulator service")
+	}
+	if args.Count > 0 && args.InstanceCount > 1 {
+		return nil, fmt.Errorf("InstanceCount applies to single instances, not to an Auto Scaling Group")
+	}
+	if args.AllocateEip && (args.Count > 0 || args.Private) {
+		return nil, fmt.Errorf("AllocateEip applies to single instances with a public IP")
+	}
+	if len(args.SubnetIds) == 0 {
+		return nil, fmt.Errorf("the simulator host needs at least one subnet")
+	}
+	if args.IamDbUser != "" && args.ClusterResourceId == nil {
+		return nil, fmt.Errorf("IAM database authentication requires the cluster resource ID")
+	}
+
+	c := &LabSimulatorHost{}
+	err := ctx.RegisterComponentResource(typePrefix+"LabSimulatorHost", name, c, opts...)
+	if err != nil {
+		return nil, err
+	}
+	lb := args.Labels
+
+	userData := pulumi.String(hostUserData).ToStringOutput()
+
+	// Create the instance profile used by the simulator service, the
+	// artifact download, IAM database authentication, custom metrics, the
+	// experiment registry and logs; a private instance needs it for SSM
+	// Session Manager access
+	var instanceProfileName pulumi.StringPtrInput
+	if args.Private || args.Service != nil || args.JarPath != "" || args.IamDbUser != "" || args.MetricsNamespace != "" || args.ExperimentTable != "" || args.LogRetentionDays > 0 {
+		if err := c.newProfile(ctx, lb); err != nil {
+			return nil, err
+		}
+		instanceProfileName = c.InstanceProfile.Name
+	}
+
+	// Allow the simulator to generate IAM auth tokens for the cluster
+	if args.IamDbUser != "" {
+		if err := c.newIamAuthPolicy(ctx, lb, args.Region, args.ClusterResourceId, args.IamDbUser); err != nil {
+			return nil, err
+		}
+	}
+
+	// Allow the simulator to publish its statistics as custom metrics
+	if args.MetricsNamespace != "" {
+		if err := c.newMetricsPolicy(ctx, lb, args.MetricsNamespace); err != nil {
+			return nil, err
+		}
+	}
+
+	// Allow the simulator to register its runs in the experiment registry
+	if args.ExperimentTable != "" {
+		if err := c.newRegistryPolicy(ctx, lb, args.Region, args.ExperimentTable); err != nil {
+			return nil, err
+		}
+	}
+
+	// Create the simulator service configuration
+	if args.Service != nil {
+		serviceUserData, err := c.newService(ctx, lb, args.Region, args.Service)
+		if err != nil {
+			return nil, err
+		}
+		userData = pulumi.Sprintf("%s%s", userData, serviceUserData)
+	}
+
+	// Upload the simulator jar for the instances to download on boot
+	if args.JarPath != "" {
+		artifactsUserData, err := c.newArtifacts(ctx, lb, args.Region, args.JarPath)
+		if err != nil {
+			return nil, err
+		}
+		userData = pulumi.Sprintf("%s%s", userData, artifactsUserData)
+	}
+
+	// Ship the instance and simulator logs to CloudWatch Logs
+	if args.LogRetentionDays > 0 {
+		logsUserData, err := c.newLogs(ctx, lb, args.LogRetentionDays, args.Service != nil)
+		if err != nil {
+			return nil, err
+		}
+		userData = pulumi.Sprintf("%s%s", userData, logsUserData)
+	}
+
+	if args.Count > 0 {
+		// Create Auto Scaling Group of simulator instances
+		if err := c.newGroup(ctx, args, userData); err != nil {
+			return nil, err
+		}
+	} else {
+		// Create the EC2 instances, spread across the subnets
+		for i := 0; i < max(args.InstanceCount, 1); i++ {
+			if err := c.newInstance(ctx, args, i, userData, instanceProfileName); err != nil {
+				return nil, err
+			}
+		}
+		c.Instance = c.Instances[0]
+	}
+
+	err = ctx.RegisterResourceOutputs(c, pulumi.Map{})
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// newInstance creates the single simulator instance numbered index, in the
+// subnet whose turn it is. The first instance keeps the unnumbered name, so
+// adding instances leaves it in place.
+func (c *LabSimulatorHost) newInstance(ctx *pulumi.Context, args *LabSimulatorHostArgs, index int, userData pulumi.StringOutput, instanceProfileName pulumi.StringPtrInput) error {
+	lb := args.Labels
+	name := lb.Name("workload-simulator")
+	if index > 0 {
+		name = lb.Name(fmt.Sprintf("workload-simulator-%d", index+1))
+	}
+
+	userDataEncoded := userData.ApplyT(func(s string) string {
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	}).(pulumi.StringOutput)
+
+	// Run the single instance as a persistent Spot request that stops (rather
+	// than terminates) on interruption and restarts when capacity returns
+	var marketOptions ec2.InstanceInstanceMarketOptionsPtrInput
+	if args.UseSpot {
+		marketOptions = &ec2.InstanceInstanceMarketOptionsArgs{
+			MarketType: pulumi.String("spot"),
+			SpotOptions: &ec2.InstanceInstanceMarketOptionsSpotOptionsArgs{
+				SpotInstanceType:             pulumi.String("persistent"),
+				InstanceInterruptionBehavior: pulumi.String("stop"),
+			},
+		}
+	}
+
+	instance, err := ec2.NewInstance(ctx, name, &ec2.InstanceArgs{
+		InstanceType:                      pulumi.String(args.InstanceType),
+		Ami:                               pulumi.String(args.AmiId),
+		SubnetId:                          args.SubnetIds[index%len(args.SubnetIds)],
+		VpcSecurityGroupIds:               pulumi.StringArray{args.SecurityGroupId},
+		KeyName:                           pulumi.String(args.KeyName),
+		IamInstanceProfile:                instanceProfileName,
+		UserDataBase64:                    userDataEncoded,
+		AssociatePublicIpAddress:          pulumi.Bool(!args.Private),
+		DisableApiTermination:             pulumi.Bool(false),
+		InstanceInitiatedShutdownBehavior: pulumi.String("stop"),
+		InstanceMarketOptions:             marketOptions,
+		Monitoring:                        pulumi.Bool(true),
+		EbsOptimized:                      pulumi.Bool(true),
+		RootBlockDevice: &ec2.InstanceRootBlockDeviceArgs{
+			VolumeSize:          pulumi.Int(30),
+			VolumeType:          pulumi.String("gp3"),
+			DeleteOnTermination: pulumi.Bool(true),
+			Encrypted:           pulumi.Bool(true),
+			Tags:                lb.Tags(name, labels.Role("workload-simulator")),
+		},
+		Tags: lb.Tags(name, labels.Role("workload-simulator")),
+	}, childOptions(c)...)
+	if err != nil {
+		return err
+	}
+	c.Instances = append(c.Instances, instance)
+
+	if args.AllocateEip {
+		eip, err := ec2.NewEip(ctx, name+"-eip", &ec2.EipArgs{
+			Domain:   pulumi.String("vpc"),
+			Instance: instance.ID(),
+			Tags:     lb.Tags(name+"-eip", labels.Role("workload-simulator")),
+		}, childOptions(c)...)
+		if err != nil {
+			return err
+		}
+		c.Eips = append(c.Eips, eip)
+	}
+	return nil
+}
+
+// hostUserData installs Java and prepares the workload simulator directory.
+const hostUserData = `#!/bin/bash
+set -e
+
+# Keep the output of the instance setup
+exec > >(tee -a /var/log/user-data.log) 2>&1
+
+# Update system
+yum update -y
+
+# Install Amazon Corretto 17 (OpenJDK)
+yum install -y java-17-amazon-corretto-headless
+
+# Install MySQL client for testing
+yum install -y mysql
+
+# Install git (for cloning the workload simulator if needed)
+yum install -y git
+
+# Create directory for workload simulator
+mkdir -p /opt/workload-simulator
+chown ec2-user:ec2-user /opt/workload-simulator
+
+# Create a helper script to run the workload simulator
+cat > /opt/workload-simulator/run-simulator.sh << 'EOF'
+#!/bin/bash
+# Helper script to run the workload simulator
+# Usage: ./run-simulator.sh <aurora-endpoint> [additional-options]
+
+if [ -z "$1" ]; then
+  echo "Usage: $0 <aurora-endpoint> [additional-options]"
+  echo "Example: $0 my-cluster.cluster-xxxxx.us-east-1.rds.amazonaws.com --write-workers 10"
+  exit 1
+fi
+
+AURORA_ENDPOINT=$1
+shift
+
+java -jar /opt/workload-simulator/workload-simulator.jar \
+  --aurora-endpoint "$AURORA_ENDPOINT" \
+  --database-name lab_db \
+  --write-workers 10 \
+  --write-rate 100 \
+  --connection-pool-size 100 \
+  "$@"
+EOF
+
+chmod +x /opt/workload-simulator/run-simulator.sh
+chown ec2-user:ec2-user /opt/workload-simulator/run-simulator.sh
+
+# Create a README with instructions
+cat > /opt/workload-simulator/README.txt << 'EOF'
+Aurora Blue-Green Deployment Lab - Workload Simulator
+
+This directory contains the workload simulator for testing Aurora Blue-Green deployments.
+
+SETUP:
+1. Upload the workload-simulator.jar file to this directory:
+   scp -i your-key.pem workload-simulator.jar ec2-user@<instance-ip>:/opt/workload-simulator/
+   (Not needed when the stack's simulatorJar config is set; the jar is then
+   downloaded from S3 on boot.)
+
+USAGE:
+1. Run the workload simulator directly:
+   java -jar workload-simulator.jar \
+     --aurora-endpoint <your-cluster-endpoint> \
+     --database-name lab_db \
+     --write-workers 10 \
+     --write-rate 100 \
+     --connection-pool-size 100
+
+2. Or use the helper script:
+   ./run-simulator.sh <your-cluster-endpoint>
+
+3. To run with custom parameters:
+   ./run-simulator.sh <your-cluster-endpoint> --write-workers 20 --write-rate 200
+
+AVAILABLE PARAMETERS:
+  --aurora-endpoint       : Aurora cluster writer endpoint (required)
+  --database-name         : Database name (default: lab_db)
+  --write-workers         : Number of concurrent write workers (default: 10)
+  --write-rate            : Writes per second per worker (default: 100)
+  --connection-pool-size  : Database connection pool size (default: 100)
+  --log-interval          : Statistics log interval in seconds (default: 10)
+
+TESTING THE BLUE-GREEN DEPLOYMENT:
+1. Start the workload simulator
+2. Observe the console output showing successful write operations
+3. In AWS Console or CLI, create a Blue-Green deployment for your Aurora cluster
+4. Keep the workload simulator running during the upgrade
+5. Watch for connection errors during the Blue-Green switchover
+6. Validate that the workload resumes after the switchover completes
+
+For more information, see the project documentation at:
+/home/ec2-user/aurora-blue-green-deployment-lab/README.md
+EOF
+
+chown ec2-user:ec2-user /opt/workload-simulator/README.txt
+
+echo "EC2 instance setup completed successfully"
+`
@@ -0,0 +1,90 @@
+package components
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/iam"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/s3"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"aurora-bluegreen-lab/internal/labels"
+)
+
+// simulatorJarKey is the S3 object key of the uploaded simulator jar.
+const simulatorJarKey = "workload-simulator/workload-simulator.jar"
+
+// newArtifacts creates a private S3 bucket, uploads the simulator jar at
+// jarPath as a Pulumi asset and returns the user data that downloads it to
+// /opt/workload-simulator on boot. Re-running pulumi up after a rebuild
+// uploads the new jar.
+func (c *LabSimulatorHost) newArtifacts(ctx *pulumi.Context, lb *labels.Labels, region, jarPath string) (pulumi.StringOutput, error) {
+	var err error
+
+	// Create S3 bucket for the simulator artifacts
+	c.ArtifactsBucket, err = s3.NewBucketV2(ctx, lb.Name("artifacts"), &s3.BucketV2Args{
+		BucketPrefix: pulumi.String(lb.Name("artifacts-")),
+		// Lab artifacts are deleted with the stack
+		ForceDestroy: pulumi.Bool(true),
+		Tags:         lb.Tags(lb.Name("artifacts")),
+	}, childOptions(c)...)
+	if err != nil {
+		return pulumi.StringOutput{}, err
+	}
+
+	_, err = s3.NewBucketPublicAccessBlock(ctx, lb.Name("artifacts-public-access-block"), &s3.BucketPublicAccessBlockArgs{
+		Bucket:                c.ArtifactsBucket.ID(),
+		BlockPublicAcls:       pulumi.Bool(true),
+		BlockPublicPolicy:     pulumi.Bool(true),
+		IgnorePublicAcls:      pulumi.Bool(true),
+		RestrictPublicBuckets: pulumi.Bool(true),
+	}, childOptions(c)...)
+	if err != nil {
+		return pulumi.StringOutput{}, err
+	}
+
+	// Upload the simulator jar
+	c.Jar, err = s3.NewBucketObjectv2(ctx, lb.Name("simulator-jar"), &s3.BucketObjectv2Args{
+		Bucket:      c.ArtifactsBucket.ID(),
+		Key:         pulumi.String(simulatorJarKey),
+		Source:      pulumi.NewFileAsset(jarPath),
+		ContentType: pulumi.String("application/java-archive"),
+		Tags:        lb.Tags(lb.Name("simulator-jar")),
+	}, childOptions(c)...)
+	if err != nil {
+		return pulumi.StringOutput{}, err
+	}
+
+	// Allow the instances to download the jar
+	_, err = iam.NewRolePolicy(ctx, lb.Name("simulator-artifacts-policy"), &iam.RolePolicyArgs{
+		Role: c.Role.ID(),
+		Policy: pulumi.Sprintf(`{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Action": "s3:GetObject",
+      "Resource": "%s/%s"
+    }
+  ]
+}`, c.ArtifactsBucket.Arn, simulatorJarKey),
+	}, childOptions(c)...)
+	if err != nil {
+		return pulumi.StringOutput{}, err
+	}
+
+	return c.ArtifactsBucket.Bucket.ApplyT(func(name string) string {
+		return artifactsUserData(region, fmt.Sprintf("s3://%s/%s", name, simulatorJarKey))
+	}).(pulumi.StringOutput), nil
+}
+
+// artifactsUserData returns the user data section that downloads the
+// simulator jar. The jar is moved into place only once complete, so the
+// workload-simulator path unit never sees a partial file.
+func artifactsUserData(region, jarUri string) string {
+	return fmt.Sprintf(`
+# Download the workload simulator jar from S3
+aws s3 cp --region %s %s /opt/workload-simulator/workload-simulator.jar.download
+chown ec2-user:ec2-user /opt/workload-simulator/workload-simulator.jar.download
+mv /opt/workload-simulator/workload-simulator.jar.download /opt/workload-simulator/workload-simulator.jar
+`, region, jarUri)
+}
@@ -0,0 +1,52 @@
+package components
+
+import (
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/iam"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"aurora-bluegreen-lab/internal/labels"
+)
+
+// newContainerPolicy allows the simulator role to log in to ECR and pull
+// images, for the service's container image.
+func (c *LabSimulatorHost) newContainerPolicy(ctx *pulumi.Context, lb *labels.Labels) error {
+	_, err := iam.NewRolePolicyAttachment(ctx, lb.Name("simulator-ecr-policy"), &iam.RolePolicyAttachmentArgs{
+		Role:      c.Role.Name,
+		PolicyArn: pulumi.String("arn:aws:iam::aws:policy/AmazonEC2ContainerRegistryReadOnly"),
+	}, childOptions(c)...)
+	return err
+}
+
+// containerSetup installs Docker for the service's container image. The
+// simulator keeps running as ec2-user, a member of the docker group, and the
+// runs directory is created up front so Docker does not create it as root.
+const containerSetup = `
+# Install Docker for the simulator container
+yum install -y docker
+systemctl enable --now docker
+usermod -aG docker ec2-user
+sudo -u ec2-user mkdir -p /opt/workload-simulator/runs
+`
+
+// containerLaunch is the end of the start script that runs the container
+// image. The image is built from workload-simulator/Dockerfile; its
+// entrypoint is replaced so the options are passed as with the jar and the
+// password stays in the environment. The host network and the shared runs
+// directory let lab-scenario runs work as with the jar: the image's user has
+// ec2-user's UID, and SIGTERM reaches the simulator through docker run.
+const containerLaunch = `
+# Pull with the instance role's credentials; the registry is the image's host,
+# and a ref pinned to a digest pulls exactly the image the registry stack pushed
+aws ecr get-login-password --region "$AWS_REGION" | \
+  docker login --username AWS --password-stdin "${SIMULATOR_IMAGE%%/*}"
+docker pull --quiet "$SIMULATOR_IMAGE"
+
+# SIMULATOR_OPTS is intentionally unquoted to split into separate options
+exec docker run --rm --init --network host \
+  --env DB_PASSWORD \
+  --volume /opt/workload-simulator/runs:/opt/workload-simulator/runs \
+  --entrypoint sh "$SIMULATOR_IMAGE" \
+  -c 'exec java $JAVA_OPTS -jar workload-simulator.jar "$@"' workload-simulator \
+  --aurora-endpoint "$ENDPOINT" \
+  --username "$DB_USERNAME" \
+  $SIMULATOR_OPTS`
@@ -0,0 +1,163 @@
+package components
+
+import (
+	"encoding/base64"
+	"slices"
+	"sort"
+	"strconv"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/autoscaling"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ec2"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"aurora-bluegreen-lab/internal/labels"
+)
+
+// newGroup creates a Launch Template and an Auto Scaling Group of simulator
+// instances that run the simulator service on boot.
+func (c *LabSimulatorHost) newGroup(ctx *pulumi.Context, args *LabSimulatorHostArgs, userData pulumi.StringInput) error {
+	lb := args.Labels
+	var err error
+
+	// Create Launch Template; provider default tags do not reach the
+	// instances, volumes and network interfaces it launches
+	instanceTags := lb.AllTags(lb.Name("workload-simulator"), labels.Role("workload-simulator"))
+	c.LaunchTemplate, err = ec2.NewLaunchTemplate(ctx, lb.Name("simulator-lt"), &ec2.LaunchTemplateArgs{
+		Name:         pulumi.String(lb.Name("simulator-lt")),
+		ImageId:      pulumi.String(args.AmiId),
+		InstanceType: pulumi.String(args.InstanceType),
+		KeyName:      pulumi.String(args.KeyName),
+		UserData: userData.ToStringOutput().ApplyT(func(s string) string {
+			return base64.StdEncoding.EncodeToString([]byte(s))
+		}).(pulumi.StringOutput),
+		EbsOptimized: pulumi.String("true"),
+		IamInstanceProfile: &ec2.LaunchTemplateIamInstanceProfileArgs{
+			Arn: c.InstanceProfile.Arn,
+		},
+		Monitoring: &ec2.LaunchTemplateMonitoringArgs{
+			Enabled: pulumi.Bool(true),
+		},
+		NetworkInterfaces: ec2.LaunchTemplateNetworkInterfaceArray{
+			&ec2.LaunchTemplateNetworkInterfaceArgs{
+				AssociatePublicIpAddress: pulumi.String(strconv.FormatBool(!args.Private)),
+				SecurityGroups:           pulumi.StringArray{args.SecurityGroupId},
+				DeleteOnTermination:      pulumi.String("true"),
+			},
+		},
+		BlockDeviceMappings: ec2.LaunchTemplateBlockDeviceMappingArray{
+			&ec2.LaunchTemplateBlockDeviceMappingArgs{
+				DeviceName: pulumi.String("/dev/xvda"),
+				Ebs: &ec2.LaunchTemplateBlockDeviceMappingEbsArgs{
+					VolumeSize:          pulumi.Int(30),
+					VolumeType:          pulumi.String("gp3"),
+					DeleteOnTermination: pulumi.String("true"),
+					Encrypted:           pulumi.String("true"),
+				},
+			},
+		},
+		TagSpecifications: ec2.LaunchTemplateTagSpecificationArray{
+			&ec2.LaunchTemplateTagSpecificationArgs{
+				ResourceType: pulumi.String("instance"),
+				Tags:         instanceTags,
+			},
+			&ec2.LaunchTemplateTagSpecificationArgs{
+				ResourceType: pulumi.String("volume"),
+				Tags:         instanceTags,
+			},
+			&ec2.LaunchTemplateTagSpecificationArgs{
+				ResourceType: pulumi.String("network-interface"),
+				Tags:         instanceTags,
+			},
+		},
+		UpdateDefaultVersion: pulumi.Bool(true),
+		Tags:                 lb.Tags(lb.Name("simulator-lt")),
+	}, childOptions(c)...)
+	if err != nil {
+		return err
+	}
+
+	// Create Auto Scaling Group
+	groupArgs := &autoscaling.GroupArgs{
+		Name:               pulumi.String(lb.Name("simulator-asg")),
+		MinSize:            pulumi.Int(args.Count),
+		MaxSize:            pulumi.Int(args.Count),
+		DesiredCapacity:    pulumi.Int(args.Count),
+		VpcZoneIdentifiers: subnetZones(args.SubnetIds),
+		HealthCheckType:    pulumi.String("EC2"),
+		InstanceRefresh: &autoscaling.GroupInstanceRefreshArgs{
+			Strategy: pulumi.String("Rolling"),
+		},
+		Tags: groupTags(lb.AllTags(lb.Name("simulator-asg"))),
+	}
+
+	if args.UseSpot {
+		// Spot above the on-demand base, diversified across instance types so
+		// interrupted capacity is replaced from other pools
+		overrides := autoscaling.GroupMixedInstancesPolicyLaunchTemplateOverrideArray{
+			&autoscaling.GroupMixedInstancesPolicyLaunchTemplateOverrideArgs{
+				InstanceType: pulumi.String(args.InstanceType),
+			},
+		}
+		for _, instanceType := range args.SpotInstanceTypes {
+			overrides = append(overrides, &autoscaling.GroupMixedInstancesPolicyLaunchTemplateOverrideArgs{
+				InstanceType: pulumi.String(instanceType),
+			})
+		}
+
+		groupArgs.CapacityRebalance = pulumi.Bool(true)
+		groupArgs.MixedInstancesPolicy = &autoscaling.GroupMixedInstancesPolicyArgs{
+			InstancesDistribution: &autoscaling.GroupMixedInstancesPolicyInstancesDistributionArgs{
+				OnDemandBaseCapacity:                pulumi.Int(args.OnDemandBaseCapacity),
+				OnDemandPercentageAboveBaseCapacity: pulumi.Int(0),
+				SpotAllocationStrategy:              pulumi.String("price-capacity-optimized"),
+			},
+			LaunchTemplate: &autoscaling.GroupMixedInstancesPolicyLaunchTemplateArgs{
+				LaunchTemplateSpecification: &autoscaling.GroupMixedInstancesPolicyLaunchTemplateLaunchTemplateSpecificationArgs{
+					LaunchTemplateId: c.LaunchTemplate.ID(),
+					Version:          pulumi.String("$Latest"),
+				},
+				Overrides: overrides,
+			},
+		}
+	} else {
+		groupArgs.LaunchTemplate = &autoscaling.GroupLaunchTemplateArgs{
+			Id:      c.LaunchTemplate.ID(),
+			Version: pulumi.String("$Latest"),
+		}
+	}
+
+	c.Group, err = autoscaling.NewGroup(ctx, lb.Name("simulator-asg"), groupArgs, childOptions(c)...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// groupTags converts resource tags to Auto Scaling Group tags propagated to
+// the launched instances.
+func groupTags(tags pulumi.StringMap) autoscaling.GroupTagArray {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := autoscaling.GroupTagArray{}
+	for _, key := range keys {
+		result = append(result, &autoscaling.GroupTagArgs{
+			Key:               pulumi.String(key),
+			Value:             tags[key],
+			PropagateAtLaunch: pulumi.Bool(false),
+		})
+	}
+	return result
+}
+
+// subnetZones returns the distinct subnets of the group; a VPC with one
+// public subnet gives the same subnet for both zones.
+func subnetZones(subnetIds []pulumi.StringInput) pulumi.StringArrayOutput {
+	return pulumi.StringArray(subnetIds).ToStringArrayOutput().ApplyT(func(ids []string) []string {
+		return slices.Compact(ids)
+	}).(pulumi.StringArrayOutput)
+}
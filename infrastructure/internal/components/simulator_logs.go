@@ -0,0 +1,131 @@
+package components
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/cloudwatch"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/iam"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"aurora-bluegreen-lab/internal/labels"
+)
+
+// simulatorServiceLog is the file the journal of the simulator service is
+// copied to; the CloudWatch agent only collects files.
+const simulatorServiceLog = "/var/log/workload-simulator/service.log"
+
+// newLogs creates the CloudWatch Logs group of the simulator instances, allows
+// the simulator role to write to it and returns the user data that installs
+// the CloudWatch agent shipping the instance setup log, the simulator service
+// log and the lab-scenario run logs to it.
+func (c *LabSimulatorHost) newLogs(ctx *pulumi.Context, lb *labels.Labels, retentionDays int, service bool) (string, error) {
+	logGroupName := fmt.Sprintf("/%s/simulator", lb.ProjectName)
+	var err error
+	c.LogGroup, err = cloudwatch.NewLogGroup(ctx, lb.Name("simulator-logs"), &cloudwatch.LogGroupArgs{
+		Name:            pulumi.String(logGroupName),
+		RetentionInDays: pulumi.Int(retentionDays),
+		Tags:            lb.Tags(lb.Name("simulator-logs")),
+	}, childOptions(c)...)
+	if err != nil {
+		return "", err
+	}
+
+	// The agent creates one stream per instance and log file
+	_, err = iam.NewRolePolicy(ctx, lb.Name("simulator-logs-policy"), &iam.RolePolicyArgs{
+		Role: c.Role.ID(),
+		Policy: pulumi.Sprintf(`{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Action": ["logs:CreateLogStream", "logs:PutLogEvents", "logs:DescribeLogStreams"],
+      "Resource": ["%s", "%s:*"]
+    }
+  ]
+}`, c.LogGroup.Arn, c.LogGroup.Arn),
+	}, childOptions(c)...)
+	if err != nil {
+		return "", err
+	}
+
+	return logsUserData(logGroupName, service)
+}
+
+// logsUserData returns the user data section that installs and starts the
+// CloudWatch agent. With the service, a helper unit copies the service's
+// journal to simulatorServiceLog, which logrotate keeps small.
+func logsUserData(logGroup string, service bool) (string, error) {
+	type logFile struct {
+		FilePath      string `json:"file_path"`
+		LogGroupName  string `json:"log_group_name"`
+		LogStreamName string `json:"log_stream_name"`
+	}
+	files := []logFile{
+		{"/var/log/user-data.log", logGroup, "{instance_id}/user-data"},
+		// The agent follows the most recently written run
+		{"/opt/workload-simulator/runs/*/simulator.log", logGroup, "{instance_id}/scenario-runs"},
+	}
+	if service {
+		files = append(files, logFile{simulatorServiceLog, logGroup, "{instance_id}/service"})
+	}
+	agentConfig, err := json.MarshalIndent(map[string]interface{}{
+		"agent": map[string]interface{}{"run_as_user": "root"},
+		"logs": map[string]interface{}{
+			"logs_collected": map[string]interface{}{
+				"files": map[string]interface{}{"collect_list": files},
+			},
+		},
+	}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	var serviceLog string
+	if service {
+		serviceLog = fmt.Sprintf(`
+# Copy the simulator service journal to a file for the agent
+mkdir -p /var/log/workload-simulator /var/lib/workload-simulator-log
+cat > /etc/systemd/system/workload-simulator-log.service << 'EOF'
+[Unit]
+Description=Copy the workload simulator journal to %[1]s
+After=systemd-journald.service
+
+[Service]
+Type=simple
+ExecStart=/bin/sh -c 'exec journalctl -u workload-simulator.service -f -o short-iso --cursor-file=/var/lib/workload-simulator-log/cursor >> %[1]s'
+Restart=always
+RestartSec=5
+
+[Install]
+WantedBy=multi-user.target
+EOF
+
+cat > /etc/logrotate.d/workload-simulator << 'EOF'
+%[1]s {
+  daily
+  rotate 3
+  compress
+  missingok
+  notifempty
+  copytruncate
+}
+EOF
+
+systemctl daemon-reload
+systemctl enable --now workload-simulator-log.service
+`, simulatorServiceLog)
+	}
+
+	return fmt.Sprintf(`
+# Ship the instance setup and simulator logs to CloudWatch Logs (%s)
+yum install -y amazon-cloudwatch-agent
+%s
+cat > /opt/aws/amazon-cloudwatch-agent/etc/amazon-cloudwatch-agent.json << 'EOF'
+%s
+EOF
+
+/opt/aws/amazon-cloudwatch-agent/bin/amazon-cloudwatch-agent-ctl -a fetch-config -m ec2 -s \
+  -c file:/opt/aws/amazon-cloudwatch-agent/etc/amazon-cloudwatch-agent.json
+`, logGroup, serviceLog, agentConfig), nil
+}
@@ -0,0 +1,114 @@
+package components
+
+import (
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/iam"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"aurora-bluegreen-lab/internal/labels"
+)
+
+// newProfile creates the IAM role and instance profile assumed by the
+// simulator instances. Optional features attach their own policies to the role.
+func (c *LabSimulatorHost) newProfile(ctx *pulumi.Context, lb *labels.Labels) error {
+	var err error
+	// Create IAM role for the simulator instances
+	c.Role, err = iam.NewRole(ctx, lb.Name("simulator-role"), &iam.RoleArgs{
+		Name: pulumi.String(lb.Name("simulator-role")),
+		AssumeRolePolicy: pulumi.String(`{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Principal": {"Service": "ec2.amazonaws.com"},
+      "Action": "sts:AssumeRole"
+    }
+  ]
+}`),
+		Tags: lb.Tags(lb.Name("simulator-role")),
+	}, childOptions(c)...)
+	if err != nil {
+		return err
+	}
+
+	// SSM RunCommand lets lab-scenario control the simulator without SSH
+	_, err = iam.NewRolePolicyAttachment(ctx, lb.Name("simulator-ssm-policy"), &iam.RolePolicyAttachmentArgs{
+		Role:      c.Role.Name,
+		PolicyArn: pulumi.String("arn:aws:iam::aws:policy/AmazonSSMManagedInstanceCore"),
+	}, childOptions(c)...)
+	if err != nil {
+		return err
+	}
+
+	c.InstanceProfile, err = iam.NewInstanceProfile(ctx, lb.Name("simulator-profile"), &iam.InstanceProfileArgs{
+		Name: pulumi.String(lb.Name("simulator-profile")),
+		Role: c.Role.Name,
+		Tags: lb.Tags(lb.Name("simulator-profile")),
+	}, childOptions(c)...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// newMetricsPolicy allows the simulator role to publish CloudWatch custom
+// metrics to namespace only (the simulator's --cloudwatch-namespace).
+func (c *LabSimulatorHost) newMetricsPolicy(ctx *pulumi.Context, lb *labels.Labels, namespace string) error {
+	// PutMetricData does not support resource-level permissions; the namespace
+	// condition key scopes it instead
+	_, err := iam.NewRolePolicy(ctx, lb.Name("simulator-metrics-policy"), &iam.RolePolicyArgs{
+		Role: c.Role.ID(),
+		Policy: pulumi.Sprintf(`{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Action": "cloudwatch:PutMetricData",
+      "Resource": "*",
+      "Condition": {"StringEquals": {"cloudwatch:namespace": %q}}
+    }
+  ]
+}`, namespace),
+	}, childOptions(c)...)
+	return err
+}
+
+// newRegistryPolicy allows the simulator role to register runs in the
+// experiment registry table (the simulator's --registry-table). The table
+// belongs to the monitoring stack, deployed after this one, so it is named
+// rather than referenced.
+func (c *LabSimulatorHost) newRegistryPolicy(ctx *pulumi.Context, lb *labels.Labels, region, table string) error {
+	_, err := iam.NewRolePolicy(ctx, lb.Name("simulator-registry-policy"), &iam.RolePolicyArgs{
+		Role: c.Role.ID(),
+		Policy: pulumi.Sprintf(`{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Action": "dynamodb:PutItem",
+      "Resource": "arn:aws:dynamodb:%s:*:table/%s"
+    }
+  ]
+}`, region, table),
+	}, childOptions(c)...)
+	return err
+}
+
+// newIamAuthPolicy allows the simulator role to connect to the cluster as
+// dbUser with IAM database authentication (the simulator's --auth iam).
+func (c *LabSimulatorHost) newIamAuthPolicy(ctx *pulumi.Context, lb *labels.Labels, region string, clusterResourceId pulumi.StringInput, dbUser string) error {
+	_, err := iam.NewRolePolicy(ctx, lb.Name("simulator-iam-auth-policy"), &iam.RolePolicyArgs{
+		Role: c.Role.ID(),
+		Policy: pulumi.Sprintf(`{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Action": "rds-db:connect",
+      "Resource": "arn:aws:rds-db:%s:*:dbuser:%s/%s"
+    }
+  ]
+}`, region, clusterResourceId, dbUser),
+	}, childOptions(c)...)
+	return err
+}
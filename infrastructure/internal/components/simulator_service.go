@@ -0,0 +1,202 @@
+package components
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/iam"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/secretsmanager"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ssm"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"aurora-bluegreen-lab/internal/labels"
+)
+
+// SimulatorServiceArgs configures the systemd-managed workload simulator.
+type SimulatorServiceArgs struct {
+	ClusterEndpoint pulumi.StringInput
+	MasterUsername  pulumi.StringInput
+	DbPassword      pulumi.StringInput
+	// Options are additional simulator command line options (SIMULATOR_OPTS)
+	Options string
+	// Image, when set, runs the simulator as this container image from ECR
+	// (repository:tag, or the registry stack's repository:tag@digest ref of
+	// the image it pushed) instead of the uploaded or copied jar
+	Image pulumi.StringInput
+}
+
+// newService stores the cluster endpoint in SSM Parameter Store and the
+// database credentials in Secrets Manager, allows the simulator role to read
+// both, and returns the user data that runs the simulator as the
+// workload-simulator systemd service.
+func (c *LabSimulatorHost) newService(ctx *pulumi.Context, lb *labels.Labels, region string, args *SimulatorServiceArgs) (pulumi.StringOutput, error) {
+	c.EndpointParameterName = fmt.Sprintf("/%s/aurora/cluster-endpoint", lb.ProjectName)
+	credentialsSecretName := fmt.Sprintf("%s/aurora/credentials", lb.ProjectName)
+
+	// Create SSM parameter with the cluster endpoint
+	endpointParameter, err := ssm.NewParameter(ctx, lb.Name("cluster-endpoint-param"), &ssm.ParameterArgs{
+		Name:        pulumi.String(c.EndpointParameterName),
+		Type:        pulumi.String("String"),
+		Value:       args.ClusterEndpoint,
+		Description: pulumi.String("Aurora cluster endpoint used by the workload simulator"),
+		Tags:        lb.Tags(lb.Name("cluster-endpoint-param")),
+	}, childOptions(c)...)
+	if err != nil {
+		return pulumi.StringOutput{}, err
+	}
+
+	// Create Secrets Manager secret with the database credentials
+	c.CredentialsSecret, err = secretsmanager.NewSecret(ctx, lb.Name("aurora-credentials"), &secretsmanager.SecretArgs{
+		Name:        pulumi.String(credentialsSecretName),
+		Description: pulumi.String("Aurora credentials used by the workload simulator"),
+		// Lab secrets are deleted immediately so the stack can be recreated
+		RecoveryWindowInDays: pulumi.Int(0),
+		Tags:                 lb.Tags(lb.Name("aurora-credentials")),
+	}, childOptions(c)...)
+	if err != nil {
+		return pulumi.StringOutput{}, err
+	}
+
+	credentials := pulumi.All(args.MasterUsername, args.DbPassword).ApplyT(func(values []interface{}) (string, error) {
+		data, err := json.Marshal(map[string]string{
+			"username": values[0].(string),
+			"password": values[1].(string),
+		})
+		return string(data), err
+	}).(pulumi.StringOutput)
+
+	_, err = secretsmanager.NewSecretVersion(ctx, lb.Name("aurora-credentials-version"), &secretsmanager.SecretVersionArgs{
+		SecretId:     c.CredentialsSecret.ID(),
+		SecretString: pulumi.ToSecret(credentials).(pulumi.StringOutput),
+	}, childOptions(c)...)
+	if err != nil {
+		return pulumi.StringOutput{}, err
+	}
+
+	// Allow the instances to read the endpoint and credentials, and the aurora
+	// stack's proxy endpoint output parameter (--proxy-endpoint auto)
+	proxyEndpointParameterArn := fmt.Sprintf("arn:aws:ssm:%s:*:parameter/%s/aurora/proxyEndpoint", region, lb.ProjectName)
+	_, err = iam.NewRolePolicy(ctx, lb.Name("simulator-config-policy"), &iam.RolePolicyArgs{
+		Role: c.Role.ID(),
+		Policy: pulumi.Sprintf(`{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Action": ["ssm:GetParameter", "ssm:GetParameters"],
+      "Resource": ["%s", "%s"]
+    },
+    {
+      "Effect": "Allow",
+      "Action": "secretsmanager:GetSecretValue",
+      "Resource": "%s"
+    }
+  ]
+}`, endpointParameter.Arn, proxyEndpointParameterArn, c.CredentialsSecret.Arn),
+	}, childOptions(c)...)
+	if err != nil {
+		return pulumi.StringOutput{}, err
+	}
+
+	if args.Image == nil {
+		return pulumi.String(serviceUserData(region, c.EndpointParameterName, credentialsSecretName, args.Options, "")).ToStringOutput(), nil
+	}
+
+	// Allow the instances to pull the image
+	if err := c.newContainerPolicy(ctx, lb); err != nil {
+		return pulumi.StringOutput{}, err
+	}
+	return args.Image.ToStringOutput().ApplyT(func(image string) string {
+		return serviceUserData(region, c.EndpointParameterName, credentialsSecretName, args.Options, image)
+	}).(pulumi.StringOutput), nil
+}
+
+// serviceUserData returns the user data section that installs the
+// workload-simulator systemd service. A path unit starts the service as soon
+// as workload-simulator.jar is present, and systemd restarts it on failure.
+// With image the service runs the container instead and starts right away.
+func serviceUserData(region, endpointParameterName, credentialsSecretName, options, image string) string {
+	setup, launch, trigger, after := "", jarLaunch, "/opt/workload-simulator/workload-simulator.jar", ""
+	if image != "" {
+		setup, launch, trigger, after = containerSetup, containerLaunch, "/opt/workload-simulator/start-simulator.sh", " docker.service"
+	}
+	return fmt.Sprintf(`
+# Install jq for reading the credentials secret
+yum install -y jq
+%s
+# Environment-based simulator configuration
+mkdir -p /etc/workload-simulator
+cat > /etc/workload-simulator/simulator.env << 'EOF'
+# Workload simulator configuration (restart the service after editing:
+#   sudo systemctl restart workload-simulator)
+AWS_REGION=%s
+ENDPOINT_PARAMETER=%s
+CREDENTIALS_SECRET=%s
+SIMULATOR_IMAGE=%s
+SIMULATOR_OPTS=%s
+EOF
+
+# Start script: resolves the endpoint and credentials, then runs the simulator
+cat > /opt/workload-simulator/start-simulator.sh << 'EOF'
+#!/bin/bash
+set -euo pipefail
+
+ENDPOINT=$(aws ssm get-parameter --region "$AWS_REGION" --name "$ENDPOINT_PARAMETER" \
+  --query Parameter.Value --output text)
+CREDENTIALS=$(aws secretsmanager get-secret-value --region "$AWS_REGION" \
+  --secret-id "$CREDENTIALS_SECRET" --query SecretString --output text)
+DB_USERNAME=$(echo "$CREDENTIALS" | jq -r .username)
+export DB_PASSWORD=$(echo "$CREDENTIALS" | jq -r .password)
+%s
+EOF
+
+chmod +x /opt/workload-simulator/start-simulator.sh
+chown ec2-user:ec2-user /opt/workload-simulator/start-simulator.sh
+
+cat > /etc/systemd/system/workload-simulator.service << 'EOF'
+[Unit]
+Description=Aurora Blue-Green Deployment Lab Workload Simulator
+After=network-online.target%s
+Wants=network-online.target%s
+ConditionPathExists=%s
+
+[Service]
+Type=simple
+User=ec2-user
+WorkingDirectory=/opt/workload-simulator
+EnvironmentFile=/etc/workload-simulator/simulator.env
+ExecStart=/opt/workload-simulator/start-simulator.sh
+Restart=always
+RestartSec=10
+
+[Install]
+WantedBy=multi-user.target
+EOF
+
+# Start the service once the jar has been uploaded (or the container start
+# script is in place)
+cat > /etc/systemd/system/workload-simulator.path << 'EOF'
+[Unit]
+Description=Start the workload simulator when it is installed
+
+[Path]
+PathExists=%s
+Unit=workload-simulator.service
+
+[Install]
+WantedBy=multi-user.target
+EOF
+
+systemctl daemon-reload
+systemctl enable workload-simulator.service
+systemctl enable --now workload-simulator.path
+`, setup, region, endpointParameterName, credentialsSecretName, image, options, launch, after, after, trigger, trigger)
+}
+
+// jarLaunch is the end of the start script that runs the jar on the host.
+const jarLaunch = `
+# SIMULATOR_OPTS is intentionally unquoted to split into separate options
+exec java -jar /opt/workload-simulator/workload-simulator.jar \
+  --aurora-endpoint "$ENDPOINT" \
+  --username "$DB_USERNAME" \
+  $SIMULATOR_OPTS`
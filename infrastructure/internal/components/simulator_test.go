@@ -0,0 +1,325 @@
+package components
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// testSimulatorArgs returns simulator host args for the mocks; change
+// modifies them before the host is created.
+func testSimulatorArgs(change func(*LabSimulatorHostArgs)) pulumi.RunFunc {
+	return func(ctx *pulumi.Context) error {
+		args := &LabSimulatorHostArgs{
+			Labels:          testLabels,
+			Region:          "us-east-1",
+			InstanceType:    "t3.xlarge",
+			AmiId:           "ami-12345678",
+			KeyName:         "lab-key",
+			SubnetIds:       []pulumi.StringInput{pulumi.String("subnet-ec2")},
+			SecurityGroupId: pulumi.String("sg-ec2"),
+		}
+		if change != nil {
+			change(args)
+		}
+		_, err := NewLabSimulatorHost(ctx, "test-simulator", args)
+		return err
+	}
+}
+
+func testService() *SimulatorServiceArgs {
+	return &SimulatorServiceArgs{
+		ClusterEndpoint: pulumi.String("cluster.example.com"),
+		MasterUsername:  pulumi.String("admin"),
+		DbPassword:      pulumi.String("password"),
+		Options:         "--write-workers 10",
+	}
+}
+
+func TestLabSimulatorHostSingleInstance(t *testing.T) {
+	m, err := run(t, testSimulatorArgs(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	instance := m.inputs(t, "test-workload-simulator")
+	assertString(t, instance, "instanceType", "t3.xlarge")
+	// The project-wide tags are the provider's default tags
+	tags := instance["tags"].ObjectValue()
+	assertString(t, tags, "Role", "workload-simulator")
+	if _, ok := tags["Project"]; ok {
+		t.Error("Project tagged on the instance instead of by the provider")
+	}
+	assertBool(t, instance["rootBlockDevice"].ObjectValue(), "encrypted", true)
+	if _, ok := instance["iamInstanceProfile"]; ok {
+		t.Error("instance profile attached without the service or artifacts")
+	}
+	if m.registered("test-simulator-asg") {
+		t.Error("Auto Scaling Group created in single instance mode")
+	}
+}
+
+func TestLabSimulatorHostService(t *testing.T) {
+	m, err := run(t, testSimulatorArgs(func(args *LabSimulatorHostArgs) {
+		args.Service = testService()
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertString(t, m.inputs(t, "test-cluster-endpoint-param"), "name", "/test/aurora/cluster-endpoint")
+	assertString(t, m.inputs(t, "test-aurora-credentials"), "name", "test/aurora/credentials")
+	assertString(t, m.inputs(t, "test-workload-simulator"), "iamInstanceProfile", "test-simulator-profile")
+	assertString(t, m.inputs(t, "test-simulator-ssm-policy"), "policyArn", "arn:aws:iam::aws:policy/AmazonSSMManagedInstanceCore")
+	if m.registered("test-simulator-ecr-policy") {
+		t.Error("ECR access granted without a container image")
+	}
+}
+
+func TestLabSimulatorHostContainer(t *testing.T) {
+	m, err := run(t, testSimulatorArgs(func(args *LabSimulatorHostArgs) {
+		args.Service = testService()
+		args.Service.Image = pulumi.String("123456789012.dkr.ecr.us-east-1.amazonaws.com/test-workload-simulator:src-0123456789abcdef@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef")
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertString(t, m.inputs(t, "test-simulator-ecr-policy"), "policyArn", "arn:aws:iam::aws:policy/AmazonEC2ContainerRegistryReadOnly")
+	userData, err := base64.StdEncoding.DecodeString(m.inputs(t, "test-workload-simulator")["userDataBase64"].StringValue())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"yum install -y docker",
+		"SIMULATOR_IMAGE=123456789012.dkr.ecr.us-east-1.amazonaws.com/test-workload-simulator:src-0123456789abcdef@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+		`docker pull --quiet "$SIMULATOR_IMAGE"`,
+		`exec docker run --rm --init --network host`,
+		"After=network-online.target docker.service",
+		"PathExists=/opt/workload-simulator/start-simulator.sh",
+	} {
+		if !strings.Contains(string(userData), want) {
+			t.Errorf("user data does not contain %q", want)
+		}
+	}
+	if strings.Contains(string(userData), "exec java -jar") {
+		t.Error("user data runs the jar as well as the image")
+	}
+}
+
+func TestLabSimulatorHostInstanceCount(t *testing.T) {
+	m, err := run(t, testSimulatorArgs(func(args *LabSimulatorHostArgs) {
+		args.SubnetIds = []pulumi.StringInput{pulumi.String("subnet-az1"), pulumi.String("subnet-az2")}
+		args.InstanceCount = 3
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first instance keeps its name, the others alternate between zones
+	for name, subnet := range map[string]string{
+		"test-workload-simulator":   "subnet-az1",
+		"test-workload-simulator-2": "subnet-az2",
+		"test-workload-simulator-3": "subnet-az1",
+	} {
+		instance := m.inputs(t, name)
+		assertString(t, instance, "subnetId", subnet)
+		assertString(t, instance["tags"].ObjectValue(), "Name", name)
+	}
+	if m.registered("test-workload-simulator-4") {
+		t.Error("more instances than InstanceCount")
+	}
+}
+
+func TestLabSimulatorHostEip(t *testing.T) {
+	m, err := run(t, testSimulatorArgs(func(args *LabSimulatorHostArgs) {
+		args.InstanceCount = 2
+		args.AllocateEip = true
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"test-workload-simulator", "test-workload-simulator-2"} {
+		eip := m.inputs(t, name+"-eip")
+		assertString(t, eip, "domain", "vpc")
+		assertString(t, eip, "instance", name+"-id")
+	}
+
+	_, err = run(t, testSimulatorArgs(func(args *LabSimulatorHostArgs) {
+		args.AllocateEip = true
+		args.Private = true
+	}))
+	if err == nil {
+		t.Error("expected an error for an Elastic IP on a private instance")
+	}
+}
+
+func TestLabSimulatorHostPrivate(t *testing.T) {
+	m, err := run(t, testSimulatorArgs(func(args *LabSimulatorHostArgs) {
+		args.Private = true
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	instance := m.inputs(t, "test-workload-simulator")
+	assertBool(t, instance, "associatePublicIpAddress", false)
+	// Session Manager is the way in without a public IP
+	assertString(t, instance, "iamInstanceProfile", "test-simulator-profile")
+	assertString(t, m.inputs(t, "test-simulator-ssm-policy"), "policyArn", "arn:aws:iam::aws:policy/AmazonSSMManagedInstanceCore")
+}
+
+func TestLabSimulatorHostIamAuth(t *testing.T) {
+	m, err := run(t, testSimulatorArgs(func(args *LabSimulatorHostArgs) {
+		args.IamDbUser = "lab_iam"
+		args.ClusterResourceId = pulumi.String("cluster-ABCDEFGHIJKL")
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policy := m.inputs(t, "test-simulator-iam-auth-policy")["policy"].StringValue()
+	if !strings.Contains(policy, `"arn:aws:rds-db:us-east-1:*:dbuser:cluster-ABCDEFGHIJKL/lab_iam"`) {
+		t.Errorf("policy %s does not allow connecting as lab_iam", policy)
+	}
+	assertString(t, m.inputs(t, "test-workload-simulator"), "iamInstanceProfile", "test-simulator-profile")
+}
+
+func TestLabSimulatorHostMetricsPolicy(t *testing.T) {
+	m, err := run(t, testSimulatorArgs(func(args *LabSimulatorHostArgs) {
+		args.MetricsNamespace = "AuroraLab/Simulator"
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policy := m.inputs(t, "test-simulator-metrics-policy")["policy"].StringValue()
+	if !strings.Contains(policy, `{"cloudwatch:namespace": "AuroraLab/Simulator"}`) {
+		t.Errorf("policy %s is not scoped to the simulator namespace", policy)
+	}
+}
+
+func TestLabSimulatorHostRegistryPolicy(t *testing.T) {
+	m, err := run(t, testSimulatorArgs(func(args *LabSimulatorHostArgs) {
+		args.ExperimentTable = "test-experiments"
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policy := m.inputs(t, "test-simulator-registry-policy")["policy"].StringValue()
+	if !strings.Contains(policy, `"arn:aws:dynamodb:us-east-1:*:table/test-experiments"`) {
+		t.Errorf("policy %s is not scoped to the experiment table", policy)
+	}
+	assertString(t, m.inputs(t, "test-workload-simulator"), "iamInstanceProfile", "test-simulator-profile")
+}
+
+func TestLabSimulatorHostLogs(t *testing.T) {
+	m, err := run(t, testSimulatorArgs(func(args *LabSimulatorHostArgs) {
+		args.Service = testService()
+		args.LogRetentionDays = 30
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logGroup := m.inputs(t, "test-simulator-logs")
+	assertString(t, logGroup, "name", "/test/simulator")
+	if days := logGroup["retentionInDays"].NumberValue(); days != 30 {
+		t.Errorf("retentionInDays: got %v, want 30", days)
+	}
+	if !m.registered("test-simulator-logs-policy") {
+		t.Error("simulator role cannot write to the log group")
+	}
+
+	userData, err := base64.StdEncoding.DecodeString(m.inputs(t, "test-workload-simulator")["userDataBase64"].StringValue())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"yum install -y amazon-cloudwatch-agent",
+		`"file_path": "/var/log/user-data.log"`,
+		`"file_path": "/var/log/workload-simulator/service.log"`,
+		`"log_group_name": "/test/simulator"`,
+		"systemctl enable --now workload-simulator-log.service",
+	} {
+		if !strings.Contains(string(userData), want) {
+			t.Errorf("user data does not contain %q", want)
+		}
+	}
+}
+
+func TestLabSimulatorHostGroupRequiresService(t *testing.T) {
+	_, err := run(t, testSimulatorArgs(func(args *LabSimulatorHostArgs) {
+		args.Count = 2
+	}))
+	if err == nil {
+		t.Fatal("expected an error for an Auto Scaling Group without the service")
+	}
+}
+
+func TestLabSimulatorHostSpotGroup(t *testing.T) {
+	m, err := run(t, testSimulatorArgs(func(args *LabSimulatorHostArgs) {
+		args.Count = 3
+		args.Service = testService()
+		args.UseSpot = true
+		args.OnDemandBaseCapacity = 1
+		args.SpotInstanceTypes = []string{"m5.xlarge"}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m.registered("test-workload-simulator") {
+		t.Error("single instance created in Auto Scaling Group mode")
+	}
+	group := m.inputs(t, "test-simulator-asg")
+	if size := group["desiredCapacity"].NumberValue(); size != 3 {
+		t.Errorf("desiredCapacity: got %v, want 3", size)
+	}
+	policy := group["mixedInstancesPolicy"].ObjectValue()
+	distribution := policy["instancesDistribution"].ObjectValue()
+	if base := distribution["onDemandBaseCapacity"].NumberValue(); base != 1 {
+		t.Errorf("onDemandBaseCapacity: got %v, want 1", base)
+	}
+	if overrides := policy["launchTemplate"].ObjectValue()["overrides"].ArrayValue(); len(overrides) != 2 {
+		t.Errorf("expected the instance type plus one Spot override, got %d", len(overrides))
+	}
+}
+
+func TestLabSimulatorHostGroupTags(t *testing.T) {
+	m, err := run(t, testSimulatorArgs(func(args *LabSimulatorHostArgs) {
+		args.Count = 2
+		args.Service = testService()
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Provider default tags do not reach the launched resources
+	specs := m.inputs(t, "test-simulator-lt")["tagSpecifications"].ArrayValue()
+	var resourceTypes []string
+	for _, spec := range specs {
+		spec := spec.ObjectValue()
+		resourceTypes = append(resourceTypes, spec["resourceType"].StringValue())
+		tags := spec["tags"].ObjectValue()
+		assertString(t, tags, "Project", "test")
+		assertString(t, tags, "Environment", "dev")
+		assertString(t, tags, "Owner", "jane")
+		assertString(t, tags, "Role", "workload-simulator")
+	}
+	if got := strings.Join(resourceTypes, ","); got != "instance,volume,network-interface" {
+		t.Errorf("tag specifications: got %s", got)
+	}
+
+	var groupTags []string
+	for _, tag := range m.inputs(t, "test-simulator-asg")["tags"].ArrayValue() {
+		groupTags = append(groupTags, tag.ObjectValue()["key"].StringValue())
+	}
+	if got := strings.Join(groupTags, ","); got != "Environment,Name,Owner,Project" {
+		t.Errorf("Auto Scaling Group tags: got %s", got)
+	}
+}
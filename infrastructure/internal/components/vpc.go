@@ -0,0 +1,450 @@
+package components
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/cloudwatch"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ec2"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/iam"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/s3"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"aurora-bluegreen-lab/internal/labels"
+)
+
+// LabVpcArgs configures a LabVpc.
+type LabVpcArgs struct {
+	Labels *labels.Labels
+	// CidrBlock is the VPC CIDR; the Aurora and EC2 subnets use fixed
+	// 10.0.x.0/24 ranges
+	CidrBlock string
+	// SecondaryCidrBlocks are associated with the VPC in addition to CidrBlock
+	SecondaryCidrBlocks []string
+	// EksSubnetCidrs places the two EKS subnets, e.g. in a secondary block;
+	// empty uses 10.0.20.0/24 and 10.0.21.0/24
+	EksSubnetCidrs []string
+	// AvailabilityZones needs at least two zones; the Aurora, EC2 and EKS
+	// subnets are spread across the first two
+	AvailabilityZones []string
+	// SshCidrBlock is allowed to reach the EC2 subnet over SSH
+	SshCidrBlock string
+	// StrictNetworkAcls restricts the Aurora subnets to MySQL from the EC2 and
+	// EKS subnets with a network ACL
+	StrictNetworkAcls bool
+	// FlowLogs records the VPC's traffic; nil disables flow logs
+	FlowLogs *FlowLogsArgs
+	// EnableIpv6 makes the VPC dual-stack: an Amazon-provided IPv6 block, an
+	// IPv6 /64 per subnet and an egress-only internet gateway for the private
+	// subnets
+	EnableIpv6 bool
+	// NatGateway routes the private subnets to the internet through a NAT
+	// gateway in the EC2 subnet
+	NatGateway bool
+	// Existing, when set, reuses a VPC and subnets created outside the lab
+	// instead of creating them; CidrBlock and AvailabilityZones are ignored
+	Existing *ExistingVpcArgs
+}
+
+// LabVpc is the lab network: a VPC with private Aurora and EKS subnets and
+// public EC2 subnets in two availability zones, and a security group per tier.
+// With an existing VPC, the VPC and subnets are read-only references and
+// there is no internet gateway or route table.
+type LabVpc struct {
+	pulumi.ResourceState
+
+	Vpc                       *ec2.Vpc
+	InternetGateway           *ec2.InternetGateway
+	EgressOnlyInternetGateway *ec2.EgressOnlyInternetGateway // nil without EnableIpv6
+	NatGateway                *ec2.NatGateway                // nil without NatGateway
+	NatEip                    *ec2.Eip                       // nil without NatGateway
+	SecondaryCidrBlocks       []*ec2.VpcIpv4CidrBlockAssociation
+	AuroraSubnets             []*ec2.Subnet
+	Ec2Subnet                 *ec2.Subnet
+	Ec2Subnet2                *ec2.Subnet // nil with an existing VPC
+	EksSubnets                []*ec2.Subnet
+	PublicRouteTable          *ec2.RouteTable
+	PrivateRouteTable         *ec2.RouteTable
+
+	AuroraSecurityGroup          *ec2.SecurityGroup
+	Ec2SecurityGroup             *ec2.SecurityGroup
+	EksSecurityGroup             *ec2.SecurityGroup
+	InstanceConnectSecurityGroup *ec2.SecurityGroup
+
+	AuroraNetworkAcl *ec2.NetworkAcl // nil without StrictNetworkAcls
+
+	FlowLog       *ec2.FlowLog         // nil without FlowLogs
+	FlowLogGroup  *cloudwatch.LogGroup // nil unless FlowLogs go to CloudWatch
+	FlowLogRole   *iam.Role            // nil unless FlowLogs go to CloudWatch
+	FlowLogBucket *s3.BucketV2         // nil unless FlowLogs go to S3
+}
+
+// NewLabVpc creates the lab network, or reads the Existing one, and adds the
+// security groups.
+func NewLabVpc(ctx *pulumi.Context, name string, args *LabVpcArgs, opts ...pulumi.ResourceOption) (*LabVpc, error) {
+	if args.Existing != nil {
+		if err := args.Existing.check(); err != nil {
+			return nil, err
+		}
+		if args.StrictNetworkAcls || args.EnableIpv6 || args.NatGateway {
+			return nil, fmt.Errorf("StrictNetworkAcls, EnableIpv6 and NatGateway cannot change an existing VPC")
+		}
+	} else if len(args.AvailabilityZones) < 2 {
+		// Ensure we have at least 2 AZs
+		return nil, fmt.Errorf("need at least 2 availability zones")
+	}
+
+	c := &LabVpc{}
+	err := ctx.RegisterComponentResource(typePrefix+"LabVpc", name, c, opts...)
+	if err != nil {
+		return nil, err
+	}
+	lb := args.Labels
+
+	if args.Existing != nil {
+		err = c.readExistingNetwork(ctx, lb, args.Existing)
+	} else {
+		err = c.newNetwork(ctx, args)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Create Security Group for Aurora; its MySQL ingress rules reference the
+	// EC2 and EKS security groups below
+	c.AuroraSecurityGroup, err = ec2.NewSecurityGroup(ctx, lb.Name("aurora-sg"), &ec2.SecurityGroupArgs{
+		VpcId:       c.Vpc.ID(),
+		Description: pulumi.String("Security group for Aurora MySQL cluster"),
+		Egress:      allOutbound(args.EnableIpv6),
+		Tags:        lb.Tags(lb.Name("aurora-sg")),
+	}, childOptions(c)...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create Security Group for an EC2 Instance Connect Endpoint (the access
+	// stack), so the simulator can be reached over SSH without a public IP
+	c.InstanceConnectSecurityGroup, err = ec2.NewSecurityGroup(ctx, lb.Name("instance-connect-sg"), &ec2.SecurityGroupArgs{
+		VpcId:       c.Vpc.ID(),
+		Description: pulumi.String("Security group for the EC2 Instance Connect Endpoint"),
+		Egress: ec2.SecurityGroupEgressArray{
+			&ec2.SecurityGroupEgressArgs{
+				Protocol:    pulumi.String("tcp"),
+				FromPort:    pulumi.Int(22),
+				ToPort:      pulumi.Int(22),
+				CidrBlocks:  pulumi.StringArray{c.Vpc.CidrBlock},
+				Description: pulumi.String("SSH to instances in the VPC"),
+			},
+		},
+		Tags: lb.Tags(lb.Name("instance-connect-sg")),
+	}, childOptions(c)...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create Security Group for EC2; its ingress rules are inline, so other
+	// stacks cannot add to them
+	c.Ec2SecurityGroup, err = ec2.NewSecurityGroup(ctx, lb.Name("ec2-sg"), &ec2.SecurityGroupArgs{
+		VpcId:       c.Vpc.ID(),
+		Description: pulumi.String("Security group for EC2 workload simulator"),
+		Ingress: ec2.SecurityGroupIngressArray{
+			&ec2.SecurityGroupIngressArgs{
+				Protocol:    pulumi.String("tcp"),
+				FromPort:    pulumi.Int(22),
+				ToPort:      pulumi.Int(22),
+				CidrBlocks:  pulumi.StringArray{pulumi.String(args.SshCidrBlock)},
+				Description: pulumi.String("SSH access"),
+			},
+			&ec2.SecurityGroupIngressArgs{
+				Protocol:       pulumi.String("tcp"),
+				FromPort:       pulumi.Int(22),
+				ToPort:         pulumi.Int(22),
+				SecurityGroups: pulumi.StringArray{c.InstanceConnectSecurityGroup.ID()},
+				Description:    pulumi.String("SSH through the EC2 Instance Connect Endpoint"),
+			},
+		},
+		Egress: allOutbound(args.EnableIpv6),
+		Tags:   lb.Tags(lb.Name("ec2-sg")),
+	}, childOptions(c)...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create Security Group for EKS
+	c.EksSecurityGroup, err = ec2.NewSecurityGroup(ctx, lb.Name("eks-sg"), &ec2.SecurityGroupArgs{
+		VpcId:       c.Vpc.ID(),
+		Description: pulumi.String("Security group for EKS cluster nodes"),
+		Egress:      allOutbound(args.EnableIpv6),
+		Tags:        lb.Tags(lb.Name("eks-sg")),
+	}, childOptions(c)...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Allow EKS nodes to communicate with each other
+	_, err = ec2.NewSecurityGroupRule(ctx, lb.Name("eks-self-ingress"), &ec2.SecurityGroupRuleArgs{
+		Type:                  pulumi.String("ingress"),
+		FromPort:              pulumi.Int(0),
+		ToPort:                pulumi.Int(65535),
+		Protocol:              pulumi.String("-1"),
+		SourceSecurityGroupId: c.EksSecurityGroup.ID(),
+		SecurityGroupId:       c.EksSecurityGroup.ID(),
+		Description:           pulumi.String("Allow nodes to communicate with each other"),
+	}, childOptions(c)...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Allow MySQL from the EC2 and EKS security groups, so the rules follow
+	// the instances and nodes whatever subnet addressing they use
+	for _, source := range []struct {
+		tier  string
+		group *ec2.SecurityGroup
+	}{
+		{tier: "ec2", group: c.Ec2SecurityGroup},
+		{tier: "eks", group: c.EksSecurityGroup},
+	} {
+		_, err = ec2.NewSecurityGroupRule(ctx, lb.Name("aurora-mysql-from-"+source.tier), &ec2.SecurityGroupRuleArgs{
+			Type:                  pulumi.String("ingress"),
+			FromPort:              pulumi.Int(3306),
+			ToPort:                pulumi.Int(3306),
+			Protocol:              pulumi.String("tcp"),
+			SourceSecurityGroupId: source.group.ID(),
+			SecurityGroupId:       c.AuroraSecurityGroup.ID(),
+			Description:           pulumi.String(fmt.Sprintf("MySQL access from the %s security group", strings.ToUpper(source.tier))),
+		}, childOptions(c)...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if args.StrictNetworkAcls {
+		if err := c.newAuroraNetworkAcl(ctx, args); err != nil {
+			return nil, err
+		}
+	}
+
+	if args.FlowLogs != nil {
+		if err := c.newFlowLogs(ctx, lb, args.FlowLogs); err != nil {
+			return nil, err
+		}
+	}
+
+	err = ctx.RegisterResourceOutputs(c, pulumi.Map{
+		"vpcId":                 c.Vpc.ID(),
+		"auroraSecurityGroupId": c.AuroraSecurityGroup.ID(),
+		"ec2SecurityGroupId":    c.Ec2SecurityGroup.ID(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// newNetwork creates the VPC, its subnets and route tables.
+func (c *LabVpc) newNetwork(ctx *pulumi.Context, args *LabVpcArgs) error {
+	lb := args.Labels
+	var err error
+
+	// Create VPC
+	c.Vpc, err = ec2.NewVpc(ctx, lb.Name("vpc"), &ec2.VpcArgs{
+		CidrBlock:                    pulumi.String(args.CidrBlock),
+		EnableDnsHostnames:           pulumi.Bool(true),
+		EnableDnsSupport:             pulumi.Bool(true),
+		AssignGeneratedIpv6CidrBlock: pulumi.Bool(args.EnableIpv6),
+		Tags:                         lb.Tags(lb.Name("vpc")),
+	}, childOptions(c)...)
+	if err != nil {
+		return err
+	}
+
+	// Associate the secondary CIDR blocks; subnets in them wait for the
+	// association
+	var secondaryBlocks []pulumi.Resource
+	for i, block := range args.SecondaryCidrBlocks {
+		association, err := ec2.NewVpcIpv4CidrBlockAssociation(ctx, lb.Name(fmt.Sprintf("secondary-cidr-%d", i+1)), &ec2.VpcIpv4CidrBlockAssociationArgs{
+			VpcId:     c.Vpc.ID(),
+			CidrBlock: pulumi.String(block),
+		}, childOptions(c)...)
+		if err != nil {
+			return err
+		}
+		c.SecondaryCidrBlocks = append(c.SecondaryCidrBlocks, association)
+		secondaryBlocks = append(secondaryBlocks, association)
+	}
+
+	// Create Internet Gateway for public subnet
+	c.InternetGateway, err = ec2.NewInternetGateway(ctx, lb.Name("igw"), &ec2.InternetGatewayArgs{
+		VpcId: c.Vpc.ID(),
+		Tags:  lb.Tags(lb.Name("igw")),
+	}, childOptions(c)...)
+	if err != nil {
+		return err
+	}
+
+	// Create Aurora Private Subnets (2 AZs)
+	auroraSubnet1, err := ec2.NewSubnet(ctx, lb.Name("aurora-subnet-1"), c.dualStack(args, &ec2.SubnetArgs{
+		VpcId:            c.Vpc.ID(),
+		CidrBlock:        pulumi.String("10.0.1.0/24"),
+		AvailabilityZone: pulumi.String(args.AvailabilityZones[0]),
+		Tags:             lb.Tags(lb.Name("aurora-private-subnet-az1"), labels.Type("private-aurora")),
+	}, 1), childOptions(c)...)
+	if err != nil {
+		return err
+	}
+
+	auroraSubnet2, err := ec2.NewSubnet(ctx, lb.Name("aurora-subnet-2"), c.dualStack(args, &ec2.SubnetArgs{
+		VpcId:            c.Vpc.ID(),
+		CidrBlock:        pulumi.String("10.0.2.0/24"),
+		AvailabilityZone: pulumi.String(args.AvailabilityZones[1]),
+		Tags:             lb.Tags(lb.Name("aurora-private-subnet-az2"), labels.Type("private-aurora")),
+	}, 2), childOptions(c)...)
+	if err != nil {
+		return err
+	}
+
+	// Create EC2 Public Subnets (2 AZs); the second spreads additional
+	// simulator instances to the second zone
+	c.Ec2Subnet, err = ec2.NewSubnet(ctx, lb.Name("ec2-subnet"), c.dualStack(args, &ec2.SubnetArgs{
+		VpcId:               c.Vpc.ID(),
+		CidrBlock:           pulumi.String("10.0.10.0/24"),
+		AvailabilityZone:    pulumi.String(args.AvailabilityZones[0]),
+		MapPublicIpOnLaunch: pulumi.Bool(true),
+		Tags:                lb.Tags(lb.Name("ec2-public-subnet-az1"), labels.Type("public-ec2")),
+	}, 10), childOptions(c)...)
+	if err != nil {
+		return err
+	}
+
+	c.Ec2Subnet2, err = ec2.NewSubnet(ctx, lb.Name("ec2-subnet-2"), c.dualStack(args, &ec2.SubnetArgs{
+		VpcId:               c.Vpc.ID(),
+		CidrBlock:           pulumi.String("10.0.11.0/24"),
+		AvailabilityZone:    pulumi.String(args.AvailabilityZones[1]),
+		MapPublicIpOnLaunch: pulumi.Bool(true),
+		Tags:                lb.Tags(lb.Name("ec2-public-subnet-az2"), labels.Type("public-ec2")),
+	}, 11), childOptions(c)...)
+	if err != nil {
+		return err
+	}
+
+	// Create EKS Private Subnets (2 AZs) - Optional
+	eksCidrs := args.EksSubnetCidrs
+	if len(eksCidrs) == 0 {
+		eksCidrs = []string{"10.0.20.0/24", "10.0.21.0/24"}
+	}
+	eksSubnet1, err := ec2.NewSubnet(ctx, lb.Name("eks-subnet-1"), c.dualStack(args, &ec2.SubnetArgs{
+		VpcId:            c.Vpc.ID(),
+		CidrBlock:        pulumi.String(eksCidrs[0]),
+		AvailabilityZone: pulumi.String(args.AvailabilityZones[0]),
+		Tags:             lb.Tags(lb.Name("eks-private-subnet-az1"), labels.Type("private-eks")),
+	}, 20), childOptions(c, pulumi.DependsOn(secondaryBlocks))...)
+	if err != nil {
+		return err
+	}
+
+	eksSubnet2, err := ec2.NewSubnet(ctx, lb.Name("eks-subnet-2"), c.dualStack(args, &ec2.SubnetArgs{
+		VpcId:            c.Vpc.ID(),
+		CidrBlock:        pulumi.String(eksCidrs[1]),
+		AvailabilityZone: pulumi.String(args.AvailabilityZones[1]),
+		Tags:             lb.Tags(lb.Name("eks-private-subnet-az2"), labels.Type("private-eks")),
+	}, 21), childOptions(c, pulumi.DependsOn(secondaryBlocks))...)
+	if err != nil {
+		return err
+	}
+
+	// Create Route Table for Public Subnet
+	c.PublicRouteTable, err = ec2.NewRouteTable(ctx, lb.Name("public-rt"), &ec2.RouteTableArgs{
+		VpcId: c.Vpc.ID(),
+		Tags:  lb.Tags(lb.Name("public-route-table")),
+	}, childOptions(c)...)
+	if err != nil {
+		return err
+	}
+
+	// Add route to Internet Gateway
+	_, err = ec2.NewRoute(ctx, lb.Name("public-route"), &ec2.RouteArgs{
+		RouteTableId:         c.PublicRouteTable.ID(),
+		DestinationCidrBlock: pulumi.String("0.0.0.0/0"),
+		GatewayId:            c.InternetGateway.ID(),
+	}, childOptions(c)...)
+	if err != nil {
+		return err
+	}
+
+	// Associate public route table with EC2 subnets
+	_, err = ec2.NewRouteTableAssociation(ctx, lb.Name("ec2-rt-assoc"), &ec2.RouteTableAssociationArgs{
+		SubnetId:     c.Ec2Subnet.ID(),
+		RouteTableId: c.PublicRouteTable.ID(),
+	}, childOptions(c)...)
+	if err != nil {
+		return err
+	}
+
+	_, err = ec2.NewRouteTableAssociation(ctx, lb.Name("ec2-rt-assoc-2"), &ec2.RouteTableAssociationArgs{
+		SubnetId:     c.Ec2Subnet2.ID(),
+		RouteTableId: c.PublicRouteTable.ID(),
+	}, childOptions(c)...)
+	if err != nil {
+		return err
+	}
+
+	// Create Route Table for Private Subnets (Aurora and EKS)
+	c.PrivateRouteTable, err = ec2.NewRouteTable(ctx, lb.Name("private-rt"), &ec2.RouteTableArgs{
+		VpcId: c.Vpc.ID(),
+		Tags:  lb.Tags(lb.Name("private-route-table")),
+	}, childOptions(c)...)
+	if err != nil {
+		return err
+	}
+
+	// Associate private route table with Aurora subnets
+	_, err = ec2.NewRouteTableAssociation(ctx, lb.Name("aurora-rt-assoc-1"), &ec2.RouteTableAssociationArgs{
+		SubnetId:     auroraSubnet1.ID(),
+		RouteTableId: c.PrivateRouteTable.ID(),
+	}, childOptions(c)...)
+	if err != nil {
+		return err
+	}
+
+	_, err = ec2.NewRouteTableAssociation(ctx, lb.Name("aurora-rt-assoc-2"), &ec2.RouteTableAssociationArgs{
+		SubnetId:     auroraSubnet2.ID(),
+		RouteTableId: c.PrivateRouteTable.ID(),
+	}, childOptions(c)...)
+	if err != nil {
+		return err
+	}
+
+	// Associate private route table with EKS subnets
+	_, err = ec2.NewRouteTableAssociation(ctx, lb.Name("eks-rt-assoc-1"), &ec2.RouteTableAssociationArgs{
+		SubnetId:     eksSubnet1.ID(),
+		RouteTableId: c.PrivateRouteTable.ID(),
+	}, childOptions(c)...)
+	if err != nil {
+		return err
+	}
+
+	_, err = ec2.NewRouteTableAssociation(ctx, lb.Name("eks-rt-assoc-2"), &ec2.RouteTableAssociationArgs{
+		SubnetId:     eksSubnet2.ID(),
+		RouteTableId: c.PrivateRouteTable.ID(),
+	}, childOptions(c)...)
+	if err != nil {
+		return err
+	}
+
+	if args.EnableIpv6 {
+		if err := c.newIpv6Routes(ctx, lb); err != nil {
+			return err
+		}
+	}
+
+	if args.NatGateway {
+		if err := c.newNatGateway(ctx, lb); err != nil {
+			return err
+		}
+	}
+
+	c.AuroraSubnets = []*ec2.Subnet{auroraSubnet1, auroraSubnet2}
+	c.EksSubnets = []*ec2.Subnet{eksSubnet1, eksSubnet2}
+	return nil
+}
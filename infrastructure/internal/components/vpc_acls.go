@@ -0,0 +1,74 @@
+package components
+
+import (
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ec2"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// newAuroraNetworkAcl isolates the Aurora subnets with a network ACL that
+// only admits MySQL from the EC2 and EKS subnets, over IPv6 too when it is
+// enabled. Network ACLs are stateless, so the responses to the clients'
+// ephemeral ports are allowed out explicitly; everything else is denied by
+// the ACL's implicit final rule, underneath the security groups.
+func (c *LabVpc) newAuroraNetworkAcl(ctx *pulumi.Context, args *LabVpcArgs) error {
+	lb := args.Labels
+
+	var ingress ec2.NetworkAclIngressArray
+	var egress ec2.NetworkAclEgressArray
+	// The second EC2 subnet comes last, keeping the rule numbers of the
+	// others
+	clients := append([]*ec2.Subnet{c.Ec2Subnet}, c.EksSubnets...)
+	if c.Ec2Subnet2 != nil {
+		clients = append(clients, c.Ec2Subnet2)
+	}
+	for i, client := range clients {
+		ruleNo := pulumi.Int(100 + 10*i)
+		ingress = append(ingress, &ec2.NetworkAclIngressArgs{
+			RuleNo:    ruleNo,
+			Action:    pulumi.String("allow"),
+			Protocol:  pulumi.String("tcp"),
+			CidrBlock: client.CidrBlock,
+			FromPort:  pulumi.Int(3306),
+			ToPort:    pulumi.Int(3306),
+		})
+		// Linux clients use ports 32768-60999 and NAT devices 1024-65535
+		egress = append(egress, &ec2.NetworkAclEgressArgs{
+			RuleNo:    ruleNo,
+			Action:    pulumi.String("allow"),
+			Protocol:  pulumi.String("tcp"),
+			CidrBlock: client.CidrBlock,
+			FromPort:  pulumi.Int(1024),
+			ToPort:    pulumi.Int(65535),
+		})
+		if !args.EnableIpv6 {
+			continue
+		}
+		ipv6RuleNo := pulumi.Int(200 + 10*i)
+		ingress = append(ingress, &ec2.NetworkAclIngressArgs{
+			RuleNo:        ipv6RuleNo,
+			Action:        pulumi.String("allow"),
+			Protocol:      pulumi.String("tcp"),
+			Ipv6CidrBlock: client.Ipv6CidrBlock,
+			FromPort:      pulumi.Int(3306),
+			ToPort:        pulumi.Int(3306),
+		})
+		egress = append(egress, &ec2.NetworkAclEgressArgs{
+			RuleNo:        ipv6RuleNo,
+			Action:        pulumi.String("allow"),
+			Protocol:      pulumi.String("tcp"),
+			Ipv6CidrBlock: client.Ipv6CidrBlock,
+			FromPort:      pulumi.Int(1024),
+			ToPort:        pulumi.Int(65535),
+		})
+	}
+
+	var err error
+	c.AuroraNetworkAcl, err = ec2.NewNetworkAcl(ctx, lb.Name("aurora-nacl"), &ec2.NetworkAclArgs{
+		VpcId:     c.Vpc.ID(),
+		SubnetIds: pulumi.StringArray{c.AuroraSubnets[0].ID(), c.AuroraSubnets[1].ID()},
+		Ingress:   ingress,
+		Egress:    egress,
+		Tags:      lb.Tags(lb.Name("aurora-nacl")),
+	}, childOptions(c)...)
+	return err
+}
@@ -0,0 +1,95 @@
+package components
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ec2"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"aurora-bluegreen-lab/internal/labels"
+)
+
+// ExistingVpcArgs describes a VPC created outside the lab, as looked up by
+// the stack.
+type ExistingVpcArgs struct {
+	VpcId string
+	// EnableDnsSupport and EnableDnsHostnames are the VPC's DNS attributes;
+	// the Aurora endpoints need both
+	EnableDnsSupport   bool
+	EnableDnsHostnames bool
+	// AuroraSubnets are two private subnets in different availability zones
+	AuroraSubnets []ExistingSubnet
+	// Ec2Subnet is a public subnet routed to an internet gateway
+	Ec2Subnet ExistingSubnet
+	// EksSubnets are optional, two subnets in different availability zones
+	EksSubnets []ExistingSubnet
+}
+
+// ExistingSubnet is a subnet of an existing VPC.
+type ExistingSubnet struct {
+	Id               string
+	VpcId            string
+	AvailabilityZone string
+}
+
+// check returns every reason the VPC cannot host the lab.
+func (e *ExistingVpcArgs) check() error {
+	var problems []error
+	if !e.EnableDnsSupport || !e.EnableDnsHostnames {
+		problems = append(problems, fmt.Errorf("VPC %s needs DNS resolution and DNS hostnames enabled for the Aurora endpoints", e.VpcId))
+	}
+	for _, subnet := range append(append([]ExistingSubnet{e.Ec2Subnet}, e.AuroraSubnets...), e.EksSubnets...) {
+		if subnet.VpcId != e.VpcId {
+			problems = append(problems, fmt.Errorf("subnet %s is in VPC %s, not %s", subnet.Id, subnet.VpcId, e.VpcId))
+		}
+	}
+	for _, tier := range []struct {
+		name     string
+		subnets  []ExistingSubnet
+		optional bool
+	}{
+		{name: "Aurora", subnets: e.AuroraSubnets},
+		{name: "EKS", subnets: e.EksSubnets, optional: true},
+	} {
+		if tier.optional && len(tier.subnets) == 0 {
+			continue
+		}
+		if len(tier.subnets) != 2 || tier.subnets[0].AvailabilityZone == tier.subnets[1].AvailabilityZone {
+			problems = append(problems, fmt.Errorf("the %s subnets must be two subnets in different availability zones", tier.name))
+		}
+	}
+	return errors.Join(problems...)
+}
+
+// readExistingNetwork reads the existing VPC and subnets into the component,
+// so the stack exports them like a network it created. Pulumi refreshes them
+// on every update but never modifies or deletes them.
+func (c *LabVpc) readExistingNetwork(ctx *pulumi.Context, lb *labels.Labels, existing *ExistingVpcArgs) error {
+	var err error
+	c.Vpc, err = ec2.GetVpc(ctx, lb.Name("existing-vpc"), pulumi.ID(existing.VpcId), nil, childOptions(c)...)
+	if err != nil {
+		return err
+	}
+
+	readSubnets := func(name string, subnets []ExistingSubnet) ([]*ec2.Subnet, error) {
+		var result []*ec2.Subnet
+		for i, subnet := range subnets {
+			s, err := ec2.GetSubnet(ctx, lb.Name(fmt.Sprintf("existing-%s-subnet-%d", name, i+1)), pulumi.ID(subnet.Id), nil, childOptions(c)...)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, s)
+		}
+		return result, nil
+	}
+
+	if c.AuroraSubnets, err = readSubnets("aurora", existing.AuroraSubnets); err != nil {
+		return err
+	}
+	if c.EksSubnets, err = readSubnets("eks", existing.EksSubnets); err != nil {
+		return err
+	}
+	c.Ec2Subnet, err = ec2.GetSubnet(ctx, lb.Name("existing-ec2-subnet"), pulumi.ID(existing.Ec2Subnet.Id), nil, childOptions(c)...)
+	return err
+}
@@ -0,0 +1,190 @@
+package components
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/cloudwatch"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ec2"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/iam"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/s3"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"aurora-bluegreen-lab/internal/labels"
+)
+
+// flowLogFormat adds the subnet, instance, TCP flags and flow direction to
+// the default fields, so the resets around a switchover show up as flows
+// between the clients and the Aurora subnets with the RST bit (4) set.
+const flowLogFormat = "${version} ${account-id} ${interface-id} ${srcaddr} ${dstaddr} ${srcport} ${dstport} " +
+	"${protocol} ${packets} ${bytes} ${start} ${end} ${action} ${log-status} " +
+	"${vpc-id} ${subnet-id} ${instance-id} ${tcp-flags} ${flow-direction}"
+
+// FlowLogsArgs configures the VPC Flow Logs of a LabVpc.
+type FlowLogsArgs struct {
+	// Destination is "cloudwatch" for a CloudWatch Logs group or "s3" for a
+	// private bucket
+	Destination string
+	// TrafficType is ALL, ACCEPT or REJECT
+	TrafficType string
+	// RetentionDays is the log group retention, or the age at which the S3
+	// objects expire
+	RetentionDays int
+}
+
+// newFlowLogs records the VPC's traffic to a CloudWatch Logs group or an S3
+// bucket. Flows are aggregated over a minute, the shortest interval, so they
+// line up with the switchover timeline.
+func (c *LabVpc) newFlowLogs(ctx *pulumi.Context, lb *labels.Labels, args *FlowLogsArgs) error {
+	flowLogArgs := &ec2.FlowLogArgs{
+		VpcId:                  c.Vpc.ID(),
+		TrafficType:            pulumi.String(args.TrafficType),
+		LogFormat:              pulumi.String(flowLogFormat),
+		MaxAggregationInterval: pulumi.Int(60),
+		Tags:                   lb.Tags(lb.Name("vpc-flow-log")),
+	}
+	var dependsOn []pulumi.Resource
+
+	switch args.Destination {
+	case "cloudwatch":
+		if err := c.newFlowLogGroup(ctx, lb, args.RetentionDays); err != nil {
+			return err
+		}
+		flowLogArgs.LogDestinationType = pulumi.String("cloud-watch-logs")
+		flowLogArgs.LogDestination = c.FlowLogGroup.Arn
+		flowLogArgs.IamRoleArn = c.FlowLogRole.Arn
+	case "s3":
+		policy, err := c.newFlowLogBucket(ctx, lb, args.RetentionDays)
+		if err != nil {
+			return err
+		}
+		flowLogArgs.LogDestinationType = pulumi.String("s3")
+		flowLogArgs.LogDestination = c.FlowLogBucket.Arn
+		// The log delivery service checks its access when the flow log is created
+		dependsOn = append(dependsOn, policy)
+	default:
+		return fmt.Errorf("unsupported flow log destination %q", args.Destination)
+	}
+
+	var err error
+	c.FlowLog, err = ec2.NewFlowLog(ctx, lb.Name("vpc-flow-log"), flowLogArgs,
+		childOptions(c, pulumi.DependsOn(dependsOn))...)
+	return err
+}
+
+// newFlowLogGroup creates the flow log group and the role the flow logs
+// service assumes to write to it.
+func (c *LabVpc) newFlowLogGroup(ctx *pulumi.Context, lb *labels.Labels, retentionDays int) error {
+	var err error
+	c.FlowLogGroup, err = cloudwatch.NewLogGroup(ctx, lb.Name("vpc-flow-logs"), &cloudwatch.LogGroupArgs{
+		Name:            pulumi.String(fmt.Sprintf("/%s/vpc-flow-logs", lb.ProjectName)),
+		RetentionInDays: pulumi.Int(retentionDays),
+		Tags:            lb.Tags(lb.Name("vpc-flow-logs")),
+	}, childOptions(c)...)
+	if err != nil {
+		return err
+	}
+
+	c.FlowLogRole, err = iam.NewRole(ctx, lb.Name("vpc-flow-logs-role"), &iam.RoleArgs{
+		Name: pulumi.String(lb.Name("vpc-flow-logs-role")),
+		AssumeRolePolicy: pulumi.String(`{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Principal": {"Service": "vpc-flow-logs.amazonaws.com"},
+      "Action": "sts:AssumeRole"
+    }
+  ]
+}`),
+		Tags: lb.Tags(lb.Name("vpc-flow-logs-role")),
+	}, childOptions(c)...)
+	if err != nil {
+		return err
+	}
+
+	// The service creates one stream per network interface
+	_, err = iam.NewRolePolicy(ctx, lb.Name("vpc-flow-logs-policy"), &iam.RolePolicyArgs{
+		Role: c.FlowLogRole.ID(),
+		Policy: pulumi.Sprintf(`{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Action": ["logs:CreateLogStream", "logs:PutLogEvents", "logs:DescribeLogGroups", "logs:DescribeLogStreams"],
+      "Resource": ["%s", "%s:*"]
+    }
+  ]
+}`, c.FlowLogGroup.Arn, c.FlowLogGroup.Arn),
+	}, childOptions(c)...)
+	return err
+}
+
+// newFlowLogBucket creates the private flow log bucket, whose objects expire
+// after retentionDays, and returns the bucket policy allowing the log
+// delivery service to write to it.
+func (c *LabVpc) newFlowLogBucket(ctx *pulumi.Context, lb *labels.Labels, retentionDays int) (*s3.BucketPolicy, error) {
+	var err error
+	c.FlowLogBucket, err = s3.NewBucketV2(ctx, lb.Name("vpc-flow-logs"), &s3.BucketV2Args{
+		BucketPrefix: pulumi.String(lb.Name("vpc-flow-logs-")),
+		// Lab flow logs are deleted with the stack
+		ForceDestroy: pulumi.Bool(true),
+		Tags:         lb.Tags(lb.Name("vpc-flow-logs")),
+	}, childOptions(c)...)
+	if err != nil {
+		return nil, err
+	}
+
+	publicAccessBlock, err := s3.NewBucketPublicAccessBlock(ctx, lb.Name("vpc-flow-logs-public-access-block"), &s3.BucketPublicAccessBlockArgs{
+		Bucket:                c.FlowLogBucket.ID(),
+		BlockPublicAcls:       pulumi.Bool(true),
+		BlockPublicPolicy:     pulumi.Bool(true),
+		IgnorePublicAcls:      pulumi.Bool(true),
+		RestrictPublicBuckets: pulumi.Bool(true),
+	}, childOptions(c)...)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s3.NewBucketLifecycleConfigurationV2(ctx, lb.Name("vpc-flow-logs-lifecycle"), &s3.BucketLifecycleConfigurationV2Args{
+		Bucket: c.FlowLogBucket.ID(),
+		Rules: s3.BucketLifecycleConfigurationV2RuleArray{
+			&s3.BucketLifecycleConfigurationV2RuleArgs{
+				Id:     pulumi.String("expire-flow-logs"),
+				Status: pulumi.String("Enabled"),
+				Filter: &s3.BucketLifecycleConfigurationV2RuleFilterArgs{},
+				Expiration: &s3.BucketLifecycleConfigurationV2RuleExpirationArgs{
+					Days: pulumi.Float64(float64(retentionDays)),
+				},
+			},
+		},
+	}, childOptions(c)...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without an explicit policy the service adds its own on first delivery,
+	// which the next pulumi up would not know about
+	return s3.NewBucketPolicy(ctx, lb.Name("vpc-flow-logs-policy"), &s3.BucketPolicyArgs{
+		Bucket: c.FlowLogBucket.ID(),
+		Policy: pulumi.Sprintf(`{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Sid": "AWSLogDeliveryWrite",
+      "Effect": "Allow",
+      "Principal": {"Service": "delivery.logs.amazonaws.com"},
+      "Action": "s3:PutObject",
+      "Resource": "%s/AWSLogs/*",
+      "Condition": {"StringEquals": {"s3:x-amz-acl": "bucket-owner-full-control"}}
+    },
+    {
+      "Sid": "AWSLogDeliveryAclCheck",
+      "Effect": "Allow",
+      "Principal": {"Service": "delivery.logs.amazonaws.com"},
+      "Action": ["s3:GetBucketAcl", "s3:ListBucket"],
+      "Resource": "%s"
+    }
+  ]
+}`, c.FlowLogBucket.Arn, c.FlowLogBucket.Arn),
+	}, childOptions(c, pulumi.DependsOn([]pulumi.Resource{publicAccessBlock}))...)
+}
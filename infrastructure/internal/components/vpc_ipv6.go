@@ -0,0 +1,86 @@
+package components
+
+import (
+	"fmt"
+	"net/netip"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ec2"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"aurora-bluegreen-lab/internal/labels"
+)
+
+// dualStack gives subnet the IPv6 /64 numbered netNum within the VPC's
+// Amazon-provided /56 when IPv6 is enabled. The lab subnets use the third
+// octet of their default IPv4 range as netNum, so the first EKS subnet
+// (10.0.20.0/24) gets <prefix>:xx14::/64 wherever it is placed.
+func (c *LabVpc) dualStack(args *LabVpcArgs, subnet *ec2.SubnetArgs, netNum int) *ec2.SubnetArgs {
+	if !args.EnableIpv6 {
+		return subnet
+	}
+	subnet.Ipv6CidrBlock = c.Vpc.Ipv6CidrBlock.ApplyT(func(vpcBlock string) (string, error) {
+		return ipv6SubnetCidr(vpcBlock, netNum)
+	}).(pulumi.StringOutput)
+	subnet.AssignIpv6AddressOnCreation = pulumi.Bool(true)
+	return subnet
+}
+
+// ipv6SubnetCidr returns the /64 numbered netNum within the /56 vpcBlock.
+func ipv6SubnetCidr(vpcBlock string, netNum int) (string, error) {
+	prefix, err := netip.ParsePrefix(vpcBlock)
+	if err != nil || !prefix.Addr().Is6() || prefix.Bits() != 56 {
+		return "", fmt.Errorf("VPC IPv6 CIDR block %q is not a /56", vpcBlock)
+	}
+	if netNum < 0 || netNum > 255 {
+		return "", fmt.Errorf("IPv6 subnet number %d is outside the /56", netNum)
+	}
+	addr := prefix.Masked().Addr().As16()
+	addr[7] = byte(netNum)
+	return netip.PrefixFrom(netip.AddrFrom16(addr), 64).String(), nil
+}
+
+// newIpv6Routes routes IPv6 traffic of the public subnet through the
+// internet gateway and of the private subnets through an egress-only
+// internet gateway, which lets them reach out over IPv6 but accepts no
+// connections from the internet.
+func (c *LabVpc) newIpv6Routes(ctx *pulumi.Context, lb *labels.Labels) error {
+	var err error
+	c.EgressOnlyInternetGateway, err = ec2.NewEgressOnlyInternetGateway(ctx, lb.Name("eigw"), &ec2.EgressOnlyInternetGatewayArgs{
+		VpcId: c.Vpc.ID(),
+		Tags:  lb.Tags(lb.Name("eigw")),
+	}, childOptions(c)...)
+	if err != nil {
+		return err
+	}
+
+	_, err = ec2.NewRoute(ctx, lb.Name("public-route-ipv6"), &ec2.RouteArgs{
+		RouteTableId:             c.PublicRouteTable.ID(),
+		DestinationIpv6CidrBlock: pulumi.String("::/0"),
+		GatewayId:                c.InternetGateway.ID(),
+	}, childOptions(c)...)
+	if err != nil {
+		return err
+	}
+
+	_, err = ec2.NewRoute(ctx, lb.Name("private-route-ipv6"), &ec2.RouteArgs{
+		RouteTableId:             c.PrivateRouteTable.ID(),
+		DestinationIpv6CidrBlock: pulumi.String("::/0"),
+		EgressOnlyGatewayId:      c.EgressOnlyInternetGateway.ID(),
+	}, childOptions(c)...)
+	return err
+}
+
+// allOutbound is the egress rule of the lab security groups: all traffic to
+// anywhere, over IPv6 too when it is enabled.
+func allOutbound(ipv6 bool) ec2.SecurityGroupEgressArray {
+	rule := &ec2.SecurityGroupEgressArgs{
+		Protocol:   pulumi.String("-1"),
+		FromPort:   pulumi.Int(0),
+		ToPort:     pulumi.Int(0),
+		CidrBlocks: pulumi.StringArray{pulumi.String("0.0.0.0/0")},
+	}
+	if ipv6 {
+		rule.Ipv6CidrBlocks = pulumi.StringArray{pulumi.String("::/0")}
+	}
+	return ec2.SecurityGroupEgressArray{rule}
+}
@@ -0,0 +1,40 @@
+package components
+
+import (
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ec2"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"aurora-bluegreen-lab/internal/labels"
+)
+
+// newNatGateway routes IPv4 traffic of the private subnets to the internet
+// through a NAT gateway in the public EC2 subnet, so instances without a
+// public IP can install packages and reach SSM. A single gateway keeps the
+// cost down; the private subnets in the second zone lose their route when
+// the first zone fails.
+func (c *LabVpc) newNatGateway(ctx *pulumi.Context, lb *labels.Labels) error {
+	var err error
+	c.NatEip, err = ec2.NewEip(ctx, lb.Name("nat-eip"), &ec2.EipArgs{
+		Domain: pulumi.String("vpc"),
+		Tags:   lb.Tags(lb.Name("nat-eip")),
+	}, childOptions(c, pulumi.DependsOn([]pulumi.Resource{c.InternetGateway}))...)
+	if err != nil {
+		return err
+	}
+
+	c.NatGateway, err = ec2.NewNatGateway(ctx, lb.Name("nat-gateway"), &ec2.NatGatewayArgs{
+		AllocationId: c.NatEip.ID(),
+		SubnetId:     c.Ec2Subnet.ID(),
+		Tags:         lb.Tags(lb.Name("nat-gateway")),
+	}, childOptions(c)...)
+	if err != nil {
+		return err
+	}
+
+	_, err = ec2.NewRoute(ctx, lb.Name("private-route-nat"), &ec2.RouteArgs{
+		RouteTableId:         c.PrivateRouteTable.ID(),
+		DestinationCidrBlock: pulumi.String("0.0.0.0/0"),
+		NatGatewayId:         c.NatGateway.ID(),
+	}, childOptions(c)...)
+	return err
+}
@@ -0,0 +1,454 @@
+package components
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+func newTestVpc(ctx *pulumi.Context) error {
+	_, err := NewLabVpc(ctx, "test-network", &LabVpcArgs{
+		Labels:            testLabels,
+		CidrBlock:         "10.0.0.0/16",
+		AvailabilityZones: []string{"us-east-1a", "us-east-1b", "us-east-1c"},
+		SshCidrBlock:      "203.0.113.10/32",
+	})
+	return err
+}
+
+func TestLabVpcSubnetAvailabilityZones(t *testing.T) {
+	m, err := run(t, newTestVpc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, az := range map[string]string{
+		"test-aurora-subnet-1": "us-east-1a",
+		"test-aurora-subnet-2": "us-east-1b",
+		"test-ec2-subnet":      "us-east-1a",
+		"test-ec2-subnet-2":    "us-east-1b",
+		"test-eks-subnet-1":    "us-east-1a",
+		"test-eks-subnet-2":    "us-east-1b",
+	} {
+		assertString(t, m.inputs(t, name), "availabilityZone", az)
+	}
+
+	assertBool(t, m.inputs(t, "test-ec2-subnet"), "mapPublicIpOnLaunch", true)
+	if _, ok := m.inputs(t, "test-aurora-subnet-1")["mapPublicIpOnLaunch"]; ok {
+		t.Error("Aurora subnets must not map public IPs")
+	}
+}
+
+func TestLabVpcAuroraSecurityGroupOnlyAllowsMySQL(t *testing.T) {
+	m, err := run(t, newTestVpc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Inline rules would conflict with the standalone rules
+	if _, ok := m.inputs(t, "test-aurora-sg")["ingress"]; ok {
+		t.Error("Aurora security group has inline ingress rules")
+	}
+
+	var sources []string
+	for _, name := range []string{"test-aurora-mysql-from-ec2", "test-aurora-mysql-from-eks"} {
+		rule := m.inputs(t, name)
+		assertString(t, rule, "type", "ingress")
+		assertString(t, rule, "protocol", "tcp")
+		assertString(t, rule, "securityGroupId", "test-aurora-sg-id")
+		if from, to := rule["fromPort"].NumberValue(), rule["toPort"].NumberValue(); from != 3306 || to != 3306 {
+			t.Errorf("%s: expected port 3306 only, got %v-%v", name, from, to)
+		}
+		if _, ok := rule["cidrBlocks"]; ok {
+			t.Errorf("%s: allows CIDR blocks", name)
+		}
+		sources = append(sources, rule["sourceSecurityGroupId"].StringValue())
+	}
+	want := []string{"test-ec2-sg-id", "test-eks-sg-id"}
+	if !slices.Equal(sources, want) {
+		t.Errorf("Aurora ingress sources: got %v, want %v", sources, want)
+	}
+}
+
+func TestLabVpcSshCidr(t *testing.T) {
+	m, err := run(t, newTestVpc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rule := m.inputs(t, "test-ec2-sg")["ingress"].ArrayValue()[0].ObjectValue()
+	cidrs := rule["cidrBlocks"].ArrayValue()
+	if len(cidrs) != 1 || cidrs[0].StringValue() != "203.0.113.10/32" {
+		t.Errorf("SSH CIDRs: got %v, want [203.0.113.10/32]", cidrs)
+	}
+}
+
+func TestLabVpcInstanceConnectSecurityGroup(t *testing.T) {
+	m, err := run(t, newTestVpc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	egress := m.inputs(t, "test-instance-connect-sg")["egress"].ArrayValue()
+	if len(egress) != 1 || egress[0].ObjectValue()["toPort"].NumberValue() != 22 {
+		t.Errorf("Instance Connect egress: got %v, want SSH only", egress)
+	}
+
+	rule := m.inputs(t, "test-ec2-sg")["ingress"].ArrayValue()[1].ObjectValue()
+	groups := rule["securityGroups"].ArrayValue()
+	if len(groups) != 1 || groups[0].StringValue() != "test-instance-connect-sg-id" {
+		t.Errorf("SSH source groups: got %v, want [test-instance-connect-sg-id]", groups)
+	}
+	if _, ok := rule["cidrBlocks"]; ok {
+		t.Error("Instance Connect SSH rule allows CIDR blocks")
+	}
+}
+
+func TestLabVpcRequiresTwoAvailabilityZones(t *testing.T) {
+	_, err := run(t, func(ctx *pulumi.Context) error {
+		_, err := NewLabVpc(ctx, "test-network", &LabVpcArgs{
+			Labels:            testLabels,
+			CidrBlock:         "10.0.0.0/16",
+			AvailabilityZones: []string{"us-east-1a"},
+		})
+		return err
+	})
+	if err == nil {
+		t.Fatal("expected an error with a single availability zone")
+	}
+}
+
+func TestLabVpcStrictNetworkAcls(t *testing.T) {
+	m, err := run(t, newTestVpc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.registered("test-aurora-nacl") {
+		t.Error("network ACL created without StrictNetworkAcls")
+	}
+
+	m, err = run(t, func(ctx *pulumi.Context) error {
+		_, err := NewLabVpc(ctx, "test-network", &LabVpcArgs{
+			Labels:            testLabels,
+			CidrBlock:         "10.0.0.0/16",
+			AvailabilityZones: []string{"us-east-1a", "us-east-1b"},
+			SshCidrBlock:      "203.0.113.10/32",
+			StrictNetworkAcls: true,
+		})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acl := m.inputs(t, "test-aurora-nacl")
+	var subnets []string
+	for _, id := range acl["subnetIds"].ArrayValue() {
+		subnets = append(subnets, id.StringValue())
+	}
+	if want := []string{"test-aurora-subnet-1-id", "test-aurora-subnet-2-id"}; !slices.Equal(subnets, want) {
+		t.Errorf("subnetIds: got %v, want %v", subnets, want)
+	}
+
+	clients := []string{"10.0.10.0/24", "10.0.20.0/24", "10.0.21.0/24", "10.0.11.0/24"}
+	for direction, ports := range map[string][2]float64{"ingress": {3306, 3306}, "egress": {1024, 65535}} {
+		var cidrs []string
+		for _, rule := range acl[resource.PropertyKey(direction)].ArrayValue() {
+			rule := rule.ObjectValue()
+			assertString(t, rule, "action", "allow")
+			assertString(t, rule, "protocol", "tcp")
+			if from, to := rule["fromPort"].NumberValue(), rule["toPort"].NumberValue(); from != ports[0] || to != ports[1] {
+				t.Errorf("%s: got ports %v-%v, want %v-%v", direction, from, to, ports[0], ports[1])
+			}
+			cidrs = append(cidrs, rule["cidrBlock"].StringValue())
+		}
+		if !slices.Equal(cidrs, clients) {
+			t.Errorf("%s CIDRs: got %v, want %v", direction, cidrs, clients)
+		}
+	}
+}
+
+func TestLabVpcFlowLogs(t *testing.T) {
+	m, err := run(t, newTestVpc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.registered("test-vpc-flow-log") {
+		t.Error("flow logs created without FlowLogs")
+	}
+
+	for destination, tt := range map[string]struct {
+		destinationType string
+		resources       []string
+		absent          []string
+	}{
+		"cloudwatch": {
+			destinationType: "cloud-watch-logs",
+			resources:       []string{"test-vpc-flow-logs", "test-vpc-flow-logs-role", "test-vpc-flow-logs-policy"},
+			absent:          []string{"test-vpc-flow-logs-lifecycle"},
+		},
+		"s3": {
+			destinationType: "s3",
+			resources:       []string{"test-vpc-flow-logs", "test-vpc-flow-logs-public-access-block", "test-vpc-flow-logs-lifecycle", "test-vpc-flow-logs-policy"},
+			absent:          []string{"test-vpc-flow-logs-role"},
+		},
+	} {
+		m, err := run(t, func(ctx *pulumi.Context) error {
+			_, err := NewLabVpc(ctx, "test-network", &LabVpcArgs{
+				Labels:            testLabels,
+				CidrBlock:         "10.0.0.0/16",
+				AvailabilityZones: []string{"us-east-1a", "us-east-1b"},
+				SshCidrBlock:      "203.0.113.10/32",
+				FlowLogs:          &FlowLogsArgs{Destination: destination, TrafficType: "REJECT", RetentionDays: 7},
+			})
+			return err
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", destination, err)
+		}
+
+		flowLog := m.inputs(t, "test-vpc-flow-log")
+		assertString(t, flowLog, "logDestinationType", tt.destinationType)
+		assertString(t, flowLog, "trafficType", "REJECT")
+		assertString(t, flowLog, "logFormat", flowLogFormat)
+		if got := flowLog["maxAggregationInterval"].NumberValue(); got != 60 {
+			t.Errorf("%s: maxAggregationInterval = %v, want 60", destination, got)
+		}
+		for _, name := range tt.resources {
+			if !m.registered(name) {
+				t.Errorf("%s: %s not registered", destination, name)
+			}
+		}
+		for _, name := range tt.absent {
+			if m.registered(name) {
+				t.Errorf("%s: unexpected %s", destination, name)
+			}
+		}
+	}
+}
+
+func TestLabVpcIpv6(t *testing.T) {
+	m, err := run(t, newTestVpc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.registered("test-eigw") {
+		t.Error("egress-only internet gateway created without EnableIpv6")
+	}
+	if _, ok := m.inputs(t, "test-aurora-subnet-1")["ipv6CidrBlock"]; ok {
+		t.Error("IPv6 subnet CIDR set without EnableIpv6")
+	}
+
+	m, err = run(t, func(ctx *pulumi.Context) error {
+		_, err := NewLabVpc(ctx, "test-network", &LabVpcArgs{
+			Labels:            testLabels,
+			CidrBlock:         "10.0.0.0/16",
+			AvailabilityZones: []string{"us-east-1a", "us-east-1b"},
+			SshCidrBlock:      "203.0.113.10/32",
+			StrictNetworkAcls: true,
+			EnableIpv6:        true,
+		})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertBool(t, m.inputs(t, "test-vpc"), "assignGeneratedIpv6CidrBlock", true)
+	// The mock VPC gets 2600:1f18:abcd:ef00::/56
+	for name, cidr := range map[string]string{
+		"test-aurora-subnet-1": "2600:1f18:abcd:ef01::/64",
+		"test-aurora-subnet-2": "2600:1f18:abcd:ef02::/64",
+		"test-ec2-subnet":      "2600:1f18:abcd:ef0a::/64",
+		"test-ec2-subnet-2":    "2600:1f18:abcd:ef0b::/64",
+		"test-eks-subnet-1":    "2600:1f18:abcd:ef14::/64",
+		"test-eks-subnet-2":    "2600:1f18:abcd:ef15::/64",
+	} {
+		subnet := m.inputs(t, name)
+		assertString(t, subnet, "ipv6CidrBlock", cidr)
+		assertBool(t, subnet, "assignIpv6AddressOnCreation", true)
+	}
+
+	assertString(t, m.inputs(t, "test-public-route-ipv6"), "gatewayId", "test-igw-id")
+	private := m.inputs(t, "test-private-route-ipv6")
+	assertString(t, private, "destinationIpv6CidrBlock", "::/0")
+	assertString(t, private, "egressOnlyGatewayId", "test-eigw-id")
+
+	for _, name := range []string{"test-aurora-sg", "test-ec2-sg", "test-eks-sg"} {
+		egress := m.inputs(t, name)["egress"].ArrayValue()[0].ObjectValue()
+		if blocks := egress["ipv6CidrBlocks"].ArrayValue(); len(blocks) != 1 || blocks[0].StringValue() != "::/0" {
+			t.Errorf("%s: got IPv6 egress %v, want ::/0", name, blocks)
+		}
+	}
+
+	var ipv6Clients []string
+	for _, rule := range m.inputs(t, "test-aurora-nacl")["ingress"].ArrayValue() {
+		if block, ok := rule.ObjectValue()["ipv6CidrBlock"]; ok {
+			ipv6Clients = append(ipv6Clients, block.StringValue())
+		}
+	}
+	if want := []string{"2600:1f18:abcd:ef0a::/64", "2600:1f18:abcd:ef14::/64", "2600:1f18:abcd:ef15::/64", "2600:1f18:abcd:ef0b::/64"}; !slices.Equal(ipv6Clients, want) {
+		t.Errorf("IPv6 network ACL clients: got %v, want %v", ipv6Clients, want)
+	}
+}
+
+func TestLabVpcNatGateway(t *testing.T) {
+	m, err := run(t, func(ctx *pulumi.Context) error {
+		_, err := NewLabVpc(ctx, "test-network", &LabVpcArgs{
+			Labels:            testLabels,
+			CidrBlock:         "10.0.0.0/16",
+			AvailabilityZones: []string{"us-east-1a", "us-east-1b"},
+			SshCidrBlock:      "203.0.113.10/32",
+			NatGateway:        true,
+		})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nat := m.inputs(t, "test-nat-gateway")
+	assertString(t, nat, "subnetId", "test-ec2-subnet-id")
+	assertString(t, nat, "allocationId", "test-nat-eip-id")
+	route := m.inputs(t, "test-private-route-nat")
+	assertString(t, route, "routeTableId", "test-private-rt-id")
+	assertString(t, route, "destinationCidrBlock", "0.0.0.0/0")
+	assertString(t, route, "natGatewayId", "test-nat-gateway-id")
+
+	m, err = run(t, newTestVpc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.registered("test-nat-gateway") {
+		t.Error("NAT gateway created without NatGateway")
+	}
+}
+
+func TestIpv6SubnetCidr(t *testing.T) {
+	got, err := ipv6SubnetCidr("2600:1f18:abcd:ef00::/56", 21)
+	if err != nil || got != "2600:1f18:abcd:ef15::/64" {
+		t.Errorf("got %q, %v, want 2600:1f18:abcd:ef15::/64", got, err)
+	}
+	for _, block := range []string{"10.0.0.0/16", "2600:1f18:abcd::/48", ""} {
+		if _, err := ipv6SubnetCidr(block, 1); err == nil {
+			t.Errorf("%q: expected an error", block)
+		}
+	}
+}
+
+// existingVpc is a VPC created outside the lab that can host it.
+func existingVpc() *ExistingVpcArgs {
+	return &ExistingVpcArgs{
+		VpcId:              "vpc-0123456789abcdef0",
+		EnableDnsSupport:   true,
+		EnableDnsHostnames: true,
+		AuroraSubnets: []ExistingSubnet{
+			{Id: "subnet-0aaaaaaaaaaaaaaaa", VpcId: "vpc-0123456789abcdef0", AvailabilityZone: "us-east-1a"},
+			{Id: "subnet-0bbbbbbbbbbbbbbbb", VpcId: "vpc-0123456789abcdef0", AvailabilityZone: "us-east-1b"},
+		},
+		Ec2Subnet: ExistingSubnet{Id: "subnet-0cccccccccccccccc", VpcId: "vpc-0123456789abcdef0", AvailabilityZone: "us-east-1a"},
+	}
+}
+
+func TestLabVpcExisting(t *testing.T) {
+	m, err := run(t, func(ctx *pulumi.Context) error {
+		network, err := NewLabVpc(ctx, "test-network", &LabVpcArgs{
+			Labels:       testLabels,
+			SshCidrBlock: "203.0.113.10/32",
+			Existing:     existingVpc(),
+		})
+		if err != nil {
+			return err
+		}
+		if network.InternetGateway != nil || network.PublicRouteTable != nil || len(network.EksSubnets) != 0 {
+			t.Error("existing network has lab-created gateway, route tables or EKS subnets")
+		}
+		if len(network.AuroraSubnets) != 2 || network.Ec2Subnet == nil {
+			t.Error("existing subnets not read")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"test-vpc", "test-igw", "test-aurora-subnet-1", "test-ec2-subnet", "test-public-rt", "test-private-rt"} {
+		if m.registered(name) {
+			t.Errorf("%s created for an existing VPC", name)
+		}
+	}
+	for _, name := range []string{"test-existing-vpc", "test-existing-aurora-subnet-1", "test-existing-aurora-subnet-2", "test-existing-ec2-subnet"} {
+		if !m.registered(name) {
+			t.Errorf("%s not read", name)
+		}
+	}
+
+	// The security groups are created in the existing VPC
+	for _, name := range []string{"test-aurora-sg", "test-ec2-sg", "test-eks-sg"} {
+		assertString(t, m.inputs(t, name), "vpcId", "vpc-0123456789abcdef0")
+	}
+	assertString(t, m.inputs(t, "test-aurora-mysql-from-ec2"), "sourceSecurityGroupId", "test-ec2-sg-id")
+}
+
+func TestExistingVpcCheck(t *testing.T) {
+	if err := existingVpc().check(); err != nil {
+		t.Errorf("valid VPC: %v", err)
+	}
+
+	e := existingVpc()
+	e.EnableDnsHostnames = false
+	e.AuroraSubnets[1].AvailabilityZone = "us-east-1a"
+	e.Ec2Subnet.VpcId = "vpc-0fedcba9876543210"
+	e.EksSubnets = []ExistingSubnet{{Id: "subnet-0dddddddddddddddd", VpcId: "vpc-0123456789abcdef0", AvailabilityZone: "us-east-1a"}}
+	err := e.check()
+	if err == nil {
+		t.Fatal("expected problems")
+	}
+	want := "VPC vpc-0123456789abcdef0 needs DNS resolution and DNS hostnames enabled for the Aurora endpoints\n" +
+		"subnet subnet-0cccccccccccccccc is in VPC vpc-0fedcba9876543210, not vpc-0123456789abcdef0\n" +
+		"the Aurora subnets must be two subnets in different availability zones\n" +
+		"the EKS subnets must be two subnets in different availability zones"
+	if err.Error() != want {
+		t.Errorf("got:\n%v\nwant:\n%s", err, want)
+	}
+}
+
+func TestLabVpcSecondaryCidrBlocks(t *testing.T) {
+	m, err := run(t, newTestVpc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.registered("test-secondary-cidr-1") {
+		t.Error("secondary CIDR block associated without SecondaryCidrBlocks")
+	}
+	assertString(t, m.inputs(t, "test-eks-subnet-1"), "cidrBlock", "10.0.20.0/24")
+	assertString(t, m.inputs(t, "test-eks-subnet-2"), "cidrBlock", "10.0.21.0/24")
+
+	m, err = run(t, func(ctx *pulumi.Context) error {
+		_, err := NewLabVpc(ctx, "test-network", &LabVpcArgs{
+			Labels:              testLabels,
+			CidrBlock:           "10.0.0.0/16",
+			SecondaryCidrBlocks: []string{"100.64.0.0/16", "100.65.0.0/16"},
+			EksSubnetCidrs:      []string{"100.64.0.0/18", "100.64.64.0/18"},
+			AvailabilityZones:   []string{"us-east-1a", "us-east-1b"},
+			SshCidrBlock:        "203.0.113.10/32",
+		})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, block := range map[string]string{"test-secondary-cidr-1": "100.64.0.0/16", "test-secondary-cidr-2": "100.65.0.0/16"} {
+		association := m.inputs(t, name)
+		assertString(t, association, "cidrBlock", block)
+		assertString(t, association, "vpcId", "test-vpc-id")
+	}
+	assertString(t, m.inputs(t, "test-eks-subnet-1"), "cidrBlock", "100.64.0.0/18")
+	assertString(t, m.inputs(t, "test-eks-subnet-2"), "cidrBlock", "100.64.64.0/18")
+	// The Aurora and EC2 subnets stay in the primary block
+	assertString(t, m.inputs(t, "test-aurora-subnet-1"), "cidrBlock", "10.0.1.0/24")
+	assertString(t, m.inputs(t, "test-ec2-subnet"), "cidrBlock", "10.0.10.0/24")
+}
@@ -0,0 +1,174 @@
+package components
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/iam"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/secretsmanager"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"aurora-bluegreen-lab/internal/labels"
+)
+
+// LabWorkloadIdentityArgs configures LabWorkloadIdentity.
+type LabWorkloadIdentityArgs struct {
+	Labels *labels.Labels
+	// OidcProviderArn is the EKS cluster's IAM OIDC provider
+	OidcProviderArn string
+	// Namespace and ServiceAccount name the Kubernetes service account the
+	// role is for; no other service account can assume it
+	Namespace      string
+	ServiceAccount string
+	DbPassword     pulumi.StringInput
+	// ExporterNamespace and ExporterServiceAccount name the service account
+	// of the Prometheus CloudWatch exporter; empty, there is no exporter
+	ExporterNamespace      string
+	ExporterServiceAccount string
+}
+
+// LabWorkloadIdentity is the IAM role of the simulator pods on EKS (IAM
+// roles for service accounts, IRSA) with the secret holding the database
+// password the role can read, and optionally the role of the CloudWatch
+// exporter reading the cluster's metrics.
+type LabWorkloadIdentity struct {
+	pulumi.ResourceState
+
+	Role           *iam.Role
+	PasswordSecret *secretsmanager.Secret
+	ExporterRole   *iam.Role // nil without ExporterNamespace
+}
+
+// webIdentityPolicy is the trust policy of an IRSA role: only the service
+// account's tokens, issued by the cluster's OIDC provider, assume it.
+func webIdentityPolicy(oidcProviderArn, issuer, namespace, serviceAccount string) pulumi.String {
+	return pulumi.String(fmt.Sprintf(`{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Principal": {"Federated": "%s"},
+      "Action": "sts:AssumeRoleWithWebIdentity",
+      "Condition": {
+        "StringEquals": {
+          "%s:sub": "system:serviceaccount:%s:%s",
+          "%s:aud": "sts.amazonaws.com"
+        }
+      }
+    }
+  ]
+}`, oidcProviderArn, issuer, namespace, serviceAccount, issuer))
+}
+
+// NewLabWorkloadIdentity creates the password secret and the role trusted
+// by the cluster's OIDC provider for the service account.
+func NewLabWorkloadIdentity(ctx *pulumi.Context, name string, args *LabWorkloadIdentityArgs, opts ...pulumi.ResourceOption) (*LabWorkloadIdentity, error) {
+	// The trust policy's condition keys are the provider's issuer, the part
+	// of the ARN after oidc-provider/
+	_, issuer, ok := strings.Cut(args.OidcProviderArn, ":oidc-provider/")
+	if !ok || issuer == "" {
+		return nil, fmt.Errorf("%q is not the ARN of an IAM OIDC provider", args.OidcProviderArn)
+	}
+
+	c := &LabWorkloadIdentity{}
+	err := ctx.RegisterComponentResource(typePrefix+"LabWorkloadIdentity", name, c, opts...)
+	if err != nil {
+		return nil, err
+	}
+	lb := args.Labels
+
+	// The pods read the password itself, not the JSON credentials of the
+	// simulator hosts' secret, so no JSON parsing is needed in the pod
+	c.PasswordSecret, err = secretsmanager.NewSecret(ctx, lb.Name("eks-workload-db-password"), &secretsmanager.SecretArgs{
+		Name:        pulumi.String(lb.ProjectName + "/eks-workload/db-password"),
+		Description: pulumi.String("Aurora password of the workload simulator pods"),
+		// Lab secrets are deleted immediately so the stack can be recreated
+		RecoveryWindowInDays: pulumi.Int(0),
+		Tags:                 lb.Tags(lb.Name("eks-workload-db-password")),
+	}, childOptions(c)...)
+	if err != nil {
+		return nil, err
+	}
+	_, err = secretsmanager.NewSecretVersion(ctx, lb.Name("eks-workload-db-password-version"), &secretsmanager.SecretVersionArgs{
+		SecretId:     c.PasswordSecret.ID(),
+		SecretString: pulumi.ToSecret(args.DbPassword).(pulumi.StringOutput),
+	}, childOptions(c)...)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Role, err = iam.NewRole(ctx, lb.Name("eks-workload-role"), &iam.RoleArgs{
+		Name:             pulumi.String(lb.Name("eks-workload-role")),
+		AssumeRolePolicy: webIdentityPolicy(args.OidcProviderArn, issuer, args.Namespace, args.ServiceAccount),
+		Tags:             lb.Tags(lb.Name("eks-workload-role")),
+	}, childOptions(c)...)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = iam.NewRolePolicy(ctx, lb.Name("eks-workload-policy"), &iam.RolePolicyArgs{
+		Role: c.Role.ID(),
+		Policy: pulumi.Sprintf(`{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Action": "secretsmanager:GetSecretValue",
+      "Resource": "%s"
+    }
+  ]
+}`, c.PasswordSecret.Arn),
+	}, childOptions(c)...)
+	if err != nil {
+		return nil, err
+	}
+
+	if args.ExporterNamespace != "" {
+		err = c.newExporterRole(ctx, lb, args, issuer)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = ctx.RegisterResourceOutputs(c, pulumi.Map{})
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// newExporterRole creates the role of the CloudWatch exporter. CloudWatch
+// has no resource-level permissions for reading metrics, so the role reads
+// those of the whole account.
+func (c *LabWorkloadIdentity) newExporterRole(ctx *pulumi.Context, lb *labels.Labels, args *LabWorkloadIdentityArgs, issuer string) error {
+	var err error
+	c.ExporterRole, err = iam.NewRole(ctx, lb.Name("eks-cloudwatch-exporter-role"), &iam.RoleArgs{
+		Name:             pulumi.String(lb.Name("eks-cloudwatch-exporter-role")),
+		AssumeRolePolicy: webIdentityPolicy(args.OidcProviderArn, issuer, args.ExporterNamespace, args.ExporterServiceAccount),
+		Tags:             lb.Tags(lb.Name("eks-cloudwatch-exporter-role")),
+	}, childOptions(c)...)
+	if err != nil {
+		return err
+	}
+
+	_, err = iam.NewRolePolicy(ctx, lb.Name("eks-cloudwatch-exporter-policy"), &iam.RolePolicyArgs{
+		Role: c.ExporterRole.ID(),
+		Policy: pulumi.String(`{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Action": [
+        "cloudwatch:GetMetricData",
+        "cloudwatch:GetMetricStatistics",
+        "cloudwatch:ListMetrics",
+        "tag:GetResources"
+      ],
+      "Resource": "*"
+    }
+  ]
+}`),
+	}, childOptions(c)...)
+	return err
+}
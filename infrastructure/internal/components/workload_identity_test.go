@@ -0,0 +1,92 @@
+package components
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+const testOidcProviderArn = "arn:aws:iam::123456789012:oidc-provider/oidc.eks.us-east-1.amazonaws.com/id/EXAMPLE"
+
+func TestLabWorkloadIdentity(t *testing.T) {
+	m, err := run(t, func(ctx *pulumi.Context) error {
+		_, err := NewLabWorkloadIdentity(ctx, "test-eks-workload", &LabWorkloadIdentityArgs{
+			Labels:          testLabels,
+			OidcProviderArn: testOidcProviderArn,
+			Namespace:       "workload-simulator",
+			ServiceAccount:  "workload-simulator",
+			DbPassword:      pulumi.String("secret"),
+		})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertString(t, m.inputs(t, "test-eks-workload-db-password"), "name", "test/eks-workload/db-password")
+	if got := m.inputs(t, "test-eks-workload-db-password-version")["secretString"]; !got.IsSecret() || got.SecretValue().Element.StringValue() != "secret" {
+		t.Errorf("secretString: got %v, want the password as a secret", got)
+	}
+
+	var trust struct {
+		Statement []struct {
+			Principal map[string]string
+			Action    string
+			Condition map[string]map[string]string
+		}
+	}
+	if err := json.Unmarshal([]byte(m.inputs(t, "test-eks-workload-role")["assumeRolePolicy"].StringValue()), &trust); err != nil {
+		t.Fatal(err)
+	}
+	statement := trust.Statement[0]
+	if statement.Principal["Federated"] != testOidcProviderArn || statement.Action != "sts:AssumeRoleWithWebIdentity" {
+		t.Errorf("trust statement: got %+v", statement)
+	}
+	conditions := statement.Condition["StringEquals"]
+	if got := conditions["oidc.eks.us-east-1.amazonaws.com/id/EXAMPLE:sub"]; got != "system:serviceaccount:workload-simulator:workload-simulator" {
+		t.Errorf("sub condition: got %q", got)
+	}
+	if got := conditions["oidc.eks.us-east-1.amazonaws.com/id/EXAMPLE:aud"]; got != "sts.amazonaws.com" {
+		t.Errorf("aud condition: got %q", got)
+	}
+
+	if m.registered("test-eks-cloudwatch-exporter-role") {
+		t.Error("exporter role registered without an exporter service account")
+	}
+
+	m, err = run(t, func(ctx *pulumi.Context) error {
+		_, err := NewLabWorkloadIdentity(ctx, "test-eks-workload", &LabWorkloadIdentityArgs{
+			Labels:                 testLabels,
+			OidcProviderArn:        testOidcProviderArn,
+			Namespace:              "workload-simulator",
+			ServiceAccount:         "workload-simulator",
+			DbPassword:             pulumi.String("secret"),
+			ExporterNamespace:      "monitoring",
+			ExporterServiceAccount: "cloudwatch-exporter",
+		})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exporterTrust := m.inputs(t, "test-eks-cloudwatch-exporter-role")["assumeRolePolicy"].StringValue()
+	if !strings.Contains(exporterTrust, `"oidc.eks.us-east-1.amazonaws.com/id/EXAMPLE:sub": "system:serviceaccount:monitoring:cloudwatch-exporter"`) {
+		t.Errorf("exporter trust policy: got %s", exporterTrust)
+	}
+	if policy := m.inputs(t, "test-eks-cloudwatch-exporter-policy")["policy"].StringValue(); !strings.Contains(policy, "cloudwatch:GetMetricData") {
+		t.Errorf("exporter policy: got %s", policy)
+	}
+
+	if _, err := run(t, func(ctx *pulumi.Context) error {
+		_, err := NewLabWorkloadIdentity(ctx, "test-eks-workload", &LabWorkloadIdentityArgs{
+			Labels:          testLabels,
+			OidcProviderArn: "arn:aws:iam::123456789012:role/not-a-provider",
+			DbPassword:      pulumi.String("secret"),
+		})
+		return err
+	}); err == nil {
+		t.Error("expected an error for an ARN that is not an OIDC provider's")
+	}
+}
@@ -0,0 +1,24 @@
+package config
+
+// Access is the validated configuration of the access stack.
+type Access struct {
+	VpcStackName string
+	// SubnetId places the EC2 Instance Connect Endpoint; empty uses the VPC
+	// stack's first EKS subnet, a private subnet outside the Aurora tier
+	SubnetId string
+}
+
+// LoadAccess loads and validates the access stack configuration.
+func LoadAccess(src Source) (*Access, error) {
+	l := newLoader(src)
+	c := &Access{
+		VpcStackName: l.require("vpcStackName", `pulumi config set vpcStackName "organization/aurora-bluegreen-vpc/dev"`),
+		SubnetId:     l.get("subnetId", ""),
+	}
+
+	if c.SubnetId != "" && !subnetIdPattern.MatchString(c.SubnetId) {
+		l.errorf("subnetId must be a subnet ID such as subnet-0123456789abcdef0 (got %q)", c.SubnetId)
+	}
+
+	return c, l.err()
+}
@@ -0,0 +1,493 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"aurora-bluegreen-lab/internal/components"
+)
+
+var (
+	// Aurora MySQL 2 (MySQL 5.7 compatible) or 3 (MySQL 8.0 compatible); the
+	// MySQL version selects the parameter group family
+	engineVersionPattern = regexp.MustCompile(`^(5\.7\.mysql_aurora\.2|8\.0\.mysql_aurora\.3)\.\d{2}\.\d+$`)
+	databaseNamePattern  = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{0,63}$`)
+	usernamePattern      = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{0,15}$`)
+
+	maintenanceWindowPattern = regexp.MustCompile(`^(mon|tue|wed|thu|fri|sat|sun):([01]\d|2[0-3]):([0-5]\d)-(mon|tue|wed|thu|fri|sat|sun):([01]\d|2[0-3]):([0-5]\d)$`)
+	backupWindowPattern      = regexp.MustCompile(`^([01]\d|2[0-3]):([0-5]\d)-([01]\d|2[0-3]):([0-5]\d)$`)
+	weekdays                 = []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"}
+
+	// RDS for MySQL versions of the external replica: a major version or a
+	// specific minor version
+	mysqlVersionPattern = regexp.MustCompile(`^(5\.7|8\.0)(\.\d+)?$`)
+)
+
+// instanceClasses lists the Aurora MySQL 3 instance classes the lab accepts,
+// by family.
+var instanceClasses = map[string][]string{
+	"db.t3":   {"medium", "large"},
+	"db.t4g":  {"medium", "large"},
+	"db.r5":   {"large", "xlarge", "2xlarge", "4xlarge", "8xlarge", "12xlarge", "16xlarge", "24xlarge"},
+	"db.r6g":  {"large", "xlarge", "2xlarge", "4xlarge", "8xlarge", "12xlarge", "16xlarge"},
+	"db.r6gd": {"xlarge", "2xlarge", "4xlarge", "8xlarge", "12xlarge", "16xlarge"},
+	"db.r6i":  {"large", "xlarge", "2xlarge", "4xlarge", "8xlarge", "12xlarge", "16xlarge", "24xlarge", "32xlarge"},
+	"db.r6id": {"xlarge", "2xlarge", "4xlarge", "8xlarge", "12xlarge", "16xlarge", "24xlarge", "32xlarge"},
+	"db.r7g":  {"large", "xlarge", "2xlarge", "4xlarge", "8xlarge", "12xlarge", "16xlarge"},
+	"db.r7i":  {"large", "xlarge", "2xlarge", "4xlarge", "8xlarge", "12xlarge", "16xlarge", "24xlarge", "48xlarge"},
+	"db.r8g":  {"large", "xlarge", "2xlarge", "4xlarge", "8xlarge", "12xlarge", "16xlarge", "24xlarge", "48xlarge"},
+	"db.x2g":  {"large", "xlarge", "2xlarge", "4xlarge", "8xlarge", "12xlarge", "16xlarge"},
+}
+
+// mysql8InstanceClasses are the instance class families, and Serverless v2,
+// that Aurora MySQL 2 does not support.
+var mysql8InstanceClasses = []string{"db.r7g", "db.r7i", "db.r8g", "db.serverless"}
+
+// ParameterGroupFamily returns the parameter group family of an Aurora MySQL
+// engine version, e.g. aurora-mysql5.7 for 5.7.mysql_aurora.2.12.1.
+func ParameterGroupFamily(engineVersion string) string {
+	mysqlVersion, _, _ := strings.Cut(engineVersion, ".mysql_aurora.")
+	return "aurora-mysql" + mysqlVersion
+}
+
+// Aurora is the validated configuration of the Aurora stack. The master
+// password is only checked here; the stack reads it with RequireSecret so it
+// stays a secret output.
+type Aurora struct {
+	VpcStackName            string
+	DatabaseName            string
+	MasterUsername          string
+	EngineVersion           string
+	InstanceClass           string
+	StorageType             string
+	DeletionProtection      bool
+	FinalSnapshotIdentifier string
+	MonitoringInterval      int
+	IamAuthentication       bool
+	BacktrackWindow         int
+	ActivityStream          bool
+	ActivityStreamKmsKeyId  string
+	SnapshotIdentifier      string
+	GlobalDatabase          bool
+	SecondaryRegion         string
+	SecondaryVpcStackName   string
+	BinlogFormat            string
+	BinlogRowImage          string
+	BinlogRetentionHours    int
+
+	// Performance Insights settings, applied to every instance
+	PerformanceInsightsEnabled         bool
+	PerformanceInsightsRetentionPeriod int
+	PerformanceInsightsKmsKeyId        string
+
+	// Maintenance timing; AutoMinorVersionUpgrade applies to the instances
+	AutoMinorVersionUpgrade    bool
+	PreferredMaintenanceWindow string
+	PreferredBackupWindow      string
+
+	// ReaderAutoScaling is set when readerAutoScaling is enabled
+	ReaderAutoScaling *components.ReaderAutoScalingArgs
+	// BackupCopy is set when backupCopyRegion is set
+	BackupCopy *components.BackupCopyArgs
+	// ExternalReplica is set when externalReplica is enabled; the stack
+	// program sets its VPC and client security group
+	ExternalReplica *components.ExternalReplicaArgs
+	// RdsProxy is set when rdsProxy is enabled; the stack program sets its
+	// VPC and client security group
+	RdsProxy *components.RdsProxyArgs
+
+	// ParameterGroupFamily is the family of the blue parameter groups, the
+	// engine version's
+	ParameterGroupFamily string
+	// GreenEngineVersion is the engine version the green environment is
+	// upgraded to; empty when not set
+	GreenEngineVersion string
+
+	// Parameters and GreenParameters are the parameter overrides from the
+	// parameters/parametersFile and greenParameters/greenParametersFile
+	// config; nil when not set. GreenParameters is also set, with the new
+	// family, when greenEngineVersion is a major version upgrade
+	Parameters      *components.ParameterSet
+	GreenParameters *components.ParameterSet
+}
+
+// LoadAurora loads and validates the Aurora stack configuration.
+func LoadAurora(src Source) (*Aurora, error) {
+	l := newLoader(src)
+	c := &Aurora{
+		VpcStackName:            l.require("vpcStackName", `pulumi config set vpcStackName "organization/aurora-bluegreen-vpc/dev"`),
+		DatabaseName:            l.get("databaseName", "lab_db"),
+		MasterUsername:          l.get("masterUsername", "admin"),
+		EngineVersion:           l.get("engineVersion", "8.0.mysql_aurora.3.04.0"),
+		GreenEngineVersion:      l.get("greenEngineVersion", ""),
+		InstanceClass:           l.get("instanceClass", "db.r6g.xlarge"),
+		StorageType:             l.get("storageType", "aurora"),
+		DeletionProtection:      l.bool("deletionProtection", false),
+		FinalSnapshotIdentifier: l.get("finalSnapshotIdentifier", ""),
+		MonitoringInterval:      l.int("monitoringInterval", 0),
+		IamAuthentication:       l.bool("iamAuthentication", false),
+		BacktrackWindow:         l.int("backtrackWindow", 0),
+		ActivityStream:          l.bool("activityStream", false),
+		ActivityStreamKmsKeyId:  l.get("activityStreamKmsKeyId", ""),
+		SnapshotIdentifier:      l.get("snapshotIdentifier", ""),
+		GlobalDatabase:          l.bool("globalDatabase", false),
+		SecondaryRegion:         l.get("secondaryRegion", ""),
+		SecondaryVpcStackName:   l.get("secondaryVpcStackName", ""),
+		BinlogFormat:            l.get("binlogFormat", "ROW"),
+		BinlogRowImage:          l.get("binlogRowImage", "FULL"),
+		BinlogRetentionHours:    l.int("binlogRetentionHours", 24),
+
+		PerformanceInsightsEnabled:         l.bool("performanceInsightsEnabled", true),
+		PerformanceInsightsRetentionPeriod: l.int("performanceInsightsRetentionPeriod", 7),
+		PerformanceInsightsKmsKeyId:        l.get("performanceInsightsKmsKeyId", ""),
+
+		AutoMinorVersionUpgrade:    l.bool("autoMinorVersionUpgrade", false),
+		PreferredMaintenanceWindow: l.get("preferredMaintenanceWindow", "mon:04:00-mon:05:00"),
+		PreferredBackupWindow:      l.get("preferredBackupWindow", "03:00-04:00"),
+	}
+
+	// RDS master password rules: 8-41 printable ASCII characters other than
+	// /, ", @ and space. The value is never echoed back.
+	password := l.require("masterPassword", "pulumi config set --secret masterPassword <password>")
+	if password != "" {
+		if len(password) < 8 || len(password) > 41 {
+			l.errorf("masterPassword must be 8 to 41 characters long (got %d)", len(password))
+		}
+		if strings.ContainsAny(password, `/"@ `) {
+			l.errorf(`masterPassword must not contain /, ", @ or spaces`)
+		}
+	}
+
+	if !databaseNamePattern.MatchString(c.DatabaseName) {
+		l.errorf("databaseName must start with a letter and contain only letters, digits and underscores, up to 64 characters (got %q)", c.DatabaseName)
+	}
+	if !usernamePattern.MatchString(c.MasterUsername) {
+		l.errorf("masterUsername must start with a letter and contain only letters, digits and underscores, up to 16 characters (got %q)", c.MasterUsername)
+	}
+	if !engineVersionPattern.MatchString(c.EngineVersion) {
+		l.errorf("engineVersion must be an Aurora MySQL 2 or 3 version such as 8.0.mysql_aurora.3.04.0 or 5.7.mysql_aurora.2.12.1 (got %q)", c.EngineVersion)
+	}
+	c.ParameterGroupFamily = ParameterGroupFamily(c.EngineVersion)
+	l.instanceClass(c.InstanceClass)
+	if c.ParameterGroupFamily == "aurora-mysql5.7" && slices.ContainsFunc(mysql8InstanceClasses, func(family string) bool {
+		return c.InstanceClass == family || strings.HasPrefix(c.InstanceClass, family+".")
+	}) {
+		l.errorf("instanceClass %q needs Aurora MySQL 3; Aurora MySQL 2 (engineVersion %s) does not support it", c.InstanceClass, c.EngineVersion)
+	}
+	// Aurora Standard bills I/O per request; I/O-Optimized includes it in a
+	// higher instance and storage price
+	l.oneOf("storageType", c.StorageType, "aurora", "aurora-iopt1")
+
+	// Enhanced Monitoring interval in seconds (0 disables Enhanced Monitoring)
+	switch c.MonitoringInterval {
+	case 0, 1, 5, 10, 15, 30, 60:
+	default:
+		l.errorf("monitoringInterval must be one of 0, 1, 5, 10, 15, 30, 60 (got %d)", c.MonitoringInterval)
+	}
+
+	// Backtrack is an Aurora MySQL feature (the engine version check above
+	// rejects other engines); the window is in seconds, up to 72 hours
+	if c.BacktrackWindow < 0 || c.BacktrackWindow > 259200 {
+		l.errorf("backtrackWindow must be between 0 and 259200 seconds (72 hours) (got %d)", c.BacktrackWindow)
+	}
+	if c.BacktrackWindow > 0 && c.GlobalDatabase {
+		l.errorf("backtrackWindow cannot be combined with globalDatabase; Aurora Global Databases do not support backtracking")
+	}
+
+	// Database Activity Streams are not available on burstable instance classes
+	if c.ActivityStream && (strings.HasPrefix(c.InstanceClass, "db.t3.") || strings.HasPrefix(c.InstanceClass, "db.t4g.")) {
+		l.errorf("activityStream is not supported on burstable instance classes (got %q)", c.InstanceClass)
+	}
+	if !c.ActivityStream && c.ActivityStreamKmsKeyId != "" {
+		l.errorf("activityStreamKmsKeyId requires activityStream to be enabled")
+	}
+
+	// Performance Insights keeps 7 days for free, or 1 to 24 months (731 days)
+	if r := c.PerformanceInsightsRetentionPeriod; r != 7 && r != 731 && (r%31 != 0 || r < 31 || r > 713) {
+		l.errorf("performanceInsightsRetentionPeriod must be 7, a multiple of 31 up to 713 (months) or 731 days (got %d)", r)
+	}
+	if !c.PerformanceInsightsEnabled && c.PerformanceInsightsKmsKeyId != "" {
+		l.errorf("performanceInsightsKmsKeyId requires performanceInsightsEnabled")
+	}
+
+	l.maintenanceWindows(c.PreferredMaintenanceWindow, c.PreferredBackupWindow)
+
+	// Aurora Auto Scaling adds and removes readers between the capacities; a
+	// cluster has at most 15 readers
+	if l.bool("readerAutoScaling", false) {
+		scaling := &components.ReaderAutoScalingArgs{
+			MinCapacity: l.int("readerMinCapacity", 1),
+			MaxCapacity: l.int("readerMaxCapacity", 3),
+			Metric:      l.get("readerScalingMetric", "cpu"),
+			TargetValue: l.float("readerScalingTargetValue", 70),
+		}
+		if scaling.MinCapacity < 1 || scaling.MaxCapacity > 15 || scaling.MinCapacity > scaling.MaxCapacity {
+			l.errorf("readerMinCapacity and readerMaxCapacity must satisfy 1 <= min <= max <= 15 (got %d and %d)", scaling.MinCapacity, scaling.MaxCapacity)
+		}
+		l.oneOf("readerScalingMetric", scaling.Metric, "cpu", "connections")
+		if scaling.TargetValue <= 0 || (scaling.Metric == "cpu" && scaling.TargetValue > 100) {
+			l.errorf("readerScalingTargetValue must be greater than 0, and at most 100 for cpu (got %v)", scaling.TargetValue)
+		}
+		c.ReaderAutoScaling = scaling
+	}
+
+	// Aurora has no cross-region automated backup replication; AWS Backup
+	// snapshots the cluster on a schedule and copies the snapshots instead
+	if copyRegion := l.get("backupCopyRegion", ""); copyRegion != "" {
+		backupCopy := &components.BackupCopyArgs{
+			Region:        copyRegion,
+			Schedule:      l.get("backupCopySchedule", "cron(0 5 * * ? *)"),
+			RetentionDays: l.int("backupCopyRetentionDays", 7),
+		}
+		l.region("backupCopyRegion", copyRegion)
+		if copyRegion == l.src.Get("region") {
+			l.errorf("backupCopyRegion must differ from the stack's region %s", copyRegion)
+		}
+		if !strings.HasPrefix(backupCopy.Schedule, "cron(") || !strings.HasSuffix(backupCopy.Schedule, ")") {
+			l.errorf("backupCopySchedule must be an AWS Backup cron expression such as cron(0 5 * * ? *) (got %q)", backupCopy.Schedule)
+		}
+		if backupCopy.RetentionDays < 1 || backupCopy.RetentionDays > 365 {
+			l.errorf("backupCopyRetentionDays must be between 1 and 365 (got %d)", backupCopy.RetentionDays)
+		}
+		c.BackupCopy = backupCopy
+	}
+
+	// An RDS for MySQL instance replicating the cluster's binary log, outside
+	// the Blue/Green deployment. MySQL replicates to the same or a newer
+	// version only.
+	if l.bool("externalReplica", false) {
+		replica := &components.ExternalReplicaArgs{
+			InstanceClass: l.get("externalReplicaInstanceClass", "db.t4g.micro"),
+			EngineVersion: l.get("externalReplicaEngineVersion", "8.0"),
+		}
+		if !strings.HasPrefix(replica.InstanceClass, "db.") {
+			l.errorf("externalReplicaInstanceClass must be an RDS instance class such as db.t4g.micro (got %q)", replica.InstanceClass)
+		}
+		if !mysqlVersionPattern.MatchString(replica.EngineVersion) {
+			l.errorf("externalReplicaEngineVersion must be an RDS for MySQL 5.7 or 8.0 version such as 8.0 or 8.0.35 (got %q)", replica.EngineVersion)
+		} else if strings.HasPrefix(replica.EngineVersion, "5.7") && c.ParameterGroupFamily == "aurora-mysql8.0" {
+			l.errorf("externalReplicaEngineVersion %s cannot replicate from Aurora MySQL 3 (engineVersion %s); use 8.0", replica.EngineVersion, c.EngineVersion)
+		}
+		c.ExternalReplica = replica
+	}
+
+	// An RDS Proxy in front of the cluster, for comparing proxied and direct
+	// connections through a switchover
+	if l.bool("rdsProxy", false) {
+		proxy := &components.RdsProxyArgs{
+			RequireTls:            l.bool("rdsProxyRequireTls", false),
+			MaxConnectionsPercent: l.int("rdsProxyMaxConnectionsPercent", 100),
+		}
+		if proxy.MaxConnectionsPercent < 1 || proxy.MaxConnectionsPercent > 100 {
+			l.errorf("rdsProxyMaxConnectionsPercent must be between 1 and 100 (got %d)", proxy.MaxConnectionsPercent)
+		}
+		c.RdsProxy = proxy
+	}
+
+	// Global Database mode makes the lab cluster the primary of an
+	// rds.GlobalCluster, optionally with a secondary cluster in another region
+	if !c.GlobalDatabase && c.SecondaryRegion != "" {
+		l.errorf("secondaryRegion requires globalDatabase to be enabled")
+	}
+	if c.GlobalDatabase && c.SnapshotIdentifier != "" {
+		l.errorf("globalDatabase cannot be combined with snapshotIdentifier")
+	}
+	if c.SecondaryRegion != "" {
+		l.region("secondaryRegion", c.SecondaryRegion)
+		if c.SecondaryVpcStackName == "" {
+			l.errorf("secondaryRegion requires secondaryVpcStackName (a VPC stack deployed in %s)", c.SecondaryRegion)
+		}
+	}
+
+	// Binary logging is required to create a Blue/Green deployment on Aurora MySQL
+	l.oneOf("binlogFormat", c.BinlogFormat, "ROW", "MIXED", "STATEMENT", "OFF")
+	l.oneOf("binlogRowImage", c.BinlogRowImage, "FULL", "MINIMAL", "NOBLOB")
+	// Binlog retention is not a parameter group setting on Aurora MySQL; it is
+	// applied with mysql.rds_set_configuration after deployment (see README)
+	if c.BinlogRetentionHours < 1 || c.BinlogRetentionHours > 2160 {
+		l.errorf("binlogRetentionHours must be between 1 and 2160 (got %d)", c.BinlogRetentionHours)
+	}
+	if c.ExternalReplica != nil && c.BinlogFormat == "OFF" {
+		l.errorf("externalReplica needs binary logging; binlogFormat must not be OFF")
+	}
+
+	c.Parameters = l.parameterSet("parameters", "parametersFile")
+	c.GreenParameters = l.parameterSet("greenParameters", "greenParametersFile")
+	l.greenEngineVersion(c)
+
+	return c, l.err()
+}
+
+// greenEngineVersion validates the green environment's engine version. A
+// major version upgrade (Aurora MySQL 2 to 3) needs green parameter groups of
+// the new family, so they are created even without greenParameters.
+func (l *loader) greenEngineVersion(c *Aurora) {
+	if c.GreenEngineVersion == "" {
+		return
+	}
+	if !engineVersionPattern.MatchString(c.GreenEngineVersion) {
+		l.errorf("greenEngineVersion must be an Aurora MySQL 2 or 3 version such as 8.0.mysql_aurora.3.08.0 (got %q)", c.GreenEngineVersion)
+		return
+	}
+	family := ParameterGroupFamily(c.GreenEngineVersion)
+	if family < c.ParameterGroupFamily {
+		l.errorf("greenEngineVersion %s is older than engineVersion %s; Blue/Green deployments cannot downgrade the major version", c.GreenEngineVersion, c.EngineVersion)
+		return
+	}
+	if family == c.ParameterGroupFamily {
+		return
+	}
+	if c.GreenParameters == nil {
+		c.GreenParameters = &components.ParameterSet{}
+	}
+	if c.GreenParameters.Family != "" && c.GreenParameters.Family != family {
+		l.errorf("greenParameters family %s does not match greenEngineVersion %s (%s)", c.GreenParameters.Family, c.GreenEngineVersion, family)
+		return
+	}
+	c.GreenParameters.Family = family
+}
+
+// instanceClass records a problem when class is not a supported Aurora MySQL
+// instance class.
+func (l *loader) instanceClass(class string) {
+	if class == "db.serverless" {
+		return
+	}
+	if i := strings.LastIndex(class, "."); i > 0 {
+		if sizes, ok := instanceClasses[class[:i]]; ok && slices.Contains(sizes, class[i+1:]) {
+			return
+		}
+	}
+
+	families := make([]string, 0, len(instanceClasses))
+	for family := range instanceClasses {
+		families = append(families, family)
+	}
+	slices.Sort(families)
+	l.errorf("instanceClass %q is not a supported Aurora MySQL instance class (families: %s, or db.serverless)",
+		class, strings.Join(families, ", "))
+}
+
+// maintenanceWindows records a problem when the weekly maintenance window
+// (ddd:hh24:mi-ddd:hh24:mi, UTC) or the daily backup window (hh24:mi-hh24:mi,
+// UTC) is malformed, shorter than the 30 minutes RDS requires, or when the
+// two overlap.
+func (l *loader) maintenanceWindows(maintenance, backup string) {
+	const day, week = 24 * 60, 7 * 24 * 60
+
+	var maintenanceStart, maintenanceEnd int
+	m := maintenanceWindowPattern.FindStringSubmatch(maintenance)
+	if m == nil {
+		l.errorf("preferredMaintenanceWindow must be ddd:hh24:mi-ddd:hh24:mi in UTC, e.g. mon:04:00-mon:05:00 (got %q)", maintenance)
+	} else {
+		maintenanceStart = slices.Index(weekdays, m[1])*day + clockMinutes(m[2], m[3])
+		maintenanceEnd = slices.Index(weekdays, m[4])*day + clockMinutes(m[5], m[6])
+		if maintenanceEnd <= maintenanceStart {
+			maintenanceEnd += week
+		}
+		if maintenanceEnd-maintenanceStart < 30 {
+			l.errorf("preferredMaintenanceWindow must be at least 30 minutes long (got %q)", maintenance)
+		}
+	}
+
+	b := backupWindowPattern.FindStringSubmatch(backup)
+	if b == nil {
+		l.errorf("preferredBackupWindow must be hh24:mi-hh24:mi in UTC, e.g. 03:00-04:00 (got %q)", backup)
+		return
+	}
+	backupStart, backupEnd := clockMinutes(b[1], b[2]), clockMinutes(b[3], b[4])
+	if backupEnd <= backupStart {
+		backupEnd += day
+	}
+	if backupEnd-backupStart < 30 {
+		l.errorf("preferredBackupWindow must be at least 30 minutes long (got %q)", backup)
+	}
+	if m == nil {
+		return
+	}
+
+	// The backup window recurs daily; compare it with the maintenance window
+	// on every day the maintenance window can touch
+	for d := -1; d <= 8; d++ {
+		start, end := d*day+backupStart, d*day+backupEnd
+		if start < maintenanceEnd && maintenanceStart < end {
+			l.errorf("preferredBackupWindow %q overlaps preferredMaintenanceWindow %q", backup, maintenance)
+			return
+		}
+	}
+}
+
+// clockMinutes returns the minutes since midnight of an hh, mm pair matched
+// by the window patterns.
+func clockMinutes(hh, mm string) int {
+	h, _ := strconv.Atoi(hh)
+	m, _ := strconv.Atoi(mm)
+	return h*60 + m
+}
+
+// parameterSet reads a parameter set from the structured config object
+// objectKey or from the JSON or YAML (.yaml, .yml) file named by fileKey. It
+// returns nil when neither is set.
+func (l *loader) parameterSet(objectKey, fileKey string) *components.ParameterSet {
+	var set components.ParameterSet
+	hasObject := l.src.Get(objectKey) != ""
+	path := l.src.Get(fileKey)
+
+	switch {
+	case hasObject && path != "":
+		l.errorf("set either %s or %s, not both", objectKey, fileKey)
+		return nil
+	case hasObject:
+		if err := l.src.GetObject(objectKey, &set); err != nil {
+			l.errorf("invalid %s config: %v", objectKey, err)
+			return nil
+		}
+	case path != "":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			l.errorf("reading %s: %v", fileKey, err)
+			return nil
+		}
+		unmarshal := json.Unmarshal
+		if ext := filepath.Ext(path); ext == ".yaml" || ext == ".yml" {
+			unmarshal = yaml.Unmarshal
+		}
+		if err := unmarshal(data, &set); err != nil {
+			l.errorf("parsing %s %q: %v", fileKey, path, err)
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	valid := true
+	for _, p := range append(append([]components.Parameter{}, set.Cluster...), set.Instance...) {
+		if p.Name == "" {
+			l.errorf("%s: every parameter needs a name", objectKey)
+			valid = false
+			continue
+		}
+		if p.Reset && p.Value != "" {
+			l.errorf("%s: parameter %s sets both a value and reset", objectKey, p.Name)
+			valid = false
+		}
+		switch p.ApplyMethod {
+		case "", "immediate", "pending-reboot":
+		default:
+			l.errorf("%s: parameter %s has invalid applyMethod %q (expected immediate or pending-reboot)",
+				objectKey, p.Name, p.ApplyMethod)
+			valid = false
+		}
+	}
+	if !valid {
+		return nil
+	}
+	return &set
+}
@@ -0,0 +1,74 @@
+package config
+
+import "strings"
+
+// maxBudgetNotifications is the number of notifications AWS Budgets allows
+// per budget, and maxBudgetSubscribers the subscribers per notification.
+const (
+	maxBudgetNotifications = 5
+	maxBudgetSubscribers   = 10
+)
+
+// Budget is the validated configuration of the budget stack.
+type Budget struct {
+	// MonthlyLimitUsd is the monthly budget of the resources tagged with the
+	// lab's Project tag
+	MonthlyLimitUsd float64
+	// AlertThresholds are the percentages of the limit at which the actual
+	// spend is alerted
+	AlertThresholds []float64
+	// ForecastThreshold is the percentage of the limit at which the forecast
+	// spend is alerted; 0 disables the forecast alert
+	ForecastThreshold float64
+	AlertEmails       []string
+	// ActivateCostAllocationTag activates the Project tag for cost
+	// allocation, which only the management account of an organization can do
+	ActivateCostAllocationTag bool
+}
+
+// LoadBudget loads and validates the budget stack configuration.
+func LoadBudget(src Source) (*Budget, error) {
+	l := newLoader(src)
+	c := &Budget{
+		MonthlyLimitUsd:           l.float("monthlyLimitUsd", 100),
+		AlertThresholds:           []float64{50, 80, 100},
+		ForecastThreshold:         l.float("forecastThreshold", 100),
+		ActivateCostAllocationTag: l.bool("activateCostAllocationTag", false),
+	}
+	l.object("alertThresholds", "a list of percentages such as [50, 80, 100]", &c.AlertThresholds)
+	l.object("alertEmails", "a list of email addresses", &c.AlertEmails)
+
+	if c.MonthlyLimitUsd <= 0 {
+		l.errorf("monthlyLimitUsd must be greater than 0 (got %g)", c.MonthlyLimitUsd)
+	}
+	if len(c.AlertThresholds) == 0 {
+		l.errorf("alertThresholds must list at least one percentage")
+	}
+	for i, threshold := range c.AlertThresholds {
+		if threshold <= 0 {
+			l.errorf("alertThresholds[%d] must be greater than 0 (got %g)", i, threshold)
+		}
+	}
+	if c.ForecastThreshold < 0 {
+		l.errorf("forecastThreshold must not be negative (got %g)", c.ForecastThreshold)
+	}
+	notifications := len(c.AlertThresholds)
+	if c.ForecastThreshold > 0 {
+		notifications++
+	}
+	if notifications > maxBudgetNotifications {
+		l.errorf("a budget allows %d notifications; alertThresholds and forecastThreshold add up to %d", maxBudgetNotifications, notifications)
+	}
+
+	for i, email := range c.AlertEmails {
+		if !strings.Contains(email, "@") {
+			l.errorf("alertEmails[%d] must be an email address (got %q)", i, email)
+		}
+	}
+	// Every notification also goes to the stack's SNS topic
+	if len(c.AlertEmails) > maxBudgetSubscribers-1 {
+		l.errorf("alertEmails allows at most %d addresses (got %d)", maxBudgetSubscribers-1, len(c.AlertEmails))
+	}
+
+	return c, l.err()
+}
@@ -0,0 +1,198 @@
+// Package config loads and validates the Pulumi configuration of each lab
+// stack before any resource is registered.
+//
+// Every problem found (a malformed CIDR, an unsupported engine version or
+// instance class, a missing required key, ...) is collected and returned
+// together as an *Error, so a misconfigured stack fails once with the full
+// list instead of partway through an update with a provider error:
+//
+//	error: invalid configuration:
+//	  - keyName is required; set it with: pulumi config set keyName <your-key-pair-name>
+//	  - simulatorCount must be an integer (got "four")
+package config
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Source is the part of *config.Config the loaders read from.
+type Source interface {
+	Get(key string) string
+	GetObject(key string, output interface{}) error
+}
+
+// Error lists every problem found in a stack's configuration.
+type Error struct {
+	Problems []error
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	b.WriteString("invalid configuration:")
+	for _, p := range e.Problems {
+		b.WriteString("\n  - ")
+		b.WriteString(p.Error())
+	}
+	return b.String()
+}
+
+// Unwrap returns the individual problems for errors.Is and errors.As.
+func (e *Error) Unwrap() []error {
+	return e.Problems
+}
+
+var (
+	projectNamePattern = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+	regionPattern      = regexp.MustCompile(`^[a-z]{2}(-gov|-iso[a-z]*)?-[a-z]+-\d+$`)
+	// Custom metric namespaces must not start with the reserved "AWS/" prefix
+	namespacePattern = regexp.MustCompile(`^[A-Za-z0-9._/#:-]{1,255}$`)
+)
+
+// loader reads config values and collects the problems found along the way.
+type loader struct {
+	src      Source
+	problems []error
+}
+
+func newLoader(src Source) *loader {
+	l := &loader{src: src}
+
+	// projectName prefixes every resource name (RDS identifiers must start
+	// with a letter and only contain lowercase letters, digits and hyphens)
+	if name := src.Get("projectName"); name != "" && !projectNamePattern.MatchString(name) {
+		l.errorf("projectName must start with a letter and contain only lowercase letters, digits and hyphens (got %q)", name)
+	}
+	if region := src.Get("region"); region != "" {
+		l.region("region", region)
+	}
+	return l
+}
+
+func (l *loader) errorf(format string, args ...interface{}) {
+	l.problems = append(l.problems, fmt.Errorf(format, args...))
+}
+
+// err returns the collected problems, or nil when there are none.
+func (l *loader) err() error {
+	if len(l.problems) == 0 {
+		return nil
+	}
+	return &Error{Problems: l.problems}
+}
+
+// get returns the value of key, or def when it is not set.
+func (l *loader) get(key, def string) string {
+	if value := l.src.Get(key); value != "" {
+		return value
+	}
+	return def
+}
+
+// require returns the value of key and records a problem with hint (the
+// command that sets it) when it is not set.
+func (l *loader) require(key, hint string) string {
+	value := l.src.Get(key)
+	if value == "" {
+		l.errorf("%s is required; set it with: %s", key, hint)
+	}
+	return value
+}
+
+// int returns the integer value of key, or def when it is not set.
+func (l *loader) int(key string, def int) int {
+	raw := l.src.Get(key)
+	if raw == "" {
+		return def
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		l.errorf("%s must be an integer (got %q)", key, raw)
+		return def
+	}
+	return value
+}
+
+// float returns the numeric value of key, or def when it is not set.
+func (l *loader) float(key string, def float64) float64 {
+	raw := l.src.Get(key)
+	if raw == "" {
+		return def
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		l.errorf("%s must be a number (got %q)", key, raw)
+		return def
+	}
+	return value
+}
+
+// bool returns the boolean value of key, or def when it is not set.
+func (l *loader) bool(key string, def bool) bool {
+	raw := l.src.Get(key)
+	if raw == "" {
+		return def
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		l.errorf("%s must be true or false (got %q)", key, raw)
+		return def
+	}
+	return value
+}
+
+// object decodes the structured value of key into output.
+func (l *loader) object(key, expected string, output interface{}) {
+	if err := l.src.GetObject(key, output); err != nil {
+		l.errorf("invalid %s config (expected %s): %v", key, expected, err)
+	}
+}
+
+// oneOf records a problem when value is not one of allowed.
+func (l *loader) oneOf(key, value string, allowed ...string) {
+	if !slices.Contains(allowed, value) {
+		l.errorf("%s must be one of %s (got %q)", key, strings.Join(allowed, ", "), value)
+	}
+}
+
+// cidr parses value as an IPv4 CIDR block, recording a problem when it is
+// malformed or has host bits set.
+func (l *loader) cidr(key, value string) (netip.Prefix, bool) {
+	prefix, err := netip.ParsePrefix(value)
+	switch {
+	case err != nil || !prefix.Addr().Is4():
+		l.errorf("%s must be an IPv4 CIDR block such as 10.0.0.0/16 (got %q)", key, value)
+		return netip.Prefix{}, false
+	case prefix.Masked() != prefix:
+		l.errorf("%s has host bits set; use %s (got %q)", key, prefix.Masked(), value)
+		return netip.Prefix{}, false
+	}
+	return prefix, true
+}
+
+// metricsNamespace records a problem when value is set but is not a valid
+// CloudWatch custom metric namespace.
+func (l *loader) metricsNamespace(key, value string) {
+	if value != "" && (!namespacePattern.MatchString(value) || strings.HasPrefix(value, "AWS/")) {
+		l.errorf("%s must be a CloudWatch custom namespace such as AuroraLab/Simulator (got %q)", key, value)
+	}
+}
+
+// schedule records a problem when value is not an EventBridge cron or rate
+// schedule expression.
+func (l *loader) schedule(key, value, example string) {
+	if !(strings.HasPrefix(value, "cron(") || strings.HasPrefix(value, "rate(")) || !strings.HasSuffix(value, ")") {
+		l.errorf("%s must be an EventBridge schedule expression such as %s or rate(1 day) (got %q)", key, example, value)
+	}
+}
+
+// region records a problem when value does not look like an AWS region name.
+func (l *loader) region(key, value string) {
+	if !regionPattern.MatchString(value) {
+		l.errorf("%s must be an AWS region name such as us-east-1 (got %q)", key, value)
+	}
+}
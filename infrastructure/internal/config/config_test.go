@@ -0,0 +1,927 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// values is a Source backed by a map; structured values are JSON, as in the
+// Pulumi stack configuration.
+type values map[string]string
+
+func (v values) Get(key string) string {
+	return v[key]
+}
+
+func (v values) GetObject(key string, output interface{}) error {
+	if v[key] == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(v[key]), output)
+}
+
+// expectProblems fails the test unless err lists exactly one problem
+// containing each of want, in order.
+func expectProblems(t *testing.T, err error, want ...string) {
+	t.Helper()
+	if len(want) == 0 {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return
+	}
+	var cfgErr *Error
+	if !errors.As(err, &cfgErr) {
+		t.Fatalf("got %v, want a config error listing %v", err, want)
+	}
+	if len(cfgErr.Problems) != len(want) {
+		t.Fatalf("got %d problems, want %d:\n%v", len(cfgErr.Problems), len(want), err)
+	}
+	for i, problem := range cfgErr.Problems {
+		if !strings.Contains(problem.Error(), want[i]) {
+			t.Errorf("problem %d: got %q, want it to mention %q", i, problem, want[i])
+		}
+	}
+}
+
+func TestLoadVpcDefaults(t *testing.T) {
+	c, err := LoadVpc(values{})
+	expectProblems(t, err)
+	if c.VpcCidr != "10.0.0.0/16" || c.SshCidr != "0.0.0.0/0" || c.StrictNetworkAcls || c.EnableIpv6 {
+		t.Errorf("got %+v, want the default IPv4 CIDRs without strict network ACLs", c)
+	}
+	if c.ExistingVpcId != "" || c.ExistingAuroraSubnetIds != nil || c.ExistingEksSubnetIds != nil {
+		t.Errorf("got %+v, want a new VPC", c)
+	}
+	if want := []string{"10.0.20.0/24", "10.0.21.0/24"}; c.SecondaryCidrBlocks != nil || !slices.Equal(c.EksSubnetCidrs, want) {
+		t.Errorf("got %+v, want the EKS subnets %v without secondary CIDR blocks", c, want)
+	}
+	if c.FlowLogs || c.FlowLogDestination != "cloudwatch" || c.FlowLogTrafficType != "ALL" || c.FlowLogRetentionDays != 14 {
+		t.Errorf("got %+v, want flow logs disabled with CloudWatch, ALL and 14 days defaults", c)
+	}
+}
+
+func TestLoadVpcExisting(t *testing.T) {
+	c, err := LoadVpc(values{
+		"existingVpcId":           "vpc-0123456789abcdef0",
+		"existingAuroraSubnetIds": `["subnet-0aaaaaaaaaaaaaaaa", "subnet-0bbbbbbbbbbbbbbbb"]`,
+		"existingEc2SubnetId":     "subnet-0cccccccccccccccc",
+		"flowLogs":                "true",
+	})
+	expectProblems(t, err)
+	if want := []string{"subnet-0aaaaaaaaaaaaaaaa", "subnet-0bbbbbbbbbbbbbbbb"}; !slices.Equal(c.ExistingAuroraSubnetIds, want) {
+		t.Errorf("ExistingAuroraSubnetIds = %v, want %v", c.ExistingAuroraSubnetIds, want)
+	}
+
+	_, err = LoadVpc(values{
+		"existingVpcId":           "vpc-123",
+		"existingAuroraSubnetIds": `["subnet-0aaaaaaaaaaaaaaaa"]`,
+		"existingEksSubnetIds":    `["subnet-0dddddddddddddddd", "subnet-0eeeeeeeeeeeeeeee", "subnet-0ffffffffffffffff"]`,
+		"strictNetworkAcls":       "true",
+		"enableIpv6":              "true",
+		"natGateway":              "true",
+	})
+	expectProblems(t, err,
+		"existingVpcId must be a VPC ID",
+		"existingAuroraSubnetIds must list two subnets",
+		"existingEc2SubnetId is required",
+		"existingEksSubnetIds must list two subnets",
+		"strictNetworkAcls is not supported",
+		"enableIpv6 is not supported",
+		"natGateway is not supported")
+
+	_, err = LoadVpc(values{
+		"existingVpcId":           "vpc-0123456789abcdef0",
+		"existingAuroraSubnetIds": `["subnet-0aaaaaaaaaaaaaaaa", "sn-2"]`,
+		"existingEc2SubnetId":     "subnet-0cccccccccccccccc",
+	})
+	expectProblems(t, err, `existing subnet IDs must look like subnet-0123456789abcdef0 (got "sn-2")`)
+
+	_, err = LoadVpc(values{"existingEc2SubnetId": "subnet-0cccccccccccccccc"})
+	expectProblems(t, err, "existingAuroraSubnetIds, existingEc2SubnetId and existingEksSubnetIds require existingVpcId")
+}
+
+func TestLoadVpcFlowLogs(t *testing.T) {
+	_, err := LoadVpc(values{"flowLogs": "true", "flowLogDestination": "s3", "flowLogTrafficType": "REJECT", "flowLogRetentionDays": "10"})
+	expectProblems(t, err)
+
+	_, err = LoadVpc(values{"flowLogDestination": "firehose", "flowLogTrafficType": "all"})
+	expectProblems(t, err, "flowLogDestination must be one of cloudwatch, s3", "flowLogTrafficType must be one of ALL, ACCEPT, REJECT")
+
+	// CloudWatch Logs only accepts its fixed retention periods
+	_, err = LoadVpc(values{"flowLogRetentionDays": "10"})
+	expectProblems(t, err, "flowLogRetentionDays must be a CloudWatch Logs retention period")
+	_, err = LoadVpc(values{"flowLogDestination": "s3", "flowLogRetentionDays": "0"})
+	expectProblems(t, err, "flowLogRetentionDays must be at least 1")
+}
+
+func TestLoadVpcCidrs(t *testing.T) {
+	_, err := LoadVpc(values{"vpcCidr": "10.0.0.0/16", "sshCidr": "203.0.113.10/32"})
+	expectProblems(t, err)
+
+	_, err = LoadVpc(values{"vpcCidr": "10.0.0/16", "sshCidr": "203.0.113.10/24"})
+	expectProblems(t, err, "vpcCidr must be an IPv4 CIDR block", "sshCidr has host bits set; use 203.0.113.0/24")
+
+	// The lab subnets use fixed 10.0.x.0/24 ranges
+	_, err = LoadVpc(values{"vpcCidr": "172.16.0.0/16"})
+	expectProblems(t, err, "vpcCidr must contain the lab subnets")
+	_, err = LoadVpc(values{"vpcCidr": "10.0.0.0/24"})
+	expectProblems(t, err, "vpcCidr must contain the lab subnets")
+}
+
+func TestLoadVpcSecondaryCidrBlocks(t *testing.T) {
+	c, err := LoadVpc(values{
+		"secondaryCidrBlocks": `["100.64.0.0/16"]`,
+		"eksSubnetCidrs":      `["100.64.0.0/18", "100.64.64.0/18"]`,
+	})
+	expectProblems(t, err)
+	if !slices.Equal(c.SecondaryCidrBlocks, []string{"100.64.0.0/16"}) {
+		t.Errorf("SecondaryCidrBlocks = %v", c.SecondaryCidrBlocks)
+	}
+
+	// The EKS subnets stay in the primary block by default
+	_, err = LoadVpc(values{"vpcCidr": "10.0.0.0/20", "secondaryCidrBlocks": `["100.64.0.0/16"]`})
+	expectProblems(t, err,
+		"eksSubnetCidrs entry 10.0.20.0/24 must be within vpcCidr or one of the secondaryCidrBlocks",
+		"eksSubnetCidrs entry 10.0.21.0/24 must be within vpcCidr or one of the secondaryCidrBlocks")
+
+	_, err = LoadVpc(values{
+		"secondaryCidrBlocks": `["10.0.128.0/17", "100.0.0.0/8", "100.64.0.1/16", "100.64.0.0/16", "100.65.0.0/16"]`,
+		"eksSubnetCidrs":      `["10.0.10.0/23", "100.64.0.0/17"]`,
+	})
+	expectProblems(t, err,
+		"secondaryCidrBlocks must list at most 4 blocks (got 5)",
+		"secondaryCidrBlocks entry 10.0.128.0/17 overlaps 10.0.0.0/16",
+		`secondaryCidrBlocks must be between /16 and /28 (got "100.0.0.0/8")`,
+		"secondaryCidrBlocks has host bits set",
+		"eksSubnetCidrs entry 10.0.10.0/23 overlaps the subnet 10.0.10.0/24")
+
+	_, err = LoadVpc(values{"eksSubnetCidrs": `["10.0.20.0/24"]`})
+	expectProblems(t, err, "eksSubnetCidrs must list two CIDR blocks (got 1)")
+
+	_, err = LoadVpc(values{"eksSubnetCidrs": `["10.0.20.0/24", "10.0.20.0/25"]`})
+	expectProblems(t, err, "eksSubnetCidrs entry 10.0.20.0/25 overlaps the subnet 10.0.20.0/24")
+}
+
+func TestLoadCommonKeys(t *testing.T) {
+	_, err := LoadVpc(values{"projectName": "Aurora_Lab", "region": "us-east"})
+	expectProblems(t, err, "projectName must start with a letter", "region must be an AWS region name")
+
+	_, err = LoadVpc(values{"projectName": "lab-2", "region": "us-gov-west-1"})
+	expectProblems(t, err)
+}
+
+// auroraValues is the minimal valid Aurora stack configuration.
+func auroraValues() values {
+	return values{
+		"vpcStackName":   "organization/aurora-bluegreen-vpc/dev",
+		"masterPassword": "YourStrongPassword123!",
+	}
+}
+
+func TestLoadAuroraDefaults(t *testing.T) {
+	c, err := LoadAurora(auroraValues())
+	expectProblems(t, err)
+	if c.EngineVersion != "8.0.mysql_aurora.3.04.0" || c.InstanceClass != "db.r6g.xlarge" || c.BinlogRetentionHours != 24 || c.StorageType != "aurora" {
+		t.Errorf("got %+v, want the lab defaults", c)
+	}
+	if c.Parameters != nil || c.GreenParameters != nil {
+		t.Errorf("got parameter overrides %v, %v, want none", c.Parameters, c.GreenParameters)
+	}
+}
+
+func TestLoadAuroraAggregatesProblems(t *testing.T) {
+	_, err := LoadAurora(values{
+		"engineVersion":      "5.6.mysql_aurora.1.23.4",
+		"instanceClass":      "db.m5.large",
+		"monitoringInterval": "often",
+		"binlogFormat":       "row",
+		"storageType":        "io1",
+	})
+	expectProblems(t, err,
+		"vpcStackName is required; set it with: pulumi config set vpcStackName",
+		"monitoringInterval must be an integer",
+		"masterPassword is required",
+		"engineVersion must be an Aurora MySQL 2 or 3 version",
+		`instanceClass "db.m5.large" is not a supported`,
+		"storageType must be one of aurora, aurora-iopt1",
+		"binlogFormat must be one of ROW, MIXED, STATEMENT, OFF",
+	)
+}
+
+func TestLoadAuroraInstanceClass(t *testing.T) {
+	for _, class := range []string{"db.r6g.xlarge", "db.t4g.medium", "db.r7i.48xlarge", "db.serverless"} {
+		v := auroraValues()
+		v["instanceClass"] = class
+		_, err := LoadAurora(v)
+		expectProblems(t, err)
+	}
+	for _, class := range []string{"r6g.xlarge", "db.t4g.xlarge", "db.r6g"} {
+		v := auroraValues()
+		v["instanceClass"] = class
+		_, err := LoadAurora(v)
+		expectProblems(t, err, "is not a supported Aurora MySQL instance class")
+	}
+}
+
+func TestLoadAuroraMajorVersionUpgrade(t *testing.T) {
+	v := auroraValues()
+	v["engineVersion"] = "5.7.mysql_aurora.2.12.1"
+	v["greenEngineVersion"] = "8.0.mysql_aurora.3.08.0"
+	c, err := LoadAurora(v)
+	expectProblems(t, err)
+	if c.ParameterGroupFamily != "aurora-mysql5.7" {
+		t.Errorf("family: got %q, want aurora-mysql5.7", c.ParameterGroupFamily)
+	}
+	if c.GreenParameters == nil || c.GreenParameters.Family != "aurora-mysql8.0" {
+		t.Errorf("greenParameters: got %+v, want the aurora-mysql8.0 family", c.GreenParameters)
+	}
+
+	// A minor upgrade keeps the blue family and creates no green groups
+	v["greenEngineVersion"] = "5.7.mysql_aurora.2.12.2"
+	c, err = LoadAurora(v)
+	expectProblems(t, err)
+	if c.GreenParameters != nil {
+		t.Errorf("greenParameters: got %+v, want none", c.GreenParameters)
+	}
+
+	v["greenEngineVersion"] = "8.0.mysql_aurora.3.08.0"
+	v["greenParameters"] = `{"family": "aurora-mysql5.7"}`
+	v["instanceClass"] = "db.r7g.large"
+	_, err = LoadAurora(v)
+	expectProblems(t, err, `instanceClass "db.r7g.large" needs Aurora MySQL 3`, "greenParameters family aurora-mysql5.7 does not match")
+
+	v = auroraValues()
+	v["greenEngineVersion"] = "5.7.mysql_aurora.2.12.1"
+	_, err = LoadAurora(v)
+	expectProblems(t, err, "cannot downgrade the major version")
+}
+
+func TestLoadAuroraMasterPassword(t *testing.T) {
+	v := auroraValues()
+	v["masterPassword"] = "p@ss"
+	_, err := LoadAurora(v)
+	expectProblems(t, err, "must be 8 to 41 characters", "must not contain")
+	if strings.Contains(err.Error(), "p@ss") {
+		t.Errorf("error %q echoes the password", err)
+	}
+}
+
+func TestLoadAuroraGlobalDatabase(t *testing.T) {
+	v := auroraValues()
+	v["secondaryRegion"] = "us-west-2"
+	_, err := LoadAurora(v)
+	expectProblems(t, err, "secondaryRegion requires globalDatabase", "secondaryRegion requires secondaryVpcStackName")
+
+	v["globalDatabase"] = "true"
+	v["secondaryVpcStackName"] = "organization/aurora-bluegreen-vpc/dr"
+	c, err := LoadAurora(v)
+	expectProblems(t, err)
+	if !c.GlobalDatabase {
+		t.Error("globalDatabase: got false, want true")
+	}
+}
+
+func TestLoadAuroraBacktrackWindow(t *testing.T) {
+	v := auroraValues()
+	v["backtrackWindow"] = "86400"
+	c, err := LoadAurora(v)
+	expectProblems(t, err)
+	if c.BacktrackWindow != 86400 {
+		t.Errorf("backtrackWindow: got %d, want 86400", c.BacktrackWindow)
+	}
+
+	v["backtrackWindow"] = "300000"
+	v["globalDatabase"] = "true"
+	_, err = LoadAurora(v)
+	expectProblems(t, err, "backtrackWindow must be between 0 and 259200", "backtrackWindow cannot be combined with globalDatabase")
+}
+
+func TestLoadAuroraActivityStream(t *testing.T) {
+	v := auroraValues()
+	v["activityStreamKmsKeyId"] = "alias/lab-das"
+	v["instanceClass"] = "db.t4g.medium"
+	_, err := LoadAurora(v)
+	expectProblems(t, err, "activityStreamKmsKeyId requires activityStream")
+
+	v["activityStream"] = "true"
+	_, err = LoadAurora(v)
+	expectProblems(t, err, "activityStream is not supported on burstable instance classes")
+
+	v["instanceClass"] = "db.r6g.large"
+	c, err := LoadAurora(v)
+	expectProblems(t, err)
+	if !c.ActivityStream || c.ActivityStreamKmsKeyId != "alias/lab-das" {
+		t.Errorf("got activityStream %v, key %q", c.ActivityStream, c.ActivityStreamKmsKeyId)
+	}
+}
+
+func TestLoadAuroraPerformanceInsights(t *testing.T) {
+	c, err := LoadAurora(auroraValues())
+	expectProblems(t, err)
+	if !c.PerformanceInsightsEnabled || c.PerformanceInsightsRetentionPeriod != 7 {
+		t.Errorf("got Performance Insights %v, %d days, want enabled for 7 days", c.PerformanceInsightsEnabled, c.PerformanceInsightsRetentionPeriod)
+	}
+
+	for _, days := range []string{"7", "31", "372", "713", "731"} {
+		v := auroraValues()
+		v["performanceInsightsRetentionPeriod"] = days
+		_, err := LoadAurora(v)
+		expectProblems(t, err)
+	}
+	for _, days := range []string{"0", "30", "62.5", "744"} {
+		v := auroraValues()
+		v["performanceInsightsRetentionPeriod"] = days
+		_, err := LoadAurora(v)
+		if err == nil {
+			t.Errorf("performanceInsightsRetentionPeriod %s: got no error", days)
+		}
+	}
+
+	v := auroraValues()
+	v["performanceInsightsEnabled"] = "false"
+	v["performanceInsightsKmsKeyId"] = "alias/pi"
+	_, err = LoadAurora(v)
+	expectProblems(t, err, "performanceInsightsKmsKeyId requires performanceInsightsEnabled")
+}
+
+func TestLoadAuroraMaintenanceWindows(t *testing.T) {
+	c, err := LoadAurora(auroraValues())
+	expectProblems(t, err)
+	if c.AutoMinorVersionUpgrade || c.PreferredMaintenanceWindow != "mon:04:00-mon:05:00" || c.PreferredBackupWindow != "03:00-04:00" {
+		t.Errorf("got %v, %q, %q, want the lab defaults", c.AutoMinorVersionUpgrade, c.PreferredMaintenanceWindow, c.PreferredBackupWindow)
+	}
+
+	for _, windows := range [][2]string{
+		{"sun:23:30-mon:00:30", "02:00-02:30"},
+		{"wed:10:00-wed:10:30", "23:45-00:30"},
+	} {
+		v := auroraValues()
+		v["preferredMaintenanceWindow"], v["preferredBackupWindow"] = windows[0], windows[1]
+		_, err := LoadAurora(v)
+		expectProblems(t, err)
+	}
+
+	for windows, problem := range map[[2]string]string{
+		{"monday:04:00-monday:05:00", "03:00-04:00"}: "preferredMaintenanceWindow must be ddd:hh24:mi-ddd:hh24:mi",
+		{"mon:04:00-mon:04:15", "03:00-04:00"}:       "preferredMaintenanceWindow must be at least 30 minutes",
+		{"mon:04:00-mon:05:00", "3:00-4:00"}:         "preferredBackupWindow must be hh24:mi-hh24:mi",
+		{"mon:04:00-mon:05:00", "03:00-03:20"}:       "preferredBackupWindow must be at least 30 minutes",
+		{"mon:04:00-mon:05:00", "04:30-05:30"}:       "overlaps preferredMaintenanceWindow",
+		{"sun:23:30-mon:00:30", "00:00-01:00"}:       "overlaps preferredMaintenanceWindow",
+	} {
+		v := auroraValues()
+		v["preferredMaintenanceWindow"], v["preferredBackupWindow"] = windows[0], windows[1]
+		_, err := LoadAurora(v)
+		expectProblems(t, err, problem)
+	}
+}
+
+func TestLoadAuroraReaderAutoScaling(t *testing.T) {
+	c, err := LoadAurora(auroraValues())
+	expectProblems(t, err)
+	if c.ReaderAutoScaling != nil {
+		t.Errorf("got reader auto scaling %+v, want none", c.ReaderAutoScaling)
+	}
+
+	v := auroraValues()
+	v["readerAutoScaling"] = "true"
+	c, err = LoadAurora(v)
+	expectProblems(t, err)
+	if s := c.ReaderAutoScaling; s == nil || s.MinCapacity != 1 || s.MaxCapacity != 3 || s.Metric != "cpu" || s.TargetValue != 70 {
+		t.Errorf("got %+v, want the defaults", s)
+	}
+
+	v["readerMinCapacity"] = "4"
+	v["readerScalingMetric"] = "memory"
+	v["readerScalingTargetValue"] = "0"
+	_, err = LoadAurora(v)
+	expectProblems(t, err,
+		"readerMinCapacity and readerMaxCapacity must satisfy 1 <= min <= max <= 15",
+		"readerScalingMetric must be one of cpu, connections",
+		"readerScalingTargetValue must be greater than 0",
+	)
+}
+
+func TestLoadAuroraBackupCopy(t *testing.T) {
+	v := auroraValues()
+	v["region"] = "us-east-1"
+	v["backupCopyRegion"] = "us-west-2"
+	c, err := LoadAurora(v)
+	expectProblems(t, err)
+	if b := c.BackupCopy; b == nil || b.Region != "us-west-2" || b.Schedule != "cron(0 5 * * ? *)" || b.RetentionDays != 7 {
+		t.Errorf("got %+v, want a copy to us-west-2 with the defaults", b)
+	}
+
+	v["backupCopyRegion"] = "us-east-1"
+	v["backupCopySchedule"] = "daily"
+	v["backupCopyRetentionDays"] = "0"
+	_, err = LoadAurora(v)
+	expectProblems(t, err,
+		"backupCopyRegion must differ from the stack's region",
+		"backupCopySchedule must be an AWS Backup cron expression",
+		"backupCopyRetentionDays must be between 1 and 365",
+	)
+}
+
+func TestLoadAuroraExternalReplica(t *testing.T) {
+	v := auroraValues()
+	c, err := LoadAurora(v)
+	expectProblems(t, err)
+	if c.ExternalReplica != nil {
+		t.Errorf("got %+v, want no external replica by default", c.ExternalReplica)
+	}
+
+	v["externalReplica"] = "true"
+	c, err = LoadAurora(v)
+	expectProblems(t, err)
+	if r := c.ExternalReplica; r == nil || r.InstanceClass != "db.t4g.micro" || r.EngineVersion != "8.0" {
+		t.Errorf("got %+v, want a db.t4g.micro MySQL 8.0 replica", r)
+	}
+
+	v["externalReplicaInstanceClass"] = "t4g.micro"
+	v["externalReplicaEngineVersion"] = "5.7"
+	v["binlogFormat"] = "OFF"
+	_, err = LoadAurora(v)
+	expectProblems(t, err,
+		"externalReplicaInstanceClass must be an RDS instance class",
+		"externalReplicaEngineVersion 5.7 cannot replicate from Aurora MySQL 3",
+		"externalReplica needs binary logging",
+	)
+
+	v["externalReplicaEngineVersion"] = "8.4"
+	v["binlogFormat"] = "ROW"
+	v["externalReplicaInstanceClass"] = "db.t4g.small"
+	_, err = LoadAurora(v)
+	expectProblems(t, err, "externalReplicaEngineVersion must be an RDS for MySQL 5.7 or 8.0 version")
+}
+
+func TestLoadAuroraRdsProxy(t *testing.T) {
+	v := auroraValues()
+	c, err := LoadAurora(v)
+	expectProblems(t, err)
+	if c.RdsProxy != nil {
+		t.Errorf("got %+v, want no proxy by default", c.RdsProxy)
+	}
+
+	v["rdsProxy"] = "true"
+	c, err = LoadAurora(v)
+	expectProblems(t, err)
+	if p := c.RdsProxy; p == nil || p.MaxConnectionsPercent != 100 || p.RequireTls {
+		t.Errorf("got %+v, want a proxy using 100%% of the connections without TLS", p)
+	}
+
+	v["rdsProxyMaxConnectionsPercent"] = "0"
+	_, err = LoadAurora(v)
+	expectProblems(t, err, "rdsProxyMaxConnectionsPercent must be between 1 and 100")
+}
+
+func TestLoadAuroraParameters(t *testing.T) {
+	v := auroraValues()
+	v["parameters"] = `{"cluster": [{"name": "binlog_format", "value": "MIXED"}]}`
+	c, err := LoadAurora(v)
+	expectProblems(t, err)
+	if c.Parameters == nil || c.Parameters.Value("binlog_format") != "MIXED" {
+		t.Errorf("parameters: got %+v, want binlog_format=MIXED", c.Parameters)
+	}
+
+	path := filepath.Join(t.TempDir(), "green.json")
+	if err := os.WriteFile(path, []byte(`{"instance": [{"name": "max_connections", "applyMethod": "later"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	v["greenParametersFile"] = path
+	_, err = LoadAurora(v)
+	expectProblems(t, err, `parameter max_connections has invalid applyMethod "later"`)
+
+	v["parametersFile"] = path
+	_, err = LoadAurora(v)
+	expectProblems(t, err, "set either parameters or parametersFile, not both", "invalid applyMethod")
+}
+
+func TestLoadAuroraYamlParameterDiff(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "green.yaml")
+	diff := `cluster:
+  - name: binlog_format
+    value: MIXED
+    applyMethod: pending-reboot
+  - name: binlog_row_image
+    reset: true
+instance:
+  - name: max_connections
+    value: "2000"
+`
+	if err := os.WriteFile(path, []byte(diff), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	v := auroraValues()
+	v["greenParametersFile"] = path
+	c, err := LoadAurora(v)
+	expectProblems(t, err)
+	green := c.GreenParameters
+	if green == nil || len(green.Cluster) != 2 || green.Cluster[0].ApplyMethod != "pending-reboot" || !green.Cluster[1].Reset {
+		t.Fatalf("greenParameters: got %+v", green)
+	}
+	if green.Instance[0].Value != "2000" {
+		t.Errorf("max_connections: got %q, want 2000", green.Instance[0].Value)
+	}
+
+	if err := os.WriteFile(path, []byte("cluster:\n  - name: binlog_format\n    value: ROW\n    reset: true\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = LoadAurora(v)
+	expectProblems(t, err, "parameter binlog_format sets both a value and reset")
+}
+
+// ec2Values is the minimal valid EC2 stack configuration.
+func ec2Values() values {
+	return values{
+		"vpcStackName": "organization/aurora-bluegreen-vpc/dev",
+		"keyName":      "aurora-lab-key",
+	}
+}
+
+func TestLoadEc2Defaults(t *testing.T) {
+	c, err := LoadEc2(ec2Values())
+	expectProblems(t, err)
+	if c.Architecture != "x86_64" || c.InstanceType != "t3.xlarge" || c.SimulatorCount != 0 {
+		t.Errorf("got %+v, want the lab defaults", c)
+	}
+
+	v := ec2Values()
+	v["architecture"] = "arm64"
+	c, err = LoadEc2(v)
+	expectProblems(t, err)
+	if c.InstanceType != "t4g.xlarge" {
+		t.Errorf("instanceType: got %s, want t4g.xlarge", c.InstanceType)
+	}
+}
+
+func TestLoadEc2AggregatesProblems(t *testing.T) {
+	_, err := LoadEc2(values{
+		"architecture":      "aarch64",
+		"simulatorCount":    "4",
+		"spotInstanceTypes": `["m5.xlarge", "m6i"]`,
+		"simulatorJar":      filepath.Join(t.TempDir(), "workload-simulator.jar"),
+	})
+	expectProblems(t, err,
+		"vpcStackName is required",
+		"keyName is required; set it with: pulumi config set keyName <your-key-pair-name>",
+		"architecture must be x86_64 or arm64",
+		"simulatorCount requires auroraStackName and dbPassword",
+		`spotInstanceTypes must contain EC2 instance types such as m5.xlarge (got "m6i")`,
+		"simulatorJar",
+	)
+}
+
+func TestLoadEc2SimulatorGroup(t *testing.T) {
+	v := ec2Values()
+	v["simulatorCount"] = "2"
+	v["auroraStackName"] = "organization/aurora-bluegreen-aurora/dev"
+	v["dbPassword"] = "YourStrongPassword123!"
+	v["spotOnDemandBaseCapacity"] = "3"
+	_, err := LoadEc2(v)
+	expectProblems(t, err, "spotOnDemandBaseCapacity must be between 0 and simulatorCount")
+
+	v["spotOnDemandBaseCapacity"] = "1"
+	c, err := LoadEc2(v)
+	expectProblems(t, err)
+	if !c.HasDbPassword || c.SimulatorCount != 2 {
+		t.Errorf("got %+v, want a group of 2 with the service configured", c)
+	}
+}
+
+func TestLoadEc2InstanceCount(t *testing.T) {
+	c, err := LoadEc2(ec2Values())
+	expectProblems(t, err)
+	if c.InstanceCount != 1 {
+		t.Errorf("instanceCount: got %d, want 1", c.InstanceCount)
+	}
+
+	v := ec2Values()
+	v["instanceCount"] = "7"
+	_, err = LoadEc2(v)
+	expectProblems(t, err, "instanceCount must be between 1 and 6 (got 7)")
+
+	v["instanceCount"] = "2"
+	v["simulatorCount"] = "2"
+	v["auroraStackName"] = "organization/aurora-bluegreen-aurora/dev"
+	v["dbPassword"] = "YourStrongPassword123!"
+	_, err = LoadEc2(v)
+	expectProblems(t, err, "instanceCount is for single instances")
+}
+
+func TestLoadEc2AllocateEip(t *testing.T) {
+	v := ec2Values()
+	v["allocateEip"] = "true"
+	c, err := LoadEc2(v)
+	expectProblems(t, err)
+	if !c.AllocateEip {
+		t.Error("allocateEip: got false, want true")
+	}
+
+	v["privateSimulator"] = "true"
+	_, err = LoadEc2(v)
+	expectProblems(t, err, "allocateEip needs a public instance")
+}
+
+func TestLoadEc2RegistryStackName(t *testing.T) {
+	v := ec2Values()
+	v["registryStackName"] = "organization/aurora-bluegreen-registry/dev"
+	_, err := LoadEc2(v)
+	expectProblems(t, err, "registryStackName requires auroraStackName and dbPassword")
+
+	v["auroraStackName"] = "organization/aurora-bluegreen-aurora/dev"
+	v["dbPassword"] = "secret"
+	c, err := LoadEc2(v)
+	expectProblems(t, err)
+	if c.RegistryStackName != "organization/aurora-bluegreen-registry/dev" {
+		t.Errorf("registryStackName: got %q", c.RegistryStackName)
+	}
+}
+
+func TestLoadEc2IamDbUser(t *testing.T) {
+	v := ec2Values()
+	v["iamDbUser"] = "lab-iam"
+	_, err := LoadEc2(v)
+	expectProblems(t, err, "iamDbUser must start with a letter", "iamDbUser requires auroraStackName")
+
+	v["iamDbUser"] = "lab_iam"
+	v["auroraStackName"] = "organization/aurora-bluegreen-aurora/dev"
+	c, err := LoadEc2(v)
+	expectProblems(t, err)
+	if c.IamDbUser != "lab_iam" {
+		t.Errorf("iamDbUser: got %q, want lab_iam", c.IamDbUser)
+	}
+}
+
+func TestLoadEc2ExperimentTableName(t *testing.T) {
+	v := ec2Values()
+	v["experimentTableName"] = "lab experiments"
+	_, err := LoadEc2(v)
+	expectProblems(t, err, "experimentTableName must be a DynamoDB table name")
+
+	v["experimentTableName"] = "aurora-bluegreen-lab-experiments"
+	c, err := LoadEc2(v)
+	expectProblems(t, err)
+	if c.ExperimentTableName != "aurora-bluegreen-lab-experiments" {
+		t.Errorf("experimentTableName: got %q", c.ExperimentTableName)
+	}
+}
+
+func TestLoadEc2SimulatorLogs(t *testing.T) {
+	c, err := LoadEc2(ec2Values())
+	expectProblems(t, err)
+	if c.SimulatorLogs || c.SimulatorLogRetentionDays != 14 {
+		t.Errorf("got %+v, want logs off with 14 days retention", c)
+	}
+
+	v := ec2Values()
+	v["simulatorLogs"] = "true"
+	v["simulatorLogRetentionDays"] = "10"
+	_, err = LoadEc2(v)
+	expectProblems(t, err, "simulatorLogRetentionDays must be a CloudWatch Logs retention period")
+}
+
+func TestLoadMonitoring(t *testing.T) {
+	c, err := LoadMonitoring(values{"auroraStackName": "organization/aurora-bluegreen-aurora/dev"})
+	expectProblems(t, err)
+	if c.MetricPeriod != 60 || c.CpuAlarmThreshold != 80 || c.EventLogRetentionDays != 14 ||
+		!c.RecordBlueGreenEvents || c.BlueGreenMetricsNamespace != "AuroraLab/BlueGreen" || !c.ExperimentRegistry {
+		t.Errorf("got %+v, want the lab defaults", c)
+	}
+
+	_, err = LoadMonitoring(values{
+		"metricPeriod":              "90",
+		"eventAnnotations":          `[{"label": "Switchover started", "value": "13:40:12"}]`,
+		"alarmEmail":                "ops.example.com",
+		"cpuAlarmThreshold":         "120",
+		"eventLogRetentionDays":     "10",
+		"simulatorMetricsNamespace": "AWS/Simulator",
+		"recordBlueGreenEvents":     "yes",
+		"blueGreenMetricsNamespace": "Blue Green",
+		"experimentRegistry":        "on",
+	})
+	expectProblems(t, err,
+		"auroraStackName is required",
+		"recordBlueGreenEvents must be true or false",
+		"experimentRegistry must be true or false",
+		"metricPeriod must be 1, 5, 10, 30 or a multiple of 60",
+		"eventAnnotations[0].value must be an ISO 8601 timestamp",
+		"alarmEmail must be an email address",
+		"cpuAlarmThreshold is a percentage",
+		"eventLogRetentionDays must be a CloudWatch Logs retention period",
+		"simulatorMetricsNamespace must be a CloudWatch custom namespace",
+		"blueGreenMetricsNamespace must be a CloudWatch custom namespace",
+	)
+}
+
+func TestLoadOps(t *testing.T) {
+	c, err := LoadOps(values{"auroraStackName": "organization/aurora-bluegreen-aurora/dev"})
+	expectProblems(t, err)
+	if c.SnapshotSchedule != "cron(0 8 ? * MON-FRI *)" || c.SnapshotRetentionDays != 7 || c.LambdaLogRetentionDays != 14 {
+		t.Errorf("got %+v, want the lab defaults", c)
+	}
+
+	_, err = LoadOps(values{
+		"snapshotSchedule":       "0 8 * * *",
+		"snapshotRetentionDays":  "0",
+		"lambdaLogRetentionDays": "10",
+	})
+	expectProblems(t, err,
+		"auroraStackName is required",
+		"snapshotSchedule must be an EventBridge schedule expression",
+		"snapshotRetentionDays must be at least 1",
+		"lambdaLogRetentionDays must be a CloudWatch Logs retention period",
+	)
+}
+
+func TestLoadScheduler(t *testing.T) {
+	c, err := LoadScheduler(values{"ec2StackName": "organization/aurora-bluegreen-ec2/dev"})
+	expectProblems(t, err)
+	if c.StopCluster || c.StopSchedule != "cron(0 19 ? * MON-FRI *)" || c.StartSchedule != "cron(0 7 ? * MON-FRI *)" || c.ScheduleTimezone != "UTC" {
+		t.Errorf("got %+v, want the lab defaults", c)
+	}
+
+	_, err = LoadScheduler(values{
+		"stopCluster":      "true",
+		"stopSchedule":     "19:00",
+		"scheduleTimezone": "Central European Time",
+	})
+	expectProblems(t, err,
+		"ec2StackName is required",
+		"stopCluster requires auroraStackName",
+		"stopSchedule must be an EventBridge schedule expression",
+		"scheduleTimezone must be an IANA time zone",
+	)
+}
+
+func TestLoadBudget(t *testing.T) {
+	c, err := LoadBudget(values{"alertEmails": `["teacher@example.com"]`})
+	expectProblems(t, err)
+	if c.MonthlyLimitUsd != 100 || !slices.Equal(c.AlertThresholds, []float64{50, 80, 100}) || c.ForecastThreshold != 100 || c.ActivateCostAllocationTag {
+		t.Errorf("got %+v, want the lab defaults", c)
+	}
+	if !slices.Equal(c.AlertEmails, []string{"teacher@example.com"}) {
+		t.Errorf("got alertEmails %v", c.AlertEmails)
+	}
+
+	_, err = LoadBudget(values{
+		"monthlyLimitUsd": "0",
+		"alertThresholds": `[25, 50, 75, 100, -1]`,
+		"alertEmails":     `["teacher"]`,
+	})
+	expectProblems(t, err,
+		"monthlyLimitUsd must be greater than 0",
+		"alertThresholds[4] must be greater than 0",
+		"a budget allows 5 notifications",
+		"alertEmails[0] must be an email address",
+	)
+
+	_, err = LoadBudget(values{"alertThresholds": `[]`, "forecastThreshold": "0"})
+	expectProblems(t, err, "alertThresholds must list at least one percentage")
+}
+
+func TestLoadAccess(t *testing.T) {
+	c, err := LoadAccess(values{"vpcStackName": "organization/aurora-bluegreen-vpc/dev"})
+	expectProblems(t, err)
+	if c.SubnetId != "" {
+		t.Errorf("SubnetId = %q, want the VPC stack's EKS subnet", c.SubnetId)
+	}
+
+	_, err = LoadAccess(values{"subnetId": "subnet-1"})
+	expectProblems(t, err, "vpcStackName is required", "subnetId must be a subnet ID")
+}
+
+func TestLoadRegistry(t *testing.T) {
+	c, err := LoadRegistry(values{})
+	expectProblems(t, err)
+	if c.Platform != "linux/amd64" || !c.BuildImages {
+		t.Errorf("got %+v, want linux/amd64 images built by the stack", c)
+	}
+
+	if c.KeepImages != 10 || len(c.Repositories) != 0 {
+		t.Errorf("got %+v, want 10 images kept of the simulator alone", c)
+	}
+
+	_, err = LoadRegistry(values{"platform": "arm64"})
+	expectProblems(t, err, "platform must be one of linux/amd64, linux/arm64")
+
+	c, err = LoadRegistry(values{"keepImages": "3", "repositories": `["lab-agent", "lab-scheduler"]`})
+	expectProblems(t, err)
+	if c.KeepImages != 3 || len(c.Repositories) != 2 {
+		t.Errorf("got %+v, want 3 images kept of two more repositories", c)
+	}
+
+	_, err = LoadRegistry(values{"keepImages": "0", "repositories": `["Lab_Agent", "workload-simulator", "lab-agent", "lab-agent"]`})
+	expectProblems(t, err,
+		"keepImages must be between 1 and 1000",
+		"repositories[0] must be an image name",
+		"repositories lists workload-simulator more than once",
+		"repositories lists lab-agent more than once",
+	)
+}
+
+func TestLoadDms(t *testing.T) {
+	c, err := LoadDms(values{
+		"vpcStackName":    "org/aurora-bluegreen-vpc/dev",
+		"auroraStackName": "org/aurora-bluegreen-aurora/dev",
+		"dbPassword":      "YourStrongPassword123!",
+	})
+	expectProblems(t, err)
+	if c.ReplicationInstanceClass != "dms.t3.micro" || c.MigrationType != "full-load-and-cdc" || c.TablePattern != "%" || !c.S3GatewayEndpoint || !c.CreateDmsVpcRole {
+		t.Errorf("got %+v, want the lab defaults", c)
+	}
+
+	_, err = LoadDms(values{
+		"replicationInstanceClass": "t3.micro",
+		"migrationType":            "full-load-cdc",
+		"tablePattern":             `test_"%`,
+	})
+	expectProblems(t, err,
+		"vpcStackName is required",
+		"auroraStackName is required",
+		"dbPassword is required",
+		"replicationInstanceClass must be a DMS instance class",
+		"migrationType must be one of",
+		"tablePattern must be a table name",
+	)
+}
+
+func TestLoadFis(t *testing.T) {
+	c, err := LoadFis(values{"auroraStackName": "org/aurora-bluegreen-aurora/dev"})
+	expectProblems(t, err)
+	if c.NetworkDisruptionMinutes != 2 || c.NetworkDisruptionPort != 3306 {
+		t.Errorf("got %+v, want the lab defaults", c)
+	}
+
+	_, err = LoadFis(values{
+		"networkDisruptionMinutes": "0",
+		"networkDisruptionPort":    "70000",
+	})
+	expectProblems(t, err,
+		"auroraStackName is required",
+		"networkDisruptionMinutes must be between 1 and 720",
+		"networkDisruptionPort must be a TCP port",
+	)
+}
+
+func TestLoadEksWorkload(t *testing.T) {
+	v := values{
+		"auroraStackName":   "org/aurora-bluegreen-aurora/dev",
+		"registryStackName": "org/aurora-bluegreen-registry/dev",
+		"dbPassword":        "secret",
+		"oidcProviderArn":   "arn:aws:iam::123456789012:oidc-provider/oidc.eks.us-east-1.amazonaws.com/id/EXAMPLED539D4633E53DE1B71EXAMPLE",
+	}
+	c, err := LoadEksWorkload(v)
+	expectProblems(t, err)
+	if c.Namespace != "workload-simulator" || c.Kind != "deployment" || c.Replicas != 1 || c.MaxReplicas != 1 || !c.EnableMetrics {
+		t.Errorf("got %+v, want one pod of a deployment in the workload-simulator namespace", c)
+	}
+
+	v["kind"] = "job"
+	v["replicas"] = "3"
+	c, err = LoadEksWorkload(v)
+	expectProblems(t, err)
+	if c.Duration != 600 || c.MaxReplicas != 3 {
+		t.Errorf("job: got %+v, want three pods running for 600 seconds", c)
+	}
+
+	v["maxReplicas"] = "5"
+	v["simulatorOptions"] = "--write-workers 5 --duration 60"
+	v["observability"] = "true"
+	v["enableMetrics"] = "false"
+	v["monitoringNamespace"] = "Monitoring"
+	_, err = LoadEksWorkload(v)
+	expectProblems(t, err,
+		"maxReplicas scales a Deployment",
+		"observability scrapes the simulator's metrics",
+		"monitoringNamespace must be a Kubernetes namespace name",
+		"simulatorOptions must not set --duration",
+	)
+
+	_, err = LoadEksWorkload(values{
+		"image":                "workload-simulator:latest",
+		"registryStackName":    "org/aurora-bluegreen-registry/dev",
+		"namespace":            "Load_Test",
+		"oidcProviderArn":      "oidc.eks.us-east-1.amazonaws.com/id/EXAMPLE",
+		"replicas":             "2",
+		"maxReplicas":          "1",
+		"targetCpuUtilization": "0",
+		"duration":             "60",
+	})
+	expectProblems(t, err,
+		"auroraStackName is required",
+		"dbPassword is required",
+		"registryStackName runs the registry stack's simulator image instead of image",
+		"namespace must be a Kubernetes namespace name",
+		"oidcProviderArn must be the ARN of the cluster's IAM OIDC provider",
+		"maxReplicas must be between replicas and 50",
+		"targetCpuUtilization must be a percentage",
+		"duration applies to kind job",
+	)
+}
@@ -0,0 +1,49 @@
+package config
+
+import "strings"
+
+// Dms is the validated configuration of the dms stack.
+type Dms struct {
+	VpcStackName    string
+	AuroraStackName string
+	// ReplicationInstanceClass is the class of the DMS replication instance
+	ReplicationInstanceClass string
+	// MigrationType is full-load, cdc or full-load-and-cdc
+	MigrationType string
+	// TablePattern selects the replicated tables of the lab database, with %
+	// as a wildcard
+	TablePattern string
+	// S3GatewayEndpoint adds an S3 gateway endpoint to the VPC's private
+	// route table, so the replication instance reaches its target bucket
+	// without a NAT gateway
+	S3GatewayEndpoint bool
+	// CreateDmsVpcRole creates dms-vpc-role, the role DMS needs once per
+	// account to manage replication instances in a VPC
+	CreateDmsVpcRole bool
+}
+
+// LoadDms loads and validates the dms stack configuration.
+func LoadDms(src Source) (*Dms, error) {
+	l := newLoader(src)
+	c := &Dms{
+		VpcStackName:             l.require("vpcStackName", `pulumi config set vpcStackName "organization/aurora-bluegreen-vpc/dev"`),
+		AuroraStackName:          l.require("auroraStackName", `pulumi config set auroraStackName "organization/aurora-bluegreen-aurora/dev"`),
+		ReplicationInstanceClass: l.get("replicationInstanceClass", "dms.t3.micro"),
+		MigrationType:            l.get("migrationType", "full-load-and-cdc"),
+		TablePattern:             l.get("tablePattern", "%"),
+		S3GatewayEndpoint:        l.bool("s3GatewayEndpoint", true),
+		CreateDmsVpcRole:         l.bool("createDmsVpcRole", true),
+	}
+	// The source endpoint connects with the cluster's master user
+	l.require("dbPassword", "pulumi config set --secret dbPassword <the aurora stack's masterPassword>")
+
+	if !strings.HasPrefix(c.ReplicationInstanceClass, "dms.") {
+		l.errorf("replicationInstanceClass must be a DMS instance class such as dms.t3.micro (got %q)", c.ReplicationInstanceClass)
+	}
+	l.oneOf("migrationType", c.MigrationType, "full-load", "cdc", "full-load-and-cdc")
+	if c.TablePattern == "" || strings.ContainsAny(c.TablePattern, `"\`) {
+		l.errorf("tablePattern must be a table name, with %% as a wildcard (got %q)", c.TablePattern)
+	}
+
+	return c, l.err()
+}
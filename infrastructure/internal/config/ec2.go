@@ -0,0 +1,178 @@
+package config
+
+import (
+	"os"
+	"regexp"
+	"slices"
+)
+
+var (
+	instanceTypePattern = regexp.MustCompile(`^[a-z][a-z0-9-]*\.[a-z0-9]+$`)
+	tableNamePattern    = regexp.MustCompile(`^[A-Za-z0-9_.-]{3,255}$`)
+)
+
+// maxInstanceCount caps the single simulator instances, which are operated
+// one by one; larger fleets belong in an Auto Scaling Group.
+const maxInstanceCount = 6
+
+// Ec2 is the validated configuration of the EC2 stack. The database password
+// is only checked for presence; the stack reads it with TrySecret so it stays
+// a secret output.
+type Ec2 struct {
+	VpcStackName    string
+	AuroraStackName string
+	KeyName         string
+	// Architecture is x86_64 or arm64; InstanceType defaults to t3.xlarge or
+	// t4g.xlarge accordingly
+	Architecture   string
+	InstanceType   string
+	SimulatorCount int
+	// InstanceCount single instances are spread across the availability
+	// zones, e.g. to compare how their clients ride out a switchover
+	InstanceCount            int
+	UseSpot                  bool
+	SpotOnDemandBaseCapacity int
+	SpotInstanceTypes        []string
+	HasDbPassword            bool
+	SimulatorOptions         string
+	SimulatorJar             string
+	// RegistryStackName is the registry stack whose simulator image the
+	// service runs as a container instead of the jar
+	RegistryStackName string
+	// IamDbUser is the database user the simulators may connect as with IAM
+	// database authentication (--auth iam)
+	IamDbUser string
+	// SimulatorMetricsNamespace is the CloudWatch namespace the simulators may
+	// publish custom metrics to (--cloudwatch-namespace)
+	SimulatorMetricsNamespace string
+	// ExperimentTableName is the monitoring stack's experiment registry table
+	// the simulators may register their runs in (--registry-table)
+	ExperimentTableName string
+	// SimulatorLogs ships the instance setup and simulator logs to a
+	// CloudWatch Logs group kept for SimulatorLogRetentionDays
+	SimulatorLogs             bool
+	SimulatorLogRetentionDays int
+	// PrivateSimulator launches the instances in a private subnet without a
+	// public IP, reached through SSM or an EC2 Instance Connect Endpoint
+	PrivateSimulator bool
+	// AllocateEip gives each single instance an Elastic IP that survives
+	// the scheduler stopping and starting it
+	AllocateEip bool
+}
+
+// LoadEc2 loads and validates the EC2 stack configuration. Whether the
+// instance type exists and supports the architecture is checked by the stack
+// with an ec2.GetInstanceType lookup.
+func LoadEc2(src Source) (*Ec2, error) {
+	l := newLoader(src)
+	c := &Ec2{
+		VpcStackName:              l.require("vpcStackName", `pulumi config set vpcStackName "organization/aurora-bluegreen-vpc/dev"`),
+		AuroraStackName:           l.get("auroraStackName", ""),
+		KeyName:                   l.require("keyName", "pulumi config set keyName <your-key-pair-name>"),
+		Architecture:              l.get("architecture", "x86_64"),
+		SimulatorCount:            l.int("simulatorCount", 0),
+		InstanceCount:             l.int("instanceCount", 1),
+		UseSpot:                   l.bool("useSpot", false),
+		SpotOnDemandBaseCapacity:  l.int("spotOnDemandBaseCapacity", 0),
+		HasDbPassword:             src.Get("dbPassword") != "",
+		SimulatorOptions:          l.get("simulatorOptions", "--write-workers 10 --write-rate 100 --connection-pool-size 100 --control-port 8081"),
+		SimulatorJar:              l.get("simulatorJar", ""),
+		RegistryStackName:         l.get("registryStackName", ""),
+		IamDbUser:                 l.get("iamDbUser", ""),
+		SimulatorMetricsNamespace: l.get("simulatorMetricsNamespace", ""),
+		ExperimentTableName:       l.get("experimentTableName", ""),
+		SimulatorLogs:             l.bool("simulatorLogs", false),
+		SimulatorLogRetentionDays: l.int("simulatorLogRetentionDays", 14),
+		PrivateSimulator:          l.bool("privateSimulator", false),
+		AllocateEip:               l.bool("allocateEip", false),
+	}
+
+	var defaultInstanceType string
+	switch c.Architecture {
+	case "x86_64":
+		defaultInstanceType = "t3.xlarge"
+	case "arm64":
+		defaultInstanceType = "t4g.xlarge"
+	default:
+		l.errorf("architecture must be x86_64 or arm64 (got %q)", c.Architecture)
+	}
+	c.InstanceType = l.get("instanceType", defaultInstanceType)
+	if c.InstanceType != "" && !instanceTypePattern.MatchString(c.InstanceType) {
+		l.errorf("instanceType must be an EC2 instance type such as t3.xlarge (got %q)", c.InstanceType)
+	}
+
+	// Number of simulator instances in an Auto Scaling Group; 0 keeps the
+	// single manually operated instance
+	if c.SimulatorCount < 0 {
+		l.errorf("simulatorCount must not be negative (got %d)", c.SimulatorCount)
+	}
+	if c.SimulatorCount > 0 && (c.AuroraStackName == "" || !c.HasDbPassword) {
+		l.errorf("simulatorCount requires auroraStackName and dbPassword so instances can start the simulator service")
+	}
+
+	if c.InstanceCount < 1 || c.InstanceCount > maxInstanceCount {
+		l.errorf("instanceCount must be between 1 and %d (got %d)", maxInstanceCount, c.InstanceCount)
+	}
+	if c.InstanceCount > 1 && c.SimulatorCount > 0 {
+		l.errorf("instanceCount is for single instances; set simulatorCount alone for an Auto Scaling Group")
+	}
+
+	if c.AllocateEip && c.PrivateSimulator {
+		l.errorf("allocateEip needs a public instance; unset privateSimulator")
+	}
+	if c.AllocateEip && c.SimulatorCount > 0 {
+		l.errorf("allocateEip is for single instances, not an Auto Scaling Group (simulatorCount)")
+	}
+
+	// Spot keeps long-running lab sessions cheap; in Auto Scaling Group mode
+	// spotOnDemandBaseCapacity instances stay on-demand as a fallback floor
+	if c.SpotOnDemandBaseCapacity < 0 || (c.SimulatorCount > 0 && c.SpotOnDemandBaseCapacity > c.SimulatorCount) {
+		l.errorf("spotOnDemandBaseCapacity must be between 0 and simulatorCount (got %d)", c.SpotOnDemandBaseCapacity)
+	}
+	l.object("spotInstanceTypes", "a list of instance types", &c.SpotInstanceTypes)
+	for _, instanceType := range c.SpotInstanceTypes {
+		if !instanceTypePattern.MatchString(instanceType) {
+			l.errorf("spotInstanceTypes must contain EC2 instance types such as m5.xlarge (got %q)", instanceType)
+		}
+	}
+
+	// With a locally built jar configured, the stack uploads it to S3 and the
+	// instances download it on boot instead of it being copied with scp
+	if c.SimulatorJar != "" {
+		if _, err := os.Stat(c.SimulatorJar); err != nil {
+			l.errorf("simulatorJar %s not found; build it with mvn clean package in workload-simulator: %v", c.SimulatorJar, err)
+		}
+	}
+
+	// The container image replaces the jar; only the service runs it
+	if c.RegistryStackName != "" {
+		if c.SimulatorJar != "" {
+			l.errorf("registryStackName runs the simulator image instead of simulatorJar; set only one")
+		}
+		if c.AuroraStackName == "" || !c.HasDbPassword {
+			l.errorf("registryStackName requires auroraStackName and dbPassword so instances can start the simulator service")
+		}
+	}
+
+	// The rds-db:connect policy is scoped to the Aurora stack's cluster
+	if c.IamDbUser != "" {
+		if !usernamePattern.MatchString(c.IamDbUser) {
+			l.errorf("iamDbUser must start with a letter and contain only letters, digits and underscores, up to 16 characters (got %q)", c.IamDbUser)
+		}
+		if c.AuroraStackName == "" {
+			l.errorf("iamDbUser requires auroraStackName")
+		}
+	}
+
+	l.metricsNamespace("simulatorMetricsNamespace", c.SimulatorMetricsNamespace)
+
+	if c.ExperimentTableName != "" && !tableNamePattern.MatchString(c.ExperimentTableName) {
+		l.errorf("experimentTableName must be a DynamoDB table name, the monitoring stack's experimentTableName output (got %q)", c.ExperimentTableName)
+	}
+
+	if !slices.Contains(logRetentionDays, c.SimulatorLogRetentionDays) {
+		l.errorf("simulatorLogRetentionDays must be a CloudWatch Logs retention period such as 7, 14, 30 or 90 (got %d)", c.SimulatorLogRetentionDays)
+	}
+
+	return c, l.err()
+}
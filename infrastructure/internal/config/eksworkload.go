@@ -0,0 +1,146 @@
+package config
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// kubernetesNamePattern matches Kubernetes namespace names (DNS labels)
+	kubernetesNamePattern  = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]{0,61}[a-z0-9])?$`)
+	oidcProviderArnPattern = regexp.MustCompile(`^arn:aws[a-z-]*:iam::\d{12}:oidc-provider/[^/]+/.+$`)
+)
+
+// maxWorkloadReplicas caps the simulator pods; each one runs its own
+// workers and connection pool against the cluster.
+const maxWorkloadReplicas = 50
+
+// workloadReservedOptions are simulator options the eks-workload stack sets
+// for every pod.
+var workloadReservedOptions = []string{"aurora-endpoint", "username", "password", "database-name", "enable-metrics", "duration"}
+
+// EksWorkload is the validated configuration of the eks-workload stack. The
+// database password is only checked for presence; the stack reads it with
+// TrySecret so it stays a secret output.
+type EksWorkload struct {
+	AuroraStackName string
+	// RegistryStackName is the registry stack whose simulator image the pods
+	// run; Image names any other image built from workload-simulator/Dockerfile
+	RegistryStackName string
+	Image             string
+	HasDbPassword     bool
+	// Kubeconfig is the path or contents of the kubeconfig of the cluster and
+	// KubeContext one of its contexts; unset, the ambient kubeconfig
+	// ($KUBECONFIG or ~/.kube/config) and its current context are used
+	Kubeconfig  string
+	KubeContext string
+	Namespace   string
+	// OidcProviderArn is the cluster's IAM OIDC provider, which the IRSA role
+	// of the simulator's service account trusts
+	OidcProviderArn string
+	// Kind is deployment, pods running until the stack is destroyed, or job,
+	// pods running for Duration seconds
+	Kind string
+	// Replicas is the number of pods: the Deployment's minimum, or the Job's
+	// parallelism. MaxReplicas above it scales the Deployment on CPU
+	// utilization with a HorizontalPodAutoscaler
+	Replicas             int
+	MaxReplicas          int
+	TargetCpuUtilization int
+	Duration             int
+	SimulatorOptions     string
+	// EnableMetrics serves the simulator's Prometheus metrics on port 8080
+	// behind the workload-simulator Service
+	EnableMetrics bool
+	// Observability installs kube-prometheus-stack and the Prometheus
+	// CloudWatch exporter into MonitoringNamespace, with a Grafana dashboard
+	// of the simulator's and the Aurora cluster's metrics
+	Observability           bool
+	MonitoringNamespace     string
+	HasGrafanaAdminPassword bool
+}
+
+// LoadEksWorkload loads and validates the eks-workload stack configuration.
+func LoadEksWorkload(src Source) (*EksWorkload, error) {
+	l := newLoader(src)
+	c := &EksWorkload{
+		AuroraStackName:         l.require("auroraStackName", `pulumi config set auroraStackName "organization/aurora-bluegreen-aurora/dev"`),
+		RegistryStackName:       l.get("registryStackName", ""),
+		Image:                   l.get("image", ""),
+		HasDbPassword:           src.Get("dbPassword") != "",
+		Kubeconfig:              l.get("kubeconfig", ""),
+		KubeContext:             l.get("kubeContext", ""),
+		Namespace:               l.get("namespace", "workload-simulator"),
+		OidcProviderArn:         l.require("oidcProviderArn", `pulumi config set oidcProviderArn "arn:aws:iam::123456789012:oidc-provider/oidc.eks.us-east-1.amazonaws.com/id/EXAMPLE"`),
+		Kind:                    l.get("kind", "deployment"),
+		Replicas:                l.int("replicas", 1),
+		TargetCpuUtilization:    l.int("targetCpuUtilization", 70),
+		SimulatorOptions:        l.get("simulatorOptions", "--write-workers 10 --write-rate 100 --connection-pool-size 100"),
+		EnableMetrics:           l.bool("enableMetrics", true),
+		Observability:           l.bool("observability", false),
+		MonitoringNamespace:     l.get("monitoringNamespace", "monitoring"),
+		HasGrafanaAdminPassword: src.Get("grafanaAdminPassword") != "",
+	}
+	c.MaxReplicas = l.int("maxReplicas", c.Replicas)
+
+	if !c.HasDbPassword {
+		l.errorf("dbPassword is required; set it with: pulumi config set --secret dbPassword <password>")
+	}
+	switch {
+	case c.RegistryStackName == "" && c.Image == "":
+		l.errorf("registryStackName or image is required; set the registry stack that builds the simulator image with: pulumi config set registryStackName \"organization/aurora-bluegreen-registry/dev\"")
+	case c.RegistryStackName != "" && c.Image != "":
+		l.errorf("registryStackName runs the registry stack's simulator image instead of image; set only one")
+	}
+
+	if !kubernetesNamePattern.MatchString(c.Namespace) {
+		l.errorf("namespace must be a Kubernetes namespace name of at most 63 lowercase letters, digits and hyphens (got %q)", c.Namespace)
+	}
+	if c.OidcProviderArn != "" && !oidcProviderArnPattern.MatchString(c.OidcProviderArn) {
+		l.errorf("oidcProviderArn must be the ARN of the cluster's IAM OIDC provider, arn:aws:iam::<account>:oidc-provider/oidc.eks.<region>.amazonaws.com/id/<id> (got %q)", c.OidcProviderArn)
+	}
+
+	l.oneOf("kind", c.Kind, "deployment", "job")
+	if c.Replicas < 1 || c.Replicas > maxWorkloadReplicas {
+		l.errorf("replicas must be between 1 and %d (got %d)", maxWorkloadReplicas, c.Replicas)
+	}
+	if c.MaxReplicas < c.Replicas || c.MaxReplicas > maxWorkloadReplicas {
+		l.errorf("maxReplicas must be between replicas and %d (got %d)", maxWorkloadReplicas, c.MaxReplicas)
+	}
+	if c.TargetCpuUtilization < 1 || c.TargetCpuUtilization > 100 {
+		l.errorf("targetCpuUtilization must be a percentage between 1 and 100 (got %d)", c.TargetCpuUtilization)
+	}
+
+	// A Job runs its pods for a fixed time; a Deployment runs until destroyed
+	// and restarts pods that exit
+	if c.Kind == "job" {
+		c.Duration = l.int("duration", 600)
+		if c.Duration < 1 {
+			l.errorf("duration must be a positive number of seconds (got %d)", c.Duration)
+		}
+		if c.MaxReplicas != c.Replicas {
+			l.errorf("maxReplicas scales a Deployment; a job runs replicas pods")
+		}
+	} else if src.Get("duration") != "" {
+		l.errorf("duration applies to kind job; a deployment runs until the stack is destroyed")
+	}
+
+	if c.Observability {
+		if !c.EnableMetrics {
+			l.errorf("observability scrapes the simulator's metrics; set enableMetrics to true")
+		}
+		if !kubernetesNamePattern.MatchString(c.MonitoringNamespace) {
+			l.errorf("monitoringNamespace must be a Kubernetes namespace name of at most 63 lowercase letters, digits and hyphens (got %q)", c.MonitoringNamespace)
+		}
+	}
+
+	for _, option := range strings.Fields(c.SimulatorOptions) {
+		for _, reserved := range workloadReservedOptions {
+			if option == "--"+reserved || strings.HasPrefix(option, "--"+reserved+"=") {
+				l.errorf("simulatorOptions must not set --%s; the stack sets it", reserved)
+			}
+		}
+	}
+
+	return c, l.err()
+}
@@ -0,0 +1,32 @@
+package config
+
+// Fis is the validated configuration of the fis stack.
+type Fis struct {
+	AuroraStackName string
+	// NetworkDisruptionMinutes is how long the simulator network template
+	// blocks NetworkDisruptionPort
+	NetworkDisruptionMinutes int
+	// NetworkDisruptionPort is the port the simulator hosts can no longer
+	// reach, by default the cluster's MySQL port
+	NetworkDisruptionPort int
+}
+
+// LoadFis loads and validates the fis stack configuration.
+func LoadFis(src Source) (*Fis, error) {
+	l := newLoader(src)
+	c := &Fis{
+		AuroraStackName:          l.require("auroraStackName", `pulumi config set auroraStackName "organization/aurora-bluegreen-aurora/dev"`),
+		NetworkDisruptionMinutes: l.int("networkDisruptionMinutes", 2),
+		NetworkDisruptionPort:    l.int("networkDisruptionPort", 3306),
+	}
+
+	// FIS actions last at most 12 hours
+	if c.NetworkDisruptionMinutes < 1 || c.NetworkDisruptionMinutes > 720 {
+		l.errorf("networkDisruptionMinutes must be between 1 and 720 (got %d)", c.NetworkDisruptionMinutes)
+	}
+	if c.NetworkDisruptionPort < 1 || c.NetworkDisruptionPort > 65535 {
+		l.errorf("networkDisruptionPort must be a TCP port (got %d)", c.NetworkDisruptionPort)
+	}
+
+	return c, l.err()
+}
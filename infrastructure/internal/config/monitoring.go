@@ -0,0 +1,114 @@
+package config
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// logRetentionDays are the retention periods CloudWatch Logs accepts.
+var logRetentionDays = []int{1, 3, 5, 7, 14, 30, 60, 90, 120, 150, 180, 365, 400, 545, 731, 1096, 1827, 2192, 2557, 2922, 3288, 3653}
+
+// EventAnnotation marks a point in time (e.g., an RDS Blue/Green event) on every
+// metric widget of the dashboard.
+type EventAnnotation struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+// Monitoring is the validated configuration of the monitoring stack.
+type Monitoring struct {
+	AuroraStackName              string
+	Ec2StackName                 string
+	MetricPeriod                 int
+	EventAnnotations             []EventAnnotation
+	AlarmEmail                   string
+	CpuAlarmThreshold            float64
+	FreeableMemoryAlarmThreshold float64
+	ConnectionsAlarmThreshold    float64
+	ReplicaLagAlarmThreshold     float64
+	EventLogRetentionDays        int
+	// SimulatorMetricsNamespace is the CloudWatch namespace the simulator
+	// publishes to (--cloudwatch-namespace); empty omits the simulator widgets
+	SimulatorMetricsNamespace string
+	// RecordBlueGreenEvents deploys the Lambda function storing every
+	// Blue/Green event in a DynamoDB table (cmd/lab-bluegreen-events); the
+	// events expire after EventLogRetentionDays
+	RecordBlueGreenEvents bool
+	// BlueGreenMetricsNamespace is the CloudWatch namespace the function
+	// publishes the events to; empty publishes no metrics
+	BlueGreenMetricsNamespace string
+	// ExperimentRegistry creates the DynamoDB table where lab-scenario, bgctl
+	// and the simulator register their runs (internal/experiments)
+	ExperimentRegistry bool
+}
+
+// LoadMonitoring loads and validates the monitoring stack configuration.
+func LoadMonitoring(src Source) (*Monitoring, error) {
+	l := newLoader(src)
+	c := &Monitoring{
+		AuroraStackName:              l.require("auroraStackName", `pulumi config set auroraStackName "organization/aurora-bluegreen-aurora/dev"`),
+		Ec2StackName:                 l.get("ec2StackName", ""),
+		MetricPeriod:                 l.int("metricPeriod", 60),
+		AlarmEmail:                   l.get("alarmEmail", ""),
+		CpuAlarmThreshold:            l.float("cpuAlarmThreshold", 80),
+		FreeableMemoryAlarmThreshold: l.float("freeableMemoryAlarmThreshold", 1073741824), // 1 GiB
+		// 80% of max_connections in the instance parameter group
+		ConnectionsAlarmThreshold: l.float("connectionsAlarmThreshold", 800),
+		ReplicaLagAlarmThreshold:  l.float("replicaLagAlarmThreshold", 1000), // milliseconds
+		EventLogRetentionDays:     l.int("eventLogRetentionDays", 14),
+		SimulatorMetricsNamespace: l.get("simulatorMetricsNamespace", ""),
+		RecordBlueGreenEvents:     l.bool("recordBlueGreenEvents", true),
+		BlueGreenMetricsNamespace: l.get("blueGreenMetricsNamespace", "AuroraLab/BlueGreen"),
+		ExperimentRegistry:        l.bool("experimentRegistry", true),
+	}
+
+	// CloudWatch supports high-resolution periods of 1, 5, 10 and 30 seconds
+	// and multiples of 60 seconds
+	switch {
+	case slices.Contains([]int{1, 5, 10, 30}, c.MetricPeriod):
+	case c.MetricPeriod > 0 && c.MetricPeriod%60 == 0:
+	default:
+		l.errorf("metricPeriod must be 1, 5, 10, 30 or a multiple of 60 seconds (got %d)", c.MetricPeriod)
+	}
+
+	// Optional vertical annotations, e.g. the RDS events "Switchover started"
+	// and "Switchover completed" copied from the RDS console or event log
+	l.object("eventAnnotations", "a list of {label, value} objects", &c.EventAnnotations)
+	for i, a := range c.EventAnnotations {
+		if a.Label == "" {
+			l.errorf("eventAnnotations[%d] needs a label", i)
+		}
+		if _, err := time.Parse(time.RFC3339, a.Value); err != nil {
+			l.errorf("eventAnnotations[%d].value must be an ISO 8601 timestamp such as 2024-11-19T13:40:12Z (got %q)", i, a.Value)
+		}
+	}
+
+	if c.AlarmEmail != "" && !strings.Contains(c.AlarmEmail, "@") {
+		l.errorf("alarmEmail must be an email address (got %q)", c.AlarmEmail)
+	}
+	for _, t := range []struct {
+		key   string
+		value float64
+	}{
+		{"cpuAlarmThreshold", c.CpuAlarmThreshold},
+		{"freeableMemoryAlarmThreshold", c.FreeableMemoryAlarmThreshold},
+		{"connectionsAlarmThreshold", c.ConnectionsAlarmThreshold},
+		{"replicaLagAlarmThreshold", c.ReplicaLagAlarmThreshold},
+	} {
+		if t.value <= 0 {
+			l.errorf("%s must be greater than 0 (got %g)", t.key, t.value)
+		}
+	}
+	if c.CpuAlarmThreshold > 100 {
+		l.errorf("cpuAlarmThreshold is a percentage and must not exceed 100 (got %g)", c.CpuAlarmThreshold)
+	}
+	if !slices.Contains(logRetentionDays, c.EventLogRetentionDays) {
+		l.errorf("eventLogRetentionDays must be a CloudWatch Logs retention period such as 7, 14, 30 or 90 (got %d)", c.EventLogRetentionDays)
+	}
+
+	l.metricsNamespace("simulatorMetricsNamespace", c.SimulatorMetricsNamespace)
+	l.metricsNamespace("blueGreenMetricsNamespace", c.BlueGreenMetricsNamespace)
+
+	return c, l.err()
+}
@@ -0,0 +1,36 @@
+package config
+
+import "slices"
+
+// Ops is the validated configuration of the ops stack.
+type Ops struct {
+	AuroraStackName string
+	// SnapshotSchedule is the EventBridge schedule expression of the
+	// snapshot Lambda (UTC), e.g. before each experiment window
+	SnapshotSchedule string
+	// SnapshotRetentionDays is the age after which the Lambda deletes the
+	// snapshots it took
+	SnapshotRetentionDays  int
+	LambdaLogRetentionDays int
+}
+
+// LoadOps loads and validates the ops stack configuration.
+func LoadOps(src Source) (*Ops, error) {
+	l := newLoader(src)
+	c := &Ops{
+		AuroraStackName:        l.require("auroraStackName", `pulumi config set auroraStackName "organization/aurora-bluegreen-aurora/dev"`),
+		SnapshotSchedule:       l.get("snapshotSchedule", "cron(0 8 ? * MON-FRI *)"),
+		SnapshotRetentionDays:  l.int("snapshotRetentionDays", 7),
+		LambdaLogRetentionDays: l.int("lambdaLogRetentionDays", 14),
+	}
+
+	l.schedule("snapshotSchedule", c.SnapshotSchedule, "cron(0 8 ? * MON-FRI *)")
+	if c.SnapshotRetentionDays < 1 {
+		l.errorf("snapshotRetentionDays must be at least 1 (got %d)", c.SnapshotRetentionDays)
+	}
+	if !slices.Contains(logRetentionDays, c.LambdaLogRetentionDays) {
+		l.errorf("lambdaLogRetentionDays must be a CloudWatch Logs retention period such as 7, 14, 30 or 90 (got %d)", c.LambdaLogRetentionDays)
+	}
+
+	return c, l.err()
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"regexp"
+	"slices"
+)
+
+// imageNamePattern matches the image names the repositories are named by,
+// a single ECR repository path component.
+var imageNamePattern = regexp.MustCompile(`^[a-z0-9]+(?:[._-][a-z0-9]+)*$`)
+
+// SimulatorImage is the image the registry stack builds from
+// workload-simulator/Dockerfile.
+const SimulatorImage = "workload-simulator"
+
+// Registry is the validated configuration of the registry stack.
+type Registry struct {
+	// Platform is the image platform, linux/amd64 or linux/arm64 to match
+	// the EC2 stack's architecture
+	Platform string
+	// BuildImages builds and pushes the images with the docker-build provider
+	// during pulumi up; without it the repositories are left to be pushed
+	// to by hand or by CI
+	BuildImages bool
+	// KeepImages is the number of most recent images each repository keeps
+	KeepImages int
+	// Repositories are the names of additional images, e.g. Lambda container
+	// images or agents, whose repositories are created for pushing by hand
+	// or by CI
+	Repositories []string
+}
+
+// LoadRegistry loads and validates the registry stack configuration.
+func LoadRegistry(src Source) (*Registry, error) {
+	l := newLoader(src)
+	c := &Registry{
+		Platform:    l.get("platform", "linux/amd64"),
+		BuildImages: l.bool("buildImages", true),
+		KeepImages:  l.int("keepImages", 10),
+	}
+	l.object("repositories", "a list of image names", &c.Repositories)
+
+	l.oneOf("platform", c.Platform, "linux/amd64", "linux/arm64")
+
+	// ECR lifecycle rules count images from 1
+	if c.KeepImages < 1 || c.KeepImages > 1000 {
+		l.errorf("keepImages must be between 1 and 1000 (got %d)", c.KeepImages)
+	}
+
+	for i, name := range c.Repositories {
+		switch {
+		case !imageNamePattern.MatchString(name):
+			l.errorf("repositories[%d] must be an image name of lowercase letters, digits and . _ - such as lab-agent (got %q)", i, name)
+		case name == SimulatorImage || slices.Contains(c.Repositories[:i], name):
+			l.errorf("repositories lists %s more than once", name)
+		}
+	}
+
+	return c, l.err()
+}
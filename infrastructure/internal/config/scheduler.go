@@ -0,0 +1,51 @@
+package config
+
+import (
+	"regexp"
+	"slices"
+)
+
+// timezonePattern matches IANA time zone names such as UTC or Europe/Berlin.
+var timezonePattern = regexp.MustCompile(`^[A-Za-z]+(/[A-Za-z0-9_+-]+)*$`)
+
+// Scheduler is the validated configuration of the scheduler stack.
+type Scheduler struct {
+	Ec2StackName    string
+	AuroraStackName string
+	// StopCluster also stops the Aurora cluster of AuroraStackName
+	StopCluster bool
+	// StopSchedule and StartSchedule are EventBridge Scheduler expressions
+	// in ScheduleTimezone
+	StopSchedule           string
+	StartSchedule          string
+	ScheduleTimezone       string
+	LambdaLogRetentionDays int
+}
+
+// LoadScheduler loads and validates the scheduler stack configuration.
+func LoadScheduler(src Source) (*Scheduler, error) {
+	l := newLoader(src)
+	c := &Scheduler{
+		Ec2StackName:           l.require("ec2StackName", `pulumi config set ec2StackName "organization/aurora-bluegreen-ec2/dev"`),
+		AuroraStackName:        l.get("auroraStackName", ""),
+		StopCluster:            l.bool("stopCluster", false),
+		StopSchedule:           l.get("stopSchedule", "cron(0 19 ? * MON-FRI *)"),
+		StartSchedule:          l.get("startSchedule", "cron(0 7 ? * MON-FRI *)"),
+		ScheduleTimezone:       l.get("scheduleTimezone", "UTC"),
+		LambdaLogRetentionDays: l.int("lambdaLogRetentionDays", 14),
+	}
+
+	if c.StopCluster && c.AuroraStackName == "" {
+		l.errorf(`stopCluster requires auroraStackName; set it with: pulumi config set auroraStackName "organization/aurora-bluegreen-aurora/dev"`)
+	}
+	l.schedule("stopSchedule", c.StopSchedule, "cron(0 19 ? * MON-FRI *)")
+	l.schedule("startSchedule", c.StartSchedule, "cron(0 7 ? * MON-FRI *)")
+	if !timezonePattern.MatchString(c.ScheduleTimezone) {
+		l.errorf("scheduleTimezone must be an IANA time zone such as UTC or Europe/Berlin (got %q)", c.ScheduleTimezone)
+	}
+	if !slices.Contains(logRetentionDays, c.LambdaLogRetentionDays) {
+		l.errorf("lambdaLogRetentionDays must be a CloudWatch Logs retention period such as 7, 14, 30 or 90 (got %d)", c.LambdaLogRetentionDays)
+	}
+
+	return c, l.err()
+}
@@ -0,0 +1,211 @@
+package config
+
+import (
+	"net/netip"
+	"regexp"
+	"slices"
+)
+
+var (
+	vpcIdPattern    = regexp.MustCompile(`^vpc-[0-9a-f]{8,17}$`)
+	subnetIdPattern = regexp.MustCompile(`^subnet-[0-9a-f]{8,17}$`)
+)
+
+// labSubnets are the fixed Aurora and EC2 subnet ranges created by
+// components.NewLabVpc; the VPC CIDR must contain all of them.
+var labSubnets = []netip.Prefix{
+	netip.MustParsePrefix("10.0.1.0/24"),
+	netip.MustParsePrefix("10.0.2.0/24"),
+	netip.MustParsePrefix("10.0.10.0/24"),
+	netip.MustParsePrefix("10.0.11.0/24"),
+}
+
+// maxSecondaryCidrBlocks keeps a VPC within the default quota of five IPv4
+// CIDR blocks.
+const maxSecondaryCidrBlocks = 4
+
+// Vpc is the validated configuration of the VPC stack.
+type Vpc struct {
+	VpcCidr string
+	SshCidr string
+	// SecondaryCidrBlocks are associated with the VPC after VpcCidr, e.g. a
+	// 100.64.0.0/16 range for EKS pods
+	SecondaryCidrBlocks []string
+	// EksSubnetCidrs are the two EKS subnets, within VpcCidr or one of the
+	// SecondaryCidrBlocks
+	EksSubnetCidrs []string
+	// StrictNetworkAcls isolates the Aurora subnets with a network ACL
+	// admitting only MySQL from the EC2 and EKS subnets
+	StrictNetworkAcls bool
+	// FlowLogs records the VPC's traffic of FlowLogTrafficType (ALL, ACCEPT
+	// or REJECT) to FlowLogDestination (cloudwatch or s3), kept for
+	// FlowLogRetentionDays
+	FlowLogs             bool
+	FlowLogDestination   string
+	FlowLogTrafficType   string
+	FlowLogRetentionDays int
+	// EnableIpv6 makes the VPC and its subnets dual-stack
+	EnableIpv6 bool
+	// NatGateway routes the private subnets to the internet through a NAT
+	// gateway in the EC2 subnet, e.g. for a simulator host without a public IP
+	NatGateway bool
+	// ExistingVpcId, when set, reuses a VPC created outside the lab and its
+	// subnets instead of creating them; the stack only adds the security
+	// groups. ExistingEksSubnetIds is optional.
+	ExistingVpcId           string
+	ExistingAuroraSubnetIds []string
+	ExistingEc2SubnetId     string
+	ExistingEksSubnetIds    []string
+}
+
+// LoadVpc loads and validates the VPC stack configuration.
+func LoadVpc(src Source) (*Vpc, error) {
+	l := newLoader(src)
+	c := &Vpc{
+		VpcCidr: l.get("vpcCidr", "10.0.0.0/16"),
+		// SSH stays open to the internet unless restricted (e.g., to your IP)
+		SshCidr:              l.get("sshCidr", "0.0.0.0/0"),
+		StrictNetworkAcls:    l.bool("strictNetworkAcls", false),
+		FlowLogs:             l.bool("flowLogs", false),
+		FlowLogDestination:   l.get("flowLogDestination", "cloudwatch"),
+		FlowLogTrafficType:   l.get("flowLogTrafficType", "ALL"),
+		FlowLogRetentionDays: l.int("flowLogRetentionDays", 14),
+		EnableIpv6:           l.bool("enableIpv6", false),
+		NatGateway:           l.bool("natGateway", false),
+		ExistingVpcId:        l.get("existingVpcId", ""),
+		ExistingEc2SubnetId:  l.get("existingEc2SubnetId", ""),
+	}
+	l.object("secondaryCidrBlocks", "a list of CIDR blocks", &c.SecondaryCidrBlocks)
+	l.object("eksSubnetCidrs", "a list of two CIDR blocks", &c.EksSubnetCidrs)
+	if c.EksSubnetCidrs == nil {
+		c.EksSubnetCidrs = []string{"10.0.20.0/24", "10.0.21.0/24"}
+	}
+	l.object("existingAuroraSubnetIds", "a list of two subnet IDs", &c.ExistingAuroraSubnetIds)
+	l.object("existingEksSubnetIds", "a list of two subnet IDs", &c.ExistingEksSubnetIds)
+
+	if vpc, ok := l.cidr("vpcCidr", c.VpcCidr); ok {
+		containsLabSubnets := true
+		for _, subnet := range labSubnets {
+			if !contains(vpc, subnet) {
+				l.errorf("vpcCidr must contain the lab subnets 10.0.1.0/24, 10.0.2.0/24, 10.0.10.0/24 and 10.0.11.0/24, e.g. 10.0.0.0/16 (got %q)", c.VpcCidr)
+				containsLabSubnets = false
+				break
+			}
+		}
+		if containsLabSubnets {
+			l.vpcRanges(c, vpc)
+		}
+	}
+	l.cidr("sshCidr", c.SshCidr)
+
+	l.oneOf("flowLogDestination", c.FlowLogDestination, "cloudwatch", "s3")
+	l.oneOf("flowLogTrafficType", c.FlowLogTrafficType, "ALL", "ACCEPT", "REJECT")
+	// S3 expires the flow log objects after any number of days
+	if c.FlowLogDestination == "s3" && c.FlowLogRetentionDays < 1 {
+		l.errorf("flowLogRetentionDays must be at least 1 (got %d)", c.FlowLogRetentionDays)
+	}
+	if c.FlowLogDestination != "s3" && !slices.Contains(logRetentionDays, c.FlowLogRetentionDays) {
+		l.errorf("flowLogRetentionDays must be a CloudWatch Logs retention period such as 7, 14, 30 or 90 (got %d)", c.FlowLogRetentionDays)
+	}
+
+	l.existingVpc(c)
+
+	return c, l.err()
+}
+
+// vpcRanges checks that the secondary CIDR blocks do not overlap the VPC or
+// each other and that the EKS subnets fit in one of the blocks without
+// overlapping the other lab subnets.
+func (l *loader) vpcRanges(c *Vpc, vpc netip.Prefix) {
+	if len(c.SecondaryCidrBlocks) > maxSecondaryCidrBlocks {
+		l.errorf("secondaryCidrBlocks must list at most %d blocks (got %d)", maxSecondaryCidrBlocks, len(c.SecondaryCidrBlocks))
+	}
+	blocks := []netip.Prefix{vpc}
+	for _, value := range c.SecondaryCidrBlocks {
+		block, ok := l.cidr("secondaryCidrBlocks", value)
+		if !ok {
+			continue
+		}
+		if block.Bits() < 16 || block.Bits() > 28 {
+			l.errorf("secondaryCidrBlocks must be between /16 and /28 (got %q)", value)
+			continue
+		}
+		if i := slices.IndexFunc(blocks, block.Overlaps); i >= 0 {
+			l.errorf("secondaryCidrBlocks entry %s overlaps %s", block, blocks[i])
+			continue
+		}
+		blocks = append(blocks, block)
+	}
+
+	if len(c.EksSubnetCidrs) != 2 {
+		l.errorf("eksSubnetCidrs must list two CIDR blocks (got %d)", len(c.EksSubnetCidrs))
+		return
+	}
+	taken := slices.Clone(labSubnets)
+	for _, value := range c.EksSubnetCidrs {
+		subnet, ok := l.cidr("eksSubnetCidrs", value)
+		if !ok {
+			continue
+		}
+		if !slices.ContainsFunc(blocks, func(block netip.Prefix) bool { return contains(block, subnet) }) {
+			l.errorf("eksSubnetCidrs entry %s must be within vpcCidr or one of the secondaryCidrBlocks", subnet)
+			continue
+		}
+		if i := slices.IndexFunc(taken, subnet.Overlaps); i >= 0 {
+			l.errorf("eksSubnetCidrs entry %s overlaps the subnet %s", subnet, taken[i])
+			continue
+		}
+		taken = append(taken, subnet)
+	}
+}
+
+// contains reports whether subnet lies entirely within block.
+func contains(block, subnet netip.Prefix) bool {
+	return block.Bits() <= subnet.Bits() && block.Contains(subnet.Addr())
+}
+
+// existingVpc checks the IDs of a reused VPC. Whether they exist, belong
+// together and spread across availability zones is checked by the stack
+// after looking them up.
+func (l *loader) existingVpc(c *Vpc) {
+	if c.ExistingVpcId == "" {
+		if len(c.ExistingAuroraSubnetIds) > 0 || c.ExistingEc2SubnetId != "" || len(c.ExistingEksSubnetIds) > 0 {
+			l.errorf("existingAuroraSubnetIds, existingEc2SubnetId and existingEksSubnetIds require existingVpcId")
+		}
+		return
+	}
+
+	if !vpcIdPattern.MatchString(c.ExistingVpcId) {
+		l.errorf("existingVpcId must be a VPC ID such as vpc-0123456789abcdef0 (got %q)", c.ExistingVpcId)
+	}
+	if len(c.ExistingAuroraSubnetIds) != 2 {
+		l.errorf("existingAuroraSubnetIds must list two subnets in different availability zones (got %d)", len(c.ExistingAuroraSubnetIds))
+	}
+	if c.ExistingEc2SubnetId == "" {
+		l.errorf("existingEc2SubnetId is required with existingVpcId; set it to a public subnet of the VPC")
+	}
+	if n := len(c.ExistingEksSubnetIds); n != 0 && n != 2 {
+		l.errorf("existingEksSubnetIds must list two subnets in different availability zones or none (got %d)", n)
+	}
+	subnetIds := append(append([]string{c.ExistingEc2SubnetId}, c.ExistingAuroraSubnetIds...), c.ExistingEksSubnetIds...)
+	for _, id := range subnetIds {
+		if id != "" && !subnetIdPattern.MatchString(id) {
+			l.errorf("existing subnet IDs must look like subnet-0123456789abcdef0 (got %q)", id)
+		}
+	}
+
+	// These change the addressing or filtering of the whole network, which
+	// belongs to whoever owns the VPC
+	if c.StrictNetworkAcls {
+		l.errorf("strictNetworkAcls is not supported with existingVpcId")
+	}
+	if c.EnableIpv6 {
+		l.errorf("enableIpv6 is not supported with existingVpcId")
+	}
+	if len(c.SecondaryCidrBlocks) > 0 {
+		l.errorf("secondaryCidrBlocks is not supported with existingVpcId; associate the blocks with the VPC yourself")
+	}
+	if c.NatGateway {
+		l.errorf("natGateway is not supported with existingVpcId; route the private subnets of the VPC yourself")
+	}
+}
@@ -0,0 +1,302 @@
+// Package cost estimates the monthly on-demand cost of the resources a lab
+// stack provisions, from us-east-1 list prices and 730 hours a month. Each
+// stack exports its estimate as estimatedMonthlyCostUsd, which pulumi up
+// shows in the preview before the update is confirmed, and lab-deploy adds
+// the stacks' estimates up.
+//
+// The estimate covers what is billed while resources exist: instances, NAT
+// gateways, public IPv4 addresses, provisioned volumes, KMS keys, dashboards,
+// alarms and secrets. Usage-based charges (Aurora storage and I/O, data
+// transfer, snapshots, Lambda, logs) and Spot discounts are not included, so
+// the real bill of an idle lab is close to it and a busy lab's is higher.
+package cost
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// HoursPerMonth is the number of hours AWS prices a month with.
+const HoursPerMonth = 730
+
+// rdsLargeHourly is the Aurora MySQL on-demand price per hour of the large
+// size of each instance family (half the xlarge price for the families
+// without large); sizes scale linearly.
+var rdsLargeHourly = map[string]float64{
+	"db.r5":   0.29,
+	"db.r6g":  0.26,
+	"db.r6gd": 0.3117,
+	"db.r6i":  0.29,
+	"db.r6id": 0.348,
+	"db.r7g":  0.276,
+	"db.r7i":  0.3045,
+	"db.r8g":  0.2627,
+	"db.x2g":  0.40,
+}
+
+// rdsBurstableHourly prices the burstable classes, which do not scale linearly.
+var rdsBurstableHourly = map[string]float64{
+	"db.t3.medium":  0.082,
+	"db.t3.large":   0.164,
+	"db.t4g.medium": 0.073,
+	"db.t4g.large":  0.146,
+}
+
+// mysqlLargeHourly is the RDS for MySQL Single-AZ on-demand price per hour of
+// the large size of each instance family; sizes, burstable ones included,
+// scale linearly.
+var mysqlLargeHourly = map[string]float64{
+	"db.t3":  0.136,
+	"db.t4g": 0.129,
+	"db.m5":  0.171,
+	"db.m6g": 0.152,
+	"db.m6i": 0.171,
+	"db.m7g": 0.168,
+	"db.r5":  0.24,
+	"db.r6g": 0.215,
+	"db.r6i": 0.24,
+}
+
+// dmsLargeHourly is the Single-AZ DMS replication instance price per hour of
+// the large size of each instance family; sizes scale linearly.
+var dmsLargeHourly = map[string]float64{
+	"dms.t3":  0.146,
+	"dms.c5":  0.154,
+	"dms.c6i": 0.154,
+	"dms.r5":  0.21,
+	"dms.r6i": 0.21,
+}
+
+// ec2LargeHourly is the Linux on-demand price per hour of the large size of
+// each EC2 instance family; sizes scale linearly.
+var ec2LargeHourly = map[string]float64{
+	"t3":  0.0832,
+	"t3a": 0.0752,
+	"t4g": 0.0672,
+	"m5":  0.096,
+	"m6a": 0.0864,
+	"m6g": 0.077,
+	"m6i": 0.096,
+	"m7g": 0.0816,
+	"m7i": 0.1008,
+	"c5":  0.085,
+	"c6g": 0.068,
+	"c6i": 0.085,
+	"c7g": 0.0725,
+	"r5":  0.126,
+	"r6g": 0.1008,
+	"r6i": 0.126,
+}
+
+// sizeFactors scales a large price to the other instance sizes.
+var sizeFactors = map[string]float64{
+	"nano": 1.0 / 16, "micro": 1.0 / 8, "small": 1.0 / 4, "medium": 1.0 / 2,
+	"large": 1, "xlarge": 2, "2xlarge": 4, "4xlarge": 8, "8xlarge": 16,
+	"12xlarge": 24, "16xlarge": 32, "24xlarge": 48, "32xlarge": 64, "48xlarge": 96,
+}
+
+const (
+	// ioOptimizedFactor is the instance price premium of Aurora I/O-Optimized
+	ioOptimizedFactor = 1.3
+	natGatewayHourly  = 0.045
+	publicIPv4Hourly  = 0.005
+	gp3GbMonthly      = 0.08
+	rdsGp3GbMonthly   = 0.115
+	dmsGbMonthly      = 0.115
+	kmsKeyMonthly     = 1.0
+	dashboardMonthly  = 3.0
+	alarmMonthly      = 0.10
+	secretMonthly     = 0.40
+	// rdsProxyVcpuHourly is billed per vCPU of the proxy's target instances
+	rdsProxyVcpuHourly = 0.015
+)
+
+// Item is one priced line of an estimate.
+type Item struct {
+	Description string
+	MonthlyUsd  float64
+}
+
+// Estimate collects the priced resources of a stack.
+type Estimate struct {
+	Items []Item
+	// Unpriced lists the resources without a list price in the catalog,
+	// e.g. an instance class added after it; they are not in Total
+	Unpriced []string
+}
+
+// Total returns the monthly cost of all items, rounded to cents.
+func (e *Estimate) Total() float64 {
+	var total float64
+	for _, item := range e.Items {
+		total += item.MonthlyUsd
+	}
+	return math.Round(total*100) / 100
+}
+
+// Export exports the estimate as the stack output estimatedMonthlyCostUsd and
+// warns about the unpriced resources, which it leaves out.
+func (e *Estimate) Export(ctx *pulumi.Context) error {
+	if len(e.Unpriced) > 0 {
+		err := ctx.Log.Warn(fmt.Sprintf("no list price for %s; it is not in estimatedMonthlyCostUsd", strings.Join(e.Unpriced, ", ")), nil)
+		if err != nil {
+			return err
+		}
+	}
+	ctx.Export("estimatedMonthlyCostUsd", pulumi.Float64(e.Total()))
+	return nil
+}
+
+func (e *Estimate) add(description string, monthly float64) {
+	e.Items = append(e.Items, Item{Description: description, MonthlyUsd: monthly})
+}
+
+// RdsInstances prices count Aurora MySQL instances of class. db.serverless
+// is billed per ACU-hour used and is listed as unpriced.
+func (e *Estimate) RdsInstances(class string, count int, ioOptimized bool) {
+	if count == 0 {
+		return
+	}
+	hourly, ok := rdsBurstableHourly[class]
+	if !ok {
+		hourly, ok = linearPrice(rdsLargeHourly, class)
+	}
+	if !ok {
+		e.Unpriced = append(e.Unpriced, fmt.Sprintf("%d x %s", count, class))
+		return
+	}
+	description := fmt.Sprintf("%d x Aurora %s", count, class)
+	if ioOptimized {
+		hourly *= ioOptimizedFactor
+		description += " (I/O-Optimized)"
+	}
+	e.add(description, hourly*HoursPerMonth*float64(count))
+}
+
+// MysqlInstances prices count Single-AZ RDS for MySQL instances of class,
+// each with storageGb of gp3 storage.
+func (e *Estimate) MysqlInstances(class string, count, storageGb int) {
+	if count == 0 {
+		return
+	}
+	hourly, ok := linearPrice(mysqlLargeHourly, class)
+	if !ok {
+		e.Unpriced = append(e.Unpriced, fmt.Sprintf("%d x %s", count, class))
+		return
+	}
+	e.add(fmt.Sprintf("%d x RDS for MySQL %s (%d GB gp3)", count, class, storageGb),
+		(hourly*HoursPerMonth+rdsGp3GbMonthly*float64(storageGb))*float64(count))
+}
+
+// DmsInstances prices count Single-AZ DMS replication instances of class,
+// each with storageGb of storage.
+func (e *Estimate) DmsInstances(class string, count, storageGb int) {
+	if count == 0 {
+		return
+	}
+	hourly, ok := linearPrice(dmsLargeHourly, class)
+	if !ok {
+		e.Unpriced = append(e.Unpriced, fmt.Sprintf("%d x %s", count, class))
+		return
+	}
+	e.add(fmt.Sprintf("%d x DMS %s (%d GB)", count, class, storageGb),
+		(hourly*HoursPerMonth+dmsGbMonthly*float64(storageGb))*float64(count))
+}
+
+// RdsProxy prices an RDS Proxy in front of count provisioned instances of
+// class. Large instances have 2 vCPUs, and the proxy bills at least 2 vCPUs
+// per instance.
+func (e *Estimate) RdsProxy(class string, count int) {
+	if count == 0 {
+		return
+	}
+	i := strings.LastIndex(class, ".")
+	factor, ok := sizeFactors[class[i+1:]]
+	if i <= 0 || !ok {
+		e.Unpriced = append(e.Unpriced, fmt.Sprintf("RDS Proxy for %d x %s", count, class))
+		return
+	}
+	vcpus := max(2, 2*factor) * float64(count)
+	e.add(fmt.Sprintf("RDS Proxy (%g vCPUs)", vcpus), rdsProxyVcpuHourly*HoursPerMonth*vcpus)
+}
+
+// Ec2Instances prices count on-demand Linux instances of instanceType.
+func (e *Estimate) Ec2Instances(instanceType string, count int) {
+	if count == 0 {
+		return
+	}
+	hourly, ok := linearPrice(ec2LargeHourly, instanceType)
+	if !ok {
+		e.Unpriced = append(e.Unpriced, fmt.Sprintf("%d x %s", count, instanceType))
+		return
+	}
+	e.add(fmt.Sprintf("%d x EC2 %s", count, instanceType), hourly*HoursPerMonth*float64(count))
+}
+
+// Gp3Volumes prices count gp3 volumes of sizeGb at the baseline IOPS and
+// throughput.
+func (e *Estimate) Gp3Volumes(sizeGb, count int) {
+	if count > 0 {
+		e.add(fmt.Sprintf("%d x %d GB gp3", count, sizeGb), gp3GbMonthly*float64(sizeGb*count))
+	}
+}
+
+// NatGateways prices count NAT gateways, without data processing.
+func (e *Estimate) NatGateways(count int) {
+	if count > 0 {
+		e.add(fmt.Sprintf("%d x NAT gateway", count), natGatewayHourly*HoursPerMonth*float64(count))
+	}
+}
+
+// PublicIPv4 prices count public IPv4 addresses.
+func (e *Estimate) PublicIPv4(count int) {
+	if count > 0 {
+		e.add(fmt.Sprintf("%d x public IPv4 address", count), publicIPv4Hourly*HoursPerMonth*float64(count))
+	}
+}
+
+// KmsKeys prices count customer managed KMS keys, without requests.
+func (e *Estimate) KmsKeys(count int) {
+	if count > 0 {
+		e.add(fmt.Sprintf("%d x KMS key", count), kmsKeyMonthly*float64(count))
+	}
+}
+
+// Dashboards prices count CloudWatch dashboards.
+func (e *Estimate) Dashboards(count int) {
+	if count > 0 {
+		e.add(fmt.Sprintf("%d x CloudWatch dashboard", count), dashboardMonthly*float64(count))
+	}
+}
+
+// Alarms prices count standard resolution CloudWatch alarms.
+func (e *Estimate) Alarms(count int) {
+	if count > 0 {
+		e.add(fmt.Sprintf("%d x CloudWatch alarm", count), alarmMonthly*float64(count))
+	}
+}
+
+// Secrets prices count Secrets Manager secrets, without API calls.
+func (e *Estimate) Secrets(count int) {
+	if count > 0 {
+		e.add(fmt.Sprintf("%d x Secrets Manager secret", count), secretMonthly*float64(count))
+	}
+}
+
+// linearPrice returns the hourly price of "<family>.<size>" from the large
+// prices of the families.
+func linearPrice(largeHourly map[string]float64, name string) (float64, bool) {
+	i := strings.LastIndex(name, ".")
+	if i <= 0 {
+		return 0, false
+	}
+	large, ok := largeHourly[name[:i]]
+	factor, known := sizeFactors[name[i+1:]]
+	if !ok || !known {
+		return 0, false
+	}
+	return large * factor, true
+}
@@ -0,0 +1,100 @@
+package cost
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestEstimate(t *testing.T) {
+	var e Estimate
+	e.RdsInstances("db.r6g.xlarge", 2, false)
+	e.Ec2Instances("t3.xlarge", 1)
+	e.Gp3Volumes(30, 1)
+	e.PublicIPv4(1)
+	e.NatGateways(0)
+
+	// 2 x 0.52 + 0.1664 + 0.005 per hour, plus 30 GB of gp3
+	want := math.Round(((2*0.52+0.1664+0.005)*HoursPerMonth+30*0.08)*100) / 100
+	if got := e.Total(); got != want {
+		t.Errorf("Total() = %v, want %v", got, want)
+	}
+	if len(e.Items) != 4 || e.Items[0].Description != "2 x Aurora db.r6g.xlarge" {
+		t.Errorf("got items %+v", e.Items)
+	}
+}
+
+func TestRdsInstances(t *testing.T) {
+	for _, tt := range []struct {
+		class       string
+		ioOptimized bool
+		want        float64
+	}{
+		{class: "db.r6g.large", want: 0.26 * HoursPerMonth},
+		{class: "db.r6g.4xlarge", want: 0.26 * 8 * HoursPerMonth},
+		{class: "db.t4g.medium", want: 0.073 * HoursPerMonth},
+		{class: "db.r6g.large", ioOptimized: true, want: 0.26 * 1.3 * HoursPerMonth},
+	} {
+		var e Estimate
+		e.RdsInstances(tt.class, 1, tt.ioOptimized)
+		if len(e.Items) != 1 || math.Abs(e.Items[0].MonthlyUsd-tt.want) > 1e-9 {
+			t.Errorf("%s (I/O-Optimized %v): got %+v, want %v a month", tt.class, tt.ioOptimized, e.Items, tt.want)
+		}
+	}
+}
+
+func TestMysqlInstances(t *testing.T) {
+	var e Estimate
+	e.MysqlInstances("db.t4g.micro", 1, 20)
+	want := 0.129/8*HoursPerMonth + 20*0.115
+	if len(e.Items) != 1 || math.Abs(e.Items[0].MonthlyUsd-want) > 1e-9 || e.Items[0].Description != "1 x RDS for MySQL db.t4g.micro (20 GB gp3)" {
+		t.Errorf("got %+v, want %v a month", e.Items, want)
+	}
+	e.MysqlInstances("db.x2g.large", 1, 20)
+	if len(e.Unpriced) != 1 {
+		t.Errorf("Unpriced = %v, want db.x2g.large", e.Unpriced)
+	}
+}
+
+func TestDmsInstances(t *testing.T) {
+	var e Estimate
+	e.DmsInstances("dms.t3.micro", 1, 20)
+	want := 0.146/8*HoursPerMonth + 20*0.115
+	if len(e.Items) != 1 || math.Abs(e.Items[0].MonthlyUsd-want) > 1e-9 || e.Items[0].Description != "1 x DMS dms.t3.micro (20 GB)" {
+		t.Errorf("got %+v, want %v a month", e.Items, want)
+	}
+	e.DmsInstances("dms.x2g.large", 1, 20)
+	if len(e.Unpriced) != 1 {
+		t.Errorf("Unpriced = %v, want dms.x2g.large", e.Unpriced)
+	}
+}
+
+func TestRdsProxy(t *testing.T) {
+	var e Estimate
+	e.RdsProxy("db.r6g.xlarge", 2)
+	e.RdsProxy("db.t4g.medium", 2)
+	if len(e.Items) != 2 || e.Items[0].Description != "RDS Proxy (8 vCPUs)" || e.Items[1].Description != "RDS Proxy (4 vCPUs)" {
+		t.Fatalf("got %+v", e.Items)
+	}
+	if want := 8 * 0.015 * HoursPerMonth; math.Abs(e.Items[0].MonthlyUsd-want) > 1e-9 {
+		t.Errorf("got %v, want %v a month", e.Items[0].MonthlyUsd, want)
+	}
+	e.RdsProxy("db.serverless", 2)
+	if len(e.Unpriced) != 1 {
+		t.Errorf("Unpriced = %v, want db.serverless", e.Unpriced)
+	}
+}
+
+func TestUnpriced(t *testing.T) {
+	var e Estimate
+	e.RdsInstances("db.serverless", 2, false)
+	e.Ec2Instances("p5.48xlarge", 1)
+	e.Ec2Instances("t3", 1)
+	if e.Total() != 0 || len(e.Items) != 0 {
+		t.Errorf("got items %+v, want none", e.Items)
+	}
+	want := []string{"2 x db.serverless", "1 x p5.48xlarge", "1 x t3"}
+	if !slices.Equal(e.Unpriced, want) {
+		t.Errorf("Unpriced = %v, want %v", e.Unpriced, want)
+	}
+}
@@ -0,0 +1,42 @@
+// Package dbconn formats ready-to-use connection strings of the lab's Aurora
+// endpoints for the Go MySQL driver, JDBC and the mysql CLI.
+package dbconn
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// Strings are the connection strings of one endpoint. None contains the
+// password: the DSN takes it after the user name (user:password@...), the
+// JDBC driver as the password property, and the mysql CLI prompts for it.
+type Strings struct {
+	// Dsn is a github.com/go-sql-driver/mysql data source name
+	Dsn string
+	// JdbcUrl is an AWS Advanced JDBC Wrapper URL, as the workload simulator
+	// uses for its failover handling
+	JdbcUrl string
+	// MysqlCommand runs the mysql CLI against the endpoint
+	MysqlCommand string
+}
+
+// For returns the connection strings of database on host:port as username.
+func For(host string, port int, database, username string) Strings {
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	return Strings{
+		Dsn:          fmt.Sprintf("%s@tcp(%s)/%s?parseTime=true", username, address, database),
+		JdbcUrl:      fmt.Sprintf("jdbc:aws-wrapper:mysql://%s/%s", address, database),
+		MysqlCommand: fmt.Sprintf("mysql -h %s -P %d -u %s -p %s", host, port, username, database),
+	}
+}
+
+// Map returns the strings by the keys of the stack outputs: dsn, jdbcUrl
+// and mysqlCommand.
+func (s Strings) Map() map[string]string {
+	return map[string]string{
+		"dsn":          s.Dsn,
+		"jdbcUrl":      s.JdbcUrl,
+		"mysqlCommand": s.MysqlCommand,
+	}
+}
@@ -0,0 +1,19 @@
+package dbconn
+
+import "testing"
+
+func TestFor(t *testing.T) {
+	s := For("lab.cluster-abc.us-east-1.rds.amazonaws.com", 3306, "lab_db", "admin")
+	for got, want := range map[string]string{
+		s.Dsn:          "admin@tcp(lab.cluster-abc.us-east-1.rds.amazonaws.com:3306)/lab_db?parseTime=true",
+		s.JdbcUrl:      "jdbc:aws-wrapper:mysql://lab.cluster-abc.us-east-1.rds.amazonaws.com:3306/lab_db",
+		s.MysqlCommand: "mysql -h lab.cluster-abc.us-east-1.rds.amazonaws.com -P 3306 -u admin -p lab_db",
+	} {
+		if got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+	if m := s.Map(); m["jdbcUrl"] != s.JdbcUrl || len(m) != 3 {
+		t.Errorf("Map() = %v", m)
+	}
+}
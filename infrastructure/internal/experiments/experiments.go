@@ -0,0 +1,319 @@
+// Package experiments is the lab's experiment registry: a DynamoDB table of
+// the monitoring stack (experimentTableName) where lab-scenario, bgctl and the
+// workload simulator register their runs with the configuration, the times
+// of their steps and the results, so repeated lab sessions can be listed and
+// compared with lab-report list.
+//
+// A run ID is shared by the tools taking part in a run (see internal/runid),
+// so each tool registers its own item under the run ID, keyed by its Source.
+// bgctl commands joining a run register as bgctl/<command> (CommandSource),
+// so the commands of one run keep their own items.
+package experiments
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"aurora-bluegreen-lab/internal/stacks"
+)
+
+// Sources registering runs.
+const (
+	SourceScenario  = "lab-scenario"
+	SourceBgctl     = "bgctl"
+	SourceSimulator = "simulator"
+)
+
+// CommandSource returns the source of a bgctl command joining a run started
+// by another tool or command, e.g. bgctl/switchover.
+func CommandSource(command string) string {
+	return SourceBgctl + "/" + command
+}
+
+// Tool returns the tool of a source: bgctl for bgctl/switchover, else the
+// source itself.
+func Tool(source string) string {
+	if tool, _, ok := strings.Cut(source, "/"); ok {
+		return tool
+	}
+	return source
+}
+
+// Statuses of a run.
+const (
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// TimeFormat formats the run times with milliseconds and a fixed width, so
+// they sort as strings.
+const TimeFormat = "2006-01-02T15:04:05.000Z"
+
+// Attributes of the registry table's items, one item per run and source.
+const (
+	// AttrRunID is the partition key
+	AttrRunID = "RunId"
+	// AttrSource is the sort key, e.g. SourceScenario
+	AttrSource    = "Source"
+	AttrName      = "Name"
+	AttrStatus    = "Status"
+	AttrStartedAt = "StartedAt"
+	AttrEndedAt   = "EndedAt"
+	// AttrConfig is a map of strings
+	AttrConfig = "Config"
+	// AttrTimings is a map of times in TimeFormat
+	AttrTimings = "Timings"
+	// AttrResults is a map of numbers
+	AttrResults = "Results"
+	AttrError   = "Error"
+)
+
+// Run is what one source registered about a run.
+type Run struct {
+	RunID  string
+	Source string
+	// Name is what was run, e.g. the scenario name or the bgctl command
+	Name      string
+	Status    string
+	StartedAt time.Time
+	// EndedAt is zero while the run is running
+	EndedAt time.Time
+	// Config is the configuration the run was started with
+	Config map[string]string
+	// Timings are the times of the run's steps, e.g. switchover-started
+	Timings map[string]time.Time
+	// Results are the run's measurements, e.g. successRate
+	Results map[string]float64
+	// Error is why a failed run failed
+	Error string
+}
+
+// Duration returns how long the run took, or has been running until now.
+func (r *Run) Duration(now time.Time) time.Duration {
+	if r.EndedAt.IsZero() {
+		return now.Sub(r.StartedAt)
+	}
+	return r.EndedAt.Sub(r.StartedAt)
+}
+
+// Finish ends the run at t as succeeded, or as failed with err.
+func (r *Run) Finish(t time.Time, err error) {
+	r.EndedAt = t
+	r.Status = StatusSucceeded
+	if err != nil {
+		r.Status = StatusFailed
+		r.Error = err.Error()
+	}
+}
+
+// Registry reads and writes the runs of a registry table.
+type Registry struct {
+	client *dynamodb.Client
+	table  string
+}
+
+// New returns the registry of the table.
+func New(cfg aws.Config, table string) *Registry {
+	return &Registry{client: dynamodb.NewFromConfig(cfg), table: table}
+}
+
+// Table returns the registry's table name.
+func (r *Registry) Table() string {
+	return r.table
+}
+
+// Put registers the run, replacing what its source registered before.
+func (r *Registry) Put(ctx context.Context, run *Run) error {
+	_, err := r.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(r.table),
+		Item:      runItem(run),
+	})
+	if err != nil {
+		return fmt.Errorf("registering run %s in %s: %w", run.RunID, r.table, err)
+	}
+	return nil
+}
+
+// Get returns what every source registered about the run, or no runs when
+// the run is unknown.
+func (r *Registry) Get(ctx context.Context, runID string) ([]Run, error) {
+	paginator := dynamodb.NewQueryPaginator(r.client, &dynamodb.QueryInput{
+		TableName:                aws.String(r.table),
+		KeyConditionExpression:   aws.String("#run = :run"),
+		ExpressionAttributeNames: map[string]string{"#run": AttrRunID},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":run": &types.AttributeValueMemberS{Value: runID},
+		},
+	})
+	var runs []Run
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("reading run %s from %s: %w", runID, r.table, err)
+		}
+		if runs, err = appendItems(runs, page.Items); err != nil {
+			return nil, fmt.Errorf("reading run %s from %s: %w", runID, r.table, err)
+		}
+	}
+	Sort(runs)
+	return runs, nil
+}
+
+// List returns every registered run, oldest first.
+func (r *Registry) List(ctx context.Context) ([]Run, error) {
+	paginator := dynamodb.NewScanPaginator(r.client, &dynamodb.ScanInput{
+		TableName: aws.String(r.table),
+	})
+	var runs []Run
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("listing runs of %s: %w", r.table, err)
+		}
+		if runs, err = appendItems(runs, page.Items); err != nil {
+			return nil, fmt.Errorf("listing runs of %s: %w", r.table, err)
+		}
+	}
+	Sort(runs)
+	return runs, nil
+}
+
+// Sort sorts runs by start time, then run ID and source.
+func Sort(runs []Run) {
+	sort.SliceStable(runs, func(i, j int) bool {
+		a, b := runs[i], runs[j]
+		if !a.StartedAt.Equal(b.StartedAt) {
+			return a.StartedAt.Before(b.StartedAt)
+		}
+		if a.RunID != b.RunID {
+			return a.RunID < b.RunID
+		}
+		return a.Source < b.Source
+	})
+}
+
+// TableFromStacks returns the registry table the lab's monitoring stack
+// exports (experimentTableName), or "" when it exports none.
+func TableFromStacks(ctx context.Context, reader *stacks.Reader) (string, error) {
+	monitoring, err := reader.Outputs(ctx, "monitoring")
+	if err != nil {
+		return "", err
+	}
+	return monitoring.String("experimentTableName"), nil
+}
+
+func appendItems(runs []Run, items []map[string]types.AttributeValue) ([]Run, error) {
+	for _, item := range items {
+		run, err := itemRun(item)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// runItem returns the table item of the run; empty attributes are omitted.
+func runItem(run *Run) map[string]types.AttributeValue {
+	item := map[string]types.AttributeValue{
+		AttrRunID:     &types.AttributeValueMemberS{Value: run.RunID},
+		AttrSource:    &types.AttributeValueMemberS{Value: run.Source},
+		AttrStatus:    &types.AttributeValueMemberS{Value: run.Status},
+		AttrStartedAt: &types.AttributeValueMemberS{Value: run.StartedAt.UTC().Format(TimeFormat)},
+	}
+	if run.Name != "" {
+		item[AttrName] = &types.AttributeValueMemberS{Value: run.Name}
+	}
+	if !run.EndedAt.IsZero() {
+		item[AttrEndedAt] = &types.AttributeValueMemberS{Value: run.EndedAt.UTC().Format(TimeFormat)}
+	}
+	if run.Error != "" {
+		item[AttrError] = &types.AttributeValueMemberS{Value: run.Error}
+	}
+	if len(run.Config) > 0 {
+		config := map[string]types.AttributeValue{}
+		for key, value := range run.Config {
+			config[key] = &types.AttributeValueMemberS{Value: value}
+		}
+		item[AttrConfig] = &types.AttributeValueMemberM{Value: config}
+	}
+	if len(run.Timings) > 0 {
+		timings := map[string]types.AttributeValue{}
+		for key, t := range run.Timings {
+			timings[key] = &types.AttributeValueMemberS{Value: t.UTC().Format(TimeFormat)}
+		}
+		item[AttrTimings] = &types.AttributeValueMemberM{Value: timings}
+	}
+	if len(run.Results) > 0 {
+		results := map[string]types.AttributeValue{}
+		for key, value := range run.Results {
+			results[key] = &types.AttributeValueMemberN{Value: strconv.FormatFloat(value, 'f', -1, 64)}
+		}
+		item[AttrResults] = &types.AttributeValueMemberM{Value: results}
+	}
+	return item
+}
+
+// itemRun converts a table item to a run.
+func itemRun(item map[string]types.AttributeValue) (Run, error) {
+	run := Run{
+		RunID:  stringAttr(item[AttrRunID]),
+		Source: stringAttr(item[AttrSource]),
+		Name:   stringAttr(item[AttrName]),
+		Status: stringAttr(item[AttrStatus]),
+		Error:  stringAttr(item[AttrError]),
+	}
+	var err error
+	if run.StartedAt, err = time.Parse(TimeFormat, stringAttr(item[AttrStartedAt])); err != nil {
+		return Run{}, fmt.Errorf("run %s (%s): %w", run.RunID, run.Source, err)
+	}
+	if ended := stringAttr(item[AttrEndedAt]); ended != "" {
+		if run.EndedAt, err = time.Parse(TimeFormat, ended); err != nil {
+			return Run{}, fmt.Errorf("run %s (%s): %w", run.RunID, run.Source, err)
+		}
+	}
+
+	if m, ok := item[AttrConfig].(*types.AttributeValueMemberM); ok {
+		run.Config = map[string]string{}
+		for key, value := range m.Value {
+			run.Config[key] = stringAttr(value)
+		}
+	}
+	if m, ok := item[AttrTimings].(*types.AttributeValueMemberM); ok {
+		run.Timings = map[string]time.Time{}
+		for key, value := range m.Value {
+			if run.Timings[key], err = time.Parse(TimeFormat, stringAttr(value)); err != nil {
+				return Run{}, fmt.Errorf("run %s (%s) timing %s: %w", run.RunID, run.Source, key, err)
+			}
+		}
+	}
+	if m, ok := item[AttrResults].(*types.AttributeValueMemberM); ok {
+		run.Results = map[string]float64{}
+		for key, value := range m.Value {
+			n, ok := value.(*types.AttributeValueMemberN)
+			if !ok {
+				return Run{}, fmt.Errorf("run %s (%s) result %s is not a number", run.RunID, run.Source, key)
+			}
+			if run.Results[key], err = strconv.ParseFloat(n.Value, 64); err != nil {
+				return Run{}, fmt.Errorf("run %s (%s) result %s: %w", run.RunID, run.Source, key, err)
+			}
+		}
+	}
+	return run, nil
+}
+
+func stringAttr(value types.AttributeValue) string {
+	if s, ok := value.(*types.AttributeValueMemberS); ok {
+		return s.Value
+	}
+	return ""
+}
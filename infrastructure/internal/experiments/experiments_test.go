@@ -0,0 +1,112 @@
+package experiments
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestRunItemRoundTrip(t *testing.T) {
+	started := time.Date(2026, 1, 2, 15, 4, 5, 123e6, time.UTC)
+	run := &Run{
+		RunID:     "minor-upgrade-20260102-150405",
+		Source:    SourceScenario,
+		Name:      "minor-upgrade",
+		Status:    StatusRunning,
+		StartedAt: started,
+		Config:    map[string]string{"cluster": "lab-cluster", "targetEngineVersion": "8.0.mysql_aurora.3.08.0"},
+		Timings:   map[string]time.Time{"switchover-started": started.Add(20 * time.Minute)},
+		Results:   map[string]float64{"successRate": 99.87, "failedRequests": 42},
+	}
+	run.Finish(started.Add(30*time.Minute), errors.New("switchover timed out"))
+
+	item := runItem(run)
+	if got := item[AttrResults].(*types.AttributeValueMemberM).Value["successRate"]; got.(*types.AttributeValueMemberN).Value != "99.87" {
+		t.Errorf("successRate stored as %v", got)
+	}
+	if got := item[AttrEndedAt].(*types.AttributeValueMemberS).Value; got != "2026-01-02T15:34:05.123Z" {
+		t.Errorf("EndedAt stored as %s", got)
+	}
+
+	parsed, err := itemRun(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&parsed, run) {
+		t.Errorf("got %+v, want %+v", parsed, *run)
+	}
+	if parsed.Status != StatusFailed || parsed.Duration(time.Time{}) != 30*time.Minute {
+		t.Errorf("got status %s and duration %s, want a failed 30m run", parsed.Status, parsed.Duration(time.Time{}))
+	}
+}
+
+func TestRunItemOmitsEmptyAttributes(t *testing.T) {
+	started := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	item := runItem(&Run{RunID: "sim-20260102-150405", Source: SourceSimulator, Status: StatusRunning, StartedAt: started})
+	for _, attr := range []string{AttrName, AttrEndedAt, AttrError, AttrConfig, AttrTimings, AttrResults} {
+		if _, ok := item[attr]; ok {
+			t.Errorf("item has an empty %s attribute", attr)
+		}
+	}
+
+	run, err := itemRun(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := started.Add(time.Minute)
+	if run.Duration(now) != time.Minute {
+		t.Errorf("running run lasted %s, want 1m", run.Duration(now))
+	}
+}
+
+func TestItemRunRejectsInvalidItems(t *testing.T) {
+	for name, item := range map[string]map[string]types.AttributeValue{
+		"missing start": {
+			AttrRunID: &types.AttributeValueMemberS{Value: "run"},
+		},
+		"result not a number": {
+			AttrRunID:     &types.AttributeValueMemberS{Value: "run"},
+			AttrStartedAt: &types.AttributeValueMemberS{Value: "2026-01-02T15:04:05.000Z"},
+			AttrResults: &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+				"successRate": &types.AttributeValueMemberS{Value: "high"},
+			}},
+		},
+	} {
+		if _, err := itemRun(item); err == nil || !strings.Contains(err.Error(), "run run") {
+			t.Errorf("%s: got error %v", name, err)
+		}
+	}
+}
+
+func TestSort(t *testing.T) {
+	t0 := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	runs := []Run{
+		{RunID: "b", Source: SourceSimulator, StartedAt: t0},
+		{RunID: "c", Source: SourceBgctl, StartedAt: t0.Add(-time.Hour)},
+		{RunID: "b", Source: SourceScenario, StartedAt: t0},
+		{RunID: "a", Source: SourceSimulator, StartedAt: t0},
+	}
+	Sort(runs)
+	var got []string
+	for _, run := range runs {
+		got = append(got, run.RunID+"/"+run.Source)
+	}
+	want := []string{"c/bgctl", "a/simulator", "b/lab-scenario", "b/simulator"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestCommandSource(t *testing.T) {
+	source := CommandSource("switchover")
+	if source != "bgctl/switchover" || Tool(source) != SourceBgctl {
+		t.Errorf("got %q (tool %q)", source, Tool(source))
+	}
+	if Tool(SourceScenario) != SourceScenario {
+		t.Errorf("got %q", Tool(SourceScenario))
+	}
+}
@@ -0,0 +1,29 @@
+package golambda
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// Build cross-compiles the command pkg (e.g. ./cmd/lab-snapshots) of the Go
+// module in moduleDir for the arm64 provided.al2023 runtime and returns the
+// path of the bootstrap executable in a temporary directory. The build is
+// reproducible, so a function deployed from it is only updated when its code
+// changes.
+func Build(moduleDir, pkg string) (string, error) {
+	dir, err := os.MkdirTemp("", filepath.Base(pkg))
+	if err != nil {
+		return "", err
+	}
+	bootstrap := filepath.Join(dir, "bootstrap")
+
+	cmd := exec.Command("go", "build", "-trimpath", "-ldflags", "-s -w -buildid=", "-o", bootstrap, pkg)
+	cmd.Dir = moduleDir
+	cmd.Env = append(os.Environ(), "GOOS=linux", "GOARCH=arm64", "CGO_ENABLED=0")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("building %s: %w\n%s", pkg, err, out)
+	}
+	return bootstrap, nil
+}
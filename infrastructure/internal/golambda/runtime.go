@@ -0,0 +1,87 @@
+// Package golambda runs and builds the lab's Go Lambda functions (cmd/lab-*)
+// on the provided.al2023 runtime without the aws-lambda-go library:
+//
+//   - Serve implements the Lambda Runtime API loop inside the function
+//   - Build cross-compiles a function's bootstrap executable for the stack
+//     that deploys it
+package golambda
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// runtimeAPIVersion is the path prefix of the Lambda Runtime API.
+const runtimeAPIVersion = "2018-06-01"
+
+// Handler handles one invocation; its result is returned as JSON.
+type Handler func(ctx context.Context, event json.RawMessage) (interface{}, error)
+
+// Serve implements the Lambda Runtime API loop of a custom runtime: it
+// fetches the next invocation, runs handler with the invocation's deadline
+// and posts the result or error, until the execution environment is shut
+// down.
+func Serve(handler Handler) error {
+	api := os.Getenv("AWS_LAMBDA_RUNTIME_API")
+	if api == "" {
+		return fmt.Errorf("AWS_LAMBDA_RUNTIME_API is not set; the command only runs as a Lambda function")
+	}
+	base := fmt.Sprintf("http://%s/%s/runtime", api, runtimeAPIVersion)
+	// No timeout: the next invocation request blocks until there is one
+	client := &http.Client{}
+
+	for {
+		resp, err := client.Get(base + "/invocation/next")
+		if err != nil {
+			return fmt.Errorf("fetching the next invocation: %w", err)
+		}
+		event, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("reading the invocation event: %w", err)
+		}
+		requestID := resp.Header.Get("Lambda-Runtime-Aws-Request-Id")
+
+		ctx := context.Background()
+		cancel := func() {}
+		if ms, err := strconv.ParseInt(resp.Header.Get("Lambda-Runtime-Deadline-Ms"), 10, 64); err == nil {
+			ctx, cancel = context.WithDeadline(ctx, time.UnixMilli(ms))
+		}
+		result, err := handler(ctx, event)
+		cancel()
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			body, _ := json.Marshal(map[string]string{"errorMessage": err.Error(), "errorType": "HandlerError"})
+			err = post(client, base+"/invocation/"+requestID+"/error", body)
+		} else {
+			body, merr := json.Marshal(result)
+			if merr != nil {
+				return fmt.Errorf("encoding the result: %w", merr)
+			}
+			err = post(client, base+"/invocation/"+requestID+"/response", body)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func post(client *http.Client, url string, body []byte) error {
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("posting to the runtime API: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("posting to the runtime API: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,289 @@
+// Package guardrails is the lab's policy pack: policies that every planned
+// resource must pass before lab-deploy updates a stack.
+//
+// The policies run against the resource inputs reported by a stack preview,
+// so they see the same properties a Pulumi CrossGuard pack would, without
+// requiring a Node.js or Python policy runtime:
+//
+//   - rds-no-public-access: RDS instances must not be publicly accessible
+//   - storage-encryption: RDS clusters and EC2 volumes must be encrypted
+//   - no-public-ssh: security groups must not open SSH to the internet
+//     (unless Config.AllowPublicSsh is set)
+//   - instance-size-ceiling: EC2 and RDS instance sizes must not exceed
+//     Config.MaxInstanceSize
+package guardrails
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultMaxInstanceSize is the instance size ceiling when Config.MaxInstanceSize is empty.
+const DefaultMaxInstanceSize = "2xlarge"
+
+// Resource is a resource planned by a stack update.
+type Resource struct {
+	URN  string
+	Type string
+	// Inputs are the resource inputs as reported by the preview engine events
+	Inputs map[string]interface{}
+}
+
+// Config holds the policy settings.
+type Config struct {
+	// AllowPublicSsh permits security groups that open port 22 to 0.0.0.0/0
+	AllowPublicSsh bool
+	// MaxInstanceSize is the largest allowed size (e.g., "xlarge", "2xlarge")
+	MaxInstanceSize string
+}
+
+// Violation is a resource failing a policy.
+type Violation struct {
+	Policy  string
+	URN     string
+	Message string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("[%s] %s: %s", v.Policy, v.URN, v.Message)
+}
+
+// Policy is a single guardrail.
+type Policy struct {
+	Name        string
+	Description string
+	validate    func(cfg Config, maxUnits float64, r Resource) []string
+}
+
+// Policies lists all guardrails in the order they are evaluated.
+var Policies = []Policy{
+	{
+		Name:        "rds-no-public-access",
+		Description: "RDS instances must not be publicly accessible",
+		validate:    rdsNoPublicAccess,
+	},
+	{
+		Name:        "storage-encryption",
+		Description: "RDS clusters and EC2 volumes must be encrypted",
+		validate:    storageEncryption,
+	},
+	{
+		Name:        "no-public-ssh",
+		Description: "Security groups must not open SSH (port 22) to the internet",
+		validate:    noPublicSsh,
+	},
+	{
+		Name:        "instance-size-ceiling",
+		Description: "EC2 and RDS instance sizes must not exceed the cost ceiling",
+		validate:    instanceSizeCeiling,
+	},
+}
+
+// Check evaluates every policy against the resources and returns the
+// violations sorted by URN.
+func Check(cfg Config, resources []Resource) ([]Violation, error) {
+	if cfg.MaxInstanceSize == "" {
+		cfg.MaxInstanceSize = DefaultMaxInstanceSize
+	}
+	maxUnits, ok := sizeUnits(cfg.MaxInstanceSize)
+	if !ok {
+		return nil, fmt.Errorf("invalid instance size ceiling %q (expected e.g. large, xlarge, 2xlarge)", cfg.MaxInstanceSize)
+	}
+
+	var violations []Violation
+	for _, r := range resources {
+		for _, p := range Policies {
+			for _, message := range p.validate(cfg, maxUnits, r) {
+				violations = append(violations, Violation{Policy: p.Name, URN: r.URN, Message: message})
+			}
+		}
+	}
+	sort.SliceStable(violations, func(i, j int) bool { return violations[i].URN < violations[j].URN })
+	return violations, nil
+}
+
+func rdsNoPublicAccess(_ Config, _ float64, r Resource) []string {
+	switch r.Type {
+	case "aws:rds/clusterInstance:ClusterInstance", "aws:rds/instance:Instance":
+		if isTrue(r.Inputs["publiclyAccessible"]) {
+			return []string{"publiclyAccessible must be false"}
+		}
+	}
+	return nil
+}
+
+func storageEncryption(_ Config, _ float64, r Resource) []string {
+	var messages []string
+	switch r.Type {
+	case "aws:rds/cluster:Cluster", "aws:rds/instance:Instance":
+		if !isTrue(r.Inputs["storageEncrypted"]) {
+			messages = append(messages, "storageEncrypted must be true")
+		}
+	case "aws:ec2/instance:Instance":
+		if root, ok := r.Inputs["rootBlockDevice"].(map[string]interface{}); !ok || !isTrue(root["encrypted"]) {
+			messages = append(messages, "rootBlockDevice.encrypted must be true")
+		}
+		for _, device := range objects(r.Inputs["ebsBlockDevices"]) {
+			if !isTrue(device["encrypted"]) {
+				messages = append(messages, fmt.Sprintf("EBS volume %v must be encrypted", device["deviceName"]))
+			}
+		}
+	case "aws:ec2/launchTemplate:LaunchTemplate":
+		for _, mapping := range objects(r.Inputs["blockDeviceMappings"]) {
+			ebs, ok := mapping["ebs"].(map[string]interface{})
+			if ok && !isTrue(ebs["encrypted"]) {
+				messages = append(messages, fmt.Sprintf("EBS volume %v must be encrypted", mapping["deviceName"]))
+			}
+		}
+	case "aws:ebs/volume:Volume":
+		if !isTrue(r.Inputs["encrypted"]) {
+			messages = append(messages, "encrypted must be true")
+		}
+	}
+	return messages
+}
+
+func noPublicSsh(cfg Config, _ float64, r Resource) []string {
+	if cfg.AllowPublicSsh {
+		return nil
+	}
+
+	var rules []map[string]interface{}
+	switch r.Type {
+	case "aws:ec2/securityGroup:SecurityGroup":
+		rules = objects(r.Inputs["ingress"])
+	case "aws:ec2/securityGroupRule:SecurityGroupRule":
+		if r.Inputs["type"] == "ingress" {
+			rules = []map[string]interface{}{r.Inputs}
+		}
+	case "aws:vpc/securityGroupIngressRule:SecurityGroupIngressRule":
+		rules = []map[string]interface{}{{
+			"protocol":       r.Inputs["ipProtocol"],
+			"fromPort":       r.Inputs["fromPort"],
+			"toPort":         r.Inputs["toPort"],
+			"cidrBlocks":     []interface{}{r.Inputs["cidrIpv4"]},
+			"ipv6CidrBlocks": []interface{}{r.Inputs["cidrIpv6"]},
+		}}
+	}
+
+	var messages []string
+	for _, rule := range rules {
+		if !allowsPort(rule, 22) {
+			continue
+		}
+		for _, cidr := range append(stringValues(rule["cidrBlocks"]), stringValues(rule["ipv6CidrBlocks"])...) {
+			if cidr == "0.0.0.0/0" || cidr == "::/0" {
+				messages = append(messages, fmt.Sprintf("SSH is open to %s; restrict it to your IP or explicitly allow public SSH", cidr))
+			}
+		}
+	}
+	return messages
+}
+
+func instanceSizeCeiling(cfg Config, maxUnits float64, r Resource) []string {
+	var instanceTypes []string
+	switch r.Type {
+	case "aws:ec2/instance:Instance", "aws:ec2/launchTemplate:LaunchTemplate":
+		instanceTypes = stringValues([]interface{}{r.Inputs["instanceType"]})
+	case "aws:rds/clusterInstance:ClusterInstance", "aws:rds/instance:Instance":
+		instanceTypes = stringValues([]interface{}{r.Inputs["instanceClass"]})
+	case "aws:autoscaling/group:Group":
+		if policy, ok := r.Inputs["mixedInstancesPolicy"].(map[string]interface{}); ok {
+			if template, ok := policy["launchTemplate"].(map[string]interface{}); ok {
+				for _, override := range objects(template["overrides"]) {
+					instanceTypes = append(instanceTypes, stringValues([]interface{}{override["instanceType"]})...)
+				}
+			}
+		}
+	}
+
+	var messages []string
+	for _, instanceType := range instanceTypes {
+		size := instanceType[strings.LastIndex(instanceType, ".")+1:]
+		units, ok := sizeUnits(size)
+		if !ok {
+			// Unknown sizes (e.g., values not known until apply) are not judged
+			continue
+		}
+		if units > maxUnits {
+			messages = append(messages, fmt.Sprintf("%s exceeds the instance size ceiling %s", instanceType, cfg.MaxInstanceSize))
+		}
+	}
+	return messages
+}
+
+// sizeUnits returns the AWS normalization factor of an instance size
+// (large = 4, xlarge = 8, 2xlarge = 16, ...). Bare metal sizes are treated as
+// larger than any ceiling.
+func sizeUnits(size string) (float64, bool) {
+	switch size {
+	case "nano":
+		return 0.25, true
+	case "micro":
+		return 0.5, true
+	case "small":
+		return 1, true
+	case "medium":
+		return 2, true
+	case "large":
+		return 4, true
+	case "xlarge":
+		return 8, true
+	}
+	if strings.HasPrefix(size, "metal") {
+		return 1 << 20, true
+	}
+	if n, err := strconv.Atoi(strings.TrimSuffix(size, "xlarge")); err == nil && strings.HasSuffix(size, "xlarge") && n > 0 {
+		return float64(8 * n), true
+	}
+	return 0, false
+}
+
+// allowsPort reports whether an ingress rule covers the TCP port.
+func allowsPort(rule map[string]interface{}, port float64) bool {
+	protocol := fmt.Sprint(rule["protocol"])
+	if protocol == "-1" || protocol == "all" {
+		return true
+	}
+	if protocol != "tcp" && protocol != "6" {
+		return false
+	}
+	from, fromOk := rule["fromPort"].(float64)
+	to, toOk := rule["toPort"].(float64)
+	return fromOk && toOk && from <= port && port <= to
+}
+
+// isTrue accepts both booleans and the "true" strings used by launch templates.
+func isTrue(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+	return false
+}
+
+func objects(value interface{}) []map[string]interface{} {
+	items, _ := value.([]interface{})
+	var result []map[string]interface{}
+	for _, item := range items {
+		if object, ok := item.(map[string]interface{}); ok {
+			result = append(result, object)
+		}
+	}
+	return result
+}
+
+func stringValues(value interface{}) []string {
+	items, _ := value.([]interface{})
+	var result []string
+	for _, item := range items {
+		if s, ok := item.(string); ok && s != "" {
+			result = append(result, s)
+		}
+	}
+	return result
+}
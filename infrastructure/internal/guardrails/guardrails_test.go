@@ -0,0 +1,127 @@
+package guardrails
+
+import (
+	"strings"
+	"testing"
+)
+
+func check(t *testing.T, cfg Config, resources ...Resource) []Violation {
+	t.Helper()
+	violations, err := Check(cfg, resources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return violations
+}
+
+func expectPolicies(t *testing.T, violations []Violation, want ...string) {
+	t.Helper()
+	var got []string
+	for _, v := range violations {
+		got = append(got, v.Policy)
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("violations: got %v, want %v", violations, want)
+	}
+}
+
+func sshGroup(cidr string) Resource {
+	return Resource{
+		URN:  "urn:ec2-sg",
+		Type: "aws:ec2/securityGroup:SecurityGroup",
+		Inputs: map[string]interface{}{
+			"ingress": []interface{}{
+				map[string]interface{}{
+					"protocol":   "tcp",
+					"fromPort":   float64(22),
+					"toPort":     float64(22),
+					"cidrBlocks": []interface{}{cidr},
+				},
+			},
+		},
+	}
+}
+
+func TestPublicSsh(t *testing.T) {
+	expectPolicies(t, check(t, Config{}, sshGroup("0.0.0.0/0")), "no-public-ssh")
+	expectPolicies(t, check(t, Config{}, sshGroup("203.0.113.10/32")))
+	expectPolicies(t, check(t, Config{AllowPublicSsh: true}, sshGroup("0.0.0.0/0")))
+
+	allTraffic := Resource{
+		URN:  "urn:rule",
+		Type: "aws:ec2/securityGroupRule:SecurityGroupRule",
+		Inputs: map[string]interface{}{
+			"type":       "ingress",
+			"protocol":   "-1",
+			"fromPort":   float64(0),
+			"toPort":     float64(0),
+			"cidrBlocks": []interface{}{"0.0.0.0/0"},
+		},
+	}
+	expectPolicies(t, check(t, Config{}, allTraffic), "no-public-ssh")
+}
+
+func TestRdsPublicAccessAndEncryption(t *testing.T) {
+	cluster := Resource{
+		URN:    "urn:cluster",
+		Type:   "aws:rds/cluster:Cluster",
+		Inputs: map[string]interface{}{"storageEncrypted": false},
+	}
+	instance := Resource{
+		URN:  "urn:writer",
+		Type: "aws:rds/clusterInstance:ClusterInstance",
+		Inputs: map[string]interface{}{
+			"publiclyAccessible": true,
+			"instanceClass":      "db.r6g.xlarge",
+		},
+	}
+	expectPolicies(t, check(t, Config{}, cluster, instance), "storage-encryption", "rds-no-public-access")
+}
+
+func TestVolumeEncryption(t *testing.T) {
+	template := Resource{
+		URN:  "urn:lt",
+		Type: "aws:ec2/launchTemplate:LaunchTemplate",
+		Inputs: map[string]interface{}{
+			"instanceType": "t3.xlarge",
+			"blockDeviceMappings": []interface{}{
+				map[string]interface{}{
+					"deviceName": "/dev/xvda",
+					"ebs":        map[string]interface{}{"encrypted": "true"},
+				},
+			},
+		},
+	}
+	expectPolicies(t, check(t, Config{}, template))
+
+	instance := Resource{
+		URN:    "urn:instance",
+		Type:   "aws:ec2/instance:Instance",
+		Inputs: map[string]interface{}{"instanceType": "t3.xlarge"},
+	}
+	expectPolicies(t, check(t, Config{}, instance), "storage-encryption")
+}
+
+func TestInstanceSizeCeiling(t *testing.T) {
+	instance := func(instanceType string) Resource {
+		return Resource{
+			URN:  "urn:instance",
+			Type: "aws:ec2/instance:Instance",
+			Inputs: map[string]interface{}{
+				"instanceType":    instanceType,
+				"rootBlockDevice": map[string]interface{}{"encrypted": true},
+			},
+		}
+	}
+
+	expectPolicies(t, check(t, Config{}, instance("t3.xlarge")))
+	expectPolicies(t, check(t, Config{}, instance("m5.2xlarge")))
+	expectPolicies(t, check(t, Config{}, instance("m5.4xlarge")), "instance-size-ceiling")
+	expectPolicies(t, check(t, Config{}, instance("c7g.metal")), "instance-size-ceiling")
+	expectPolicies(t, check(t, Config{MaxInstanceSize: "large"}, instance("t3.xlarge")), "instance-size-ceiling")
+	expectPolicies(t, check(t, Config{MaxInstanceSize: "8xlarge"}, instance("m5.4xlarge")))
+
+	if _, err := Check(Config{MaxInstanceSize: "huge"}, nil); err == nil {
+		t.Error("expected an error for an invalid ceiling")
+	}
+}
@@ -0,0 +1,53 @@
+// Package imagebuild tags the lab's container images by their sources, for
+// the registry stack's docker-build Image resources to push them to ECR.
+package imagebuild
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// ignoredDirs are not part of an image's sources: VCS metadata and the
+// local Maven build output the Dockerfiles rebuild anyway.
+var ignoredDirs = []string{".git", "target"}
+
+// ContentTag returns an image tag derived from the names and contents of the
+// files in the build context dir, so an image is only rebuilt and pushed
+// when its sources change.
+func ContentTag(dir string) (string, error) {
+	hash := sha256.New()
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && slices.Contains(ignoredDirs, d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		// WalkDir visits the files in lexical order, so the hash is stable
+		fmt.Fprintf(hash, "%s\x00", filepath.ToSlash(rel))
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(hash, f)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("hashing %s: %w", dir, err)
+	}
+	return "src-" + hex.EncodeToString(hash.Sum(nil))[:16], nil
+}
@@ -0,0 +1,50 @@
+package imagebuild
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestContentTag(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "Dockerfile"), "FROM scratch\n")
+	writeFile(t, filepath.Join(dir, "src", "Main.java"), "class Main {}\n")
+
+	tag, err := ContentTag(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tag) != len("src-")+16 {
+		t.Errorf("tag %q: want src- and 16 hex digits", tag)
+	}
+
+	// The build output does not change the sources
+	writeFile(t, filepath.Join(dir, "target", "workload-simulator.jar"), "jar")
+	if got, _ := ContentTag(dir); got != tag {
+		t.Errorf("after a local build: got %q, want %q", got, tag)
+	}
+
+	writeFile(t, filepath.Join(dir, "src", "Main.java"), "class Main { int x; }\n")
+	if got, _ := ContentTag(dir); got == tag {
+		t.Errorf("after a source change: got the unchanged tag %q", got)
+	}
+
+	// Moving content between files changes the tag too
+	moved := t.TempDir()
+	writeFile(t, filepath.Join(moved, "Dockerfile"), "FROM scratch\nclass Main {}\n")
+	writeFile(t, filepath.Join(moved, "src", "Main.java"), "")
+	if got, _ := ContentTag(moved); got == tag {
+		t.Errorf("after moving content: got the unchanged tag %q", got)
+	}
+}
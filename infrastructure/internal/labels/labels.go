@@ -0,0 +1,136 @@
+// Package labels provides the resource naming and tagging conventions shared
+// by all lab stacks.
+//
+// Every resource is named "{projectName}-{suffix}". The project-wide tags
+// (Project, Environment, Owner, RunId and any user-defined tags from the
+// stack's "tags" config object) are the default tags of the stack's AWS
+// providers, so every taggable resource carries them, including resources
+// added later; the resources themselves only set Name and their own tags:
+//
+//	pulumi config set owner "jane@example.com"
+//	pulumi config set runId "2024-11-19-upgrade"
+//	pulumi config set --path 'tags.CostCenter' "1234"
+package labels
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+)
+
+// DefaultProjectName is used when the projectName config value is not set.
+const DefaultProjectName = "aurora-bluegreen-lab"
+
+// tagValuePattern matches the values AWS accepts for tags on all services.
+var tagValuePattern = regexp.MustCompile(`^[\p{L}\p{Z}\p{N}_.:/=+\-@]{0,256}$`)
+
+// Tag is a resource-specific tag added on top of the standard tags.
+type Tag struct {
+	Key   string
+	Value string
+}
+
+// Role tags a resource with its role in the lab (e.g., "writer", "workload-simulator").
+func Role(role string) Tag {
+	return Tag{Key: "Role", Value: role}
+}
+
+// Type tags a resource with its type (e.g., "private-aurora", "public-ec2").
+func Type(typ string) Tag {
+	return Tag{Key: "Type", Value: typ}
+}
+
+// Labels builds resource names and tags for a stack.
+type Labels struct {
+	// ProjectName prefixes every resource name
+	ProjectName string
+	// Environment is the lab environment, by default the stack name (e.g. dev)
+	Environment string
+	// Owner and RunId attribute costs to a person and an experiment run; they
+	// are not tagged when empty
+	Owner string
+	RunId string
+	// ExtraTags are user-defined tags applied to every resource
+	ExtraTags map[string]string
+}
+
+// New loads the projectName, environment, owner, runId and tags values from
+// the stack configuration.
+func New(ctx *pulumi.Context, cfg *config.Config) (*Labels, error) {
+	projectName := cfg.Get("projectName")
+	if projectName == "" {
+		projectName = DefaultProjectName
+	}
+	environment := cfg.Get("environment")
+	if environment == "" {
+		environment = ctx.Stack()
+	}
+
+	extraTags := map[string]string{}
+	if err := cfg.GetObject("tags", &extraTags); err != nil {
+		return nil, fmt.Errorf("invalid tags config (expected a map of strings): %w", err)
+	}
+
+	l := &Labels{
+		ProjectName: projectName,
+		Environment: environment,
+		Owner:       cfg.Get("owner"),
+		RunId:       cfg.Get("runId"),
+		ExtraTags:   extraTags,
+	}
+	for key, value := range l.DefaultTags() {
+		if !tagValuePattern.MatchString(value) {
+			return nil, fmt.Errorf("invalid value %q of tag %s: tag values are at most 256 letters, digits, spaces and _.:/=+-@", value, key)
+		}
+	}
+	return l, nil
+}
+
+// Name returns the resource name "{projectName}-{suffix}".
+func (l *Labels) Name(suffix string) string {
+	return fmt.Sprintf("%s-%s", l.ProjectName, suffix)
+}
+
+// DefaultTags returns the project-wide tags the stack's AWS providers apply
+// to every resource: the user-defined tags plus Project, Environment, Owner
+// and RunId. Standard tags take precedence over user-defined ones.
+func (l *Labels) DefaultTags() map[string]string {
+	result := map[string]string{}
+	for key, value := range l.ExtraTags {
+		result[key] = value
+	}
+	result["Project"] = l.ProjectName
+	result["Environment"] = l.Environment
+	if l.Owner != "" {
+		result["Owner"] = l.Owner
+	}
+	if l.RunId != "" {
+		result["RunId"] = l.RunId
+	}
+	return result
+}
+
+// Tags returns the Name tag plus any resource-specific tags; the provider
+// adds the project-wide DefaultTags.
+func (l *Labels) Tags(name string, tags ...Tag) pulumi.StringMap {
+	result := pulumi.StringMap{"Name": pulumi.String(name)}
+	for _, tag := range tags {
+		result[tag.Key] = pulumi.String(tag.Value)
+	}
+	return result
+}
+
+// AllTags returns DefaultTags plus Tags, for the tags provider default tags
+// do not reach: launch template tag specifications and Auto Scaling Group
+// tags applied to the instances, volumes and network interfaces AWS launches.
+func (l *Labels) AllTags(name string, tags ...Tag) pulumi.StringMap {
+	result := l.Tags(name, tags...)
+	for key, value := range l.DefaultTags() {
+		if _, ok := result[key]; !ok {
+			result[key] = pulumi.String(value)
+		}
+	}
+	return result
+}
@@ -0,0 +1,150 @@
+// Package notify posts the lab's switchover lifecycle events and downtime
+// reports to a webhook, so a team running the lab together can follow a run
+// in a chat channel:
+//
+//   - Slack incoming webhooks (https://hooks.slack.com/...) get a Slack
+//     message with the event's text and fields
+//   - any other HTTPS endpoint gets the Message as JSON
+//
+// The tools take the webhook URL with -webhook or the LAB_WEBHOOK_URL
+// environment variable. The URL is a secret (a Slack webhook URL is all it
+// takes to post to the channel), so it is never printed, and errors name
+// the webhook's host only.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// EnvVar is the environment variable the tools read the webhook URL from.
+const EnvVar = "LAB_WEBHOOK_URL"
+
+// Timeout bounds each post, so an unreachable webhook cannot hold up a run.
+const Timeout = 10 * time.Second
+
+// Field is a named value of a message, e.g. a downtime report row.
+type Field struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Message is an event posted to the webhook.
+type Message struct {
+	Time time.Time `json:"time"`
+	// Source is the tool posting the event, e.g. bgctl/switchover
+	Source string `json:"source"`
+	// Event is the timeline event name, e.g. switchover-started
+	Event string `json:"event"`
+	// Text is the one-line summary shown in the channel
+	Text   string  `json:"text"`
+	RunID  string  `json:"runId,omitempty"`
+	Fields []Field `json:"fields,omitempty"`
+}
+
+// Notifier posts messages to a webhook. A nil Notifier posts nothing.
+type Notifier struct {
+	url   string
+	host  string
+	slack bool
+	// Client is the HTTP client posting the messages
+	Client *http.Client
+}
+
+// New returns a notifier posting to the webhook URL, or nil when the URL is
+// empty. The URL must be HTTPS; plain HTTP is only accepted on the loopback
+// interface, e.g. for a local test receiver.
+func New(webhook string) (*Notifier, error) {
+	if webhook == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(webhook)
+	if err != nil || u.Host == "" {
+		// The parse error quotes the URL
+		return nil, errors.New("the webhook URL is not a valid URL")
+	}
+	if u.Scheme != "https" && !(u.Scheme == "http" && loopback(u.Hostname())) {
+		return nil, fmt.Errorf("the webhook URL must be https, got %s://%s", u.Scheme, u.Host)
+	}
+	return &Notifier{
+		url:    webhook,
+		host:   u.Host,
+		slack:  u.Hostname() == "hooks.slack.com",
+		Client: &http.Client{Timeout: Timeout},
+	}, nil
+}
+
+func loopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// Send posts the message, stamped with the current time when it has none.
+func (n *Notifier) Send(ctx context.Context, m Message) error {
+	if n == nil {
+		return nil
+	}
+	if m.Time.IsZero() {
+		m.Time = time.Now().UTC()
+	}
+	var payload any = m
+	if n.slack {
+		payload = struct {
+			Text string `json:"text"`
+		}{slackText(m)}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("posting %s to %s: invalid request", m.Event, n.host)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		// url.Error quotes the URL
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			err = ue.Err
+		}
+		return fmt.Errorf("posting %s to %s: %w", m.Event, n.host, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("posting %s to %s: %s %s", m.Event, n.host, resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// slackEscaper escapes the characters Slack's mrkdwn reserves for links and
+// mentions.
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// slackText formats the message as Slack mrkdwn: the source and text, the
+// run ID and one line per field.
+func slackText(m Message) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%s* %s", slackEscaper.Replace(m.Source), slackEscaper.Replace(m.Text))
+	if m.RunID != "" {
+		fmt.Fprintf(&b, " (run `%s`)", m.RunID)
+	}
+	for _, f := range m.Fields {
+		fmt.Fprintf(&b, "\n• %s: %s", slackEscaper.Replace(f.Name), slackEscaper.Replace(f.Value))
+	}
+	return b.String()
+}
@@ -0,0 +1,99 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	for _, tc := range []struct {
+		url   string
+		ok    bool
+		slack bool
+	}{
+		{"", true, false},
+		{"https://hooks.slack.com/services/T000/B000/XXXX", true, true},
+		{"https://example.com/lab-events", true, false},
+		{"http://127.0.0.1:8080/events", true, false},
+		{"http://localhost/events", true, false},
+		{"http://example.com/events", false, false},
+		{"hooks.slack.com/services/T000", false, false},
+	} {
+		n, err := New(tc.url)
+		if (err == nil) != tc.ok {
+			t.Errorf("%q: error %v", tc.url, err)
+			continue
+		}
+		if err != nil {
+			if strings.Contains(err.Error(), "T000") || strings.Contains(err.Error(), "/events") {
+				t.Errorf("%q: error quotes the URL: %v", tc.url, err)
+			}
+			continue
+		}
+		if (n == nil) != (tc.url == "") {
+			t.Errorf("%q: notifier %v", tc.url, n)
+		}
+		if n != nil && n.slack != tc.slack {
+			t.Errorf("%q: slack %v", tc.url, n.slack)
+		}
+	}
+}
+
+func TestSend(t *testing.T) {
+	var got []byte
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+		io.WriteString(w, "no_text")
+	}))
+	defer server.Close()
+	n, err := New(server.URL + "/hook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := Message{
+		Time:   time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC),
+		Source: "bgctl/switchover",
+		Event:  "switchover-completed",
+		Text:   "Switched over to lab-green-abc <1s>",
+		RunID:  "minor-upgrade-1",
+		Fields: []Field{{"Switchover", "28236 ms"}},
+	}
+
+	if err := n.Send(context.Background(), m); err != nil {
+		t.Fatal(err)
+	}
+	var posted Message
+	if err := json.Unmarshal(got, &posted); err != nil || posted.Event != m.Event || posted.RunID != m.RunID || len(posted.Fields) != 1 {
+		t.Errorf("posted %s (%v)", got, err)
+	}
+
+	n.slack = true
+	if err := n.Send(context.Background(), m); err != nil {
+		t.Fatal(err)
+	}
+	var slack struct{ Text string }
+	json.Unmarshal(got, &slack)
+	want := "*bgctl/switchover* Switched over to lab-green-abc &lt;1s&gt; (run `minor-upgrade-1`)\n• Switchover: 28236 ms"
+	if slack.Text != want {
+		t.Errorf("Slack text %q, want %q", slack.Text, want)
+	}
+
+	status = http.StatusBadRequest
+	err = n.Send(context.Background(), m)
+	if err == nil || !strings.Contains(err.Error(), "400 Bad Request no_text") || strings.Contains(err.Error(), "/hook") {
+		t.Errorf("rejected post: %v", err)
+	}
+
+	var none *Notifier
+	if err := none.Send(context.Background(), m); err != nil {
+		t.Errorf("nil notifier: %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+// Package providers creates the explicit AWS provider used by every lab stack,
+// so each stack can be deployed into its own region (e.g., a DR copy of the
+// lab) without changing environment variables:
+//
+//	pulumi config set region us-west-2
+//
+// The providers tag every resource with the stack's project-wide tags (see
+// labels.Labels.DefaultTags).
+package providers
+
+import (
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+
+	"aurora-bluegreen-lab/internal/labels"
+)
+
+// New creates the stack's aws.Provider for the "region" config value and
+// returns it together with the resolved region name. When region is unset the
+// provider falls back to aws:region and then to the AWS SDK default resolution
+// (AWS_REGION, AWS_DEFAULT_REGION, profile).
+func New(ctx *pulumi.Context, cfg *config.Config, lb *labels.Labels) (*aws.Provider, string, error) {
+	region := cfg.Get("region")
+	if region == "" {
+		region = config.Get(ctx, "aws:region")
+	}
+
+	var regionInput pulumi.StringPtrInput
+	if region != "" {
+		regionInput = pulumi.String(region)
+	}
+
+	provider, err := aws.NewProvider(ctx, lb.Name("aws"), &aws.ProviderArgs{
+		Region:      regionInput,
+		DefaultTags: DefaultTags(lb),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Resolve the effective region (covers the environment/profile fallback)
+	current, err := aws.GetRegion(ctx, &aws.GetRegionArgs{}, pulumi.Provider(provider))
+	if err != nil {
+		return nil, "", err
+	}
+	return provider, current.Name, nil
+}
+
+// DefaultTags returns the default tags of a provider of the stack, for the
+// additional providers a component creates in other regions.
+func DefaultTags(lb *labels.Labels) *aws.ProviderDefaultTagsArgs {
+	return &aws.ProviderDefaultTagsArgs{
+		Tags: pulumi.ToStringMap(lb.DefaultTags()),
+	}
+}
@@ -0,0 +1,135 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ControlPort is the --control-port the lab's tools start the simulator with.
+// The control API is opt-in, off unless a port is passed, so lab-scenario
+// runs and the ec2 stack's default simulatorOptions pass it explicitly.
+const ControlPort = 8081
+
+// Control calls the HTTP control API of the simulator running on the host.
+// The API listens on the host's loopback interface only, so the requests are
+// sent with curl on the host.
+type Control struct {
+	host Host
+	port int
+}
+
+// NewControl returns the control API on port of the simulator on host.
+func NewControl(host Host, port int) *Control {
+	return &Control{host: host, port: port}
+}
+
+// SimulatorStats is the simulator's answer to GET /stats.
+type SimulatorStats struct {
+	Timestamp string `json:"timestamp"`
+	RunID     string `json:"runId"`
+	// State is running, paused or stopping
+	State    string `json:"state"`
+	Requests struct {
+		Total       int64   `json:"total"`
+		Success     int64   `json:"success"`
+		Failed      int64   `json:"failed"`
+		SuccessRate float64 `json:"successRate"`
+	} `json:"requests"`
+	Rate struct {
+		Target   float64 `json:"target"`
+		Achieved float64 `json:"achieved"`
+		// WriteRate is set per worker, TotalRate with --total-rate
+		WriteRate int `json:"writeRate"`
+		TotalRate int `json:"totalRate"`
+	} `json:"rate"`
+	Profile      string `json:"profile"`
+	Backpressure *struct {
+		InFlight int   `json:"inFlight"`
+		Queued   int   `json:"queued"`
+		Shed     int64 `json:"shed"`
+	} `json:"backpressure"`
+}
+
+// requestScript sends the request and prints the response body; a response
+// other than 200 prints the body to stderr and fails.
+func (c *Control) requestScript(method, path string, query url.Values) string {
+	u := fmt.Sprintf("http://127.0.0.1:%d%s", c.port, path)
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	return fmt.Sprintf(`set -uo pipefail
+if ! OUT=$(curl -sS --max-time 10 -X %s -w '\n%%{http_code}' %s); then
+  echo "the simulator's control API does not answer on port %d (is the simulator running, with --control-port %d? The API is off without it)" >&2
+  exit 1
+fi
+CODE=$(echo "$OUT" | tail -n 1)
+BODY=$(echo "$OUT" | sed '$d')
+if [ "$CODE" != "200" ]; then
+  echo "$BODY" >&2
+  exit 1
+fi
+echo "$BODY"
+`, method, Quote(u), c.port, c.port)
+}
+
+func (c *Control) call(ctx context.Context, method, path string, query url.Values) (string, error) {
+	out, err := c.host.Run(ctx, c.requestScript(method, path, query))
+	if err != nil {
+		return "", fmt.Errorf("simulator control API %s %s: %w", method, path, err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// Stats returns the simulator's current counters, rates and state.
+func (c *Control) Stats(ctx context.Context) (*SimulatorStats, error) {
+	out, err := c.call(ctx, "GET", "/stats", nil)
+	if err != nil {
+		return nil, err
+	}
+	var stats SimulatorStats
+	if err := json.Unmarshal([]byte(out), &stats); err != nil {
+		return nil, fmt.Errorf("parsing the simulator stats: %w", err)
+	}
+	return &stats, nil
+}
+
+// Pause stops the workers from sending operations; the run goes on.
+func (c *Control) Pause(ctx context.Context) error {
+	_, err := c.call(ctx, "POST", "/pause", nil)
+	return err
+}
+
+// Resume lets paused workers send operations again.
+func (c *Control) Resume(ctx context.Context) error {
+	_, err := c.call(ctx, "POST", "/resume", nil)
+	return err
+}
+
+// Stop shuts the simulator down as SIGTERM does.
+func (c *Control) Stop(ctx context.Context) error {
+	_, err := c.call(ctx, "POST", "/stop", nil)
+	return err
+}
+
+// SetRate changes the write rate: writes per second per worker, or of all
+// workers together when the simulator runs with --total-rate.
+func (c *Control) SetRate(ctx context.Context, rate int) error {
+	_, err := c.call(ctx, "POST", "/rate", url.Values{"rate": {strconv.Itoa(rate)}})
+	return err
+}
+
+// MarkEvent marks an event of the experiment that happened at at; the
+// simulator logs it with its counters at that moment.
+func (c *Control) MarkEvent(ctx context.Context, name, detail string, at time.Time) error {
+	_, err := c.call(ctx, "POST", "/events", url.Values{
+		"name":   {name},
+		"detail": {detail},
+		"time":   {at.UTC().Format(time.RFC3339Nano)},
+	})
+	return err
+}
@@ -0,0 +1,125 @@
+package remote
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MySQL runs SQL on an Aurora endpoint from the simulator host, with its
+// mysql client and the database credentials the ec2 stack stores for the
+// simulator service.
+type MySQL struct {
+	host     Host
+	endpoint string
+	database string
+}
+
+// NewMySQL returns the database on endpoint, reached from host.
+func NewMySQL(host Host, endpoint, database string) *MySQL {
+	return &MySQL{host: host, endpoint: endpoint, database: database}
+}
+
+// credentialsScript exports the simulator's database user and password as
+// DB_USERNAME and MYSQL_PWD, which the mysql client reads.
+const credentialsScript = `if [ ! -f /etc/workload-simulator/simulator.env ] || ! grep -q '^CREDENTIALS_SECRET=.' /etc/workload-simulator/simulator.env; then
+  echo "the database credentials are not set up on this host (deploy the ec2 stack with auroraStackName and dbPassword)" >&2
+  exit 1
+fi
+export $(grep -E '^(AWS_REGION|CREDENTIALS_SECRET)=' /etc/workload-simulator/simulator.env | xargs)
+CREDENTIALS=$(aws secretsmanager get-secret-value --region "$AWS_REGION" \
+  --secret-id "$CREDENTIALS_SECRET" --query SecretString --output text)
+DB_USERNAME=$(echo "$CREDENTIALS" | jq -r .username)
+export MYSQL_PWD=$(echo "$CREDENTIALS" | jq -r .password)
+`
+
+// applyScript runs the statements with the client's echo of each one and its
+// warnings, after checking that the endpoint accepts writes.
+func (m *MySQL) applyScript(statements []string) string {
+	sql := strings.Join(statements, ";\n") + ";\n"
+	return fmt.Sprintf(`set -euo pipefail
+%s
+MYSQL=(mysql -h %s -u "$DB_USERNAME" --connect-timeout=10 %s)
+READ_ONLY=$("${MYSQL[@]}" -N -B -e 'SELECT @@global.read_only')
+if [ "$READ_ONLY" != "0" ]; then
+  echo "%s is read-only (read_only=$READ_ONLY); set read_only to 0 in its DB cluster parameter group" >&2
+  exit 1
+fi
+echo %s | base64 -d | "${MYSQL[@]}" --verbose --show-warnings
+`, credentialsScript, Quote(m.endpoint), Quote(m.database), m.endpoint,
+		base64.StdEncoding.EncodeToString([]byte(sql)))
+}
+
+// Apply runs the statements in order and streams the client's output to w.
+// Nothing runs when the endpoint is read-only; the client stops at the first
+// failing statement.
+func (m *MySQL) Apply(ctx context.Context, statements []string, w io.Writer) error {
+	if err := m.host.Stream(ctx, m.applyScript(statements), w); err != nil {
+		return fmt.Errorf("running the statements on %s: %w", m.endpoint, err)
+	}
+	return nil
+}
+
+// queryScript prints the rows of the query, one per line with tab-separated
+// columns.
+func (m *MySQL) queryScript(sql string) string {
+	return fmt.Sprintf(`set -euo pipefail
+%s
+echo %s | base64 -d | mysql -h %s -u "$DB_USERNAME" --connect-timeout=10 -N -B %s
+`, credentialsScript, base64.StdEncoding.EncodeToString([]byte(sql)), Quote(m.endpoint), Quote(m.database))
+}
+
+// Query runs a query and returns its rows as columns.
+func (m *MySQL) Query(ctx context.Context, sql string) ([][]string, error) {
+	out, err := m.host.Run(ctx, m.queryScript(sql))
+	if err != nil {
+		return nil, fmt.Errorf("querying %s: %w", m.endpoint, err)
+	}
+	var rows [][]string
+	for _, line := range strings.Split(strings.TrimRight(out, "\n"), "\n") {
+		if line != "" {
+			rows = append(rows, strings.Split(line, "\t"))
+		}
+	}
+	return rows, nil
+}
+
+// latencyMarker prefixes the latency rows among the rows of the timed query.
+const latencyMarker = "__latency_us"
+
+// Latency runs a query runs times in one session and returns the time each
+// run took on the server, without connecting and transferring the rows.
+func (m *MySQL) Latency(ctx context.Context, sql string, runs int) ([]time.Duration, error) {
+	var timed strings.Builder
+	for i := 0; i < runs; i++ {
+		fmt.Fprintf(&timed, "SET @started := SYSDATE(6);\n%s;\nSELECT '%s', TIMESTAMPDIFF(MICROSECOND, @started, SYSDATE(6));\n", sql, latencyMarker)
+	}
+	rows, err := m.Query(ctx, timed.String())
+	if err != nil {
+		return nil, err
+	}
+	return parseLatencies(rows)
+}
+
+// parseLatencies returns the latencies of the marked rows.
+func parseLatencies(rows [][]string) ([]time.Duration, error) {
+	var latencies []time.Duration
+	for _, row := range rows {
+		if len(row) != 2 || row[0] != latencyMarker {
+			continue
+		}
+		us, err := strconv.ParseInt(row[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid latency %q", row[1])
+		}
+		latencies = append(latencies, time.Duration(us)*time.Microsecond)
+	}
+	if len(latencies) == 0 {
+		return nil, fmt.Errorf("the query returned no latencies")
+	}
+	return latencies, nil
+}
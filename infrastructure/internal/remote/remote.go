@@ -0,0 +1,48 @@
+// Package remote controls the workload simulator on the simulator host
+// without an interactive shell. Commands run through SSM Run Command (no open
+// SSH port or key pair needed) or, as a fallback, SSH:
+//
+//	host := remote.NewSSM(cfg, instanceID)
+//	remote.NewService(host).Logs(ctx, 100, true, os.Stdout)
+//
+// Service manages the workload-simulator systemd service installed by the ec2
+// stack; Run starts a separate simulator run with its own options and output
+// directory, as used by lab-scenario; Control calls the HTTP control API of
+// the running simulator; MySQL runs SQL on the lab's endpoints from the host,
+// and Replica controls the binary log replication of the external replica.
+package remote
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+)
+
+// Host runs commands on the simulator host.
+type Host interface {
+	// Run executes a bash script on the host and returns its standard output.
+	Run(ctx context.Context, script string) (string, error)
+	// Stream executes a bash script on the host and copies its standard
+	// output and error to w while it runs. Cancelling ctx stops the script.
+	Stream(ctx context.Context, script string, w io.Writer) error
+	// Fetch copies a file from the host to localPath.
+	Fetch(ctx context.Context, remotePath, localPath string) error
+}
+
+// Quote quotes value as a single bash word.
+func Quote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+func writeFile(path string, r io.Reader) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
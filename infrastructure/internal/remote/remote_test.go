@@ -0,0 +1,186 @@
+package remote
+
+import (
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSimulatorRunScripts(t *testing.T) {
+	r := NewSimulatorRun(nil, "minor-upgrade-20250118-101500")
+	if got, want := r.Options("--write-workers 20"),
+		"--run-id minor-upgrade-20250118-101500 --output-format json --output-file /opt/workload-simulator/runs/minor-upgrade-20250118-101500/stats.jsonl --control-port 8081 --write-workers 20"; got != want {
+		t.Errorf("options: got %q, want %q", got, want)
+	}
+	if got := Quote("it's"); got != `'it'\''s'` {
+		t.Errorf("Quote: got %s", got)
+	}
+
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not available")
+	}
+	for name, script := range map[string]string{
+		"run start":       r.startScript("--workload transactional --transaction-size 5"),
+		"run check":       r.checkScript(),
+		"run stop":        r.stopScript(),
+		"service status":  serviceStatusScript,
+		"mysql apply":     NewMySQL(nil, "lab-green-abc123.cluster-xyz.us-east-1.rds.amazonaws.com", "labdb").applyScript([]string{"ALTER TABLE orders ADD COLUMN note varchar(64)"}),
+		"mysql query":     NewMySQL(nil, "lab.cluster-xyz.us-east-1.rds.amazonaws.com", "labdb").queryScript("SELECT @@global.read_only"),
+		"replica setup":   testReplica().setupScript(),
+		"replica repoint": testReplica().repointScript("mysql-bin-changelog.000003", 804),
+		"replica status":  testReplica().statusScript(),
+		"control request": NewControl(nil, ControlPort).requestScript("POST", "/rate", url.Values{"rate": {"50"}}),
+	} {
+		cmd := exec.Command(bash, "-n")
+		cmd.Stdin = strings.NewReader(script)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Errorf("%s script: %v\n%s", name, err, out)
+		}
+	}
+}
+
+func testReplica() *Replica {
+	return NewReplica(nil, "lab-external-replica.xyz.us-east-1.rds.amazonaws.com", "lab.cluster-xyz.us-east-1.rds.amazonaws.com", "labdb")
+}
+
+func TestReplicaPassword(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not available")
+	}
+	// The password is escaped for the SQL string of rds_set_external_master
+	functions := strings.Replace(testReplica().replicaFunctions(), credentialsScript, "", 1)
+	cmd := exec.Command(bash)
+	cmd.Stdin = strings.NewReader(functions + `echo "$PASSWORD"`)
+	cmd.Env = append(cmd.Environ(), "DB_USERNAME=admin", `MYSQL_PWD=it's\secret`)
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.TrimSpace(string(out)), `it''s\\secret`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestParseVerticalRow(t *testing.T) {
+	out := `*************************** 1. row ***************************
+               Slave_IO_State: Waiting for source to send event
+                  Master_Host: lab.cluster-xyz.us-east-1.rds.amazonaws.com
+              Master_Log_File: mysql-bin-changelog.000002
+             Slave_IO_Running: No
+                Last_IO_Error: Got fatal error 1236 from source when reading data from binary log: 'Client requested source to start replication from position > file size'
+        Seconds_Behind_Master: NULL
+`
+	row := parseVerticalRow(out)
+	if len(row) != 6 || row["Slave_IO_Running"] != "No" || row["Master_Log_File"] != "mysql-bin-changelog.000002" {
+		t.Errorf("got %v", row)
+	}
+	if !strings.HasPrefix(row["Last_IO_Error"], "Got fatal error 1236 from source") {
+		t.Errorf("Last_IO_Error: got %q", row["Last_IO_Error"])
+	}
+	if row := parseVerticalRow(""); row != nil {
+		t.Errorf("got %v without rows", row)
+	}
+}
+
+func TestStreamScripts(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not available")
+	}
+	run := func(script string) string {
+		t.Helper()
+		cmd := exec.Command(bash)
+		cmd.Stdin = strings.NewReader(script)
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("%v\n%s", err, script)
+		}
+		return string(out)
+	}
+
+	// Output larger than a chunk, then a failure
+	dir := strings.TrimSpace(run(streamStartScript("head -c 20000 /dev/zero | tr '\\0' x\necho done\nexit 3")))
+	defer exec.Command("rm", "-rf", dir).Run()
+
+	var output strings.Builder
+	offset := 1
+	for i := 0; ; i++ {
+		if i == 100 {
+			t.Fatal("the streamed script did not finish")
+		}
+		chunk, status, done, err := parseStreamPoll(run(streamPollScript(Quote(dir), offset)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		output.Write(chunk)
+		offset += len(chunk)
+		if done {
+			if status != 3 {
+				t.Errorf("status: got %d, want 3", status)
+			}
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if want := strings.Repeat("x", 20000) + "done\n"; output.String() != want {
+		t.Errorf("output: got %d bytes, want %d", output.Len(), len(want))
+	}
+}
+
+func TestParseLatencies(t *testing.T) {
+	latencies, err := parseLatencies([][]string{
+		{"1", "order-1"},
+		{latencyMarker, "1500"},
+		{"2", "order-2"},
+		{latencyMarker, "250"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []time.Duration{1500 * time.Microsecond, 250 * time.Microsecond}; !reflect.DeepEqual(latencies, want) {
+		t.Errorf("got %v, want %v", latencies, want)
+	}
+	if _, err := parseLatencies([][]string{{"1", "order-1"}}); err == nil {
+		t.Error("no error without latencies")
+	}
+}
+
+func TestControlRequestScript(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not available")
+	}
+	// A fake curl that echoes its URL as the body, with the status code of
+	// STATUS as curl's -w output
+	bin := t.TempDir()
+	fake := "#!/bin/sh\nfor a; do u=$a; done\nprintf '%s\\n%s' \"$u\" \"$STATUS\"\n"
+	if err := os.WriteFile(filepath.Join(bin, "curl"), []byte(fake), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	script := NewControl(nil, 8082).requestScript("POST", "/events", url.Values{"name": {"switchover-started"}, "detail": {"bgd-1 it's"}})
+	run := func(status string) (string, error) {
+		cmd := exec.Command(bash)
+		cmd.Stdin = strings.NewReader(script)
+		cmd.Env = append(cmd.Environ(), "PATH="+bin+":"+os.Getenv("PATH"), "STATUS="+status)
+		out, err := cmd.Output()
+		return strings.TrimSpace(string(out)), err
+	}
+
+	out, err := run("200")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "http://127.0.0.1:8082/events?detail=bgd-1+it%27s&name=switchover-started"; out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+	if _, err := run("400"); err == nil {
+		t.Error("no error for status 400")
+	}
+}
@@ -0,0 +1,137 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Replica controls the binary log replication of the aurora stack's external
+// replica, an RDS for MySQL instance, from the cluster endpoint. It runs on
+// the simulator host with the simulator's database credentials, the master
+// credentials the cluster and the replica share.
+type Replica struct {
+	host     Host
+	endpoint string
+	source   string
+	database string
+}
+
+// NewReplica returns the replica on endpoint, replicating database from the
+// source endpoint, reached from host.
+func NewReplica(host Host, endpoint, source, database string) *Replica {
+	return &Replica{host: host, endpoint: endpoint, source: source, database: database}
+}
+
+// replicaFunctions defines the mysql clients of the source and the replica,
+// and point, which points the replica at a binary log file and position of
+// the source and starts replication. The password is escaped on the host, so
+// it never leaves it.
+func (r *Replica) replicaFunctions() string {
+	return fmt.Sprintf(`set -euo pipefail
+%s
+SOURCE=(mysql -h %s -u "$DB_USERNAME" --connect-timeout=10)
+REPLICA=(mysql -h %s -u "$DB_USERNAME" --connect-timeout=10)
+SQ="'"
+PASSWORD=${MYSQL_PWD//\\/\\\\}
+PASSWORD=${PASSWORD//$SQ/$SQ$SQ}
+point() {
+  echo "Replicating from %s at $1:$2"
+  "${REPLICA[@]}" -e "CALL mysql.rds_set_external_master('%s', 3306, '$DB_USERNAME', '$PASSWORD', '$1', $2, 0)"
+  "${REPLICA[@]}" --table -e 'CALL mysql.rds_start_replication'
+}
+stop_replication() {
+  # Both fail harmlessly when replication is not set up
+  "${REPLICA[@]}" -e 'CALL mysql.rds_stop_replication' >/dev/null 2>&1 || true
+  "${REPLICA[@]}" -e 'CALL mysql.rds_reset_external_master' >/dev/null 2>&1 || true
+}
+`, credentialsScript, Quote(r.source), Quote(r.endpoint), r.source, r.source)
+}
+
+// setupScript copies the database's schema to the replica, without rows, and
+// starts replication at the source's current binary log position.
+func (r *Replica) setupScript() string {
+	return fmt.Sprintf(`%s
+stop_replication
+echo "Copying the schema of %s from %s"
+# GTIDs and binary logging settings of the dump cannot be set on RDS
+mysqldump -h %s -u "$DB_USERNAME" --no-data --skip-lock-tables --databases %s |
+  sed -e '/GTID_PURGED/d' -e '/SQL_LOG_BIN/d' | "${REPLICA[@]}"
+COORDINATES=$("${SOURCE[@]}" -N -B -e 'SHOW MASTER STATUS' | cut -f1,2)
+if [ -z "$COORDINATES" ]; then
+  echo "%s has no binary log; set binlogFormat in the aurora stack and reboot the writer" >&2
+  exit 1
+fi
+point $COORDINATES
+`, r.replicaFunctions(), r.database, r.source, Quote(r.source), Quote(r.database), r.source)
+}
+
+// repointScript restarts replication at a binary log file and position.
+func (r *Replica) repointScript(file string, position int64) string {
+	return fmt.Sprintf(`%s
+stop_replication
+point %s %d
+`, r.replicaFunctions(), Quote(file), position)
+}
+
+// statusScript prints the replication status, one column per line.
+func (r *Replica) statusScript() string {
+	return fmt.Sprintf(`set -euo pipefail
+%s
+mysql -h %s -u "$DB_USERNAME" --connect-timeout=10 -e 'SHOW SLAVE STATUS\G'
+`, credentialsScript, Quote(r.endpoint))
+}
+
+// Setup copies the schema of the database to the replica and starts
+// replication from the source's current binary log position, streaming the
+// output to w. Rows written before are not copied; the replica skips the
+// changes to rows it does not have (slave_exec_mode IDEMPOTENT). Running it
+// again starts over, dropping the replica's tables.
+func (r *Replica) Setup(ctx context.Context, w io.Writer) error {
+	if err := r.host.Stream(ctx, r.setupScript(), w); err != nil {
+		return fmt.Errorf("setting up replication from %s to %s: %w", r.source, r.endpoint, err)
+	}
+	return nil
+}
+
+// Repoint stops replication and starts it again from the source's binary
+// log file and position, such as the coordinates RDS reports for the green
+// environment after a switchover.
+func (r *Replica) Repoint(ctx context.Context, file string, position int64, w io.Writer) error {
+	if err := r.host.Stream(ctx, r.repointScript(file, position), w); err != nil {
+		return fmt.Errorf("repointing %s to %s:%d: %w", r.endpoint, file, position, err)
+	}
+	return nil
+}
+
+// Status returns the replica's SHOW SLAVE STATUS by column, e.g.
+// Slave_IO_Running or Last_IO_Error; nil when replication is not set up.
+func (r *Replica) Status(ctx context.Context) (map[string]string, error) {
+	out, err := r.host.Run(ctx, r.statusScript())
+	if err != nil {
+		return nil, fmt.Errorf("reading the replication status of %s: %w", r.endpoint, err)
+	}
+	return parseVerticalRow(out), nil
+}
+
+// parseVerticalRow parses the first row of the mysql client's vertical
+// output (\G), "Column: value" lines after a row header.
+func parseVerticalRow(out string) map[string]string {
+	var row map[string]string
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, "***") {
+			if row != nil {
+				break
+			}
+			row = map[string]string{}
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if row == nil || !ok {
+			continue
+		}
+		row[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return row
+}
@@ -0,0 +1,218 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// ServiceName is the systemd unit the ec2 stack installs for the simulator
+	ServiceName = "workload-simulator.service"
+	// RunsDir holds the output of every separate simulator run on the host
+	RunsDir = "/opt/workload-simulator/runs"
+)
+
+// Service controls the workload-simulator systemd service. The service and
+// its path unit, which starts it once the jar is present, are started and
+// stopped together.
+type Service struct {
+	host Host
+}
+
+// NewService returns the simulator service on host.
+func NewService(host Host) *Service {
+	return &Service{host: host}
+}
+
+// Start starts the service and streams its status to w.
+func (s *Service) Start(ctx context.Context, w io.Writer) error {
+	return s.systemctl(ctx, "start", w)
+}
+
+// Stop stops the service and streams its status to w.
+func (s *Service) Stop(ctx context.Context, w io.Writer) error {
+	return s.systemctl(ctx, "stop", w)
+}
+
+// Restart restarts the service, e.g. after editing simulator.env, and
+// streams its status to w.
+func (s *Service) Restart(ctx context.Context, w io.Writer) error {
+	return s.systemctl(ctx, "restart", w)
+}
+
+// Status streams the service status and its latest log lines to w.
+func (s *Service) Status(ctx context.Context, w io.Writer) error {
+	return s.host.Stream(ctx, serviceStatusScript, w)
+}
+
+// Logs streams the last lines of the service log to w; with follow it keeps
+// streaming new lines until ctx is cancelled.
+func (s *Service) Logs(ctx context.Context, lines int, follow bool, w io.Writer) error {
+	script := fmt.Sprintf("sudo journalctl --no-pager -u %s -n %d", ServiceName, lines)
+	if follow {
+		script += " -f"
+	}
+	return s.host.Stream(ctx, script, w)
+}
+
+// serviceStatusScript never fails: systemctl status exits non-zero for a
+// stopped service, which is a valid answer.
+const serviceStatusScript = `if [ ! -f /etc/systemd/system/` + ServiceName + ` ]; then
+  echo "the workload-simulator service is not set up on this host (deploy the ec2 stack with auroraStackName and dbPassword)" >&2
+  exit 1
+fi
+sudo systemctl status --no-pager -n 20 ` + ServiceName + ` || true
+`
+
+func (s *Service) systemctl(ctx context.Context, action string, w io.Writer) error {
+	script := fmt.Sprintf("set -e\nsudo systemctl %s workload-simulator.path %s\n", action, ServiceName)
+	if action == "restart" {
+		// Restarting the path unit does not restart a running service
+		script = fmt.Sprintf("set -e\nsudo systemctl start workload-simulator.path\nsudo systemctl restart %s\n", ServiceName)
+	}
+	return s.host.Stream(ctx, script+serviceStatusScript, w)
+}
+
+// SimulatorRun is a simulator run with its own options and output directory
+// (RunsDir/<run ID>). It reuses the service setup (start-simulator.sh
+// resolves the endpoint and credentials from SSM and Secrets Manager), but
+// runs as its own process; the service is stopped for the run and started
+// again afterwards if it was active.
+type SimulatorRun struct {
+	host  Host
+	runID string
+}
+
+// NewSimulatorRun returns the run with the given ID on host.
+func NewSimulatorRun(host Host, runID string) *SimulatorRun {
+	return &SimulatorRun{host: host, runID: runID}
+}
+
+// Dir returns the run's directory on the host.
+func (r *SimulatorRun) Dir() string {
+	return RunsDir + "/" + r.runID
+}
+
+// Options returns SIMULATOR_OPTS for the run: machine-readable stats tagged
+// with the run ID and the control API on ControlPort, followed by the
+// workload options.
+func (r *SimulatorRun) Options(workload string) string {
+	return strings.TrimSpace(fmt.Sprintf("--run-id %s --output-format json --output-file %s/stats.jsonl --control-port %d %s",
+		r.runID, r.Dir(), ControlPort, workload))
+}
+
+// startScript stops the simulator service, starts the run in the background
+// and prints its PID once it is still running a few seconds later.
+func (r *SimulatorRun) startScript(workload string) string {
+	return fmt.Sprintf(`set -euo pipefail
+RUN_DIR=%s
+# The service runs the jar, or the container image named in simulator.env
+if [ ! -x /opt/workload-simulator/start-simulator.sh ] || { [ ! -f /opt/workload-simulator/workload-simulator.jar ] &&
+  ! grep -q '^SIMULATOR_IMAGE=.' /etc/workload-simulator/simulator.env; }; then
+  echo "the workload-simulator service is not set up on this host (deploy the ec2 stack with auroraStackName and dbPassword, and upload the jar or set registryStackName)" >&2
+  exit 1
+fi
+
+# Only this run may write to the cluster
+SERVICE_ACTIVE=0
+if systemctl is-active --quiet workload-simulator.service; then SERVICE_ACTIVE=1; fi
+sudo systemctl stop workload-simulator.path workload-simulator.service
+
+sudo -u ec2-user mkdir -p "$RUN_DIR"
+echo "$SERVICE_ACTIVE" | sudo -u ec2-user tee "$RUN_DIR/service-active" > /dev/null
+sudo -u ec2-user env $(grep -E '^(AWS_REGION|ENDPOINT_PARAMETER|CREDENTIALS_SECRET|SIMULATOR_IMAGE)=' /etc/workload-simulator/simulator.env | xargs) \
+  SIMULATOR_OPTS=%s \
+  bash -c 'cd "$1" || exit 1; setsid nohup /opt/workload-simulator/start-simulator.sh > simulator.log 2>&1 < /dev/null & echo $! > "$1/simulator.pid"' _ "$RUN_DIR"
+
+sleep 5
+PID=$(cat "$RUN_DIR/simulator.pid")
+if ! kill -0 "$PID" 2>/dev/null; then
+  echo "the simulator exited right after starting:" >&2
+  tail -n 20 "$RUN_DIR/simulator.log" >&2
+  exit 1
+fi
+echo "$PID"
+`, Quote(r.Dir()), Quote(r.Options(workload)))
+}
+
+// checkScript fails when the simulator is no longer running.
+func (r *SimulatorRun) checkScript() string {
+	return fmt.Sprintf(`set -uo pipefail
+RUN_DIR=%s
+if ! kill -0 "$(cat "$RUN_DIR/simulator.pid")" 2>/dev/null; then
+  echo "the simulator is not running:" >&2
+  tail -n 20 "$RUN_DIR/simulator.log" >&2
+  exit 1
+fi
+`, Quote(r.Dir()))
+}
+
+// stopScript stops the simulator with SIGTERM, so it writes its final report,
+// keeps the last lines of its log for fetching, and restores the service.
+func (r *SimulatorRun) stopScript() string {
+	return fmt.Sprintf(`set -uo pipefail
+RUN_DIR=%s
+PID=$(cat "$RUN_DIR/simulator.pid" 2>/dev/null || true)
+if [ -n "$PID" ] && kill -0 "$PID" 2>/dev/null; then
+  sudo -u ec2-user kill -TERM "$PID"
+  for i in $(seq 1 60); do
+    kill -0 "$PID" 2>/dev/null || break
+    sleep 1
+  done
+  if kill -0 "$PID" 2>/dev/null; then
+    echo "the simulator did not stop within 60s; killing it" >&2
+    sudo -u ec2-user kill -KILL "$PID"
+  fi
+fi
+tail -n 5000 "$RUN_DIR/simulator.log" > "$RUN_DIR/simulator-tail.log" 2>/dev/null || true
+if [ "$(cat "$RUN_DIR/service-active" 2>/dev/null)" = "1" ]; then
+  sudo systemctl start workload-simulator.path workload-simulator.service
+fi
+`, Quote(r.Dir()))
+}
+
+// Start starts the run and returns the simulator's PID.
+func (r *SimulatorRun) Start(ctx context.Context, workload string) (string, error) {
+	out, err := r.host.Run(ctx, r.startScript(workload))
+	if err != nil {
+		return "", fmt.Errorf("starting the simulator: %w", err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// Check fails when the run's simulator is no longer running.
+func (r *SimulatorRun) Check(ctx context.Context) error {
+	if _, err := r.host.Run(ctx, r.checkScript()); err != nil {
+		return fmt.Errorf("checking the simulator: %w", err)
+	}
+	return nil
+}
+
+// Stop stops the run's simulator and restores the service.
+func (r *SimulatorRun) Stop(ctx context.Context) error {
+	if _, err := r.host.Run(ctx, r.stopScript()); err != nil {
+		return fmt.Errorf("stopping the simulator: %w", err)
+	}
+	return nil
+}
+
+// Logs streams the last lines of the run's simulator log to w; with follow
+// it keeps streaming new lines until ctx is cancelled.
+func (r *SimulatorRun) Logs(ctx context.Context, lines int, follow bool, w io.Writer) error {
+	script := fmt.Sprintf("tail -n %d %s/simulator.log", lines, Quote(r.Dir()))
+	if follow {
+		script = fmt.Sprintf("tail -n %d -F %s/simulator.log", lines, Quote(r.Dir()))
+	}
+	return r.host.Stream(ctx, script, w)
+}
+
+// Collect copies the run's statistics and the end of its log to localDir.
+func (r *SimulatorRun) Collect(ctx context.Context, localDir string) error {
+	if err := r.host.Fetch(ctx, r.Dir()+"/stats.jsonl", filepath.Join(localDir, "stats.jsonl")); err != nil {
+		return err
+	}
+	return r.host.Fetch(ctx, r.Dir()+"/simulator-tail.log", filepath.Join(localDir, "simulator.log"))
+}
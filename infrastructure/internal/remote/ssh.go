@@ -0,0 +1,64 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// SSH runs commands over SSH with the lab key pair.
+type SSH struct {
+	host string
+	user string
+	// key is the private key file; empty uses the SSH agent or config
+	key string
+}
+
+// NewSSH returns a Host reached as user@host; key is the private key file,
+// or empty to use the SSH agent or config.
+func NewSSH(host, user, key string) *SSH {
+	return &SSH{host: host, user: user, key: key}
+}
+
+func (h *SSH) command(ctx context.Context, args ...string) *exec.Cmd {
+	sshArgs := []string{"-o", "BatchMode=yes", "-o", "StrictHostKeyChecking=accept-new"}
+	if h.key != "" {
+		sshArgs = append(sshArgs, "-i", h.key)
+	}
+	sshArgs = append(sshArgs, h.user+"@"+h.host)
+	return exec.CommandContext(ctx, "ssh", append(sshArgs, args...)...)
+}
+
+func (h *SSH) Run(ctx context.Context, script string) (string, error) {
+	cmd := h.command(ctx, "bash", "-s")
+	cmd.Stdin = strings.NewReader(script)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("ssh %s: %w: %s", h.host, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+func (h *SSH) Stream(ctx context.Context, script string, w io.Writer) error {
+	cmd := h.command(ctx, "bash", "-s")
+	cmd.Stdin = strings.NewReader(script)
+	cmd.Stdout, cmd.Stderr = w, w
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("ssh %s: %w", h.host, err)
+	}
+	return nil
+}
+
+func (h *SSH) Fetch(ctx context.Context, remotePath, localPath string) error {
+	cmd := h.command(ctx, "cat", Quote(remotePath))
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("fetching %s: %w: %s", remotePath, err, strings.TrimSpace(stderr.String()))
+	}
+	return writeFile(localPath, &stdout)
+}
@@ -0,0 +1,192 @@
+package remote
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+// SSM runs commands with SSM Run Command (AWS-RunShellScript). The instance
+// needs the SSM agent and the AmazonSSMManagedInstanceCore policy, which the
+// ec2 stack attaches to the simulator role.
+type SSM struct {
+	client     *ssm.Client
+	instanceID string
+	// PollInterval is how often command status and streamed output are read
+	PollInterval time.Duration
+}
+
+// ssmChunkSize keeps a base64-encoded chunk below the 24,000 character limit
+// of the command output returned by GetCommandInvocation.
+const ssmChunkSize = 16 * 1024
+
+// NewSSM returns a Host for the instance.
+func NewSSM(cfg aws.Config, instanceID string) *SSM {
+	return &SSM{client: ssm.NewFromConfig(cfg), instanceID: instanceID, PollInterval: 2 * time.Second}
+}
+
+func (h *SSM) Run(ctx context.Context, script string) (string, error) {
+	out, err := h.client.SendCommand(ctx, &ssm.SendCommandInput{
+		DocumentName: aws.String("AWS-RunShellScript"),
+		InstanceIds:  []string{h.instanceID},
+		Comment:      aws.String("aurora-bluegreen-lab"),
+		Parameters:   map[string][]string{"commands": {script}},
+	})
+	if err != nil {
+		return "", fmt.Errorf("sending command to %s: %w", h.instanceID, err)
+	}
+	commandID := aws.ToString(out.Command.CommandId)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(h.PollInterval):
+		}
+
+		inv, err := h.client.GetCommandInvocation(ctx, &ssm.GetCommandInvocationInput{
+			CommandId:  aws.String(commandID),
+			InstanceId: aws.String(h.instanceID),
+		})
+		var notYet *types.InvocationDoesNotExist
+		if errors.As(err, &notYet) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("reading command %s output: %w", commandID, err)
+		}
+		switch inv.Status {
+		case types.CommandInvocationStatusPending, types.CommandInvocationStatusInProgress, types.CommandInvocationStatusDelayed:
+			continue
+		case types.CommandInvocationStatusSuccess:
+			return aws.ToString(inv.StandardOutputContent), nil
+		}
+		return "", fmt.Errorf("command %s on %s %s: %s", commandID, h.instanceID, inv.Status,
+			strings.TrimSpace(aws.ToString(inv.StandardErrorContent)))
+	}
+}
+
+// Stream runs the script in the background on the host, since Run Command
+// only returns output once a command has finished, and polls its output
+// file from the last offset read until the script exits.
+func (h *SSM) Stream(ctx context.Context, script string, w io.Writer) error {
+	out, err := h.Run(ctx, streamStartScript(script))
+	if err != nil {
+		return err
+	}
+	dir := Quote(strings.TrimSpace(out))
+
+	offset := 1
+	for {
+		out, err := h.Run(ctx, streamPollScript(dir, offset))
+		if err != nil {
+			if ctx.Err() != nil {
+				// Stop the script with a fresh context; ctx is already done
+				stopCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+				defer cancel()
+				h.Run(stopCtx, fmt.Sprintf(`D=%s; kill -TERM -- -"$(cat "$D/pid")" 2>/dev/null; rm -rf "$D"`, dir))
+				return ctx.Err()
+			}
+			return err
+		}
+
+		chunk, status, done, err := parseStreamPoll(out)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(chunk); err != nil {
+			return err
+		}
+		offset += len(chunk)
+		if done {
+			h.Run(ctx, fmt.Sprintf(`rm -rf %s`, dir))
+			if status != 0 {
+				return fmt.Errorf("command on %s exited with status %d", h.instanceID, status)
+			}
+			return nil
+		}
+	}
+}
+
+// streamStartScript starts script in the background with its output going to
+// a file and prints the directory holding the script, its output, its PID
+// and, once it has finished, its exit status.
+func streamStartScript(script string) string {
+	return fmt.Sprintf(`set -euo pipefail
+D=$(mktemp -d /tmp/aurora-lab-stream.XXXXXX)
+echo %s | base64 -d > "$D/script"
+touch "$D/output"
+setsid bash -c 'bash "$1/script" > "$1/output" 2>&1; echo $? > "$1/exit"' _ "$D" > /dev/null 2>&1 < /dev/null &
+echo $! > "$D/pid"
+echo "$D"
+`, base64.StdEncoding.EncodeToString([]byte(script)))
+}
+
+// streamPollScript prints "running" or "exit <status>", followed by the next
+// chunk of output from offset (1-based) in base64. The status is read before
+// the output, so the output is complete once the script is seen to have
+// exited.
+func streamPollScript(dir string, offset int) string {
+	return fmt.Sprintf(`set -uo pipefail
+D=%s
+if [ -f "$D/exit" ]; then echo "exit $(cat "$D/exit")"; else echo running; fi
+tail -c +%d "$D/output" | head -c %d | base64 -w0
+`, dir, offset, ssmChunkSize)
+}
+
+// parseStreamPoll parses the output of streamPollScript; done reports that
+// the script has exited and chunk was the rest of its output.
+func parseStreamPoll(out string) (chunk []byte, status int, done bool, err error) {
+	state, data, _ := strings.Cut(out, "\n")
+	chunk, err = base64.StdEncoding.DecodeString(strings.TrimSpace(data))
+	if err != nil {
+		return nil, 0, false, fmt.Errorf("reading streamed output: %w", err)
+	}
+	code, exited := strings.CutPrefix(strings.TrimSpace(state), "exit ")
+	if !exited || len(chunk) == ssmChunkSize {
+		return chunk, 0, false, nil
+	}
+	status, err = strconv.Atoi(code)
+	if err != nil {
+		return nil, 0, false, fmt.Errorf("reading streamed exit status %q: %w", code, err)
+	}
+	return chunk, status, true, nil
+}
+
+// Fetch transfers the file gzip-compressed in base64 chunks, since command
+// output is the only channel back from the host.
+func (h *SSM) Fetch(ctx context.Context, remotePath, localPath string) error {
+	var compressed bytes.Buffer
+	for offset := 1; ; offset += ssmChunkSize {
+		out, err := h.Run(ctx, fmt.Sprintf("set -euo pipefail\ngzip -cn %s | tail -c +%d | head -c %d | base64 -w0",
+			Quote(remotePath), offset, ssmChunkSize))
+		if err != nil {
+			return fmt.Errorf("fetching %s: %w", remotePath, err)
+		}
+		chunk, err := base64.StdEncoding.DecodeString(strings.TrimSpace(out))
+		if err != nil {
+			return fmt.Errorf("fetching %s: %w", remotePath, err)
+		}
+		compressed.Write(chunk)
+		if len(chunk) < ssmChunkSize {
+			break
+		}
+	}
+
+	r, err := gzip.NewReader(&compressed)
+	if err != nil {
+		return fmt.Errorf("fetching %s: %w", remotePath, err)
+	}
+	return writeFile(localPath, r)
+}
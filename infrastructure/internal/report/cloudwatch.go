@@ -0,0 +1,70 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+)
+
+// replicaLagPeriod is the period of the AWS/RDS metrics, which are published
+// every minute.
+const replicaLagPeriod = 60 * time.Second
+
+// ReplicaLag fetches the maximum Aurora replica lag of each cluster in
+// milliseconds over the window. CloudWatch metrics follow the cluster
+// identifier and a switchover renames the clusters, so pass every identifier
+// the blue and green clusters had during the run.
+func ReplicaLag(ctx context.Context, cfg aws.Config, clusters []string, w Window) ([]Series, error) {
+	queries := make([]types.MetricDataQuery, len(clusters))
+	for i, cluster := range clusters {
+		queries[i] = types.MetricDataQuery{
+			Id:    aws.String(fmt.Sprintf("lag%d", i)),
+			Label: aws.String(cluster),
+			MetricStat: &types.MetricStat{
+				Metric: &types.Metric{
+					Namespace:  aws.String("AWS/RDS"),
+					MetricName: aws.String("AuroraReplicaLagMaximum"),
+					Dimensions: []types.Dimension{
+						{Name: aws.String("DBClusterIdentifier"), Value: aws.String(cluster)},
+					},
+				},
+				Period: aws.Int32(int32(replicaLagPeriod.Seconds())),
+				Stat:   aws.String("Maximum"),
+			},
+		}
+	}
+
+	byID := map[string]*Series{}
+	result := make([]Series, len(clusters))
+	for i, cluster := range clusters {
+		result[i].Name = cluster
+		byID[*queries[i].Id] = &result[i]
+	}
+
+	paginator := cloudwatch.NewGetMetricDataPaginator(cloudwatch.NewFromConfig(cfg), &cloudwatch.GetMetricDataInput{
+		MetricDataQueries: queries,
+		StartTime:         aws.Time(w.Start.Truncate(replicaLagPeriod)),
+		EndTime:           aws.Time(w.End.Truncate(replicaLagPeriod).Add(replicaLagPeriod)),
+		ScanBy:            types.ScanByTimestampAscending,
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("fetching replica lag metrics: %w", err)
+		}
+		for _, r := range page.MetricDataResults {
+			s := byID[aws.ToString(r.Id)]
+			if s == nil {
+				continue
+			}
+			for j := range r.Timestamps {
+				s.Points = append(s.Points, Point{Time: r.Timestamps[j], Value: r.Values[j]})
+			}
+		}
+	}
+	return result, nil
+}
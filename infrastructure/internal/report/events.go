@@ -0,0 +1,95 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"aurora-bluegreen-lab/internal/bluegreen"
+)
+
+// rdsEventPrefix marks the events RDS recorded server-side in the timeline,
+// apart from the client-side ones of the same name.
+const rdsEventPrefix = "rds:"
+
+// BlueGreenEvents reads the RDS Blue/Green events within the window from the
+// monitoring stack's event table (eventTableName output) as timeline events,
+// named rds:<event> (e.g. rds:switchover-started) with the deployment ID and
+// the RDS message as detail, and the deployment's run ID. RunEvents keeps the
+// events of one run.
+func BlueGreenEvents(ctx context.Context, cfg aws.Config, table string, w Window) ([]Event, error) {
+	paginator := dynamodb.NewScanPaginator(dynamodb.NewFromConfig(cfg), &dynamodb.ScanInput{
+		TableName:        aws.String(table),
+		FilterExpression: aws.String("#time BETWEEN :start AND :end"),
+		ExpressionAttributeNames: map[string]string{
+			"#time": bluegreen.AttrEventTime,
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":start": &types.AttributeValueMemberS{Value: w.Start.UTC().Format(bluegreen.EventTimeFormat)},
+			":end":   &types.AttributeValueMemberS{Value: w.End.UTC().Format(bluegreen.EventTimeFormat)},
+		},
+	})
+
+	var events []Event
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("reading Blue/Green events from %s: %w", table, err)
+		}
+		for _, item := range page.Items {
+			e, err := itemEvent(item)
+			if err != nil {
+				return nil, fmt.Errorf("reading Blue/Green events from %s: %w", table, err)
+			}
+			events = append(events, e)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Timestamp.Before(events[j].Timestamp) })
+	return events, nil
+}
+
+// itemEvent converts an event table item to a timeline event.
+func itemEvent(item map[string]types.AttributeValue) (Event, error) {
+	attr := func(name string) string {
+		if value, ok := item[name].(*types.AttributeValueMemberS); ok {
+			return value.Value
+		}
+		return ""
+	}
+	t, err := time.Parse(bluegreen.EventTimeFormat, attr(bluegreen.AttrEventTime))
+	if err != nil {
+		return Event{}, fmt.Errorf("event %s: %w", attr(bluegreen.AttrEventKey), err)
+	}
+	detail := attr(bluegreen.AttrDeploymentID)
+	if message := attr(bluegreen.AttrMessage); message != "" {
+		detail += ": " + message
+	}
+	return Event{Timestamp: t, Event: rdsEventPrefix + attr(bluegreen.AttrEvent), Detail: detail, RunID: attr(bluegreen.AttrRunID)}, nil
+}
+
+// RunEvents returns the events of the run runID.
+func RunEvents(events []Event, runID string) []Event {
+	var kept []Event
+	for _, e := range events {
+		if e.RunID == runID {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
+
+// MergeTimelines merges timelines into one sorted by time; events of the
+// same time keep the order of the timelines.
+func MergeTimelines(timelines ...[]Event) []Event {
+	var merged []Event
+	for _, timeline := range timelines {
+		merged = append(merged, timeline...)
+	}
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].Timestamp.Before(merged[j].Timestamp) })
+	return merged
+}
@@ -0,0 +1,27 @@
+package report
+
+import (
+	"aurora-bluegreen-lab/internal/notify"
+)
+
+// EventDowntimeReport is the event of the downtime report posted to a
+// webhook once a run's report is written.
+const EventDowntimeReport = "downtime-report"
+
+// Notification returns the downtime report to post to a webhook: the report
+// title and error window as the text, the summary rows as fields.
+func (r *Report) Notification(source string) notify.Message {
+	m := notify.Message{Source: source, Event: EventDowntimeReport, RunID: r.Stats.RunID()}
+	errorWindow := "none"
+	for _, row := range r.Summary() {
+		switch row.Label {
+		case "Run ID":
+			continue
+		case "Error window":
+			errorWindow = row.Value
+		}
+		m.Fields = append(m.Fields, notify.Field{Name: row.Label, Value: row.Value})
+	}
+	m.Text = r.Title + ": error window " + errorWindow
+	return m
+}
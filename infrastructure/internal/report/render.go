@@ -0,0 +1,365 @@
+package report
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// palette colors the series of a chart in order.
+var palette = []string{"#1f77b4", "#d62728", "#2ca02c", "#ff7f0e", "#9467bd", "#8c564b"}
+
+var markdownTemplate = template.Must(template.New("markdown").Funcs(template.FuncMap{
+	"cell":    markdownCell,
+	"mermaid": mermaid,
+	"time":    formatTime,
+	"ms":      formatMillis,
+}).Parse(`# {{.Title}}
+
+Generated {{.Generated.Format "2006-01-02 15:04:05"}} UTC from ` + "`{{.Source}}`" + `.
+
+## Summary
+
+| | |
+|---|---|
+{{- range .Summary}}
+| {{cell .Label}} | {{cell .Value}} |
+{{- end}}
+{{- if .Timeline}}
+
+## Switchover Timeline
+
+| Time (UTC) | Offset | Event | Detail |
+|---|---|---|---|
+{{- range .Timeline}}
+| {{time .Timestamp}} | {{$.Offset .Timestamp}} | {{cell .Event}} | {{cell .Detail}} |
+{{- end}}
+{{- end}}
+
+## Charts
+{{range .Charts}}
+### {{.Title}}
+
+` + "```mermaid" + `
+{{mermaid .}}
+` + "```" + `
+{{- if gt (len .Series) 1}}
+
+Lines in order: {{range $i, $s := .Series}}{{if $i}}, {{end}}{{cell $s.Name}}{{end}}.
+{{- end}}
+{{end}}
+{{- with .Comparisons}}
+## Connection Path Comparison
+
+Fastest to recover first; a target with failed requests that never recovered ranks last.
+
+| Target | Endpoint | Requests | Failed | Success rate | Error window | Recoveries | Recovery p50 | Recovery max |
+|---|---|---|---|---|---|---|---|---|
+{{- range .}}
+| {{cell .Target}} | {{cell .Endpoint}} | {{.Total}} | {{.Failed}} | {{printf "%.2f%%" .SuccessRate}} | {{.ErrorWindow}} | {{with .Recovery}}{{.Count}} | {{ms .P50}} | {{ms .Max}}{{else}}0 | – | –{{end}} |
+{{- end}}
+{{end}}
+{{- with .Stats.Final}}
+## Latency (whole run)
+
+| Operation | Count | p50 | p95 | p99 | p99.9 | Max |
+|---|---|---|---|---|---|---|
+{{- range .Latency}}
+| {{cell .Name}} | {{.Count}} | {{ms .P50}} | {{ms .P95}} | {{ms .P99}} | {{ms .P999}} | {{ms .Max}} |
+{{- end}}
+{{- if .Recovery}}
+
+## Recovery Time (whole run)
+
+| Strategy | Recoveries | p50 | p95 | p99 | p99.9 | Max |
+|---|---|---|---|---|---|---|
+{{- range .Recovery}}
+| {{cell .Name}} | {{.Count}} | {{ms .P50}} | {{ms .P95}} | {{ms .P99}} | {{ms .P999}} | {{ms .Max}} |
+{{- end}}
+{{- end}}
+{{end}}`))
+
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("html").Funcs(htmltemplate.FuncMap{
+	"time": formatTime,
+	"ms":   formatMillis,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em auto; max-width: 960px; color: #222; }
+table { border-collapse: collapse; margin: 1em 0; }
+th, td { border: 1px solid #ccc; padding: 4px 10px; text-align: left; }
+th { background: #f3f3f3; }
+svg { display: block; margin: 0.5em 0 1.5em; }
+.note { color: #666; font-size: 0.9em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="note">Generated {{.Generated.Format "2006-01-02 15:04:05"}} UTC from <code>{{.Source}}</code>.</p>
+
+<h2>Summary</h2>
+<table>
+{{- range .Summary}}
+<tr><th>{{.Label}}</th><td>{{.Value}}</td></tr>
+{{- end}}
+</table>
+{{- if .Timeline}}
+
+<h2>Switchover Timeline</h2>
+<table>
+<tr><th>Time (UTC)</th><th>Offset</th><th>Event</th><th>Detail</th></tr>
+{{- range .Timeline}}
+<tr><td>{{time .Timestamp}}</td><td>{{$.Offset .Timestamp}}</td><td>{{.Event}}</td><td>{{.Detail}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+
+<h2>Charts</h2>
+<p class="note">Red shading marks the error window, blue shading the switchover window, orange shading the failover window; dashed lines are timeline events.</p>
+{{- range .SVGCharts}}
+<h3>{{.Title}}</h3>
+{{.SVG}}
+{{- end}}
+{{- with .Comparisons}}
+
+<h2>Connection Path Comparison</h2>
+<p class="note">Fastest to recover first; a target with failed requests that never recovered ranks last.</p>
+<table>
+<tr><th>Target</th><th>Endpoint</th><th>Requests</th><th>Failed</th><th>Success rate</th><th>Error window</th><th>Recoveries</th><th>Recovery p50</th><th>Recovery max</th></tr>
+{{- range .}}
+<tr><td>{{.Target}}</td><td>{{.Endpoint}}</td><td>{{.Total}}</td><td>{{.Failed}}</td><td>{{printf "%.2f%%" .SuccessRate}}</td><td>{{.ErrorWindow}}</td>{{with .Recovery}}<td>{{.Count}}</td><td>{{ms .P50}}</td><td>{{ms .Max}}</td>{{else}}<td>0</td><td>–</td><td>–</td>{{end}}</tr>
+{{- end}}
+</table>
+{{- end}}
+{{- with .Stats.Final}}
+
+<h2>Latency (whole run)</h2>
+<table>
+<tr><th>Operation</th><th>Count</th><th>p50</th><th>p95</th><th>p99</th><th>p99.9</th><th>Max</th></tr>
+{{- range .Latency}}
+<tr><td>{{.Name}}</td><td>{{.Count}}</td><td>{{ms .P50}}</td><td>{{ms .P95}}</td><td>{{ms .P99}}</td><td>{{ms .P999}}</td><td>{{ms .Max}}</td></tr>
+{{- end}}
+</table>
+{{- if .Recovery}}
+
+<h2>Recovery Time (whole run)</h2>
+<table>
+<tr><th>Strategy</th><th>Recoveries</th><th>p50</th><th>p95</th><th>p99</th><th>p99.9</th><th>Max</th></tr>
+{{- range .Recovery}}
+<tr><td>{{.Name}}</td><td>{{.Count}}</td><td>{{ms .P50}}</td><td>{{ms .P95}}</td><td>{{ms .P99}}</td><td>{{ms .P999}}</td><td>{{ms .Max}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
+
+// Markdown writes the report as Markdown with Mermaid charts, which GitHub
+// and most Markdown viewers render inline.
+func (r *Report) Markdown(w io.Writer) error {
+	return markdownTemplate.Execute(w, r)
+}
+
+// HTML writes the report as a self-contained HTML page with inline SVG
+// charts.
+func (r *Report) HTML(w io.Writer) error {
+	return htmlTemplate.Execute(w, r)
+}
+
+// SVGChart is a chart rendered for the HTML report.
+type SVGChart struct {
+	Title string
+	SVG   htmltemplate.HTML
+}
+
+// SVGCharts renders the charts on the time axis of the run with the error,
+// switchover and failover windows and the timeline events marked.
+func (r *Report) SVGCharts() []SVGChart {
+	charts := make([]SVGChart, len(r.Charts))
+	for i, c := range r.Charts {
+		charts[i] = SVGChart{Title: c.Title, SVG: htmltemplate.HTML(r.svg(c))}
+	}
+	return charts
+}
+
+// Chart geometry in pixels.
+const (
+	svgWidth  = 900
+	svgHeight = 240
+	svgLeft   = 60
+	svgRight  = 20
+	svgTop    = 28
+	svgBottom = 30
+)
+
+func (r *Report) svg(c Chart) string {
+	span := r.Window()
+	plotWidth := float64(svgWidth - svgLeft - svgRight)
+	plotHeight := float64(svgHeight - svgTop - svgBottom)
+	x := func(t time.Time) float64 {
+		if span.Duration() <= 0 {
+			return svgLeft
+		}
+		return svgLeft + plotWidth*float64(t.Sub(span.Start))/float64(span.Duration())
+	}
+	inSpan := func(t time.Time) bool {
+		return !t.Before(span.Start) && !t.After(span.End)
+	}
+
+	maxValue := 0.0
+	for _, s := range c.Series {
+		for _, p := range s.Points {
+			if inSpan(p.Time) {
+				maxValue = math.Max(maxValue, p.Value)
+			}
+		}
+	}
+	if maxValue == 0 {
+		maxValue = 1
+	}
+	maxValue *= 1.1
+	y := func(v float64) float64 {
+		return svgTop + plotHeight*(1-v/maxValue)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-size="11" font-family="sans-serif">`, svgWidth, svgHeight)
+
+	shade := func(w *Window, color string) {
+		if w == nil {
+			return
+		}
+		x1, x2 := x(maxTime(w.Start, span.Start)), x(minTime(w.End, span.End))
+		if x2 > x1 {
+			fmt.Fprintf(&b, `<rect x="%.1f" y="%d" width="%.1f" height="%.0f" fill="%s" fill-opacity="0.12"/>`,
+				x1, svgTop, x2-x1, plotHeight, color)
+		}
+	}
+	shade(r.ErrorWindow, "#d62728")
+	shade(r.Switchover, "#1f77b4")
+	shade(r.Failover, "#ff7f0e")
+
+	// Horizontal grid lines and y axis labels
+	for i := 0; i <= 4; i++ {
+		v := maxValue * float64(i) / 4
+		fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#e5e5e5"/>`, svgLeft, y(v), svgWidth-svgRight, y(v))
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" text-anchor="end" dominant-baseline="middle">%s</text>`, svgLeft-6, y(v), formatAxis(v))
+	}
+	fmt.Fprintf(&b, `<text x="12" y="%d" transform="rotate(-90 12 %d)" text-anchor="middle">%s</text>`,
+		svgTop+int(plotHeight/2), svgTop+int(plotHeight/2), escape(c.Unit))
+
+	// Time axis labels
+	for i := 0; i <= 4; i++ {
+		t := span.Start.Add(span.Duration() * time.Duration(i) / 4)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" text-anchor="middle">%s</text>`, x(t), svgHeight-10, formatTime(t))
+	}
+
+	for _, e := range r.Timeline {
+		if !inSpan(e.Timestamp) {
+			continue
+		}
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%d" stroke="#555" stroke-dasharray="4 3"><title>%s %s</title></line>`,
+			x(e.Timestamp), svgTop, x(e.Timestamp), svgHeight-svgBottom, formatTime(e.Timestamp), escape(e.Event))
+	}
+
+	for i, s := range c.Series {
+		color := palette[i%len(palette)]
+		var coords []string
+		for _, p := range s.Points {
+			if inSpan(p.Time) {
+				coords = append(coords, fmt.Sprintf("%.1f,%.1f", x(p.Time), y(p.Value)))
+			}
+		}
+		if len(coords) > 0 {
+			fmt.Fprintf(&b, `<polyline fill="none" stroke="%s" stroke-width="1.5" points="%s"/>`, color, strings.Join(coords, " "))
+		}
+		// Legend along the top of the chart
+		lx := svgLeft + 140*i
+		fmt.Fprintf(&b, `<rect x="%d" y="8" width="12" height="3" fill="%s"/><text x="%d" y="13">%s</text>`, lx, color, lx+16, escape(s.Name))
+	}
+
+	fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%.0f" height="%.0f" fill="none" stroke="#999"/>`, svgLeft, svgTop, plotWidth, plotHeight)
+	b.WriteString(`</svg>`)
+	return b.String()
+}
+
+// mermaid renders a chart as a Mermaid xychart. Series are aligned on the
+// union of their timestamps; missing values are drawn as 0.
+func mermaid(c Chart) string {
+	var times []time.Time
+	seen := map[time.Time]bool{}
+	for _, s := range c.Series {
+		for _, p := range s.Points {
+			if !seen[p.Time] {
+				seen[p.Time] = true
+				times = append(times, p.Time)
+			}
+		}
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+
+	labels := make([]string, len(times))
+	for i, t := range times {
+		labels[i] = fmt.Sprintf("%q", formatTime(t))
+	}
+
+	var b strings.Builder
+	b.WriteString("xychart-beta\n")
+	fmt.Fprintf(&b, "    title %q\n", c.Title)
+	fmt.Fprintf(&b, "    x-axis [%s]\n", strings.Join(labels, ", "))
+	fmt.Fprintf(&b, "    y-axis %q", c.Unit)
+	for _, s := range c.Series {
+		values := map[time.Time]float64{}
+		for _, p := range s.Points {
+			values[p.Time] = p.Value
+		}
+		formatted := make([]string, len(times))
+		for i, t := range times {
+			formatted[i] = formatAxis(values[t])
+		}
+		fmt.Fprintf(&b, "\n    line [%s]", strings.Join(formatted, ", "))
+	}
+	return b.String()
+}
+
+func markdownCell(value string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(value)
+}
+
+func formatMillis(value float64) string {
+	return fmt.Sprintf("%.2f ms", value)
+}
+
+func formatAxis(value float64) string {
+	if value == math.Trunc(value) {
+		return fmt.Sprintf("%.0f", value)
+	}
+	return fmt.Sprintf("%.2f", value)
+}
+
+func escape(value string) string {
+	return htmltemplate.HTMLEscapeString(value)
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
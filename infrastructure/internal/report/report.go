@@ -0,0 +1,259 @@
+// Package report builds the report of a lab run from the simulator's JSON
+// Lines statistics, an optional switchover timeline and the Aurora replica lag
+// from CloudWatch, and renders it as Markdown (Mermaid charts) or HTML (inline
+// SVG charts).
+//
+// It is shared by lab-report, which renders the outputs of a manual run, and
+// lab-scenario, which renders the report at the end of every scenario.
+package report
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Point is one value of a chart series.
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// Series is one line of a chart.
+type Series struct {
+	Name   string
+	Points []Point
+}
+
+// Chart is a time series chart of the report.
+type Chart struct {
+	Title string
+	Unit  string
+	// Series share the time axis of the run
+	Series []Series
+}
+
+// Report is everything known about one lab run.
+type Report struct {
+	Title     string
+	Source    string
+	Generated time.Time
+	Stats     *Stats
+	Timeline  []Event
+	// ErrorWindow spans the Impacted intervals; nil when no interval was
+	// impacted
+	ErrorWindow *Window
+	Impacted    int
+	// Switchover is the switchover window of the timeline; nil without a
+	// timeline or when the switchover did not complete
+	Switchover *Window
+	// Failover is the failover window of a timeline recorded by bgctl
+	// failover; nil without one
+	Failover *Window
+	Charts   []Chart
+}
+
+// Row is a label/value row of the summary table.
+type Row struct {
+	Label string
+	Value string
+}
+
+// New analyses the simulator output and builds the report charts.
+// The timeline and replica lag are optional.
+func New(title, source string, stats *Stats, timeline []Event, lag []Series) *Report {
+	r := &Report{
+		Title:     title,
+		Source:    source,
+		Generated: time.Now().UTC(),
+		Stats:     stats,
+		Timeline:  timeline,
+	}
+	if w, impacted, ok := ErrorWindow(stats.Intervals); ok {
+		r.ErrorWindow, r.Impacted = &w, impacted
+	}
+	if w, ok := SwitchoverWindow(timeline); ok {
+		r.Switchover = &w
+	}
+	if w, ok := FailoverWindow(timeline); ok {
+		r.Failover = &w
+	}
+
+	success := Series{Name: "success"}
+	failed := Series{Name: "failed"}
+	latency := map[string]*Chart{}
+	var operations []string
+	for _, i := range stats.Intervals {
+		success.Points = append(success.Points, Point{Time: i.End, Value: float64(i.Success)})
+		failed.Points = append(failed.Points, Point{Time: i.End, Value: float64(i.Failed)})
+		for _, p := range i.Latency {
+			c := latency[p.Name()]
+			if c == nil {
+				c = &Chart{
+					Title:  "Latency percentiles: " + p.Name(),
+					Unit:   "ms",
+					Series: []Series{{Name: "p50"}, {Name: "p95"}, {Name: "p99"}},
+				}
+				latency[p.Name()] = c
+				operations = append(operations, p.Name())
+			}
+			for j, value := range []float64{p.P50, p.P95, p.P99} {
+				c.Series[j].Points = append(c.Series[j].Points, Point{Time: i.End, Value: value})
+			}
+		}
+	}
+
+	r.Charts = append(r.Charts,
+		Chart{Title: "Successful requests per interval", Unit: "requests", Series: []Series{success}},
+		Chart{Title: "Failed requests per interval", Unit: "requests", Series: []Series{failed}})
+	if targets := r.Stats.Last.Targets; len(targets) > 0 {
+		chart := Chart{Title: "Failed requests per interval by target", Unit: "requests"}
+		for _, t := range targets {
+			s := Series{Name: t.Target}
+			for _, i := range stats.Intervals {
+				s.Points = append(s.Points, Point{Time: i.End, Value: float64(i.TargetFailed[t.Target])})
+			}
+			chart.Series = append(chart.Series, s)
+		}
+		r.Charts = append(r.Charts, chart)
+	}
+	for _, operation := range operations {
+		r.Charts = append(r.Charts, *latency[operation])
+	}
+	if len(lag) > 0 {
+		r.Charts = append(r.Charts, Chart{Title: "Aurora replica lag (maximum)", Unit: "ms", Series: lag})
+	}
+	return r
+}
+
+// Window returns the period covered by the simulator output.
+func (r *Report) Window() Window {
+	return Window{Start: r.Stats.Start(), End: r.Stats.End()}
+}
+
+// Summary returns the headline numbers of the run.
+func (r *Report) Summary() []Row {
+	last := r.Stats.Last
+	rows := []Row{
+		{"Run", r.Stats.Start().UTC().Format("2006-01-02") + " " + formatWindow(r.Window())},
+	}
+	if id := r.Stats.RunID(); id != "" {
+		rows = append(rows, Row{"Run ID", id})
+	}
+	rows = append(rows,
+		Row{"Requests", fmt.Sprintf("%d total, %d succeeded, %d failed (%.2f%% success)",
+			last.Requests.Total, last.Requests.Success, last.Requests.Failed, last.Requests.SuccessRate)})
+	if t := last.Transactions; t != nil {
+		rows = append(rows, Row{"Transactions", fmt.Sprintf("%d committed, %d rolled back, %d commit unknown",
+			t.Committed, t.RolledBack, t.CommitUnknown)})
+	}
+	if s := last.Statements; s != nil && (s.Mode != "prepared" || s.Stale > 0) {
+		rows = append(rows, Row{"Statements", fmt.Sprintf("%s, %d stale statement error(s)", s.Mode, s.Stale)})
+	}
+	if p := last.Probe; p != nil {
+		value := fmt.Sprintf("%d probes, %d anomalies", p.Probes, p.Anomalies)
+		if r.Switchover != nil {
+			value += fmt.Sprintf(", %d in intervals overlapping the switchover", ProbeAnomalies(r.Stats.Intervals, *r.Switchover))
+		}
+		rows = append(rows, Row{"Read-after-write probe", value})
+	}
+	if r.ErrorWindow != nil {
+		rows = append(rows, Row{"Error window", fmt.Sprintf("%s, %d impacted interval(s)", formatWindow(*r.ErrorWindow), r.Impacted)})
+	} else {
+		rows = append(rows, Row{"Error window", "none"})
+	}
+	if r.Switchover != nil {
+		rows = append(rows, Row{"Switchover", formatWindow(*r.Switchover)})
+	}
+	if r.Failover != nil {
+		rows = append(rows, Row{"Failover", formatWindow(*r.Failover)})
+	}
+	if r.Stats.Final != nil {
+		for _, p := range r.Stats.Final.Recovery {
+			rows = append(rows, Row{"Recovery time (" + p.Name() + ")",
+				fmt.Sprintf("p50 %.0f ms, max %.0f ms over %d recoveries", p.P50, p.Max, p.Count)})
+		}
+	} else {
+		rows = append(rows, Row{"Final report", "missing (the simulator did not shut down cleanly)"})
+	}
+	if comparisons := r.Comparisons(); len(comparisons) > 0 {
+		first := comparisons[0]
+		switch {
+		case first.Recovery != nil:
+			rows = append(rows, Row{"Fastest recovery", fmt.Sprintf("%s (%s), max %.0f ms", first.Target, first.Endpoint, first.Recovery.Max)})
+		case first.Failed == 0 && first.ErrorWindowMs == 0:
+			rows = append(rows, Row{"Fastest recovery", fmt.Sprintf("%s (%s) saw no connection errors", first.Target, first.Endpoint)})
+		}
+	}
+	return rows
+}
+
+// Comparison is one compared endpoint of the run.
+type Comparison struct {
+	TargetRequests
+	// Recovery is the target's recovery time; nil when its connections
+	// never recovered from an error or there is no final record
+	Recovery *Percentiles
+}
+
+// ErrorWindow returns the time between the target's first and latest
+// connection error.
+func (c Comparison) ErrorWindow() time.Duration {
+	return time.Duration(c.ErrorWindowMs) * time.Millisecond
+}
+
+// rank orders targets by their slowest recovery, a target with failed
+// requests that never recovered last, and then by their failed requests.
+func (c Comparison) rank() float64 {
+	switch {
+	case c.Recovery != nil:
+		return c.Recovery.Max
+	case c.Failed > 0:
+		return math.Inf(1)
+	}
+	return 0
+}
+
+// Comparisons returns the endpoints the simulator compared, the fastest to
+// recover first; nil when it wrote through one endpoint.
+func (r *Report) Comparisons() []Comparison {
+	var result []Comparison
+	for _, t := range r.Stats.Last.Targets {
+		c := Comparison{TargetRequests: t}
+		if r.Stats.Final != nil {
+			// With several targets the simulator names recoveries "strategy (target)"
+			for i, p := range r.Stats.Final.Recovery {
+				if strings.HasSuffix(p.Name(), " ("+t.Target+")") {
+					c.Recovery = &r.Stats.Final.Recovery[i]
+				}
+			}
+		}
+		result = append(result, c)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].rank() != result[j].rank() {
+			return result[i].rank() < result[j].rank()
+		}
+		return result[i].Failed < result[j].Failed
+	})
+	return result
+}
+
+// Offset returns the time since the start of the run.
+func (r *Report) Offset(t time.Time) string {
+	offset := t.Sub(r.Stats.Start()).Round(time.Second)
+	if offset < 0 {
+		return "-" + (-offset).String()
+	}
+	return "+" + offset.String()
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format("15:04:05")
+}
+
+func formatWindow(w Window) string {
+	return fmt.Sprintf("%s – %s UTC (%s)", formatTime(w.Start), formatTime(w.End), w.Duration().Round(time.Second))
+}
@@ -0,0 +1,299 @@
+package report
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const statsFile = `{"timestamp":"2025-01-18T10:15:10Z","record":"interval","requests":{"total":100,"success":100,"failed":0,"successRate":100.00},"probe":{"probes":10,"anomalies":0},"latency":[{"operation":"insert","count":100,"p50":10.00,"p95":15.00,"p99":20.00,"p999":25.00,"max":30.00}],"recovery":[]}
+{"timestamp":"2025-01-18T10:15:20Z","record":"interval","requests":{"total":180,"success":170,"failed":10,"successRate":94.44},"probe":{"probes":20,"anomalies":2},"latency":[{"operation":"insert","count":70,"p50":12.00,"p95":900.00,"p99":1500.00,"p999":1800.00,"max":2000.00}],"recovery":[]}
+{"timestamp":"2025-01-18T10:15:30Z","record":"interval","requests":{"total":185,"success":170,"failed":15,"successRate":91.89},"probe":{"probes":25,"anomalies":3},"latency":[],"recovery":[]}
+
+{"timestamp":"2025-01-18T10:15:40.5+00:00","record":"interval","requests":{"total":285,"success":270,"failed":15,"successRate":94.74},"probe":{"probes":35,"anomalies":3},"latency":[{"operation":"insert","count":100,"p50":11.00,"p95":16.00,"p99":21.00,"p999":26.00,"max":31.00}],"recovery":[{"strategy":"pool","count":4,"p50":8000.00,"p95":9000.00,"p99":9000.00,"p999":9000.00,"max":9000.00}]}
+{"timestamp":"2025-01-18T10:16Z","record":"final","runId":"minor-upgrade-20250118-101000","requests":{"total":300,"success":285,"failed":15,"successRate":95.00},"statements":{"mode":"reuse","stale":24},"probe":{"probes":37,"anomalies":3},"latency":[{"operation":"insert","count":285,"p50":11.00,"p95":16.00,"p99":900.00,"p999":1800.00,"max":2000.00}],"recovery":[{"strategy":"pool","count":4,"p50":8000.00,"p95":9000.00,"p99":9000.00,"p999":9000.00,"max":9000.00}]}
+`
+
+// A --dual-target run: the proxy recovered, the direct workers' requests
+// failed until the final record
+const targetsFile = `{"timestamp":"2025-01-18T10:15:10Z","record":"interval","requests":{"total":200,"success":200,"failed":0,"successRate":100.00},"targets":[{"target":"direct","endpoint":"lab.cluster-xyz.us-east-1.rds.amazonaws.com","total":100,"success":100,"failed":0,"successRate":100.00,"errorWindowMs":0},{"target":"proxy","endpoint":"lab-rds-proxy.proxy-xyz.us-east-1.rds.amazonaws.com","total":100,"success":100,"failed":0,"successRate":100.00,"errorWindowMs":0}],"latency":[],"recovery":[]}
+{"timestamp":"2025-01-18T10:15:20Z","record":"interval","requests":{"total":380,"success":368,"failed":12,"successRate":96.84},"targets":[{"target":"direct","endpoint":"lab.cluster-xyz.us-east-1.rds.amazonaws.com","total":180,"success":170,"failed":10,"successRate":94.44,"errorWindowMs":6000},{"target":"proxy","endpoint":"lab-rds-proxy.proxy-xyz.us-east-1.rds.amazonaws.com","total":200,"success":198,"failed":2,"successRate":99.00,"errorWindowMs":1500}],"latency":[],"recovery":[]}
+{"timestamp":"2025-01-18T10:16Z","record":"final","requests":{"total":480,"success":460,"failed":20,"successRate":95.83},"targets":[{"target":"direct","endpoint":"lab.cluster-xyz.us-east-1.rds.amazonaws.com","total":190,"success":172,"failed":18,"successRate":90.53,"errorWindowMs":9000},{"target":"proxy","endpoint":"lab-rds-proxy.proxy-xyz.us-east-1.rds.amazonaws.com","total":290,"success":288,"failed":2,"successRate":99.31,"errorWindowMs":1500}],"latency":[],"recovery":[{"strategy":"pool (proxy)","count":3,"p50":1200.00,"p95":1800.00,"p99":1800.00,"p999":1800.00,"max":1800.00}]}
+`
+
+const timelineFile = `{"timestamp":"2025-01-18T10:15:25Z","event":"switchover-completed","detail":"green is now the writer"}
+{"timestamp":"2025-01-18T10:15:12Z","event":"switchover-started","detail":"bgd-abc123"}
+`
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func at(clock string) time.Time {
+	ts, err := time.Parse(time.RFC3339Nano, "2025-01-18T"+clock+"Z")
+	if err != nil {
+		panic(err)
+	}
+	return ts
+}
+
+func TestReadStats(t *testing.T) {
+	stats, err := ReadStats(writeFile(t, "stats.jsonl", statsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Final == nil || stats.Last != stats.Final || stats.Final.Requests.Total != 300 {
+		t.Fatalf("final record: got %+v", stats.Final)
+	}
+	if stats.RunID() != "minor-upgrade-20250118-101000" {
+		t.Errorf("run ID: got %q", stats.RunID())
+	}
+
+	want := []Interval{
+		{Start: at("10:15:00"), End: at("10:15:10"), Success: 100, Failed: 0},
+		{Start: at("10:15:10"), End: at("10:15:20"), Success: 70, Failed: 10},
+		{Start: at("10:15:20"), End: at("10:15:30"), Success: 0, Failed: 5},
+		{Start: at("10:15:30"), End: at("10:15:40.5"), Success: 100, Failed: 0},
+	}
+	if len(stats.Intervals) != len(want) {
+		t.Fatalf("intervals: got %d, want %d", len(stats.Intervals), len(want))
+	}
+	for i, w := range want {
+		got := stats.Intervals[i]
+		if !got.Start.Equal(w.Start) || !got.End.Equal(w.End) || got.Success != w.Success || got.Failed != w.Failed {
+			t.Errorf("interval %d: got %v-%v %d/%d, want %v-%v %d/%d", i,
+				got.Start, got.End, got.Success, got.Failed, w.Start, w.End, w.Success, w.Failed)
+		}
+	}
+
+	w, impacted, ok := ErrorWindow(stats.Intervals)
+	if !ok || impacted != 2 || !w.Start.Equal(at("10:15:10")) || !w.End.Equal(at("10:15:30")) {
+		t.Errorf("error window: got %v-%v (%d intervals, %v)", w.Start, w.End, impacted, ok)
+	}
+}
+
+func TestReadStatsWithoutFinalRecord(t *testing.T) {
+	// A resumed run that was killed after writing a final record earlier
+	content := statsFile + `{"timestamp":"2025-01-18T10:20:00Z","record":"interval","requests":{"total":400,"success":385,"failed":15,"successRate":96.25},"latency":[],"recovery":[]}` + "\n"
+	stats, err := ReadStats(writeFile(t, "stats.jsonl", content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Final != nil {
+		t.Errorf("final record: got %+v, want nil", stats.Final)
+	}
+	if stats.Last.Requests.Total != 400 {
+		t.Errorf("last record: got %d requests, want 400", stats.Last.Requests.Total)
+	}
+}
+
+func TestReadStatsErrors(t *testing.T) {
+	for name, content := range map[string]string{
+		"no interval records": `{"timestamp":"2025-01-18T10:16:00Z","record":"final","requests":{}}`,
+		"unknown record type": `{"timestamp":"2025-01-18T10:16:00Z","record":"summary","requests":{}}`,
+		"invalid timestamp":   `{"timestamp":"10:16","record":"interval","requests":{}}`,
+		"invalid JSON":        `timestamp,record,metric`,
+	} {
+		if _, err := ReadStats(writeFile(t, "stats.jsonl", content)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestComparisons(t *testing.T) {
+	stats, err := ReadStats(writeFile(t, "stats.jsonl", targetsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := stats.Intervals[1].TargetFailed; got["direct"] != 10 || got["proxy"] != 2 {
+		t.Errorf("failed requests per target: got %v", got)
+	}
+
+	r := New("Report", "stats.jsonl", stats, nil, nil)
+	comparisons := r.Comparisons()
+	if len(comparisons) != 2 || comparisons[0].Target != "proxy" || comparisons[0].Recovery == nil || comparisons[1].Recovery != nil {
+		t.Fatalf("got %+v, want the recovered proxy before the direct target", comparisons)
+	}
+	if got := comparisons[1].ErrorWindow(); got != 9*time.Second {
+		t.Errorf("error window: got %v", got)
+	}
+	if got := r.Charts[2]; got.Title != "Failed requests per interval by target" || len(got.Series) != 2 || got.Series[0].Name != "direct" {
+		t.Errorf("chart: got %+v", got)
+	}
+
+	var markdown bytes.Buffer
+	if err := r.Markdown(&markdown); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"| Fastest recovery | proxy (lab-rds-proxy.proxy-xyz.us-east-1.rds.amazonaws.com), max 1800 ms |",
+		"| proxy | lab-rds-proxy.proxy-xyz.us-east-1.rds.amazonaws.com | 290 | 2 | 99.31% | 1.5s | 3 | 1200.00 ms | 1800.00 ms |",
+		"| direct | lab.cluster-xyz.us-east-1.rds.amazonaws.com | 190 | 18 | 90.53% | 9s | 0 | – | – |",
+	} {
+		if !strings.Contains(markdown.String(), want) {
+			t.Errorf("markdown report is missing %q:\n%s", want, markdown.String())
+		}
+	}
+}
+
+func TestReadTimeline(t *testing.T) {
+	events, err := ReadTimeline(writeFile(t, "timeline.jsonl", timelineFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].Event != EventSwitchoverStarted {
+		t.Fatalf("events are not sorted by time: %+v", events)
+	}
+	w, ok := SwitchoverWindow(events)
+	if !ok || w.Duration() != 13*time.Second {
+		t.Errorf("switchover window: got %v (%v)", w.Duration(), ok)
+	}
+	if _, ok := SwitchoverWindow(events[:1]); ok {
+		t.Error("switchover window without a completed event")
+	}
+
+	// bgctl failover records a failover window instead
+	failover := []Event{
+		{Timestamp: at("10:15:12"), Event: EventFailoverStarted},
+		{Timestamp: at("10:15:40"), Event: EventFailoverCompleted},
+	}
+	if w, ok := FailoverWindow(failover); !ok || w.Duration() != 28*time.Second {
+		t.Errorf("failover window: got %v (%v)", w.Duration(), ok)
+	}
+	if _, ok := SwitchoverWindow(failover); ok {
+		t.Error("switchover window of a failover")
+	}
+}
+
+func TestBlueGreenEventTimeline(t *testing.T) {
+	e, err := itemEvent(map[string]types.AttributeValue{
+		"DeploymentId": &types.AttributeValueMemberS{Value: "bgd-abc123"},
+		"EventKey":     &types.AttributeValueMemberS{Value: "2025-01-18T10:15:13.250Z#RDS-EVENT-0247"},
+		"EventTime":    &types.AttributeValueMemberS{Value: "2025-01-18T10:15:13.250Z"},
+		"Event":        &types.AttributeValueMemberS{Value: "switchover-started"},
+		"Message":      &types.AttributeValueMemberS{Value: "Switchover started."},
+		"RunId":        &types.AttributeValueMemberS{Value: "minor-upgrade-20250118-101000"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Event{Timestamp: at("10:15:13.25"), Event: "rds:switchover-started", Detail: "bgd-abc123: Switchover started.", RunID: "minor-upgrade-20250118-101000"}
+	if e != want {
+		t.Errorf("got %+v, want %+v", e, want)
+	}
+
+	client, err := ReadTimeline(writeFile(t, "timeline.jsonl", timelineFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged := MergeTimelines(client, []Event{e})
+	if len(merged) != 3 || merged[1] != e {
+		t.Errorf("got %+v, want the RDS event between the client events", merged)
+	}
+	// The server-side events do not change the client-side switchover window
+	if w, ok := SwitchoverWindow(merged); !ok || !w.Start.Equal(at("10:15:12")) {
+		t.Errorf("switchover window: got %+v (%v)", w, ok)
+	}
+
+	if _, err := itemEvent(map[string]types.AttributeValue{}); err == nil {
+		t.Error("an item without an event time got no error")
+	}
+
+	other := Event{Timestamp: at("10:15:14"), Event: "rds:switchover-started", Detail: "bgd-other", RunID: "other-20250118-101000"}
+	if got := RunEvents([]Event{e, other, {Event: "rds:deployment-available"}}, e.RunID); len(got) != 1 || got[0] != e {
+		t.Errorf("RunEvents: got %+v, want only the event of the run", got)
+	}
+}
+
+func TestRender(t *testing.T) {
+	stats, err := ReadStats(writeFile(t, "stats.jsonl", statsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := ReadTimeline(writeFile(t, "timeline.jsonl", timelineFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lag := []Series{{Name: "lab-cluster", Points: []Point{{Time: at("10:15:00"), Value: 12}, {Time: at("10:16:00"), Value: 20}}}}
+	r := New("Report", "stats.jsonl", stats, events, lag)
+
+	if len(r.Charts) != 4 {
+		t.Fatalf("charts: got %d, want success, failed, insert latency and replica lag", len(r.Charts))
+	}
+
+	var markdown bytes.Buffer
+	if err := r.Markdown(&markdown); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"| Error window | 10:15:10 – 10:15:30 UTC (20s), 2 impacted interval(s) |",
+		"| Run ID | minor-upgrade-20250118-101000 |",
+		"| Statements | reuse, 24 stale statement error(s) |",
+		"| Read-after-write probe | 37 probes, 3 anomalies, 3 in intervals overlapping the switchover |",
+		"| Switchover | 10:15:12 – 10:15:25 UTC (13s) |",
+		"| Recovery time (pool) | p50 8000 ms, max 9000 ms over 4 recoveries |",
+		"| 10:15:12 | +12s | switchover-started | bgd-abc123 |",
+		"```mermaid\nxychart-beta\n    title \"Failed requests per interval\"\n    x-axis [\"10:15:10\", \"10:15:20\", \"10:15:30\", \"10:15:40\"]\n    y-axis \"requests\"\n    line [0, 10, 5, 0]\n```",
+		"Lines in order: p50, p95, p99.",
+		"| insert | 285 | 11.00 ms | 16.00 ms | 900.00 ms | 1800.00 ms | 2000.00 ms |",
+		"| pool | 4 | 8000.00 ms |",
+	} {
+		if !strings.Contains(markdown.String(), want) {
+			t.Errorf("markdown report is missing %q:\n%s", want, markdown.String())
+		}
+	}
+
+	var html bytes.Buffer
+	if err := r.HTML(&html); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(html.String(), "<svg "); got != 4 {
+		t.Errorf("html report: got %d charts, want 4", got)
+	}
+	for _, want := range []string{
+		"<h3>Aurora replica lag (maximum)</h3>",
+		`fill="#d62728" fill-opacity="0.12"`,
+		`stroke-dasharray="4 3"><title>10:15:12 switchover-started</title>`,
+		"<td>green is now the writer</td>",
+	} {
+		if !strings.Contains(html.String(), want) {
+			t.Errorf("html report is missing %q", want)
+		}
+	}
+}
+
+func TestNotification(t *testing.T) {
+	stats, err := ReadStats(writeFile(t, "stats.jsonl", statsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := ReadTimeline(writeFile(t, "timeline.jsonl", timelineFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := New("Scenario minor-upgrade", "stats.jsonl", stats, events, nil).Notification("lab-report")
+	if m.Event != EventDowntimeReport || m.RunID != "minor-upgrade-20250118-101000" {
+		t.Errorf("event %q, run %q", m.Event, m.RunID)
+	}
+	if want := "Scenario minor-upgrade: error window 10:15:10 – 10:15:30 UTC (20s), 2 impacted interval(s)"; m.Text != want {
+		t.Errorf("text %q, want %q", m.Text, want)
+	}
+	for _, f := range m.Fields {
+		if f.Name == "Run ID" {
+			t.Error("the run ID is a field")
+		}
+		if f.Name == "Switchover" && f.Value != "10:15:12 – 10:15:25 UTC (13s)" {
+			t.Errorf("switchover field %q", f.Value)
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package report
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Record is one line of the simulator's JSON Lines output
+// (--output-format json).
+type Record struct {
+	Timestamp    time.Time `json:"-"`
+	RawTimestamp string    `json:"timestamp"`
+	Record       string    `json:"record"`
+	// RunID is the simulator's --run-id; empty in the output of simulators
+	// that did not write it
+	RunID        string        `json:"runId"`
+	Requests     Requests      `json:"requests"`
+	Transactions *Transactions `json:"transactions"`
+	// Targets are the requests per endpoint when the simulator compares
+	// endpoints (--target or --dual-target)
+	Targets []TargetRequests `json:"targets"`
+	// Statements is the --statement-mode of the run and its stale statement
+	// errors; nil in the output of simulators that did not write it
+	Statements *Statements `json:"statements"`
+	// Probe holds the read-after-write probes when the simulator ran them
+	// (--probe-interval)
+	Probe    *Probe        `json:"probe"`
+	Latency  []Percentiles `json:"latency"`
+	Recovery []Percentiles `json:"recovery"`
+}
+
+// Requests holds the cumulative request counters of a record.
+type Requests struct {
+	Total       int64   `json:"total"`
+	Success     int64   `json:"success"`
+	Failed      int64   `json:"failed"`
+	SuccessRate float64 `json:"successRate"`
+}
+
+// TargetRequests holds the cumulative request counters of one endpoint the
+// simulator compares, and the time between its first and latest connection
+// error.
+type TargetRequests struct {
+	Target        string  `json:"target"`
+	Endpoint      string  `json:"endpoint"`
+	Total         int64   `json:"total"`
+	Success       int64   `json:"success"`
+	Failed        int64   `json:"failed"`
+	SuccessRate   float64 `json:"successRate"`
+	ErrorWindowMs int64   `json:"errorWindowMs"`
+}
+
+// Transactions holds the cumulative transaction outcomes of the
+// transactional workload.
+type Transactions struct {
+	Committed     int64 `json:"committed"`
+	RolledBack    int64 `json:"rolledBack"`
+	CommitUnknown int64 `json:"commitUnknown"`
+}
+
+// Statements holds the statement mode of the run and the cumulative
+// statements that failed as unknown or closed prepared statements.
+type Statements struct {
+	Mode  string `json:"mode"`
+	Stale int64  `json:"stale"`
+}
+
+// Probe holds the cumulative read-after-write probes and their anomalies.
+type Probe struct {
+	Probes    int64 `json:"probes"`
+	Anomalies int64 `json:"anomalies"`
+}
+
+// Percentiles are the latency (by operation) or recovery time (by connection
+// strategy) percentiles in milliseconds.
+type Percentiles struct {
+	Operation string  `json:"operation"`
+	Strategy  string  `json:"strategy"`
+	Count     int64   `json:"count"`
+	P50       float64 `json:"p50"`
+	P95       float64 `json:"p95"`
+	P99       float64 `json:"p99"`
+	P999      float64 `json:"p999"`
+	Max       float64 `json:"max"`
+}
+
+// Name returns the operation or strategy the percentiles belong to.
+func (p Percentiles) Name() string {
+	if p.Operation != "" {
+		return p.Operation
+	}
+	return p.Strategy
+}
+
+// Interval is the activity between two consecutive interval records.
+type Interval struct {
+	Start   time.Time
+	End     time.Time
+	Success int64
+	Failed  int64
+	// TargetFailed are the failed requests per compared endpoint
+	TargetFailed map[string]int64
+	// ProbeAnomalies are the read-after-write anomalies of the interval
+	ProbeAnomalies int64
+	Latency        []Percentiles
+}
+
+// Impacted reports whether requests failed or none succeeded in the interval.
+func (i Interval) Impacted() bool {
+	return i.Failed > 0 || i.Success == 0
+}
+
+// Stats is the simulator output of one run.
+type Stats struct {
+	Intervals []Interval
+	// Final is the final record; nil when the simulator did not shut down
+	// cleanly after its last (re)start
+	Final *Record
+	// Last is the last record of the file, holding the run's counters
+	Last *Record
+}
+
+// RunID returns the run ID of the last record; a resumed run keeps its run ID.
+func (s *Stats) RunID() string {
+	return s.Last.RunID
+}
+
+// Start returns the start of the first interval.
+func (s *Stats) Start() time.Time {
+	return s.Intervals[0].Start
+}
+
+// End returns the end of the last interval.
+func (s *Stats) End() time.Time {
+	return s.Intervals[len(s.Intervals)-1].End
+}
+
+// Window is a period of the run.
+type Window struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Duration returns the length of the window.
+func (w Window) Duration() time.Duration {
+	return w.End.Sub(w.Start)
+}
+
+// ReadStats reads the simulator's JSON Lines output. A resumed run appends to
+// the same file and its counters stay cumulative across restarts, so only the
+// last record describes the whole run.
+func ReadStats(path string) (*Stats, error) {
+	var records []Record
+	err := readJSONLines(path, func(data []byte) error {
+		var r Record
+		if err := json.Unmarshal(data, &r); err != nil {
+			return err
+		}
+		ts, err := parseTimestamp(r.RawTimestamp)
+		if err != nil {
+			return err
+		}
+		r.Timestamp = ts
+		records = append(records, r)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &Stats{}
+	var samples []Record
+	for i := range records {
+		switch records[i].Record {
+		case "interval":
+			samples = append(samples, records[i])
+		case "final":
+		default:
+			return nil, fmt.Errorf("%s: unknown record type %q", path, records[i].Record)
+		}
+	}
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("%s: no interval records (was the simulator run with --output-format json?)", path)
+	}
+	stats.Last = &records[len(records)-1]
+	if stats.Last.Record == "final" {
+		stats.Final = stats.Last
+	}
+	stats.Intervals = intervals(samples)
+	return stats, nil
+}
+
+// intervals converts cumulative interval records into per-interval counts.
+func intervals(samples []Record) []Interval {
+	result := make([]Interval, len(samples))
+	for i, s := range samples {
+		current := Interval{
+			End:     s.Timestamp,
+			Success: s.Requests.Success,
+			Failed:  s.Requests.Failed,
+			Latency: s.Latency,
+		}
+		if s.Probe != nil {
+			current.ProbeAnomalies = s.Probe.Anomalies
+		}
+		for _, t := range s.Targets {
+			if current.TargetFailed == nil {
+				current.TargetFailed = map[string]int64{}
+			}
+			current.TargetFailed[t.Target] = t.Failed
+		}
+		if i > 0 {
+			current.Start = samples[i-1].Timestamp
+			current.Success -= samples[i-1].Requests.Success
+			current.Failed -= samples[i-1].Requests.Failed
+			if p := samples[i-1].Probe; p != nil && s.Probe != nil {
+				current.ProbeAnomalies -= p.Anomalies
+			}
+			for _, t := range samples[i-1].Targets {
+				if _, ok := current.TargetFailed[t.Target]; ok {
+					current.TargetFailed[t.Target] -= t.Failed
+				}
+			}
+		} else if len(samples) > 1 {
+			// The run started one log interval before the first record
+			current.Start = s.Timestamp.Add(-samples[1].Timestamp.Sub(s.Timestamp))
+		} else {
+			current.Start = s.Timestamp
+		}
+		result[i] = current
+	}
+	return result
+}
+
+// ProbeAnomalies returns the read-after-write anomalies of the intervals that
+// overlap w.
+func ProbeAnomalies(intervals []Interval, w Window) int64 {
+	var anomalies int64
+	for _, i := range intervals {
+		if i.End.After(w.Start) && i.Start.Before(w.End) {
+			anomalies += i.ProbeAnomalies
+		}
+	}
+	return anomalies
+}
+
+// ErrorWindow returns the period from the start of the first to the end of
+// the last impacted interval, and the number of impacted intervals. Its
+// resolution is the simulator's log interval.
+func ErrorWindow(intervals []Interval) (Window, int, bool) {
+	var w Window
+	impacted := 0
+	for _, i := range intervals {
+		if !i.Impacted() {
+			continue
+		}
+		if impacted == 0 {
+			w.Start = i.Start
+		}
+		w.End = i.End
+		impacted++
+	}
+	return w, impacted, impacted > 0
+}
+
+func readJSONLines(path string, parse func(data []byte) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := strings.TrimSpace(scanner.Text())
+		if data == "" {
+			continue
+		}
+		if err := parse([]byte(data)); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// parseTimestamp parses RFC 3339 timestamps, including Java's
+// OffsetDateTime format that omits zero seconds.
+func parseTimestamp(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, fmt.Errorf("missing timestamp")
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04Z07:00"} {
+		if ts, err := time.Parse(layout, value); err == nil {
+			return ts, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q", value)
+}
@@ -0,0 +1,77 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Event is one step of a switchover timeline. Timelines are JSON Lines files
+// with one event per line.
+type Event struct {
+	Timestamp time.Time `json:"timestamp"`
+	Event     string    `json:"event"`
+	Detail    string    `json:"detail,omitempty"`
+	// RunID is the run the event belongs to, when known
+	RunID string `json:"runId,omitempty"`
+}
+
+// Timeline events that bound the switchover window, and the failover window
+// of bgctl failover.
+const (
+	EventSwitchoverStarted   = "switchover-started"
+	EventSwitchoverCompleted = "switchover-completed"
+	EventFailoverStarted     = "failover-started"
+	EventFailoverCompleted   = "failover-completed"
+)
+
+// ReadTimeline reads a switchover timeline and sorts its events by time.
+func ReadTimeline(path string) ([]Event, error) {
+	var events []Event
+	err := readJSONLines(path, func(data []byte) error {
+		var e Event
+		if err := json.Unmarshal(data, &e); err != nil {
+			return err
+		}
+		if e.Timestamp.IsZero() {
+			return fmt.Errorf("missing timestamp")
+		}
+		if e.Event == "" {
+			return fmt.Errorf("missing event")
+		}
+		events = append(events, e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Timestamp.Before(events[j].Timestamp) })
+	return events, nil
+}
+
+// SwitchoverWindow returns the period between the switchover-started and
+// switchover-completed events of the timeline.
+func SwitchoverWindow(events []Event) (Window, bool) {
+	return eventWindow(events, EventSwitchoverStarted, EventSwitchoverCompleted)
+}
+
+// FailoverWindow returns the period between the failover-started and
+// failover-completed events of the timeline.
+func FailoverWindow(events []Event) (Window, bool) {
+	return eventWindow(events, EventFailoverStarted, EventFailoverCompleted)
+}
+
+func eventWindow(events []Event, start, end string) (Window, bool) {
+	var w Window
+	var started, completed bool
+	for _, e := range events {
+		switch e.Event {
+		case start:
+			w.Start, started = e.Timestamp, true
+		case end:
+			w.End, completed = e.Timestamp, true
+		}
+	}
+	return w, started && completed && !w.End.Before(w.Start)
+}
@@ -0,0 +1,56 @@
+// Package runid names the lab's experiment runs. A run ID is generated once,
+// by the tool starting the run (lab-scenario, or a bgctl command run on its
+// own), and carried by everything taking part in the run, so the client-side
+// and server-side data of the run can be joined on it:
+//
+//   - lab-scenario passes it to the simulator (--run-id), which writes it to
+//     its statistics, Prometheus and CloudWatch metrics and registry item
+//   - bgctl commands join it with -run-id or the LAB_RUN_ID environment
+//     variable and tag the Blue/Green deployments they create with it
+//   - the monitoring stack's event recorder stores the RunId tag of the
+//     deployment with every RDS Blue/Green event
+//   - lab-report reads the run ID from the simulator statistics and keeps
+//     the RDS events of that run only
+package runid
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+)
+
+// Tag is the tag key of the run ID on AWS resources, and the attribute name
+// of the run ID in the lab's DynamoDB tables.
+const Tag = "RunId"
+
+// EnvVar is the environment variable the tools read the run ID to join from.
+const EnvVar = "LAB_RUN_ID"
+
+// timeFormat is the time suffix of generated run IDs.
+const timeFormat = "20060102-150405"
+
+// pattern matches the run IDs safe as tag values, DynamoDB keys, file names
+// and simulator arguments.
+var pattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,127}$`)
+
+// New returns a new run ID "<prefix>-<time>", e.g. minor-upgrade-20260102-150405
+// for a run of the minor-upgrade scenario started at t.
+func New(prefix string, t time.Time) string {
+	return prefix + "-" + t.UTC().Format(timeFormat)
+}
+
+// Validate checks that id is usable as a run ID: at most 128 letters, digits
+// and ._- characters, starting with a letter or digit.
+func Validate(id string) error {
+	if !pattern.MatchString(id) {
+		return fmt.Errorf("invalid run ID %q: run IDs are at most 128 letters, digits and ._- characters, starting with a letter or digit", id)
+	}
+	return nil
+}
+
+// FromEnv returns the run ID of the LAB_RUN_ID environment variable; empty
+// when it is not set.
+func FromEnv() string {
+	return os.Getenv(EnvVar)
+}
@@ -0,0 +1,38 @@
+package runid
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	started := time.Date(2026, 1, 2, 16, 4, 5, 0, time.FixedZone("CET", 3600))
+	id := New("minor-upgrade", started)
+	if id != "minor-upgrade-20260102-150405" {
+		t.Errorf("got %q", id)
+	}
+	if err := Validate(id); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, id := range []string{"switchover-20260102-150405", "sim-1", "2024.11.19_upgrade"} {
+		if err := Validate(id); err != nil {
+			t.Errorf("%q: %v", id, err)
+		}
+	}
+	for _, id := range []string{"", "-flag", "two words", "run;rm", "run/1", strings.Repeat("a", 129)} {
+		if err := Validate(id); err == nil {
+			t.Errorf("%q got no error", id)
+		}
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv(EnvVar, "upgrade-20260102-150405")
+	if got := FromEnv(); got != "upgrade-20260102-150405" {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,255 @@
+// Package schemachange checks DDL meant for the green environment of a
+// Blue/Green deployment before it runs.
+//
+// The green cluster is a binary log replica of the blue cluster until the
+// switchover, so schema changes on green must leave every replicated row
+// applicable: new tables, new indexes and columns added at the end of a
+// table are safe; dropping, renaming or retyping columns and tables, moving
+// columns, unique constraints and writing data on green are not. Split splits
+// a script into statements and Check rejects the ones replication cannot
+// survive, with the reason.
+package schemachange
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Split splits a SQL script into its statements, without comments and
+// trailing semicolons.
+func Split(script string) ([]string, error) {
+	var statements []string
+	var current strings.Builder
+	flush := func() {
+		if s := strings.TrimSpace(current.String()); s != "" {
+			statements = append(statements, s)
+		}
+		current.Reset()
+	}
+
+	runes := []rune(script)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		next := rune(0)
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+		switch {
+		case r == '\'' || r == '"' || r == '`':
+			end := closingQuote(runes, i)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated %c quote in statement %q", r, strings.TrimSpace(current.String()))
+			}
+			current.WriteString(string(runes[i : end+1]))
+			i = end
+		case r == '#' || (r == '-' && next == '-' && (i+2 >= len(runes) || runes[i+2] == ' ' || runes[i+2] == '\t' || runes[i+2] == '\n')):
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			current.WriteRune('\n')
+		case r == '/' && next == '*':
+			end := strings.Index(string(runes[i+2:]), "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated /* comment")
+			}
+			i += 2 + len([]rune(string(runes[i+2:])[:end])) + 1
+			current.WriteRune(' ')
+		case r == ';':
+			flush()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	flush()
+	return statements, nil
+}
+
+// Validate splits the script and checks every statement. It returns the
+// statements, or an error listing each unsafe one with its reason.
+func Validate(script string) ([]string, error) {
+	statements, err := Split(script)
+	if err != nil {
+		return nil, err
+	}
+	if len(statements) == 0 {
+		return nil, fmt.Errorf("no SQL statements")
+	}
+	var problems []string
+	for _, statement := range statements {
+		if err := Check(statement); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", abbreviate(statement), err))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%d of %d statements would break replication from the blue cluster:\n  - %s",
+			len(problems), len(statements), strings.Join(problems, "\n  - "))
+	}
+	return statements, nil
+}
+
+// abbreviate shortens a statement to one line of at most 60 characters.
+func abbreviate(statement string) string {
+	s := strings.Join(strings.Fields(statement), " ")
+	if r := []rune(s); len(r) > 60 {
+		return string(r[:57]) + "..."
+	}
+	return s
+}
+
+// closingQuote returns the index of the quote closing the one at start, or
+// -1. Doubled quotes and, except in identifiers, backslashes escape it.
+func closingQuote(runes []rune, start int) int {
+	quote := runes[start]
+	for i := start + 1; i < len(runes); i++ {
+		switch {
+		case runes[i] == '\\' && quote != '`':
+			i++
+		case runes[i] == quote && i+1 < len(runes) && runes[i+1] == quote:
+			i++
+		case runes[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// Check returns an error explaining why the statement is not safe to run on
+// the green environment, or nil.
+func Check(statement string) error {
+	words := strings.Fields(mask(statement))
+	switch {
+	case len(words) == 0:
+		return nil
+	case hasPrefix(words, "CREATE", "TABLE"), hasPrefix(words, "CREATE", "TEMPORARY", "TABLE"):
+		if contains(words, "SELECT") {
+			return fmt.Errorf("CREATE TABLE ... SELECT writes data on green")
+		}
+		return nil
+	case hasPrefix(words, "CREATE", "INDEX"), hasPrefix(words, "CREATE", "FULLTEXT", "INDEX"), hasPrefix(words, "CREATE", "SPATIAL", "INDEX"),
+		hasPrefix(words, "DROP", "INDEX"):
+		return nil
+	case hasPrefix(words, "CREATE", "UNIQUE"):
+		return errUnique
+	case hasPrefix(words, "ALTER", "TABLE"):
+		return checkAlterTable(statement)
+	case hasPrefix(words, "DROP", "TABLE"), hasPrefix(words, "DROP", "DATABASE"), hasPrefix(words, "DROP", "SCHEMA"), hasPrefix(words, "TRUNCATE"):
+		return fmt.Errorf("%s removes tables or rows that replication from the blue cluster still writes to", words[0])
+	case hasPrefix(words, "RENAME"):
+		return fmt.Errorf("RENAME TABLE breaks replication, which keeps writing to the old name")
+	}
+	switch words[0] {
+	case "INSERT", "UPDATE", "DELETE", "REPLACE", "LOAD", "CALL":
+		return fmt.Errorf("%s writes data on green; the green environment may only receive the blue cluster's replicated writes", words[0])
+	}
+	return fmt.Errorf("%s is not a replication-safe schema change (allowed: CREATE TABLE, CREATE INDEX, DROP INDEX, ALTER TABLE adding columns at the end or indexes)", strings.Join(words[:min(2, len(words))], " "))
+}
+
+var errUnique = fmt.Errorf("a unique index fails replicated writes that duplicate its key")
+
+// checkAlterTable checks each clause of an ALTER TABLE statement.
+func checkAlterTable(statement string) error {
+	// The clauses follow ALTER TABLE <name>
+	rest := mask(statement)
+	for i := 0; i < 3; i++ {
+		rest = strings.TrimLeft(rest, " \t\r\n")
+		end := strings.IndexAny(rest, " \t\r\n")
+		if end < 0 {
+			return fmt.Errorf("ALTER TABLE without changes")
+		}
+		rest = rest[end:]
+	}
+	for _, clause := range splitClauses(rest) {
+		c := strings.Fields(strings.ReplaceAll(strings.ReplaceAll(clause, "(", " ( "), "=", " = "))
+		if len(c) == 0 {
+			continue
+		}
+		switch {
+		case hasPrefix(c, "ADD", "INDEX"), hasPrefix(c, "ADD", "KEY"), hasPrefix(c, "ADD", "FULLTEXT"), hasPrefix(c, "ADD", "SPATIAL"),
+			hasPrefix(c, "DROP", "INDEX"), hasPrefix(c, "DROP", "KEY"), hasPrefix(c, "ALTER", "INDEX"),
+			hasPrefix(c, "ALGORITHM"), hasPrefix(c, "LOCK"), hasPrefix(c, "COMMENT"):
+		case hasPrefix(c, "ADD", "UNIQUE"), hasPrefix(c, "ADD", "CONSTRAINT"):
+			return errUnique
+		case hasPrefix(c, "ADD", "PRIMARY"), hasPrefix(c, "DROP", "PRIMARY"):
+			return fmt.Errorf("changing the primary key changes how replicated rows are found")
+		case hasPrefix(c, "ADD", "FOREIGN"), hasPrefix(c, "ADD", "CHECK"):
+			return fmt.Errorf("a constraint on green can reject replicated writes")
+		case hasPrefix(c, "ADD"):
+			if contains(c, "FIRST") || contains(c, "AFTER") {
+				return fmt.Errorf("a column added with FIRST or AFTER shifts the columns of replicated rows; add it at the end of the table")
+			}
+		case hasPrefix(c, "ALTER"):
+			// ALTER [COLUMN] name SET DEFAULT / DROP DEFAULT
+			if !contains(c, "DEFAULT") {
+				return fmt.Errorf("ALTER TABLE ... %s is not a replication-safe change", strings.Join(c, " "))
+			}
+		case hasPrefix(c, "DROP"):
+			return fmt.Errorf("dropping a column or constraint breaks replicated rows that still carry it")
+		case hasPrefix(c, "MODIFY"), hasPrefix(c, "CHANGE"), hasPrefix(c, "RENAME"), hasPrefix(c, "CONVERT"):
+			return fmt.Errorf("%s changes a column's or table's name, type or position that replicated rows depend on", c[0])
+		default:
+			return fmt.Errorf("ALTER TABLE ... %s is not a replication-safe change (allowed: ADD COLUMN at the end, ADD/DROP INDEX)", c[0])
+		}
+	}
+	return nil
+}
+
+// splitClauses splits the clauses of an ALTER TABLE statement at the commas
+// outside parentheses.
+func splitClauses(s string) []string {
+	var clauses []string
+	depth, start := 0, 0
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				clauses = append(clauses, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(clauses, s[start:])
+}
+
+// mask upper-cases the statement with the contents of its quoted strings and
+// identifiers blanked, so names and values cannot look like keywords.
+func mask(statement string) string {
+	runes := []rune(statement)
+	for i := 0; i < len(runes); i++ {
+		if r := runes[i]; r == '\'' || r == '"' || r == '`' {
+			end := closingQuote(runes, i)
+			if end < 0 {
+				end = len(runes) - 1
+			}
+			for j := i + 1; j < end; j++ {
+				runes[j] = 'x'
+			}
+			i = end
+		}
+	}
+	return strings.ToUpper(string(runes))
+}
+
+func hasPrefix(words []string, prefix ...string) bool {
+	if len(words) < len(prefix) {
+		return false
+	}
+	for i, p := range prefix {
+		if words[i] != p {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(words []string, word string) bool {
+	for _, w := range words {
+		if w == word {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package schemachange
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	got, err := Split(`-- add the note column
+ALTER TABLE orders ADD COLUMN note varchar(64) DEFAULT 'a;b' COMMENT "it's";
+/* index; for lookups */ CREATE INDEX idx_note ON orders (note) ;
+# trailing statement without semicolon
+CREATE TABLE ` + "`audit;log`" + ` (id bigint PRIMARY KEY)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`ALTER TABLE orders ADD COLUMN note varchar(64) DEFAULT 'a;b' COMMENT "it's"`,
+		`CREATE INDEX idx_note ON orders (note)`,
+		"CREATE TABLE `audit;log` (id bigint PRIMARY KEY)",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	for _, script := range []string{"ALTER TABLE t COMMENT 'unterminated", "CREATE INDEX i ON t (c) /* open"} {
+		if _, err := Split(script); err == nil {
+			t.Errorf("%q: expected an error", script)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	allowed := []string{
+		"CREATE TABLE audit_log (id bigint PRIMARY KEY, note text)",
+		"create index idx_note on orders (note)",
+		"CREATE FULLTEXT INDEX ft_note ON orders (note)",
+		"DROP INDEX idx_note ON orders",
+		"ALTER TABLE orders ADD COLUMN note varchar(64) NULL, ADD INDEX idx_note (note), ALGORITHM=INSTANT",
+		"ALTER TABLE orders ADD note varchar(64) DEFAULT 'first', LOCK=NONE",
+		"ALTER TABLE orders ALTER COLUMN status SET DEFAULT 'new'",
+		"ALTER TABLE orders ALTER INDEX idx_note INVISIBLE, COMMENT = 'drop after'",
+		"ALTER TABLE `drop` ADD COLUMN `after` int",
+		"ALTER TABLE t ADD COLUMN a int",
+	}
+	for _, statement := range allowed {
+		if err := Check(statement); err != nil {
+			t.Errorf("%q: %v", statement, err)
+		}
+	}
+
+	rejected := map[string]string{
+		"INSERT INTO orders VALUES (1)":                                     "writes data",
+		"UPDATE orders SET note = 'x'":                                      "writes data",
+		"CREATE TABLE copy AS SELECT * FROM orders":                         "writes data",
+		"DROP TABLE orders":                                                 "removes tables",
+		"TRUNCATE TABLE orders":                                             "removes tables",
+		"RENAME TABLE orders TO orders_old":                                 "RENAME",
+		"CREATE UNIQUE INDEX u ON orders (note)":                            "unique index",
+		"ALTER TABLE orders ADD COLUMN note text AFTER id":                  "FIRST or AFTER",
+		"ALTER TABLE orders ADD UNIQUE KEY u (note)":                        "unique index",
+		"ALTER TABLE orders ADD INDEX i (note), DROP COLUMN status":         "dropping a column",
+		"ALTER TABLE orders MODIFY COLUMN amount decimal(12,2)":             "MODIFY",
+		"ALTER TABLE orders CHANGE note memo text":                          "CHANGE",
+		"ALTER TABLE orders ADD FOREIGN KEY (customer_id) REFERENCES c(id)": "constraint",
+		"ALTER TABLE orders DROP PRIMARY KEY":                               "primary key",
+		"ALTER TABLE orders ENGINE=MyISAM":                                  "ENGINE",
+		"ALTER TABLE orders":                                                "without changes",
+		"SET GLOBAL read_only = 0":                                          "not a replication-safe",
+	}
+	for statement, want := range rejected {
+		err := Check(statement)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: got %v, want an error containing %q", statement, err, want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	statements, err := Validate("ALTER TABLE orders ADD COLUMN note text;\nCREATE INDEX idx_note ON orders (note(16));\n")
+	if err != nil || len(statements) != 2 {
+		t.Fatalf("got %q, %v", statements, err)
+	}
+
+	_, err = Validate("ALTER TABLE orders ADD COLUMN note text;\nDROP TABLE customers;\nUPDATE orders SET note = 'a much longer value than fits on one line of the error message'")
+	want := `2 of 3 statements would break replication from the blue cluster:
+  - DROP TABLE customers: DROP removes tables or rows that replication from the blue cluster still writes to
+  - UPDATE orders SET note = 'a much longer value than fits o...: UPDATE writes data on green; the green environment may only receive the blue cluster's replicated writes`
+	if err == nil || err.Error() != want {
+		t.Errorf("got %v, want %s", err, want)
+	}
+
+	if _, err := Validate("-- nothing yet\n"); err == nil {
+		t.Error("expected an error for a script without statements")
+	}
+}
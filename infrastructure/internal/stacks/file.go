@@ -0,0 +1,149 @@
+package stacks
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+)
+
+// File is the consolidated outputs file lab-deploy writes after a
+// deployment: the outputs of every deployed stack by component, so the
+// commands operating on the lab and scripts can read them without Pulumi.
+// Secret outputs are left out.
+type File struct {
+	Org   string `json:"org"`
+	Stack string `json:"stack"`
+	// Outputs are the outputs by component (e.g. "aurora") and output name
+	Outputs map[string]map[string]interface{} `json:"outputs"`
+}
+
+// NewFile collects the outputs of the components' stacks.
+func NewFile(org, stack string, outputs map[string]auto.OutputMap) *File {
+	f := &File{Org: org, Stack: stack, Outputs: map[string]map[string]interface{}{}}
+	for component, values := range outputs {
+		f.Outputs[component] = map[string]interface{}{}
+		for key, value := range values {
+			if !value.Secret {
+				f.Outputs[component][key] = value.Value
+			}
+		}
+	}
+	return f
+}
+
+// ReadFile reads a file written by WriteJSON.
+func ReadFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f := &File{}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// WriteJSON writes the file as indented JSON.
+func (f *File) WriteJSON(path string) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
+
+// simulatorEnv maps the environment variables of the workload simulator
+// image (workload-simulator/Dockerfile) to the outputs they are set from, so
+// the env file can be passed to docker run --env-file as is.
+var simulatorEnv = []struct{ name, component, key string }{
+	{"AURORA_ENDPOINT", "aurora", "clusterEndpoint"},
+	{"DATABASE_NAME", "aurora", "databaseName"},
+	{"USERNAME", "aurora", "masterUsername"},
+}
+
+// Env returns the outputs as KEY=value lines named COMPONENT_OUTPUT_NAME
+// (e.g. AURORA_CLUSTER_ENDPOINT), followed by the workload simulator image's
+// variables. Lists are joined with commas and maps left out; values that the
+// shell would split are single-quoted, so the file can be sourced.
+func (f *File) Env() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Outputs of the %s lab stacks, written by lab-deploy\n", f.Stack)
+	components := make([]string, 0, len(f.Outputs))
+	for component := range f.Outputs {
+		components = append(components, component)
+	}
+	sort.Strings(components)
+	for _, component := range components {
+		keys := make([]string, 0, len(f.Outputs[component]))
+		for key := range f.Outputs[component] {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if value, ok := envValue(f.Outputs[component][key]); ok {
+				fmt.Fprintf(&b, "%s=%s\n", envName(component, key), value)
+			}
+		}
+	}
+
+	b.WriteString("\n# Workload simulator image (docker run --env-file)\n")
+	for _, v := range simulatorEnv {
+		if value, ok := envValue(f.Outputs[v.component][v.key]); ok {
+			fmt.Fprintf(&b, "%s=%s\n", v.name, value)
+		}
+	}
+	return b.String()
+}
+
+// WriteEnv writes the Env lines to path.
+func (f *File) WriteEnv(path string) error {
+	return os.WriteFile(path, []byte(f.Env()), 0o600)
+}
+
+// envName returns the variable name of a component's output: clusterEndpoint
+// of aurora is AURORA_CLUSTER_ENDPOINT.
+func envName(component, key string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToUpper(component))
+	b.WriteByte('_')
+	for i, r := range key {
+		if i > 0 && unicode.IsUpper(r) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// envValue formats an output value, reporting false for values without a
+// single-line form.
+func envValue(value interface{}) (string, bool) {
+	var s string
+	switch v := value.(type) {
+	case nil, map[string]interface{}:
+		return "", false
+	case string:
+		s = v
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = fmt.Sprint(item)
+		}
+		s = strings.Join(items, ",")
+	default:
+		s = fmt.Sprint(v)
+	}
+	if strings.ContainsRune(s, '\n') {
+		return "", false
+	}
+	if strings.ContainsAny(s, " \t\"'$`\\#;&|<>()*?[]{}!~") {
+		s = "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+	}
+	return s, true
+}
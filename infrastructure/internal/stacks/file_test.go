@@ -0,0 +1,79 @@
+package stacks
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+)
+
+func testFile() *File {
+	return NewFile("org", "dev", map[string]auto.OutputMap{
+		"aurora": {
+			"clusterEndpoint":    {Value: "lab.cluster-abc.us-east-1.rds.amazonaws.com"},
+			"clusterPort":        {Value: float64(3306)},
+			"databaseName":       {Value: "lab_db"},
+			"masterUsername":     {Value: "admin"},
+			"writerMysqlCommand": {Value: "mysql -h lab -P 3306 -u admin -p lab_db"},
+			"connectionStrings":  {Value: map[string]interface{}{"writer": map[string]interface{}{}}},
+			"masterPassword":     {Value: "hunter2", Secret: true},
+		},
+		"ec2": {
+			"instanceIds": {Value: []interface{}{"i-1", "i-2"}},
+		},
+	})
+}
+
+func TestFileEnv(t *testing.T) {
+	env := testFile().Env()
+	for _, want := range []string{
+		"AURORA_CLUSTER_ENDPOINT=lab.cluster-abc.us-east-1.rds.amazonaws.com\n",
+		"AURORA_CLUSTER_PORT=3306\n",
+		"AURORA_WRITER_MYSQL_COMMAND='mysql -h lab -P 3306 -u admin -p lab_db'\n",
+		"EC2_INSTANCE_IDS=i-1,i-2\n",
+		"AURORA_ENDPOINT=lab.cluster-abc.us-east-1.rds.amazonaws.com\n",
+		"DATABASE_NAME=lab_db\n",
+		"USERNAME=admin\n",
+	} {
+		if !strings.Contains(env, want) {
+			t.Errorf("env is missing %q:\n%s", want, env)
+		}
+	}
+	for _, unwanted := range []string{"CONNECTION_STRINGS", "hunter2"} {
+		if strings.Contains(env, unwanted) {
+			t.Errorf("env contains %q:\n%s", unwanted, env)
+		}
+	}
+}
+
+func TestEnvValueQuotes(t *testing.T) {
+	if got, _ := envValue("it's"); got != `'it'\''s'` {
+		t.Errorf("envValue() = %s", got)
+	}
+	if _, ok := envValue("two\nlines"); ok {
+		t.Error("envValue() formatted a multi-line value")
+	}
+}
+
+func TestReaderFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lab-outputs.json")
+	if err := testFile().WriteJSON(path); err != nil {
+		t.Fatal(err)
+	}
+	reader := &Reader{File: path}
+	aurora, err := reader.Outputs(context.Background(), "aurora")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := aurora.String("clusterEndpoint"); got != "lab.cluster-abc.us-east-1.rds.amazonaws.com" {
+		t.Errorf("clusterEndpoint = %q", got)
+	}
+	if _, ok := aurora["masterPassword"]; ok {
+		t.Error("the file contains a secret output")
+	}
+	if _, err := reader.Outputs(context.Background(), "monitoring"); err == nil || !strings.Contains(err.Error(), "no monitoring outputs") {
+		t.Errorf("Outputs(monitoring) error = %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+// Package stacks reads the outputs of the deployed lab stacks with the Pulumi
+// Automation API, or from the consolidated outputs file lab-deploy writes,
+// for the commands that operate on a running lab (lab-scenario, bgctl).
+package stacks
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+)
+
+// projects maps each component directory to its Pulumi project name.
+var projects = map[string]string{
+	"vpc":        "aurora-bluegreen-vpc",
+	"aurora":     "aurora-bluegreen-aurora",
+	"ec2":        "aurora-bluegreen-ec2",
+	"monitoring": "aurora-bluegreen-monitoring",
+	"ops":        "aurora-bluegreen-ops",
+	"scheduler":  "aurora-bluegreen-scheduler",
+	"dms":        "aurora-bluegreen-dms",
+	"fis":        "aurora-bluegreen-fis",
+	"budget":     "aurora-bluegreen-budget",
+	"access":     "aurora-bluegreen-access",
+	"registry":   "aurora-bluegreen-registry",
+}
+
+// Reader reads the outputs of one lab stack name (e.g. dev) across the
+// component projects.
+type Reader struct {
+	// InfraDir is the infrastructure directory containing the projects
+	InfraDir string
+	// Org is the Pulumi organization; empty uses 'pulumi whoami'
+	Org string
+	// Stack is the stack name shared by the component stacks
+	Stack string
+	// File, when set, is an outputs file written by lab-deploy (see File),
+	// read instead of the stacks
+	File string
+}
+
+// Outputs are the outputs of one stack.
+type Outputs auto.OutputMap
+
+// String returns a string output, or "" if the stack has no such output.
+func (o Outputs) String(key string) string {
+	value, _ := o[key].Value.(string)
+	return value
+}
+
+// Outputs returns the outputs of the component's stack, e.g. "aurora".
+func (r *Reader) Outputs(ctx context.Context, component string) (Outputs, error) {
+	project, ok := projects[component]
+	if !ok {
+		return nil, fmt.Errorf("unknown lab component %q", component)
+	}
+	if r.File != "" {
+		return r.fileOutputs(component)
+	}
+	stack, err := r.selectStack(ctx, component, project)
+	if err != nil {
+		return nil, err
+	}
+	out, err := stack.Outputs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("reading outputs of %s: %w", stack.Name(), err)
+	}
+	return Outputs(out), nil
+}
+
+// SetConfig sets a config value of the component's stack, e.g.
+// greenEngineVersion of "aurora", in the stack's Pulumi.<stack>.yaml; it
+// takes effect with the stack's next pulumi up. The outputs file cannot be
+// written to, so File is ignored.
+func (r *Reader) SetConfig(ctx context.Context, component, key, value string) error {
+	project, ok := projects[component]
+	if !ok {
+		return fmt.Errorf("unknown lab component %q", component)
+	}
+	stack, err := r.selectStack(ctx, component, project)
+	if err != nil {
+		return err
+	}
+	if err := stack.SetConfig(ctx, key, auto.ConfigValue{Value: value}); err != nil {
+		return fmt.Errorf("setting %s of %s: %w", key, stack.Name(), err)
+	}
+	return nil
+}
+
+// selectStack selects the component's stack from its project directory.
+func (r *Reader) selectStack(ctx context.Context, component, project string) (auto.Stack, error) {
+	infraDir, err := filepath.Abs(r.InfraDir)
+	if err != nil {
+		return auto.Stack{}, err
+	}
+	workDir := filepath.Join(infraDir, component)
+
+	if r.Org == "" {
+		ws, err := auto.NewLocalWorkspace(ctx, auto.WorkDir(workDir))
+		if err != nil {
+			return auto.Stack{}, fmt.Errorf("creating workspace for %s: %w", component, err)
+		}
+		if r.Org, err = ws.WhoAmI(ctx); err != nil {
+			return auto.Stack{}, fmt.Errorf("determining Pulumi organization (are you logged in?): %w", err)
+		}
+	}
+	stackName := auto.FullyQualifiedStackName(r.Org, project, r.Stack)
+	stack, err := auto.SelectStackLocalSource(ctx, stackName, workDir)
+	if err != nil {
+		return auto.Stack{}, fmt.Errorf("selecting stack %s: %w", stackName, err)
+	}
+	return stack, nil
+}
+
+// fileOutputs returns the component's outputs from the outputs file.
+func (r *Reader) fileOutputs(component string) (Outputs, error) {
+	f, err := ReadFile(r.File)
+	if err != nil {
+		return nil, err
+	}
+	values, ok := f.Outputs[component]
+	if !ok {
+		return nil, fmt.Errorf("%s has no %s outputs (was the %s stack deployed?)", r.File, component, component)
+	}
+	out := Outputs{}
+	for key, value := range values {
+		out[key] = auto.OutputValue{Value: value}
+	}
+	return out, nil
+}
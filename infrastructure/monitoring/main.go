@@ -0,0 +1,647 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/cloudwatch"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/dynamodb"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/lambda"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/rds"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/sns"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+
+	"aurora-bluegreen-lab/internal/components"
+	labconfig "aurora-bluegreen-lab/internal/config"
+	"aurora-bluegreen-lab/internal/cost"
+	"aurora-bluegreen-lab/internal/golambda"
+	"aurora-bluegreen-lab/internal/labels"
+	"aurora-bluegreen-lab/internal/providers"
+)
+
+// dashboardTargets holds the resolved identifiers the dashboard widgets refer to.
+type dashboardTargets struct {
+	region            string
+	clusterIdentifier string
+	writerInstanceId  string
+	readerInstanceId  string
+	ec2InstanceId     string
+	// simulatorNamespace is the simulator's custom metric namespace; empty
+	// omits the simulator widgets
+	simulatorNamespace string
+	period             int
+	annotations        []labconfig.EventAnnotation
+}
+
+// instanceAlarm describes a CloudWatch alarm created for each Aurora instance.
+type instanceAlarm struct {
+	suffix      string
+	metricName  string
+	comparison  string
+	threshold   float64
+	description string
+	readerOnly  bool
+}
+
+func main() {
+	pulumi.Run(func(ctx *pulumi.Context) error {
+		// Load configuration
+		cfg := config.New(ctx, "")
+		settings, err := labconfig.LoadMonitoring(cfg)
+		if err != nil {
+			return err
+		}
+
+		lb, err := labels.New(ctx, cfg)
+		if err != nil {
+			return err
+		}
+
+		// Create the AWS provider for the stack's region
+		provider, region, err := providers.New(ctx, cfg, lb)
+		if err != nil {
+			return err
+		}
+		inRegion := pulumi.Provider(provider)
+
+		// Reference Aurora stack outputs
+		auroraStackRef, err := pulumi.NewStackReference(ctx, settings.AuroraStackName, nil)
+		if err != nil {
+			return err
+		}
+
+		clusterIdentifier := auroraStackRef.GetStringOutput(pulumi.String("clusterIdentifier"))
+		writerInstanceId := auroraStackRef.GetStringOutput(pulumi.String("writerInstanceId"))
+		readerInstanceId := auroraStackRef.GetStringOutput(pulumi.String("readerInstanceId"))
+
+		// Reference EC2 stack outputs (optional, adds workload simulator host widgets)
+		ec2InstanceId := pulumi.String("").ToStringOutput()
+		if settings.Ec2StackName != "" {
+			ec2StackRef, err := pulumi.NewStackReference(ctx, settings.Ec2StackName, nil)
+			if err != nil {
+				return err
+			}
+			ec2InstanceId = ec2StackRef.GetStringOutput(pulumi.String("instanceId"))
+		}
+
+		dashboardBody := pulumi.All(clusterIdentifier, writerInstanceId, readerInstanceId, ec2InstanceId).ApplyT(
+			func(args []interface{}) (string, error) {
+				return buildDashboardBody(dashboardTargets{
+					region:             region,
+					clusterIdentifier:  args[0].(string),
+					writerInstanceId:   args[1].(string),
+					readerInstanceId:   args[2].(string),
+					ec2InstanceId:      args[3].(string),
+					simulatorNamespace: settings.SimulatorMetricsNamespace,
+					period:             settings.MetricPeriod,
+					annotations:        settings.EventAnnotations,
+				})
+			}).(pulumi.StringOutput)
+
+		// Create CloudWatch Dashboard
+		dashboardName := lb.Name("switchover")
+		dashboard, err := cloudwatch.NewDashboard(ctx, lb.Name("dashboard"), &cloudwatch.DashboardArgs{
+			DashboardName: pulumi.String(dashboardName),
+			DashboardBody: dashboardBody,
+		}, inRegion)
+		if err != nil {
+			return err
+		}
+
+		// Create SNS Topic for alarm notifications
+		alarmTopic, err := sns.NewTopic(ctx, lb.Name("alarms"), &sns.TopicArgs{
+			Name: pulumi.String(lb.Name("alarms")),
+			Tags: lb.Tags(lb.Name("alarms")),
+		}, inRegion)
+		if err != nil {
+			return err
+		}
+
+		// Allow CloudWatch alarms, RDS event subscriptions, and EventBridge to publish to the topic
+		topicPolicy := alarmTopic.Arn.ApplyT(func(arn string) (string, error) {
+			return servicePublishPolicy(arn, "sns:Publish",
+				"cloudwatch.amazonaws.com", "events.amazonaws.com", "events.rds.amazonaws.com")
+		}).(pulumi.StringOutput)
+
+		_, err = sns.NewTopicPolicy(ctx, lb.Name("alarms-policy"), &sns.TopicPolicyArgs{
+			Arn:    alarmTopic.Arn,
+			Policy: topicPolicy,
+		}, inRegion)
+		if err != nil {
+			return err
+		}
+
+		// Subscribe an email address (the subscription must be confirmed from the inbox)
+		if settings.AlarmEmail != "" {
+			_, err = sns.NewTopicSubscription(ctx, lb.Name("alarms-email"), &sns.TopicSubscriptionArgs{
+				Topic:    alarmTopic.Arn,
+				Protocol: pulumi.String("email"),
+				Endpoint: pulumi.String(settings.AlarmEmail),
+			}, inRegion)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Create CloudWatch Alarms for the writer and reader instances
+		alarms := []instanceAlarm{
+			{
+				suffix:      "cpu",
+				metricName:  "CPUUtilization",
+				comparison:  "GreaterThanThreshold",
+				threshold:   settings.CpuAlarmThreshold,
+				description: "Aurora instance CPU utilization is high",
+			},
+			{
+				suffix:      "freeable-memory",
+				metricName:  "FreeableMemory",
+				comparison:  "LessThanThreshold",
+				threshold:   settings.FreeableMemoryAlarmThreshold,
+				description: "Aurora instance freeable memory is low",
+			},
+			{
+				suffix:      "connections",
+				metricName:  "DatabaseConnections",
+				comparison:  "GreaterThanThreshold",
+				threshold:   settings.ConnectionsAlarmThreshold,
+				description: "Aurora instance connection count is high",
+			},
+			{
+				suffix:      "replica-lag",
+				metricName:  "AuroraReplicaLag",
+				comparison:  "GreaterThanThreshold",
+				threshold:   settings.ReplicaLagAlarmThreshold,
+				description: "Aurora replica lag is high",
+				readerOnly:  true,
+			},
+		}
+
+		instances := []struct {
+			role string
+			id   pulumi.StringOutput
+		}{
+			{role: "writer", id: writerInstanceId},
+			{role: "reader", id: readerInstanceId},
+		}
+
+		var alarmNames pulumi.StringArray
+		for _, instance := range instances {
+			for _, alarm := range alarms {
+				// AuroraReplicaLag is only reported by replicas
+				if alarm.readerOnly && instance.role != "reader" {
+					continue
+				}
+
+				alarmName := lb.Name(fmt.Sprintf("%s-%s", instance.role, alarm.suffix))
+				metricAlarm, err := cloudwatch.NewMetricAlarm(ctx, alarmName, &cloudwatch.MetricAlarmArgs{
+					Name:               pulumi.String(alarmName),
+					AlarmDescription:   pulumi.String(fmt.Sprintf("%s (%s)", alarm.description, instance.role)),
+					Namespace:          pulumi.String("AWS/RDS"),
+					MetricName:         pulumi.String(alarm.metricName),
+					Dimensions:         pulumi.StringMap{"DBInstanceIdentifier": instance.id},
+					Statistic:          pulumi.String("Average"),
+					Period:             pulumi.Int(60),
+					EvaluationPeriods:  pulumi.Int(3),
+					Threshold:          pulumi.Float64(alarm.threshold),
+					ComparisonOperator: pulumi.String(alarm.comparison),
+					TreatMissingData:   pulumi.String("missing"),
+					AlarmActions:       pulumi.Array{alarmTopic.Arn},
+					OkActions:          pulumi.Array{alarmTopic.Arn},
+					Tags:               lb.Tags(alarmName, labels.Role(instance.role)),
+				}, inRegion)
+				if err != nil {
+					return err
+				}
+				alarmNames = append(alarmNames, metricAlarm.Name)
+			}
+		}
+
+		// Create RDS Event Subscriptions for the cluster, its instances, and Blue/Green deployments
+		eventSources := []struct {
+			suffix     string
+			sourceType string
+			sourceIds  pulumi.StringArrayInput
+		}{
+			{suffix: "cluster", sourceType: "db-cluster", sourceIds: pulumi.StringArray{clusterIdentifier}},
+			{suffix: "instances", sourceType: "db-instance", sourceIds: pulumi.StringArray{writerInstanceId, readerInstanceId}},
+			// Blue/Green deployments are created outside of Pulumi, so subscribe to all of them
+			{suffix: "bluegreen", sourceType: "blue-green-deployment"},
+		}
+
+		var eventSubscriptionIds pulumi.StringArray
+		for _, source := range eventSources {
+			subscriptionName := lb.Name(fmt.Sprintf("%s-events", source.suffix))
+			subscription, err := rds.NewEventSubscription(ctx, subscriptionName, &rds.EventSubscriptionArgs{
+				Name:       pulumi.String(subscriptionName),
+				SnsTopic:   alarmTopic.Arn,
+				SourceType: pulumi.String(source.sourceType),
+				SourceIds:  source.sourceIds,
+				Tags:       lb.Tags(subscriptionName),
+			}, inRegion)
+			if err != nil {
+				return err
+			}
+			eventSubscriptionIds = append(eventSubscriptionIds, subscription.ID().ToStringOutput())
+		}
+
+		// Create CloudWatch Log Group for the Blue/Green event audit trail
+		// (EventBridge requires log group names starting with /aws/events/)
+		eventLogGroup, err := cloudwatch.NewLogGroup(ctx, lb.Name("bluegreen-events"), &cloudwatch.LogGroupArgs{
+			Name:            pulumi.String(fmt.Sprintf("/aws/events/%s", lb.Name("bluegreen"))),
+			RetentionInDays: pulumi.Int(settings.EventLogRetentionDays),
+			Tags:            lb.Tags(lb.Name("bluegreen-events")),
+		}, inRegion)
+		if err != nil {
+			return err
+		}
+
+		// Allow EventBridge to write to the log group
+		logPolicy := eventLogGroup.Arn.ApplyT(func(arn string) (string, error) {
+			return servicePublishPolicy(arn+":*", []string{"logs:CreateLogStream", "logs:PutLogEvents"}, "events.amazonaws.com")
+		}).(pulumi.StringOutput)
+
+		_, err = cloudwatch.NewLogResourcePolicy(ctx, lb.Name("bluegreen-events-policy"), &cloudwatch.LogResourcePolicyArgs{
+			PolicyName:     pulumi.String(lb.Name("bluegreen-events")),
+			PolicyDocument: logPolicy,
+		}, inRegion)
+		if err != nil {
+			return err
+		}
+
+		// Create EventBridge Rule capturing Blue/Green lifecycle events
+		// (creation, switchover started/completed, deletion)
+		eventRule, err := cloudwatch.NewEventRule(ctx, lb.Name("bluegreen-rule"), &cloudwatch.EventRuleArgs{
+			Name:        pulumi.String(lb.Name("bluegreen-events")),
+			Description: pulumi.String("Aurora Blue-Green deployment lifecycle events"),
+			EventPattern: pulumi.String(`{
+  "source": ["aws.rds"],
+  "detail-type": ["RDS Blue Green Deployment Event"]
+}`),
+			Tags: lb.Tags(lb.Name("bluegreen-events")),
+		}, inRegion)
+		if err != nil {
+			return err
+		}
+
+		_, err = cloudwatch.NewEventTarget(ctx, lb.Name("bluegreen-logs-target"), &cloudwatch.EventTargetArgs{
+			Rule: eventRule.Name,
+			Arn:  eventLogGroup.Arn,
+		}, inRegion)
+		if err != nil {
+			return err
+		}
+
+		_, err = cloudwatch.NewEventTarget(ctx, lb.Name("bluegreen-sns-target"), &cloudwatch.EventTargetArgs{
+			Rule: eventRule.Name,
+			Arn:  alarmTopic.Arn,
+		}, inRegion)
+		if err != nil {
+			return err
+		}
+
+		// Record every Blue/Green event with its RDS timestamp in DynamoDB and
+		// as CloudWatch metrics, the server-side timeline of lab-report
+		var recorder *eventRecorder
+		if settings.RecordBlueGreenEvents {
+			recorder, err = newEventRecorder(ctx, lb, settings, eventRule, inRegion)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Registry of the lab runs, so repeated sessions can be compared with
+		// lab-report list
+		var experimentTable *dynamodb.Table
+		if settings.ExperimentRegistry {
+			experimentTable, err = newExperimentTable(ctx, lb, inRegion)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Export outputs
+		ctx.Export("region", pulumi.String(region))
+		ctx.Export("dashboardName", dashboard.DashboardName)
+		ctx.Export("dashboardArn", dashboard.DashboardArn)
+		ctx.Export("dashboardUrl", pulumi.Sprintf(
+			"https://%s.console.aws.amazon.com/cloudwatch/home?region=%s#dashboards:name=%s",
+			region, region, dashboard.DashboardName,
+		))
+		ctx.Export("alarmTopicArn", alarmTopic.Arn)
+		ctx.Export("alarmNames", alarmNames)
+		ctx.Export("eventSubscriptionIds", eventSubscriptionIds)
+		ctx.Export("eventRuleArn", eventRule.Arn)
+		ctx.Export("eventLogGroupName", eventLogGroup.Name)
+		if recorder != nil {
+			ctx.Export("eventTableName", recorder.table.Name)
+			ctx.Export("eventFunctionName", recorder.function.Function.Name)
+			ctx.Export("blueGreenMetricsNamespace", pulumi.String(settings.BlueGreenMetricsNamespace))
+		}
+		if experimentTable != nil {
+			ctx.Export("experimentTableName", experimentTable.Name)
+		}
+
+		// Estimate the monthly cost of the dashboard and alarms
+		var estimate cost.Estimate
+		estimate.Dashboards(1)
+		estimate.Alarms(len(alarmNames))
+		if err := estimate.Export(ctx); err != nil {
+			return err
+		}
+
+		// Publish the key outputs for runtime discovery without Pulumi access
+		outputValues := map[string]pulumi.StringInput{
+			"region":            pulumi.String(region),
+			"dashboardName":     dashboard.DashboardName,
+			"alarmTopicArn":     alarmTopic.Arn,
+			"eventLogGroupName": eventLogGroup.Name,
+		}
+		if recorder != nil {
+			outputValues["eventTableName"] = recorder.table.Name
+		}
+		if experimentTable != nil {
+			outputValues["experimentTableName"] = experimentTable.Name
+		}
+		outputParameters, err := components.NewLabOutputParameters(ctx, lb.Name("monitoring-outputs"), &components.LabOutputParametersArgs{
+			Labels: lb,
+			Stack:  "monitoring",
+			Values: outputValues,
+		}, inRegion)
+		if err != nil {
+			return err
+		}
+		ctx.Export("outputParameterPrefix", pulumi.String(outputParameters.Prefix))
+
+		return nil
+	})
+}
+
+// eventRecorder is the Blue/Green event recorder function and its table.
+type eventRecorder struct {
+	table    *dynamodb.Table
+	function *components.LabFunction
+}
+
+// newEventRecorder creates the event table and the function
+// (cmd/lab-bluegreen-events) invoked by the Blue/Green event rule.
+func newEventRecorder(ctx *pulumi.Context, lb *labels.Labels, settings *labconfig.Monitoring, rule *cloudwatch.EventRule, opts ...pulumi.ResourceOption) (*eventRecorder, error) {
+	// One item per event: the deployment ID and "<event time>#<event ID>"
+	// (see bluegreen.DeploymentEvent), expired by DynamoDB TTL
+	table, err := dynamodb.NewTable(ctx, lb.Name("bluegreen-events-table"), &dynamodb.TableArgs{
+		Name:        pulumi.String(lb.Name("bluegreen-events")),
+		BillingMode: pulumi.String("PAY_PER_REQUEST"),
+		HashKey:     pulumi.String("DeploymentId"),
+		RangeKey:    pulumi.String("EventKey"),
+		Attributes: dynamodb.TableAttributeArray{
+			&dynamodb.TableAttributeArgs{Name: pulumi.String("DeploymentId"), Type: pulumi.String("S")},
+			&dynamodb.TableAttributeArgs{Name: pulumi.String("EventKey"), Type: pulumi.String("S")},
+		},
+		Ttl: &dynamodb.TableTtlArgs{
+			AttributeName: pulumi.String("ExpiresAt"),
+			Enabled:       pulumi.Bool(true),
+		},
+		Tags: lb.Tags(lb.Name("bluegreen-events")),
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	bootstrap, err := golambda.Build("..", "./cmd/lab-bluegreen-events")
+	if err != nil {
+		return nil, err
+	}
+	policy := table.Arn.ApplyT(func(arn string) (string, error) {
+		statements := []map[string]interface{}{
+			{
+				"Effect":   "Allow",
+				"Action":   []string{"dynamodb:PutItem", "dynamodb:Query"},
+				"Resource": arn,
+			},
+			{
+				// The RunId tag of the event's deployment
+				"Effect":   "Allow",
+				"Action":   "rds:DescribeBlueGreenDeployments",
+				"Resource": "*",
+			},
+		}
+		if settings.BlueGreenMetricsNamespace != "" {
+			statements = append(statements, map[string]interface{}{
+				"Effect":   "Allow",
+				"Action":   "cloudwatch:PutMetricData",
+				"Resource": "*",
+				"Condition": map[string]interface{}{
+					"StringEquals": map[string]string{"cloudwatch:namespace": settings.BlueGreenMetricsNamespace},
+				},
+			})
+		}
+		policy, err := json.Marshal(map[string]interface{}{"Version": "2012-10-17", "Statement": statements})
+		return string(policy), err
+	}).(pulumi.StringOutput)
+
+	function, err := components.NewLabFunction(ctx, lb.Name("bluegreen-events-function"), &components.LabFunctionArgs{
+		Labels:      lb,
+		Name:        "bluegreen-events",
+		Description: "Records RDS Blue/Green events in DynamoDB and CloudWatch metrics",
+		Bootstrap:   bootstrap,
+		Policy:      policy,
+		Environment: pulumi.StringMap{
+			"TABLE_NAME":        table.Name,
+			"METRICS_NAMESPACE": pulumi.String(settings.BlueGreenMetricsNamespace),
+			"RETENTION_DAYS":    pulumi.String(strconv.Itoa(settings.EventLogRetentionDays)),
+		},
+		Timeout:          30,
+		LogRetentionDays: settings.EventLogRetentionDays,
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = lambda.NewPermission(ctx, lb.Name("bluegreen-events-invoke"), &lambda.PermissionArgs{
+		Action:    pulumi.String("lambda:InvokeFunction"),
+		Function:  function.Function.Name,
+		Principal: pulumi.String("events.amazonaws.com"),
+		SourceArn: rule.Arn,
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	_, err = cloudwatch.NewEventTarget(ctx, lb.Name("bluegreen-function-target"), &cloudwatch.EventTargetArgs{
+		Rule: rule.Name,
+		Arn:  function.Function.Arn,
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &eventRecorder{table: table, function: function}, nil
+}
+
+// newExperimentTable creates the experiment registry table, one item per run
+// and source (see experiments.Run).
+func newExperimentTable(ctx *pulumi.Context, lb *labels.Labels, opts ...pulumi.ResourceOption) (*dynamodb.Table, error) {
+	return dynamodb.NewTable(ctx, lb.Name("experiments-table"), &dynamodb.TableArgs{
+		Name:        pulumi.String(lb.Name("experiments")),
+		BillingMode: pulumi.String("PAY_PER_REQUEST"),
+		HashKey:     pulumi.String("RunId"),
+		RangeKey:    pulumi.String("Source"),
+		Attributes: dynamodb.TableAttributeArray{
+			&dynamodb.TableAttributeArgs{Name: pulumi.String("RunId"), Type: pulumi.String("S")},
+			&dynamodb.TableAttributeArgs{Name: pulumi.String("Source"), Type: pulumi.String("S")},
+		},
+		Tags: lb.Tags(lb.Name("experiments")),
+	}, opts...)
+}
+
+// buildDashboardBody renders the CloudWatch dashboard JSON document.
+func buildDashboardBody(t dashboardTargets) (string, error) {
+	writer := []string{"DBInstanceIdentifier", t.writerInstanceId}
+	reader := []string{"DBInstanceIdentifier", t.readerInstanceId}
+	cluster := []string{"DBClusterIdentifier", t.clusterIdentifier}
+
+	widgets := []map[string]interface{}{
+		textWidget(0, 0, 24, 2, fmt.Sprintf(
+			"# Aurora Blue-Green Switchover\nCluster `%s` — writer `%s`, reader `%s`. "+
+				"Vertical markers show configured RDS event annotations.",
+			t.clusterIdentifier, t.writerInstanceId, t.readerInstanceId)),
+		metricWidget(t, 0, 2, 12, 6, "Database Connections", [][]string{
+			rdsMetric("DatabaseConnections", writer),
+			rdsMetric("DatabaseConnections", reader),
+		}),
+		metricWidget(t, 12, 2, 12, 6, "Commit Latency (ms)", [][]string{
+			rdsMetric("CommitLatency", writer),
+			rdsMetric("CommitLatency", reader),
+		}),
+		metricWidget(t, 0, 8, 12, 6, "Aurora Replica Lag (ms)", [][]string{
+			rdsMetric("AuroraReplicaLag", reader),
+			rdsMetric("AuroraReplicaLagMaximum", cluster),
+		}),
+		metricWidget(t, 12, 8, 12, 6, "Deadlocks (per second)", [][]string{
+			rdsMetric("Deadlocks", writer),
+			rdsMetric("Deadlocks", reader),
+		}),
+		metricWidget(t, 0, 14, 12, 6, "Aurora CPU Utilization (%)", [][]string{
+			rdsMetric("CPUUtilization", writer),
+			rdsMetric("CPUUtilization", reader),
+		}),
+		metricWidget(t, 12, 14, 12, 6, "DML Throughput (per second)", [][]string{
+			rdsMetric("DMLThroughput", writer),
+			rdsMetric("InsertThroughput", writer),
+		}),
+	}
+
+	if t.ec2InstanceId != "" {
+		instance := []string{"InstanceId", t.ec2InstanceId}
+		widgets = append(widgets,
+			metricWidget(t, 0, 20, 12, 6, "Simulator Host CPU Utilization (%)", [][]string{
+				metric("AWS/EC2", "CPUUtilization", instance),
+			}),
+			metricWidget(t, 12, 20, 12, 6, "Simulator Host Network (bytes)", [][]string{
+				metric("AWS/EC2", "NetworkIn", instance),
+				metric("AWS/EC2", "NetworkOut", instance),
+			}),
+		)
+	}
+
+	// The simulator publishes one time series per run; SEARCH graphs every run
+	// in the namespace without knowing the run IDs up front
+	if t.simulatorNamespace != "" {
+		widgets = append(widgets,
+			searchWidget(t, 0, 26, 12, 6, "Simulator Writes (per period)", "Sum", []string{
+				simulatorSearch(t, "SuccessCount", "RunId", "Sum"),
+				simulatorSearch(t, "FailureCount", "RunId", "Sum"),
+			}),
+			searchWidget(t, 12, 26, 12, 6, "Simulator Latency p99 (ms)", "Average", []string{
+				simulatorSearch(t, "LatencyP99", "Operation,RunId", "Average"),
+			}),
+		)
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"widgets": widgets})
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+func metricWidget(t dashboardTargets, x, y, width, height int, title string, metrics [][]string) map[string]interface{} {
+	properties := map[string]interface{}{
+		"title":   title,
+		"region":  t.region,
+		"metrics": metrics,
+		"period":  t.period,
+		"stat":    "Average",
+		"view":    "timeSeries",
+		"stacked": false,
+	}
+	if len(t.annotations) > 0 {
+		properties["annotations"] = map[string]interface{}{"vertical": t.annotations}
+	}
+
+	return map[string]interface{}{
+		"type":       "metric",
+		"x":          x,
+		"y":          y,
+		"width":      width,
+		"height":     height,
+		"properties": properties,
+	}
+}
+
+// searchWidget is a metric widget graphing CloudWatch SEARCH expressions.
+func searchWidget(t dashboardTargets, x, y, width, height int, title, stat string, expressions []string) map[string]interface{} {
+	widget := metricWidget(t, x, y, width, height, title, nil)
+	metrics := make([][]map[string]string, len(expressions))
+	for i, expression := range expressions {
+		metrics[i] = []map[string]string{{"expression": expression, "id": fmt.Sprintf("e%d", i+1)}}
+	}
+	properties := widget["properties"].(map[string]interface{})
+	properties["metrics"] = metrics
+	properties["stat"] = stat
+	return widget
+}
+
+func simulatorSearch(t dashboardTargets, name, dimensions, stat string) string {
+	return fmt.Sprintf(`SEARCH('{%s,%s} MetricName="%s"', '%s', %d)`, t.simulatorNamespace, dimensions, name, stat, t.period)
+}
+
+func textWidget(x, y, width, height int, markdown string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "text",
+		"x":          x,
+		"y":          y,
+		"width":      width,
+		"height":     height,
+		"properties": map[string]interface{}{"markdown": markdown},
+	}
+}
+
+func rdsMetric(name string, dimension []string) []string {
+	return metric("AWS/RDS", name, dimension)
+}
+
+func metric(namespace, name string, dimension []string) []string {
+	return append([]string{namespace, name}, dimension...)
+}
+
+// servicePublishPolicy renders a resource policy allowing the given AWS service
+// principals to perform action (a string or list of strings) on resource.
+func servicePublishPolicy(resource string, action interface{}, services ...string) (string, error) {
+	policy, err := json.Marshal(map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []map[string]interface{}{
+			{
+				"Sid":       "AllowServicePublish",
+				"Effect":    "Allow",
+				"Principal": map[string]interface{}{"Service": services},
+				"Action":    action,
+				"Resource":  resource,
+			},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(policy), nil
+}
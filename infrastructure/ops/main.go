@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/cloudwatch"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/lambda"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+
+	"aurora-bluegreen-lab/internal/components"
+	labconfig "aurora-bluegreen-lab/internal/config"
+	"aurora-bluegreen-lab/internal/cost"
+	"aurora-bluegreen-lab/internal/golambda"
+	"aurora-bluegreen-lab/internal/labels"
+	"aurora-bluegreen-lab/internal/providers"
+)
+
+func main() {
+	pulumi.Run(func(ctx *pulumi.Context) error {
+		// Load configuration
+		cfg := config.New(ctx, "")
+		settings, err := labconfig.LoadOps(cfg)
+		if err != nil {
+			return err
+		}
+
+		lb, err := labels.New(ctx, cfg)
+		if err != nil {
+			return err
+		}
+
+		// Create the AWS provider for the stack's region
+		provider, region, err := providers.New(ctx, cfg, lb)
+		if err != nil {
+			return err
+		}
+		inRegion := pulumi.Provider(provider)
+
+		// Reference Aurora stack outputs
+		auroraStackRef, err := pulumi.NewStackReference(ctx, settings.AuroraStackName, nil)
+		if err != nil {
+			return err
+		}
+		clusterIdentifier := auroraStackRef.GetStringOutput(pulumi.String("clusterIdentifier"))
+		clusterArn := auroraStackRef.GetStringOutput(pulumi.String("clusterArn"))
+
+		// Build the snapshot function (cmd/lab-snapshots); the program runs in
+		// ops/ and the function lives in the parent module
+		bootstrap, err := golambda.Build("..", "./cmd/lab-snapshots")
+		if err != nil {
+			return err
+		}
+		snapshotPrefix := lb.Name("scheduled")
+		snapshotTags, err := json.Marshal(lb.DefaultTags())
+		if err != nil {
+			return err
+		}
+
+		// Allow the function to snapshot the cluster and delete its own snapshots
+		snapshots, err := components.NewLabFunction(ctx, lb.Name("snapshots-function"), &components.LabFunctionArgs{
+			Labels:      lb,
+			Name:        "snapshots",
+			Description: "Snapshots the lab cluster and prunes old scheduled snapshots",
+			Bootstrap:   bootstrap,
+			Policy: pulumi.Sprintf(`{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Action": ["rds:CreateDBClusterSnapshot", "rds:AddTagsToResource"],
+      "Resource": [%q, "arn:aws:rds:%s:*:cluster-snapshot:%s-*"]
+    },
+    {
+      "Effect": "Allow",
+      "Action": "rds:DeleteDBClusterSnapshot",
+      "Resource": "arn:aws:rds:%s:*:cluster-snapshot:%s-*"
+    },
+    {
+      "Effect": "Allow",
+      "Action": "rds:DescribeDBClusterSnapshots",
+      "Resource": "*"
+    }
+  ]
+}`, clusterArn, region, snapshotPrefix, region, snapshotPrefix),
+			Environment: pulumi.StringMap{
+				"CLUSTER_IDENTIFIER": clusterIdentifier,
+				"SNAPSHOT_PREFIX":    pulumi.String(snapshotPrefix),
+				"RETENTION_DAYS":     pulumi.String(strconv.Itoa(settings.SnapshotRetentionDays)),
+				"SNAPSHOT_TAGS":      pulumi.String(snapshotTags),
+			},
+			Timeout:          60,
+			LogRetentionDays: settings.LambdaLogRetentionDays,
+		}, inRegion)
+		if err != nil {
+			return err
+		}
+
+		// Invoke the function on the snapshot schedule
+		rule, err := cloudwatch.NewEventRule(ctx, lb.Name("snapshots-schedule"), &cloudwatch.EventRuleArgs{
+			Name:               pulumi.String(lb.Name("snapshots-schedule")),
+			Description:        pulumi.String("Snapshots the lab cluster before each experiment window"),
+			ScheduleExpression: pulumi.String(settings.SnapshotSchedule),
+			Tags:               lb.Tags(lb.Name("snapshots-schedule")),
+		}, inRegion)
+		if err != nil {
+			return err
+		}
+		_, err = lambda.NewPermission(ctx, lb.Name("snapshots-schedule-invoke"), &lambda.PermissionArgs{
+			Action:    pulumi.String("lambda:InvokeFunction"),
+			Function:  snapshots.Function.Name,
+			Principal: pulumi.String("events.amazonaws.com"),
+			SourceArn: rule.Arn,
+		}, inRegion)
+		if err != nil {
+			return err
+		}
+		_, err = cloudwatch.NewEventTarget(ctx, lb.Name("snapshots-schedule-target"), &cloudwatch.EventTargetArgs{
+			Rule: rule.Name,
+			Arn:  snapshots.Function.Arn,
+		}, inRegion)
+		if err != nil {
+			return err
+		}
+
+		// Export outputs
+		ctx.Export("region", pulumi.String(region))
+		ctx.Export("functionName", snapshots.Function.Name)
+		ctx.Export("functionArn", snapshots.Function.Arn)
+		ctx.Export("logGroupName", snapshots.LogGroup.Name)
+		ctx.Export("scheduleRuleName", rule.Name)
+		ctx.Export("snapshotSchedule", pulumi.String(settings.SnapshotSchedule))
+		ctx.Export("snapshotPrefix", pulumi.String(snapshotPrefix))
+		ctx.Export("snapshotRetentionDays", pulumi.Int(settings.SnapshotRetentionDays))
+
+		// The function and rule are billed per use; the snapshots per GB stored
+		var estimate cost.Estimate
+		if err := estimate.Export(ctx); err != nil {
+			return err
+		}
+
+		// Publish the key outputs for runtime discovery without Pulumi access
+		outputParameters, err := components.NewLabOutputParameters(ctx, lb.Name("ops-outputs"), &components.LabOutputParametersArgs{
+			Labels: lb,
+			Stack:  "ops",
+			Values: map[string]pulumi.StringInput{
+				"region":           pulumi.String(region),
+				"functionName":     snapshots.Function.Name,
+				"scheduleRuleName": rule.Name,
+				"snapshotPrefix":   pulumi.String(snapshotPrefix),
+			},
+		}, inRegion)
+		if err != nil {
+			return err
+		}
+		ctx.Export("outputParameterPrefix", pulumi.String(outputParameters.Prefix))
+
+		return nil
+	})
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ecr"
+	"github.com/pulumi/pulumi-docker-build/sdk/go/dockerbuild"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+
+	"aurora-bluegreen-lab/internal/components"
+	labconfig "aurora-bluegreen-lab/internal/config"
+	"aurora-bluegreen-lab/internal/cost"
+	"aurora-bluegreen-lab/internal/imagebuild"
+	"aurora-bluegreen-lab/internal/labels"
+	"aurora-bluegreen-lab/internal/providers"
+)
+
+// simulatorContext is the build context of the workload simulator image; the
+// program runs in registry/ and the simulator lives next to infrastructure/.
+const simulatorContext = "../../workload-simulator"
+
+func main() {
+	pulumi.Run(func(ctx *pulumi.Context) error {
+		// Load configuration
+		cfg := config.New(ctx, "")
+		settings, err := labconfig.LoadRegistry(cfg)
+		if err != nil {
+			return err
+		}
+
+		lb, err := labels.New(ctx, cfg)
+		if err != nil {
+			return err
+		}
+
+		// Create the AWS provider for the stack's region
+		provider, region, err := providers.New(ctx, cfg, lb)
+		if err != nil {
+			return err
+		}
+		inRegion := pulumi.Provider(provider)
+
+		// Every repository keeps the last keepImages images
+		newRepository := func(name string) (*ecr.Repository, error) {
+			repository, err := components.NewLabRepository(ctx, lb.Name(name+"-repository"), &components.LabRepositoryArgs{
+				Labels:     lb,
+				Name:       name,
+				KeepImages: settings.KeepImages,
+			}, inRegion)
+			if err != nil {
+				return nil, err
+			}
+			return repository.Repository, nil
+		}
+
+		// Create the simulator's repository, built by this stack, and those
+		// of the additional images pushed by hand or by CI
+		repository, err := newRepository(labconfig.SimulatorImage)
+		if err != nil {
+			return err
+		}
+		repositoryUrls := pulumi.StringMap{labconfig.SimulatorImage: repository.RepositoryUrl}
+		for _, name := range settings.Repositories {
+			additional, err := newRepository(name)
+			if err != nil {
+				return err
+			}
+			repositoryUrls[name] = additional.RepositoryUrl
+		}
+
+		tag, err := imagebuild.ContentTag(simulatorContext)
+		if err != nil {
+			return err
+		}
+
+		// Build and push the image during pulumi up with the docker-build
+		// provider; its ref pins the pushed digest and only resolves once the
+		// push is done, so stacks deployed after this one never reference a
+		// missing image. Without buildImages the tag is left to be pushed to
+		image := pulumi.Sprintf("%s:%s", repository.RepositoryUrl, tag)
+		if settings.BuildImages {
+			token, err := ecr.GetAuthorizationToken(ctx, &ecr.GetAuthorizationTokenArgs{}, inRegion)
+			if err != nil {
+				return err
+			}
+			registry := repository.RepositoryUrl.ApplyT(func(url string) string {
+				host, _, _ := strings.Cut(url, "/")
+				return host
+			}).(pulumi.StringOutput)
+			built, err := dockerbuild.NewImage(ctx, lb.Name("simulator-image"), &dockerbuild.ImageArgs{
+				Context:        &dockerbuild.BuildContextArgs{Location: pulumi.String(simulatorContext)},
+				Platforms:      dockerbuild.PlatformArray{dockerbuild.Platform(settings.Platform)},
+				Tags:           pulumi.StringArray{image},
+				Push:           pulumi.Bool(true),
+				BuildOnPreview: pulumi.Bool(false),
+				Registries: dockerbuild.RegistryArray{&dockerbuild.RegistryArgs{
+					Address:  registry,
+					Username: pulumi.String(token.UserName),
+					Password: pulumi.ToSecret(pulumi.String(token.Password)).(pulumi.StringOutput),
+				}},
+			})
+			if err != nil {
+				return err
+			}
+			image = built.Ref
+		}
+
+		// Export outputs
+		ctx.Export("region", pulumi.String(region))
+		ctx.Export("simulatorRepositoryName", repository.Name)
+		ctx.Export("simulatorRepositoryUrl", repository.RepositoryUrl)
+		ctx.Export("simulatorImageTag", pulumi.String(tag))
+		ctx.Export("simulatorImageUri", image)
+		ctx.Export("platform", pulumi.String(settings.Platform))
+		ctx.Export("repositoryUrls", repositoryUrls)
+
+		// The few images the lifecycle policies keep cost cents of ECR storage a month
+		var estimate cost.Estimate
+		if err := estimate.Export(ctx); err != nil {
+			return err
+		}
+
+		// Publish the key outputs for runtime discovery without Pulumi access
+		outputParameters, err := components.NewLabOutputParameters(ctx, lb.Name("registry-outputs"), &components.LabOutputParametersArgs{
+			Labels: lb,
+			Stack:  "registry",
+			Values: map[string]pulumi.StringInput{
+				"region":            pulumi.String(region),
+				"simulatorImageUri": image,
+			},
+		}, inRegion)
+		if err != nil {
+			return err
+		}
+		ctx.Export("outputParameterPrefix", pulumi.String(outputParameters.Prefix))
+
+		return nil
+	})
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/iam"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/scheduler"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+
+	"aurora-bluegreen-lab/internal/components"
+	labconfig "aurora-bluegreen-lab/internal/config"
+	"aurora-bluegreen-lab/internal/cost"
+	"aurora-bluegreen-lab/internal/golambda"
+	"aurora-bluegreen-lab/internal/labels"
+	"aurora-bluegreen-lab/internal/providers"
+)
+
+// targets are the resolved resources the function stops and starts; empty
+// values are skipped.
+type targets struct {
+	region           string
+	instanceID       string
+	autoScalingGroup string
+	clusterArn       string
+}
+
+func main() {
+	pulumi.Run(func(ctx *pulumi.Context) error {
+		// Load configuration
+		cfg := config.New(ctx, "")
+		settings, err := labconfig.LoadScheduler(cfg)
+		if err != nil {
+			return err
+		}
+
+		lb, err := labels.New(ctx, cfg)
+		if err != nil {
+			return err
+		}
+
+		// Create the AWS provider for the stack's region
+		provider, region, err := providers.New(ctx, cfg, lb)
+		if err != nil {
+			return err
+		}
+		inRegion := pulumi.Provider(provider)
+
+		// Reference EC2 stack outputs: a single instance exports instanceId,
+		// a simulator group autoScalingGroupName and simulatorCount
+		ec2StackRef, err := pulumi.NewStackReference(ctx, settings.Ec2StackName, nil)
+		if err != nil {
+			return err
+		}
+		instanceID := optionalOutput(ec2StackRef, "instanceId")
+		autoScalingGroup := optionalOutput(ec2StackRef, "autoScalingGroupName")
+		simulatorCount := optionalOutput(ec2StackRef, "simulatorCount")
+
+		// Reference Aurora stack outputs (only when the cluster is stopped too)
+		clusterIdentifier := pulumi.String("").ToStringOutput()
+		clusterArn := pulumi.String("").ToStringOutput()
+		if settings.StopCluster {
+			auroraStackRef, err := pulumi.NewStackReference(ctx, settings.AuroraStackName, nil)
+			if err != nil {
+				return err
+			}
+			clusterIdentifier = auroraStackRef.GetStringOutput(pulumi.String("clusterIdentifier"))
+			clusterArn = auroraStackRef.GetStringOutput(pulumi.String("clusterArn"))
+		}
+
+		policy := pulumi.All(instanceID, autoScalingGroup, clusterArn).ApplyT(func(args []interface{}) (string, error) {
+			return lambdaPolicy(targets{
+				region:           region,
+				instanceID:       args[0].(string),
+				autoScalingGroup: args[1].(string),
+				clusterArn:       args[2].(string),
+			})
+		}).(pulumi.StringOutput)
+
+		// Build the scheduler function (cmd/lab-scheduler); the program runs in
+		// scheduler/ and the function lives in the parent module
+		bootstrap, err := golambda.Build("..", "./cmd/lab-scheduler")
+		if err != nil {
+			return err
+		}
+
+		function, err := components.NewLabFunction(ctx, lb.Name("scheduler-function"), &components.LabFunctionArgs{
+			Labels:      lb,
+			Name:        "scheduler",
+			Description: "Stops the lab outside working hours and starts it again",
+			Bootstrap:   bootstrap,
+			Policy:      policy,
+			Environment: pulumi.StringMap{
+				"INSTANCE_ID":        instanceID,
+				"AUTO_SCALING_GROUP": autoScalingGroup,
+				"SIMULATOR_COUNT":    simulatorCount,
+				"CLUSTER_IDENTIFIER": clusterIdentifier,
+			},
+			Timeout:          60,
+			LogRetentionDays: settings.LambdaLogRetentionDays,
+		}, inRegion)
+		if err != nil {
+			return err
+		}
+
+		// Allow EventBridge Scheduler to invoke the function
+		schedulerRole, err := iam.NewRole(ctx, lb.Name("scheduler-invoke-role"), &iam.RoleArgs{
+			Name: pulumi.String(lb.Name("scheduler-invoke-role")),
+			AssumeRolePolicy: pulumi.String(`{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Principal": {"Service": "scheduler.amazonaws.com"},
+      "Action": "sts:AssumeRole"
+    }
+  ]
+}`),
+			Tags: lb.Tags(lb.Name("scheduler-invoke-role")),
+		}, inRegion)
+		if err != nil {
+			return err
+		}
+		_, err = iam.NewRolePolicy(ctx, lb.Name("scheduler-invoke-policy"), &iam.RolePolicyArgs{
+			Role: schedulerRole.ID(),
+			Policy: pulumi.Sprintf(`{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Action": "lambda:InvokeFunction",
+      "Resource": %q
+    }
+  ]
+}`, function.Function.Arn),
+		}, inRegion)
+		if err != nil {
+			return err
+		}
+
+		group, err := scheduler.NewScheduleGroup(ctx, lb.Name("lab-schedule"), &scheduler.ScheduleGroupArgs{
+			Name: pulumi.String(lb.Name("lab-schedule")),
+			Tags: lb.Tags(lb.Name("lab-schedule")),
+		}, inRegion)
+		if err != nil {
+			return err
+		}
+
+		// One schedule per action, both invoking the function
+		schedules := []struct {
+			action     string
+			expression string
+		}{
+			{action: "stop", expression: settings.StopSchedule},
+			{action: "start", expression: settings.StartSchedule},
+		}
+		scheduleNames := map[string]pulumi.StringOutput{}
+		for _, s := range schedules {
+			schedule, err := scheduler.NewSchedule(ctx, lb.Name(s.action+"-schedule"), &scheduler.ScheduleArgs{
+				Name:                       pulumi.String(lb.Name(s.action)),
+				GroupName:                  group.Name,
+				Description:                pulumi.String(fmt.Sprintf("Invokes %s with action %s", lb.Name("scheduler"), s.action)),
+				ScheduleExpression:         pulumi.String(s.expression),
+				ScheduleExpressionTimezone: pulumi.String(settings.ScheduleTimezone),
+				FlexibleTimeWindow: &scheduler.ScheduleFlexibleTimeWindowArgs{
+					Mode: pulumi.String("OFF"),
+				},
+				Target: &scheduler.ScheduleTargetArgs{
+					Arn:     function.Function.Arn,
+					RoleArn: schedulerRole.Arn,
+					Input:   pulumi.String(fmt.Sprintf(`{"action": %q}`, s.action)),
+				},
+			}, inRegion)
+			if err != nil {
+				return err
+			}
+			scheduleNames[s.action] = schedule.Name
+		}
+
+		// Export outputs
+		ctx.Export("region", pulumi.String(region))
+		ctx.Export("functionName", function.Function.Name)
+		ctx.Export("functionArn", function.Function.Arn)
+		ctx.Export("logGroupName", function.LogGroup.Name)
+		ctx.Export("scheduleGroupName", group.Name)
+		ctx.Export("stopScheduleName", scheduleNames["stop"])
+		ctx.Export("startScheduleName", scheduleNames["start"])
+		ctx.Export("stopSchedule", pulumi.String(settings.StopSchedule))
+		ctx.Export("startSchedule", pulumi.String(settings.StartSchedule))
+		ctx.Export("scheduleTimezone", pulumi.String(settings.ScheduleTimezone))
+		ctx.Export("stopCluster", pulumi.Bool(settings.StopCluster))
+
+		// The function and schedules are billed per use, well within the free tier
+		var estimate cost.Estimate
+		if err := estimate.Export(ctx); err != nil {
+			return err
+		}
+
+		// Publish the key outputs for runtime discovery without Pulumi access
+		outputParameters, err := components.NewLabOutputParameters(ctx, lb.Name("scheduler-outputs"), &components.LabOutputParametersArgs{
+			Labels: lb,
+			Stack:  "scheduler",
+			Values: map[string]pulumi.StringInput{
+				"region":            pulumi.String(region),
+				"functionName":      function.Function.Name,
+				"scheduleGroupName": group.Name,
+			},
+		}, inRegion)
+		if err != nil {
+			return err
+		}
+		ctx.Export("outputParameterPrefix", pulumi.String(outputParameters.Prefix))
+
+		return nil
+	})
+}
+
+// optionalOutput returns a string or number output of the referenced stack,
+// or "" when the stack does not export it.
+func optionalOutput(ref *pulumi.StackReference, name string) pulumi.StringOutput {
+	return ref.GetOutput(pulumi.String(name)).ApplyT(func(out interface{}) string {
+		switch value := out.(type) {
+		case string:
+			return value
+		case float64:
+			return strconv.FormatFloat(value, 'f', -1, 64)
+		}
+		return ""
+	}).(pulumi.StringOutput)
+}
+
+// lambdaPolicy allows the function to stop and start the targets only.
+func lambdaPolicy(t targets) (string, error) {
+	var statements []map[string]interface{}
+	if t.instanceID != "" {
+		statements = append(statements, map[string]interface{}{
+			"Effect":   "Allow",
+			"Action":   []string{"ec2:StopInstances", "ec2:StartInstances"},
+			"Resource": fmt.Sprintf("arn:aws:ec2:%s:*:instance/%s", t.region, t.instanceID),
+		})
+	}
+	if t.autoScalingGroup != "" {
+		statements = append(statements, map[string]interface{}{
+			"Effect":   "Allow",
+			"Action":   "autoscaling:UpdateAutoScalingGroup",
+			"Resource": fmt.Sprintf("arn:aws:autoscaling:%s:*:autoScalingGroup:*:autoScalingGroupName/%s", t.region, t.autoScalingGroup),
+		})
+	}
+	if t.clusterArn != "" {
+		statements = append(statements, map[string]interface{}{
+			"Effect":   "Allow",
+			"Action":   []string{"rds:DescribeDBClusters", "rds:StopDBCluster", "rds:StartDBCluster"},
+			"Resource": t.clusterArn,
+		})
+	}
+	if len(statements) == 0 {
+		return "", fmt.Errorf("the EC2 stack exports neither instanceId nor autoScalingGroupName")
+	}
+
+	policy, err := json.Marshal(map[string]interface{}{
+		"Version":   "2012-10-17",
+		"Statement": statements,
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(policy), nil
+}
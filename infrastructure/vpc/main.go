@@ -3,331 +3,223 @@ package main
 import (
 	"fmt"
 
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws"
 	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ec2"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+
+	"aurora-bluegreen-lab/internal/components"
+	labconfig "aurora-bluegreen-lab/internal/config"
+	"aurora-bluegreen-lab/internal/cost"
+	"aurora-bluegreen-lab/internal/labels"
+	"aurora-bluegreen-lab/internal/providers"
 )
 
 func main() {
 	pulumi.Run(func(ctx *pulumi.Context) error {
 		// Load configuration
 		cfg := config.New(ctx, "")
-		vpcCidr := cfg.Get("vpcCidr")
-		if vpcCidr == "" {
-			vpcCidr = "10.0.0.0/16"
-		}
-
-		projectName := cfg.Get("projectName")
-		if projectName == "" {
-			projectName = "aurora-bluegreen-lab"
-		}
-
-		// Get availability zones
-		azs, err := ec2.GetAvailabilityZones(ctx, &ec2.GetAvailabilityZonesArgs{
-			State: pulumi.StringRef("available"),
-		})
+		settings, err := labconfig.LoadVpc(cfg)
 		if err != nil {
 			return err
 		}
 
-		// Ensure we have at least 2 AZs
-		if len(azs.Names) < 2 {
-			return fmt.Errorf("need at least 2 availability zones")
-		}
-
-		// Create VPC
-		vpc, err := ec2.NewVpc(ctx, fmt.Sprintf("%s-vpc", projectName), &ec2.VpcArgs{
-			CidrBlock:          pulumi.String(vpcCidr),
-			EnableDnsHostnames: pulumi.Bool(true),
-			EnableDnsSupport:   pulumi.Bool(true),
-			Tags: pulumi.StringMap{
-				"Name":    pulumi.String(fmt.Sprintf("%s-vpc", projectName)),
-				"Project": pulumi.String(projectName),
-			},
-		})
+		lb, err := labels.New(ctx, cfg)
 		if err != nil {
 			return err
 		}
 
-		// Create Internet Gateway for public subnet
-		igw, err := ec2.NewInternetGateway(ctx, fmt.Sprintf("%s-igw", projectName), &ec2.InternetGatewayArgs{
-			VpcId: vpc.ID(),
-			Tags: pulumi.StringMap{
-				"Name":    pulumi.String(fmt.Sprintf("%s-igw", projectName)),
-				"Project": pulumi.String(projectName),
-			},
-		})
+		// Create the AWS provider for the stack's region
+		provider, region, err := providers.New(ctx, cfg, lb)
 		if err != nil {
 			return err
 		}
+		inRegion := pulumi.Provider(provider)
 
-		// Create Aurora Private Subnets (2 AZs)
-		auroraSubnet1, err := ec2.NewSubnet(ctx, fmt.Sprintf("%s-aurora-subnet-1", projectName), &ec2.SubnetArgs{
-			VpcId:            vpc.ID(),
-			CidrBlock:        pulumi.String("10.0.1.0/24"),
-			AvailabilityZone: pulumi.String(azs.Names[0]),
-			Tags: pulumi.StringMap{
-				"Name":    pulumi.String(fmt.Sprintf("%s-aurora-private-subnet-az1", projectName)),
-				"Project": pulumi.String(projectName),
-				"Type":    pulumi.String("private-aurora"),
-			},
-		})
+		// Get availability zones
+		azs, err := aws.GetAvailabilityZones(ctx, &aws.GetAvailabilityZonesArgs{
+			State: pulumi.StringRef("available"),
+		}, inRegion)
 		if err != nil {
 			return err
 		}
+		azNames := azs.Names
 
-		auroraSubnet2, err := ec2.NewSubnet(ctx, fmt.Sprintf("%s-aurora-subnet-2", projectName), &ec2.SubnetArgs{
-			VpcId:            vpc.ID(),
-			CidrBlock:        pulumi.String("10.0.2.0/24"),
-			AvailabilityZone: pulumi.String(azs.Names[1]),
-			Tags: pulumi.StringMap{
-				"Name":    pulumi.String(fmt.Sprintf("%s-aurora-private-subnet-az2", projectName)),
-				"Project": pulumi.String(projectName),
-				"Type":    pulumi.String("private-aurora"),
-			},
-		})
-		if err != nil {
-			return err
+		// Look up the VPC and subnets to reuse instead of creating them; the
+		// Aurora subnets' zones are the lab's availability zones
+		var existing *components.ExistingVpcArgs
+		if settings.ExistingVpcId != "" {
+			existing, err = lookupExistingVpc(ctx, settings, inRegion)
+			if err != nil {
+				return err
+			}
+			azNames = nil
+			for _, subnet := range existing.AuroraSubnets {
+				azNames = append(azNames, subnet.AvailabilityZone)
+			}
 		}
 
-		// Create EC2 Public Subnet (1 AZ)
-		ec2Subnet, err := ec2.NewSubnet(ctx, fmt.Sprintf("%s-ec2-subnet", projectName), &ec2.SubnetArgs{
-			VpcId:                   vpc.ID(),
-			CidrBlock:               pulumi.String("10.0.10.0/24"),
-			AvailabilityZone:        pulumi.String(azs.Names[0]),
-			MapPublicIpOnLaunch:     pulumi.Bool(true),
-			Tags: pulumi.StringMap{
-				"Name":    pulumi.String(fmt.Sprintf("%s-ec2-public-subnet-az1", projectName)),
-				"Project": pulumi.String(projectName),
-				"Type":    pulumi.String("public-ec2"),
-			},
-		})
-		if err != nil {
-			return err
+		// Record the VPC's traffic to inspect connection resets during switchover
+		var flowLogs *components.FlowLogsArgs
+		if settings.FlowLogs {
+			flowLogs = &components.FlowLogsArgs{
+				Destination:   settings.FlowLogDestination,
+				TrafficType:   settings.FlowLogTrafficType,
+				RetentionDays: settings.FlowLogRetentionDays,
+			}
 		}
 
-		// Create EKS Private Subnets (2 AZs) - Optional
-		eksSubnet1, err := ec2.NewSubnet(ctx, fmt.Sprintf("%s-eks-subnet-1", projectName), &ec2.SubnetArgs{
-			VpcId:            vpc.ID(),
-			CidrBlock:        pulumi.String("10.0.20.0/24"),
-			AvailabilityZone: pulumi.String(azs.Names[0]),
-			Tags: pulumi.StringMap{
-				"Name":    pulumi.String(fmt.Sprintf("%s-eks-private-subnet-az1", projectName)),
-				"Project": pulumi.String(projectName),
-				"Type":    pulumi.String("private-eks"),
-			},
-		})
+		// Create the lab network
+		network, err := components.NewLabVpc(ctx, lb.Name("network"), &components.LabVpcArgs{
+			Labels:              lb,
+			CidrBlock:           settings.VpcCidr,
+			SecondaryCidrBlocks: settings.SecondaryCidrBlocks,
+			EksSubnetCidrs:      settings.EksSubnetCidrs,
+			AvailabilityZones:   azNames,
+			SshCidrBlock:        settings.SshCidr,
+			StrictNetworkAcls:   settings.StrictNetworkAcls,
+			FlowLogs:            flowLogs,
+			EnableIpv6:          settings.EnableIpv6,
+			NatGateway:          settings.NatGateway,
+			Existing:            existing,
+		}, inRegion)
 		if err != nil {
 			return err
 		}
 
-		eksSubnet2, err := ec2.NewSubnet(ctx, fmt.Sprintf("%s-eks-subnet-2", projectName), &ec2.SubnetArgs{
-			VpcId:            vpc.ID(),
-			CidrBlock:        pulumi.String("10.0.21.0/24"),
-			AvailabilityZone: pulumi.String(azs.Names[1]),
-			Tags: pulumi.StringMap{
-				"Name":    pulumi.String(fmt.Sprintf("%s-eks-private-subnet-az2", projectName)),
-				"Project": pulumi.String(projectName),
-				"Type":    pulumi.String("private-eks"),
-			},
-		})
-		if err != nil {
-			return err
+		// Export outputs
+		ctx.Export("region", pulumi.String(region))
+		ctx.Export("vpcId", network.Vpc.ID())
+		ctx.Export("vpcCidr", network.Vpc.CidrBlock)
+		ctx.Export("secondaryCidrBlocks", pulumi.ToStringArray(settings.SecondaryCidrBlocks))
+		ctx.Export("auroraSubnet1Id", network.AuroraSubnets[0].ID())
+		ctx.Export("auroraSubnet2Id", network.AuroraSubnets[1].ID())
+		ctx.Export("ec2SubnetId", network.Ec2Subnet.ID())
+		if network.Ec2Subnet2 != nil {
+			ctx.Export("ec2Subnet2Id", network.Ec2Subnet2.ID())
 		}
-
-		// Create Route Table for Public Subnet
-		publicRouteTable, err := ec2.NewRouteTable(ctx, fmt.Sprintf("%s-public-rt", projectName), &ec2.RouteTableArgs{
-			VpcId: vpc.ID(),
-			Tags: pulumi.StringMap{
-				"Name":    pulumi.String(fmt.Sprintf("%s-public-route-table", projectName)),
-				"Project": pulumi.String(projectName),
-			},
-		})
-		if err != nil {
-			return err
+		if len(network.EksSubnets) == 2 {
+			ctx.Export("eksSubnet1Id", network.EksSubnets[0].ID())
+			ctx.Export("eksSubnet2Id", network.EksSubnets[1].ID())
 		}
-
-		// Add route to Internet Gateway
-		_, err = ec2.NewRoute(ctx, fmt.Sprintf("%s-public-route", projectName), &ec2.RouteArgs{
-			RouteTableId:         publicRouteTable.ID(),
-			DestinationCidrBlock: pulumi.String("0.0.0.0/0"),
-			GatewayId:            igw.ID(),
-		})
-		if err != nil {
-			return err
+		ctx.Export("auroraSecurityGroupId", network.AuroraSecurityGroup.ID())
+		ctx.Export("ec2SecurityGroupId", network.Ec2SecurityGroup.ID())
+		ctx.Export("eksSecurityGroupId", network.EksSecurityGroup.ID())
+		ctx.Export("instanceConnectSecurityGroupId", network.InstanceConnectSecurityGroup.ID())
+		ctx.Export("existingVpc", pulumi.Bool(existing != nil))
+		if existing == nil {
+			ctx.Export("internetGatewayId", network.InternetGateway.ID())
+			ctx.Export("publicRouteTableId", network.PublicRouteTable.ID())
+			ctx.Export("privateRouteTableId", network.PrivateRouteTable.ID())
 		}
-
-		// Associate public route table with EC2 subnet
-		_, err = ec2.NewRouteTableAssociation(ctx, fmt.Sprintf("%s-ec2-rt-assoc", projectName), &ec2.RouteTableAssociationArgs{
-			SubnetId:     ec2Subnet.ID(),
-			RouteTableId: publicRouteTable.ID(),
-		})
-		if err != nil {
-			return err
+		ctx.Export("availabilityZone1", pulumi.String(azNames[0]))
+		ctx.Export("availabilityZone2", pulumi.String(azNames[1]))
+		ctx.Export("strictNetworkAcls", pulumi.Bool(settings.StrictNetworkAcls))
+		if network.AuroraNetworkAcl != nil {
+			ctx.Export("auroraNetworkAclId", network.AuroraNetworkAcl.ID())
 		}
-
-		// Create Route Table for Private Subnets (Aurora and EKS)
-		privateRouteTable, err := ec2.NewRouteTable(ctx, fmt.Sprintf("%s-private-rt", projectName), &ec2.RouteTableArgs{
-			VpcId: vpc.ID(),
-			Tags: pulumi.StringMap{
-				"Name":    pulumi.String(fmt.Sprintf("%s-private-route-table", projectName)),
-				"Project": pulumi.String(projectName),
-			},
-		})
-		if err != nil {
-			return err
+		ctx.Export("ipv6Enabled", pulumi.Bool(settings.EnableIpv6))
+		if network.EgressOnlyInternetGateway != nil {
+			ctx.Export("vpcIpv6CidrBlock", network.Vpc.Ipv6CidrBlock)
+			ctx.Export("egressOnlyInternetGatewayId", network.EgressOnlyInternetGateway.ID())
 		}
-
-		// Associate private route table with Aurora subnets
-		_, err = ec2.NewRouteTableAssociation(ctx, fmt.Sprintf("%s-aurora-rt-assoc-1", projectName), &ec2.RouteTableAssociationArgs{
-			SubnetId:     auroraSubnet1.ID(),
-			RouteTableId: privateRouteTable.ID(),
-		})
-		if err != nil {
-			return err
+		if network.NatGateway != nil {
+			ctx.Export("natGatewayId", network.NatGateway.ID())
+			ctx.Export("natPublicIp", network.NatEip.PublicIp)
 		}
-
-		_, err = ec2.NewRouteTableAssociation(ctx, fmt.Sprintf("%s-aurora-rt-assoc-2", projectName), &ec2.RouteTableAssociationArgs{
-			SubnetId:     auroraSubnet2.ID(),
-			RouteTableId: privateRouteTable.ID(),
-		})
-		if err != nil {
-			return err
+		if network.FlowLog != nil {
+			ctx.Export("flowLogId", network.FlowLog.ID())
 		}
-
-		// Associate private route table with EKS subnets
-		_, err = ec2.NewRouteTableAssociation(ctx, fmt.Sprintf("%s-eks-rt-assoc-1", projectName), &ec2.RouteTableAssociationArgs{
-			SubnetId:     eksSubnet1.ID(),
-			RouteTableId: privateRouteTable.ID(),
-		})
-		if err != nil {
-			return err
+		if network.FlowLogGroup != nil {
+			ctx.Export("flowLogGroupName", network.FlowLogGroup.Name)
 		}
-
-		_, err = ec2.NewRouteTableAssociation(ctx, fmt.Sprintf("%s-eks-rt-assoc-2", projectName), &ec2.RouteTableAssociationArgs{
-			SubnetId:     eksSubnet2.ID(),
-			RouteTableId: privateRouteTable.ID(),
-		})
-		if err != nil {
-			return err
+		if network.FlowLogBucket != nil {
+			ctx.Export("flowLogBucketName", network.FlowLogBucket.Bucket)
 		}
 
-		// Create Security Group for Aurora
-		auroraSg, err := ec2.NewSecurityGroup(ctx, fmt.Sprintf("%s-aurora-sg", projectName), &ec2.SecurityGroupArgs{
-			VpcId:       vpc.ID(),
-			Description: pulumi.String("Security group for Aurora MySQL cluster"),
-			Ingress: ec2.SecurityGroupIngressArray{
-				&ec2.SecurityGroupIngressArgs{
-					Protocol:   pulumi.String("tcp"),
-					FromPort:   pulumi.Int(3306),
-					ToPort:     pulumi.Int(3306),
-					CidrBlocks: pulumi.StringArray{
-						pulumi.String("10.0.10.0/24"), // EC2 subnet
-						pulumi.String("10.0.20.0/24"), // EKS subnet 1
-						pulumi.String("10.0.21.0/24"), // EKS subnet 2
-					},
-					Description: pulumi.String("MySQL access from EC2 and EKS subnets"),
-				},
-			},
-			Egress: ec2.SecurityGroupEgressArray{
-				&ec2.SecurityGroupEgressArgs{
-					Protocol:   pulumi.String("-1"),
-					FromPort:   pulumi.Int(0),
-					ToPort:     pulumi.Int(0),
-					CidrBlocks: pulumi.StringArray{pulumi.String("0.0.0.0/0")},
-				},
-			},
-			Tags: pulumi.StringMap{
-				"Name":    pulumi.String(fmt.Sprintf("%s-aurora-sg", projectName)),
-				"Project": pulumi.String(projectName),
-			},
-		})
-		if err != nil {
-			return err
+		// The network itself is free unless it has a NAT gateway; the other
+		// public addresses belong to the instances. Flow logs and NAT data
+		// processing are billed by volume, which the estimate leaves out
+		var estimate cost.Estimate
+		if network.NatGateway != nil {
+			estimate.NatGateways(1)
+			estimate.PublicIPv4(1)
 		}
-
-		// Create Security Group for EC2
-		ec2Sg, err := ec2.NewSecurityGroup(ctx, fmt.Sprintf("%s-ec2-sg", projectName), &ec2.SecurityGroupArgs{
-			VpcId:       vpc.ID(),
-			Description: pulumi.String("Security group for EC2 workload simulator"),
-			Ingress: ec2.SecurityGroupIngressArray{
-				&ec2.SecurityGroupIngressArgs{
-					Protocol:    pulumi.String("tcp"),
-					FromPort:    pulumi.Int(22),
-					ToPort:      pulumi.Int(22),
-					CidrBlocks:  pulumi.StringArray{pulumi.String("0.0.0.0/0")},
-					Description: pulumi.String("SSH access"),
-				},
-			},
-			Egress: ec2.SecurityGroupEgressArray{
-				&ec2.SecurityGroupEgressArgs{
-					Protocol:   pulumi.String("-1"),
-					FromPort:   pulumi.Int(0),
-					ToPort:     pulumi.Int(0),
-					CidrBlocks: pulumi.StringArray{pulumi.String("0.0.0.0/0")},
-				},
-			},
-			Tags: pulumi.StringMap{
-				"Name":    pulumi.String(fmt.Sprintf("%s-ec2-sg", projectName)),
-				"Project": pulumi.String(projectName),
-			},
-		})
-		if err != nil {
+		if err := estimate.Export(ctx); err != nil {
 			return err
 		}
 
-		// Create Security Group for EKS
-		eksSg, err := ec2.NewSecurityGroup(ctx, fmt.Sprintf("%s-eks-sg", projectName), &ec2.SecurityGroupArgs{
-			VpcId:       vpc.ID(),
-			Description: pulumi.String("Security group for EKS cluster nodes"),
-			Egress: ec2.SecurityGroupEgressArray{
-				&ec2.SecurityGroupEgressArgs{
-					Protocol:   pulumi.String("-1"),
-					FromPort:   pulumi.Int(0),
-					ToPort:     pulumi.Int(0),
-					CidrBlocks: pulumi.StringArray{pulumi.String("0.0.0.0/0")},
-				},
-			},
-			Tags: pulumi.StringMap{
-				"Name":    pulumi.String(fmt.Sprintf("%s-eks-sg", projectName)),
-				"Project": pulumi.String(projectName),
-			},
-		})
-		if err != nil {
-			return err
-		}
+		// Publish the key outputs for runtime discovery without Pulumi access
+		parameters := map[string]pulumi.StringInput{
+			"region":                pulumi.String(region),
+			"vpcId":                 network.Vpc.ID(),
+			"auroraSubnet1Id":       network.AuroraSubnets[0].ID(),
+			"auroraSubnet2Id":       network.AuroraSubnets[1].ID(),
+			"ec2SubnetId":           network.Ec2Subnet.ID(),
+			"auroraSecurityGroupId": network.AuroraSecurityGroup.ID(),
+			"ec2SecurityGroupId":    network.Ec2SecurityGroup.ID(),
+			"eksSecurityGroupId":    network.EksSecurityGroup.ID(),
 
-		// Allow EKS nodes to communicate with each other
-		_, err = ec2.NewSecurityGroupRule(ctx, fmt.Sprintf("%s-eks-self-ingress", projectName), &ec2.SecurityGroupRuleArgs{
-			Type:                  pulumi.String("ingress"),
-			FromPort:              pulumi.Int(0),
-			ToPort:                pulumi.Int(65535),
-			Protocol:              pulumi.String("-1"),
-			SourceSecurityGroupId: eksSg.ID(),
-			SecurityGroupId:       eksSg.ID(),
-			Description:           pulumi.String("Allow nodes to communicate with each other"),
-		})
+			"instanceConnectSecurityGroupId": network.InstanceConnectSecurityGroup.ID(),
+		}
+		if network.Ec2Subnet2 != nil {
+			parameters["ec2Subnet2Id"] = network.Ec2Subnet2.ID()
+		}
+		if len(network.EksSubnets) == 2 {
+			parameters["eksSubnet1Id"] = network.EksSubnets[0].ID()
+			parameters["eksSubnet2Id"] = network.EksSubnets[1].ID()
+		}
+		outputParameters, err := components.NewLabOutputParameters(ctx, lb.Name("vpc-outputs"), &components.LabOutputParametersArgs{
+			Labels: lb,
+			Stack:  "vpc",
+			Values: parameters,
+		}, inRegion)
 		if err != nil {
 			return err
 		}
-
-		// Export outputs
-		ctx.Export("vpcId", vpc.ID())
-		ctx.Export("vpcCidr", vpc.CidrBlock)
-		ctx.Export("auroraSubnet1Id", auroraSubnet1.ID())
-		ctx.Export("auroraSubnet2Id", auroraSubnet2.ID())
-		ctx.Export("ec2SubnetId", ec2Subnet.ID())
-		ctx.Export("eksSubnet1Id", eksSubnet1.ID())
-		ctx.Export("eksSubnet2Id", eksSubnet2.ID())
-		ctx.Export("auroraSecurityGroupId", auroraSg.ID())
-		ctx.Export("ec2SecurityGroupId", ec2Sg.ID())
-		ctx.Export("eksSecurityGroupId", eksSg.ID())
-		ctx.Export("internetGatewayId", igw.ID())
-		ctx.Export("publicRouteTableId", publicRouteTable.ID())
-		ctx.Export("privateRouteTableId", privateRouteTable.ID())
-		ctx.Export("availabilityZone1", pulumi.String(azs.Names[0]))
-		ctx.Export("availabilityZone2", pulumi.String(azs.Names[1]))
+		ctx.Export("outputParameterPrefix", pulumi.String(outputParameters.Prefix))
 
 		return nil
 	})
 }
+
+// lookupExistingVpc looks up the VPC and subnets configured with
+// existingVpcId; NewLabVpc checks that they can host the lab.
+func lookupExistingVpc(ctx *pulumi.Context, settings *labconfig.Vpc, opts ...pulumi.InvokeOption) (*components.ExistingVpcArgs, error) {
+	vpc, err := ec2.LookupVpc(ctx, &ec2.LookupVpcArgs{Id: pulumi.StringRef(settings.ExistingVpcId)}, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("looking up existingVpcId %s: %w", settings.ExistingVpcId, err)
+	}
+
+	lookupSubnet := func(id string) (components.ExistingSubnet, error) {
+		subnet, err := ec2.LookupSubnet(ctx, &ec2.LookupSubnetArgs{Id: pulumi.StringRef(id)}, opts...)
+		if err != nil {
+			return components.ExistingSubnet{}, fmt.Errorf("looking up subnet %s: %w", id, err)
+		}
+		return components.ExistingSubnet{Id: id, VpcId: subnet.VpcId, AvailabilityZone: subnet.AvailabilityZone}, nil
+	}
+
+	existing := &components.ExistingVpcArgs{
+		VpcId:              vpc.Id,
+		EnableDnsSupport:   vpc.EnableDnsSupport,
+		EnableDnsHostnames: vpc.EnableDnsHostnames,
+	}
+	if existing.Ec2Subnet, err = lookupSubnet(settings.ExistingEc2SubnetId); err != nil {
+		return nil, err
+	}
+	for _, id := range settings.ExistingAuroraSubnetIds {
+		subnet, err := lookupSubnet(id)
+		if err != nil {
+			return nil, err
+		}
+		existing.AuroraSubnets = append(existing.AuroraSubnets, subnet)
+	}
+	for _, id := range settings.ExistingEksSubnetIds {
+		subnet, err := lookupSubnet(id)
+		if err != nil {
+			return nil, err
+		}
+		existing.EksSubnets = append(existing.EksSubnets, subnet)
+	}
+	return existing, nil
+}
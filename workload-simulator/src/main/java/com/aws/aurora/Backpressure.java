@@ -0,0 +1,82 @@
+package com.aws.aurora;
+
+import java.util.concurrent.Semaphore;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicInteger;
+import java.util.concurrent.atomic.AtomicLong;
+
+/**
+ * Backpressure of the write workers
+ * Bounds the operations in flight against the database. When the database slows down, for
+ * example while a switchover blocks writes, operations queue for a slot instead of all piling
+ * onto the connection pool; an operation that finds the queue full, or waits longer than the
+ * queue timeout, is shed: the worker records it and moves on to its next operation without
+ * touching the database. The client keeps running at a bounded load through the overload, and
+ * the shed count shows how much of the workload the database could not take.
+ */
+public class Backpressure {
+    private final int maxInFlight;
+    private final int maxQueued;
+    private final long queueTimeoutMs;
+    private final Semaphore slots;
+    private final AtomicInteger queued = new AtomicInteger(0);
+    private final AtomicLong shed = new AtomicLong(0);
+
+    /**
+     * @param maxInFlight    operations running against the database at once
+     * @param maxQueued      operations waiting for a slot at once; 0 for no limit
+     * @param queueTimeoutMs how long an operation waits for a slot before it is shed
+     * @param shed           operations shed by an earlier process of a resumed run
+     */
+    public Backpressure(int maxInFlight, int maxQueued, long queueTimeoutMs, long shed) {
+        this.maxInFlight = maxInFlight;
+        this.maxQueued = maxQueued;
+        this.queueTimeoutMs = queueTimeoutMs;
+        this.slots = new Semaphore(maxInFlight, true);
+        this.shed.set(shed);
+    }
+
+    /**
+     * Take a slot for an operation, waiting in the queue up to the queue timeout; false when the
+     * operation is shed. A taken slot must be given back with {@link #release()}.
+     */
+    public boolean acquire() throws InterruptedException {
+        if (slots.tryAcquire()) {
+            return true;
+        }
+        if (queued.incrementAndGet() > maxQueued && maxQueued > 0) {
+            queued.decrementAndGet();
+            shed.incrementAndGet();
+            return false;
+        }
+        try {
+            if (slots.tryAcquire(queueTimeoutMs, TimeUnit.MILLISECONDS)) {
+                return true;
+            }
+        } finally {
+            queued.decrementAndGet();
+        }
+        shed.incrementAndGet();
+        return false;
+    }
+
+    public void release() {
+        slots.release();
+    }
+
+    public int getInFlight() {
+        return maxInFlight - slots.availablePermits();
+    }
+
+    public int getQueued() {
+        return queued.get();
+    }
+
+    public long getShed() {
+        return shed.get();
+    }
+
+    public int getMaxInFlight() {
+        return maxInFlight;
+    }
+}
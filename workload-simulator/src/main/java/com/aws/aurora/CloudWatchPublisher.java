@@ -0,0 +1,107 @@
+package com.aws.aurora;
+
+import software.amazon.awssdk.core.client.config.ClientOverrideConfiguration;
+import software.amazon.awssdk.regions.Region;
+import software.amazon.awssdk.services.cloudwatch.CloudWatchClient;
+import software.amazon.awssdk.services.cloudwatch.CloudWatchClientBuilder;
+import software.amazon.awssdk.services.cloudwatch.model.Dimension;
+import software.amazon.awssdk.services.cloudwatch.model.MetricDatum;
+import software.amazon.awssdk.services.cloudwatch.model.PutMetricDataRequest;
+import software.amazon.awssdk.services.cloudwatch.model.StandardUnit;
+
+import java.time.Duration;
+import java.time.Instant;
+import java.util.ArrayList;
+import java.util.List;
+import java.util.regex.Matcher;
+import java.util.regex.Pattern;
+
+/**
+ * CloudWatch custom metrics publisher
+ * Every stats interval the success and failure counts of the interval and the latency and
+ * recovery percentiles are published as high-resolution custom metrics with a RunId dimension,
+ * so the simulator's view of the switchover can be graphed next to the Aurora metrics on the
+ * monitoring stack's dashboard.
+ */
+public class CloudWatchPublisher implements AutoCloseable {
+    // PutMetricData accepts up to 1000 metrics per request
+    private static final int MAX_BATCH = 1000;
+    static final Pattern ENDPOINT_REGION = Pattern.compile("\\.([a-z]{2}(-gov)?-[a-z]+-\\d)\\.rds\\.amazonaws\\.com$");
+
+    private final CloudWatchClient client;
+    private final String namespace;
+    private final Dimension runDimension;
+    private long lastSuccess;
+    private long lastFailed;
+
+    public CloudWatchPublisher(String namespace, String runId, String auroraEndpoint, long success, long failed) {
+        CloudWatchClientBuilder builder = CloudWatchClient.builder()
+                .overrideConfiguration(ClientOverrideConfiguration.builder()
+                        .apiCallTimeout(Duration.ofSeconds(5))
+                        .build());
+        // Publish in the cluster's region; otherwise fall back to the default region provider chain
+        Matcher matcher = ENDPOINT_REGION.matcher(auroraEndpoint);
+        if (matcher.find()) {
+            builder.region(Region.of(matcher.group(1)));
+        }
+        this.client = builder.build();
+        this.namespace = namespace;
+        this.runDimension = Dimension.builder().name("RunId").value(runId).build();
+        // Counts are published per interval, starting from the (possibly resumed) counters
+        this.lastSuccess = success;
+        this.lastFailed = failed;
+    }
+
+    /**
+     * Publish the counts since the previous call and the interval's percentiles
+     */
+    public void publish(long success, long failed, List<LatencyTracker.Snapshot> latency,
+                        List<LatencyTracker.Snapshot> recovery) {
+        Instant now = Instant.now();
+        List<MetricDatum> data = new ArrayList<>();
+        data.add(datum("SuccessCount", success - lastSuccess, StandardUnit.COUNT, now));
+        data.add(datum("FailureCount", failed - lastFailed, StandardUnit.COUNT, now));
+        lastSuccess = success;
+        lastFailed = failed;
+
+        for (LatencyTracker.Snapshot s : latency) {
+            Dimension operation = Dimension.builder().name("Operation").value(s.operation).build();
+            data.add(datum("LatencyP50", s.p50, StandardUnit.MILLISECONDS, now, operation));
+            data.add(datum("LatencyP95", s.p95, StandardUnit.MILLISECONDS, now, operation));
+            data.add(datum("LatencyP99", s.p99, StandardUnit.MILLISECONDS, now, operation));
+            data.add(datum("LatencyP999", s.p999, StandardUnit.MILLISECONDS, now, operation));
+            data.add(datum("LatencyMax", s.max, StandardUnit.MILLISECONDS, now, operation));
+        }
+        for (LatencyTracker.Snapshot s : recovery) {
+            Dimension strategy = Dimension.builder().name("Strategy").value(s.operation).build();
+            data.add(datum("RecoveryTimeP50", s.p50, StandardUnit.MILLISECONDS, now, strategy));
+            data.add(datum("RecoveryTimeMax", s.max, StandardUnit.MILLISECONDS, now, strategy));
+        }
+
+        for (int i = 0; i < data.size(); i += MAX_BATCH) {
+            client.putMetricData(PutMetricDataRequest.builder()
+                    .namespace(namespace)
+                    .metricData(data.subList(i, Math.min(i + MAX_BATCH, data.size())))
+                    .build());
+        }
+    }
+
+    @Override
+    public void close() {
+        client.close();
+    }
+
+    private MetricDatum datum(String name, double value, StandardUnit unit, Instant timestamp, Dimension... dimensions) {
+        List<Dimension> all = new ArrayList<>();
+        all.add(runDimension);
+        all.addAll(List.of(dimensions));
+        return MetricDatum.builder()
+                .metricName(name)
+                .value(value)
+                .unit(unit)
+                .timestamp(timestamp)
+                .storageResolution(1)
+                .dimensions(all)
+                .build();
+    }
+}
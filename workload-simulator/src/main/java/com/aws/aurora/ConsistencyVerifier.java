@@ -0,0 +1,270 @@
+package com.aws.aurora;
+
+import com.zaxxer.hikari.HikariConfig;
+import com.zaxxer.hikari.HikariDataSource;
+import org.apache.commons.cli.*;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+import java.io.BufferedReader;
+import java.io.IOException;
+import java.nio.charset.StandardCharsets;
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.nio.file.Paths;
+import java.sql.Connection;
+import java.sql.PreparedStatement;
+import java.sql.ResultSet;
+import java.sql.SQLException;
+import java.util.ArrayList;
+import java.util.HashMap;
+import java.util.List;
+import java.util.Map;
+import java.util.TreeMap;
+
+/**
+ * Consistency verification across a Blue-Green switchover.
+ * Replays the write ledger recorded during a simulator run (--verify-ledger) against
+ * the current cluster endpoint (the green environment after switchover) and reports:
+ * - lost writes: acknowledged by the database but missing afterwards
+ * - duplicated writes: the same sequence ID stored more than once (e.g., a retried
+ *   write whose first attempt was committed before the connection dropped)
+ * - corrupted writes: stored payload does not match the recorded checksum
+ * - unacknowledged commits: rows committed although the client saw an error (informational)
+ *
+ * Usage: java -jar workload-simulator.jar verify --aurora-endpoint <endpoint> --ledger <file>
+ */
+public class ConsistencyVerifier {
+    private static final Logger logger = LoggerFactory.getLogger(ConsistencyVerifier.class);
+
+    // Maximum number of individual problems printed in the report
+    private static final int MAX_REPORTED_PROBLEMS = 20;
+
+    private final String auroraEndpoint;
+    private final String databaseName;
+    private final String username;
+    private final String password;
+    private final Path ledgerPath;
+
+    // Report
+    private long ledgerEntries;
+    private long verified;
+    private long lost;
+    private long duplicated;
+    private long corrupted;
+    private long unacknowledged;
+    private final List<String> problems = new ArrayList<>();
+
+    public ConsistencyVerifier(String auroraEndpoint, String databaseName, String username, String password,
+                               Path ledgerPath) {
+        this.auroraEndpoint = auroraEndpoint;
+        this.databaseName = databaseName;
+        this.username = username;
+        this.password = password;
+        this.ledgerPath = ledgerPath;
+    }
+
+    /**
+     * Verify every ledger entry against the database
+     *
+     * @return true when no writes were lost, duplicated, or corrupted
+     */
+    public boolean verify() throws IOException, SQLException {
+        Map<String, Map<Long, Long>> ledgerByTable = readLedger();
+
+        HikariConfig config = new HikariConfig();
+        config.setJdbcUrl(String.format("jdbc:mysql://%s:3306/%s", auroraEndpoint, databaseName));
+        config.setUsername(username);
+        config.setPassword(password);
+        config.setMaximumPoolSize(1);
+
+        try (HikariDataSource dataSource = new HikariDataSource(config);
+             Connection conn = dataSource.getConnection()) {
+            for (Map.Entry<String, Map<Long, Long>> table : ledgerByTable.entrySet()) {
+                verifyTable(conn, table.getKey(), table.getValue());
+            }
+        }
+
+        logReport(ledgerByTable.size());
+        return lost == 0 && duplicated == 0 && corrupted == 0;
+    }
+
+    /**
+     * Read the ledger grouped by table: table -> (seq -> checksum)
+     */
+    private Map<String, Map<Long, Long>> readLedger() throws IOException {
+        Map<String, Map<Long, Long>> ledgerByTable = new TreeMap<>();
+        try (BufferedReader reader = Files.newBufferedReader(ledgerPath, StandardCharsets.UTF_8)) {
+            String line;
+            while ((line = reader.readLine()) != null) {
+                if (line.isEmpty() || line.equals(WriteLedger.HEADER)) {
+                    continue;
+                }
+                String[] fields = line.split(",");
+                if (fields.length != 3) {
+                    throw new IOException("Malformed ledger line: " + line);
+                }
+                ledgerByTable.computeIfAbsent(fields[1], k -> new HashMap<>())
+                        .put(Long.parseLong(fields[0]), Long.parseLong(fields[2]));
+                ledgerEntries++;
+            }
+        }
+        return ledgerByTable;
+    }
+
+    /**
+     * Compare the rows of one table within the run's sequence range against the ledger
+     */
+    private void verifyTable(Connection conn, String tableName, Map<Long, Long> expected) throws SQLException {
+        long minSeq = Long.MAX_VALUE;
+        long maxSeq = Long.MIN_VALUE;
+        for (long seq : expected.keySet()) {
+            minSeq = Math.min(minSeq, seq);
+            maxSeq = Math.max(maxSeq, seq);
+        }
+
+        Map<Long, Integer> seen = new HashMap<>();
+        try (PreparedStatement stmt = conn.prepareStatement(
+                "SELECT col5, col1, col2, col3 FROM " + tableName + " WHERE col5 BETWEEN ? AND ?")) {
+            stmt.setLong(1, minSeq);
+            stmt.setLong(2, maxSeq);
+            try (ResultSet rs = stmt.executeQuery()) {
+                while (rs.next()) {
+                    long seq = rs.getLong(1);
+                    Long checksum = expected.get(seq);
+                    if (checksum == null) {
+                        unacknowledged++;
+                        continue;
+                    }
+                    seen.merge(seq, 1, Integer::sum);
+                    if (WriteLedger.checksum(rs.getString(2), rs.getInt(3), rs.getString(4)) != checksum) {
+                        corrupted++;
+                        addProblem("CORRUPTED: seq %d in %s does not match its checksum", seq, tableName);
+                    }
+                }
+            }
+        }
+
+        for (long seq : expected.keySet()) {
+            int count = seen.getOrDefault(seq, 0);
+            if (count == 0) {
+                lost++;
+                addProblem("LOST: seq %d in %s was acknowledged but is missing", seq, tableName);
+            } else {
+                verified++;
+                if (count > 1) {
+                    duplicated++;
+                    addProblem("DUPLICATED: seq %d in %s stored %d times", seq, tableName, count);
+                }
+            }
+        }
+    }
+
+    private void addProblem(String format, Object... args) {
+        if (problems.size() < MAX_REPORTED_PROBLEMS) {
+            problems.add(String.format(format, args));
+        }
+    }
+
+    private void logReport(int tables) {
+        boolean passed = lost == 0 && duplicated == 0 && corrupted == 0;
+
+        logger.info("=".repeat(80));
+        logger.info("CONSISTENCY REPORT");
+        logger.info("=".repeat(80));
+        logger.info("  Endpoint: {}", auroraEndpoint);
+        logger.info("  Ledger: {} ({} writes across {} tables)", ledgerPath, ledgerEntries, tables);
+        logger.info("  Verified: {}", verified);
+        logger.info("  Lost: {}", lost);
+        logger.info("  Duplicated: {}", duplicated);
+        logger.info("  Corrupted: {}", corrupted);
+        logger.info("  Unacknowledged commits: {} (committed although the client saw an error)", unacknowledged);
+        for (String problem : problems) {
+            logger.info("  {}", problem);
+        }
+        if (lost + duplicated + corrupted > problems.size()) {
+            logger.info("  ... {} more problems not shown", lost + duplicated + corrupted - problems.size());
+        }
+        logger.info("  Result: {}", passed ? "PASS" : "FAIL");
+        logger.info("=".repeat(80));
+    }
+
+    /**
+     * Entry point for the "verify" subcommand
+     *
+     * @return process exit code (0 = pass, 2 = consistency failure, 1 = error)
+     */
+    public static int run(String[] args) {
+        Options options = new Options();
+
+        options.addOption(Option.builder()
+                .longOpt("aurora-endpoint")
+                .hasArg()
+                .required()
+                .desc("Aurora cluster writer endpoint (required)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("ledger")
+                .hasArg()
+                .required()
+                .desc("Write ledger file recorded with --verify-ledger (required)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("database-name")
+                .hasArg()
+                .desc("Database name (default: lab_db)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("username")
+                .hasArg()
+                .desc("Database username (default: admin)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("password")
+                .hasArg()
+                .desc("Database password (default: from environment variable DB_PASSWORD)")
+                .build());
+
+        options.addOption("h", "help", false, "Show help message");
+
+        CommandLineParser parser = new DefaultParser();
+        HelpFormatter formatter = new HelpFormatter();
+
+        try {
+            CommandLine cmd = parser.parse(options, args);
+
+            if (cmd.hasOption("help")) {
+                formatter.printHelp("workload-simulator verify", options);
+                return 0;
+            }
+
+            String password = cmd.getOptionValue("password", System.getenv("DB_PASSWORD"));
+            if (password == null || password.isEmpty()) {
+                logger.error("Database password not provided. Use --password or set DB_PASSWORD environment variable.");
+                return 1;
+            }
+
+            ConsistencyVerifier verifier = new ConsistencyVerifier(
+                    cmd.getOptionValue("aurora-endpoint"),
+                    cmd.getOptionValue("database-name", "lab_db"),
+                    cmd.getOptionValue("username", "admin"),
+                    password,
+                    Paths.get(cmd.getOptionValue("ledger"))
+            );
+
+            return verifier.verify() ? 0 : 2;
+
+        } catch (ParseException e) {
+            logger.error("Failed to parse command line arguments: {}", e.getMessage());
+            formatter.printHelp("workload-simulator verify", options);
+            return 1;
+        } catch (Exception e) {
+            logger.error("Consistency verification failed", e);
+            return 1;
+        }
+    }
+}
@@ -0,0 +1,216 @@
+package com.aws.aurora;
+
+import com.sun.net.httpserver.HttpExchange;
+import com.sun.net.httpserver.HttpServer;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+import java.io.IOException;
+import java.io.OutputStream;
+import java.net.InetAddress;
+import java.net.InetSocketAddress;
+import java.net.URLDecoder;
+import java.nio.charset.StandardCharsets;
+import java.time.Instant;
+import java.time.format.DateTimeParseException;
+import java.util.ArrayList;
+import java.util.HashMap;
+import java.util.List;
+import java.util.Locale;
+import java.util.Map;
+import java.util.concurrent.Executors;
+
+/**
+ * HTTP control API of a running simulator
+ * Lets bgctl and lab-scenario coordinate with the workload instead of parsing its log. The API
+ * listens on the loopback interface only, so it is reached from the simulator host (through SSM
+ * or SSH). It is opt-in: the simulator starts it only on an explicit --control-port, as anything
+ * on the host could otherwise pause or stop the workload. Every response is a JSON object:
+ * <ul>
+ *   <li>GET /stats: the current counters, rates and state</li>
+ *   <li>POST /pause, POST /resume: stop and restart sending operations, keeping the run going</li>
+ *   <li>POST /stop: shut down as on SIGTERM</li>
+ *   <li>POST /rate?rate=N: change --write-rate, or --total-rate when set</li>
+ *   <li>POST /events?name=NAME&amp;detail=TEXT&amp;time=RFC3339: mark an event of the experiment</li>
+ *   <li>GET /events: the events marked so far</li>
+ * </ul>
+ */
+public class ControlServer implements AutoCloseable {
+    private static final Logger logger = LoggerFactory.getLogger(ControlServer.class);
+
+    /**
+     * The simulator operations the API exposes
+     */
+    public interface Controls {
+        Map<String, Object> stats();
+
+        void pause();
+
+        void resume();
+
+        void stop();
+
+        void setRate(int rate);
+
+        void markEvent(String name, String detail, Instant time);
+
+        List<Map<String, Object>> events();
+    }
+
+    private final HttpServer server;
+    private final Controls controls;
+
+    /**
+     * Listen on the given port; 0 is the simulator's "disabled" and never an ephemeral port
+     */
+    public ControlServer(int port, Controls controls) throws IOException {
+        if (port <= 0 || port > 65535) {
+            throw new IllegalArgumentException("control API port must be between 1 and 65535, got " + port);
+        }
+        this.controls = controls;
+        this.server = HttpServer.create(new InetSocketAddress(InetAddress.getLoopbackAddress(), port), 0);
+        server.createContext("/", this::handle);
+        server.setExecutor(Executors.newSingleThreadExecutor(r -> {
+            Thread thread = new Thread(r, "control-api");
+            thread.setDaemon(true);
+            return thread;
+        }));
+        server.start();
+    }
+
+    private void handle(HttpExchange exchange) throws IOException {
+        String method = exchange.getRequestMethod();
+        String path = exchange.getRequestURI().getPath();
+        Map<String, String> query = query(exchange.getRequestURI().getRawQuery());
+        try {
+            switch (method + " " + path) {
+                case "GET /stats":
+                    respond(exchange, 200, json(controls.stats()));
+                    break;
+                case "POST /pause":
+                    controls.pause();
+                    respond(exchange, 200, json(Map.of("state", "paused")));
+                    break;
+                case "POST /resume":
+                    controls.resume();
+                    respond(exchange, 200, json(Map.of("state", "running")));
+                    break;
+                case "POST /stop":
+                    respond(exchange, 200, json(Map.of("state", "stopping")));
+                    controls.stop();
+                    break;
+                case "POST /rate":
+                    int rate = Integer.parseInt(required(query, "rate"));
+                    if (rate < 1) {
+                        throw new IllegalArgumentException("rate must be at least 1, got " + rate);
+                    }
+                    controls.setRate(rate);
+                    respond(exchange, 200, json(Map.of("rate", rate)));
+                    break;
+                case "POST /events":
+                    String name = required(query, "name");
+                    Instant time = query.containsKey("time") ? Instant.parse(query.get("time")) : Instant.now();
+                    controls.markEvent(name, query.getOrDefault("detail", ""), time);
+                    respond(exchange, 200, json(Map.of("event", name, "time", time.toString())));
+                    break;
+                case "GET /events":
+                    respond(exchange, 200, json(Map.of("events", controls.events())));
+                    break;
+                default:
+                    respond(exchange, 404, json(Map.of("error", "unknown request " + method + " " + path)));
+            }
+        } catch (IllegalArgumentException | DateTimeParseException e) {
+            // NumberFormatException is an IllegalArgumentException
+            respond(exchange, 400, json(Map.of("error", String.valueOf(e.getMessage()))));
+        } catch (RuntimeException e) {
+            logger.error("Control API request {} {} failed", method, path, e);
+            respond(exchange, 500, json(Map.of("error", String.valueOf(e.getMessage()))));
+        }
+    }
+
+    private static String required(Map<String, String> query, String name) {
+        String value = query.get(name);
+        if (value == null || value.isEmpty()) {
+            throw new IllegalArgumentException("missing parameter " + name);
+        }
+        return value;
+    }
+
+    private static Map<String, String> query(String rawQuery) {
+        Map<String, String> values = new HashMap<>();
+        if (rawQuery == null) {
+            return values;
+        }
+        for (String pair : rawQuery.split("&")) {
+            int eq = pair.indexOf('=');
+            String key = eq >= 0 ? pair.substring(0, eq) : pair;
+            String value = eq >= 0 ? pair.substring(eq + 1) : "";
+            values.put(URLDecoder.decode(key, StandardCharsets.UTF_8), URLDecoder.decode(value, StandardCharsets.UTF_8));
+        }
+        return values;
+    }
+
+    private static void respond(HttpExchange exchange, int status, String body) throws IOException {
+        byte[] bytes = (body + "\n").getBytes(StandardCharsets.UTF_8);
+        exchange.getResponseHeaders().set("Content-Type", "application/json");
+        exchange.sendResponseHeaders(status, bytes.length);
+        try (OutputStream out = exchange.getResponseBody()) {
+            out.write(bytes);
+        }
+    }
+
+    /**
+     * JSON of maps, lists, strings, numbers and booleans
+     */
+    static String json(Object value) {
+        if (value == null) {
+            return "null";
+        }
+        if (value instanceof Map) {
+            List<String> members = new ArrayList<>();
+            for (Map.Entry<?, ?> entry : ((Map<?, ?>) value).entrySet()) {
+                members.add(json(String.valueOf(entry.getKey())) + ":" + json(entry.getValue()));
+            }
+            return "{" + String.join(",", members) + "}";
+        }
+        if (value instanceof List) {
+            List<String> elements = new ArrayList<>();
+            for (Object element : (List<?>) value) {
+                elements.add(json(element));
+            }
+            return "[" + String.join(",", elements) + "]";
+        }
+        if (value instanceof Double || value instanceof Float) {
+            return String.format(Locale.ROOT, "%.2f", ((Number) value).doubleValue());
+        }
+        if (value instanceof Number || value instanceof Boolean) {
+            return value.toString();
+        }
+        StringBuilder sb = new StringBuilder("\"");
+        for (char c : value.toString().toCharArray()) {
+            switch (c) {
+                case '"':
+                    sb.append("\\\"");
+                    break;
+                case '\\':
+                    sb.append("\\\\");
+                    break;
+                case '\n':
+                    sb.append("\\n");
+                    break;
+                default:
+                    if (c < 0x20) {
+                        sb.append(String.format("\\u%04x", (int) c));
+                    } else {
+                        sb.append(c);
+                    }
+            }
+        }
+        return sb.append('"').toString();
+    }
+
+    @Override
+    public void close() {
+        server.stop(0);
+    }
+}
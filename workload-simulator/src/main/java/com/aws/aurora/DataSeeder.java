@@ -0,0 +1,352 @@
+package com.aws.aurora;
+
+import com.zaxxer.hikari.HikariConfig;
+import com.zaxxer.hikari.HikariDataSource;
+import org.apache.commons.cli.*;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+import java.sql.Connection;
+import java.sql.PreparedStatement;
+import java.sql.SQLException;
+import java.sql.Statement;
+import java.util.ArrayList;
+import java.util.List;
+import java.util.Random;
+import java.util.concurrent.*;
+import java.util.concurrent.atomic.AtomicLong;
+
+/**
+ * Data seeding subsystem for the workload simulator.
+ * Creates the lab schema (test_0001 .. test_NNNN) and bulk loads rows with parallel
+ * batched inserts, so switchover tests run against clusters with meaningful data sizes.
+ *
+ * Usage: java -jar workload-simulator.jar seed --aurora-endpoint <endpoint> [options]
+ */
+public class DataSeeder {
+    private static final Logger logger = LoggerFactory.getLogger(DataSeeder.class);
+
+    // Secondary indexes in creation order; --secondary-indexes selects the first N
+    private static final String[][] SECONDARY_INDEXES = {
+            {"idx_col1", "col1"},
+            {"idx_col2", "col2"},
+            {"idx_col5", "col5"},
+    };
+
+    private static final String CHARS = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789";
+
+    // Configuration
+    private final String auroraEndpoint;
+    private final String databaseName;
+    private final String username;
+    private final String password;
+    private final int tableCount;
+    private final int rowsPerTable;
+    private final int secondaryIndexes;
+    private final int parallelism;
+    private final int batchSize;
+
+    // Statistics
+    private final AtomicLong tablesCompleted = new AtomicLong(0);
+    private final AtomicLong rowsInserted = new AtomicLong(0);
+    private final AtomicLong tablesFailed = new AtomicLong(0);
+
+    private HikariDataSource dataSource;
+
+    public DataSeeder(String auroraEndpoint, String databaseName, String username, String password,
+                      int tableCount, int rowsPerTable, int secondaryIndexes, int parallelism, int batchSize) {
+        this.auroraEndpoint = auroraEndpoint;
+        this.databaseName = databaseName;
+        this.username = username;
+        this.password = password;
+        this.tableCount = tableCount;
+        this.rowsPerTable = rowsPerTable;
+        this.secondaryIndexes = secondaryIndexes;
+        this.parallelism = parallelism;
+        this.batchSize = batchSize;
+    }
+
+    /**
+     * Initialize a plain MySQL connection pool (no Blue-Green plugins needed for bulk loading)
+     */
+    private void initializeDataSource() {
+        HikariConfig config = new HikariConfig();
+        config.setJdbcUrl(String.format("jdbc:mysql://%s:3306/%s", auroraEndpoint, databaseName));
+        config.setUsername(username);
+        config.setPassword(password);
+        config.setMaximumPoolSize(parallelism);
+        config.setMinimumIdle(parallelism);
+        config.setConnectionTimeout(30000); // 30 seconds
+
+        // Batched multi-row inserts for bulk loading
+        config.addDataSourceProperty("rewriteBatchedStatements", "true");
+        config.addDataSourceProperty("cachePrepStmts", "true");
+
+        this.dataSource = new HikariDataSource(config);
+    }
+
+    /**
+     * Create all tables and load data using a fixed pool of seeding threads
+     *
+     * @return true when every table was created and loaded successfully
+     */
+    public boolean seed() throws InterruptedException {
+        logConfiguration();
+        initializeDataSource();
+
+        long startTime = System.currentTimeMillis();
+        ExecutorService executor = Executors.newFixedThreadPool(parallelism);
+        ScheduledExecutorService progressExecutor = Executors.newSingleThreadScheduledExecutor();
+        progressExecutor.scheduleAtFixedRate(() -> logProgress(startTime), 10, 10, TimeUnit.SECONDS);
+
+        try {
+            List<Future<?>> futures = new ArrayList<>();
+            for (int i = 1; i <= tableCount; i++) {
+                String tableName = String.format("test_%04d", i);
+                futures.add(executor.submit(() -> seedTable(tableName)));
+            }
+            for (Future<?> future : futures) {
+                try {
+                    future.get();
+                } catch (ExecutionException e) {
+                    logger.error("Seeding task failed", e.getCause());
+                }
+            }
+        } finally {
+            executor.shutdownNow();
+            progressExecutor.shutdownNow();
+            dataSource.close();
+        }
+
+        logFinalSummary(startTime);
+        return tablesFailed.get() == 0;
+    }
+
+    /**
+     * Create a single table and bulk load its rows in batches
+     */
+    private void seedTable(String tableName) {
+        Random random = new Random();
+
+        try (Connection conn = dataSource.getConnection()) {
+            try (Statement stmt = conn.createStatement()) {
+                stmt.execute(createTableSql(tableName));
+            }
+
+            conn.setAutoCommit(false);
+            try (PreparedStatement stmt = conn.prepareStatement(
+                    "INSERT INTO " + tableName + " (col1, col2, col3, col4, col5) VALUES (?, ?, ?, ?, ?)")) {
+                int pending = 0;
+                for (int row = 1; row <= rowsPerTable; row++) {
+                    stmt.setString(1, randomString(random, 20));
+                    stmt.setInt(2, random.nextInt(1000));
+                    stmt.setString(3, randomString(random, 50));
+                    stmt.setDouble(4, random.nextDouble() * 1000);
+                    stmt.setLong(5, System.currentTimeMillis());
+                    stmt.addBatch();
+
+                    if (++pending == batchSize || row == rowsPerTable) {
+                        stmt.executeBatch();
+                        conn.commit();
+                        rowsInserted.addAndGet(pending);
+                        pending = 0;
+                    }
+                }
+            }
+
+            tablesCompleted.incrementAndGet();
+            logger.debug("Seeded table {} with {} rows", tableName, rowsPerTable);
+        } catch (SQLException e) {
+            tablesFailed.incrementAndGet();
+            logger.error("Failed to seed table {}: {}", tableName, e.getMessage());
+        }
+    }
+
+    /**
+     * Build the CREATE TABLE statement (same layout as scripts/init-schema.sh)
+     */
+    private String createTableSql(String tableName) {
+        StringBuilder sql = new StringBuilder();
+        sql.append("CREATE TABLE IF NOT EXISTS ").append(tableName).append(" (")
+                .append("id BIGINT AUTO_INCREMENT PRIMARY KEY, ")
+                .append("col1 VARCHAR(255) NOT NULL, ")
+                .append("col2 INT DEFAULT 0, ")
+                .append("col3 TEXT, ")
+                .append("col4 DECIMAL(10,2) DEFAULT 0.00, ")
+                .append("col5 BIGINT DEFAULT 0, ")
+                .append("created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, ")
+                .append("updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP");
+        for (int i = 0; i < secondaryIndexes; i++) {
+            sql.append(", INDEX ").append(SECONDARY_INDEXES[i][0]).append(" (").append(SECONDARY_INDEXES[i][1]).append(")");
+        }
+        sql.append(") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci");
+        return sql.toString();
+    }
+
+    private static String randomString(Random random, int length) {
+        StringBuilder sb = new StringBuilder(length);
+        for (int i = 0; i < length; i++) {
+            sb.append(CHARS.charAt(random.nextInt(CHARS.length())));
+        }
+        return sb.toString();
+    }
+
+    private void logProgress(long startTime) {
+        double elapsedSec = (System.currentTimeMillis() - startTime) / 1000.0;
+        long rows = rowsInserted.get();
+        logger.info("SEED PROGRESS: Tables: {}/{} | Failed: {} | Rows: {} | Rate: {} rows/sec",
+                tablesCompleted.get(), tableCount, tablesFailed.get(), rows,
+                String.format("%.0f", elapsedSec > 0 ? rows / elapsedSec : 0.0));
+    }
+
+    private void logConfiguration() {
+        logger.info("=".repeat(80));
+        logger.info("Aurora Blue-Green Deployment Lab - Data Seeder");
+        logger.info("=".repeat(80));
+        logger.info("  Aurora Endpoint: {}", auroraEndpoint);
+        logger.info("  Database Name: {}", databaseName);
+        logger.info("  Tables: {}", tableCount);
+        logger.info("  Rows per Table: {}", rowsPerTable);
+        logger.info("  Secondary Indexes: {}", secondaryIndexes);
+        logger.info("  Parallel Connections: {}", parallelism);
+        logger.info("  Batch Size: {}", batchSize);
+        logger.info("=".repeat(80));
+    }
+
+    private void logFinalSummary(long startTime) {
+        logger.info("=".repeat(80));
+        logger.info("SEED SUMMARY");
+        logger.info("=".repeat(80));
+        logProgress(startTime);
+        logger.info("  Duration: {} seconds", String.format("%.1f", (System.currentTimeMillis() - startTime) / 1000.0));
+        logger.info("=".repeat(80));
+    }
+
+    /**
+     * Entry point for the "seed" subcommand
+     *
+     * @return process exit code
+     */
+    public static int run(String[] args) {
+        Options options = new Options();
+
+        options.addOption(Option.builder()
+                .longOpt("aurora-endpoint")
+                .hasArg()
+                .required()
+                .desc("Aurora cluster writer endpoint (required)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("database-name")
+                .hasArg()
+                .desc("Database name (default: lab_db)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("username")
+                .hasArg()
+                .desc("Database username (default: admin)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("password")
+                .hasArg()
+                .desc("Database password (default: from environment variable DB_PASSWORD)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("tables")
+                .hasArg()
+                .type(Number.class)
+                .desc("Number of tables to create (default: 12000)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("rows-per-table")
+                .hasArg()
+                .type(Number.class)
+                .desc("Rows to insert into each table (default: 1000)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("secondary-indexes")
+                .hasArg()
+                .type(Number.class)
+                .desc("Secondary indexes per table, 0-" + SECONDARY_INDEXES.length + " (default: " + SECONDARY_INDEXES.length + ")")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("parallel")
+                .hasArg()
+                .type(Number.class)
+                .desc("Parallel seeding connections (default: 8)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("batch-size")
+                .hasArg()
+                .type(Number.class)
+                .desc("Rows per insert batch (default: 1000)")
+                .build());
+
+        options.addOption("h", "help", false, "Show help message");
+
+        CommandLineParser parser = new DefaultParser();
+        HelpFormatter formatter = new HelpFormatter();
+
+        try {
+            CommandLine cmd = parser.parse(options, args);
+
+            if (cmd.hasOption("help")) {
+                formatter.printHelp("workload-simulator seed", options);
+                return 0;
+            }
+
+            String password = cmd.getOptionValue("password", System.getenv("DB_PASSWORD"));
+            if (password == null || password.isEmpty()) {
+                logger.error("Database password not provided. Use --password or set DB_PASSWORD environment variable.");
+                return 1;
+            }
+
+            int tables = intOption(cmd, "tables", 12000);
+            int rowsPerTable = intOption(cmd, "rows-per-table", 1000);
+            int secondaryIndexes = intOption(cmd, "secondary-indexes", SECONDARY_INDEXES.length);
+            int parallel = intOption(cmd, "parallel", 8);
+            int batchSize = intOption(cmd, "batch-size", 1000);
+
+            // Validate parameters
+            if (tables < 1 || rowsPerTable < 0 || parallel < 1 || batchSize < 1) {
+                logger.error("Invalid seed parameters: tables and parallel must be >= 1, rows-per-table >= 0, batch-size >= 1");
+                return 1;
+            }
+            if (secondaryIndexes < 0 || secondaryIndexes > SECONDARY_INDEXES.length) {
+                logger.error("secondary-indexes must be between 0 and {}. Provided: {}",
+                        SECONDARY_INDEXES.length, secondaryIndexes);
+                return 1;
+            }
+
+            DataSeeder seeder = new DataSeeder(
+                    cmd.getOptionValue("aurora-endpoint"),
+                    cmd.getOptionValue("database-name", "lab_db"),
+                    cmd.getOptionValue("username", "admin"),
+                    password,
+                    tables, rowsPerTable, secondaryIndexes, parallel, batchSize
+            );
+
+            return seeder.seed() ? 0 : 1;
+
+        } catch (ParseException e) {
+            logger.error("Failed to parse command line arguments: {}", e.getMessage());
+            formatter.printHelp("workload-simulator seed", options);
+            return 1;
+        } catch (Exception e) {
+            logger.error("Data seeding failed", e);
+            return 1;
+        }
+    }
+
+    private static int intOption(CommandLine cmd, String name, int defaultValue) throws ParseException {
+        return cmd.hasOption(name) ? ((Number) cmd.getParsedOptionValue(name)).intValue() : defaultValue;
+    }
+}
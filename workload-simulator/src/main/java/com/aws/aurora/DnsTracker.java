@@ -0,0 +1,227 @@
+package com.aws.aurora;
+
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+import javax.naming.NamingEnumeration;
+import javax.naming.NamingException;
+import javax.naming.directory.Attribute;
+import javax.naming.directory.Attributes;
+import javax.naming.directory.DirContext;
+import javax.naming.directory.InitialDirContext;
+import java.net.InetAddress;
+import java.net.UnknownHostException;
+import java.util.ArrayList;
+import java.util.Collections;
+import java.util.HashMap;
+import java.util.Hashtable;
+import java.util.List;
+import java.util.Map;
+import java.util.concurrent.Executors;
+import java.util.concurrent.ScheduledExecutorService;
+import java.util.concurrent.TimeUnit;
+
+/**
+ * DNS propagation tracker
+ * Resolves the cluster and reader endpoints every second and logs when the resolved target
+ * changes (the CNAME flips from blue to green during switchover). Writer endpoint flips are
+ * correlated with the first successful write to the new writer host reported by the workers.
+ *
+ * Lookups go through the JNDI DNS provider so they bypass the JVM's InetAddress cache. With
+ * compareCached the writer endpoint is also resolved through InetAddress (as the JDBC driver
+ * does) and the tracker logs how long that cached resolution lagged behind DNS after a flip.
+ */
+public class DnsTracker {
+    private static final Logger logger = LoggerFactory.getLogger(DnsTracker.class);
+
+    private final Map<String, String> endpoints = new HashMap<>();
+    private final Map<String, String> lastTargets = new HashMap<>();
+    private final String writerEndpoint;
+    private final boolean compareCached;
+    private DirContext dnsContext;
+    private ScheduledExecutorService executor;
+
+    // Correlation state between DNS flips and worker host switches (epoch millis, 0 = none pending)
+    private long pendingDnsFlipTime;
+    private long pendingHostSwitchTime;
+    private String pendingHost;
+
+    // Cached (InetAddress) resolution of the writer endpoint that no longer matches DNS
+    private String staleAddresses;
+    private long staleSince;
+
+    public DnsTracker(String clusterEndpoint) {
+        this(clusterEndpoint, false);
+    }
+
+    public DnsTracker(String clusterEndpoint, boolean compareCached) {
+        this.writerEndpoint = clusterEndpoint;
+        this.compareCached = compareCached;
+        endpoints.put("cluster", clusterEndpoint);
+        // Reader endpoint: <name>.cluster-ro-<id>.<region>.rds.amazonaws.com
+        if (clusterEndpoint.contains(".cluster-") && !clusterEndpoint.contains(".cluster-ro-")) {
+            endpoints.put("reader", clusterEndpoint.replace(".cluster-", ".cluster-ro-"));
+        }
+    }
+
+    /**
+     * Start resolving endpoints in the background
+     */
+    public void start() throws NamingException {
+        Hashtable<String, String> env = new Hashtable<>();
+        env.put("java.naming.factory.initial", "com.sun.jndi.dns.DnsContextFactory");
+        dnsContext = new InitialDirContext(env);
+
+        executor = Executors.newSingleThreadScheduledExecutor(r -> {
+            Thread thread = new Thread(r, "dns-tracker");
+            thread.setDaemon(true);
+            return thread;
+        });
+        executor.scheduleAtFixedRate(this::resolveAll, 0, 1, TimeUnit.SECONDS);
+        logger.info("DNS tracker started for endpoints: {}", endpoints.values());
+    }
+
+    public void stop() {
+        if (executor != null) {
+            executor.shutdownNow();
+        }
+        if (dnsContext != null) {
+            try {
+                dnsContext.close();
+            } catch (NamingException e) {
+                logger.debug("Failed to close DNS context: {}", e.getMessage());
+            }
+        }
+    }
+
+    private void resolveAll() {
+        for (Map.Entry<String, String> endpoint : endpoints.entrySet()) {
+            String role = endpoint.getKey();
+            String host = endpoint.getValue();
+            String target;
+            try {
+                target = resolve(host);
+            } catch (NamingException e) {
+                logger.debug("[{}] DNS: Failed to resolve {} endpoint {}: {}",
+                        WorkloadSimulator.getCurrentTime(), role, host, e.getMessage());
+                continue;
+            }
+
+            String previous = lastTargets.put(role, target);
+            if (previous == null) {
+                logger.info("[{}] DNS: {} endpoint {} -> {}", WorkloadSimulator.getCurrentTime(), role, host, target);
+            } else if (!previous.equals(target)) {
+                logger.info("[{}] DNS: {} endpoint {} changed: {} -> {}",
+                        WorkloadSimulator.getCurrentTime(), role, host, previous, target);
+                if (host.equals(writerEndpoint)) {
+                    onWriterDnsFlip(System.currentTimeMillis());
+                }
+            }
+
+            if (compareCached && host.equals(writerEndpoint)) {
+                compareCachedResolution(host, target);
+            }
+        }
+    }
+
+    /**
+     * Compare the JVM's cached resolution of the writer endpoint with the addresses DNS currently
+     * returns for its target, logging when the cache goes stale and when it catches up
+     */
+    private void compareCachedResolution(String host, String target) {
+        String current;
+        String cached;
+        try {
+            // The target is either the CNAME (an instance endpoint) or already the endpoint's A records
+            current = target.matches("[0-9.,]+") ? target : String.join(",", addresses(target));
+            List<String> cachedAddresses = new ArrayList<>();
+            for (InetAddress address : InetAddress.getAllByName(host)) {
+                cachedAddresses.add(address.getHostAddress());
+            }
+            Collections.sort(cachedAddresses);
+            cached = String.join(",", cachedAddresses);
+        } catch (NamingException | UnknownHostException e) {
+            logger.debug("[{}] DNS: Failed to compare cached resolution of {}: {}",
+                    WorkloadSimulator.getCurrentTime(), host, e.getMessage());
+            return;
+        }
+
+        if (!cached.equals(current)) {
+            if (staleAddresses == null) {
+                staleAddresses = cached;
+                staleSince = System.currentTimeMillis();
+                logger.info("[{}] DNS: Cached resolution of cluster endpoint is stale: {} (DNS: {})",
+                        WorkloadSimulator.getCurrentTime(), cached, current);
+            }
+        } else if (staleAddresses != null) {
+            logger.info("[{}] DNS: Cached resolution of cluster endpoint caught up after {}ms: {}",
+                    WorkloadSimulator.getCurrentTime(), System.currentTimeMillis() - staleSince, cached);
+            staleAddresses = null;
+        }
+    }
+
+    /**
+     * Resolve the CNAME target of an endpoint, falling back to its sorted A records
+     */
+    private String resolve(String host) throws NamingException {
+        String cname = firstRecord(host, "CNAME");
+        if (cname != null) {
+            return cname.endsWith(".") ? cname.substring(0, cname.length() - 1) : cname;
+        }
+
+        List<String> addresses = addresses(host);
+        if (addresses.isEmpty()) {
+            throw new NamingException("no CNAME or A records");
+        }
+        return String.join(",", addresses);
+    }
+
+    /**
+     * Sorted A records of a host
+     */
+    private List<String> addresses(String host) throws NamingException {
+        List<String> addresses = new ArrayList<>();
+        Attributes attrs = dnsContext.getAttributes("dns:/" + host, new String[]{"A"});
+        Attribute a = attrs.get("A");
+        if (a != null) {
+            NamingEnumeration<?> values = a.getAll();
+            while (values.hasMore()) {
+                addresses.add(values.next().toString());
+            }
+        }
+        Collections.sort(addresses);
+        return addresses;
+    }
+
+    private String firstRecord(String host, String type) throws NamingException {
+        Attributes attrs = dnsContext.getAttributes("dns:/" + host, new String[]{type});
+        Attribute attr = attrs.get(type);
+        return attr != null && attr.size() > 0 ? attr.get().toString() : null;
+    }
+
+    private synchronized void onWriterDnsFlip(long flipTime) {
+        if (pendingHostSwitchTime > 0) {
+            // The JDBC wrapper connected to the new writer before DNS caught up
+            logger.info("[{}] DNS: Writer DNS flip observed {}ms after first successful write to new writer {}",
+                    WorkloadSimulator.getCurrentTime(), flipTime - pendingHostSwitchTime, pendingHost);
+            pendingHostSwitchTime = 0;
+            pendingHost = null;
+        } else {
+            pendingDnsFlipTime = flipTime;
+        }
+    }
+
+    /**
+     * Called by write workers after their first successful write to a different writer host
+     */
+    public synchronized void onWriterHostSwitch(String newHost, long writeTime) {
+        if (pendingDnsFlipTime > 0) {
+            logger.info("[{}] DNS: First successful write to new writer {} {}ms after writer DNS flip",
+                    WorkloadSimulator.getCurrentTime(), newHost, writeTime - pendingDnsFlipTime);
+            pendingDnsFlipTime = 0;
+        } else if (pendingHostSwitchTime == 0) {
+            pendingHostSwitchTime = writeTime;
+            pendingHost = newHost;
+        }
+    }
+}
@@ -0,0 +1,82 @@
+package com.aws.aurora;
+
+import javax.sql.DataSource;
+import java.io.PrintWriter;
+import java.sql.Connection;
+import java.sql.DriverManager;
+import java.sql.SQLException;
+import java.sql.SQLFeatureNotSupportedException;
+import java.util.Properties;
+import java.util.logging.Logger;
+
+/**
+ * Unpooled DataSource for the "fresh" connection strategy
+ * Every getConnection() opens a new connection through the AWS JDBC Wrapper driver (closed by the
+ * caller after the operation), so no connection ever outlives a switchover.
+ */
+public class FreshConnectionDataSource implements DataSource {
+    private final String jdbcUrl;
+    private final Properties properties;
+    private int loginTimeout;
+    private PrintWriter logWriter;
+
+    public FreshConnectionDataSource(String jdbcUrl, String username, String password, Properties driverProperties) {
+        this.jdbcUrl = jdbcUrl;
+        this.properties = new Properties();
+        this.properties.putAll(driverProperties);
+        this.properties.setProperty("user", username);
+        this.properties.setProperty("password", password);
+    }
+
+    @Override
+    public Connection getConnection() throws SQLException {
+        return DriverManager.getConnection(jdbcUrl, properties);
+    }
+
+    @Override
+    public Connection getConnection(String username, String password) throws SQLException {
+        Properties props = new Properties();
+        props.putAll(properties);
+        props.setProperty("user", username);
+        props.setProperty("password", password);
+        return DriverManager.getConnection(jdbcUrl, props);
+    }
+
+    @Override
+    public PrintWriter getLogWriter() {
+        return logWriter;
+    }
+
+    @Override
+    public void setLogWriter(PrintWriter out) {
+        this.logWriter = out;
+    }
+
+    @Override
+    public void setLoginTimeout(int seconds) {
+        this.loginTimeout = seconds;
+    }
+
+    @Override
+    public int getLoginTimeout() {
+        return loginTimeout;
+    }
+
+    @Override
+    public Logger getParentLogger() throws SQLFeatureNotSupportedException {
+        throw new SQLFeatureNotSupportedException();
+    }
+
+    @Override
+    public <T> T unwrap(Class<T> iface) throws SQLException {
+        if (iface.isInstance(this)) {
+            return iface.cast(this);
+        }
+        throw new SQLException("Not a wrapper for " + iface.getName());
+    }
+
+    @Override
+    public boolean isWrapperFor(Class<?> iface) {
+        return iface.isInstance(this);
+    }
+}
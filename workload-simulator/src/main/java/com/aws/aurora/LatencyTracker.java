@@ -0,0 +1,98 @@
+package com.aws.aurora;
+
+import org.HdrHistogram.Histogram;
+import org.HdrHistogram.Recorder;
+
+import java.util.ArrayList;
+import java.util.List;
+import java.util.Map;
+import java.util.concurrent.ConcurrentSkipListMap;
+
+/**
+ * Per-operation latency tracking backed by HdrHistogram
+ * Workers record the end-to-end latency of every successful operation (including retries, as
+ * seen by the application) in microseconds. Each log interval the interval histograms are
+ * reported as p50/p95/p99/p99.9 and folded into the run totals for the final report, so latency
+ * degradation during the switchover window is visible alongside the success rate.
+ */
+public class LatencyTracker {
+    private static final int SIGNIFICANT_DIGITS = 3;
+
+    // Sorted by operation type so report lines have a stable order
+    private final Map<String, Recorder> recorders = new ConcurrentSkipListMap<>();
+    private final Map<String, Histogram> totals = new ConcurrentSkipListMap<>();
+
+    /**
+     * Record the latency of a successful operation (thread-safe, wait-free)
+     */
+    public void record(String operation, long latencyNanos) {
+        recorders.computeIfAbsent(operation, op -> new Recorder(SIGNIFICANT_DIGITS))
+                .recordValue(Math.max(1, latencyNanos / 1_000));
+    }
+
+    /**
+     * Percentiles of the latencies recorded since the previous call, per operation
+     */
+    public synchronized List<Snapshot> interval() {
+        List<Snapshot> snapshots = new ArrayList<>();
+        for (Map.Entry<String, Recorder> entry : recorders.entrySet()) {
+            Histogram interval = entry.getValue().getIntervalHistogram();
+            totals.computeIfAbsent(entry.getKey(), op -> new Histogram(SIGNIFICANT_DIGITS)).add(interval);
+            if (interval.getTotalCount() > 0) {
+                snapshots.add(new Snapshot(entry.getKey(), interval));
+            }
+        }
+        return snapshots;
+    }
+
+    /**
+     * Percentiles for the whole run, per operation
+     */
+    public synchronized List<Snapshot> total() {
+        // Fold in latencies recorded after the last interval report
+        interval();
+        List<Snapshot> snapshots = new ArrayList<>();
+        for (Map.Entry<String, Histogram> entry : totals.entrySet()) {
+            if (entry.getValue().getTotalCount() > 0) {
+                snapshots.add(new Snapshot(entry.getKey(), entry.getValue()));
+            }
+        }
+        return snapshots;
+    }
+
+    /**
+     * Latency percentiles of one operation type, in milliseconds
+     */
+    public static final class Snapshot {
+        public final String operation;
+        public final long count;
+        public final double p50;
+        public final double p95;
+        public final double p99;
+        public final double p999;
+        public final double max;
+
+        private Snapshot(String operation, Histogram histogram) {
+            this.operation = operation;
+            this.count = histogram.getTotalCount();
+            this.p50 = millis(histogram.getValueAtPercentile(50));
+            this.p95 = millis(histogram.getValueAtPercentile(95));
+            this.p99 = millis(histogram.getValueAtPercentile(99));
+            this.p999 = millis(histogram.getValueAtPercentile(99.9));
+            this.max = millis(histogram.getMaxValue());
+        }
+
+        /**
+         * Report line: operation | Count | p50 | p95 | p99 | p99.9 | Max
+         */
+        @Override
+        public String toString() {
+            return String.format("%s | Count: %d | p50: %.2fms | p95: %.2fms | p99: %.2fms | p99.9: %.2fms | Max: %.2fms",
+                    operation, count, p50, p95, p99, p999, max);
+        }
+
+        private static double millis(long micros) {
+            return micros / 1000.0;
+        }
+    }
+}
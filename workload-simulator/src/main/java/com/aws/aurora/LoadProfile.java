@@ -0,0 +1,86 @@
+package com.aws.aurora;
+
+import java.util.List;
+import java.util.Locale;
+
+/**
+ * Load profile of the write workers
+ * Scales the write rate over time, so a switchover can be tested under a realistic traffic shape
+ * instead of a constant rate. Every shape moves between the minimum (a percentage of the write
+ * rate) and the full write rate over the profile duration:
+ * <ul>
+ *   <li>constant: the full write rate</li>
+ *   <li>ramp: rises linearly from the minimum to the full rate, then stays there</li>
+ *   <li>step: rises in equal steps from the minimum to the full rate, then stays there</li>
+ *   <li>spike: the minimum, with a burst at the full rate in the middle tenth of every duration</li>
+ *   <li>sine: a diurnal wave from the minimum up to the full rate and back, every duration</li>
+ * </ul>
+ * Time is measured from the start of the run, so a resumed run continues its profile.
+ */
+public class LoadProfile {
+    static final List<String> SHAPES = List.of("constant", "ramp", "step", "spike", "sine");
+
+    private final String shape;
+    private final long durationMs;
+    private final double minFactor;
+    private final int steps;
+
+    /**
+     * @param shape      one of SHAPES
+     * @param duration   seconds the shape plays over (its period for spike and sine)
+     * @param minPercent lowest rate as a percentage of the write rate
+     * @param steps      number of steps of the step shape
+     */
+    public LoadProfile(String shape, int duration, int minPercent, int steps) {
+        if (!SHAPES.contains(shape)) {
+            throw new IllegalArgumentException("unknown load profile " + shape + " (expected " + String.join(", ", SHAPES) + ")");
+        }
+        if (duration < 1 || minPercent < 0 || minPercent > 100 || steps < 2) {
+            throw new IllegalArgumentException("the profile duration must be at least 1 second, the minimum between 0 and 100% and the steps at least 2");
+        }
+        this.shape = shape;
+        this.durationMs = duration * 1000L;
+        this.minFactor = minPercent / 100.0;
+        this.steps = steps;
+    }
+
+    public boolean isConstant() {
+        return "constant".equals(shape);
+    }
+
+    /**
+     * Fraction of the write rate the workers run at, elapsedMs into the run
+     */
+    public double factor(long elapsedMs) {
+        double position = Math.max(0, elapsedMs) / (double) durationMs;
+        double level;
+        switch (shape) {
+            case "ramp":
+                level = Math.min(1.0, position);
+                break;
+            case "step":
+                // The first step runs at the minimum and the last at the full rate
+                level = Math.min(1.0, Math.floor(position * steps) / (steps - 1));
+                break;
+            case "spike":
+                double phase = position - Math.floor(position);
+                level = phase >= 0.45 && phase < 0.55 ? 1.0 : 0.0;
+                break;
+            case "sine":
+                level = (1 - Math.cos(2 * Math.PI * position)) / 2;
+                break;
+            default:
+                return 1.0;
+        }
+        return minFactor + (1 - minFactor) * level;
+    }
+
+    @Override
+    public String toString() {
+        if (isConstant()) {
+            return "constant";
+        }
+        return String.format(Locale.ROOT, "%s over %ds from %.0f%%%s", shape, durationMs / 1000, minFactor * 100,
+                "step".equals(shape) ? " in " + steps + " steps" : "");
+    }
+}
@@ -0,0 +1,123 @@
+package com.aws.aurora;
+
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+import javax.sql.DataSource;
+import java.sql.Connection;
+import java.sql.SQLException;
+import java.sql.Statement;
+import java.util.Random;
+import java.util.concurrent.ExecutorService;
+import java.util.concurrent.Executors;
+import java.util.concurrent.TimeUnit;
+
+/**
+ * Long-running transaction and lock-holding chaos mode
+ * Each holder keeps a write transaction (an uncommitted INSERT) or a LOCK TABLES ... WRITE open
+ * for the configured duration, then releases it and immediately starts the next one, so there is
+ * always long-running write activity on the blue cluster. Long-running writes are a documented
+ * cause of Blue/Green switchover timeouts; the holders let users reproduce that and observe how
+ * the switchover handles (or waits for) the open transactions.
+ */
+public class LockHolder {
+    private static final Logger logger = LoggerFactory.getLogger(LockHolder.class);
+
+    private final DataSource dataSource;
+    private final int count;
+    private final int durationSeconds;
+    private final boolean lockTables;
+    private ExecutorService executor;
+
+    public LockHolder(DataSource dataSource, int count, int durationSeconds, boolean lockTables) {
+        this.dataSource = dataSource;
+        this.count = count;
+        this.durationSeconds = durationSeconds;
+        this.lockTables = lockTables;
+    }
+
+    public void start() {
+        executor = Executors.newFixedThreadPool(count, r -> {
+            Thread thread = new Thread(r, "lock-holder");
+            thread.setDaemon(true);
+            return thread;
+        });
+        for (int i = 1; i <= count; i++) {
+            int holderId = i;
+            executor.submit(() -> run(holderId));
+        }
+        logger.info("Lock holder started: {} holders keeping {} open for {}s each",
+                count, lockTables ? "table locks" : "write transactions", durationSeconds);
+    }
+
+    public void stop() {
+        if (executor != null) {
+            executor.shutdownNow();
+        }
+    }
+
+    private void run(int holderId) {
+        Random random = new Random();
+        while (!Thread.currentThread().isInterrupted()) {
+            String tableName = String.format("test_%04d", random.nextInt(12000) + 1);
+            try {
+                hold(holderId, tableName);
+            } catch (InterruptedException e) {
+                Thread.currentThread().interrupt();
+            } catch (SQLException e) {
+                // Typically the switchover dropping the connection; start over after a short pause
+                try {
+                    Thread.sleep(1000);
+                } catch (InterruptedException ie) {
+                    Thread.currentThread().interrupt();
+                }
+            }
+        }
+    }
+
+    private void hold(int holderId, String tableName) throws SQLException, InterruptedException {
+        long start = System.nanoTime();
+        try (Connection conn = dataSource.getConnection();
+             Statement stmt = conn.createStatement()) {
+            if (lockTables) {
+                stmt.execute("LOCK TABLES " + tableName + " WRITE");
+            } else {
+                conn.setAutoCommit(false);
+                stmt.executeUpdate("INSERT INTO " + tableName + " (col1, col2, col3, col4, col5) " +
+                        "VALUES ('lock-holder', " + holderId + ", 'long-running transaction', 0, " +
+                        System.currentTimeMillis() + ")");
+            }
+            logger.info("[{}] HOLD: Holder-{} | {} {} (holding for {}s)", WorkloadSimulator.getCurrentTime(),
+                    holderId, lockTables ? "Locked table" : "Opened write transaction on", tableName, durationSeconds);
+
+            try {
+                Thread.sleep(TimeUnit.SECONDS.toMillis(durationSeconds));
+            } catch (InterruptedException e) {
+                // Shutting down: release without committing so nothing outlives the simulator
+                release(conn, stmt, false);
+                throw e;
+            }
+            release(conn, stmt, true);
+            logger.info("[{}] HOLD: Holder-{} | Released {} after {}s", WorkloadSimulator.getCurrentTime(),
+                    holderId, tableName, String.format("%.1f", elapsedSeconds(start)));
+        } catch (SQLException e) {
+            logger.warn("[{}] HOLD: Holder-{} | Lost {} after {}s: {}", WorkloadSimulator.getCurrentTime(),
+                    holderId, tableName, String.format("%.1f", elapsedSeconds(start)), e.getMessage());
+            throw e;
+        }
+    }
+
+    private void release(Connection conn, Statement stmt, boolean commit) throws SQLException {
+        if (lockTables) {
+            stmt.execute("UNLOCK TABLES");
+        } else if (commit) {
+            conn.commit();
+        } else {
+            conn.rollback();
+        }
+    }
+
+    private static double elapsedSeconds(long startNanos) {
+        return (System.nanoTime() - startNanos) / 1_000_000_000.0;
+    }
+}
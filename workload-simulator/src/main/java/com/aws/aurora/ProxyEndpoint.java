@@ -0,0 +1,49 @@
+package com.aws.aurora;
+
+import software.amazon.awssdk.core.client.config.ClientOverrideConfiguration;
+import software.amazon.awssdk.regions.Region;
+import software.amazon.awssdk.services.ssm.SsmClient;
+import software.amazon.awssdk.services.ssm.SsmClientBuilder;
+import software.amazon.awssdk.services.ssm.model.GetParameterRequest;
+import software.amazon.awssdk.services.ssm.model.ParameterNotFoundException;
+
+import java.time.Duration;
+import java.util.regex.Matcher;
+
+/**
+ * RDS Proxy endpoint discovery
+ * With --proxy-endpoint auto the endpoint is read from the aurora stack's proxyEndpoint output
+ * parameter in SSM Parameter Store, which the stack publishes when rdsProxy is enabled, so the
+ * simulator host needs no Pulumi access.
+ */
+public class ProxyEndpoint {
+    public static final String DEFAULT_PARAMETER = "/aurora-bluegreen-lab/aurora/proxyEndpoint";
+
+    private ProxyEndpoint() {
+    }
+
+    /**
+     * Return the proxy endpoint: value itself, or for "auto" the value of the SSM parameter, read
+     * in the cluster's region
+     */
+    public static String resolve(String value, String parameterName, String auroraEndpoint) {
+        if (!"auto".equals(value)) {
+            return value;
+        }
+        SsmClientBuilder builder = SsmClient.builder()
+                .overrideConfiguration(ClientOverrideConfiguration.builder()
+                        .apiCallTimeout(Duration.ofSeconds(10))
+                        .build());
+        Matcher matcher = CloudWatchPublisher.ENDPOINT_REGION.matcher(auroraEndpoint);
+        if (matcher.find()) {
+            builder.region(Region.of(matcher.group(1)));
+        }
+        try (SsmClient client = builder.build()) {
+            return client.getParameter(GetParameterRequest.builder().name(parameterName).build())
+                    .parameter().value();
+        } catch (ParameterNotFoundException e) {
+            throw new IllegalStateException("parameter " + parameterName
+                    + " does not exist; enable rdsProxy in the aurora stack or pass the endpoint", e);
+        }
+    }
+}
@@ -0,0 +1,56 @@
+package com.aws.aurora;
+
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.locks.ReentrantLock;
+import java.util.function.DoubleSupplier;
+
+/**
+ * Token bucket rate limiter of the write workers
+ * Hands out permits at a rate that may change over time (the load profile), so workers hold
+ * the target rate however long their operations take, instead of sleeping a fixed delay after
+ * each one. A limiter shared by several workers serves them in arrival order through a fair
+ * lock, so each worker gets an equal share of a global rate. The bucket holds at most a tenth
+ * of a second of permits: enough to catch up on a slow operation, without a burst of writes
+ * after an outage.
+ */
+public class RateLimiter {
+    private static final double BURST_SECONDS = 0.1;
+    // The longest a waiting worker sleeps before it reads the rate again
+    private static final long MAX_WAIT_NANOS = TimeUnit.SECONDS.toNanos(1);
+
+    private final DoubleSupplier rate;
+    private final ReentrantLock lock = new ReentrantLock(true);
+    private double tokens = 1;
+    private long refilledAt = System.nanoTime();
+
+    /**
+     * @param rate permits per second, read on every acquire
+     */
+    public RateLimiter(DoubleSupplier rate) {
+        this.rate = rate;
+    }
+
+    /**
+     * Wait for a permit; workers waiting on a shared limiter are served first come, first served
+     */
+    public void acquire() throws InterruptedException {
+        lock.lockInterruptibly();
+        try {
+            while (true) {
+                double current = rate.getAsDouble();
+                long now = System.nanoTime();
+                double capacity = Math.max(1, current * BURST_SECONDS);
+                tokens = Math.min(capacity, tokens + (now - refilledAt) / 1e9 * current);
+                refilledAt = now;
+                if (tokens >= 1) {
+                    tokens -= 1;
+                    return;
+                }
+                long waitNanos = current > 0 ? (long) Math.ceil((1 - tokens) / current * 1e9) : MAX_WAIT_NANOS;
+                TimeUnit.NANOSECONDS.sleep(Math.min(waitNanos, MAX_WAIT_NANOS));
+            }
+        } finally {
+            lock.unlock();
+        }
+    }
+}
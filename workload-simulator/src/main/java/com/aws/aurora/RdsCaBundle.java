@@ -0,0 +1,134 @@
+package com.aws.aurora;
+
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+import java.io.IOException;
+import java.io.InputStream;
+import java.io.OutputStream;
+import java.net.URI;
+import java.net.http.HttpClient;
+import java.net.http.HttpRequest;
+import java.net.http.HttpResponse;
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.nio.file.Paths;
+import java.nio.file.StandardCopyOption;
+import java.security.GeneralSecurityException;
+import java.security.KeyStore;
+import java.security.SecureRandom;
+import java.security.cert.Certificate;
+import java.security.cert.CertificateFactory;
+import java.time.Duration;
+import java.util.Base64;
+import java.util.Collection;
+
+/**
+ * RDS certificate authority bundle for TLS connections
+ * The global bundle is bundled into the jar at build time (see pom.xml); when it is missing it is
+ * downloaded from the RDS trust store and cached under ~/.aurora-lab. The certificates are written
+ * to a temporary PKCS12 trust store for MySQL Connector/J, which does not read PEM files.
+ */
+public class RdsCaBundle {
+    private static final Logger logger = LoggerFactory.getLogger(RdsCaBundle.class);
+
+    static final String BUNDLE_URL = "https://truststore.pki.rds.amazonaws.com/global/global-bundle.pem";
+    private static final String BUNDLED_RESOURCE = "/rds/global-bundle.pem";
+
+    private final Path trustStore;
+    private final String password;
+    private final int certificateCount;
+
+    private RdsCaBundle(Path trustStore, String password, int certificateCount) {
+        this.trustStore = trustStore;
+        this.password = password;
+        this.certificateCount = certificateCount;
+    }
+
+    /**
+     * Load the CA bundle from pemPath, or from the bundled copy (downloading it if the jar was
+     * built without one) when pemPath is null
+     */
+    public static RdsCaBundle load(String pemPath) throws IOException, GeneralSecurityException {
+        Collection<? extends Certificate> certificates;
+        try (InputStream in = open(pemPath)) {
+            certificates = CertificateFactory.getInstance("X.509").generateCertificates(in);
+        }
+        if (certificates.isEmpty()) {
+            throw new IOException("CA bundle contains no certificates");
+        }
+
+        KeyStore keyStore = KeyStore.getInstance("PKCS12");
+        keyStore.load(null, null);
+        int i = 0;
+        for (Certificate certificate : certificates) {
+            keyStore.setCertificateEntry("rds-ca-" + i++, certificate);
+        }
+
+        byte[] random = new byte[18];
+        new SecureRandom().nextBytes(random);
+        String password = Base64.getUrlEncoder().withoutPadding().encodeToString(random);
+        Path trustStore = Files.createTempFile("rds-truststore", ".p12");
+        trustStore.toFile().deleteOnExit();
+        try (OutputStream out = Files.newOutputStream(trustStore)) {
+            keyStore.store(out, password.toCharArray());
+        }
+        return new RdsCaBundle(trustStore, password, certificates.size());
+    }
+
+    private static InputStream open(String pemPath) throws IOException {
+        if (pemPath != null) {
+            logger.info("Using CA bundle {}", pemPath);
+            return Files.newInputStream(Paths.get(pemPath));
+        }
+        InputStream bundled = RdsCaBundle.class.getResourceAsStream(BUNDLED_RESOURCE);
+        if (bundled != null) {
+            logger.info("Using the RDS CA bundle bundled with the simulator");
+            return bundled;
+        }
+        return Files.newInputStream(download());
+    }
+
+    /**
+     * Download the global bundle once and cache it for later runs
+     */
+    private static Path download() throws IOException {
+        Path cached = Paths.get(System.getProperty("user.home"), ".aurora-lab", "global-bundle.pem");
+        if (Files.exists(cached)) {
+            logger.info("Using cached RDS CA bundle {}", cached);
+            return cached;
+        }
+
+        logger.info("Downloading RDS CA bundle from {}", BUNDLE_URL);
+        Files.createDirectories(cached.getParent());
+        Path download = cached.resolveSibling(cached.getFileName() + ".download");
+        HttpClient client = HttpClient.newBuilder().connectTimeout(Duration.ofSeconds(10)).build();
+        HttpRequest request = HttpRequest.newBuilder(URI.create(BUNDLE_URL)).timeout(Duration.ofSeconds(30)).build();
+        try {
+            HttpResponse<Path> response = client.send(request, HttpResponse.BodyHandlers.ofFile(download));
+            if (response.statusCode() != 200) {
+                Files.deleteIfExists(download);
+                throw new IOException("Downloading " + BUNDLE_URL + " failed with HTTP " + response.statusCode());
+            }
+        } catch (InterruptedException e) {
+            Thread.currentThread().interrupt();
+            throw new IOException("Interrupted while downloading " + BUNDLE_URL, e);
+        }
+        return Files.move(download, cached, StandardCopyOption.REPLACE_EXISTING);
+    }
+
+    /**
+     * Trust store URL for the trustCertificateKeyStoreUrl driver property
+     */
+    public String trustStoreUrl() {
+        return trustStore.toUri().toString();
+    }
+
+    public String password() {
+        return password;
+    }
+
+    public int certificateCount() {
+        return certificateCount;
+    }
+}
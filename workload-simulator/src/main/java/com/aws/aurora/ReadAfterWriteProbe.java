@@ -0,0 +1,198 @@
+package com.aws.aurora;
+
+import io.prometheus.client.Counter;
+import io.prometheus.client.Gauge;
+import org.slf4j.Logger;
+import org.slf4j.LoggerFactory;
+
+import javax.sql.DataSource;
+import java.sql.Connection;
+import java.sql.PreparedStatement;
+import java.sql.ResultSet;
+import java.sql.SQLException;
+import java.sql.Statement;
+import java.util.Random;
+import java.util.concurrent.Executors;
+import java.util.concurrent.ScheduledExecutorService;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicLong;
+
+/**
+ * Read-after-write consistency probe
+ * Every interval the probe writes a unique token to the writer and immediately reads it back
+ * from the reader endpoint by primary key, polling until the row is visible. The time from the
+ * acknowledged write to the first read that sees it is the read-your-writes lag as an
+ * application experiences it, recorded as the "read-after-write" latency next to the workers'
+ * operations. Unlike the replica lag metric in CloudWatch it includes the reader endpoint's DNS
+ * and connection routing, which change during the switchover.
+ *
+ * Anomalies are logged, counted and exported:
+ * <ul>
+ *   <li>not_visible: the token was not readable within the timeout</li>
+ *   <li>regressed: the previous token, already read, is no longer readable (the reader endpoint
+ *       moved to an instance that is further behind)</li>
+ *   <li>slow: the token became readable, but later than the lag threshold</li>
+ * </ul>
+ */
+public class ReadAfterWriteProbe {
+    private static final Logger logger = LoggerFactory.getLogger(ReadAfterWriteProbe.class);
+    static final String OPERATION = "read-after-write";
+    private static final int POLL_MILLIS = 5;
+
+    private static final Counter anomalyCounter = Counter.build()
+            .name("aurora_read_after_write_anomalies_total")
+            .help("Read-after-write probe anomalies by kind")
+            .labelNames("kind")
+            .register();
+
+    private static final Gauge lagGauge = Gauge.build()
+            .name("aurora_read_after_write_lag_seconds")
+            .help("Read-your-writes lag of the latest read-after-write probe")
+            .register();
+
+    private final DataSource writer;
+    private final DataSource reader;
+    private final String readerEndpoint;
+    private final int intervalMillis;
+    private final int timeoutMillis;
+    private final int lagThresholdMillis;
+    private final LatencyTracker latencyTracker;
+    private final Random random = new Random();
+    private final AtomicLong probes = new AtomicLong(0);
+    private final AtomicLong anomalies = new AtomicLong(0);
+    private ScheduledExecutorService executor;
+
+    // The previous token that was read back, checked again by the next probe
+    private String lastTable;
+    private long lastId;
+
+    public ReadAfterWriteProbe(DataSource writer, DataSource reader, String readerEndpoint, int intervalMillis,
+                               int timeoutMillis, int lagThresholdMillis, LatencyTracker latencyTracker,
+                               long probes, long anomalies) {
+        this.writer = writer;
+        this.reader = reader;
+        this.readerEndpoint = readerEndpoint;
+        this.intervalMillis = intervalMillis;
+        this.timeoutMillis = timeoutMillis;
+        this.lagThresholdMillis = lagThresholdMillis;
+        this.latencyTracker = latencyTracker;
+        this.probes.set(probes);
+        this.anomalies.set(anomalies);
+    }
+
+    public void start() {
+        executor = Executors.newSingleThreadScheduledExecutor(r -> {
+            Thread thread = new Thread(r, "read-after-write-probe");
+            thread.setDaemon(true);
+            return thread;
+        });
+        executor.scheduleWithFixedDelay(this::probe, intervalMillis, intervalMillis, TimeUnit.MILLISECONDS);
+        logger.info("Read-after-write probe started: reading from {} every {}ms (timeout {}ms, lag threshold {}ms)",
+                readerEndpoint, intervalMillis, timeoutMillis, lagThresholdMillis);
+    }
+
+    public void stop() {
+        if (executor != null) {
+            executor.shutdownNow();
+        }
+    }
+
+    /**
+     * Probes whose write was acknowledged, on this and earlier runs of a resumed run
+     */
+    public long getProbes() {
+        return probes.get();
+    }
+
+    public long getAnomalies() {
+        return anomalies.get();
+    }
+
+    private void probe() {
+        String tableName = String.format("test_%04d", random.nextInt(12000) + 1);
+        String token = String.format("probe-%d-%08x", System.currentTimeMillis(), random.nextInt());
+        long id;
+        long written;
+        try {
+            id = write(tableName, token);
+            written = System.nanoTime();
+        } catch (SQLException e) {
+            // The writer is unavailable (typically during the switchover); the workers report it
+            logger.debug("Read-after-write probe write failed: {}", e.getMessage());
+            return;
+        }
+        probes.incrementAndGet();
+
+        try {
+            if (lastTable != null && read(lastTable, lastId) == null) {
+                anomaly("regressed", String.format("%s id %d was read before but is not visible on %s",
+                        lastTable, lastId, readerEndpoint));
+            }
+
+            long deadline = written + TimeUnit.MILLISECONDS.toNanos(timeoutMillis);
+            Boolean readOnly;
+            while ((readOnly = read(tableName, id)) == null && System.nanoTime() < deadline) {
+                Thread.sleep(POLL_MILLIS);
+            }
+            if (readOnly == null) {
+                anomaly("not_visible", String.format("%s id %d (%s) was not visible on %s within %dms",
+                        tableName, id, token, readerEndpoint, timeoutMillis));
+                return;
+            }
+
+            long lagNanos = System.nanoTime() - written;
+            latencyTracker.record(OPERATION, lagNanos);
+            lagGauge.set(lagNanos / 1_000_000_000.0);
+            lastTable = tableName;
+            lastId = id;
+            if (lagNanos > TimeUnit.MILLISECONDS.toNanos(lagThresholdMillis)) {
+                anomaly("slow", String.format("%s id %d became visible after %.0fms%s", tableName, id,
+                        lagNanos / 1_000_000.0, readOnly ? "" : " (read from the writer)"));
+            }
+        } catch (SQLException e) {
+            logger.debug("Read-after-write probe read failed: {}", e.getMessage());
+        } catch (InterruptedException e) {
+            Thread.currentThread().interrupt();
+        }
+    }
+
+    /**
+     * Insert the token and return the row's ID once the write is acknowledged
+     */
+    private long write(String tableName, String token) throws SQLException {
+        try (Connection conn = writer.getConnection();
+             PreparedStatement stmt = conn.prepareStatement(
+                     "INSERT INTO " + tableName + " (col1, col2, col3, col4, col5) VALUES (?, 0, 'read-after-write probe', 0, ?)",
+                     Statement.RETURN_GENERATED_KEYS)) {
+            stmt.setString(1, token);
+            stmt.setLong(2, System.currentTimeMillis());
+            stmt.executeUpdate();
+            try (ResultSet keys = stmt.getGeneratedKeys()) {
+                if (!keys.next()) {
+                    throw new SQLException("No generated key for the probe row in " + tableName);
+                }
+                return keys.getLong(1);
+            }
+        }
+    }
+
+    /**
+     * Read a probe row from the reader endpoint; returns whether the instance that served it is
+     * read-only, or null when the row is not visible
+     */
+    private Boolean read(String tableName, long id) throws SQLException {
+        try (Connection conn = reader.getConnection();
+             PreparedStatement stmt = conn.prepareStatement("SELECT @@read_only FROM " + tableName + " WHERE id = ?")) {
+            stmt.setLong(1, id);
+            try (ResultSet rs = stmt.executeQuery()) {
+                return rs.next() ? rs.getInt(1) != 0 : null;
+            }
+        }
+    }
+
+    private void anomaly(String kind, String detail) {
+        anomalies.incrementAndGet();
+        anomalyCounter.labels(kind).inc();
+        logger.warn("[{}] PROBE: Anomaly {} | {}", WorkloadSimulator.getCurrentTime(), kind, detail);
+    }
+}
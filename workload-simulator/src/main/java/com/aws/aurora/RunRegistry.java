@@ -0,0 +1,100 @@
+package com.aws.aurora;
+
+import software.amazon.awssdk.core.client.config.ClientOverrideConfiguration;
+import software.amazon.awssdk.regions.Region;
+import software.amazon.awssdk.services.dynamodb.DynamoDbClient;
+import software.amazon.awssdk.services.dynamodb.DynamoDbClientBuilder;
+import software.amazon.awssdk.services.dynamodb.model.AttributeValue;
+import software.amazon.awssdk.services.dynamodb.model.PutItemRequest;
+
+import java.time.Duration;
+import java.time.Instant;
+import java.time.ZoneOffset;
+import java.time.format.DateTimeFormatter;
+import java.util.HashMap;
+import java.util.Map;
+import java.util.regex.Matcher;
+
+/**
+ * Experiment registry client
+ * Registers the run in the monitoring stack's experiment registry table (experimentTableName) when
+ * it starts and again with its results when it stops, so lab-report list can list and compare runs.
+ * The item is keyed by the run ID and the source "simulator", next to the items lab-scenario
+ * registers for the same run ID.
+ */
+public class RunRegistry implements AutoCloseable {
+    // Same format as the Go registry (internal/experiments): milliseconds, UTC, sortable as strings
+    private static final DateTimeFormatter TIME_FORMAT =
+            DateTimeFormatter.ofPattern("yyyy-MM-dd'T'HH:mm:ss.SSS'Z'").withZone(ZoneOffset.UTC);
+
+    private final DynamoDbClient client;
+    private final String table;
+    private final String runId;
+    private final String workload;
+    private final Map<String, String> config;
+    private final Instant startedAt;
+
+    public RunRegistry(String table, String runId, String auroraEndpoint, String workload,
+                       Map<String, String> config, Instant startedAt) {
+        DynamoDbClientBuilder builder = DynamoDbClient.builder()
+                .overrideConfiguration(ClientOverrideConfiguration.builder()
+                        .apiCallTimeout(Duration.ofSeconds(5))
+                        .build());
+        // The table is in the lab's region, the cluster's
+        Matcher matcher = CloudWatchPublisher.ENDPOINT_REGION.matcher(auroraEndpoint);
+        if (matcher.find()) {
+            builder.region(Region.of(matcher.group(1)));
+        }
+        this.client = builder.build();
+        this.table = table;
+        this.runId = runId;
+        this.workload = workload;
+        this.config = config;
+        this.startedAt = startedAt;
+    }
+
+    /**
+     * Register the run as running
+     */
+    public void started() {
+        put(item("running"));
+    }
+
+    /**
+     * Register the stopped run with its results
+     */
+    public void stopped(Map<String, Double> results) {
+        Map<String, AttributeValue> item = item("succeeded");
+        item.put("EndedAt", string(TIME_FORMAT.format(Instant.now())));
+        Map<String, AttributeValue> values = new HashMap<>();
+        results.forEach((key, value) -> values.put(key, AttributeValue.builder().n(String.valueOf(value)).build()));
+        item.put("Results", AttributeValue.builder().m(values).build());
+        put(item);
+    }
+
+    @Override
+    public void close() {
+        client.close();
+    }
+
+    private Map<String, AttributeValue> item(String status) {
+        Map<String, AttributeValue> item = new HashMap<>();
+        item.put("RunId", string(runId));
+        item.put("Source", string("simulator"));
+        item.put("Name", string(workload));
+        item.put("Status", string(status));
+        item.put("StartedAt", string(TIME_FORMAT.format(startedAt)));
+        Map<String, AttributeValue> values = new HashMap<>();
+        config.forEach((key, value) -> values.put(key, string(value)));
+        item.put("Config", AttributeValue.builder().m(values).build());
+        return item;
+    }
+
+    private void put(Map<String, AttributeValue> item) {
+        client.putItem(PutItemRequest.builder().tableName(table).item(item).build());
+    }
+
+    private static AttributeValue string(String value) {
+        return AttributeValue.builder().s(value).build();
+    }
+}
@@ -0,0 +1,141 @@
+package com.aws.aurora;
+
+import java.nio.file.Path;
+import java.sql.Connection;
+import java.sql.DriverManager;
+import java.sql.PreparedStatement;
+import java.sql.ResultSet;
+import java.sql.SQLException;
+import java.sql.Statement;
+import java.util.ArrayList;
+import java.util.HashMap;
+import java.util.List;
+import java.util.Map;
+
+/**
+ * Persistent run state for resumable runs
+ * The simulator checkpoints its run metadata (start time, configuration, counters and the write
+ * ledger sequence) to a local SQLite file every log interval. When a crashed or restarted
+ * simulator opens the same file it resumes the run: counters continue from the last checkpoint,
+ * ledger sequence IDs keep increasing, and the time it was not running is recorded as a gap so
+ * the final report covers the whole experiment.
+ */
+public class RunState implements AutoCloseable {
+    private final Connection conn;
+    private final Map<String, String> values = new HashMap<>();
+    private final boolean resumed;
+
+    public RunState(Path path, String config) throws SQLException {
+        this.conn = DriverManager.getConnection("jdbc:sqlite:" + path);
+        try (Statement stmt = conn.createStatement()) {
+            stmt.execute("CREATE TABLE IF NOT EXISTS run_state (key TEXT PRIMARY KEY, value TEXT NOT NULL)");
+            stmt.execute("CREATE TABLE IF NOT EXISTS gaps (started_at INTEGER NOT NULL, ended_at INTEGER NOT NULL)");
+            try (ResultSet rs = stmt.executeQuery("SELECT key, value FROM run_state")) {
+                while (rs.next()) {
+                    values.put(rs.getString(1), rs.getString(2));
+                }
+            }
+        }
+
+        long now = System.currentTimeMillis();
+        this.resumed = values.containsKey("run_start");
+        if (resumed) {
+            // The simulator observed nothing between its last checkpoint and this start
+            try (PreparedStatement stmt = conn.prepareStatement("INSERT INTO gaps (started_at, ended_at) VALUES (?, ?)")) {
+                stmt.setLong(1, getLong("last_checkpoint"));
+                stmt.setLong(2, now);
+                stmt.executeUpdate();
+            }
+            put("restarts", String.valueOf(getLong("restarts") + 1));
+        } else {
+            put("run_start", String.valueOf(now));
+            put("config", config);
+            put("restarts", "0");
+        }
+        put("last_checkpoint", String.valueOf(now));
+    }
+
+    public boolean isResumed() {
+        return resumed;
+    }
+
+    public long getRunStart() {
+        return getLong("run_start");
+    }
+
+    public long getRestarts() {
+        return getLong("restarts");
+    }
+
+    /**
+     * Configuration the run was started with
+     */
+    public String getConfig() {
+        return values.get("config");
+    }
+
+    public long getSequence() {
+        return getLong("sequence");
+    }
+
+    /**
+     * Counter value at the last checkpoint (0 for a new run)
+     */
+    public long getCounter(String name) {
+        return getLong("counter." + name);
+    }
+
+    /**
+     * Persist the current counters and ledger sequence
+     */
+    public synchronized void checkpoint(Map<String, Long> counters, long sequence) throws SQLException {
+        conn.setAutoCommit(false);
+        try {
+            for (Map.Entry<String, Long> counter : counters.entrySet()) {
+                put("counter." + counter.getKey(), String.valueOf(counter.getValue()));
+            }
+            put("sequence", String.valueOf(sequence));
+            put("last_checkpoint", String.valueOf(System.currentTimeMillis()));
+            conn.commit();
+        } catch (SQLException e) {
+            conn.rollback();
+            throw e;
+        } finally {
+            conn.setAutoCommit(true);
+        }
+    }
+
+    /**
+     * Periods the simulator was not running, as [startedAt, endedAt] epoch millis
+     */
+    public List<long[]> getGaps() throws SQLException {
+        List<long[]> gaps = new ArrayList<>();
+        try (Statement stmt = conn.createStatement();
+             ResultSet rs = stmt.executeQuery("SELECT started_at, ended_at FROM gaps ORDER BY started_at")) {
+            while (rs.next()) {
+                gaps.add(new long[]{rs.getLong(1), rs.getLong(2)});
+            }
+        }
+        return gaps;
+    }
+
+    @Override
+    public synchronized void close() throws SQLException {
+        conn.close();
+    }
+
+    private void put(String key, String value) throws SQLException {
+        try (PreparedStatement stmt = conn.prepareStatement(
+                "INSERT INTO run_state (key, value) VALUES (?, ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value")) {
+            stmt.setString(1, key);
+            stmt.setString(2, value);
+            stmt.executeUpdate();
+        }
+        values.put(key, value);
+    }
+
+    private long getLong(String key) {
+        String value = values.get(key);
+        return value != null ? Long.parseLong(value) : 0;
+    }
+}
@@ -0,0 +1,200 @@
+package com.aws.aurora;
+
+import java.io.BufferedWriter;
+import java.io.IOException;
+import java.nio.charset.StandardCharsets;
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.nio.file.StandardOpenOption;
+import java.time.OffsetDateTime;
+import java.util.ArrayList;
+import java.util.List;
+import java.util.Locale;
+
+/**
+ * Machine-readable statistics output
+ * Every stats interval and the final report are appended to the output file as JSON Lines (one
+ * object per record, with the run ID) or CSV (one row per metric, in long format for charting).
+ * Counters are cumulative since the start of the run; latency and recovery percentiles cover the
+ * interval (record "interval") or the whole run (record "final"). When the simulator compares
+ * endpoints, the requests of each target follow the totals, and its recovery names end in
+ * " (target)". The statement mode and its stale statement errors are written with every record, and the
+ * read-after-write probes and their anomalies when the probe runs.
+ */
+public class StatsWriter implements AutoCloseable {
+    static final String CSV_HEADER = "timestamp,record,metric,name,count,success,failed,p50_ms,p95_ms,p99_ms,p999_ms,max_ms";
+
+    private final String format;
+    private final String runId;
+    private final BufferedWriter writer;
+
+    public StatsWriter(Path path, String format, String runId) throws IOException {
+        this.format = format;
+        this.runId = runId;
+        boolean exists = Files.exists(path) && Files.size(path) > 0;
+        this.writer = Files.newBufferedWriter(path, StandardCharsets.UTF_8,
+                StandardOpenOption.CREATE, StandardOpenOption.APPEND);
+        if ("csv".equals(format) && !exists) {
+            writer.write(CSV_HEADER);
+            writer.newLine();
+        }
+    }
+
+    /**
+     * Counters at the time of a record
+     */
+    public static final class Counters {
+        final long total;
+        final long success;
+        final long failed;
+        // Transaction outcomes; null for the insert workload
+        final long[] transactions;
+        // Requests per endpoint; null unless the simulator compares endpoints
+        final List<TargetCounters> targets;
+        // --statement-mode and the statements that failed as unknown or closed
+        final String statementMode;
+        final long staleStatements;
+        // Read-after-write probes and their anomalies; null without --probe-interval
+        final long[] probe;
+
+        public Counters(long total, long success, long failed, long[] transactions, List<TargetCounters> targets,
+                        String statementMode, long staleStatements, long[] probe) {
+            this.total = total;
+            this.success = success;
+            this.failed = failed;
+            this.transactions = transactions;
+            this.targets = targets;
+            this.statementMode = statementMode;
+            this.staleStatements = staleStatements;
+            this.probe = probe;
+        }
+    }
+
+    /**
+     * Requests of one endpoint the simulator compares
+     */
+    public static final class TargetCounters {
+        final String name;
+        final String endpoint;
+        final long success;
+        final long failed;
+        // Time between the target's first and last connection error, 0 without errors
+        final long errorWindowMs;
+
+        public TargetCounters(String name, String endpoint, long success, long failed, long errorWindowMs) {
+            this.name = name;
+            this.endpoint = endpoint;
+            this.success = success;
+            this.failed = failed;
+            this.errorWindowMs = errorWindowMs;
+        }
+    }
+
+    /**
+     * Append a record ("interval" or "final") and flush it
+     */
+    public synchronized void write(String record, Counters counters, List<LatencyTracker.Snapshot> latency,
+                                   List<LatencyTracker.Snapshot> recovery) throws IOException {
+        String timestamp = OffsetDateTime.now().toString();
+        if ("json".equals(format)) {
+            writer.write(json(timestamp, record, runId, counters, latency, recovery));
+            writer.newLine();
+        } else {
+            for (String row : csv(timestamp, record, counters, latency, recovery)) {
+                writer.write(row);
+                writer.newLine();
+            }
+        }
+        writer.flush();
+    }
+
+    @Override
+    public synchronized void close() throws IOException {
+        writer.close();
+    }
+
+    private static String json(String timestamp, String record, String runId, Counters counters,
+                               List<LatencyTracker.Snapshot> latency, List<LatencyTracker.Snapshot> recovery) {
+        StringBuilder sb = new StringBuilder();
+        sb.append("{\"timestamp\":\"").append(timestamp).append("\",\"record\":\"").append(record).append('"');
+        sb.append(",\"runId\":\"").append(runId).append('"');
+        double successRate = counters.total > 0 ? counters.success * 100.0 / counters.total : 0.0;
+        sb.append(",\"requests\":{\"total\":").append(counters.total)
+                .append(",\"success\":").append(counters.success)
+                .append(",\"failed\":").append(counters.failed)
+                .append(",\"successRate\":").append(number(successRate)).append('}');
+        if (counters.transactions != null) {
+            sb.append(",\"transactions\":{\"committed\":").append(counters.transactions[0])
+                    .append(",\"rolledBack\":").append(counters.transactions[1])
+                    .append(",\"commitUnknown\":").append(counters.transactions[2]).append('}');
+        }
+        if (counters.targets != null) {
+            List<String> objects = new ArrayList<>();
+            for (TargetCounters t : counters.targets) {
+                long total = t.success + t.failed;
+                double rate = total > 0 ? t.success * 100.0 / total : 0.0;
+                objects.add(String.format(Locale.ROOT,
+                        "{\"target\":\"%s\",\"endpoint\":\"%s\",\"total\":%d,\"success\":%d,\"failed\":%d,\"successRate\":%s,\"errorWindowMs\":%d}",
+                        t.name, t.endpoint, total, t.success, t.failed, number(rate), t.errorWindowMs));
+            }
+            sb.append(",\"targets\":[").append(String.join(",", objects)).append(']');
+        }
+        sb.append(",\"statements\":{\"mode\":\"").append(counters.statementMode)
+                .append("\",\"stale\":").append(counters.staleStatements).append('}');
+        if (counters.probe != null) {
+            sb.append(",\"probe\":{\"probes\":").append(counters.probe[0])
+                    .append(",\"anomalies\":").append(counters.probe[1]).append('}');
+        }
+        sb.append(",\"latency\":").append(json(latency, "operation"));
+        sb.append(",\"recovery\":").append(json(recovery, "strategy"));
+        return sb.append('}').toString();
+    }
+
+    private static String json(List<LatencyTracker.Snapshot> snapshots, String nameKey) {
+        List<String> objects = new ArrayList<>();
+        for (LatencyTracker.Snapshot s : snapshots) {
+            objects.add(String.format(Locale.ROOT,
+                    "{\"%s\":\"%s\",\"count\":%d,\"p50\":%s,\"p95\":%s,\"p99\":%s,\"p999\":%s,\"max\":%s}",
+                    nameKey, s.operation, s.count, number(s.p50), number(s.p95), number(s.p99), number(s.p999), number(s.max)));
+        }
+        return "[" + String.join(",", objects) + "]";
+    }
+
+    private static List<String> csv(String timestamp, String record, Counters counters,
+                                    List<LatencyTracker.Snapshot> latency, List<LatencyTracker.Snapshot> recovery) {
+        String prefix = timestamp + "," + record + ",";
+        List<String> rows = new ArrayList<>();
+        rows.add(prefix + "requests,all," + counters.total + "," + counters.success + "," + counters.failed + ",,,,,");
+        if (counters.transactions != null) {
+            String[] outcomes = {"committed", "rolled_back", "commit_unknown"};
+            for (int i = 0; i < outcomes.length; i++) {
+                rows.add(prefix + "transactions," + outcomes[i] + "," + counters.transactions[i] + ",,,,,,,");
+            }
+        }
+        if (counters.targets != null) {
+            for (TargetCounters t : counters.targets) {
+                rows.add(prefix + "requests," + t.name + "," + (t.success + t.failed) + "," + t.success + "," + t.failed + ",,,,,");
+            }
+        }
+        rows.add(prefix + "statements," + counters.statementMode + "," + counters.staleStatements + ",,,,,,,");
+        if (counters.probe != null) {
+            rows.add(prefix + "probe,read-after-write," + counters.probe[0] + ",," + counters.probe[1] + ",,,,,");
+        }
+        for (LatencyTracker.Snapshot s : latency) {
+            rows.add(prefix + "latency," + csvRow(s));
+        }
+        for (LatencyTracker.Snapshot s : recovery) {
+            rows.add(prefix + "recovery," + csvRow(s));
+        }
+        return rows;
+    }
+
+    private static String csvRow(LatencyTracker.Snapshot s) {
+        return String.join(",", s.operation, String.valueOf(s.count), "", "",
+                number(s.p50), number(s.p95), number(s.p99), number(s.p999), number(s.max));
+    }
+
+    private static String number(double value) {
+        return String.format(Locale.ROOT, "%.2f", value);
+    }
+}
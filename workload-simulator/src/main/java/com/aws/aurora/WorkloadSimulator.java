@@ -3,25 +3,42 @@ package com.aws.aurora;
 import com.zaxxer.hikari.HikariConfig;
 import com.zaxxer.hikari.HikariDataSource;
 import io.prometheus.client.Counter;
+import io.prometheus.client.Gauge;
 import io.prometheus.client.Histogram;
 import io.prometheus.client.exporter.HTTPServer;
 import io.prometheus.client.hotspot.DefaultExports;
 import org.apache.commons.cli.*;
+import org.apache.logging.log4j.LogManager;
 import org.slf4j.Logger;
 import org.slf4j.LoggerFactory;
+import software.amazon.jdbc.plugin.iam.IamAuthConnectionPlugin;
 
 import javax.sql.DataSource;
 import java.io.IOException;
+import java.nio.file.Paths;
+import java.security.Security;
 import java.sql.Connection;
 import java.sql.PreparedStatement;
+import java.sql.ResultSet;
 import java.sql.SQLException;
+import java.sql.Statement;
+import java.time.Instant;
 import java.time.LocalDateTime;
+import java.time.ZoneId;
 import java.time.format.DateTimeFormatter;
 import java.util.ArrayList;
+import java.util.HashMap;
+import java.util.LinkedHashMap;
 import java.util.List;
+import java.util.Map;
+import java.util.Properties;
 import java.util.Random;
+import java.util.Set;
+import java.util.TreeMap;
+import java.util.TreeSet;
 import java.util.concurrent.*;
 import java.util.concurrent.atomic.AtomicLong;
+import java.util.regex.Pattern;
 
 /**
  * Aurora Blue-Green Deployment Workload Simulator
@@ -30,6 +47,15 @@ import java.util.concurrent.atomic.AtomicLong;
 public class WorkloadSimulator {
     private static final Logger logger = LoggerFactory.getLogger(WorkloadSimulator.class);
     private static final DateTimeFormatter timeFormatter = DateTimeFormatter.ofPattern("yyyy-MM-dd HH:mm:ss.SSS");
+    private static final Pattern TARGET_NAME = Pattern.compile("[a-z0-9][a-z0-9-]*");
+    // Driver profiles: how a target's connections are made (see createDataSource)
+    private static final List<String> DRIVERS = List.of("wrapper", "plain", "compressed", "client-prepare");
+    // Tables each worker writes to with --statement-mode reuse, keeping a prepared INSERT per table
+    private static final int REUSE_TABLES = 16;
+    // RDS IAM auth tokens are valid for 15 minutes; the wrapper's iam plugin caches a token for
+    // IAM_TOKEN_REFRESH_SECONDS, so a new connection never presents one that is about to expire
+    private static final int IAM_TOKEN_LIFETIME_SECONDS = 900;
+    static final int IAM_TOKEN_REFRESH_SECONDS = 600;
 
     // Configuration
     private final String auroraEndpoint;
@@ -37,21 +63,88 @@ public class WorkloadSimulator {
     private final String username;
     private final String password;
     private final int writeWorkers;
-    private final int writeRate;
+    // The rates can be changed at runtime through the control API
+    private volatile int writeRate;
+    private volatile int totalRate;
+    private final LoadProfile loadProfile;
+    private final int maxInFlight;
+    private final int maxQueued;
+    private final int queueTimeout;
     private final int connectionPoolSize;
     private final int logInterval;
+    private final int duration;
+    private final int drainTimeout;
+    private final int controlPort;
     private final boolean enableMetrics;
+    private final String verifyLedgerPath;
+    private final boolean trackDns;
+    private final String workload;
+    private final int transactionSize;
+    private final int holdTransactions;
+    private final int holdDuration;
+    private final boolean holdTableLocks;
+    private final String connectionStrategy;
+    private final int maxLifetime;
+    private final String statementMode;
+    private final boolean serverCursors;
+    private final int probeInterval;
+    private final String probeReaderEndpoint;
+    private final int probeTimeout;
+    private final int probeLagThreshold;
+    private final int dnsTtlOverride;
+    private final String tlsMode;
+    private final String tlsCaBundle;
+    private final String auth;
+    private final String stateFile;
+    private final String outputFormat;
+    private final String outputFile;
+    private final String cloudwatchNamespace;
+    private final String runId;
+    private final String registryTable;
+    // The endpoints the workers write through, each with its own workers and connection pool
+    private final List<Target> targets;
 
     // Resources
-    private DataSource dataSource;
     private ExecutorService executorService;
     private ScheduledExecutorService scheduledExecutor;
     private HTTPServer prometheusServer;
+    private ControlServer controlServer;
+    private WriteLedger writeLedger;
+    private DnsTracker dnsTracker;
+    private LockHolder lockHolder;
+    private ReadAfterWriteProbe probe;
+    private DataSource probeReader;
+    private RunState runState;
+    private StatsWriter statsWriter;
+    private CloudWatchPublisher cloudWatchPublisher;
+    private RunRegistry runRegistry;
+    private Backpressure backpressure;
+    // Set on shutdown: workers finish their operation in flight and stop
+    private volatile boolean stopping = false;
+    // Set through the control API: workers send no operations until resumed
+    private volatile boolean paused = false;
+    private final List<Map<String, Object>> events = new CopyOnWriteArrayList<>();
+    // Start of the run the load profile is timed from; a resumed run keeps its start
+    private volatile long profileStart = System.currentTimeMillis();
+    // The rate limiter all workers share with --total-rate; without it each worker has its own
+    private RateLimiter sharedLimiter;
+    private final List<WriteWorker> workers = new CopyOnWriteArrayList<>();
 
     // Statistics
     private final AtomicLong totalRequests = new AtomicLong(0);
     private final AtomicLong successfulRequests = new AtomicLong(0);
     private final AtomicLong failedRequests = new AtomicLong(0);
+    private final LatencyTracker latencyTracker = new LatencyTracker();
+    // Time from a worker's first connection error to its next successful operation
+    private final LatencyTracker recoveryTracker = new LatencyTracker();
+    private final AtomicLong committedTransactions = new AtomicLong(0);
+    private final AtomicLong rolledBackTransactions = new AtomicLong(0);
+    private final AtomicLong unknownTransactions = new AtomicLong(0);
+    // Statement errors (unknown or closed prepared statements), counted on every attempt
+    private final AtomicLong staleStatements = new AtomicLong(0);
+    // Start of the current statistics interval and of this process's run, for the achieved rate
+    private long rateIntervalStart = System.nanoTime();
+    private long rateRunStart = System.nanoTime();
 
     // Prometheus Metrics
     private static final Counter writeRequests = Counter.build()
@@ -72,31 +165,221 @@ public class WorkloadSimulator {
             .labelNames("error_type")
             .register();
 
+    private static final Counter staleStatementErrors = Counter.build()
+            .name("aurora_stale_statement_errors_total")
+            .help("Statements that failed because their prepared statement was unknown or closed, retries included")
+            .register();
+
+    private static final Counter shedOperations = Counter.build()
+            .name("aurora_shed_operations_total")
+            .help("Operations shed by backpressure")
+            .register();
+
+    private static final Gauge inFlightOperations = Gauge.build()
+            .name("aurora_in_flight_operations")
+            .help("Operations running against the database (with --max-in-flight)")
+            .register();
+
+    private static final Gauge queuedOperations = Gauge.build()
+            .name("aurora_queued_operations")
+            .help("Operations waiting for an in-flight slot (with --max-in-flight)")
+            .register();
+
+    private static final Gauge runInfo = Gauge.build()
+            .name("aurora_run_info")
+            .help("Run ID of the simulator (--run-id), always 1")
+            .labelNames("run_id")
+            .register();
+
+    private static final Counter transactions = Counter.build()
+            .name("aurora_transactions_total")
+            .help("Transactions by outcome (transactional workload)")
+            .labelNames("outcome")
+            .register();
+
     public WorkloadSimulator(String auroraEndpoint, String databaseName, String username, String password,
-                            int writeWorkers, int writeRate, int connectionPoolSize, int logInterval,
-                            boolean enableMetrics) {
+                            int writeWorkers, int writeRate, int totalRate, LoadProfile loadProfile,
+                            int maxInFlight, int maxQueued, int queueTimeout, int connectionPoolSize,
+                            int logInterval, int duration, int drainTimeout, int controlPort, boolean enableMetrics,
+                            String verifyLedgerPath, boolean trackDns,
+                            String workload, int transactionSize,
+                            int holdTransactions, int holdDuration, boolean holdTableLocks,
+                            String connectionStrategy, int maxLifetime, String statementMode, boolean serverCursors,
+                            int probeInterval, String probeReaderEndpoint, int probeTimeout, int probeLagThreshold,
+                            int dnsTtlOverride, String tlsMode, String tlsCaBundle, String auth, String stateFile,
+                            String outputFormat, String outputFile, String cloudwatchNamespace, String runId,
+                            String registryTable, List<Target> targets) {
         this.auroraEndpoint = auroraEndpoint;
         this.databaseName = databaseName;
         this.username = username;
         this.password = password;
         this.writeWorkers = writeWorkers;
         this.writeRate = writeRate;
+        this.totalRate = totalRate;
+        this.loadProfile = loadProfile;
+        this.maxInFlight = maxInFlight;
+        this.maxQueued = maxQueued;
+        this.queueTimeout = queueTimeout;
         this.connectionPoolSize = connectionPoolSize;
         this.logInterval = logInterval;
+        this.duration = duration;
+        this.drainTimeout = drainTimeout;
+        this.controlPort = controlPort;
         this.enableMetrics = enableMetrics;
+        this.verifyLedgerPath = verifyLedgerPath;
+        this.trackDns = trackDns;
+        this.workload = workload;
+        this.transactionSize = transactionSize;
+        this.holdTransactions = holdTransactions;
+        this.holdDuration = holdDuration;
+        this.holdTableLocks = holdTableLocks;
+        this.connectionStrategy = connectionStrategy;
+        this.maxLifetime = maxLifetime;
+        this.statementMode = statementMode;
+        this.serverCursors = serverCursors;
+        this.probeInterval = probeInterval;
+        this.probeReaderEndpoint = probeReaderEndpoint;
+        this.probeTimeout = probeTimeout;
+        this.probeLagThreshold = probeLagThreshold;
+        this.dnsTtlOverride = dnsTtlOverride;
+        this.tlsMode = tlsMode;
+        this.tlsCaBundle = tlsCaBundle;
+        this.auth = auth;
+        this.stateFile = stateFile;
+        this.outputFormat = outputFormat;
+        this.outputFile = outputFile;
+        this.cloudwatchNamespace = cloudwatchNamespace;
+        this.runId = runId;
+        this.registryTable = registryTable;
+        this.targets = targets;
+    }
+
+    /**
+     * An endpoint the workers write through, with its own workers, connection pool and counters:
+     * the cluster endpoint (direct), an RDS Proxy endpoint, any endpoint named with --target, or
+     * the cluster endpoint through one of the compared --driver profiles
+     */
+    private static class Target {
+        final String name;
+        final String endpoint;
+        // RDS Proxy endpoints are connected to without the wrapper's plugins
+        final boolean proxy;
+        // One of DRIVERS
+        final String driver;
+        DataSource dataSource;
+        final AtomicLong successfulRequests = new AtomicLong(0);
+        final AtomicLong failedRequests = new AtomicLong(0);
+        // Epoch millis of the first and latest connection error of the target's workers (0: none)
+        private final AtomicLong firstErrorAt = new AtomicLong(0);
+        private final AtomicLong lastErrorAt = new AtomicLong(0);
+
+        Target(String name, String endpoint, boolean proxy, String driver) {
+            this.name = name;
+            this.endpoint = endpoint;
+            this.proxy = proxy;
+            this.driver = driver;
+        }
+
+        boolean hadErrors() {
+            return firstErrorAt.get() > 0;
+        }
+
+        void recordError(long now) {
+            firstErrorAt.compareAndSet(0, now);
+            lastErrorAt.accumulateAndGet(now, Math::max);
+        }
+
+        /**
+         * Time from the first to the latest connection error, the target's error window
+         */
+        long errorWindowMillis() {
+            long first = firstErrorAt.get();
+            return first > 0 ? lastErrorAt.get() - first : 0;
+        }
+    }
+
+    /**
+     * Create the connection pool of every target
+     */
+    private void initializeDataSources() throws Exception {
+        for (Target target : targets) {
+            target.dataSource = createDataSource(target);
+        }
+    }
+
+    /**
+     * Whether the run compares several endpoints or writes through another endpoint than the
+     * cluster endpoint
+     */
+    private boolean customTargets() {
+        return targets.size() > 1 || targets.get(0).proxy;
+    }
+
+    /**
+     * The driver profiles of the targets, in target order without repeats
+     */
+    private String driverList() {
+        List<String> list = new ArrayList<>();
+        for (Target target : targets) {
+            if (!list.contains(target.driver)) {
+                list.add(target.driver);
+            }
+        }
+        return String.join(",", list);
+    }
+
+    private String targetList() {
+        List<String> list = new ArrayList<>();
+        for (Target target : targets) {
+            list.add(target.name + "=" + target.endpoint);
+        }
+        return String.join(",", list);
+    }
+
+    /**
+     * The latency or recovery key of an operation or strategy; with several targets it names the
+     * target, so direct and proxied connections are reported separately
+     */
+    private String metricKey(String name, Target target) {
+        return targets.size() > 1 ? name + " (" + target.name + ")" : name;
+    }
+
+    /**
+     * Check that the iam plugin reads a token refresh shorter than the token lifetime from the data
+     * source properties, as it does when creating a connection; otherwise new connections could
+     * present expired tokens.
+     */
+    static void checkIamTokenRefresh(Properties properties) {
+        int refresh = IamAuthConnectionPlugin.IAM_EXPIRATION.getInteger(properties);
+        if (refresh != IAM_TOKEN_REFRESH_SECONDS || refresh >= IAM_TOKEN_LIFETIME_SECONDS) {
+            throw new IllegalStateException(String.format(
+                    "The iam plugin refreshes tokens after %d s, expected %d s (tokens expire after %d s)",
+                    refresh, IAM_TOKEN_REFRESH_SECONDS, IAM_TOKEN_LIFETIME_SECONDS));
+        }
+        logger.info("IAM auth tokens are refreshed every {} s", refresh);
     }
 
     /**
-     * Initialize database connection pool with AWS JDBC Wrapper
+     * Create a target's database connection pool with AWS JDBC Wrapper, adjusted for the selected
+     * connection strategy. The target's driver profile changes how its connections are made:
+     * <ul>
+     *   <li>wrapper: AWS JDBC Wrapper with its Blue/Green, failover and EFM plugins</li>
+     *   <li>plain: MySQL Connector/J alone, as an application without the wrapper connects</li>
+     *   <li>compressed: the wrapper with compressed connections (useCompression)</li>
+     *   <li>client-prepare: the wrapper with statements interpolated on the client instead of
+     *       prepared on the server (useServerPrepStmts=false)</li>
+     * </ul>
      */
-    private void initializeDataSource() throws SQLException {
-        logger.info("Initializing HikariCP connection pool...");
+    private DataSource createDataSource(Target target) throws Exception {
+        logger.info("Initializing HikariCP connection pool ({}, driver {})...", target.name, target.driver);
 
         HikariConfig config = new HikariConfig();
+        boolean wrapper = !"plain".equals(target.driver);
 
         // AWS Advanced JDBC Wrapper configuration
-        // Format: jdbc:aws-wrapper:mysql://endpoint:port/database
-        String jdbcUrl = String.format("jdbc:aws-wrapper:mysql://%s:3306/%s", auroraEndpoint, databaseName);
+        // Format: jdbc:aws-wrapper:mysql://endpoint:port/database, or jdbc:mysql:// without the wrapper
+        String jdbcUrl = String.format(wrapper ? "jdbc:aws-wrapper:mysql://%s:3306/%s" : "jdbc:mysql://%s:3306/%s",
+                target.endpoint, databaseName);
         config.setJdbcUrl(jdbcUrl);
         config.setUsername(username);
         config.setPassword(password);
@@ -113,35 +396,93 @@ public class WorkloadSimulator {
         // Blue-Green plugin: Proactively monitors Blue-Green deployment status for minimal downtime
         // Failover plugin: Handles general cluster failover scenarios
         // EFM plugin: Enhanced Failure Monitoring for proactive connection health checks
-        config.addDataSourceProperty("wrapperPlugins", "bg,failover,efm");
+        // IAM plugin (--auth iam): generates RDS IAM auth tokens for new connections
+        // Through RDS Proxy there are no plugins: the proxy keeps the client connections open and
+        // moves them to the new writer itself, and the cluster topology the plugins monitor is
+        // hidden behind it
+        if (wrapper) {
+            if (target.proxy) {
+                config.addDataSourceProperty("wrapperPlugins", "");
+            } else {
+                config.addDataSourceProperty("wrapperPlugins", "iam".equals(auth) ? "iam,bg,failover,efm" : "bg,failover,efm");
+            }
+            if ("iam".equals(auth)) {
+                // The plugin's own property, so a rename in a wrapper upgrade fails the build instead
+                // of leaving the token cached for the plugin's default
+                config.addDataSourceProperty(IamAuthConnectionPlugin.IAM_EXPIRATION.name,
+                        String.valueOf(IAM_TOKEN_REFRESH_SECONDS));
+                checkIamTokenRefresh(config.getDataSourceProperties());
+            }
 
-        // AWS JDBC Wrapper logging - FINEST level for detailed Blue-Green plugin activity
-        config.addDataSourceProperty("wrapperLoggerLevel", "FINEST");
+            // AWS JDBC Wrapper logging - FINEST level for detailed Blue-Green plugin activity
+            config.addDataSourceProperty("wrapperLoggerLevel", "FINEST");
 
-        // Blue-Green plugin configuration
-        config.addDataSourceProperty("bgdId", "1"); // Blue-Green Deployment ID (required for bg plugin)
-        config.addDataSourceProperty("bgConnectTimeoutMs", "30000"); // 30 seconds - max wait for new connections during switchover
-        config.addDataSourceProperty("bgSwitchoverTimeoutMs", "180000"); // 3 minutes - max switchover duration
+            // Blue-Green plugin configuration
+            config.addDataSourceProperty("bgdId", "1"); // Blue-Green Deployment ID (required for bg plugin)
+            config.addDataSourceProperty("bgConnectTimeoutMs", "30000"); // 30 seconds - max wait for new connections during switchover
+            config.addDataSourceProperty("bgSwitchoverTimeoutMs", "180000"); // 3 minutes - max switchover duration
 
-        // Failover plugin configuration
-        config.addDataSourceProperty("failoverTimeoutMs", "10000"); // 10 seconds - aggressive fail-fast for minimal downtime
-        config.addDataSourceProperty("failoverClusterTopologyRefreshRateMs", "1000"); // 1 second - faster topology detection
-        config.addDataSourceProperty("enableClusterAwareFailover", "true");
-        config.addDataSourceProperty("clusterInstanceHostPattern", "?.cluster-?.us-east-1.rds.amazonaws.com");
+            // Failover plugin configuration
+            config.addDataSourceProperty("failoverTimeoutMs", "10000"); // 10 seconds - aggressive fail-fast for minimal downtime
+            config.addDataSourceProperty("failoverClusterTopologyRefreshRateMs", "1000"); // 1 second - faster topology detection
+            config.addDataSourceProperty("enableClusterAwareFailover", "true");
+            config.addDataSourceProperty("clusterInstanceHostPattern", "?.cluster-?.us-east-1.rds.amazonaws.com");
+        }
 
         // MySQL specific settings
         config.addDataSourceProperty("cachePrepStmts", "true");
         config.addDataSourceProperty("prepStmtCacheSize", "250");
         config.addDataSourceProperty("prepStmtCacheSqlLimit", "2048");
-        config.addDataSourceProperty("useServerPrepStmts", "true");
+        config.addDataSourceProperty("useServerPrepStmts", String.valueOf(!"client-prepare".equals(target.driver)));
+        if ("compressed".equals(target.driver)) {
+            config.addDataSourceProperty("useCompression", "true");
+        }
+        if (serverCursors) {
+            // Result sets are fetched through a server-side cursor, a batch at a time
+            config.addDataSourceProperty("useCursorFetch", "true");
+            config.addDataSourceProperty("defaultFetchSize", "100");
+        }
         config.addDataSourceProperty("useLocalSessionState", "true");
         config.addDataSourceProperty("rewriteBatchedStatements", "true");
         config.addDataSourceProperty("cacheResultSetMetadata", "true");
         config.addDataSourceProperty("elideSetAutoCommits", "true");
         config.addDataSourceProperty("maintainTimeStats", "false");
 
-        this.dataSource = new HikariDataSource(config);
+        // TLS settings, passed through the wrapper to MySQL Connector/J
+        config.addDataSourceProperty("sslMode", tlsMode.toUpperCase().replace('-', '_'));
+        if ("verify-ca".equals(tlsMode)) {
+            RdsCaBundle caBundle = RdsCaBundle.load(tlsCaBundle);
+            config.addDataSourceProperty("trustCertificateKeyStoreUrl", caBundle.trustStoreUrl());
+            config.addDataSourceProperty("trustCertificateKeyStoreType", "PKCS12");
+            config.addDataSourceProperty("trustCertificateKeyStorePassword", caBundle.password());
+            logger.info("Verifying server certificates against {} RDS CA certificates", caBundle.certificateCount());
+        }
+
+        switch (connectionStrategy) {
+            case "fresh":
+                // No pool: every operation opens (and closes) its own connection
+                logger.info("Using a fresh connection per operation (no pool)");
+                return new FreshConnectionDataSource(jdbcUrl, username, password,
+                        config.getDataSourceProperties());
+            case "pool-max-lifetime":
+                // Retire pooled connections quickly so connections opened before the switchover
+                // are replaced soon after it
+                config.setMaxLifetime(TimeUnit.SECONDS.toMillis(maxLifetime));
+                break;
+            case "pool-validation":
+                // Validate every borrowed connection with a query, not only those idle for 500ms
+                System.setProperty("com.zaxxer.hikari.aliveBypassWindowMs", "0");
+                config.setConnectionTestQuery("SELECT 1");
+                config.setValidationTimeout(1000);
+                config.setKeepaliveTime(30000);
+                break;
+            default:
+                break;
+        }
+
+        HikariDataSource dataSource = new HikariDataSource(config);
         logger.info("Connection pool initialized successfully");
+        return dataSource;
     }
 
     /**
@@ -150,11 +491,27 @@ public class WorkloadSimulator {
     private void startMetricsServer() throws IOException {
         if (enableMetrics) {
             DefaultExports.initialize();
+            runInfo.labels(runId).set(1);
             prometheusServer = new HTTPServer(8080);
             logger.info("Prometheus metrics server started on port 8080");
         }
     }
 
+    /**
+     * Start the control API when --control-port opts in; a simulator without it keeps running
+     */
+    private void startControlServer() {
+        if (controlPort <= 0) {
+            return;
+        }
+        try {
+            controlServer = new ControlServer(controlPort, new SimulatorControls());
+            logger.info("Control API listening on 127.0.0.1:{}", controlPort);
+        } catch (IOException e) {
+            logger.error("Failed to start the control API on port {}: {}", controlPort, e.getMessage());
+        }
+    }
+
     /**
      * Initialize and start the workload simulator
      */
@@ -163,32 +520,124 @@ public class WorkloadSimulator {
         logConfiguration();
 
         // Initialize resources
-        initializeDataSource();
+        initializeDataSources();
         startMetricsServer();
+        startControlServer();
+
+        // Persist run state so a restarted simulator resumes the same run
+        if (stateFile != null) {
+            runState = new RunState(Paths.get(stateFile), configSummary());
+            if (runState.isResumed()) {
+                resumeRun();
+                profileStart = runState.getRunStart();
+            } else {
+                logger.info("Recording run state to {}", stateFile);
+            }
+        }
+
+        // Write machine-readable statistics alongside the log output
+        if (!"text".equals(outputFormat)) {
+            statsWriter = new StatsWriter(Paths.get(outputFile), outputFormat, runId);
+            logger.info("Writing {} statistics to {}", outputFormat, outputFile);
+        }
+
+        // Publish statistics as CloudWatch custom metrics
+        if (cloudwatchNamespace != null) {
+            cloudWatchPublisher = new CloudWatchPublisher(cloudwatchNamespace, runId, auroraEndpoint,
+                    successfulRequests.get(), failedRequests.get());
+            logger.info("Publishing CloudWatch metrics to namespace {} (RunId={})", cloudwatchNamespace, runId);
+        }
+
+        // Register the run in the experiment registry; a resumed run keeps its start
+        if (registryTable != null) {
+            Instant runStart = runState != null ? Instant.ofEpochMilli(runState.getRunStart()) : Instant.now();
+            runRegistry = new RunRegistry(registryTable, runId, auroraEndpoint, workload, registryConfig(), runStart);
+            try {
+                runRegistry.started();
+                logger.info("Registered run {} in {}", runId, registryTable);
+            } catch (RuntimeException e) {
+                logger.error("Failed to register the run in {}: {}", registryTable, e.getMessage());
+            }
+        }
+
+        // Record acknowledged writes for post-switchover consistency verification
+        if (verifyLedgerPath != null) {
+            writeLedger = new WriteLedger(Paths.get(verifyLedgerPath), runState != null ? runState.getSequence() : 0);
+            logger.info("Recording write ledger to {}", verifyLedgerPath);
+        }
+
+        // Track endpoint DNS changes during switchover
+        if (trackDns) {
+            dnsTracker = new DnsTracker(auroraEndpoint, dnsTtlOverride >= 0);
+            dnsTracker.start();
+        }
+
+        // Keep long-running write transactions or table locks open (chaos mode)
+        if (holdTransactions > 0) {
+            lockHolder = new LockHolder(targets.get(0).dataSource, holdTransactions, holdDuration, holdTableLocks);
+            lockHolder.start();
+        }
+
+        // Measure read-your-writes lag: write through the first target, read from the reader endpoint
+        if (probeInterval > 0) {
+            probeReader = createDataSource(new Target("probe-reader", probeReaderEndpoint, false, targets.get(0).driver));
+            probe = new ReadAfterWriteProbe(targets.get(0).dataSource, probeReader, probeReaderEndpoint, probeInterval,
+                    probeTimeout, probeLagThreshold, latencyTracker,
+                    runState != null ? runState.getCounter("probes") : 0,
+                    runState != null ? runState.getCounter("probe_anomalies") : 0);
+            probe.start();
+        }
+
+        // Bound the operations in flight, shedding those that wait too long for a slot
+        if (maxInFlight > 0) {
+            backpressure = new Backpressure(maxInFlight, maxQueued, queueTimeout,
+                    runState != null ? runState.getCounter("shed") : 0);
+        }
 
         // Create thread pool for workers
-        executorService = Executors.newFixedThreadPool(writeWorkers);
+        executorService = Executors.newFixedThreadPool(writeWorkers * targets.size());
         scheduledExecutor = Executors.newScheduledThreadPool(2);
 
         // Schedule statistics logging
         scheduledExecutor.scheduleAtFixedRate(this::logStatistics, logInterval, logInterval, TimeUnit.SECONDS);
 
-        // Start write workers
-        logger.info("Starting {} write workers...", writeWorkers);
+        // Schedule ledger flushing
+        if (writeLedger != null) {
+            scheduledExecutor.scheduleAtFixedRate(this::flushLedger, 1, 1, TimeUnit.SECONDS);
+        }
+
+        // Start write workers, an independent set per target
+        logger.info("Starting {} write workers{}...", writeWorkers, targets.size() > 1 ? " per target" : "");
+        if (totalRate > 0) {
+            sharedLimiter = new RateLimiter(() -> currentRate(totalRate));
+        }
+        rateIntervalStart = rateRunStart = System.nanoTime();
         List<Future<?>> workerFutures = new ArrayList<>();
-        for (int i = 1; i <= writeWorkers; i++) {
-            Future<?> future = executorService.submit(new WriteWorker(i));
-            workerFutures.add(future);
+        int workerId = 1;
+        for (Target target : targets) {
+            for (int i = 0; i < writeWorkers; i++) {
+                WriteWorker worker = new WriteWorker(workerId++, target);
+                workers.add(worker);
+                Future<?> future = executorService.submit(worker);
+                workerFutures.add(future);
+            }
         }
 
-        // Wait for shutdown signal
+        // Wait for shutdown signal (SIGINT, SIGTERM) or the end of --duration
         Runtime.getRuntime().addShutdownHook(new Thread(this::shutdown));
 
         // Keep main thread alive
+        long deadline = duration > 0 ? System.nanoTime() + TimeUnit.SECONDS.toNanos(duration) : 0;
         try {
             for (Future<?> future : workerFutures) {
-                future.get();
+                if (duration > 0) {
+                    future.get(Math.max(0, deadline - System.nanoTime()), TimeUnit.NANOSECONDS);
+                } else {
+                    future.get();
+                }
             }
+        } catch (TimeoutException e) {
+            logger.info("Run duration of {} seconds reached", duration);
         } catch (InterruptedException | ExecutionException e) {
             logger.error("Worker execution interrupted", e);
         }
@@ -200,21 +649,70 @@ public class WorkloadSimulator {
     private void shutdown() {
         logger.info("Shutting down workload simulator...");
 
+        // Let the workers finish the operations in flight, so transactions are committed or
+        // rolled back rather than cut off, then interrupt the ones still retrying
+        stopping = true;
         if (executorService != null) {
-            executorService.shutdownNow();
+            executorService.shutdown();
+            try {
+                if (!executorService.awaitTermination(drainTimeout, TimeUnit.SECONDS)) {
+                    logger.warn("Operations still in flight after {} seconds; interrupting the workers", drainTimeout);
+                    executorService.shutdownNow();
+                    executorService.awaitTermination(5, TimeUnit.SECONDS);
+                }
+            } catch (InterruptedException e) {
+                executorService.shutdownNow();
+                Thread.currentThread().interrupt();
+            }
         }
         if (scheduledExecutor != null) {
             scheduledExecutor.shutdownNow();
         }
-        if (dataSource instanceof HikariDataSource) {
-            ((HikariDataSource) dataSource).close();
+        if (dnsTracker != null) {
+            dnsTracker.stop();
+        }
+        if (lockHolder != null) {
+            lockHolder.stop();
+        }
+        if (probe != null) {
+            probe.stop();
+        }
+        if (writeLedger != null) {
+            try {
+                writeLedger.close();
+            } catch (IOException e) {
+                logger.error("Failed to close write ledger", e);
+            }
+        }
+        if (runState != null) {
+            checkpointState();
+        }
+        if (cloudWatchPublisher != null) {
+            cloudWatchPublisher.close();
+        }
+        for (Target target : targets) {
+            if (target.dataSource instanceof HikariDataSource) {
+                ((HikariDataSource) target.dataSource).close();
+            }
+        }
+        if (probeReader instanceof HikariDataSource) {
+            ((HikariDataSource) probeReader).close();
         }
         if (prometheusServer != null) {
             prometheusServer.close();
         }
+        if (controlServer != null) {
+            controlServer.close();
+        }
 
         logFinalStatistics();
+        if (runRegistry != null) {
+            registerResults();
+            runRegistry.close();
+        }
         logger.info("Workload simulator stopped");
+        // Log4j2's own shutdown hook is disabled so the final report is logged; flush it now
+        LogManager.shutdown();
     }
 
     /**
@@ -222,28 +720,68 @@ public class WorkloadSimulator {
      */
     private class WriteWorker implements Runnable {
         private final int workerId;
+        private final Target target;
         private final Random random = new Random();
-        private final int delayMs;
+        private final RateLimiter rateLimiter;
+        // Operations completed, successful or failed, for the achieved rate
+        private final AtomicLong operations = new AtomicLong(0);
+        private long reportedOperations = 0; // operations at the start of the statistics interval
         private String lastKnownHost = null;
+        private long errorSince = 0; // nanoTime of the first connection error since the last success
+        // --statement-mode reuse: the connection the worker holds, with a prepared INSERT per table
+        private final List<String> reuseTables = new ArrayList<>();
+        private Connection reusedConnection;
+        private final Map<String, PreparedStatement> reusedStatements = new HashMap<>();
 
-        public WriteWorker(int workerId) {
+        public WriteWorker(int workerId, Target target) {
             this.workerId = workerId;
-            // Calculate delay to achieve target write rate
-            this.delayMs = writeRate > 0 ? 1000 / writeRate : 100;
+            this.target = target;
+            this.rateLimiter = sharedLimiter != null ? sharedLimiter : new RateLimiter(() -> currentRate(workerRate()));
+            if ("reuse".equals(statementMode)) {
+                while (reuseTables.size() < REUSE_TABLES) {
+                    String table = String.format("test_%04d", random.nextInt(12000) + 1);
+                    if (!reuseTables.contains(table)) {
+                        reuseTables.add(table);
+                    }
+                }
+            }
         }
 
         @Override
         public void run() {
-            logger.info("Worker-{} started", workerId);
+            logger.info("Worker-{} started ({})", workerId, target.name);
 
-            while (!Thread.currentThread().isInterrupted()) {
+            while (!stopping && !Thread.currentThread().isInterrupted()) {
                 try {
-                    executeWrite();
+                    if (paused) {
+                        Thread.sleep(100);
+                        continue;
+                    }
+
+                    // Rate limiting, at the load profile's share of the write rate
+                    rateLimiter.acquire();
+                    if (stopping) {
+                        break;
+                    }
 
-                    // Rate limiting
-                    if (delayMs > 0) {
-                        Thread.sleep(delayMs);
+                    if (backpressure != null && !backpressure.acquire()) {
+                        shedOperations.inc();
+                        logger.debug("[{}] SHED: Worker-{} | No in-flight slot within {}ms", getCurrentTime(),
+                                workerId, queueTimeout);
+                        continue;
+                    }
+                    try {
+                        if ("transactional".equals(workload)) {
+                            executeTransaction();
+                        } else {
+                            executeWrite();
+                        }
+                    } finally {
+                        if (backpressure != null) {
+                            backpressure.release();
+                        }
                     }
+                    operations.incrementAndGet();
                 } catch (InterruptedException e) {
                     Thread.currentThread().interrupt();
                     break;
@@ -253,6 +791,7 @@ public class WorkloadSimulator {
                 }
             }
 
+            discardReused();
             logger.info("Worker-{} stopped", workerId);
         }
 
@@ -260,43 +799,52 @@ public class WorkloadSimulator {
          * Execute a single write operation with retry logic
          */
         private void executeWrite() {
-            String tableName = String.format("test_%04d", random.nextInt(12000) + 1);
+            String tableName = "reuse".equals(statementMode)
+                    ? reuseTables.get(random.nextInt(reuseTables.size()))
+                    : String.format("test_%04d", random.nextInt(12000) + 1);
+
+            // Generate random data once so retries resend the same payload
+            String col1 = generateRandomString(20);
+            int col2 = random.nextInt(1000);
+            String col3 = generateRandomString(50);
+            double col4 = random.nextDouble() * 1000;
+            long col5 = writeLedger != null ? writeLedger.nextSequence() : System.currentTimeMillis();
+
             int maxRetries = 5; // Increased retries for minimal downtime
             int retryDelayMs = 500; // Start with 500ms - faster retry for minimal downtime
+            long operationStart = System.nanoTime();
 
             for (int attempt = 1; attempt <= maxRetries; attempt++) {
                 long startTime = System.nanoTime();
 
-                try (Connection conn = dataSource.getConnection();
-                     PreparedStatement stmt = conn.prepareStatement(
-                         "INSERT INTO " + tableName + " (col1, col2, col3, col4, col5) VALUES (?, ?, ?, ?, ?)")) {
-
-                    // Generate random data
-                    stmt.setString(1, generateRandomString(20));
-                    stmt.setInt(2, random.nextInt(1000));
-                    stmt.setString(3, generateRandomString(50));
-                    stmt.setDouble(4, random.nextDouble() * 1000);
-                    stmt.setLong(5, System.currentTimeMillis());
+                try {
+                    String currentHost;
+                    if ("reuse".equals(statementMode)) {
+                        PreparedStatement stmt = reusedInsert(tableName);
+                        bind(stmt, col1, col2, col3, col4, col5);
+                        stmt.executeUpdate();
+                        currentHost = trackHost(stmt.getConnection());
+                    } else {
+                        try (Connection conn = target.dataSource.getConnection()) {
+                            update(conn, insertSql(tableName), col1, col2, col3, col4, col5);
+                            // Get current connection info
+                            currentHost = trackHost(conn);
+                        }
+                    }
 
-                    stmt.executeUpdate();
+                    if (writeLedger != null) {
+                        recordWrite(col5, tableName, WriteLedger.checksum(col1, col2, col3));
+                    }
 
                     long latencyNanos = System.nanoTime() - startTime;
                     double latencyMs = latencyNanos / 1_000_000.0;
 
-                    // Get current connection info
-                    String currentHost = getCurrentHost(conn);
-
-                    // Detect host switch (indicates Blue-Green switchover or failover)
-                    if (lastKnownHost != null && !currentHost.equals(lastKnownHost)) {
-                        logger.info("[{}] INFO: Worker-{} | Switched to new host: {} (from: {})",
-                                getCurrentTime(), workerId, currentHost, lastKnownHost);
-                    }
-                    lastKnownHost = currentHost;
-
                     successfulRequests.incrementAndGet();
+                    target.successfulRequests.incrementAndGet();
                     totalRequests.incrementAndGet();
                     writeRequests.labels("success").inc();
                     writeLatency.observe(latencyNanos / 1_000_000_000.0);
+                    recordSuccess("insert", operationStart);
 
                     logger.debug("[{}] SUCCESS: Worker-{} | Host: {} | Table: {} | INSERT completed | Latency: {}ms{}",
                             getCurrentTime(), workerId, currentHost, tableName, String.format("%.2f", latencyMs),
@@ -305,41 +853,310 @@ public class WorkloadSimulator {
                     return; // Success - exit retry loop
 
                 } catch (SQLException e) {
-                    String errorType = categorizeError(e);
-                    boolean isFailoverError = errorType.contains("connection") || errorType.contains("failover");
-
-                    if (attempt < maxRetries && isFailoverError) {
-                        // Retry for connection/failover errors
-                        logger.warn("[{}] ERROR: Worker-{} | Table: {} | {} | Retry {}/{} in {}ms | Error: {}",
-                                getCurrentTime(), workerId, tableName, errorType, attempt, maxRetries,
-                                retryDelayMs, e.getMessage());
-
-                        try {
-                            Thread.sleep(retryDelayMs);
-                            retryDelayMs *= 2; // Exponential backoff
-                        } catch (InterruptedException ie) {
-                            Thread.currentThread().interrupt();
-                            break;
+                    if ("reuse".equals(statementMode)) {
+                        afterReuseError(tableName, e);
+                    }
+                    if (!retryAfterError(e, tableName, attempt, maxRetries, retryDelayMs)) {
+                        break;
+                    }
+                    retryDelayMs *= 2; // Exponential backoff
+                }
+            }
+        }
+
+        /**
+         * Execute a read-modify-write transaction across several tables with retry logic
+         */
+        private void executeTransaction() {
+            // Sorted so concurrent transactions lock tables in the same order
+            Set<String> tables = new TreeSet<>();
+            while (tables.size() < transactionSize) {
+                tables.add(String.format("test_%04d", random.nextInt(12000) + 1));
+            }
+            String tableList = String.join(",", tables);
+
+            int maxRetries = 5;
+            int retryDelayMs = 500;
+            long operationStart = System.nanoTime();
+
+            for (int attempt = 1; attempt <= maxRetries; attempt++) {
+                long startTime = System.nanoTime();
+                boolean started = false;
+                boolean commitSent = false;
+
+                try (Connection conn = target.dataSource.getConnection()) {
+                    conn.setAutoCommit(false);
+                    started = true;
+                    try {
+                        for (String tableName : tables) {
+                            readModifyWrite(conn, tableName);
                         }
-                    } else {
-                        // Final failure or non-retryable error
-                        failedRequests.incrementAndGet();
-                        totalRequests.incrementAndGet();
-                        writeRequests.labels("failure").inc();
-                        connectionErrors.labels(errorType).inc();
-
-                        logger.error("[{}] ERROR: Worker-{} | Table: {} | {} | Error: {}{}",
-                                getCurrentTime(), workerId, tableName, errorType, e.getMessage(),
-                                attempt > 1 ? " (after " + (attempt - 1) + " retries)" : "");
-
-                        if (isFailoverError) {
-                            logger.info("[{}] INFO: Worker-{} | Will retry on next operation...",
-                                    getCurrentTime(), workerId);
+                        commitSent = true;
+                        conn.commit();
+                    } catch (SQLException e) {
+                        if (!commitSent) {
+                            rollbackQuietly(conn);
                         }
+                        throw e;
+                    }
+
+                    long latencyNanos = System.nanoTime() - startTime;
+                    String currentHost = trackHost(conn);
+
+                    committedTransactions.incrementAndGet();
+                    successfulRequests.incrementAndGet();
+                    target.successfulRequests.incrementAndGet();
+                    totalRequests.incrementAndGet();
+                    transactions.labels("committed").inc();
+                    writeRequests.labels("success").inc();
+                    writeLatency.observe(latencyNanos / 1_000_000_000.0);
+                    recordSuccess("transaction", operationStart);
+
+                    logger.debug("[{}] SUCCESS: Worker-{} | Host: {} | Table: {} | COMMIT completed | Latency: {}ms{}",
+                            getCurrentTime(), workerId, currentHost, tableList, String.format("%.2f", latencyNanos / 1_000_000.0),
+                            attempt > 1 ? " (retry " + (attempt - 1) + ")" : "");
+                    return;
+
+                } catch (SQLException e) {
+                    if (commitSent) {
+                        // The connection dropped while the commit was in flight: the transaction
+                        // may or may not have been applied
+                        unknownTransactions.incrementAndGet();
+                        transactions.labels("unknown").inc();
+                        logger.warn("[{}] WARN: Worker-{} | Table: {} | Commit outcome unknown: {}",
+                                getCurrentTime(), workerId, tableList, e.getMessage());
+                    } else if (started) {
+                        rolledBackTransactions.incrementAndGet();
+                        transactions.labels("rolled_back").inc();
+                    }
+
+                    if (!retryAfterError(e, tableList, attempt, maxRetries, retryDelayMs)) {
                         break;
                     }
+                    retryDelayMs *= 2; // Exponential backoff
+                }
+            }
+        }
+
+        /**
+         * Read the latest row of a table with a row lock and update it, or insert a first row
+         */
+        private void readModifyWrite(Connection conn, String tableName) throws SQLException {
+            long id = 0;
+            int col2 = 0;
+            String sql = "SELECT id, col2 FROM " + tableName + " ORDER BY id DESC LIMIT 1 FOR UPDATE";
+            boolean text = "text".equals(statementMode);
+            try (Statement select = text ? conn.createStatement() : conn.prepareStatement(sql);
+                 ResultSet rs = text ? select.executeQuery(sql) : ((PreparedStatement) select).executeQuery()) {
+                if (rs.next()) {
+                    id = rs.getLong(1);
+                    col2 = rs.getInt(2);
+                }
+            }
+
+            if (id > 0) {
+                update(conn, "UPDATE " + tableName + " SET col2 = ?, col5 = ? WHERE id = ?",
+                        col2 + 1, System.currentTimeMillis(), id);
+            } else {
+                update(conn, insertSql(tableName), generateRandomString(20), 1, generateRandomString(50),
+                        random.nextDouble() * 1000, System.currentTimeMillis());
+            }
+        }
+
+        private String insertSql(String tableName) {
+            return "INSERT INTO " + tableName + " (col1, col2, col3, col4, col5) VALUES (?, ?, ?, ?, ?)";
+        }
+
+        /**
+         * Run an INSERT or UPDATE as a prepared statement, or with --statement-mode text as a plain
+         * text query with the values inlined
+         */
+        private void update(Connection conn, String sql, Object... values) throws SQLException {
+            if ("text".equals(statementMode)) {
+                try (Statement stmt = conn.createStatement()) {
+                    stmt.executeUpdate(inline(sql, values));
+                }
+                return;
+            }
+            try (PreparedStatement stmt = conn.prepareStatement(sql)) {
+                bind(stmt, values);
+                stmt.executeUpdate();
+            }
+        }
+
+        private void bind(PreparedStatement stmt, Object... values) throws SQLException {
+            for (int i = 0; i < values.length; i++) {
+                stmt.setObject(i + 1, values[i]);
+            }
+        }
+
+        /**
+         * Replace the placeholders of sql with the values, strings quoted
+         */
+        private String inline(String sql, Object... values) {
+            StringBuilder sb = new StringBuilder();
+            int value = 0;
+            for (char c : sql.toCharArray()) {
+                if (c != '?') {
+                    sb.append(c);
+                } else if (values[value] instanceof String) {
+                    sb.append('\'').append(((String) values[value++]).replace("'", "''")).append('\'');
+                } else {
+                    sb.append(values[value++]);
+                }
+            }
+            return sb.toString();
+        }
+
+        /**
+         * The prepared INSERT of a table on the worker's held connection, prepared on first use and
+         * then reused by every later write to the table
+         */
+        private PreparedStatement reusedInsert(String tableName) throws SQLException {
+            if (reusedConnection == null) {
+                reusedConnection = target.dataSource.getConnection();
+            }
+            PreparedStatement stmt = reusedStatements.get(tableName);
+            if (stmt == null) {
+                stmt = reusedConnection.prepareStatement(insertSql(tableName));
+                reusedStatements.put(tableName, stmt);
+            }
+            return stmt;
+        }
+
+        /**
+         * Keep the held connection and its statements while the connection is open, so statements
+         * prepared before a switchover are reused after it; a stale statement is prepared again on
+         * its next use, and a broken connection is replaced with its statements
+         */
+        private void afterReuseError(String tableName, SQLException e) {
+            boolean valid;
+            try {
+                // The pool's connection stays open until returned, so ask the driver
+                valid = reusedConnection != null && reusedConnection.isValid(2);
+            } catch (SQLException ce) {
+                valid = false;
+            }
+            if (!valid) {
+                discardReused();
+            } else if ("stale_statement".equals(categorizeError(e))) {
+                closeQuietly(reusedStatements.remove(tableName));
+            }
+        }
+
+        /**
+         * Close the held connection and its prepared statements
+         */
+        private void discardReused() {
+            for (PreparedStatement stmt : reusedStatements.values()) {
+                closeQuietly(stmt);
+            }
+            reusedStatements.clear();
+            if (reusedConnection != null) {
+                try {
+                    reusedConnection.close();
+                } catch (SQLException e) {
+                    logger.debug("Worker-{} failed to close its connection: {}", workerId, e.getMessage());
+                }
+                reusedConnection = null;
+            }
+        }
+
+        private void closeQuietly(PreparedStatement stmt) {
+            if (stmt == null) {
+                return;
+            }
+            try {
+                stmt.close();
+            } catch (SQLException e) {
+                logger.debug("Worker-{} failed to close a statement: {}", workerId, e.getMessage());
+            }
+        }
+
+        /**
+         * Record the latency of a successful operation and, after connection errors, how long this
+         * worker took to recover
+         */
+        private void recordSuccess(String operation, long operationStart) {
+            long now = System.nanoTime();
+            latencyTracker.record(metricKey(operation, target), now - operationStart);
+            if (errorSince > 0) {
+                long recoveryNanos = now - errorSince;
+                recoveryTracker.record(metricKey(connectionStrategy, target), recoveryNanos);
+                logger.info("[{}] RECOVERY: Worker-{} | Recovered after {}ms ({} strategy, {})",
+                        getCurrentTime(), workerId, String.format("%.0f", recoveryNanos / 1_000_000.0),
+                        connectionStrategy, target.name);
+                errorSince = 0;
+            }
+        }
+
+        private void rollbackQuietly(Connection conn) {
+            try {
+                conn.rollback();
+            } catch (SQLException e) {
+                // The server rolls back the transaction itself when the connection is lost
+                logger.debug("Worker-{} rollback failed: {}", workerId, e.getMessage());
+            }
+        }
+
+        /**
+         * Log a failed attempt and wait before the next one; returns false when the operation
+         * is not retried (final attempt or non-retryable error) and has been counted as failed
+         */
+        private boolean retryAfterError(SQLException e, String tables, int attempt, int maxRetries, int retryDelayMs) {
+            String errorType = categorizeError(e);
+            if ("stale_statement".equals(errorType)) {
+                staleStatements.incrementAndGet();
+                staleStatementErrors.inc();
+            }
+            // Statements prepared on a connection the switchover replaced fail like the connection
+            boolean isFailoverError = errorType.contains("connection") || errorType.contains("failover")
+                    || "stale_statement".equals(errorType);
+            if (isFailoverError) {
+                target.recordError(System.currentTimeMillis());
+                if (errorSince == 0) {
+                    errorSince = System.nanoTime();
+                }
+            }
+
+            if (attempt < maxRetries && isFailoverError) {
+                logger.warn("[{}] ERROR: Worker-{} | Table: {} | {} | Retry {}/{} in {}ms | Error: {}",
+                        getCurrentTime(), workerId, tables, errorType, attempt, maxRetries,
+                        retryDelayMs, e.getMessage());
+                try {
+                    Thread.sleep(retryDelayMs);
+                    return true;
+                } catch (InterruptedException ie) {
+                    Thread.currentThread().interrupt();
+                    return false;
                 }
             }
+
+            // Final failure or non-retryable error
+            failedRequests.incrementAndGet();
+            target.failedRequests.incrementAndGet();
+            totalRequests.incrementAndGet();
+            writeRequests.labels("failure").inc();
+            connectionErrors.labels(errorType).inc();
+
+            logger.error("[{}] ERROR: Worker-{} | Table: {} | {} | Error: {}{}",
+                    getCurrentTime(), workerId, tables, errorType, e.getMessage(),
+                    attempt > 1 ? " (after " + (attempt - 1) + " retries)" : "");
+
+            if (isFailoverError) {
+                logger.info("[{}] INFO: Worker-{} | Will retry on next operation...",
+                        getCurrentTime(), workerId);
+            }
+            return false;
+        }
+
+        private void recordWrite(long seq, String tableName, long checksum) {
+            try {
+                writeLedger.record(seq, tableName, checksum);
+            } catch (IOException e) {
+                logger.error("[{}] ERROR: Worker-{} | Failed to record seq {} in write ledger: {}",
+                        getCurrentTime(), workerId, seq, e.getMessage());
+            }
         }
 
         private String generateRandomString(int length) {
@@ -353,7 +1170,13 @@ public class WorkloadSimulator {
 
         private String categorizeError(SQLException e) {
             String message = e.getMessage().toLowerCase();
-            if (message.contains("communications link failure") || message.contains("connection")) {
+            // ER_UNKNOWN_STMT_HANDLER, ER_NEED_REPREPARE and statements of a replaced connection
+            if (e.getErrorCode() == 1243 || e.getErrorCode() == 1615
+                    || message.contains("prepared statement") || message.contains("statement closed")) {
+                return "stale_statement";
+            } else if (message.contains("certificate") || message.contains("ssl") || message.contains("pkix")) {
+                return "tls_error";
+            } else if (message.contains("communications link failure") || message.contains("connection")) {
                 return "connection_lost";
             } else if (message.contains("timeout")) {
                 return "timeout";
@@ -364,17 +1187,65 @@ public class WorkloadSimulator {
             }
         }
 
-        private String getCurrentHost(Connection conn) {
-            try (PreparedStatement stmt = conn.prepareStatement("SELECT @@hostname, @@read_only");
-                 java.sql.ResultSet rs = stmt.executeQuery()) {
-                if (rs.next()) {
-                    String hostname = rs.getString(1);
-                    int readOnly = rs.getInt(2);
+        /**
+         * Get the host of the connection and log when it differs from the previous operation's
+         * host (indicates Blue-Green switchover or failover)
+         */
+        private String trackHost(Connection conn) {
+            String currentHost = getCurrentHost(conn);
+            if (lastKnownHost != null && !currentHost.equals(lastKnownHost)) {
+                logger.info("[{}] INFO: Worker-{} | Switched to new host: {} (from: {})",
+                        getCurrentTime(), workerId, currentHost, lastKnownHost);
+                if (dnsTracker != null && !target.proxy && currentHost.endsWith("(writer)")) {
+                    dnsTracker.onWriterHostSwitch(currentHost, System.currentTimeMillis());
+                }
+                if (!"disabled".equals(tlsMode)) {
+                    logTlsSession(conn, currentHost);
+                }
+            }
+            lastKnownHost = currentHost;
+            return currentHost;
+        }
 
-                    // Extract instance identifier from hostname
-                    // Format: ip-10-0-1-123.ec2.internal or similar
-                    // We'll return a shortened version with role indicator
-                    if (hostname != null && !hostname.isEmpty()) {
+        /**
+         * Log the TLS version and cipher negotiated with a new host, confirming that encryption (and
+         * with verify-ca, certificate verification) still works after the switchover
+         */
+        private void logTlsSession(Connection conn, String host) {
+            try (PreparedStatement stmt = conn.prepareStatement(
+                    "SHOW SESSION STATUS WHERE Variable_name IN ('Ssl_version', 'Ssl_cipher')");
+                 ResultSet rs = stmt.executeQuery()) {
+                String version = "";
+                String cipher = "";
+                while (rs.next()) {
+                    if ("Ssl_version".equals(rs.getString(1))) {
+                        version = rs.getString(2);
+                    } else {
+                        cipher = rs.getString(2);
+                    }
+                }
+                if (version.isEmpty()) {
+                    logger.warn("[{}] TLS: Worker-{} | Connection to {} is not encrypted", getCurrentTime(), workerId, host);
+                } else {
+                    logger.info("[{}] TLS: Worker-{} | Connected to {} over {} ({}){}", getCurrentTime(), workerId,
+                            host, version, cipher, "verify-ca".equals(tlsMode) ? ", certificate verified" : "");
+                }
+            } catch (SQLException e) {
+                logger.debug("Failed to get TLS session status: {}", e.getMessage());
+            }
+        }
+
+        private String getCurrentHost(Connection conn) {
+            try (PreparedStatement stmt = conn.prepareStatement("SELECT @@hostname, @@read_only");
+                 java.sql.ResultSet rs = stmt.executeQuery()) {
+                if (rs.next()) {
+                    String hostname = rs.getString(1);
+                    int readOnly = rs.getInt(2);
+
+                    // Extract instance identifier from hostname
+                    // Format: ip-10-0-1-123.ec2.internal or similar
+                    // We'll return a shortened version with role indicator
+                    if (hostname != null && !hostname.isEmpty()) {
                         // Try to extract meaningful part (first segment before first dot)
                         String shortHost = hostname;
                         int dotIndex = hostname.indexOf('.');
@@ -396,9 +1267,113 @@ public class WorkloadSimulator {
     }
 
     /**
-     * Log current statistics
+     * Log current statistics and the latency percentiles of the last interval
      */
     private void logStatistics() {
+        logCounters();
+        List<LatencyTracker.Snapshot> latency = latencyTracker.interval();
+        List<LatencyTracker.Snapshot> recovery = recoveryTracker.interval();
+        for (LatencyTracker.Snapshot snapshot : latency) {
+            logger.info("[{}] LATENCY: {}", getCurrentTime(), snapshot);
+        }
+        for (LatencyTracker.Snapshot snapshot : recovery) {
+            logger.info("[{}] RECOVERY: {}", getCurrentTime(), snapshot);
+        }
+        logRate();
+        logBackpressure();
+        writeStats("interval", latency, recovery);
+        if (cloudWatchPublisher != null) {
+            try {
+                cloudWatchPublisher.publish(successfulRequests.get(), failedRequests.get(), latency, recovery);
+            } catch (RuntimeException e) {
+                logger.error("Failed to publish CloudWatch metrics: {}", e.getMessage());
+            }
+        }
+        if (runState != null) {
+            checkpointState();
+        }
+    }
+
+    /**
+     * Log the target and achieved rate of the last interval, and the slowest and fastest worker's
+     * share of it
+     */
+    private void logRate() {
+        if (workers.isEmpty()) {
+            return;
+        }
+        long now = System.nanoTime();
+        double seconds = (now - rateIntervalStart) / 1e9;
+        rateIntervalStart = now;
+        long operations = 0;
+        double slowest = Double.MAX_VALUE;
+        double fastest = 0;
+        for (WriteWorker worker : workers) {
+            long completed = worker.operations.get();
+            long interval = completed - worker.reportedOperations;
+            worker.reportedOperations = completed;
+            operations += interval;
+            slowest = Math.min(slowest, interval / seconds);
+            fastest = Math.max(fastest, interval / seconds);
+        }
+        String profile = loadProfile.isConstant() ? ""
+                : String.format(" | Profile: %s at %.0f%%", loadProfile,
+                        loadProfile.factor(System.currentTimeMillis() - profileStart) * 100);
+        logger.info("[{}] RATE: Target: {} ops/sec | Achieved: {} ops/sec | Per worker: {} - {} ops/sec{}",
+                getCurrentTime(), String.format("%.1f", targetRate()), String.format("%.1f", operations / seconds),
+                String.format("%.1f", slowest), String.format("%.1f", fastest), profile);
+    }
+
+    /**
+     * Log the operations in flight and queued for a slot now, and the operations shed so far
+     */
+    private void logBackpressure() {
+        if (backpressure == null) {
+            return;
+        }
+        inFlightOperations.set(backpressure.getInFlight());
+        queuedOperations.set(backpressure.getQueued());
+        logger.info("[{}] BACKPRESSURE: In flight: {}/{} | Queued: {} | Shed: {}", getCurrentTime(),
+                backpressure.getInFlight(), backpressure.getMaxInFlight(), backpressure.getQueued(),
+                backpressure.getShed());
+    }
+
+    /**
+     * Operations per second of this process's run, successful or failed
+     */
+    private double achievedRate() {
+        double seconds = (System.nanoTime() - rateRunStart) / 1e9;
+        long operations = workers.stream().mapToLong(worker -> worker.operations.get()).sum();
+        return seconds > 0 ? operations / seconds : 0.0;
+    }
+
+    /**
+     * Operations per second all workers together are limited to now
+     */
+    private double targetRate() {
+        if (totalRate > 0) {
+            return currentRate(totalRate);
+        }
+        return currentRate(workerRate()) * workers.size();
+    }
+
+    /**
+     * Writes per second of a worker without --total-rate; 0 and negative write rates keep the
+     * default of 10 writes per second
+     */
+    private double workerRate() {
+        int rate = writeRate;
+        return rate > 0 ? rate : 10;
+    }
+
+    /**
+     * The load profile's current share of rate
+     */
+    private double currentRate(double rate) {
+        return rate * loadProfile.factor(System.currentTimeMillis() - profileStart);
+    }
+
+    private void logCounters() {
         long total = totalRequests.get();
         long success = successfulRequests.get();
         long failed = failedRequests.get();
@@ -406,6 +1381,293 @@ public class WorkloadSimulator {
 
         logger.info("[{}] STATS: Total: {} | Success: {} | Failed: {} | Success Rate: {}%",
                 getCurrentTime(), total, success, failed, String.format("%.2f", successRate));
+        if ("transactional".equals(workload)) {
+            logger.info("[{}] TXN: Committed: {} | Rolled back: {} | Commit unknown: {}",
+                    getCurrentTime(), committedTransactions.get(), rolledBackTransactions.get(),
+                    unknownTransactions.get());
+        }
+        if (!"prepared".equals(statementMode) || staleStatements.get() > 0) {
+            logger.info("[{}] STMT: Mode: {} | Stale statement errors: {}",
+                    getCurrentTime(), statementMode, staleStatements.get());
+        }
+        if (probe != null) {
+            logger.info("[{}] PROBE: Probes: {} | Anomalies: {}", getCurrentTime(), probe.getProbes(), probe.getAnomalies());
+        }
+    }
+
+    /**
+     * Append a record to the machine-readable statistics output, if enabled
+     */
+    private void writeStats(String record, List<LatencyTracker.Snapshot> latency, List<LatencyTracker.Snapshot> recovery) {
+        if (statsWriter == null) {
+            return;
+        }
+        long[] transactionCounts = "transactional".equals(workload)
+                ? new long[]{committedTransactions.get(), rolledBackTransactions.get(), unknownTransactions.get()}
+                : null;
+        List<StatsWriter.TargetCounters> targetCounters = null;
+        if (targets.size() > 1) {
+            targetCounters = new ArrayList<>();
+            for (Target target : targets) {
+                targetCounters.add(new StatsWriter.TargetCounters(target.name, target.endpoint,
+                        target.successfulRequests.get(), target.failedRequests.get(), target.errorWindowMillis()));
+            }
+        }
+        StatsWriter.Counters counters = new StatsWriter.Counters(
+                totalRequests.get(), successfulRequests.get(), failedRequests.get(), transactionCounts, targetCounters,
+                statementMode, staleStatements.get(),
+                probe != null ? new long[]{probe.getProbes(), probe.getAnomalies()} : null);
+        try {
+            statsWriter.write(record, counters, latency, recovery);
+        } catch (IOException e) {
+            logger.error("Failed to write statistics to {}: {}", outputFile, e.getMessage());
+        }
+    }
+
+    /**
+     * Continue the counters of the run recorded in the state file
+     */
+    private void resumeRun() {
+        totalRequests.set(runState.getCounter("total"));
+        successfulRequests.set(runState.getCounter("success"));
+        failedRequests.set(runState.getCounter("failed"));
+        committedTransactions.set(runState.getCounter("committed"));
+        rolledBackTransactions.set(runState.getCounter("rolled_back"));
+        unknownTransactions.set(runState.getCounter("unknown"));
+        staleStatements.set(runState.getCounter("stale_statements"));
+
+        logger.info("[{}] RESUME: Resuming run started at {} (restart {}) from {}", getCurrentTime(),
+                formatTime(runState.getRunStart()), runState.getRestarts(), stateFile);
+        if (!configSummary().equals(runState.getConfig())) {
+            logger.warn("[{}] RESUME: Configuration differs from the original run: {}", getCurrentTime(), runState.getConfig());
+        }
+    }
+
+    /**
+     * Persist the counters and ledger sequence to the state file
+     */
+    private synchronized void checkpointState() {
+        Map<String, Long> counters = Map.of(
+                "total", totalRequests.get(),
+                "success", successfulRequests.get(),
+                "failed", failedRequests.get(),
+                "committed", committedTransactions.get(),
+                "rolled_back", rolledBackTransactions.get(),
+                "unknown", unknownTransactions.get(),
+                "stale_statements", staleStatements.get(),
+                "probes", probe != null ? probe.getProbes() : 0L,
+                "probe_anomalies", probe != null ? probe.getAnomalies() : 0L,
+                "shed", backpressure != null ? backpressure.getShed() : 0L);
+        try {
+            runState.checkpoint(counters, writeLedger != null ? writeLedger.getSequence() : 0);
+        } catch (SQLException e) {
+            logger.error("Failed to checkpoint run state: {}", e.getMessage());
+        }
+    }
+
+    /**
+     * Configuration registered with the run in the experiment registry
+     */
+    private Map<String, String> registryConfig() {
+        Map<String, String> config = new TreeMap<>();
+        config.put("endpoint", auroraEndpoint);
+        config.put("workload", workload);
+        config.put("writeWorkers", String.valueOf(writeWorkers));
+        config.put("writeRate", String.valueOf(writeRate));
+        if (totalRate > 0) {
+            config.put("totalRate", String.valueOf(totalRate));
+        }
+        if (maxInFlight > 0) {
+            config.put("maxInFlight", String.valueOf(maxInFlight));
+        }
+        if (!loadProfile.isConstant()) {
+            config.put("loadProfile", loadProfile.toString());
+        }
+        config.put("connectionPoolSize", String.valueOf(connectionPoolSize));
+        config.put("connectionStrategy", connectionStrategy);
+        config.put("tlsMode", tlsMode);
+        config.put("auth", auth);
+        if (customTargets()) {
+            config.put("targets", targetList());
+        }
+        if (!"wrapper".equals(driverList())) {
+            config.put("driver", driverList());
+        }
+        if (!"prepared".equals(statementMode)) {
+            config.put("statementMode", statementMode);
+        }
+        if (serverCursors) {
+            config.put("serverCursors", "true");
+        }
+        if (probeInterval > 0) {
+            config.put("probeInterval", String.valueOf(probeInterval));
+            config.put("probeReaderEndpoint", probeReaderEndpoint);
+        }
+        return config;
+    }
+
+    /**
+     * Register the stopped run with the run's counters and its worst latency and recovery time
+     */
+    private void registerResults() {
+        long total = totalRequests.get();
+        Map<String, Double> results = new TreeMap<>();
+        results.put("requests", (double) total);
+        results.put("failedRequests", (double) failedRequests.get());
+        results.put("successRate", total > 0 ? successfulRequests.get() * 100.0 / total : 0.0);
+        results.put("achievedRate", achievedRate());
+        if (backpressure != null) {
+            results.put("shedOperations", (double) backpressure.getShed());
+        }
+        results.put("staleStatements", (double) staleStatements.get());
+        if (probe != null) {
+            results.put("readAfterWriteAnomalies", (double) probe.getAnomalies());
+        }
+        latencyTracker.total().stream().mapToDouble(s -> s.p99).max()
+                .ifPresent(p99 -> results.put("latencyP99Ms", p99));
+        recoveryTracker.total().stream().mapToDouble(s -> s.max).max()
+                .ifPresent(max -> results.put("recoveryMaxMs", max));
+        try {
+            runRegistry.stopped(results);
+            logger.info("Registered the results of run {} in {}", runId, registryTable);
+        } catch (RuntimeException e) {
+            logger.error("Failed to register the results in {}: {}", registryTable, e.getMessage());
+        }
+    }
+
+    /**
+     * Configuration that identifies a run; a resumed run should use the same
+     */
+    private String configSummary() {
+        String summary = String.format("endpoint=%s workload=%s workers=%d rate=%d strategy=%s ledger=%s",
+                auroraEndpoint, workload, writeWorkers, writeRate, connectionStrategy, verifyLedgerPath);
+        if (totalRate > 0) {
+            summary += " total-rate=" + totalRate;
+        }
+        if (!loadProfile.isConstant()) {
+            summary += " profile=" + loadProfile;
+        }
+        if (customTargets()) {
+            summary += " targets=" + targetList();
+        }
+        if (!"wrapper".equals(driverList())) {
+            summary += " driver=" + driverList();
+        }
+        if (!"prepared".equals(statementMode)) {
+            summary += " statements=" + statementMode;
+        }
+        if (serverCursors) {
+            summary += " server-cursors";
+        }
+        if (probeInterval > 0) {
+            summary += " probe=" + probeInterval;
+        }
+        return summary;
+    }
+
+    /**
+     * Flush buffered write ledger entries to disk
+     */
+    private void flushLedger() {
+        try {
+            writeLedger.flush();
+        } catch (IOException e) {
+            logger.error("Failed to flush write ledger: {}", e.getMessage());
+        }
+    }
+
+    /**
+     * The control API's view of the simulator
+     */
+    private class SimulatorControls implements ControlServer.Controls {
+        @Override
+        public Map<String, Object> stats() {
+            long total = totalRequests.get();
+            Map<String, Object> requests = new LinkedHashMap<>();
+            requests.put("total", total);
+            requests.put("success", successfulRequests.get());
+            requests.put("failed", failedRequests.get());
+            requests.put("successRate", total > 0 ? successfulRequests.get() * 100.0 / total : 0.0);
+            Map<String, Object> rate = new LinkedHashMap<>();
+            rate.put("target", targetRate());
+            rate.put("achieved", achievedRate());
+            rate.put(totalRate > 0 ? "totalRate" : "writeRate", totalRate > 0 ? totalRate : writeRate);
+
+            Map<String, Object> stats = new LinkedHashMap<>();
+            stats.put("timestamp", Instant.now().toString());
+            stats.put("runId", runId);
+            stats.put("state", stopping ? "stopping" : paused ? "paused" : "running");
+            stats.put("requests", requests);
+            if ("transactional".equals(workload)) {
+                stats.put("transactions", Map.of(
+                        "committed", committedTransactions.get(),
+                        "rolledBack", rolledBackTransactions.get(),
+                        "commitUnknown", unknownTransactions.get()));
+            }
+            stats.put("statements", Map.of("mode", statementMode, "stale", staleStatements.get()));
+            if (probe != null) {
+                stats.put("probe", Map.of("probes", probe.getProbes(), "anomalies", probe.getAnomalies()));
+            }
+            stats.put("rate", rate);
+            if (!loadProfile.isConstant()) {
+                stats.put("profile", loadProfile.toString());
+            }
+            if (backpressure != null) {
+                stats.put("backpressure", Map.of(
+                        "inFlight", backpressure.getInFlight(),
+                        "queued", backpressure.getQueued(),
+                        "shed", backpressure.getShed()));
+            }
+            return stats;
+        }
+
+        @Override
+        public void pause() {
+            paused = true;
+            logger.info("[{}] CONTROL: Workload paused", getCurrentTime());
+        }
+
+        @Override
+        public void resume() {
+            paused = false;
+            logger.info("[{}] CONTROL: Workload resumed", getCurrentTime());
+        }
+
+        @Override
+        public void stop() {
+            logger.info("[{}] CONTROL: Stopping the simulator", getCurrentTime());
+            // Exiting runs the shutdown hook, which also closes this API
+            new Thread(() -> System.exit(0), "control-stop").start();
+        }
+
+        @Override
+        public void setRate(int rate) {
+            if (totalRate > 0) {
+                totalRate = rate;
+                logger.info("[{}] CONTROL: Write rate set to {} writes/sec shared by all workers", getCurrentTime(), rate);
+            } else {
+                writeRate = rate;
+                logger.info("[{}] CONTROL: Write rate set to {} writes/sec/worker", getCurrentTime(), rate);
+            }
+        }
+
+        @Override
+        public void markEvent(String name, String detail, Instant time) {
+            Map<String, Object> event = new LinkedHashMap<>();
+            event.put("time", time.toString());
+            event.put("event", name);
+            event.put("detail", detail);
+            events.add(event);
+            // The counters at the event tie the experiment's timeline to the workload
+            logger.info("[{}] EVENT: {} | {} | At: {}", getCurrentTime(), name, detail,
+                    formatTime(time.toEpochMilli()));
+            logCounters();
+        }
+
+        @Override
+        public List<Map<String, Object>> events() {
+            return new ArrayList<>(events);
+        }
     }
 
     /**
@@ -415,10 +1677,105 @@ public class WorkloadSimulator {
         logger.info("=".repeat(80));
         logger.info("FINAL STATISTICS");
         logger.info("=".repeat(80));
-        logStatistics();
+        logCounters();
+        List<LatencyTracker.Snapshot> latency = latencyTracker.total();
+        List<LatencyTracker.Snapshot> recovery = recoveryTracker.total();
+        for (LatencyTracker.Snapshot snapshot : latency) {
+            logger.info("[{}] LATENCY (run): {}", getCurrentTime(), snapshot);
+        }
+        for (LatencyTracker.Snapshot snapshot : recovery) {
+            logger.info("[{}] RECOVERY (run): {}", getCurrentTime(), snapshot);
+        }
+        if (!workers.isEmpty()) {
+            logger.info("[{}] RATE (run): Achieved: {} ops/sec", getCurrentTime(), String.format("%.1f", achievedRate()));
+        }
+        if (backpressure != null) {
+            logger.info("[{}] BACKPRESSURE (run): Shed: {}", getCurrentTime(), backpressure.getShed());
+        }
+        if (targets.size() > 1) {
+            logComparison(recovery);
+        }
+        writeStats("final", latency, recovery);
+        if (statsWriter != null) {
+            try {
+                statsWriter.close();
+            } catch (IOException e) {
+                logger.error("Failed to close statistics output: {}", e.getMessage());
+            }
+            logger.info("Statistics written to {}", outputFile);
+        }
+        if (runState != null) {
+            logRunSummary();
+        }
+        if (writeLedger != null) {
+            logger.info("Write ledger: {} acknowledged writes recorded to {}", writeLedger.getRecorded(), verifyLedgerPath);
+            logger.info("Run 'verify --ledger {}' against the new environment to check consistency", verifyLedgerPath);
+        }
         logger.info("=".repeat(80));
     }
 
+    /**
+     * Log the targets side by side, ranked by their slowest recovery and then their failed
+     * requests, so one run tells which connection path recovers fastest
+     */
+    private void logComparison(List<LatencyTracker.Snapshot> recovery) {
+        Map<String, LatencyTracker.Snapshot> byKey = new TreeMap<>();
+        for (LatencyTracker.Snapshot snapshot : recovery) {
+            byKey.put(snapshot.operation, snapshot);
+        }
+        // A target whose workers hit errors but never recovered ranks last
+        List<Target> ranked = new ArrayList<>(targets);
+        ranked.sort(java.util.Comparator
+                .comparingDouble((Target t) -> {
+                    LatencyTracker.Snapshot r = byKey.get(metricKey(connectionStrategy, t));
+                    return r != null ? r.max : t.hadErrors() ? Double.MAX_VALUE : 0.0;
+                })
+                .thenComparingLong(t -> t.failedRequests.get()));
+
+        for (Target target : ranked) {
+            long success = target.successfulRequests.get();
+            long failed = target.failedRequests.get();
+            long total = success + failed;
+            LatencyTracker.Snapshot r = byKey.get(metricKey(connectionStrategy, target));
+            logger.info("[{}] COMPARE: {} | Requests: {} | Failed: {} | Success Rate: {}% | Error window: {}s | Recoveries: {}{}",
+                    getCurrentTime(), target.name, total, failed,
+                    String.format("%.2f", total > 0 ? success * 100.0 / total : 0.0),
+                    String.format("%.1f", target.errorWindowMillis() / 1000.0),
+                    r != null ? r.count : 0,
+                    r != null ? String.format(" | Recovery p50: %.0fms | Max: %.0fms", r.p50, r.max) : "");
+        }
+
+        Target first = ranked.get(0);
+        if (ranked.stream().noneMatch(Target::hadErrors)) {
+            logger.info("[{}] COMPARE: No target saw connection errors", getCurrentTime());
+        } else {
+            logger.info("[{}] COMPARE: Fastest recovery: {} ({})", getCurrentTime(), first.name, first.endpoint);
+        }
+    }
+
+    /**
+     * Log the duration of the whole (possibly resumed) run and the periods the simulator was not
+     * running, which the counters do not cover
+     */
+    private void logRunSummary() {
+        long runStart = runState.getRunStart();
+        logger.info("[{}] RUN: Started: {} | Duration: {}s | Restarts: {}", getCurrentTime(), formatTime(runStart),
+                (System.currentTimeMillis() - runStart) / 1000, runState.getRestarts());
+        try {
+            for (long[] gap : runState.getGaps()) {
+                logger.info("[{}] RUN: Not running: {} - {} ({}s not observed)", getCurrentTime(),
+                        formatTime(gap[0]), formatTime(gap[1]), (gap[1] - gap[0]) / 1000);
+            }
+            runState.close();
+        } catch (SQLException e) {
+            logger.error("Failed to read run state: {}", e.getMessage());
+        }
+    }
+
+    private static String formatTime(long epochMillis) {
+        return LocalDateTime.ofInstant(Instant.ofEpochMilli(epochMillis), ZoneId.systemDefault()).format(timeFormatter);
+    }
+
     /**
      * Log startup banner
      */
@@ -435,19 +1792,56 @@ public class WorkloadSimulator {
     private void logConfiguration() {
         logger.info("Configuration:");
         logger.info("  Aurora Endpoint: {}", auroraEndpoint);
+        for (Target target : targets) {
+            logger.info("  Target {}: {}{} (driver {})", target.name, target.endpoint, target.proxy ? " (RDS Proxy)" : "",
+                    target.driver);
+        }
         logger.info("  Database Name: {}", databaseName);
-        logger.info("  Write Workers: {}", writeWorkers);
-        logger.info("  Write Rate: {} writes/sec/worker", writeRate);
+        logger.info("  Write Workers: {}{}", writeWorkers, targets.size() > 1 ? " per target" : "");
+        logger.info("  Workload: {}{}", workload,
+                "transactional".equals(workload) ? " (" + transactionSize + " tables per transaction)" : "");
+        if (totalRate > 0) {
+            logger.info("  Write Rate: {} writes/sec shared by all workers", totalRate);
+        } else {
+            logger.info("  Write Rate: {} writes/sec/worker", writeRate);
+        }
+        logger.info("  Load Profile: {}", loadProfile);
+        logger.info("  Max In-Flight: {}", maxInFlight > 0
+                ? maxInFlight + " (queue " + (maxQueued > 0 ? maxQueued : "unlimited") + ", timeout " + queueTimeout + "ms)"
+                : "unlimited");
+        logger.info("  Connection Strategy: {}{}", connectionStrategy,
+                "pool-max-lifetime".equals(connectionStrategy) ? " (max lifetime " + maxLifetime + "s)" : "");
+        logger.info("  Statement Mode: {}{}", statementMode, serverCursors ? " (server-side cursors)" : "");
         logger.info("  Connection Pool Size: {}", connectionPoolSize);
+        logger.info("  Read-After-Write Probe: {}", probeInterval > 0
+                ? "every " + probeInterval + "ms from " + probeReaderEndpoint + " (timeout " + probeTimeout
+                  + "ms, lag threshold " + probeLagThreshold + "ms)"
+                : "disabled");
+        logger.info("  TLS Mode: {}", tlsMode);
+        logger.info("  Authentication: {}", "iam".equals(auth) ? "IAM auth token" : "password");
         logger.info("  Log Interval: {} seconds", logInterval);
+        logger.info("  Duration: {}", duration > 0 ? duration + " seconds" : "until stopped");
+        logger.info("  Drain Timeout: {} seconds", drainTimeout);
+        logger.info("  Control API: {}", controlPort > 0 ? "127.0.0.1:" + controlPort : "disabled");
         logger.info("  Metrics Enabled: {}", enableMetrics);
+        logger.info("  Verify Ledger: {}", verifyLedgerPath != null ? verifyLedgerPath : "disabled");
+        logger.info("  State File: {}", stateFile != null ? stateFile : "disabled");
+        logger.info("  Run ID: {}", runId);
+        logger.info("  CloudWatch Metrics: {}", cloudwatchNamespace != null ? cloudwatchNamespace : "disabled");
+        logger.info("  Experiment Registry: {}", registryTable != null ? registryTable : "disabled");
+        logger.info("  Statistics Output: {}", "text".equals(outputFormat) ? "log only" : outputFormat + " -> " + outputFile);
+        logger.info("  DNS Tracking: {}", trackDns);
+        logger.info("  DNS Cache TTL: {}", dnsTtlOverride >= 0 ? dnsTtlOverride + "s (override)" : "JVM default");
+        logger.info("  Held Transactions: {}", holdTransactions > 0
+                ? holdTransactions + " x " + holdDuration + "s" + (holdTableLocks ? " (LOCK TABLES)" : "")
+                : "disabled");
         logger.info("=".repeat(80));
     }
 
     /**
      * Get current time as formatted string
      */
-    private static String getCurrentTime() {
+    static String getCurrentTime() {
         return LocalDateTime.now().format(timeFormatter);
     }
 
@@ -463,6 +1857,14 @@ public class WorkloadSimulator {
         // Set JUL root logger level to ALL to allow SLF4J bridge to control filtering
         java.util.logging.Logger.getLogger("").setLevel(java.util.logging.Level.ALL);
 
+        // Subcommands
+        if (args.length > 0 && "seed".equals(args[0])) {
+            System.exit(DataSeeder.run(java.util.Arrays.copyOfRange(args, 1, args.length)));
+        }
+        if (args.length > 0 && "verify".equals(args[0])) {
+            System.exit(ConsistencyVerifier.run(java.util.Arrays.copyOfRange(args, 1, args.length)));
+        }
+
         Options options = new Options();
 
         options.addOption(Option.builder()
@@ -472,6 +1874,29 @@ public class WorkloadSimulator {
                 .desc("Aurora cluster writer endpoint (required)")
                 .build());
 
+        options.addOption(Option.builder()
+                .longOpt("proxy-endpoint")
+                .hasArg()
+                .desc("Connect through this RDS Proxy endpoint, or auto to read it from --proxy-endpoint-parameter (default: disabled)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("proxy-endpoint-parameter")
+                .hasArg()
+                .desc("SSM parameter --proxy-endpoint auto reads (default: " + ProxyEndpoint.DEFAULT_PARAMETER + ")")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("dual-target")
+                .desc("Compare the cluster endpoint (direct) with the proxy endpoint (proxy), like --target direct=<aurora-endpoint> --target proxy=<proxy-endpoint> (default: false)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("target")
+                .hasArg()
+                .desc("NAME=HOST endpoint to compare, given once per endpoint; each gets --write-workers workers and its own pool, and HOST auto is the discovered proxy endpoint (default: the Aurora endpoint only)")
+                .build());
+
         options.addOption(Option.builder()
                 .longOpt("database-name")
                 .hasArg()
@@ -490,6 +1915,12 @@ public class WorkloadSimulator {
                 .desc("Database password (default: from environment variable DB_PASSWORD)")
                 .build());
 
+        options.addOption(Option.builder()
+                .longOpt("auth")
+                .hasArg()
+                .desc("Authentication: password or iam (RDS IAM auth tokens from the AWS credentials chain) (default: password)")
+                .build());
+
         options.addOption(Option.builder()
                 .longOpt("write-workers")
                 .hasArg()
@@ -504,6 +1935,61 @@ public class WorkloadSimulator {
                 .desc("Writes per second per worker (default: 100)")
                 .build());
 
+        options.addOption(Option.builder()
+                .longOpt("total-rate")
+                .hasArg()
+                .type(Number.class)
+                .desc("Writes per second of all workers together, shared fairly between them; replaces --write-rate")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("max-in-flight")
+                .hasArg()
+                .type(Number.class)
+                .desc("Operations running against the database at once; the others queue or are shed (default: unlimited)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("max-queued")
+                .hasArg()
+                .type(Number.class)
+                .desc("Operations waiting for an in-flight slot at once, with --max-in-flight (default: unlimited)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("queue-timeout")
+                .hasArg()
+                .type(Number.class)
+                .desc("Milliseconds an operation waits for an in-flight slot before it is shed (default: 1000)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("profile")
+                .hasArg()
+                .desc("Load profile scaling --write-rate over time: constant, ramp, step, spike or sine (default: constant)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("profile-duration")
+                .hasArg()
+                .type(Number.class)
+                .desc("Seconds the load profile plays over; the period of spike and sine (default: 600)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("profile-min")
+                .hasArg()
+                .type(Number.class)
+                .desc("Lowest rate of the load profile, as a percentage of --write-rate (default: 20)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("profile-steps")
+                .hasArg()
+                .type(Number.class)
+                .desc("Number of steps of the step profile (default: 4)")
+                .build());
+
         options.addOption(Option.builder()
                 .longOpt("connection-pool-size")
                 .hasArg()
@@ -518,11 +2004,189 @@ public class WorkloadSimulator {
                 .desc("Statistics log interval in seconds (default: 10)")
                 .build());
 
+        options.addOption(Option.builder()
+                .longOpt("duration")
+                .hasArg()
+                .type(Number.class)
+                .desc("Stop the run after this many seconds, as on SIGTERM (default: run until stopped)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("drain-timeout")
+                .hasArg()
+                .type(Number.class)
+                .desc("Seconds the workers get to finish their operations in flight on shutdown (default: 10)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("control-port")
+                .hasArg()
+                .type(Number.class)
+                .desc("Port of the HTTP control API on 127.0.0.1; the API is off unless a port is given (default: 0, disabled)")
+                .build());
+
         options.addOption(Option.builder()
                 .longOpt("enable-metrics")
                 .desc("Enable Prometheus metrics server on port 8080 (default: false)")
                 .build());
 
+        options.addOption(Option.builder()
+                .longOpt("verify-ledger")
+                .hasArg()
+                .desc("Record every acknowledged write to this ledger file for the verify subcommand (default: disabled)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("cloudwatch-namespace")
+                .hasArg()
+                .desc("Publish counts and latency percentiles as CloudWatch custom metrics in this namespace (default: disabled)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("run-id")
+                .hasArg()
+                .desc("Run ID written to the statistics, metrics and registry, e.g. of the lab-scenario run (default: $LAB_RUN_ID, else sim-<start time>)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("registry-table")
+                .hasArg()
+                .desc("Register the run with its results in this experiment registry table of the monitoring stack (default: disabled)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("output-format")
+                .hasArg()
+                .desc("Statistics output format: text (log only), json (JSON Lines) or csv (default: text)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("output-file")
+                .hasArg()
+                .desc("File the json or csv statistics are appended to (required with --output-format json|csv)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("state-file")
+                .hasArg()
+                .desc("Persist run state to this SQLite file and resume the run from it after a restart (default: disabled)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("track-dns")
+                .desc("Resolve the cluster and reader endpoints every second and log DNS changes (default: false)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("tls-mode")
+                .hasArg()
+                .desc("TLS mode: disabled, preferred, required or verify-ca (default: preferred)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("tls-ca-bundle")
+                .hasArg()
+                .desc("PEM CA bundle for verify-ca (default: the bundled RDS global bundle)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("dns-ttl-override")
+                .hasArg()
+                .type(Number.class)
+                .desc("Cache resolved endpoints in-process for this many seconds, like a JVM/OS DNS cache (default: JVM default)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("workload")
+                .hasArg()
+                .desc("Workload type: insert (single-row INSERTs) or transactional (read-modify-write transactions) (default: insert)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("transaction-size")
+                .hasArg()
+                .type(Number.class)
+                .desc("Tables read and written per transaction in the transactional workload (default: 3)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("hold-transactions")
+                .hasArg()
+                .type(Number.class)
+                .desc("Number of long-running write transactions kept open during the run (default: 0, disabled)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("hold-duration")
+                .hasArg()
+                .type(Number.class)
+                .desc("Seconds each held transaction or table lock stays open before the next one starts (default: 120)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("hold-table-locks")
+                .desc("Hold LOCK TABLES ... WRITE instead of open write transactions (default: false)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("connection-strategy")
+                .hasArg()
+                .desc("Connection management: fresh, pool, pool-max-lifetime or pool-validation (default: pool)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("max-lifetime")
+                .hasArg()
+                .type(Number.class)
+                .desc("Pooled connection max lifetime in seconds for pool-max-lifetime (default: 30, min: 30)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("statement-mode")
+                .hasArg()
+                .desc("Statements: prepared, text (plain text queries) or reuse (prepared once per worker " +
+                      "and reused across the switchover) (default: prepared)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("server-cursors")
+                .desc("Fetch result sets through server-side cursors (useCursorFetch) (default: false)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("probe-interval")
+                .hasArg()
+                .type(Number.class)
+                .desc("Milliseconds between read-after-write probes, 0 to disable (default: 0)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("probe-reader-endpoint")
+                .hasArg()
+                .desc("Endpoint the probe reads from (default: the cluster's reader endpoint)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("probe-timeout")
+                .hasArg()
+                .type(Number.class)
+                .desc("Milliseconds a probe waits for its write to become visible (default: 5000)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("probe-lag-threshold")
+                .hasArg()
+                .type(Number.class)
+                .desc("Read-your-writes lag in milliseconds above which a probe is an anomaly (default: 1000)")
+                .build());
+
+        options.addOption(Option.builder()
+                .longOpt("driver")
+                .hasArg()
+                .desc("Driver profile: wrapper, plain, compressed or client-prepare (default: wrapper); " +
+                      "repeat to compare profiles side by side against the cluster endpoint")
+                .build());
+
         options.addOption("h", "help", false, "Show help message");
 
         CommandLineParser parser = new DefaultParser();
@@ -540,8 +2204,20 @@ public class WorkloadSimulator {
             String databaseName = cmd.getOptionValue("database-name", "lab_db");
             String username = cmd.getOptionValue("username", "admin");
             String password = cmd.getOptionValue("password", System.getenv("DB_PASSWORD"));
+            String auth = cmd.getOptionValue("auth", "password");
+            String proxyEndpoint = cmd.getOptionValue("proxy-endpoint");
+            boolean dualTarget = cmd.hasOption("dual-target");
+            String[] targetOptions = cmd.getOptionValues("target");
+
+            if (!"password".equals(auth) && !"iam".equals(auth)) {
+                logger.error("Unknown authentication: {} (expected password or iam)", auth);
+                System.exit(1);
+            }
 
-            if (password == null || password.isEmpty()) {
+            if ("iam".equals(auth)) {
+                // The iam plugin replaces the password with a generated token
+                password = "";
+            } else if (password == null || password.isEmpty()) {
                 logger.error("Database password not provided. Use --password or set DB_PASSWORD environment variable.");
                 System.exit(1);
             }
@@ -552,13 +2228,116 @@ public class WorkloadSimulator {
             int writeRate = cmd.hasOption("write-rate")
                     ? ((Number) cmd.getParsedOptionValue("write-rate")).intValue()
                     : 100;
+            int totalRate = cmd.hasOption("total-rate")
+                    ? ((Number) cmd.getParsedOptionValue("total-rate")).intValue()
+                    : 0;
+            if (cmd.hasOption("total-rate") && totalRate < 1) {
+                logger.error("--total-rate must be at least 1, got {}", totalRate);
+                System.exit(1);
+            }
+            int maxInFlight = cmd.hasOption("max-in-flight")
+                    ? ((Number) cmd.getParsedOptionValue("max-in-flight")).intValue()
+                    : 0;
+            int maxQueued = cmd.hasOption("max-queued")
+                    ? ((Number) cmd.getParsedOptionValue("max-queued")).intValue()
+                    : 0;
+            int queueTimeout = cmd.hasOption("queue-timeout")
+                    ? ((Number) cmd.getParsedOptionValue("queue-timeout")).intValue()
+                    : 1000;
+            if (maxInFlight < 0 || maxQueued < 0 || queueTimeout < 0) {
+                logger.error("--max-in-flight, --max-queued and --queue-timeout must not be negative");
+                System.exit(1);
+            }
+            if ((cmd.hasOption("max-queued") || cmd.hasOption("queue-timeout")) && maxInFlight == 0) {
+                logger.error("--max-queued and --queue-timeout require --max-in-flight");
+                System.exit(1);
+            }
+            String profileShape = cmd.getOptionValue("profile", "constant");
+            int profileDuration = cmd.hasOption("profile-duration")
+                    ? ((Number) cmd.getParsedOptionValue("profile-duration")).intValue()
+                    : 600;
+            int profileMin = cmd.hasOption("profile-min")
+                    ? ((Number) cmd.getParsedOptionValue("profile-min")).intValue()
+                    : 20;
+            int profileSteps = cmd.hasOption("profile-steps")
+                    ? ((Number) cmd.getParsedOptionValue("profile-steps")).intValue()
+                    : 4;
+            LoadProfile loadProfile = null;
+            try {
+                loadProfile = new LoadProfile(profileShape, profileDuration, profileMin, profileSteps);
+            } catch (IllegalArgumentException e) {
+                logger.error("Invalid load profile: {}", e.getMessage());
+                System.exit(1);
+            }
             int connectionPoolSize = cmd.hasOption("connection-pool-size")
                     ? ((Number) cmd.getParsedOptionValue("connection-pool-size")).intValue()
                     : 100;
             int logInterval = cmd.hasOption("log-interval")
                     ? ((Number) cmd.getParsedOptionValue("log-interval")).intValue()
                     : 10;
+            int duration = cmd.hasOption("duration")
+                    ? ((Number) cmd.getParsedOptionValue("duration")).intValue()
+                    : 0;
+            int drainTimeout = cmd.hasOption("drain-timeout")
+                    ? ((Number) cmd.getParsedOptionValue("drain-timeout")).intValue()
+                    : 10;
+            int controlPort = cmd.hasOption("control-port")
+                    ? ((Number) cmd.getParsedOptionValue("control-port")).intValue()
+                    : 0;
+            if (controlPort < 0 || controlPort > 65535) {
+                logger.error("--control-port must be between 0 and 65535, got {}", controlPort);
+                System.exit(1);
+            }
+            if (duration < 0 || drainTimeout < 0) {
+                logger.error("--duration and --drain-timeout must not be negative");
+                System.exit(1);
+            }
             boolean enableMetrics = cmd.hasOption("enable-metrics");
+            String verifyLedgerPath = cmd.getOptionValue("verify-ledger");
+            String stateFile = cmd.getOptionValue("state-file");
+            String outputFormat = cmd.getOptionValue("output-format", "text");
+            String outputFile = cmd.getOptionValue("output-file");
+            String cloudwatchNamespace = cmd.getOptionValue("cloudwatch-namespace");
+            String runId = cmd.getOptionValue("run-id", System.getenv("LAB_RUN_ID"));
+            if (runId == null || runId.isEmpty()) {
+                runId = "sim-" + LocalDateTime.now().format(DateTimeFormatter.ofPattern("yyyyMMdd-HHmmss"));
+            }
+            String registryTable = cmd.getOptionValue("registry-table");
+            boolean trackDns = cmd.hasOption("track-dns");
+            String tlsMode = cmd.getOptionValue("tls-mode", "preferred");
+            String tlsCaBundle = cmd.getOptionValue("tls-ca-bundle");
+            int dnsTtlOverride = cmd.hasOption("dns-ttl-override")
+                    ? ((Number) cmd.getParsedOptionValue("dns-ttl-override")).intValue()
+                    : -1;
+            String workload = cmd.getOptionValue("workload", "insert");
+            int transactionSize = cmd.hasOption("transaction-size")
+                    ? ((Number) cmd.getParsedOptionValue("transaction-size")).intValue()
+                    : 3;
+            int holdTransactions = cmd.hasOption("hold-transactions")
+                    ? ((Number) cmd.getParsedOptionValue("hold-transactions")).intValue()
+                    : 0;
+            int holdDuration = cmd.hasOption("hold-duration")
+                    ? ((Number) cmd.getParsedOptionValue("hold-duration")).intValue()
+                    : 120;
+            boolean holdTableLocks = cmd.hasOption("hold-table-locks");
+            String connectionStrategy = cmd.getOptionValue("connection-strategy", "pool");
+            int maxLifetime = cmd.hasOption("max-lifetime")
+                    ? ((Number) cmd.getParsedOptionValue("max-lifetime")).intValue()
+                    : 30;
+            String statementMode = cmd.getOptionValue("statement-mode", "prepared");
+            boolean serverCursors = cmd.hasOption("server-cursors");
+            int probeInterval = cmd.hasOption("probe-interval")
+                    ? ((Number) cmd.getParsedOptionValue("probe-interval")).intValue()
+                    : 0;
+            String probeReaderEndpoint = cmd.getOptionValue("probe-reader-endpoint");
+            int probeTimeout = cmd.hasOption("probe-timeout")
+                    ? ((Number) cmd.getParsedOptionValue("probe-timeout")).intValue()
+                    : 5000;
+            int probeLagThreshold = cmd.hasOption("probe-lag-threshold")
+                    ? ((Number) cmd.getParsedOptionValue("probe-lag-threshold")).intValue()
+                    : 1000;
+            String[] driverOptions = cmd.getOptionValues("driver");
+            List<String> drivers = driverOptions != null ? List.of(driverOptions) : List.of("wrapper");
 
             // Validate parameters
             if (writeWorkers < 1) {
@@ -566,17 +2345,235 @@ public class WorkloadSimulator {
                 System.exit(1);
             }
 
-            if (connectionPoolSize < writeWorkers) {
+            if (!"insert".equals(workload) && !"transactional".equals(workload)) {
+                logger.error("Unknown workload: {} (expected insert or transactional)", workload);
+                System.exit(1);
+            }
+
+            if (transactionSize < 1 || transactionSize > 100) {
+                logger.error("Transaction size must be between 1 and 100. Provided: {}", transactionSize);
+                System.exit(1);
+            }
+
+            if ("transactional".equals(workload) && verifyLedgerPath != null) {
+                // Transactions update existing rows, which the ledger checksums cannot follow
+                logger.error("--verify-ledger is only supported with the insert workload");
+                System.exit(1);
+            }
+
+            if (holdTransactions < 0 || holdDuration < 1) {
+                logger.error("--hold-transactions must not be negative and --hold-duration must be at least 1 second");
+                System.exit(1);
+            }
+
+            if (!List.of("fresh", "pool", "pool-max-lifetime", "pool-validation").contains(connectionStrategy)) {
+                logger.error("Unknown connection strategy: {} (expected fresh, pool, pool-max-lifetime or pool-validation)",
+                        connectionStrategy);
+                System.exit(1);
+            }
+
+            for (String driver : drivers) {
+                if (!DRIVERS.contains(driver)) {
+                    logger.error("Unknown driver profile: {} (expected wrapper, plain, compressed or client-prepare)", driver);
+                    System.exit(1);
+                }
+                if (drivers.indexOf(driver) != drivers.lastIndexOf(driver)) {
+                    logger.error("--driver {} is given more than once", driver);
+                    System.exit(1);
+                }
+            }
+
+            if (!List.of("prepared", "text", "reuse").contains(statementMode)) {
+                logger.error("Unknown statement mode: {} (expected prepared, text or reuse)", statementMode);
+                System.exit(1);
+            }
+
+            if ("reuse".equals(statementMode)) {
+                // Each worker holds one connection for its prepared statements
+                if (!"insert".equals(workload)) {
+                    logger.error("--statement-mode reuse is only supported with the insert workload");
+                    System.exit(1);
+                }
+                if ("fresh".equals(connectionStrategy)) {
+                    logger.error("--statement-mode reuse holds a connection per worker; it cannot be combined with --connection-strategy fresh");
+                    System.exit(1);
+                }
+                if (connectionPoolSize < writeWorkers) {
+                    logger.error("--statement-mode reuse holds a connection per worker; --connection-pool-size ({}) must be at least --write-workers ({})",
+                            connectionPoolSize, writeWorkers);
+                    System.exit(1);
+                }
+            }
+
+            if (probeInterval < 0 || probeTimeout < 1 || probeLagThreshold < 1) {
+                logger.error("--probe-interval must not be negative, and --probe-timeout and --probe-lag-threshold must be at least 1ms");
+                System.exit(1);
+            }
+
+            if (probeInterval > 0 && probeReaderEndpoint == null) {
+                // Reader endpoint: <name>.cluster-ro-<id>.<region>.rds.amazonaws.com
+                if (!auroraEndpoint.contains(".cluster-") || auroraEndpoint.contains(".cluster-ro-")) {
+                    logger.error("Cannot derive the reader endpoint from {}; pass --probe-reader-endpoint", auroraEndpoint);
+                    System.exit(1);
+                }
+                probeReaderEndpoint = auroraEndpoint.replace(".cluster-", ".cluster-ro-");
+            }
+
+            if (serverCursors && ("text".equals(statementMode) || drivers.contains("client-prepare"))) {
+                // Connector/J opens cursors for server-prepared statements only
+                logger.error("--server-cursors requires server-prepared statements; it cannot be combined with --statement-mode text or --driver client-prepare");
+                System.exit(1);
+            }
+
+            if (drivers.contains("plain") && "iam".equals(auth)) {
+                // IAM tokens are generated by the wrapper's iam plugin
+                logger.error("--driver plain cannot be combined with --auth iam; the iam plugin is part of the wrapper");
+                System.exit(1);
+            }
+
+            if (drivers.size() > 1 && (targetOptions != null || proxyEndpoint != null)) {
+                logger.error("Several --driver profiles cannot be combined with --target, --proxy-endpoint or --dual-target; " +
+                        "each profile writes to the cluster endpoint");
+                System.exit(1);
+            }
+
+            if (maxLifetime < 30) {
+                // HikariCP does not allow a max lifetime below 30 seconds
+                logger.error("--max-lifetime must be at least 30 seconds. Provided: {}", maxLifetime);
+                System.exit(1);
+            }
+
+            if (!List.of("disabled", "preferred", "required", "verify-ca").contains(tlsMode)) {
+                logger.error("Unknown TLS mode: {} (expected disabled, preferred, required or verify-ca)", tlsMode);
+                System.exit(1);
+            }
+
+            if (!runId.matches("[A-Za-z0-9][A-Za-z0-9._-]{0,127}")) {
+                // The run ID is a tag value, a DynamoDB key and a file name of the lab tools
+                logger.error("Invalid run ID: {} (at most 128 letters, digits and ._- characters, starting with a letter or digit)", runId);
+                System.exit(1);
+            }
+
+            if (!List.of("text", "json", "csv").contains(outputFormat)) {
+                logger.error("Unknown output format: {} (expected text, json or csv)", outputFormat);
+                System.exit(1);
+            }
+
+            if ("text".equals(outputFormat) != (outputFile == null)) {
+                logger.error("--output-file is required with --output-format json or csv, and only with them");
+                System.exit(1);
+            }
+
+            if ("iam".equals(auth) && "disabled".equals(tlsMode)) {
+                // RDS rejects IAM authentication over unencrypted connections
+                logger.error("--auth iam requires TLS; use --tls-mode preferred, required or verify-ca");
+                System.exit(1);
+            }
+
+            if (dualTarget && proxyEndpoint == null) {
+                logger.error("--dual-target requires --proxy-endpoint");
+                System.exit(1);
+            }
+
+            if (targetOptions != null && (proxyEndpoint != null || dualTarget)) {
+                logger.error("--target cannot be combined with --proxy-endpoint or --dual-target; use --target proxy=HOST or proxy=auto");
+                System.exit(1);
+            }
+
+            if (targetOptions != null && targetOptions.length < 2) {
+                logger.error("--target compares endpoints; give it once per endpoint, at least twice");
+                System.exit(1);
+            }
+
+            if (tlsCaBundle != null && !"verify-ca".equals(tlsMode)) {
+                logger.error("--tls-ca-bundle requires --tls-mode verify-ca");
+                System.exit(1);
+            }
+
+            if (cmd.hasOption("dns-ttl-override")) {
+                if (dnsTtlOverride < 0) {
+                    logger.error("--dns-ttl-override must not be negative. Provided: {}", dnsTtlOverride);
+                    System.exit(1);
+                }
+                // The JVM reads the cache policy on its first lookup, so this must happen before
+                // the simulator resolves the endpoint
+                Security.setProperty("networkaddress.cache.ttl", String.valueOf(dnsTtlOverride));
+            }
+
+            // The endpoints the workers write through: --target, the cluster endpoint once per
+            // compared --driver profile, or the cluster endpoint and the proxy endpoint
+            Map<String, String> endpoints = new LinkedHashMap<>();
+            if (targetOptions != null) {
+                for (String option : targetOptions) {
+                    String[] parts = option.split("=", 2);
+                    if (parts.length != 2 || !TARGET_NAME.matcher(parts[0]).matches() || parts[1].isEmpty()) {
+                        logger.error("--target must be NAME=HOST, the name of lowercase letters, digits and dashes. Provided: {}", option);
+                        System.exit(1);
+                    }
+                    if (endpoints.put(parts[0], parts[1]) != null) {
+                        logger.error("--target {} is given more than once", parts[0]);
+                        System.exit(1);
+                    }
+                }
+            } else if (drivers.size() > 1) {
+                for (String driver : drivers) {
+                    endpoints.put(driver, auroraEndpoint);
+                }
+            } else {
+                if (proxyEndpoint == null || dualTarget) {
+                    endpoints.put("direct", auroraEndpoint);
+                }
+                if (proxyEndpoint != null) {
+                    endpoints.put("proxy", proxyEndpoint);
+                }
+            }
+
+            String parameterName = cmd.getOptionValue("proxy-endpoint-parameter", ProxyEndpoint.DEFAULT_PARAMETER);
+            List<Target> targets = new ArrayList<>();
+            for (Map.Entry<String, String> entry : endpoints.entrySet()) {
+                String endpoint = entry.getValue();
+                // --proxy-endpoint, a discovered endpoint and RDS Proxy hostnames (name.proxy-id.region...)
+                boolean proxy = "auto".equals(endpoint) || endpoint.contains(".proxy-")
+                        || (targetOptions == null && "proxy".equals(entry.getKey()));
+                if ("auto".equals(endpoint)) {
+                    try {
+                        endpoint = ProxyEndpoint.resolve(endpoint, parameterName, auroraEndpoint);
+                    } catch (RuntimeException e) {
+                        logger.error("Failed to discover the RDS Proxy endpoint: {}", e.getMessage());
+                        System.exit(1);
+                    }
+                    logger.info("Discovered RDS Proxy endpoint {} from {}", endpoint, parameterName);
+                }
+                if (proxy && "iam".equals(auth)) {
+                    // The lab's proxy authenticates clients with its credentials secret (IamAuth DISABLED)
+                    logger.error("--auth iam is not supported through RDS Proxy ({}); the lab's proxy uses password authentication", endpoint);
+                    System.exit(1);
+                }
+                String driver = drivers.size() > 1 ? entry.getKey() : drivers.get(0);
+                targets.add(new Target(entry.getKey(), endpoint, proxy, driver));
+            }
+
+            // --max-in-flight bounds the connections the workers use at once
+            if (connectionPoolSize < writeWorkers && (maxInFlight == 0 || connectionPoolSize < maxInFlight)) {
                 logger.warn("Connection pool size ({}) is less than worker count ({}). " +
                         "This may cause connection contention.", connectionPoolSize, writeWorkers);
             }
 
             WorkloadSimulator simulator = new WorkloadSimulator(
                     auroraEndpoint, databaseName, username, password,
-                    writeWorkers, writeRate, connectionPoolSize, logInterval, enableMetrics
+                    writeWorkers, writeRate, totalRate, loadProfile, maxInFlight, maxQueued, queueTimeout,
+                    connectionPoolSize, logInterval, duration, drainTimeout, controlPort, enableMetrics,
+                    verifyLedgerPath, trackDns, workload, transactionSize,
+                    holdTransactions, holdDuration, holdTableLocks,
+                    connectionStrategy, maxLifetime, statementMode, serverCursors,
+                    probeInterval, probeReaderEndpoint, probeTimeout, probeLagThreshold, dnsTtlOverride,
+                    tlsMode, tlsCaBundle, auth, stateFile,
+                    outputFormat, outputFile, cloudwatchNamespace, runId, registryTable, targets
             );
 
             simulator.start();
+            // The workers stopped on a signal or at the end of --duration; exiting runs the shutdown hook
+            System.exit(0);
 
         } catch (ParseException e) {
             logger.error("Failed to parse command line arguments: {}", e.getMessage());